@@ -9,16 +9,19 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/m1k1o/neko/server/internal/analytics"
 	"github.com/m1k1o/neko/server/internal/api"
 	"github.com/m1k1o/neko/server/internal/capture"
 	"github.com/m1k1o/neko/server/internal/config"
 	"github.com/m1k1o/neko/server/internal/desktop"
+	"github.com/m1k1o/neko/server/internal/errorbus"
 	"github.com/m1k1o/neko/server/internal/http"
 	"github.com/m1k1o/neko/server/internal/member"
 	"github.com/m1k1o/neko/server/internal/plugins"
 	"github.com/m1k1o/neko/server/internal/session"
 	"github.com/m1k1o/neko/server/internal/webrtc"
 	"github.com/m1k1o/neko/server/internal/websocket"
+	"github.com/m1k1o/neko/server/pkg/types"
 )
 
 func init() {
@@ -43,16 +46,19 @@ type serve struct {
 	logger zerolog.Logger
 
 	configs struct {
-		Desktop config.Desktop
-		Capture config.Capture
-		WebRTC  config.WebRTC
-		Member  config.Member
-		Session config.Session
-		Plugins config.Plugins
-		Server  config.Server
+		Desktop   config.Desktop
+		Capture   config.Capture
+		WebRTC    config.WebRTC
+		Member    config.Member
+		Session   config.Session
+		WebSocket config.WebSocket
+		Plugins   config.Plugins
+		Server    config.Server
+		Analytics config.Analytics
 	}
 
 	managers struct {
+		errorBus  *errorbus.ErrorBusCtx
 		desktop   *desktop.DesktopManagerCtx
 		capture   *capture.CaptureManagerCtx
 		webRTC    *webrtc.WebRTCManagerCtx
@@ -62,6 +68,7 @@ type serve struct {
 		plugins   *plugins.ManagerCtx
 		api       *api.ApiManagerCtx
 		http      *http.HttpManagerCtx
+		analytics *analytics.AnalyticsManagerCtx
 	}
 }
 
@@ -81,12 +88,18 @@ func (c *serve) Init(cmd *cobra.Command) error {
 	if err := c.configs.Session.Init(cmd); err != nil {
 		return err
 	}
+	if err := c.configs.WebSocket.Init(cmd); err != nil {
+		return err
+	}
 	if err := c.configs.Plugins.Init(cmd); err != nil {
 		return err
 	}
 	if err := c.configs.Server.Init(cmd); err != nil {
 		return err
 	}
+	if err := c.configs.Analytics.Init(cmd); err != nil {
+		return err
+	}
 
 	// legacy if explicitly enabled or if unspecified and legacy config is found
 	if viper.GetBool("legacy") || !viper.IsSet("legacy") {
@@ -121,8 +134,10 @@ func (c *serve) PreRun(cmd *cobra.Command, args []string) {
 	c.configs.WebRTC.Set()
 	c.configs.Member.Set()
 	c.configs.Session.Set()
+	c.configs.WebSocket.Set()
 	c.configs.Plugins.Set()
 	c.configs.Server.Set()
+	c.configs.Analytics.Set()
 
 	// legacy if explicitly enabled or if unspecified and legacy config is found
 	if viper.GetBool("legacy") || !viper.IsSet("legacy") {
@@ -136,10 +151,18 @@ func (c *serve) PreRun(cmd *cobra.Command, args []string) {
 }
 
 func (c *serve) Start(cmd *cobra.Command) {
+	c.managers.errorBus = errorbus.New()
+
 	c.managers.session = session.New(
 		&c.configs.Session,
 	)
 
+	c.managers.analytics = analytics.New(
+		c.managers.session,
+		&c.configs.Analytics,
+	)
+	c.managers.analytics.Start()
+
 	c.managers.member = member.New(
 		c.managers.session,
 		&c.configs.Member,
@@ -163,6 +186,7 @@ func (c *serve) Start(cmd *cobra.Command) {
 	c.managers.webRTC = webrtc.New(
 		c.managers.desktop,
 		c.managers.capture,
+		c.managers.errorBus,
 		&c.configs.WebRTC,
 	)
 	c.managers.webRTC.Start()
@@ -172,6 +196,8 @@ func (c *serve) Start(cmd *cobra.Command) {
 		c.managers.desktop,
 		c.managers.capture,
 		c.managers.webRTC,
+		c.managers.errorBus,
+		&c.configs.WebSocket,
 	)
 	c.managers.webSocket.Start()
 
@@ -181,6 +207,7 @@ func (c *serve) Start(cmd *cobra.Command) {
 		c.managers.desktop,
 		c.managers.capture,
 	)
+	c.managers.api.AddRouter("/events", c.managers.webSocket.LifecycleRoute)
 
 	c.managers.plugins = plugins.New(
 		&c.configs.Plugins,
@@ -194,6 +221,7 @@ func (c *serve) Start(cmd *cobra.Command) {
 	c.managers.plugins.Start(
 		c.managers.session,
 		c.managers.webSocket,
+		c.managers.webRTC,
 		c.managers.api,
 	)
 
@@ -205,29 +233,31 @@ func (c *serve) Start(cmd *cobra.Command) {
 	c.managers.http.Start()
 }
 
+// Shutdown stops accepting new connections first, then disconnects clients and
+// closes their peers, so that nothing is sent over closed connections, and only
+// then tears down webrtc, capture and desktop.
 func (c *serve) Shutdown() {
-	var err error
-
-	err = c.managers.http.Shutdown()
-	c.logger.Err(err).Msg("http manager shutdown")
-
-	err = c.managers.plugins.Shutdown()
-	c.logger.Err(err).Msg("plugins manager shutdown")
-
-	err = c.managers.webSocket.Shutdown()
-	c.logger.Err(err).Msg("websocket manager shutdown")
-
-	err = c.managers.webRTC.Shutdown()
-	c.logger.Err(err).Msg("webrtc manager shutdown")
-
-	err = c.managers.capture.Shutdown()
-	c.logger.Err(err).Msg("capture manager shutdown")
-
-	err = c.managers.desktop.Shutdown()
-	c.logger.Err(err).Msg("desktop manager shutdown")
+	runShutdown(c.logger, c.configs.Server.ShutdownTimeout, shutdownTeardownTimeout, []shutdownStep{
+		{"http manager shutdown", c.managers.http.Shutdown, false},
+		{"plugins manager shutdown", c.managers.plugins.Shutdown, false},
+		{"websocket manager shutdown", c.managers.webSocket.Shutdown, false},
+		{"webrtc peers closed", c.closePeers, false},
+		{"analytics manager shutdown", c.managers.analytics.Shutdown, false},
+		{"webrtc manager shutdown", c.managers.webRTC.Shutdown, false},
+		{"capture manager shutdown", c.managers.capture.Shutdown, true},
+		{"desktop manager shutdown", c.managers.desktop.Shutdown, true},
+		{"member manager disconnect", c.managers.member.Disconnect, true},
+	})
+}
 
-	err = c.managers.member.Disconnect()
-	c.logger.Err(err).Msg("member manager disconnect")
+// closePeers destroys webrtc peers of all sessions, peers are not closed with
+// websocket connections, because clients may reconnect.
+func (c *serve) closePeers() error {
+	c.managers.session.Range(func(session types.Session) bool {
+		c.managers.webRTC.ClosePeers(session)
+		return true
+	})
+	return nil
 }
 
 func (c *serve) Run(cmd *cobra.Command, args []string) {
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// how long each teardown step may take once the shutdown timed out
+const shutdownTeardownTimeout = 5 * time.Second
+
+type shutdownStep struct {
+	name string
+	fn   func() error
+	// releases system resources (e.g. pipelines, X server), it runs
+	// even after the shutdown timed out
+	teardown bool
+}
+
+// runShutdown runs steps one after another, each is started only after the
+// previous one finished. When timeout elapses, the step in progress is left
+// running and remaining steps are skipped, except teardown steps, that are
+// run on a best-effort basis, each limited by teardownTimeout. Returns false
+// on timeout.
+func runShutdown(logger zerolog.Logger, timeout, teardownTimeout time.Duration, steps []shutdownStep) bool {
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	timedOut := false
+	for _, step := range steps {
+		if timedOut && !step.teardown {
+			logger.Warn().Str("step", step.name).Msg("shutdown step skipped")
+			continue
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- step.fn()
+		}()
+
+		// after timeout, each teardown step has its own limit
+		stepDeadline := deadline
+		if timedOut {
+			timer := time.NewTimer(teardownTimeout)
+			stepDeadline = timer.C
+			defer timer.Stop()
+		}
+
+		select {
+		case err := <-done:
+			logger.Err(err).Msg(step.name)
+		case <-stepDeadline:
+			if timedOut {
+				logger.Error().
+					Str("step", step.name).
+					Dur("timeout", teardownTimeout).
+					Msg("shutdown teardown step timed out")
+				continue
+			}
+
+			logger.Error().
+				Str("step", step.name).
+				Dur("timeout", timeout).
+				Msg("shutdown timed out, skipping remaining steps except teardown")
+			timedOut = true
+		}
+	}
+
+	return !timedOut
+}
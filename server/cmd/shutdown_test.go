@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestRunShutdown(t *testing.T) {
+	var ran []string
+	step := func(name string, err error) shutdownStep {
+		return shutdownStep{name, func() error {
+			ran = append(ran, name)
+			return err
+		}, false}
+	}
+
+	ok := runShutdown(zerolog.Nop(), time.Second, time.Second, []shutdownStep{
+		step("first", nil),
+		step("second", errors.New("failed")),
+		step("third", nil),
+	})
+
+	// failed step does not stop the shutdown
+	if !ok {
+		t.Error("runShutdown() = false, want true")
+	}
+	if want := []string{"first", "second", "third"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("steps ran %v, want %v", ran, want)
+	}
+}
+
+func TestRunShutdownTimeout(t *testing.T) {
+	ran := make(chan string, 8)
+	step := func(name string, teardown bool) shutdownStep {
+		return shutdownStep{name, func() error {
+			ran <- name
+			return nil
+		}, teardown}
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+
+	ok := runShutdown(zerolog.Nop(), 20*time.Millisecond, 20*time.Millisecond, []shutdownStep{
+		{"stuck", func() error {
+			<-release
+			return nil
+		}, false},
+		step("skipped", false),
+		step("capture", true),
+		{"stuck teardown", func() error {
+			<-release
+			return nil
+		}, true},
+		step("desktop", true),
+		step("member", true),
+	})
+
+	if ok {
+		t.Error("runShutdown() = true, want timeout")
+	}
+
+	close(ran)
+	var got []string
+	for name := range ran {
+		got = append(got, name)
+	}
+
+	// teardown continues after timeout, even past a stuck teardown step
+	if want := []string{"capture", "desktop", "member"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("steps ran %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,201 @@
+package analytics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+type sessionStats struct {
+	connectedAt   time.Time
+	watchingSince *time.Time
+	watching      time.Duration
+	watched       bool
+	hostCount     int
+	videos        map[string]struct{}
+}
+
+func New(sessions types.SessionManager, config *config.Analytics) *AnalyticsManagerCtx {
+	logger := log.With().Str("module", "analytics").Logger()
+
+	var sink types.AnalyticsSink = noopSink{}
+	if config.Sink == "file" {
+		fileSink, err := newFileSink(config.File)
+		if err != nil {
+			logger.Err(err).Str("file", config.File).Msg("unable to open analytics file, records are discarded")
+		} else {
+			sink = fileSink
+		}
+	}
+
+	return &AnalyticsManagerCtx{
+		logger:   logger,
+		config:   config,
+		sessions: sessions,
+		sink:     sink,
+		stats:    map[string]*sessionStats{},
+	}
+}
+
+type AnalyticsManagerCtx struct {
+	logger   zerolog.Logger
+	config   *config.Analytics
+	sessions types.SessionManager
+	sink     types.AnalyticsSink
+
+	stats   map[string]*sessionStats
+	statsMu sync.Mutex
+}
+
+func (manager *AnalyticsManagerCtx) Start() {
+	manager.sessions.OnConnected(func(session types.Session) {
+		manager.statsMu.Lock()
+		defer manager.statsMu.Unlock()
+
+		// connected is emitted again when websocket reconnects
+		if _, ok := manager.stats[session.ID()]; ok {
+			manager.record(session, "session_resumed", nil)
+			return
+		}
+
+		manager.stats[session.ID()] = &sessionStats{
+			connectedAt: time.Now(),
+			videos:      map[string]struct{}{},
+		}
+
+		manager.record(session, "session_started", map[string]any{
+			"is_admin": session.Profile().IsAdmin,
+		})
+	})
+
+	manager.sessions.OnStateChanged(func(session types.Session) {
+		manager.statsMu.Lock()
+		defer manager.statsMu.Unlock()
+
+		stats, ok := manager.stats[session.ID()]
+		if !ok {
+			return
+		}
+
+		now := time.Now()
+		state := session.State()
+
+		if state.IsWatching && stats.watchingSince == nil {
+			stats.watchingSince = &now
+			manager.addVideo(session, stats)
+
+			// first webrtc connection of the session
+			if !stats.watched {
+				stats.watched = true
+				manager.record(session, "webrtc_connected", nil)
+			}
+		}
+
+		if !state.IsWatching && stats.watchingSince != nil {
+			stats.watching += now.Sub(*stats.watchingSince)
+			stats.watchingSince = nil
+		}
+	})
+
+	manager.sessions.OnHostChanged(func(session, host types.Session) {
+		if host == nil {
+			return
+		}
+
+		manager.statsMu.Lock()
+		defer manager.statsMu.Unlock()
+
+		stats, ok := manager.stats[host.ID()]
+		if !ok {
+			return
+		}
+
+		stats.hostCount++
+		manager.record(host, "host_gained", map[string]any{
+			"host_count": stats.hostCount,
+		})
+	})
+
+	manager.sessions.OnDisconnected(func(session types.Session) {
+		manager.statsMu.Lock()
+		defer manager.statsMu.Unlock()
+
+		manager.end(session, "disconnected")
+	})
+}
+
+func (manager *AnalyticsManagerCtx) Shutdown() error {
+	manager.statsMu.Lock()
+	defer manager.statsMu.Unlock()
+
+	// sessions that are still connected end now
+	for id := range manager.stats {
+		if session, ok := manager.sessions.Get(id); ok {
+			manager.end(session, "shutdown")
+		}
+	}
+
+	return manager.sink.Close()
+}
+
+// addVideo remembers video quality currently received by the session.
+func (manager *AnalyticsManagerCtx) addVideo(session types.Session, stats *sessionStats) {
+	if peer := session.GetWebRTCPeer(); peer != nil {
+		if video := peer.Video(); video.ID != "" {
+			stats.videos[video.ID] = struct{}{}
+		}
+	}
+}
+
+// end records summary of the session and stops tracking it, must be called
+// with stats lock held.
+func (manager *AnalyticsManagerCtx) end(session types.Session, reason string) {
+	stats, ok := manager.stats[session.ID()]
+	if !ok {
+		return
+	}
+
+	delete(manager.stats, session.ID())
+
+	now := time.Now()
+	if stats.watchingSince != nil {
+		manager.addVideo(session, stats)
+		stats.watching += now.Sub(*stats.watchingSince)
+	}
+
+	videos := make([]string, 0, len(stats.videos))
+	for id := range stats.videos {
+		videos = append(videos, id)
+	}
+	sort.Strings(videos)
+
+	manager.record(session, "session_ended", map[string]any{
+		"reason":          reason,
+		"is_admin":        session.Profile().IsAdmin,
+		"duration":        now.Sub(stats.connectedAt).Seconds(),
+		"watching":        stats.watched,
+		"watch_duration":  stats.watching.Seconds(),
+		"host_count":      stats.hostCount,
+		"received_videos": videos,
+	})
+}
+
+// record sends record to the sink, must be called with stats lock held.
+func (manager *AnalyticsManagerCtx) record(session types.Session, event string, data map[string]any) {
+	err := manager.sink.Record(types.AnalyticsRecord{
+		Time:      time.Now(),
+		Event:     event,
+		SessionId: session.ID(),
+		Data:      data,
+	})
+
+	if err != nil {
+		manager.logger.Err(err).Str("event", event).Msg("unable to record analytics")
+	}
+}
@@ -0,0 +1,199 @@
+package analytics
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+type testSessions struct {
+	types.SessionManager
+	sessions     map[string]types.Session
+	connected    func(session types.Session)
+	disconnected func(session types.Session)
+	stateChanged func(session types.Session)
+	hostChanged  func(session, host types.Session)
+}
+
+func (m *testSessions) Get(id string) (types.Session, bool) {
+	session, ok := m.sessions[id]
+	return session, ok
+}
+
+func (m *testSessions) OnConnected(listener func(session types.Session)) { m.connected = listener }
+func (m *testSessions) OnDisconnected(listener func(session types.Session)) {
+	m.disconnected = listener
+}
+func (m *testSessions) OnStateChanged(listener func(session types.Session)) {
+	m.stateChanged = listener
+}
+func (m *testSessions) OnHostChanged(listener func(session, host types.Session)) {
+	m.hostChanged = listener
+}
+
+type testSession struct {
+	types.Session
+	id    string
+	state types.SessionState
+	peer  types.WebRTCPeer
+}
+
+func (s *testSession) ID() string                      { return s.id }
+func (s *testSession) Profile() types.MemberProfile    { return types.MemberProfile{} }
+func (s *testSession) State() types.SessionState       { return s.state }
+func (s *testSession) GetWebRTCPeer() types.WebRTCPeer { return s.peer }
+
+type testPeer struct {
+	types.WebRTCPeer
+	video string
+}
+
+func (p *testPeer) Video() types.PeerVideo { return types.PeerVideo{ID: p.video} }
+
+type testSink struct {
+	records []types.AnalyticsRecord
+	closed  bool
+}
+
+func (s *testSink) Record(record types.AnalyticsRecord) error {
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *testSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func (s *testSink) events() []string {
+	events := make([]string, len(s.records))
+	for i, record := range s.records {
+		events[i] = record.Event
+	}
+	return events
+}
+
+func newTestManager() (*AnalyticsManagerCtx, *testSessions, *testSink) {
+	sessions := &testSessions{sessions: map[string]types.Session{}}
+	sink := &testSink{}
+
+	manager := &AnalyticsManagerCtx{
+		logger:   zerolog.Nop(),
+		config:   &config.Analytics{},
+		sessions: sessions,
+		sink:     sink,
+		stats:    map[string]*sessionStats{},
+	}
+	manager.Start()
+
+	return manager, sessions, sink
+}
+
+func TestSessionMilestones(t *testing.T) {
+	_, sessions, sink := newTestManager()
+
+	peer := &testPeer{video: "hd"}
+	session := &testSession{id: "a", peer: peer}
+
+	sessions.connected(session)
+	sessions.connected(session)
+
+	session.state.IsWatching = true
+	sessions.stateChanged(session)
+	peer.video = "sd"
+	session.state.IsWatching = false
+	sessions.stateChanged(session)
+
+	// watching again is not a new connection milestone
+	session.state.IsWatching = true
+	sessions.stateChanged(session)
+
+	sessions.hostChanged(session, session)
+	sessions.hostChanged(session, nil)
+	sessions.disconnected(session)
+
+	// events of ended session are ignored
+	sessions.stateChanged(session)
+
+	want := []string{"session_started", "session_resumed", "webrtc_connected", "host_gained", "session_ended"}
+	if got := sink.events(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+
+	summary := sink.records[len(sink.records)-1].Data
+	if summary["reason"] != "disconnected" || summary["watching"] != true || summary["host_count"] != 1 {
+		t.Errorf("unexpected summary %v", summary)
+	}
+	if videos := summary["received_videos"]; !reflect.DeepEqual(videos, []string{"hd", "sd"}) {
+		t.Errorf("received_videos = %v, want [hd sd]", videos)
+	}
+}
+
+func TestShutdownEndsSessions(t *testing.T) {
+	manager, sessions, sink := newTestManager()
+
+	session := &testSession{id: "a"}
+	sessions.sessions["a"] = session
+	sessions.connected(session)
+
+	if err := manager.Shutdown(); err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+
+	if got := sink.events(); !reflect.DeepEqual(got, []string{"session_started", "session_ended"}) {
+		t.Errorf("events = %v", got)
+	}
+	if reason := sink.records[len(sink.records)-1].Data["reason"]; reason != "shutdown" {
+		t.Errorf("reason = %v, want shutdown", reason)
+	}
+	if !sink.closed {
+		t.Error("sink was not closed")
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "analytics.jsonl")
+
+	sink, err := newFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, event := range []string{"session_started", "session_ended"} {
+		if err := sink.Record(types.AnalyticsRecord{Event: event, SessionId: "a"}); err != nil {
+			t.Fatalf("Record() = %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// one record per line
+	var events []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record types.AnalyticsRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %q is not a record: %v", scanner.Text(), err)
+		}
+		events = append(events, record.Event)
+	}
+
+	if !reflect.DeepEqual(events, []string{"session_started", "session_ended"}) {
+		t.Errorf("events = %v", events)
+	}
+}
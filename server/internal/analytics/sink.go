@@ -0,0 +1,48 @@
+package analytics
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// noopSink discards all records.
+type noopSink struct{}
+
+func (noopSink) Record(record types.AnalyticsRecord) error { return nil }
+func (noopSink) Close() error                              { return nil }
+
+// fileSink appends records to a file, one JSON object per line.
+type fileSink struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+func newFileSink(path string) (*fileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileSink{
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}, nil
+}
+
+func (s *fileSink) Record(record types.AnalyticsRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.encoder.Encode(record)
+}
+
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
@@ -3,22 +3,24 @@ package room
 import (
 	"net/http"
 
-	"github.com/m1k1o/neko/server/pkg/types/event"
-	"github.com/m1k1o/neko/server/pkg/types/message"
 	"github.com/m1k1o/neko/server/pkg/utils"
 )
 
 type BroadcastStatusPayload struct {
-	URL      string `json:"url,omitempty"`
-	IsActive bool   `json:"is_active"`
+	URL          string `json:"url,omitempty"`
+	IsActive     bool   `json:"is_active"`
+	Reconnecting bool   `json:"reconnecting,omitempty"`
+	Error        string `json:"error,omitempty"`
 }
 
 func (h *RoomHandler) broadcastStatus(w http.ResponseWriter, r *http.Request) error {
-	broadcast := h.capture.Broadcast()
+	status := h.capture.Broadcast().Status()
 
 	return utils.HttpSuccess(w, BroadcastStatusPayload{
-		IsActive: broadcast.Started(),
-		URL:      broadcast.Url(),
+		IsActive:     status.IsActive,
+		URL:          status.URL,
+		Reconnecting: status.Reconnecting,
+		Error:        status.Error,
 	})
 }
 
@@ -41,13 +43,6 @@ func (h *RoomHandler) broadcastStart(w http.ResponseWriter, r *http.Request) err
 		return utils.HttpInternalServerError().WithInternalErr(err)
 	}
 
-	h.sessions.AdminBroadcast(
-		event.BROADCAST_STATUS,
-		message.BroadcastStatus{
-			IsActive: broadcast.Started(),
-			URL:      broadcast.Url(),
-		})
-
 	return utils.HttpSuccess(w)
 }
 
@@ -59,12 +54,5 @@ func (h *RoomHandler) broadcastStop(w http.ResponseWriter, r *http.Request) erro
 
 	broadcast.Stop()
 
-	h.sessions.AdminBroadcast(
-		event.BROADCAST_STATUS,
-		message.BroadcastStatus{
-			IsActive: broadcast.Started(),
-			URL:      broadcast.Url(),
-		})
-
 	return utils.HttpSuccess(w)
 }
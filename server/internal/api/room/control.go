@@ -5,7 +5,9 @@ import (
 
 	"github.com/go-chi/chi"
 
+	"github.com/m1k1o/neko/server/internal/input"
 	"github.com/m1k1o/neko/server/pkg/auth"
+	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/types/event"
 	"github.com/m1k1o/neko/server/pkg/types/message"
 	"github.com/m1k1o/neko/server/pkg/utils"
@@ -14,12 +16,17 @@ import (
 type ControlStatusPayload struct {
 	HasHost bool   `json:"has_host"`
 	HostId  string `json:"host_id,omitempty"`
+	Locked  bool   `json:"locked"`
 }
 
 type ControlTargetPayload struct {
 	ID string `json:"id"`
 }
 
+type ControlLockPayload struct {
+	Locked bool `json:"locked"`
+}
+
 func (h *RoomHandler) controlStatus(w http.ResponseWriter, r *http.Request) error {
 	host, hasHost := h.sessions.GetHost()
 
@@ -31,12 +38,17 @@ func (h *RoomHandler) controlStatus(w http.ResponseWriter, r *http.Request) erro
 	return utils.HttpSuccess(w, ControlStatusPayload{
 		HasHost: hasHost,
 		HostId:  hostId,
+		Locked:  h.sessions.HostLocked(),
 	})
 }
 
 func (h *RoomHandler) controlRequest(w http.ResponseWriter, r *http.Request) error {
 	session, _ := auth.GetSession(r)
 	host, hasHost := h.sessions.GetHost()
+	if hasHost && h.sessions.HostLocked() {
+		return utils.HttpForbidden("host is locked")
+	}
+
 	if hasHost {
 		// TODO: Some throttling mechanism to prevent spamming.
 
@@ -73,6 +85,10 @@ func (h *RoomHandler) controlRelease(w http.ResponseWriter, r *http.Request) err
 
 func (h *RoomHandler) controlTake(w http.ResponseWriter, r *http.Request) error {
 	session, _ := auth.GetSession(r)
+	if h.sessions.HostLocked() && !session.IsHost() {
+		return utils.HttpForbidden("host is locked")
+	}
+
 	session.SetAsHost()
 
 	return utils.HttpSuccess(w)
@@ -91,6 +107,11 @@ func (h *RoomHandler) controlGive(w http.ResponseWriter, r *http.Request) error
 		return utils.HttpBadRequest("target session is not allowed to host")
 	}
 
+	// only the locked host can hand off control
+	if h.sessions.HostLocked() && !session.IsHost() {
+		return utils.HttpForbidden("host is locked")
+	}
+
 	target.SetAsHostBy(session)
 
 	return utils.HttpSuccess(w)
@@ -100,6 +121,10 @@ func (h *RoomHandler) controlReset(w http.ResponseWriter, r *http.Request) error
 	session, _ := auth.GetSession(r)
 	_, hasHost := h.sessions.GetHost()
 
+	if hasHost && h.sessions.HostLocked() && !session.IsHost() {
+		return utils.HttpForbidden("host is locked")
+	}
+
 	if hasHost {
 		h.desktop.ResetKeys()
 		session.ClearHost()
@@ -107,3 +132,59 @@ func (h *RoomHandler) controlReset(w http.ResponseWriter, r *http.Request) error
 
 	return utils.HttpSuccess(w)
 }
+
+func (h *RoomHandler) controlLock(w http.ResponseWriter, r *http.Request) error {
+	session, _ := auth.GetSession(r)
+
+	data := &ControlLockPayload{}
+	if err := utils.HttpJsonRequest(w, r, data); err != nil {
+		return err
+	}
+
+	if !session.IsHost() && !session.Profile().IsAdmin {
+		return utils.HttpUnprocessableEntity("session is not the host")
+	}
+
+	if err := h.sessions.SetHostLocked(session, data.Locked); err != nil {
+		return utils.HttpUnprocessableEntity("session host was not found")
+	}
+
+	return utils.HttpSuccess(w)
+}
+
+func (h *RoomHandler) controlRecording(w http.ResponseWriter, r *http.Request) error {
+	session, _ := auth.GetSession(r)
+
+	recording, err := input.Recording(session)
+	if err != nil {
+		return utils.HttpNotFound(err.Error())
+	}
+
+	w.Header().Set("Content-Disposition", "attachment; filename=\"input-recording.json\"")
+	return utils.HttpSuccess(w, recording)
+}
+
+func (h *RoomHandler) controlReplay(w http.ResponseWriter, r *http.Request) error {
+	session, _ := auth.GetSession(r)
+
+	recording := &types.InputRecording{}
+	if err := utils.HttpJsonRequest(w, r, recording); err != nil {
+		return err
+	}
+
+	apply := func(event types.InputEvent) error {
+		return input.Apply(h.desktop, event)
+	}
+
+	err := input.Replay(session, recording, apply, func(err error) {
+		// keys pressed by interrupted replay must not stay pressed
+		if err != nil {
+			h.desktop.ResetKeys()
+		}
+	})
+	if err != nil {
+		return utils.HttpForbidden(err.Error())
+	}
+
+	return utils.HttpSuccess(w)
+}
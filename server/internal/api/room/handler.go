@@ -66,7 +66,7 @@ func (h *RoomHandler) Route(r types.Router) {
 		r.Post("/stop", h.broadcastStop)
 	})
 
-	r.With(auth.CanAccessClipboardOnly).With(auth.HostsOnly).Route("/clipboard", func(r types.Router) {
+	r.With(auth.CanAccessClipboardOnly).With(auth.FeatureOnly(types.FeatureClipboard)).With(auth.HostsOnly).Route("/clipboard", func(r types.Router) {
 		r.Get("/", h.clipboardGetText)
 		r.Post("/", h.clipboardSetText)
 		r.Get("/image.png", h.clipboardGetImage)
@@ -97,12 +97,17 @@ func (h *RoomHandler) Route(r types.Router) {
 		r.With(auth.AdminsOnly).Post("/take", h.controlTake)
 		r.With(auth.HostsOrAdminsOnly).Post("/give/{sessionId}", h.controlGive)
 		r.With(auth.AdminsOnly).Post("/reset", h.controlReset)
+		r.With(auth.HostsOrAdminsOnly).Post("/lock", h.controlLock)
+
+		r.Get("/recording", h.controlRecording)
+		r.With(auth.HostsOnly).Post("/replay", h.controlReplay)
 	})
 
 	r.With(auth.CanWatchOnly).Route("/screen", func(r types.Router) {
 		r.Get("/", h.screenConfiguration)
 		r.With(auth.AdminsOnly).Post("/", h.screenConfigurationChange)
 		r.With(auth.AdminsOnly).Get("/configurations", h.screenConfigurationsList)
+		r.With(auth.AdminsOnly).Get("/load", h.screenLoad)
 
 		r.Get("/cast.jpg", h.screenCastGet)
 		r.With(auth.AdminsOnly).Get("/shot.jpg", h.screenShotGet)
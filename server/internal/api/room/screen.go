@@ -50,6 +50,11 @@ func (h *RoomHandler) screenConfigurationsList(w http.ResponseWriter, r *http.Re
 	return utils.HttpSuccess(w, configurations)
 }
 
+// screenLoad returns load of running video pipelines, keyed by video ID.
+func (h *RoomHandler) screenLoad(w http.ResponseWriter, r *http.Request) error {
+	return utils.HttpSuccess(w, h.capture.VideoLoad())
+}
+
 func (h *RoomHandler) screenShotGet(w http.ResponseWriter, r *http.Request) error {
 	quality, err := strconv.Atoi(r.URL.Query().Get("quality"))
 	if err != nil {
@@ -70,6 +75,11 @@ func (h *RoomHandler) screenShotGet(w http.ResponseWriter, r *http.Request) erro
 }
 
 func (h *RoomHandler) screenCastGet(w http.ResponseWriter, r *http.Request) error {
+	// screen is not captured while privacy screen is shown
+	if h.capture.Privacy() {
+		return utils.HttpUnprocessableEntity("privacy screen is active")
+	}
+
 	// display fallback image when private mode is enabled even if screencast is not
 	if session, ok := auth.GetSession(r); ok && session.PrivateModeEnabled() {
 		if h.privateModeImage != nil {
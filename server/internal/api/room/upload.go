@@ -1,12 +1,14 @@
 package room
 
 import (
+	"errors"
 	"io"
 	"net/http"
 	"os"
 	"path"
 	"strconv"
 
+	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/utils"
 )
 
@@ -82,8 +84,13 @@ func (h *RoomHandler) uploadDrop(w http.ResponseWriter, r *http.Request) error {
 		files = append(files, path)
 	}
 
-	if !h.desktop.DropFiles(X, Y, files) {
+	err = h.desktop.DropFiles(X, Y, files)
+	if errors.Is(err, types.ErrDropOutOfBounds) {
+		return utils.HttpBadRequest(err.Error())
+	}
+	if err != nil {
 		return utils.HttpInternalServerError().
+			WithInternalErr(err).
 			WithInternalMsg("unable to drop files")
 	}
 
@@ -39,6 +39,8 @@ func New(
 
 func (api *ApiManagerCtx) Route(r types.Router) {
 	r.Post("/login", api.Login)
+	r.Post("/login/invite", api.LoginInvite)
+	r.Post("/login/handoff", api.LoginHandoff)
 
 	// Authenticated area
 	r.Group(func(r types.Router) {
@@ -40,6 +40,96 @@ func (api *ApiManagerCtx) Login(w http.ResponseWriter, r *http.Request) error {
 		}
 	}
 
+	// permissions granted by authentication proxy
+	if err := api.sessions.ApplyClaims(session, r); err != nil {
+		return utils.HttpInternalServerError().WithInternalErr(err)
+	}
+
+	sessionData := SessionDataPayload{
+		ID:      session.ID(),
+		Profile: session.Profile(),
+		State:   session.State(),
+	}
+
+	if api.sessions.CookieEnabled() {
+		api.sessions.CookieSetToken(w, token)
+	} else {
+		sessionData.Token = token
+	}
+
+	return utils.HttpSuccess(w, sessionData)
+}
+
+type SessionInvitePayload struct {
+	Token string `json:"token"`
+}
+
+// LoginInvite creates guest session from one-time invite token.
+func (api *ApiManagerCtx) LoginInvite(w http.ResponseWriter, r *http.Request) error {
+	data := &SessionInvitePayload{}
+	if err := utils.HttpJsonRequest(w, r, data); err != nil {
+		return err
+	}
+
+	session, token, err := api.sessions.RedeemInvite(data.Token)
+	if err != nil {
+		if errors.Is(err, types.ErrSessionInvitesDisabled) {
+			return utils.HttpNotFound("invites are disabled")
+		} else if errors.Is(err, types.ErrSessionInviteInvalid) ||
+			errors.Is(err, types.ErrSessionInviteExpired) ||
+			errors.Is(err, types.ErrSessionInviteUsed) ||
+			errors.Is(err, types.ErrSessionInviteRevoked) {
+			return utils.HttpUnauthorized(err.Error())
+		} else if errors.Is(err, types.ErrSessionLoginDisabled) {
+			return utils.HttpForbidden("login is disabled for this session")
+		} else if errors.Is(err, types.ErrSessionLoginsLocked) {
+			return utils.HttpForbidden("logins are locked").WithInternalErr(err)
+		} else {
+			return utils.HttpInternalServerError().WithInternalErr(err)
+		}
+	}
+
+	sessionData := SessionDataPayload{
+		ID:      session.ID(),
+		Profile: session.Profile(),
+		State:   session.State(),
+	}
+
+	if api.sessions.CookieEnabled() {
+		api.sessions.CookieSetToken(w, token)
+	} else {
+		sessionData.Token = token
+	}
+
+	return utils.HttpSuccess(w, sessionData)
+}
+
+type SessionHandoffPayload struct {
+	Token string `json:"token"`
+}
+
+// LoginHandoff restores session moved from another server.
+func (api *ApiManagerCtx) LoginHandoff(w http.ResponseWriter, r *http.Request) error {
+	data := &SessionHandoffPayload{}
+	if err := utils.HttpJsonRequest(w, r, data); err != nil {
+		return err
+	}
+
+	session, token, err := api.sessions.RedeemHandoff(data.Token)
+	if err != nil {
+		if errors.Is(err, types.ErrSessionHandoffDisabled) {
+			return utils.HttpNotFound("handoff is disabled")
+		} else if errors.Is(err, types.ErrSessionHandoffInvalid) ||
+			errors.Is(err, types.ErrSessionHandoffExpired) ||
+			errors.Is(err, types.ErrSessionHandoffUsed) {
+			return utils.HttpUnauthorized(err.Error())
+		} else if errors.Is(err, types.ErrSessionLoginDisabled) {
+			return utils.HttpForbidden("login is disabled for this session")
+		} else {
+			return utils.HttpInternalServerError().WithInternalErr(err)
+		}
+	}
+
 	sessionData := SessionDataPayload{
 		ID:      session.ID(),
 		Profile: session.Profile(),
@@ -3,9 +3,12 @@ package sessions
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/m1k1o/neko/server/pkg/auth"
 	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
 	"github.com/m1k1o/neko/server/pkg/utils"
 
 	"github.com/go-chi/chi"
@@ -15,6 +18,10 @@ type SessionDataPayload struct {
 	ID      string              `json:"id"`
 	Profile types.MemberProfile `json:"profile"`
 	State   types.SessionState  `json:"state"`
+	// only for admins reading a single session
+	Reconnects *types.ReconnectStats `json:"reconnects,omitempty"`
+	AVSync     *types.PeerAVSync     `json:"av_sync,omitempty"`
+	Bandwidth  *types.PeerBandwidth  `json:"bandwidth,omitempty"`
 }
 
 func (h *SessionsHandler) sessionsList(w http.ResponseWriter, r *http.Request) error {
@@ -30,6 +37,38 @@ func (h *SessionsHandler) sessionsList(w http.ResponseWriter, r *http.Request) e
 	return utils.HttpSuccess(w, sessions)
 }
 
+type SessionInviteRequestPayload struct {
+	Profile types.MemberProfile `json:"profile"`
+	// validity in seconds, 0 uses the default
+	TTL int `json:"ttl"`
+}
+
+type SessionInvitePayload struct {
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+func (h *SessionsHandler) sessionsInvite(w http.ResponseWriter, r *http.Request) error {
+	data := &SessionInviteRequestPayload{}
+	if err := utils.HttpJsonRequest(w, r, data); err != nil {
+		return err
+	}
+
+	token, expires, err := h.sessions.CreateInvite(data.Profile, time.Duration(data.TTL)*time.Second)
+	if err != nil {
+		if errors.Is(err, types.ErrSessionInvitesDisabled) {
+			return utils.HttpUnprocessableEntity("invites are disabled")
+		} else {
+			return utils.HttpInternalServerError().WithInternalErr(err)
+		}
+	}
+
+	return utils.HttpSuccess(w, SessionInvitePayload{
+		Token:   token,
+		Expires: expires,
+	})
+}
+
 func (h *SessionsHandler) sessionsRead(w http.ResponseWriter, r *http.Request) error {
 	sessionId := chi.URLParam(r, "sessionId")
 
@@ -38,11 +77,24 @@ func (h *SessionsHandler) sessionsRead(w http.ResponseWriter, r *http.Request) e
 		return utils.HttpNotFound("session not found")
 	}
 
-	return utils.HttpSuccess(w, SessionDataPayload{
-		ID:      session.ID(),
-		Profile: session.Profile(),
-		State:   session.State(),
-	})
+	reconnects := session.Reconnects()
+	payload := SessionDataPayload{
+		ID:         session.ID(),
+		Profile:    session.Profile(),
+		State:      session.State(),
+		Reconnects: &reconnects,
+	}
+
+	if peer := session.GetWebRTCPeer(); peer != nil {
+		if avSync, ok := peer.AVSync(); ok {
+			payload.AVSync = &avSync
+		}
+		if bandwidth, ok := peer.Bandwidth(); ok {
+			payload.Bandwidth = &bandwidth
+		}
+	}
+
+	return utils.HttpSuccess(w, payload)
 }
 
 func (h *SessionsHandler) sessionsDelete(w http.ResponseWriter, r *http.Request) error {
@@ -79,3 +131,115 @@ func (h *SessionsHandler) sessionsDisconnect(w http.ResponseWriter, r *http.Requ
 
 	return utils.HttpSuccess(w)
 }
+
+func (h *SessionsHandler) sessionsApprove(w http.ResponseWriter, r *http.Request) error {
+	sessionId := chi.URLParam(r, "sessionId")
+
+	err := h.sessions.Approve(sessionId)
+	if err != nil {
+		if errors.Is(err, types.ErrSessionNotFound) {
+			return utils.HttpBadRequest("session not found")
+		} else if errors.Is(err, types.ErrSessionNotPending) {
+			return utils.HttpUnprocessableEntity("session is not pending approval")
+		} else {
+			return utils.HttpInternalServerError().WithInternalErr(err)
+		}
+	}
+
+	return utils.HttpSuccess(w)
+}
+
+func (h *SessionsHandler) sessionsDeny(w http.ResponseWriter, r *http.Request) error {
+	sessionId := chi.URLParam(r, "sessionId")
+
+	err := h.sessions.Deny(sessionId)
+	if err != nil {
+		if errors.Is(err, types.ErrSessionNotFound) {
+			return utils.HttpBadRequest("session not found")
+		} else if errors.Is(err, types.ErrSessionNotPending) {
+			return utils.HttpUnprocessableEntity("session is not pending approval")
+		} else {
+			return utils.HttpInternalServerError().WithInternalErr(err)
+		}
+	}
+
+	return utils.HttpSuccess(w)
+}
+
+type SessionHandoffRequestPayload struct {
+	// server the client should reconnect to
+	URL string `json:"url"`
+}
+
+type SessionHandoffPayload struct {
+	Token string `json:"token"`
+}
+
+// sessionsHandoff moves connected session to another server, client is asked
+// to reconnect there with the handoff token restoring the session.
+func (h *SessionsHandler) sessionsHandoff(w http.ResponseWriter, r *http.Request) error {
+	sessionId := chi.URLParam(r, "sessionId")
+
+	data := &SessionHandoffRequestPayload{}
+	if err := utils.HttpJsonRequest(w, r, data); err != nil {
+		return err
+	}
+
+	if data.URL == "" {
+		return utils.HttpBadRequest("target url is required")
+	}
+
+	session, ok := h.sessions.Get(sessionId)
+	if !ok {
+		return utils.HttpBadRequest("session not found")
+	}
+
+	if !session.State().IsConnected {
+		return utils.HttpUnprocessableEntity("session is not connected")
+	}
+
+	token, err := h.sessions.CreateHandoff(sessionId)
+	if err != nil {
+		if errors.Is(err, types.ErrSessionHandoffDisabled) {
+			return utils.HttpUnprocessableEntity("handoff is disabled")
+		} else {
+			return utils.HttpInternalServerError().WithInternalErr(err)
+		}
+	}
+
+	session.Send(
+		event.SYSTEM_HANDOFF,
+		message.SystemHandoff{
+			URL:   data.URL,
+			Token: token,
+		})
+
+	// host moves with the session to the target server
+	if session.IsHost() {
+		session.ClearHost()
+	}
+
+	return utils.HttpSuccess(w, SessionHandoffPayload{
+		Token: token,
+	})
+}
+
+func (h *SessionsHandler) tokensList(w http.ResponseWriter, r *http.Request) error {
+	return utils.HttpSuccess(w, h.sessions.Tokens())
+}
+
+func (h *SessionsHandler) tokensRevoke(w http.ResponseWriter, r *http.Request) error {
+	kind := types.SessionTokenKind(chi.URLParam(r, "kind"))
+	tokenId := chi.URLParam(r, "tokenId")
+
+	err := h.sessions.RevokeToken(kind, tokenId)
+	if err != nil {
+		if errors.Is(err, types.ErrSessionTokenNotFound) {
+			return utils.HttpNotFound("token not found")
+		} else {
+			return utils.HttpInternalServerError().WithInternalErr(err)
+		}
+	}
+
+	return utils.HttpSuccess(w)
+}
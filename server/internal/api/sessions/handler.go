@@ -21,10 +21,19 @@ func New(
 
 func (h *SessionsHandler) Route(r types.Router) {
 	r.Get("/", h.sessionsList)
+	r.With(auth.AdminsOnly).Post("/invite", h.sessionsInvite)
+
+	r.With(auth.AdminsOnly).Route("/tokens", func(r types.Router) {
+		r.Get("/", h.tokensList)
+		r.Delete("/{kind}/{tokenId}", h.tokensRevoke)
+	})
 
 	r.With(auth.AdminsOnly).Route("/{sessionId}", func(r types.Router) {
 		r.Get("/", h.sessionsRead)
 		r.Delete("/", h.sessionsDelete)
 		r.Post("/disconnect", h.sessionsDisconnect)
+		r.Post("/approve", h.sessionsApprove)
+		r.Post("/deny", h.sessionsDeny)
+		r.Post("/handoff", h.sessionsHandoff)
 	})
 }
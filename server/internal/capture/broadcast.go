@@ -1,8 +1,13 @@
 package capture
 
 import (
+	"errors"
+	"net/url"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/kataras/go-events"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/rs/zerolog"
@@ -12,9 +17,19 @@ import (
 	"github.com/m1k1o/neko/server/pkg/types"
 )
 
+// how often broadcast pipeline is checked for errors
+const broadcastPollInterval = time.Second
+
+// maximum delay between restarts of failed broadcast
+const broadcastMaxReconnectDelay = time.Minute
+
+// pipeline running for this long without errors resets the restart delay
+const broadcastStableDuration = time.Minute
+
 type BroacastManagerCtx struct {
-	logger zerolog.Logger
-	mu     sync.Mutex
+	logger  zerolog.Logger
+	mu      sync.Mutex
+	emmiter events.EventEmmiter
 
 	pipeline   gst.Pipeline
 	pipelineMu sync.Mutex
@@ -23,23 +38,41 @@ type BroacastManagerCtx struct {
 	url     string
 	started bool
 
+	// restarting of failed pipeline
+	reconnectDelay    time.Duration
+	reconnectAttempts int
+	reconnecting      bool
+	attempts          int
+	retryAt           time.Time
+	runningSince      time.Time
+	lastErr           string
+
+	// closed on shutdown
+	done chan struct{}
+
 	// metrics
 	pipelinesCounter prometheus.Counter
 	pipelinesActive  prometheus.Gauge
 }
 
-func broadcastNew(pipelineFn func(url string) (string, error), defaultUrl string, autostart bool) *BroacastManagerCtx {
+func broadcastNew(pipelineFn func(url string) (string, error), defaultUrl string, autostart bool, reconnectDelay time.Duration, reconnectAttempts int) *BroacastManagerCtx {
 	logger := log.With().
 		Str("module", "capture").
 		Str("submodule", "broadcast").
 		Logger()
 
-	return &BroacastManagerCtx{
+	manager := &BroacastManagerCtx{
 		logger:     logger,
+		emmiter:    events.New(),
 		pipelineFn: pipelineFn,
 		url:        defaultUrl,
 		started:    defaultUrl != "" && autostart,
 
+		reconnectDelay:    reconnectDelay,
+		reconnectAttempts: reconnectAttempts,
+
+		done: make(chan struct{}),
+
 		// metrics
 		pipelinesCounter: promauto.NewCounter(prometheus.CounterOpts{
 			Name:      "pipelines_total",
@@ -66,11 +99,16 @@ func broadcastNew(pipelineFn func(url string) (string, error), defaultUrl string
 			},
 		}),
 	}
+
+	go manager.monitor()
+
+	return manager
 }
 
 func (manager *BroacastManagerCtx) shutdown() {
 	manager.logger.Info().Msgf("shutdown")
 
+	close(manager.done)
 	manager.destroyPipeline()
 }
 
@@ -79,6 +117,9 @@ func (manager *BroacastManagerCtx) Start(url string) error {
 	defer manager.mu.Unlock()
 
 	manager.url = url
+	manager.reconnecting = false
+	manager.attempts = 0
+	manager.lastErr = ""
 
 	err := manager.createPipeline()
 	if err != nil {
@@ -86,6 +127,7 @@ func (manager *BroacastManagerCtx) Start(url string) error {
 	}
 
 	manager.started = true
+	manager.emitStatus()
 	return nil
 }
 
@@ -94,7 +136,31 @@ func (manager *BroacastManagerCtx) Stop() {
 	defer manager.mu.Unlock()
 
 	manager.started = false
+	manager.reconnecting = false
 	manager.destroyPipeline()
+	manager.emitStatus()
+}
+
+// restart recreates running pipeline, so that it reflects changed configuration.
+// Pipeline that is not running is created with the new configuration once started.
+func (manager *BroacastManagerCtx) restart() error {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	if !manager.started || manager.reconnecting {
+		return nil
+	}
+
+	manager.destroyPipeline()
+
+	err := manager.createPipeline()
+	if err != nil {
+		manager.logger.Warn().Err(err).Msg("unable to restart broadcast")
+		manager.schedule(err.Error())
+		return err
+	}
+
+	return nil
 }
 
 func (manager *BroacastManagerCtx) Started() bool {
@@ -111,6 +177,34 @@ func (manager *BroacastManagerCtx) Url() string {
 	return manager.url
 }
 
+func (manager *BroacastManagerCtx) Status() types.BroadcastStatus {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	return manager.status()
+}
+
+// must be called with mutex held
+func (manager *BroacastManagerCtx) status() types.BroadcastStatus {
+	return types.BroadcastStatus{
+		IsActive:     manager.started,
+		URL:          manager.url,
+		Reconnecting: manager.reconnecting,
+		Error:        manager.lastErr,
+	}
+}
+
+// must be called with mutex held
+func (manager *BroacastManagerCtx) emitStatus() {
+	manager.emmiter.Emit("status", manager.status())
+}
+
+func (manager *BroacastManagerCtx) OnStatusChange(listener func(status types.BroadcastStatus)) {
+	manager.emmiter.On("status", func(payload ...any) {
+		listener(payload[0].(types.BroadcastStatus))
+	})
+}
+
 func (manager *BroacastManagerCtx) createPipeline() error {
 	manager.pipelineMu.Lock()
 	defer manager.pipelineMu.Unlock()
@@ -124,19 +218,23 @@ func (manager *BroacastManagerCtx) createPipeline() error {
 		return err
 	}
 
+	// stream key is part of the URL, it must not be logged
+	redacted := redactBroadcastUrl(manager.url)
+
 	manager.logger.Info().
-		Str("url", manager.url).
-		Str("src", pipelineStr).
+		Str("url", redacted).
+		Str("src", strings.ReplaceAll(pipelineStr, manager.url, redacted)).
 		Msgf("starting pipeline")
 
 	manager.pipeline, err = gst.CreatePipeline(pipelineStr)
 	if err != nil {
-		return err
+		return errors.New(strings.ReplaceAll(err.Error(), manager.url, redacted))
 	}
 
 	manager.pipeline.Play()
 	manager.pipelinesCounter.Inc()
 	manager.pipelinesActive.Set(1)
+	manager.runningSince = time.Now()
 
 	return nil
 }
@@ -155,3 +253,129 @@ func (manager *BroacastManagerCtx) destroyPipeline() {
 
 	manager.pipelinesActive.Set(0)
 }
+
+func (manager *BroacastManagerCtx) pollError() error {
+	manager.pipelineMu.Lock()
+	defer manager.pipelineMu.Unlock()
+
+	if manager.pipeline == nil {
+		return nil
+	}
+
+	return manager.pipeline.PollError()
+}
+
+// monitor restarts broadcast pipeline that failed, e.g. when the network was
+// interrupted or the server closed the connection.
+func (manager *BroacastManagerCtx) monitor() {
+	ticker := time.NewTicker(broadcastPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-manager.done:
+			return
+		case <-ticker.C:
+		}
+
+		if err := manager.pollError(); err != nil {
+			manager.failed(err)
+			continue
+		}
+
+		manager.mu.Lock()
+		if manager.started && manager.reconnecting && !time.Now().Before(manager.retryAt) {
+			manager.retry()
+		} else if manager.attempts > 0 && !manager.reconnecting && time.Since(manager.runningSince) >= broadcastStableDuration {
+			manager.attempts = 0
+		}
+		manager.mu.Unlock()
+	}
+}
+
+// failed destroys the failed pipeline and schedules its restart.
+func (manager *BroacastManagerCtx) failed(err error) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	if !manager.started {
+		return
+	}
+
+	msg := strings.ReplaceAll(err.Error(), manager.url, redactBroadcastUrl(manager.url))
+	manager.logger.Warn().Str("error", msg).Msg("broadcast pipeline failed")
+
+	manager.destroyPipeline()
+	manager.schedule(msg)
+}
+
+// retry creates pipeline again, must be called with mutex held.
+func (manager *BroacastManagerCtx) retry() {
+	manager.logger.Info().Int("attempt", manager.attempts).Msg("restarting broadcast")
+
+	err := manager.createPipeline()
+	if err != nil && !errors.Is(err, types.ErrCapturePipelineAlreadyExists) {
+		manager.logger.Warn().Err(err).Msg("unable to restart broadcast")
+		manager.schedule(err.Error())
+		return
+	}
+
+	manager.reconnecting = false
+	manager.emitStatus()
+}
+
+// schedule next restart or stop broadcast when out of attempts, must be called
+// with mutex held.
+func (manager *BroacastManagerCtx) schedule(msg string) {
+	manager.lastErr = msg
+	manager.attempts++
+
+	if manager.reconnectDelay <= 0 || (manager.reconnectAttempts > 0 && manager.attempts > manager.reconnectAttempts) {
+		manager.logger.Warn().Int("attempts", manager.attempts-1).Msg("giving up restarting broadcast")
+		manager.started = false
+		manager.reconnecting = false
+		manager.emitStatus()
+		return
+	}
+
+	delay := broadcastBackoff(manager.reconnectDelay, manager.attempts)
+	manager.reconnecting = true
+	manager.retryAt = time.Now().Add(delay)
+
+	manager.logger.Info().Dur("delay", delay).Msg("broadcast will be restarted")
+	manager.emitStatus()
+}
+
+// broadcastBackoff doubles delay with every consecutive attempt.
+func broadcastBackoff(delay time.Duration, attempt int) time.Duration {
+	for i := 1; i < attempt && delay < broadcastMaxReconnectDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, broadcastMaxReconnectDelay)
+}
+
+// redactBroadcastUrl hides stream key, that is the last path segment of RTMP
+// URLs, along with query values and password.
+func redactBroadcastUrl(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Host == "" {
+		return "<redacted>"
+	}
+
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+
+	if i := strings.LastIndex(u.Path, "/"); i >= 0 && i < len(u.Path)-1 {
+		u.Path = u.Path[:i+1] + "xxxxx"
+	}
+	u.RawPath = ""
+
+	query := u.Query()
+	for key := range query {
+		query.Set(key, "xxxxx")
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
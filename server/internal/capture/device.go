@@ -0,0 +1,101 @@
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// how long the device create command may run
+const deviceCreateTimeout = 10 * time.Second
+
+// streamSrcDevice is a virtual device that stream source writes to. It is not
+// created by the pipeline, so it is checked before the pipeline is started.
+type streamSrcDevice struct {
+	name string
+	// returns ErrCaptureDeviceUnavailable if device does not exist
+	check func() error
+	// command creating the device on demand, empty disables it
+	createCommand string
+}
+
+// v4l2Device checks that v4l2 loopback device exists.
+func v4l2Device(path, createCommand string) *streamSrcDevice {
+	return &streamSrcDevice{
+		name: path,
+		check: func() error {
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("%w: %s", types.ErrCaptureDeviceUnavailable, path)
+			}
+			return nil
+		},
+		createCommand: createCommand,
+	}
+}
+
+// pulseSinkDevice checks that pulseaudio sink exists.
+func pulseSinkDevice(sink, createCommand string) *streamSrcDevice {
+	return &streamSrcDevice{
+		name: sink,
+		check: func() error {
+			out, err := exec.Command("pactl", "list", "short", "sinks").Output()
+			if err != nil {
+				// sinks can not be listed, let the pipeline find out
+				return nil
+			}
+
+			if !pulseSinkListed(out, sink) {
+				return fmt.Errorf("%w: %s", types.ErrCaptureDeviceUnavailable, sink)
+			}
+			return nil
+		},
+		createCommand: createCommand,
+	}
+}
+
+// pulseSinkListed looks for sink name in output of pactl list short sinks,
+// that has tab separated index, name, driver, format and state.
+func pulseSinkListed(out []byte, sink string) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) > 1 && fields[1] == sink {
+			return true
+		}
+	}
+	return false
+}
+
+// ensure checks that device exists, if not and create command is set, it
+// attempts to create the device and checks it again.
+func (device *streamSrcDevice) ensure(manager *StreamSrcManagerCtx) error {
+	err := device.check()
+	if err == nil || device.createCommand == "" {
+		return err
+	}
+
+	manager.logger.Info().
+		Str("device", device.name).
+		Str("command", device.createCommand).
+		Msg("device is not available, creating it")
+
+	ctx, cancel := context.WithTimeout(context.Background(), deviceCreateTimeout)
+	defer cancel()
+
+	args := strings.Fields(device.createCommand)
+	if out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput(); err != nil {
+		manager.logger.Warn().Err(err).
+			Str("device", device.name).
+			Str("output", strings.TrimSpace(string(out))).
+			Msg("failed to create device")
+	}
+
+	return device.check()
+}
@@ -0,0 +1,44 @@
+package capture
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+func TestPulseSinkListed(t *testing.T) {
+	out := []byte("0\taudio_output\tmodule-null-sink.c\ts16le 2ch 44100Hz\tRUNNING\n" +
+		"1\taudio_input\tmodule-null-sink.c\ts16le 2ch 44100Hz\tIDLE\n")
+
+	if !pulseSinkListed(out, "audio_input") {
+		t.Errorf("pulseSinkListed(audio_input) = false, want true")
+	}
+	if pulseSinkListed(out, "audio") {
+		t.Errorf("pulseSinkListed(audio) = true, want false")
+	}
+}
+
+func TestDeviceEnsure(t *testing.T) {
+	manager := &StreamSrcManagerCtx{logger: zerolog.Nop()}
+	path := filepath.Join(t.TempDir(), "video0")
+
+	// not created without command
+	err := v4l2Device(path, "").ensure(manager)
+	if !errors.Is(err, types.ErrCaptureDeviceUnavailable) {
+		t.Fatalf("ensure() = %v, want %v", err, types.ErrCaptureDeviceUnavailable)
+	}
+
+	// created on demand
+	if err := v4l2Device(path, "touch "+path).ensure(manager); err != nil {
+		t.Fatalf("ensure() with create command = %v", err)
+	}
+
+	// already exists
+	if err := v4l2Device(path, "false").ensure(manager); err != nil {
+		t.Fatalf("ensure() of existing device = %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package capture
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// number of frames the load is averaged over
+const loadWindow = 30
+
+// frames arriving this many times later than their duration count as dropped
+const loadDroppedRatio = 1.5
+
+// streamLoad estimates load of a video pipeline from the wall-clock time
+// between samples leaving the encoder. When capture or encoding takes longer
+// than the frame duration, frames arrive late or are skipped altogether.
+type streamLoad struct {
+	mu      sync.Mutex
+	last    time.Time
+	load    float64
+	dropped uint64
+
+	loadGauge      prometheus.Gauge
+	droppedCounter prometheus.Counter
+}
+
+func (l *streamLoad) onSample(sample types.Sample) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	last := l.last
+	l.last = sample.Timestamp
+
+	// duration is unknown or not meaningful for a single frame
+	if last.IsZero() || sample.Duration <= 0 || sample.Duration > time.Second {
+		return
+	}
+
+	ratio := float64(sample.Timestamp.Sub(last)) / float64(sample.Duration)
+
+	if l.load == 0 {
+		l.load = ratio
+	} else {
+		l.load += (ratio - l.load) / loadWindow
+	}
+	l.loadGauge.Set(l.load)
+
+	if ratio >= loadDroppedRatio {
+		dropped := uint64(ratio+0.5) - 1
+		l.dropped += dropped
+		l.droppedCounter.Add(float64(dropped))
+	}
+}
+
+func (l *streamLoad) get() types.StreamLoad {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return types.StreamLoad{
+		Load:          l.load,
+		DroppedFrames: l.dropped,
+	}
+}
+
+// reset starts new measurement, when pipeline is recreated.
+func (l *streamLoad) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.last = time.Time{}
+	l.load = 0
+	l.dropped = 0
+	l.loadGauge.Set(0)
+}
@@ -0,0 +1,53 @@
+package capture
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+func newTestStreamLoad() *streamLoad {
+	return &streamLoad{
+		loadGauge:      prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_load"}),
+		droppedCounter: prometheus.NewCounter(prometheus.CounterOpts{Name: "test_dropped"}),
+	}
+}
+
+func TestStreamLoad(t *testing.T) {
+	l := newTestStreamLoad()
+
+	frame := 40 * time.Millisecond
+	now := time.Now()
+
+	// frames arriving in time
+	for i := 0; i < 10; i++ {
+		now = now.Add(frame)
+		l.onSample(types.Sample{Timestamp: now, Duration: frame})
+	}
+
+	if load := l.get(); load.Load < 0.99 || load.Load > 1.01 || load.DroppedFrames != 0 {
+		t.Fatalf("expected load 1 without dropped frames, got %+v", load)
+	}
+
+	// encoder keeps up only with every other frame
+	for i := 0; i < 200; i++ {
+		now = now.Add(2 * frame)
+		l.onSample(types.Sample{Timestamp: now, Duration: frame})
+	}
+
+	load := l.get()
+	if load.Load < 1.9 {
+		t.Fatalf("expected load close to 2, got %f", load.Load)
+	}
+	if load.DroppedFrames != 200 {
+		t.Fatalf("expected 200 dropped frames, got %d", load.DroppedFrames)
+	}
+
+	l.reset()
+	if load := l.get(); load.Load != 0 || load.DroppedFrames != 0 {
+		t.Fatalf("expected reset load, got %+v", load)
+	}
+}
@@ -3,9 +3,13 @@ package capture
 import (
 	"errors"
 	"fmt"
+	"math"
 	"os"
+	"slices"
 	"strings"
+	"sync/atomic"
 
+	"github.com/kataras/go-events"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
@@ -19,6 +23,12 @@ type CaptureManagerCtx struct {
 	desktop types.DesktopManager
 	config  *config.Capture
 
+	// region of the screen video streams are cropped to
+	region  *captureRegion
+	emmiter events.EventEmmiter
+	// video streams do not capture the screen
+	privacy *atomic.Bool
+
 	// sinks
 	broadcast  *BroacastManagerCtx
 	screencast *ScreencastManagerCtx
@@ -26,33 +36,44 @@ type CaptureManagerCtx struct {
 	video      *StreamSelectorManagerCtx
 
 	// sources
-	webcam     *StreamSrcManagerCtx
-	microphone *StreamSrcManagerCtx
+	webcam        *StreamSrcManagerCtx
+	microphone    *StreamSrcManagerCtx
+	microphoneMix *StreamSrcManagerCtx
+	speakers      []types.SpeakerSrc
 }
 
 func New(desktop types.DesktopManager, config *config.Capture) *CaptureManagerCtx {
 	logger := log.With().Str("module", "capture").Logger()
 
+	region := &captureRegion{}
+	privacy := &atomic.Bool{}
+
 	videos := map[string]types.StreamSinkManager{}
 	for video_id, cnf := range config.VideoPipelines {
 		pipelineConf := cnf
 
 		createPipeline := func() (string, error) {
 			if pipelineConf.GstPipeline != "" {
-				// replace {display} with valid display
+				// replace {display} with valid display, custom pipelines are not cropped
 				return strings.Replace(pipelineConf.GstPipeline, "{display}", config.Display, 1), nil
 			}
 
 			screen := desktop.GetScreenSize()
-			pipeline, err := pipelineConf.GetPipeline(screen)
+			crop := region.apply(&screen)
+
+			pipeline, err := pipelineConf.GetPipeline(screen, config.VideoMaxFps)
 			if err != nil {
 				return "", err
 			}
 
-			return fmt.Sprintf(
-				"ximagesrc display-name=%s show-pointer=%v use-damage=false "+
-					"%s ! appsink name=appsink", config.Display, pipelineConf.ShowPointer, pipeline,
-			), nil
+			source := fmt.Sprintf("ximagesrc display-name=%s show-pointer=%v use-damage=false%s",
+				config.Display, pipelineConf.ShowPointer, crop)
+
+			if privacy.Load() {
+				source = privacySource(screen)
+			}
+
+			return fmt.Sprintf("%s %s ! appsink name=appsink", source, pipeline), nil
 		}
 
 		// trigger function to catch evaluation errors at startup
@@ -72,43 +93,43 @@ func New(desktop types.DesktopManager, config *config.Capture) *CaptureManagerCt
 		videos[video_id] = streamSinkNew(config.VideoCodec, createPipeline, video_id)
 	}
 
+	// audio is optional, e.g. for video only deployments
+	var audio *StreamSinkManagerCtx
+	if config.AudioEnabled {
+		audio = streamSinkNew(config.AudioCodec, func() (string, error) {
+			if config.AudioPipeline != "" {
+				// replace {device} with valid device
+				return strings.Replace(config.AudioPipeline, "{device}", config.AudioDevice, 1), nil
+			}
+
+			return fmt.Sprintf(
+				"pulsesrc device=%s "+
+					"! audio/x-raw,channels=2 "+
+					"! audioconvert "+
+					"! queue "+
+					"! %s "+
+					"! appsink name=appsink", config.AudioDevice, config.AudioCodec.Pipeline,
+			), nil
+		}, "audio")
+	}
+
 	return &CaptureManagerCtx{
 		logger:  logger,
 		desktop: desktop,
 		config:  config,
+		region:  region,
+		emmiter: events.New(),
+		privacy: privacy,
 
 		// sinks
 		broadcast: broadcastNew(func(url string) (string, error) {
-			if config.BroadcastPipeline != "" {
-				var pipeline = config.BroadcastPipeline
-				if hostname, err := os.Hostname(); err == nil {
-					// replace {hostname} with valid hostname
-					pipeline = strings.Replace(pipeline, "{hostname}", hostname, 1)
-				}
-				// replace {display} with valid display
-				pipeline = strings.Replace(pipeline, "{display}", config.Display, 1)
-				// replace {device} with valid device
-				pipeline = strings.Replace(pipeline, "{device}", config.AudioDevice, 1)
-				// replace {url} with valid URL
-				return strings.Replace(pipeline, "{url}", url, 1), nil
+			source := fmt.Sprintf("ximagesrc display-name=%s show-pointer=true use-damage=false", config.Display)
+			if privacy.Load() {
+				source = privacySource(desktop.GetScreenSize())
 			}
 
-			return fmt.Sprintf(
-				"flvmux name=mux ! rtmpsink location='%s live=1' "+
-					"pulsesrc device=%s "+
-					"! audio/x-raw,channels=2 "+
-					"! audioconvert "+
-					"! queue "+
-					"! voaacenc bitrate=%d "+
-					"! mux. "+
-					"ximagesrc display-name=%s show-pointer=true use-damage=false "+
-					"! video/x-raw "+
-					"! videoconvert "+
-					"! queue "+
-					"! x264enc threads=4 bitrate=%d key-int-max=15 byte-stream=true tune=zerolatency speed-preset=%s "+
-					"! mux.", url, config.AudioDevice, config.BroadcastAudioBitrate*1000, config.Display, config.BroadcastVideoBitrate, config.BroadcastPreset,
-			), nil
-		}, config.BroadcastUrl, config.BroadcastAutostart),
+			return broadcastPipeline(config, url, source), nil
+		}, config.BroadcastUrl, config.BroadcastAutostart, config.BroadcastReconnectDelay, config.BroadcastReconnectAttempts),
 		screencast: screencastNew(config.ScreencastEnabled, func() string {
 			if config.ScreencastPipeline != "" {
 				// replace {display} with valid display
@@ -125,21 +146,7 @@ func New(desktop types.DesktopManager, config *config.Capture) *CaptureManagerCt
 			)
 		}()),
 
-		audio: streamSinkNew(config.AudioCodec, func() (string, error) {
-			if config.AudioPipeline != "" {
-				// replace {device} with valid device
-				return strings.Replace(config.AudioPipeline, "{device}", config.AudioDevice, 1), nil
-			}
-
-			return fmt.Sprintf(
-				"pulsesrc device=%s "+
-					"! audio/x-raw,channels=2 "+
-					"! audioconvert "+
-					"! queue "+
-					"! %s "+
-					"! appsink name=appsink", config.AudioDevice, config.AudioCodec.Pipeline,
-			), nil
-		}, "audio"),
+		audio: audio,
 		video: streamSelectorNew(config.VideoCodec, videos, config.VideoIDs),
 
 		// sources
@@ -176,21 +183,93 @@ func New(desktop types.DesktopManager, config *config.Capture) *CaptureManagerCt
 				fmt.Sprintf("! video/x-raw,width=%d,height=%d ", config.WebcamWidth, config.WebcamHeight) +
 				"! identity drop-allocation=true " +
 				fmt.Sprintf("! v4l2sink sync=false device=%s", config.WebcamDevice),
-		}, "webcam"),
-		microphone: streamSrcNew(config.MicrophoneEnabled, map[string]string{
-			codec.Opus().Name: "appsrc format=time is-live=true do-timestamp=true name=appsrc " +
-				fmt.Sprintf("! application/x-rtp, payload=%d, encoding-name=OPUS ", codec.Opus().PayloadType) +
-				"! rtpopusdepay " +
-				"! decodebin " +
-				fmt.Sprintf("! pulsesink device=%s", config.MicrophoneDevice),
-			// TODO: Test this pipeline.
-			codec.G722().Name: "appsrc format=time is-live=true do-timestamp=true name=appsrc " +
-				"! application/x-rtp clock-rate=8000 " +
-				"! rtpg722depay " +
-				"! decodebin " +
-				fmt.Sprintf("! pulsesink device=%s", config.MicrophoneDevice),
-		}, "microphone"),
+		}, v4l2Device(config.WebcamDevice, config.WebcamCreate), "webcam"),
+		microphone:    streamSrcNew(config.MicrophoneEnabled, microphonePipelines(config.MicrophoneDevice, config.MicrophoneProcessing), pulseSinkDevice(config.MicrophoneDevice, config.MicrophoneCreate), "microphone"),
+		microphoneMix: streamSrcNew(config.MicrophoneEnabled && config.MicrophoneMixDevice != "", microphonePipelines(config.MicrophoneMixDevice, config.MicrophoneProcessing), pulseSinkDevice(config.MicrophoneMixDevice, ""), "microphone-mix"),
+		speakers:      speakerSrcs(config),
+	}
+}
+
+// speakerSrcs creates slots for concurrent speakers, sources are created up
+// front, because their metrics can be registered only once.
+func speakerSrcs(config *config.Capture) []types.SpeakerSrc {
+	speakers := make([]types.SpeakerSrc, config.MicrophoneSpeakers)
+	for i := range speakers {
+		speakers[i] = types.SpeakerSrc{
+			Microphone:    streamSrcNew(config.MicrophoneEnabled, microphonePipelines(config.MicrophoneDevice, config.MicrophoneProcessing), pulseSinkDevice(config.MicrophoneDevice, config.MicrophoneCreate), fmt.Sprintf("microphone-%d", i)),
+			MicrophoneMix: streamSrcNew(config.MicrophoneEnabled && config.MicrophoneMixDevice != "", microphonePipelines(config.MicrophoneMixDevice, config.MicrophoneProcessing), pulseSinkDevice(config.MicrophoneMixDevice, ""), fmt.Sprintf("microphone-mix-%d", i)),
+		}
 	}
+	return speakers
+}
+
+func microphonePipelines(device string, processing []string) map[string]string {
+	dsp := microphoneProcessing(processing)
+	return map[string]string{
+		codec.Opus().Name: "appsrc format=time is-live=true do-timestamp=true name=appsrc " +
+			fmt.Sprintf("! application/x-rtp, payload=%d, encoding-name=OPUS ", codec.Opus().PayloadType) +
+			"! rtpopusdepay " +
+			"! decodebin " +
+			dsp +
+			fmt.Sprintf("! pulsesink device=%s", device),
+		// TODO: Test this pipeline.
+		codec.G722().Name: "appsrc format=time is-live=true do-timestamp=true name=appsrc " +
+			"! application/x-rtp clock-rate=8000 " +
+			"! rtpg722depay " +
+			"! decodebin " +
+			dsp +
+			fmt.Sprintf("! pulsesink device=%s", device),
+	}
+}
+
+// microphoneProcessing returns pipeline elements applying the processing to
+// decoded audio, empty if there is none. Audio is resampled to the rate and
+// format supported by webrtcdsp and converted back for the sink.
+func microphoneProcessing(processing []string) string {
+	if len(processing) == 0 {
+		return ""
+	}
+
+	noiseSuppression := slices.Contains(processing, config.MicrophoneProcessingNoiseSuppression)
+	highPass := slices.Contains(processing, config.MicrophoneProcessingHighPass)
+
+	return "! audioconvert ! audioresample ! audio/x-raw,format=S16LE,rate=48000 " +
+		fmt.Sprintf("! webrtcdsp echo-cancel=false gain-control=false noise-suppression=%t high-pass-filter=%t ", noiseSuppression, highPass) +
+		"! audioconvert ! audioresample "
+}
+
+// broadcastPipeline returns broadcast pipeline streaming video of given source to the url,
+// custom pipeline does not use the source.
+func broadcastPipeline(config *config.Capture, url string, source string) string {
+	if config.BroadcastPipeline != "" {
+		var pipeline = config.BroadcastPipeline
+		if hostname, err := os.Hostname(); err == nil {
+			// replace {hostname} with valid hostname
+			pipeline = strings.Replace(pipeline, "{hostname}", hostname, 1)
+		}
+		// replace {display} with valid display
+		pipeline = strings.Replace(pipeline, "{display}", config.Display, 1)
+		// replace {device} with valid device
+		pipeline = strings.Replace(pipeline, "{device}", config.AudioDevice, 1)
+		// replace {url} with valid URL
+		return strings.Replace(pipeline, "{url}", url, 1)
+	}
+
+	return fmt.Sprintf(
+		"flvmux name=mux ! rtmpsink location='%s live=1' "+
+			"pulsesrc device=%s "+
+			"! audio/x-raw,channels=2 "+
+			"! audioconvert "+
+			"! queue "+
+			"! voaacenc bitrate=%d "+
+			"! mux. "+
+			"%s "+
+			"! video/x-raw "+
+			"! videoconvert "+
+			"! queue "+
+			"! x264enc threads=4 bitrate=%d key-int-max=15 byte-stream=true tune=zerolatency speed-preset=%s "+
+			"! mux.", url, config.AudioDevice, config.BroadcastAudioBitrate*1000, source, config.BroadcastVideoBitrate, config.BroadcastPreset,
+	)
 }
 
 func (manager *CaptureManagerCtx) Start() {
@@ -203,6 +282,13 @@ func (manager *CaptureManagerCtx) Start() {
 	manager.desktop.OnBeforeScreenSizeChange(func() {
 		manager.video.destroyPipelines()
 
+		// region may not fit the new screen size
+		if manager.region.get() != nil {
+			manager.logger.Info().Msg("screen size is changing, resetting video region")
+			manager.region.set(nil)
+			manager.emmiter.Emit("region_reset")
+		}
+
 		if manager.broadcast.Started() {
 			manager.broadcast.destroyPipeline()
 		}
@@ -240,11 +326,19 @@ func (manager *CaptureManagerCtx) Shutdown() error {
 	manager.broadcast.shutdown()
 	manager.screencast.shutdown()
 
-	manager.audio.shutdown()
+	if manager.audio != nil {
+		manager.audio.shutdown()
+	}
 	manager.video.shutdown()
 
 	manager.webcam.shutdown()
 	manager.microphone.shutdown()
+	manager.microphoneMix.shutdown()
+
+	for _, speaker := range manager.speakers {
+		speaker.Microphone.Stop()
+		speaker.MicrophoneMix.Stop()
+	}
 
 	return nil
 }
@@ -257,7 +351,12 @@ func (manager *CaptureManagerCtx) Screencast() types.ScreencastManager {
 	return manager.screencast
 }
 
+// Audio returns nil if audio is disabled.
 func (manager *CaptureManagerCtx) Audio() types.StreamSinkManager {
+	if manager.audio == nil {
+		return nil
+	}
+
 	return manager.audio
 }
 
@@ -265,6 +364,121 @@ func (manager *CaptureManagerCtx) Video() types.StreamSelectorManager {
 	return manager.video
 }
 
+// VideoLoad returns load of video streams with running pipelines.
+func (manager *CaptureManagerCtx) VideoLoad() map[string]types.StreamLoad {
+	load := map[string]types.StreamLoad{}
+	for id, stream := range manager.video.streams {
+		if stream.Started() {
+			load[id] = stream.Load()
+		}
+	}
+	return load
+}
+
+// VideoFramerate returns effective framerate of video pipelines, that is the
+// highest framerate of streams received by peers, each capped to the
+// configured maximum. Without any peers, all streams are considered.
+func (manager *CaptureManagerCtx) VideoFramerate() int16 {
+	ids := manager.video.startedIDs()
+	if len(ids) == 0 {
+		ids = manager.video.IDs()
+	}
+
+	screen := manager.desktop.GetScreenSize()
+	manager.region.apply(&screen)
+
+	return videoFramerate(manager.config.VideoPipelines, ids, screen, manager.config.VideoMaxFps)
+}
+
+// videoFramerate returns the highest framerate of given video pipelines,
+// custom pipelines are not considered, since their framerate is unknown.
+func videoFramerate(pipelines map[string]types.VideoConfig, ids []string, screen types.ScreenSize, maxFps int16) int16 {
+	rate := float64(0)
+	for _, id := range ids {
+		conf, ok := pipelines[id]
+		if !ok || conf.GstPipeline != "" {
+			continue
+		}
+
+		fps, err := conf.GetFramerate(screen, maxFps)
+		if err != nil {
+			continue
+		}
+
+		rate = max(rate, fps)
+	}
+
+	// screen refresh rate capped to the maximum
+	if rate <= 0 {
+		rate, _ = (&types.VideoConfig{}).GetFramerate(screen, maxFps)
+	}
+
+	return int16(math.Round(rate))
+}
+
+// VideoRegion returns region of the screen video streams are cropped to,
+// or nil if whole screen is captured.
+func (manager *CaptureManagerCtx) VideoRegion() *types.CaptureRegion {
+	return manager.region.get()
+}
+
+// SetVideoRegion crops video streams to the given region, nil captures whole
+// screen. Started pipelines are recreated, so that the video track carries
+// only the region in its own resolution, the same way as on screen size change.
+func (manager *CaptureManagerCtx) SetVideoRegion(region *types.CaptureRegion) (*types.CaptureRegion, error) {
+	if region != nil {
+		var err error
+		region, err = normalizeRegion(*region, manager.desktop.GetScreenSize())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := manager.region.change(region, manager.video.destroyPipelines, manager.video.recreatePipelines)
+	if err != nil {
+		manager.logger.Err(err).Interface("region", region).Msg("unable to change video region, previous region restored")
+		return nil, err
+	}
+
+	manager.logger.Info().Interface("region", region).Msg("video region changed")
+	return region, nil
+}
+
+// OnVideoRegionReset is called when region is reset, because screen size is
+// changing and the region may not fit it anymore.
+func (manager *CaptureManagerCtx) OnVideoRegionReset(listener func()) {
+	manager.emmiter.On("region_reset", func(payload ...any) {
+		listener()
+	})
+}
+
+// Privacy reports whether video streams are obscured.
+func (manager *CaptureManagerCtx) Privacy() bool {
+	return manager.privacy.Load()
+}
+
+// SetPrivacy replaces the screen in video streams and broadcast with black video,
+// started pipelines are recreated. Custom pipelines are not obscured.
+func (manager *CaptureManagerCtx) SetPrivacy(enabled bool) error {
+	if manager.privacy.Swap(enabled) == enabled {
+		return nil
+	}
+
+	manager.video.destroyPipelines()
+	if err := manager.video.recreatePipelines(); err != nil {
+		return err
+	}
+
+	if manager.config.BroadcastPipeline != "" && manager.broadcast.Started() {
+		manager.logger.Warn().Msg("custom broadcast pipeline is not obscured by privacy")
+	} else if err := manager.broadcast.restart(); err != nil {
+		return err
+	}
+
+	manager.logger.Info().Bool("enabled", enabled).Msg("video privacy changed")
+	return nil
+}
+
 func (manager *CaptureManagerCtx) Webcam() types.StreamSrcManager {
 	return manager.webcam
 }
@@ -272,3 +486,12 @@ func (manager *CaptureManagerCtx) Webcam() types.StreamSrcManager {
 func (manager *CaptureManagerCtx) Microphone() types.StreamSrcManager {
 	return manager.microphone
 }
+
+// MicrophoneMix plays shared microphone into the outbound audio.
+func (manager *CaptureManagerCtx) MicrophoneMix() types.StreamSrcManager {
+	return manager.microphoneMix
+}
+
+func (manager *CaptureManagerCtx) Speakers() []types.SpeakerSrc {
+	return manager.speakers
+}
@@ -0,0 +1,69 @@
+package capture
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/codec"
+)
+
+func TestMicrophonePipelines(t *testing.T) {
+	pipelines := microphonePipelines("audio_input", nil)
+	if strings.Contains(pipelines[codec.Opus().Name], "webrtcdsp") {
+		t.Fatalf("expected no processing by default, got %q", pipelines[codec.Opus().Name])
+	}
+
+	pipelines = microphonePipelines("audio_input", []string{config.MicrophoneProcessingHighPass})
+	for name, pipeline := range pipelines {
+		if !strings.Contains(pipeline, "noise-suppression=false high-pass-filter=true") {
+			t.Fatalf("expected %s pipeline with high pass filter only, got %q", name, pipeline)
+		}
+		if !strings.HasSuffix(pipeline, "! pulsesink device=audio_input") {
+			t.Fatalf("expected %s pipeline to end with the sink, got %q", name, pipeline)
+		}
+	}
+
+	pipelines = microphonePipelines("audio_input", []string{
+		config.MicrophoneProcessingNoiseSuppression,
+		config.MicrophoneProcessingHighPass,
+	})
+	if !strings.Contains(pipelines[codec.Opus().Name], "noise-suppression=true high-pass-filter=true") {
+		t.Fatalf("expected both processings, got %q", pipelines[codec.Opus().Name])
+	}
+}
+
+func TestVideoFramerate(t *testing.T) {
+	screen := types.ScreenSize{Width: 1920, Height: 1080, Rate: 60}
+	pipelines := map[string]types.VideoConfig{
+		"hd":     {Fps: "25"},
+		"hq":     {Fps: "fps"},
+		"custom": {GstPipeline: "ximagesrc ! appsink name=appsink"},
+		"broken": {Fps: "fps +"},
+	}
+
+	tests := []struct {
+		name   string
+		ids    []string
+		maxFps int16
+		want   int16
+	}{
+		{"highest of streams", []string{"hd", "hq"}, 0, 60},
+		{"single stream", []string{"hd"}, 0, 25},
+		{"capped streams", []string{"hd", "hq"}, 30, 30},
+		{"cap above stream", []string{"hd"}, 30, 25},
+		{"custom pipeline only", []string{"custom"}, 0, 60},
+		{"custom pipeline capped", []string{"custom"}, 30, 30},
+		{"invalid expression skipped", []string{"broken", "hd"}, 0, 25},
+		{"unknown stream", []string{"missing"}, 0, 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := videoFramerate(pipelines, tt.ids, screen, tt.maxFps); got != tt.want {
+				t.Errorf("videoFramerate() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package capture
+
+import (
+	"fmt"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// privacySource returns live black video of the screen size, that replaces the
+// screen while privacy is enabled, so that consumers do not need to renegotiate.
+func privacySource(screen types.ScreenSize) string {
+	return fmt.Sprintf("videotestsrc pattern=black is-live=true ! video/x-raw,width=%d,height=%d", screen.Width, screen.Height)
+}
@@ -0,0 +1,79 @@
+package capture
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kataras/go-events"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+func TestBroadcastPipelinePrivacy(t *testing.T) {
+	conf := &config.Capture{Display: ":99.0", AudioDevice: "audio_output"}
+	source := privacySource(types.ScreenSize{Width: 1280, Height: 720})
+
+	pipeline := broadcastPipeline(conf, "rtmp://example.com/live", source)
+	if !strings.Contains(pipeline, "videotestsrc pattern=black is-live=true ! video/x-raw,width=1280,height=720 ! video/x-raw") {
+		t.Errorf("expected broadcast of black video, got %q", pipeline)
+	}
+	if strings.Contains(pipeline, "ximagesrc") {
+		t.Errorf("expected screen not to be captured, got %q", pipeline)
+	}
+
+	// custom pipeline does not use the source
+	conf.BroadcastPipeline = "ximagesrc display-name={display} ! rtmpsink location={url}"
+	pipeline = broadcastPipeline(conf, "rtmp://example.com/live", source)
+	if pipeline != "ximagesrc display-name=:99.0 ! rtmpsink location=rtmp://example.com/live" {
+		t.Errorf("unexpected custom pipeline %q", pipeline)
+	}
+}
+
+func newTestBroadcast(pipelineFn func(url string) (string, error)) *BroacastManagerCtx {
+	return &BroacastManagerCtx{
+		logger:           zerolog.Nop(),
+		emmiter:          events.New(),
+		pipelineFn:       pipelineFn,
+		pipelinesCounter: prometheus.NewCounter(prometheus.CounterOpts{Name: "test_pipelines_total"}),
+		pipelinesActive:  prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_pipelines_active"}),
+	}
+}
+
+func TestBroadcastRestart(t *testing.T) {
+	privacy := false
+	var created []bool
+
+	manager := newTestBroadcast(func(url string) (string, error) {
+		created = append(created, privacy)
+		return "fakesrc ! fakesink", nil
+	})
+
+	// not running, new configuration is used once started
+	privacy = true
+	if err := manager.restart(); err != nil {
+		t.Fatalf("restart() = %v", err)
+	}
+	if len(created) != 0 {
+		t.Fatalf("pipeline created %d times while stopped", len(created))
+	}
+
+	if err := manager.Start("rtmp://example.com/live"); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+	defer manager.Stop()
+
+	// running pipeline is recreated with the new configuration
+	privacy = false
+	if err := manager.restart(); err != nil {
+		t.Fatalf("restart() = %v", err)
+	}
+	if len(created) != 2 || !created[0] || created[1] {
+		t.Fatalf("pipeline created with privacy %v, want [true false]", created)
+	}
+	if !manager.Started() {
+		t.Error("broadcast stopped after restart")
+	}
+}
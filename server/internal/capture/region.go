@@ -0,0 +1,87 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// captureRegion holds region of the screen that video pipelines are cropped to.
+type captureRegion struct {
+	mu     sync.RWMutex
+	region *types.CaptureRegion
+}
+
+func (r *captureRegion) get() *types.CaptureRegion {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.region == nil {
+		return nil
+	}
+
+	region := *r.region
+	return &region
+}
+
+func (r *captureRegion) set(region *types.CaptureRegion) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.region = region
+}
+
+// change crops to region and recreates pipelines, previous region is restored
+// if pipelines cannot be created with the new one.
+func (r *captureRegion) change(region *types.CaptureRegion, destroy func(), create func() error) error {
+	previous := r.get()
+
+	destroy()
+	r.set(region)
+
+	err := create()
+	if err == nil {
+		return nil
+	}
+
+	destroy()
+	r.set(previous)
+
+	if rerr := create(); rerr != nil {
+		return errors.Join(err, rerr)
+	}
+
+	return err
+}
+
+// apply returns ximagesrc properties cropping to the region and overrides
+// screen dimensions with region dimensions, so that stream expressions
+// are evaluated against the cropped size.
+func (r *captureRegion) apply(screen *types.ScreenSize) string {
+	region := r.get()
+	if region == nil {
+		return ""
+	}
+
+	screen.Width, screen.Height = region.Width, region.Height
+
+	// end coordinates are inclusive
+	return fmt.Sprintf(" startx=%d starty=%d endx=%d endy=%d",
+		region.X, region.Y, region.X+region.Width-1, region.Y+region.Height-1)
+}
+
+// normalizeRegion validates region against the screen size and rounds its
+// dimensions down to even numbers, as required by most encoders.
+func normalizeRegion(region types.CaptureRegion, screen types.ScreenSize) (*types.CaptureRegion, error) {
+	region.Width -= region.Width % 2
+	region.Height -= region.Height % 2
+
+	if region.X < 0 || region.Y < 0 || region.Width <= 0 || region.Height <= 0 ||
+		region.X+region.Width > screen.Width || region.Y+region.Height > screen.Height {
+		return nil, types.ErrCaptureRegionInvalid
+	}
+
+	return &region, nil
+}
@@ -0,0 +1,155 @@
+package capture
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+func TestNormalizeRegion(t *testing.T) {
+	screen := types.ScreenSize{Width: 1280, Height: 720}
+
+	tests := []struct {
+		name   string
+		region types.CaptureRegion
+		want   *types.CaptureRegion
+	}{
+		{"whole screen", types.CaptureRegion{X: 0, Y: 0, Width: 1280, Height: 720}, &types.CaptureRegion{X: 0, Y: 0, Width: 1280, Height: 720}},
+		{"odd dimensions", types.CaptureRegion{X: 10, Y: 10, Width: 101, Height: 51}, &types.CaptureRegion{X: 10, Y: 10, Width: 100, Height: 50}},
+		{"negative position", types.CaptureRegion{X: -1, Y: 0, Width: 100, Height: 100}, nil},
+		{"empty", types.CaptureRegion{X: 0, Y: 0, Width: 1, Height: 100}, nil},
+		{"outside screen", types.CaptureRegion{X: 1200, Y: 0, Width: 100, Height: 100}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeRegion(tt.region, screen)
+			if tt.want == nil {
+				if !errors.Is(err, types.ErrCaptureRegionInvalid) {
+					t.Errorf("normalizeRegion() error = %v, want %v", err, types.ErrCaptureRegionInvalid)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeRegion() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegionApply(t *testing.T) {
+	r := &captureRegion{}
+	screen := types.ScreenSize{Width: 1280, Height: 720}
+
+	if crop := r.apply(&screen); crop != "" || screen.Width != 1280 {
+		t.Errorf("apply() without region = %q, %+v", crop, screen)
+	}
+
+	r.set(&types.CaptureRegion{X: 10, Y: 20, Width: 100, Height: 50})
+	crop := r.apply(&screen)
+	if crop != " startx=10 starty=20 endx=109 endy=69" {
+		t.Errorf("apply() = %q", crop)
+	}
+	if screen.Width != 100 || screen.Height != 50 {
+		t.Errorf("screen size = %+v, want region size", screen)
+	}
+}
+
+func TestRegionChange(t *testing.T) {
+	r := &captureRegion{}
+	region := &types.CaptureRegion{Width: 100, Height: 50}
+
+	var created []*types.CaptureRegion
+	err := r.change(region, func() {}, func() error {
+		created = append(created, r.get())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("change() = %v", err)
+	}
+	if !reflect.DeepEqual(r.get(), region) || len(created) != 1 || !reflect.DeepEqual(created[0], region) {
+		t.Errorf("pipelines created with %+v, region %+v", created, r.get())
+	}
+}
+
+func TestRegionChangeRollback(t *testing.T) {
+	previous := &types.CaptureRegion{Width: 200, Height: 100}
+	r := &captureRegion{region: previous}
+
+	errPipeline := errors.New("pipeline failed")
+	destroyed := 0
+	var created []*types.CaptureRegion
+
+	err := r.change(&types.CaptureRegion{Width: 100, Height: 50}, func() {
+		destroyed++
+	}, func() error {
+		region := r.get()
+		created = append(created, region)
+		if region.Width != previous.Width {
+			return errPipeline
+		}
+		return nil
+	})
+
+	if !errors.Is(err, errPipeline) {
+		t.Errorf("change() = %v, want %v", err, errPipeline)
+	}
+	if !reflect.DeepEqual(r.get(), previous) {
+		t.Errorf("region = %+v, want previous %+v", r.get(), previous)
+	}
+	if destroyed != 2 || len(created) != 2 || !reflect.DeepEqual(created[1], previous) {
+		t.Errorf("pipelines destroyed %d times, created with %+v", destroyed, created)
+	}
+}
+
+type regionTestDesktop struct {
+	types.DesktopManager
+	before []func()
+}
+
+func (d *regionTestDesktop) GetScreenSize() types.ScreenSize {
+	return types.ScreenSize{Width: 1280, Height: 720}
+}
+
+func (d *regionTestDesktop) OnBeforeScreenSizeChange(listener func()) {
+	d.before = append(d.before, listener)
+}
+
+func (d *regionTestDesktop) OnAfterScreenSizeChange(listener func()) {}
+
+func TestRegionResetOnScreenSizeChange(t *testing.T) {
+	desktop := &regionTestDesktop{}
+	manager := New(desktop, &config.Capture{})
+	manager.Start()
+
+	reset := 0
+	manager.OnVideoRegionReset(func() {
+		reset++
+	})
+
+	if _, err := manager.SetVideoRegion(&types.CaptureRegion{Width: 100, Height: 50}); err != nil {
+		t.Fatalf("SetVideoRegion() = %v", err)
+	}
+
+	for _, listener := range desktop.before {
+		listener()
+	}
+
+	if manager.VideoRegion() != nil {
+		t.Errorf("region was not reset on screen size change")
+	}
+	if reset != 1 {
+		t.Errorf("region reset reported %d times, want once", reset)
+	}
+
+	// nothing to report without region
+	for _, listener := range desktop.before {
+		listener()
+	}
+	if reset != 1 {
+		t.Errorf("region reset reported %d times without region", reset)
+	}
+}
@@ -58,6 +58,17 @@ func (manager *StreamSelectorManagerCtx) recreatePipelines() error {
 	return nil
 }
 
+// startedIDs returns IDs of streams that have listeners.
+func (manager *StreamSelectorManagerCtx) startedIDs() []string {
+	ids := []string{}
+	for _, id := range manager.streamIDs {
+		if stream, ok := manager.streams[id]; ok && stream.Started() {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 func (manager *StreamSelectorManagerCtx) IDs() []string {
 	return manager.streamIDs
 }
@@ -28,6 +28,9 @@ type StreamSinkManagerCtx struct {
 	bitrate   uint64
 	brBuckets map[int]float64
 
+	// only measured for video
+	load *streamLoad
+
 	logger zerolog.Logger
 	mu     sync.Mutex
 	wg     sync.WaitGroup
@@ -119,6 +122,33 @@ func streamSinkNew(codec codec.RTPCodec, pipelineFn func() (string, error), id s
 		}),
 	}
 
+	if codec.IsVideo() {
+		manager.load = &streamLoad{
+			loadGauge: promauto.NewGauge(prometheus.GaugeOpts{
+				Name:      "streamsink_load",
+				Namespace: "neko",
+				Subsystem: "capture",
+				Help:      "Averaged time between frames relative to their duration, above 1 means the pipeline cannot keep up.",
+				ConstLabels: map[string]string{
+					"video_id":   id,
+					"codec_name": codec.Name,
+					"codec_type": codec.Type.String(),
+				},
+			}),
+			droppedCounter: promauto.NewCounter(prometheus.CounterOpts{
+				Name:      "streamsink_dropped_frames",
+				Namespace: "neko",
+				Subsystem: "capture",
+				Help:      "Total number of frames estimated to be dropped by the pipeline.",
+				ConstLabels: map[string]string{
+					"video_id":   id,
+					"codec_name": codec.Name,
+					"codec_type": codec.Type.String(),
+				},
+			}),
+		}
+	}
+
 	return manager
 }
 
@@ -146,6 +176,15 @@ func (manager *StreamSinkManagerCtx) Bitrate() uint64 {
 	return manager.bitrate
 }
 
+// Load returns load of the pipeline, it is not measured for audio.
+func (manager *StreamSinkManagerCtx) Load() types.StreamLoad {
+	if manager.load == nil {
+		return types.StreamLoad{}
+	}
+
+	return manager.load.get()
+}
+
 func (manager *StreamSinkManagerCtx) Codec() codec.RTPCodec {
 	return manager.codec
 }
@@ -378,6 +417,9 @@ func (manager *StreamSinkManagerCtx) onSample(sample types.Sample) {
 	length := float64(sample.Length)
 	manager.totalBytes.Add(length)
 	manager.saveSampleBitrate(sample.Timestamp, length)
+	if manager.load != nil {
+		manager.load.onSample(sample)
+	}
 
 	// if is not delta unit -> it can be decoded independently -> it is a keyframe
 	if manager.waitForKf && !sample.DeltaUnit && len(manager.listenersKf) > 0 {
@@ -410,4 +452,8 @@ func (manager *StreamSinkManagerCtx) DestroyPipeline() {
 
 	manager.brBuckets = make(map[int]float64)
 	manager.bitrate = 0
+
+	if manager.load != nil {
+		manager.load.reset()
+	}
 }
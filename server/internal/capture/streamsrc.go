@@ -18,6 +18,7 @@ type StreamSrcManagerCtx struct {
 	logger        zerolog.Logger
 	enabled       bool
 	codecPipeline map[string]string // codec -> pipeline
+	device        *streamSrcDevice  // nil if not checked
 
 	codec       codec.RTPCodec
 	pipeline    gst.Pipeline
@@ -30,7 +31,7 @@ type StreamSrcManagerCtx struct {
 	pipelinesActive  map[string]prometheus.Gauge
 }
 
-func streamSrcNew(enabled bool, codecPipeline map[string]string, video_id string) *StreamSrcManagerCtx {
+func streamSrcNew(enabled bool, codecPipeline map[string]string, device *streamSrcDevice, video_id string) *StreamSrcManagerCtx {
 	logger := log.With().
 		Str("module", "capture").
 		Str("submodule", "stream-src").
@@ -90,6 +91,7 @@ func streamSrcNew(enabled bool, codecPipeline map[string]string, video_id string
 		logger:        logger,
 		enabled:       enabled,
 		codecPipeline: codecPipeline,
+		device:        device,
 
 		// metrics
 		pushedData:       pushedData,
@@ -137,6 +139,12 @@ func (manager *StreamSrcManagerCtx) Start(codec codec.RTPCodec) error {
 		return errors.New("no pipeline found for a codec")
 	}
 
+	if manager.device != nil {
+		if err := manager.device.ensure(manager); err != nil {
+			return err
+		}
+	}
+
 	var err error
 
 	manager.logger.Info().
@@ -189,6 +197,10 @@ func (manager *StreamSrcManagerCtx) Push(bytes []byte) {
 	manager.pushedData[manager.codec.Name].Observe(float64(len(bytes)))
 }
 
+func (manager *StreamSrcManagerCtx) Enabled() bool {
+	return manager.enabled
+}
+
 func (manager *StreamSrcManagerCtx) Started() bool {
 	manager.pipelineMu.Lock()
 	defer manager.pipelineMu.Unlock()
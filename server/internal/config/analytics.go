@@ -0,0 +1,45 @@
+package config
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type Analytics struct {
+	// where analytics records are sent: none or file
+	Sink string
+	// path of JSON lines file used by file sink
+	File string
+}
+
+func (Analytics) Init(cmd *cobra.Command) error {
+	cmd.PersistentFlags().String("analytics.sink", "none", "where session analytics records are sent: none or file")
+	if err := viper.BindPFlag("analytics.sink", cmd.PersistentFlags().Lookup("analytics.sink")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("analytics.file", "", "path of JSON lines file where session analytics records are appended, when using file sink")
+	if err := viper.BindPFlag("analytics.file", cmd.PersistentFlags().Lookup("analytics.file")); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Analytics) Set() {
+	s.Sink = viper.GetString("analytics.sink")
+	s.File = viper.GetString("analytics.file")
+
+	switch s.Sink {
+	case "none":
+	case "file":
+		if s.File == "" {
+			log.Warn().Msg("analytics file sink requires analytics.file to be set, using none")
+			s.Sink = "none"
+		}
+	default:
+		log.Warn().Str("sink", s.Sink).Msg("unknown analytics sink, using none")
+		s.Sink = "none"
+	}
+}
@@ -3,12 +3,14 @@ package config
 import (
 	"os"
 	"strings"
+	"time"
 
 	"github.com/pion/webrtc/v3"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/m1k1o/neko/server/pkg/gst"
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/types/codec"
 	"github.com/m1k1o/neko/server/pkg/utils"
@@ -25,13 +27,23 @@ const (
 	HwEncNVENC
 )
 
+const (
+	// suppresses stationary background noise, such as fans or hum
+	MicrophoneProcessingNoiseSuppression = "noise_suppression"
+	// removes low frequencies, such as rumble or plosives
+	MicrophoneProcessingHighPass = "high_pass"
+)
+
 type Capture struct {
 	Display string
 
 	VideoCodec     codec.RTPCodec
 	VideoIDs       []string
 	VideoPipelines map[string]types.VideoConfig
+	VideoMaxFps    int16
+	VideoTune      types.VideoTune
 
+	AudioEnabled  bool
 	AudioDevice   string
 	AudioCodec    codec.RTPCodec
 	AudioPipeline string
@@ -42,6 +54,10 @@ type Capture struct {
 	BroadcastPipeline     string
 	BroadcastUrl          string
 	BroadcastAutostart    bool
+	// failed broadcast is restarted after the delay, that doubles with every attempt
+	BroadcastReconnectDelay time.Duration
+	// consecutive failed attempts after which broadcast is stopped, 0 means unlimited
+	BroadcastReconnectAttempts int
 
 	ScreencastEnabled  bool
 	ScreencastRate     string
@@ -52,13 +68,26 @@ type Capture struct {
 	WebcamDevice  string
 	WebcamWidth   int
 	WebcamHeight  int
-
-	MicrophoneEnabled bool
-	MicrophoneDevice  string
+	WebcamCreate  string
+
+	MicrophoneEnabled   bool
+	MicrophoneDevice    string
+	MicrophoneMixDevice string
+	MicrophoneCreate    string
+	// processing applied to shared microphone before it reaches the device
+	MicrophoneProcessing []string
+	// how many shared microphones of the loudest speakers are forwarded at once,
+	// 0 allows only one shared microphone at a time
+	MicrophoneSpeakers int
 }
 
 func (Capture) Init(cmd *cobra.Command) error {
 	// audio
+	cmd.PersistentFlags().Bool("capture.audio.enabled", true, "enable audio stream, if disabled no audio track is negotiated")
+	if err := viper.BindPFlag("capture.audio.enabled", cmd.PersistentFlags().Lookup("capture.audio.enabled")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().String("capture.audio.device", "audio_output.monitor", "pulseaudio device to capture")
 	if err := viper.BindPFlag("capture.audio.device", cmd.PersistentFlags().Lookup("capture.audio.device")); err != nil {
 		return err
@@ -69,6 +98,16 @@ func (Capture) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Int("capture.audio.sample_rate", 0, "sample rate of opus audio, one of 8000, 12000, 16000, 24000, 48000 (0 means codec default)")
+	if err := viper.BindPFlag("capture.audio.sample_rate", cmd.PersistentFlags().Lookup("capture.audio.sample_rate")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("capture.audio.channels", 0, "number of opus audio channels, 1 for mono or 2 for stereo (0 means codec default)")
+	if err := viper.BindPFlag("capture.audio.channels", cmd.PersistentFlags().Lookup("capture.audio.channels")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().String("capture.audio.pipeline", "", "gstreamer pipeline used for audio streaming")
 	if err := viper.BindPFlag("capture.audio.pipeline", cmd.PersistentFlags().Lookup("capture.audio.pipeline")); err != nil {
 		return err
@@ -95,6 +134,16 @@ func (Capture) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().String("capture.video.tune", string(types.VideoTuneInteractive), "encoder tuning profile of video pipelines without own tune: 'interactive' for the lowest latency at cost of ~20-30% more bitrate for the same quality, 'quality' for better compression with a few frames of latency, 'none' to use encoder parameters as configured")
+	if err := viper.BindPFlag("capture.video.tune", cmd.PersistentFlags().Lookup("capture.video.tune")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("capture.video.max_fps", 0, "maximum framerate captured by all video pipelines regardless of the screen refresh rate, 0 is for no maximum")
+	if err := viper.BindPFlag("capture.video.max_fps", cmd.PersistentFlags().Lookup("capture.video.max_fps")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().String("capture.video.pipeline", "", "shortcut for configuring only a single gstreamer pipeline, ignored if pipelines is set")
 	if err := viper.BindPFlag("capture.video.pipeline", cmd.PersistentFlags().Lookup("capture.video.pipeline")); err != nil {
 		return err
@@ -131,6 +180,16 @@ func (Capture) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Duration("capture.broadcast.reconnect_delay", 5*time.Second, "delay before failed broadcast is restarted, doubled with every consecutive failure up to a minute (0 disables restarting)")
+	if err := viper.BindPFlag("capture.broadcast.reconnect_delay", cmd.PersistentFlags().Lookup("capture.broadcast.reconnect_delay")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("capture.broadcast.reconnect_attempts", 0, "consecutive failed restarts after which broadcast is stopped (0 means unlimited)")
+	if err := viper.BindPFlag("capture.broadcast.reconnect_attempts", cmd.PersistentFlags().Lookup("capture.broadcast.reconnect_attempts")); err != nil {
+		return err
+	}
+
 	// screencast
 	cmd.PersistentFlags().Bool("capture.screencast.enabled", false, "enable screencast")
 	if err := viper.BindPFlag("capture.screencast.enabled", cmd.PersistentFlags().Lookup("capture.screencast.enabled")); err != nil {
@@ -176,6 +235,12 @@ func (Capture) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	// e.g. modprobe v4l2loopback exclusive_caps=1
+	cmd.PersistentFlags().String("capture.webcam.create_command", "", "command creating webcam device when it is not available on demand, empty disables it")
+	if err := viper.BindPFlag("capture.webcam.create_command", cmd.PersistentFlags().Lookup("capture.webcam.create_command")); err != nil {
+		return err
+	}
+
 	// microphone
 	cmd.PersistentFlags().Bool("capture.microphone.enabled", true, "enable microphone stream")
 	if err := viper.BindPFlag("capture.microphone.enabled", cmd.PersistentFlags().Lookup("capture.microphone.enabled")); err != nil {
@@ -187,6 +252,27 @@ func (Capture) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().String("capture.microphone.mix_device", "audio_output", "pulseaudio device used for mixing shared microphone into the outbound audio, empty disables mixing")
+	if err := viper.BindPFlag("capture.microphone.mix_device", cmd.PersistentFlags().Lookup("capture.microphone.mix_device")); err != nil {
+		return err
+	}
+
+	// e.g. pactl load-module module-null-sink sink_name=audio_input
+	cmd.PersistentFlags().String("capture.microphone.create_command", "", "command creating microphone device when it is not available on demand, empty disables it")
+	if err := viper.BindPFlag("capture.microphone.create_command", cmd.PersistentFlags().Lookup("capture.microphone.create_command")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("capture.microphone.speakers", 0, "how many microphones of the loudest speakers are forwarded concurrently, others are muted (0 allows only one shared microphone at a time)")
+	if err := viper.BindPFlag("capture.microphone.speakers", cmd.PersistentFlags().Lookup("capture.microphone.speakers")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringSlice("capture.microphone.processing", []string{}, "processing of shared microphone audio: noise_suppression, high_pass, empty disables it")
+	if err := viper.BindPFlag("capture.microphone.processing", cmd.PersistentFlags().Lookup("capture.microphone.processing")); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -341,6 +427,12 @@ func (s *Capture) Set() {
 		log.Warn().Err(err).Msgf("unable to parse video pipelines")
 	}
 
+	s.VideoMaxFps = int16(viper.GetInt("capture.video.max_fps"))
+	if s.VideoMaxFps < 0 {
+		log.Warn().Int16("max_fps", s.VideoMaxFps).Msg("negative video max fps, using no maximum")
+		s.VideoMaxFps = 0
+	}
+
 	videoPipeline := viper.GetString("capture.video.pipeline")
 
 	// if no video pipelines are set
@@ -400,7 +492,25 @@ func (s *Capture) Set() {
 		log.Warn().Msg("you are setting both single video pipeline and multiple video pipelines, ignoring single video pipeline")
 	}
 
+	videoTune := viper.GetString("capture.video.tune")
+	s.VideoTune, ok = types.ParseVideoTune(videoTune)
+	if !ok {
+		log.Warn().Str("tune", videoTune).Msg("unknown video tune, using interactive")
+		s.VideoTune = types.VideoTuneInteractive
+	}
+
+	for id, pipeline := range s.VideoPipelines {
+		if pipeline.Tune == "" {
+			pipeline.Tune = s.VideoTune
+		} else if pipeline.Tune, ok = types.ParseVideoTune(string(pipeline.Tune)); !ok {
+			log.Warn().Str("video_id", id).Msg("unknown video pipeline tune, using default")
+			pipeline.Tune = s.VideoTune
+		}
+		s.VideoPipelines[id] = pipeline
+	}
+
 	// audio
+	s.AudioEnabled = viper.GetBool("capture.audio.enabled")
 	s.AudioDevice = viper.GetString("capture.audio.device")
 	s.AudioPipeline = viper.GetString("capture.audio.pipeline")
 
@@ -411,6 +521,32 @@ func (s *Capture) Set() {
 		s.AudioCodec = codec.Opus()
 	}
 
+	sampleRate := viper.GetInt("capture.audio.sample_rate")
+	channels := viper.GetInt("capture.audio.channels")
+	if sampleRate != 0 || channels != 0 {
+		if s.AudioCodec.Name != codec.Opus().Name {
+			log.Warn().Str("codec", s.AudioCodec.Name).Msg("audio sample rate and channels can be set only for opus, ignoring")
+		} else {
+			if sampleRate == 0 {
+				sampleRate = int(s.AudioCodec.Capability.ClockRate)
+			}
+			if channels == 0 {
+				channels = int(s.AudioCodec.Capability.Channels)
+			}
+
+			audioCodec, err := codec.OpusFormat(sampleRate, channels)
+			if err != nil {
+				log.Warn().Err(err).Msg("invalid audio format, using codec default")
+			} else {
+				s.AudioCodec = audioCodec
+			}
+
+			if s.AudioPipeline != "" {
+				log.Warn().Msg("custom audio pipeline is used, make sure it produces configured sample rate and channels")
+			}
+		}
+	}
+
 	// broadcast
 	s.BroadcastAudioBitrate = viper.GetInt("capture.broadcast.audio_bitrate")
 	s.BroadcastVideoBitrate = viper.GetInt("capture.broadcast.video_bitrate")
@@ -418,6 +554,8 @@ func (s *Capture) Set() {
 	s.BroadcastPipeline = viper.GetString("capture.broadcast.pipeline")
 	s.BroadcastUrl = viper.GetString("capture.broadcast.url")
 	s.BroadcastAutostart = viper.GetBool("capture.broadcast.autostart")
+	s.BroadcastReconnectDelay = viper.GetDuration("capture.broadcast.reconnect_delay")
+	s.BroadcastReconnectAttempts = viper.GetInt("capture.broadcast.reconnect_attempts")
 
 	// screencast
 	s.ScreencastEnabled = viper.GetBool("capture.screencast.enabled")
@@ -430,10 +568,39 @@ func (s *Capture) Set() {
 	s.WebcamDevice = viper.GetString("capture.webcam.device")
 	s.WebcamWidth = viper.GetInt("capture.webcam.width")
 	s.WebcamHeight = viper.GetInt("capture.webcam.height")
+	s.WebcamCreate = viper.GetString("capture.webcam.create_command")
 
 	// microphone
 	s.MicrophoneEnabled = viper.GetBool("capture.microphone.enabled")
 	s.MicrophoneDevice = viper.GetString("capture.microphone.device")
+	s.MicrophoneMixDevice = viper.GetString("capture.microphone.mix_device")
+	s.MicrophoneCreate = viper.GetString("capture.microphone.create_command")
+
+	s.MicrophoneSpeakers = viper.GetInt("capture.microphone.speakers")
+	if s.MicrophoneSpeakers < 0 {
+		log.Warn().Int("speakers", s.MicrophoneSpeakers).Msg("negative microphone speakers, allowing only one shared microphone")
+		s.MicrophoneSpeakers = 0
+	}
+
+	s.MicrophoneProcessing = []string{}
+	for _, processing := range viper.GetStringSlice("capture.microphone.processing") {
+		switch processing {
+		case MicrophoneProcessingNoiseSuppression, MicrophoneProcessingHighPass:
+			s.MicrophoneProcessing = append(s.MicrophoneProcessing, processing)
+		default:
+			log.Warn().Str("processing", processing).Msg("unknown microphone processing, ignoring")
+		}
+	}
+
+	if len(s.MicrophoneProcessing) > 0 {
+		// https://gstreamer.freedesktop.org/documentation/webrtcdsp/webrtcdsp.html
+		// gstreamer1.0-plugins-bad
+		// webrtcdsp
+		if err := gst.CheckPlugins([]string{"webrtcdsp"}); err != nil {
+			log.Warn().Err(err).Msg("microphone processing is not available, disabling it")
+			s.MicrophoneProcessing = []string{}
+		}
+	}
 }
 
 func (s *Capture) SetV2() {
@@ -10,6 +10,7 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/utils"
 )
 
 type Desktop struct {
@@ -22,7 +23,31 @@ type Desktop struct {
 
 	Unminimize        bool
 	UploadDrop        bool
+	UploadDropStartX  int
+	UploadDropStartY  int
+	UploadDropButton  uint32
 	FileChooserDialog bool
+
+	NavigateCommand   string
+	NavigateAllowlist []string
+	NavigateSchemes   []string
+
+	// executables that may be run in the desktop on behalf of clients
+	ExecAllowlist []string
+
+	// commands locking and unlocking the screen, while privacy screen is shown
+	PrivacyLockCommand   string
+	PrivacyUnlockCommand string
+
+	// desktop notifications are forwarded to clients
+	Notifications bool
+	// command printing notification calls on the session bus
+	NotificationsCommand string
+
+	// key combinations emitted as named shortcuts, by their names
+	ShortcutBindings map[string]string
+	// keys of recognized shortcuts are injected as well
+	ShortcutPassthrough bool
 }
 
 func (Desktop) Init(cmd *cobra.Command) error {
@@ -56,11 +81,76 @@ func (Desktop) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Int("desktop.upload_drop_start_x", 0, "X coordinate where dragging of dropped files starts, must be inside of the drop window")
+	if err := viper.BindPFlag("desktop.upload_drop_start_x", cmd.PersistentFlags().Lookup("desktop.upload_drop_start_x")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("desktop.upload_drop_start_y", 0, "Y coordinate where dragging of dropped files starts, must be inside of the drop window")
+	if err := viper.BindPFlag("desktop.upload_drop_start_y", cmd.PersistentFlags().Lookup("desktop.upload_drop_start_y")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Uint32("desktop.upload_drop_button", 1, "mouse button used for dragging of dropped files")
+	if err := viper.BindPFlag("desktop.upload_drop_button", cmd.PersistentFlags().Lookup("desktop.upload_drop_button")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().Bool("desktop.file_chooser_dialog", false, "whether to handle file chooser dialog externally")
 	if err := viper.BindPFlag("desktop.file_chooser_dialog", cmd.PersistentFlags().Lookup("desktop.file_chooser_dialog")); err != nil {
 		return err
 	}
 
+	cmd.PersistentFlags().String("desktop.navigate.command", "", "command used by admins to open URL in the desktop, {url} is replaced with the URL (e.g. xdg-open {url}), empty disables navigation")
+	if err := viper.BindPFlag("desktop.navigate.command", cmd.PersistentFlags().Lookup("desktop.navigate.command")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringSlice("desktop.navigate.allowlist", []string{}, "hostnames that can be navigated to, supports wildcards (e.g. *.example.com), '*' allows any URL, empty disables navigation")
+	if err := viper.BindPFlag("desktop.navigate.allowlist", cmd.PersistentFlags().Lookup("desktop.navigate.allowlist")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringSlice("desktop.navigate.schemes", []string{"http", "https"}, "URL schemes that can be navigated to")
+	if err := viper.BindPFlag("desktop.navigate.schemes", cmd.PersistentFlags().Lookup("desktop.navigate.schemes")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringSlice("desktop.exec.allowlist", []string{}, "executables that can be run in the desktop on behalf of clients, supports wildcards; names match only executables found in PATH, absolute paths match after PATH lookup; empty allowlist allows any executable")
+	if err := viper.BindPFlag("desktop.exec.allowlist", cmd.PersistentFlags().Lookup("desktop.exec.allowlist")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("desktop.privacy.lock_command", "", "command locking or blanking the screen when privacy screen is shown (e.g. xset s activate), empty only obscures the video")
+	if err := viper.BindPFlag("desktop.privacy.lock_command", cmd.PersistentFlags().Lookup("desktop.privacy.lock_command")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("desktop.privacy.unlock_command", "", "command unlocking the screen when privacy screen is hidden again (e.g. xset s reset)")
+	if err := viper.BindPFlag("desktop.privacy.unlock_command", cmd.PersistentFlags().Lookup("desktop.privacy.unlock_command")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("desktop.notifications.enabled", false, "forward notifications raised by desktop applications to clients")
+	if err := viper.BindPFlag("desktop.notifications.enabled", cmd.PersistentFlags().Lookup("desktop.notifications.enabled")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("desktop.notifications.command", "dbus-monitor --session interface='org.freedesktop.Notifications',member='Notify'", "command printing notification calls on the session bus in dbus-monitor format")
+	if err := viper.BindPFlag("desktop.notifications.command", cmd.PersistentFlags().Lookup("desktop.notifications.command")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("desktop.shortcut.bindings", "{}", "map of shortcut names and key combinations recognized in input of the host, modifiers (ctrl, shift, alt, super) joined by '+' with a key (e.g. {\"mute\":\"ctrl+shift+m\",\"screenshot\":\"print\"})")
+	if err := viper.BindPFlag("desktop.shortcut.bindings", cmd.PersistentFlags().Lookup("desktop.shortcut.bindings")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("desktop.shortcut.passthrough", false, "inject keys of recognized shortcuts into the desktop as well, instead of only emitting the shortcut")
+	if err := viper.BindPFlag("desktop.shortcut.passthrough", cmd.PersistentFlags().Lookup("desktop.shortcut.passthrough")); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -106,7 +196,41 @@ func (s *Desktop) Set() {
 	s.InputSocket = viper.GetString("desktop.input.socket")
 	s.Unminimize = viper.GetBool("desktop.unminimize")
 	s.UploadDrop = viper.GetBool("desktop.upload_drop")
+	s.UploadDropStartX = viper.GetInt("desktop.upload_drop_start_x")
+	s.UploadDropStartY = viper.GetInt("desktop.upload_drop_start_y")
+	s.UploadDropButton = viper.GetUint32("desktop.upload_drop_button")
+	if s.UploadDropButton == 0 {
+		log.Warn().Msg("invalid upload drop button, using left button")
+		s.UploadDropButton = 1
+	}
 	s.FileChooserDialog = viper.GetBool("desktop.file_chooser_dialog")
+	s.NavigateCommand = viper.GetString("desktop.navigate.command")
+	s.NavigateAllowlist = viper.GetStringSlice("desktop.navigate.allowlist")
+	s.NavigateSchemes = viper.GetStringSlice("desktop.navigate.schemes")
+	if s.NavigateCommand != "" && len(s.NavigateSchemes) == 0 {
+		log.Warn().Msg("no navigation URL schemes are allowed, disabling navigation")
+		s.NavigateCommand = ""
+	}
+	if s.NavigateCommand != "" && len(s.NavigateAllowlist) == 0 {
+		log.Warn().Msg("no navigation hostnames are allowed, disabling navigation")
+		s.NavigateCommand = ""
+	}
+	s.ExecAllowlist = viper.GetStringSlice("desktop.exec.allowlist")
+	s.PrivacyLockCommand = viper.GetString("desktop.privacy.lock_command")
+	s.PrivacyUnlockCommand = viper.GetString("desktop.privacy.unlock_command")
+	s.Notifications = viper.GetBool("desktop.notifications.enabled")
+	s.NotificationsCommand = viper.GetString("desktop.notifications.command")
+	if s.Notifications && s.NotificationsCommand == "" {
+		log.Warn().Msg("desktop notifications command is empty, disabling notifications")
+		s.Notifications = false
+	}
+
+	if err := viper.UnmarshalKey("desktop.shortcut.bindings", &s.ShortcutBindings, viper.DecodeHook(
+		utils.JsonStringAutoDecode(s.ShortcutBindings),
+	)); err != nil {
+		log.Warn().Err(err).Msgf("unable to parse desktop shortcut bindings")
+	}
+	s.ShortcutPassthrough = viper.GetBool("desktop.shortcut.passthrough")
 }
 
 func (s *Desktop) SetV2() {
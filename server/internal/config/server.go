@@ -2,6 +2,7 @@ package config
 
 import (
 	"path"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -20,6 +21,11 @@ type Server struct {
 	PProf      bool
 	Metrics    bool
 	CORS       []string
+
+	// how long graceful shutdown may take, 0 means unlimited
+	ShutdownTimeout time.Duration
+	// how long a client may take to send request headers, 0 means unlimited
+	ReadHeaderTimeout time.Duration
 }
 
 func (Server) Init(cmd *cobra.Command) error {
@@ -68,6 +74,16 @@ func (Server) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Duration("server.shutdown_timeout", 10*time.Second, "how long graceful shutdown may take before remaining steps are skipped, capture, desktop and members are still torn down (0 means unlimited)")
+	if err := viper.BindPFlag("server.shutdown_timeout", cmd.PersistentFlags().Lookup("server.shutdown_timeout")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("server.read_header_timeout", 10*time.Second, "how long a client may take to send request headers before the connection is closed, protects against slow clients (0 means unlimited)")
+	if err := viper.BindPFlag("server.read_header_timeout", cmd.PersistentFlags().Lookup("server.read_header_timeout")); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -125,6 +141,18 @@ func (s *Server) Set() {
 	if len(s.CORS) == 0 || in {
 		s.CORS = []string{"*"}
 	}
+
+	s.ShutdownTimeout = viper.GetDuration("server.shutdown_timeout")
+	if s.ShutdownTimeout < 0 {
+		log.Warn().Dur("timeout", s.ShutdownTimeout).Msg("negative shutdown timeout, using no limit")
+		s.ShutdownTimeout = 0
+	}
+
+	s.ReadHeaderTimeout = viper.GetDuration("server.read_header_timeout")
+	if s.ReadHeaderTimeout < 0 {
+		log.Warn().Dur("timeout", s.ReadHeaderTimeout).Msg("negative read header timeout, using no limit")
+		s.ReadHeaderTimeout = 0
+	}
 }
 
 func (s *Server) SetV2() {
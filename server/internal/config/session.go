@@ -1,11 +1,16 @@
 package config
 
 import (
+	"net"
+	"slices"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/utils"
 )
 
 type SessionCookie struct {
@@ -18,6 +23,13 @@ type SessionCookie struct {
 	Path       string
 }
 
+// who receives inactive cursors of other sessions
+const (
+	InactiveCursorsRecipientsAll    = "all"
+	InactiveCursorsRecipientsAdmins = "admins"
+	InactiveCursorsRecipientsHost   = "host"
+)
+
 type Session struct {
 	File string
 
@@ -27,10 +39,57 @@ type Session struct {
 	ControlProtection bool
 	ImplicitHosting   bool
 	InactiveCursors   bool
+	HostCursor        bool
 	MercifulReconnect bool
+	JoinApproval      bool
+	ReconnectTokenTTL time.Duration
 	HeartbeatInterval int
+	HostTakeoverGrace int
 	APIToken          string
 
+	// who receives inactive cursors: all, admins or host (including admins)
+	InactiveCursorsRecipients string
+	// remove cursors of disconnected sessions immediately
+	InactiveCursorsCleanup bool
+	// number of sessions inactive cursors are sent to in parallel
+	InactiveCursorsConcurrency int
+
+	// send summary of changes to sessions rejoining after a gap
+	RejoinChanges      bool
+	RejoinChangesLimit int
+
+	// secret used to sign invite tokens, empty disables invites
+	InviteSecret string
+	// how long invite tokens are valid, unless specified otherwise
+	InviteTTL time.Duration
+
+	// secret shared by servers to sign handoff tokens, empty disables handoff
+	HandoffSecret string
+	// how long handoff tokens are valid
+	HandoffTTL time.Duration
+
+	// header with comma separated claims set by trusted authentication proxy
+	ClaimsHeader string
+	// claims mapped to profile permissions they grant
+	ClaimsMapping map[string][]string
+
+	// features disabled for sessions not connected over secure transport
+	SecureFeatures []string
+	// proxies whose X-Forwarded-Proto header is trusted
+	SecureTrustedProxies []*net.IPNet
+
+	// events sent within the window are delivered as a single batch
+	BroadcastCoalesce map[string]time.Duration
+
+	// session reconnecting this many times within the window is flapping, 0 disables
+	FlappingThreshold int
+	FlappingWindow    time.Duration
+
+	// text that must be acknowledged before session is admitted, empty disables
+	ConsentText string
+	// how long unacknowledged sessions stay connected, 0 means no limit
+	ConsentTimeout time.Duration
+
 	Cookie SessionCookie
 }
 
@@ -65,16 +124,101 @@ func (Session) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Bool("session.host_cursor", true, "show cursor position of the host to other sessions")
+	if err := viper.BindPFlag("session.host_cursor", cmd.PersistentFlags().Lookup("session.host_cursor")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().Bool("session.inactive_cursors", false, "show inactive cursors on the screen")
 	if err := viper.BindPFlag("session.inactive_cursors", cmd.PersistentFlags().Lookup("session.inactive_cursors")); err != nil {
 		return err
 	}
 
+	cmd.PersistentFlags().String("session.inactive_cursors_recipients", InactiveCursorsRecipientsAll, "who receives inactive cursors of other sessions: all, admins or host (host and admins)")
+	if err := viper.BindPFlag("session.inactive_cursors_recipients", cmd.PersistentFlags().Lookup("session.inactive_cursors_recipients")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("session.inactive_cursors_cleanup", true, "remove inactive cursor of a session immediately after it disconnects")
+	if err := viper.BindPFlag("session.inactive_cursors_cleanup", cmd.PersistentFlags().Lookup("session.inactive_cursors_cleanup")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("session.inactive_cursors_concurrency", 1, "number of sessions inactive cursors are sent to in parallel, increase for rooms with many sessions")
+	if err := viper.BindPFlag("session.inactive_cursors_concurrency", cmd.PersistentFlags().Lookup("session.inactive_cursors_concurrency")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().Bool("session.merciful_reconnect", true, "allow reconnecting to websocket even if previous connection was not closed")
 	if err := viper.BindPFlag("session.merciful_reconnect", cmd.PersistentFlags().Lookup("session.merciful_reconnect")); err != nil {
 		return err
 	}
 
+	cmd.PersistentFlags().Bool("session.join_approval", false, "new non-admin sessions can only watch until they are approved by an admin")
+	if err := viper.BindPFlag("session.join_approval", cmd.PersistentFlags().Lookup("session.join_approval")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("session.reconnect_token_ttl", 0, "how long a client can resume the same session using reconnect token sent in X-Reconnect-Token header after unexpected disconnect, presence and host are released meanwhile (0 disables reconnect tokens)")
+	if err := viper.BindPFlag("session.reconnect_token_ttl", cmd.PersistentFlags().Lookup("session.reconnect_token_ttl")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("session.flapping.threshold", 5, "number of reconnects within the window, after which the session is reported to admins as flapping (0 disables)")
+	if err := viper.BindPFlag("session.flapping.threshold", cmd.PersistentFlags().Lookup("session.flapping.threshold")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("session.flapping.window", 5*time.Minute, "time window in which reconnects of a session are counted for flapping detection")
+	if err := viper.BindPFlag("session.flapping.window", cmd.PersistentFlags().Lookup("session.flapping.window")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("session.consent.text", "", "terms shown to every session on connect, that must be acknowledged before it can watch or control (empty disables)")
+	if err := viper.BindPFlag("session.consent.text", cmd.PersistentFlags().Lookup("session.consent.text")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("session.consent.timeout", 2*time.Minute, "how long sessions that did not acknowledge the terms stay connected (0 means no limit)")
+	if err := viper.BindPFlag("session.consent.timeout", cmd.PersistentFlags().Lookup("session.consent.timeout")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("session.invite.secret", "", "secret used to sign one-time invite tokens creating guest sessions with given profile (empty disables invites)")
+	if err := viper.BindPFlag("session.invite.secret", cmd.PersistentFlags().Lookup("session.invite.secret")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("session.invite.ttl", time.Hour, "default validity of invite tokens, if not specified when creating them")
+	if err := viper.BindPFlag("session.invite.ttl", cmd.PersistentFlags().Lookup("session.invite.ttl")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("session.handoff.secret", "", "secret shared by servers to sign tokens moving sessions between them, empty disables handoff")
+	if err := viper.BindPFlag("session.handoff.secret", cmd.PersistentFlags().Lookup("session.handoff.secret")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("session.handoff.ttl", time.Minute, "how long handoff tokens are valid, the client is expected to reconnect to the target server within this time")
+	if err := viper.BindPFlag("session.handoff.ttl", cmd.PersistentFlags().Lookup("session.handoff.ttl")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("session.rejoin_changes", false, "send summary of changes in the room (host, members, settings) to sessions reconnecting after a gap")
+	if err := viper.BindPFlag("session.rejoin_changes", cmd.PersistentFlags().Lookup("session.rejoin_changes")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("session.rejoin_changes_limit", 20, "maximum number of changes sent to a rejoining session, the most recent ones are kept (0 for no limit)")
+	if err := viper.BindPFlag("session.rejoin_changes_limit", cmd.PersistentFlags().Lookup("session.rejoin_changes_limit")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("session.host_takeover_grace", 0, "seconds the host has to object before implicit hosting hands control to another session, 0 hands it over immediately")
+	if err := viper.BindPFlag("session.host_takeover_grace", cmd.PersistentFlags().Lookup("session.host_takeover_grace")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().Int("session.heartbeat_interval", 10, "interval in seconds for sending heartbeat messages")
 	if err := viper.BindPFlag("session.heartbeat_interval", cmd.PersistentFlags().Lookup("session.heartbeat_interval")); err != nil {
 		return err
@@ -85,6 +229,33 @@ func (Session) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	// secure transport
+	cmd.PersistentFlags().StringSlice("session.secure.features", []string{}, "features available only to sessions whose websocket connected over TLS or from localhost, others see them disabled: clipboard, file_transfer, media_sharing")
+	if err := viper.BindPFlag("session.secure.features", cmd.PersistentFlags().Lookup("session.secure.features")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringSlice("session.secure.trusted_proxies", []string{}, "IPs or CIDRs of TLS terminating reverse proxies whose X-Forwarded-Proto header is trusted")
+	if err := viper.BindPFlag("session.secure.trusted_proxies", cmd.PersistentFlags().Lookup("session.secure.trusted_proxies")); err != nil {
+		return err
+	}
+
+	// claims
+	cmd.PersistentFlags().String("session.claims.header", "", "request header with comma separated claims (e.g. groups) set by trusted authentication proxy, it must not be settable by clients (e.g. X-Forwarded-Groups)")
+	if err := viper.BindPFlag("session.claims.header", cmd.PersistentFlags().Lookup("session.claims.header")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("session.claims.mapping", "{}", "map of claims to profile permissions they grant: is_admin, can_host, can_share_media, can_access_clipboard (e.g. {\"admins\":[\"is_admin\",\"can_host\"]})")
+	if err := viper.BindPFlag("session.claims.mapping", cmd.PersistentFlags().Lookup("session.claims.mapping")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("session.broadcast_coalesce", "{}", "map of events to windows in which they are batched for each session, e.g. {\"chat/message\":\"200ms\",\"session/state\":\"500ms\"}, batched events are delayed and only the latest state or profile of each session is kept")
+	if err := viper.BindPFlag("session.broadcast_coalesce", cmd.PersistentFlags().Lookup("session.broadcast_coalesce")); err != nil {
+		return err
+	}
+
 	// cookie
 	cmd.PersistentFlags().Bool("session.cookie.enabled", true, "whether cookies authentication should be enabled")
 	if err := viper.BindPFlag("session.cookie.enabled", cmd.PersistentFlags().Lookup("session.cookie.enabled")); err != nil {
@@ -157,10 +328,102 @@ func (s *Session) Set() {
 	s.ControlProtection = viper.GetBool("session.control_protection")
 	s.ImplicitHosting = viper.GetBool("session.implicit_hosting")
 	s.InactiveCursors = viper.GetBool("session.inactive_cursors")
+	s.HostCursor = viper.GetBool("session.host_cursor")
+
+	s.InactiveCursorsRecipients = viper.GetString("session.inactive_cursors_recipients")
+	switch s.InactiveCursorsRecipients {
+	case InactiveCursorsRecipientsAll, InactiveCursorsRecipientsAdmins, InactiveCursorsRecipientsHost:
+	default:
+		log.Warn().Str("recipients", s.InactiveCursorsRecipients).Msg("unknown inactive cursors recipients, using all")
+		s.InactiveCursorsRecipients = InactiveCursorsRecipientsAll
+	}
+
+	s.InactiveCursorsCleanup = viper.GetBool("session.inactive_cursors_cleanup")
+
+	s.InactiveCursorsConcurrency = viper.GetInt("session.inactive_cursors_concurrency")
+	if s.InactiveCursorsConcurrency < 1 {
+		log.Warn().Int("concurrency", s.InactiveCursorsConcurrency).Msg("inactive cursors concurrency must be at least 1, using 1")
+		s.InactiveCursorsConcurrency = 1
+	}
+
 	s.MercifulReconnect = viper.GetBool("session.merciful_reconnect")
+	s.JoinApproval = viper.GetBool("session.join_approval")
+	s.RejoinChanges = viper.GetBool("session.rejoin_changes")
+	s.RejoinChangesLimit = viper.GetInt("session.rejoin_changes_limit")
 	s.HeartbeatInterval = viper.GetInt("session.heartbeat_interval")
+	s.HostTakeoverGrace = viper.GetInt("session.host_takeover_grace")
+	if s.HostTakeoverGrace < 0 {
+		log.Warn().Int("host_takeover_grace", s.HostTakeoverGrace).Msg("negative host takeover grace, handing over immediately")
+		s.HostTakeoverGrace = 0
+	}
 	s.APIToken = viper.GetString("session.api_token")
 
+	s.FlappingThreshold = viper.GetInt("session.flapping.threshold")
+	if s.FlappingThreshold < 0 {
+		log.Warn().Int("threshold", s.FlappingThreshold).Msg("negative flapping threshold, disabling flapping detection")
+		s.FlappingThreshold = 0
+	}
+	s.FlappingWindow = viper.GetDuration("session.flapping.window")
+	if s.FlappingThreshold > 0 && s.FlappingWindow <= 0 {
+		log.Warn().Dur("window", s.FlappingWindow).Msg("invalid flapping window, using 5m")
+		s.FlappingWindow = 5 * time.Minute
+	}
+
+	s.ConsentText = viper.GetString("session.consent.text")
+	s.ConsentTimeout = viper.GetDuration("session.consent.timeout")
+	if s.ConsentTimeout < 0 {
+		log.Warn().Dur("timeout", s.ConsentTimeout).Msg("negative consent timeout, using no limit")
+		s.ConsentTimeout = 0
+	}
+
+	s.InviteSecret = viper.GetString("session.invite.secret")
+	s.InviteTTL = viper.GetDuration("session.invite.ttl")
+	if s.InviteTTL <= 0 {
+		log.Warn().Dur("ttl", s.InviteTTL).Msg("invalid invite ttl, using 1h")
+		s.InviteTTL = time.Hour
+	}
+
+	s.HandoffSecret = viper.GetString("session.handoff.secret")
+	s.HandoffTTL = viper.GetDuration("session.handoff.ttl")
+	if s.HandoffTTL <= 0 {
+		log.Warn().Dur("ttl", s.HandoffTTL).Msg("invalid handoff ttl, using 1m")
+		s.HandoffTTL = time.Minute
+	}
+
+	s.SecureFeatures = []string{}
+	for _, feature := range viper.GetStringSlice("session.secure.features") {
+		if !slices.Contains(types.SecureFeatures, feature) {
+			log.Warn().Str("feature", feature).Msg("unknown secure feature, ignoring")
+			continue
+		}
+		s.SecureFeatures = append(s.SecureFeatures, feature)
+	}
+	s.SecureTrustedProxies = parseIPNets("session.secure.trusted_proxies", viper.GetStringSlice("session.secure.trusted_proxies"))
+
+	s.ClaimsHeader = viper.GetString("session.claims.header")
+	if err := viper.UnmarshalKey("session.claims.mapping", &s.ClaimsMapping, viper.DecodeHook(
+		utils.JsonStringAutoDecode(s.ClaimsMapping),
+	)); err != nil {
+		log.Warn().Err(err).Msgf("unable to parse session claims mapping")
+	}
+
+	coalesce := map[string]string{}
+	if err := viper.UnmarshalKey("session.broadcast_coalesce", &coalesce, viper.DecodeHook(
+		utils.JsonStringAutoDecode(coalesce),
+	)); err != nil {
+		log.Warn().Err(err).Msgf("unable to parse broadcast coalesce windows")
+	}
+
+	s.BroadcastCoalesce = map[string]time.Duration{}
+	for event, window := range coalesce {
+		d, err := time.ParseDuration(window)
+		if err != nil || d <= 0 {
+			log.Warn().Str("event", event).Str("window", window).Msg("invalid broadcast coalesce window, ignoring")
+			continue
+		}
+		s.BroadcastCoalesce[event] = d
+	}
+
 	s.Cookie.Enabled = viper.GetBool("session.cookie.enabled")
 	s.Cookie.Name = viper.GetString("session.cookie.name")
 	s.Cookie.Expiration = viper.GetDuration("session.cookie.expiration")
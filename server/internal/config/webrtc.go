@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -17,11 +18,47 @@ import (
 // default stun server
 const defStunSrv = "stun:stun.l.google.com:19302"
 
+// safe range for receive MTU, upper bound allows jumbo frames
+const (
+	defReceiveMTU = 1460
+	minReceiveMTU = 1200
+	maxReceiveMTU = 9000
+)
+
+// upper bound of UDP socket buffers, kernel may cap them further
+// (e.g. net.core.rmem_max and net.core.wmem_max on Linux)
+const maxSocketBuffer = 64 << 20
+
+const (
+	// peers over relay limit are allowed only direct (host and STUN) connections
+	RelayOverflowDirect = "direct"
+	// peers over relay limit are rejected
+	RelayOverflowReject = "reject"
+)
+
+const (
+	// google congestion control, reacts to growing delay before packets are
+	// lost, requires transport-wide feedback from the client
+	CongestionControlGCC = "gcc"
+	// reacts only to packet loss in receiver reports, simpler and cheaper, but
+	// lowers bitrate only once the network is already congested
+	CongestionControlLoss = "loss"
+)
+
+const (
+	// data channels with labels not claimed by any handler are left open
+	UnknownDataChannelsIgnore = "ignore"
+	// data channels with labels not claimed by any handler are closed
+	UnknownDataChannelsClose = "close"
+)
+
 type WebRTCEstimator struct {
 	Enabled        bool
 	Passive        bool
 	Debug          bool
 	InitialBitrate int
+	// congestion control algorithm estimating the bandwidth
+	CongestionControl string
 
 	// how often to read and process bandwidth estimation reports
 	ReadInterval time.Duration
@@ -37,6 +74,42 @@ type WebRTCEstimator struct {
 	UpgradeBackoff time.Duration
 	// how bigger the difference between estimated and stream bitrate must be to trigger upgrade/downgrade
 	DiffThreshold float64
+	// how long bandwidth must be good before upgrading after a downgrade, 0 disables
+	RestoreDelay time.Duration
+	// max upgrades within upgrade window, 0 means unlimited
+	MaxUpgrades   int
+	UpgradeWindow time.Duration
+
+	// how long to probe bandwidth at connection start to pick initial stream, 0 disables probing
+	ProbeDuration time.Duration
+	// how many times estimated bitrate must exceed stream bitrate to upgrade during probing
+	ProbeUpgradeRatio float64
+}
+
+type WebRTCAudioRED struct {
+	Enabled bool
+	// how many previous audio frames are repeated in every packet
+	Distance int
+	// packet loss in percent reported by the receiver, from which redundancy is sent
+	LossThreshold float64
+	// jitter reported by the receiver, from which redundancy is sent, 0 disables
+	JitterThreshold time.Duration
+}
+
+type WebRTCAVSync struct {
+	// how often audio/video offset of peers is estimated, 0 disables
+	Interval time.Duration
+	// offset above which peer is reported as out of sync
+	Threshold time.Duration
+	// send clients playout delays compensating the offset
+	PlayoutHints bool
+}
+
+type WebRTCSenderReport struct {
+	// how often RTCP sender reports are sent
+	Interval time.Duration
+	// map timestamps to the time of capture instead of the time of sending
+	CaptureClock bool
 }
 
 type WebRTC struct {
@@ -44,15 +117,104 @@ type WebRTC struct {
 	ICETrickle         bool
 	ICEServersFrontend []types.ICEServer
 	ICEServersBackend  []types.ICEServer
-	EphemeralMin       uint16
-	EphemeralMax       uint16
-	TCPMux             int
-	UDPMux             int
+
+	// header with client region or country, set by geo-IP aware proxy
+	ICERegionHeader string
+	// maps header values (e.g. countries) to regions of ICE servers
+	ICERegionMap map[string]string
+	EphemeralMin uint16
+	EphemeralMax uint16
+	TCPMux       int
+	UDPMux       int
+
+	// how many times binding of mux port is retried, with exponential backoff
+	MuxBindRetries int
+	MuxBindBackoff time.Duration
+	// bind mux to ephemeral port when configured port stays unavailable
+	MuxBindFallback bool
 
 	NAT1To1IPs     []string
 	IpRetrievalUrl string
 
+	// ingress hosts mapped to public IPs advertised to sessions using them
+	NAT1To1Hosts map[string]string
+
+	// network interfaces used for ICE candidate gathering
+	InterfacesAllow   []string
+	InterfacesDeny    []string
+	ExcludePrivateIPs bool
+
+	// size of buffer used for reading incoming RTP packets
+	ReceiveMTU uint
+	// size of UDP socket receive and send buffers, 0 keeps the system default
+	SocketReadBuffer  int
+	SocketWriteBuffer int
+
+	// how long disconnected peer connection can recover before it is closed
+	DisconnectedGrace time.Duration
+	// restart ICE once the peer connection gets disconnected, within grace period
+	DisconnectedRestart bool
+
+	// how long shared media of a closed peer can be resumed by the client, 0 disables
+	MediaResumeWindow time.Duration
+
+	// failed peers of a session within the window, after which client is notified, 0 disables
+	FailedPeersThreshold int
+	FailedPeersWindow    time.Duration
+
+	// how long video manually selected by the client is remembered for reconnects, 0 disables
+	VideoPreferenceTTL time.Duration
+
+	// how long peers stay on the fast start video after connecting, 0 disables
+	FastStartDuration time.Duration
+	// video stream used at start, empty means the lowest one
+	FastStartVideo string
+
+	// how often connected peers are checked for receiving video, 0 disables
+	WatchdogInterval time.Duration
+
+	// how often connection statistics are sent to clients, 0 disables
+	StatsInterval time.Duration
+
+	// max renegotiations in progress across all peers, 0 means unlimited
+	RenegotiationMax int
+	// how long renegotiation waits for an answer before its slot is released
+	RenegotiationTimeout time.Duration
+
+	// echo messages on diagnostics data channel created by client
+	Diagnostics bool
+	// what happens with data channels created by client, that no handler claimed
+	UnknownDataChannels string
+
+	// DSCP marking of outbound audio and video RTP packets, 0 means not marked
+	DSCPAudio int
+	DSCPVideo int
+
+	// max peers using relay (TURN) connection, 0 means unlimited
+	RelayMax int
+	// what happens with new peers when relay limit is reached
+	RelayOverflow string
+
+	// new peers are rejected while video pipeline load exceeds it, 0 disables
+	AdmissionMaxLoad float64
+
+	// default route of shared microphone
+	MicrophoneRoute types.MicrophoneRoute
+
+	// max inbound bitrate of shared webcam and microphone in kbps, 0 means unlimited
+	SharedVideoMaxBitrate int
+	SharedAudioMaxBitrate int
+
+	// audio level in -dBov above which a shared microphone is speaking
+	SpeakersThreshold uint8
+	// how long a speaker keeps its slot after it stopped speaking
+	SpeakersHold time.Duration
+
 	Estimator WebRTCEstimator
+	AudioRED  WebRTCAudioRED
+	AVSync    WebRTCAVSync
+
+	SenderReport WebRTCSenderReport
 }
 
 func (WebRTC) Init(cmd *cobra.Command) error {
@@ -82,6 +244,16 @@ func (WebRTC) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().String("webrtc.iceservers.region_header", "", "request header with client region or country set by geo-IP aware proxy (e.g. CF-IPCountry), frontend ICE servers with matching region are preferred, clients can also send region query parameter")
+	if err := viper.BindPFlag("webrtc.iceservers.region_header", cmd.PersistentFlags().Lookup("webrtc.iceservers.region_header")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("webrtc.iceservers.region_map", "{}", "map of region header values to regions of frontend ICE servers, unmapped values are used as region directly (e.g. {\"DE\":\"eu\",\"US\":\"us\"})")
+	if err := viper.BindPFlag("webrtc.iceservers.region_map", cmd.PersistentFlags().Lookup("webrtc.iceservers.region_map")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().String("webrtc.epr", "", "limits the pool of ephemeral ports that ICE UDP connections can allocate from")
 	if err := viper.BindPFlag("webrtc.epr", cmd.PersistentFlags().Lookup("webrtc.epr")); err != nil {
 		return err
@@ -97,16 +269,237 @@ func (WebRTC) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Int("webrtc.mux_bind_retries", 5, "how many times to retry binding TCP and UDP mux port when it is unavailable (e.g. still in use after unclean restart)")
+	if err := viper.BindPFlag("webrtc.mux_bind_retries", cmd.PersistentFlags().Lookup("webrtc.mux_bind_retries")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.mux_bind_backoff", time.Second, "initial delay between mux port bind retries, doubled after each attempt")
+	if err := viper.BindPFlag("webrtc.mux_bind_backoff", cmd.PersistentFlags().Lookup("webrtc.mux_bind_backoff")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("webrtc.mux_bind_fallback", false, "bind mux to an ephemeral port when configured port stays unavailable, it must be reachable by clients (not usable with port forwarding)")
+	if err := viper.BindPFlag("webrtc.mux_bind_fallback", cmd.PersistentFlags().Lookup("webrtc.mux_bind_fallback")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().StringSlice("webrtc.nat1to1", []string{}, "sets a list of external IP addresses of 1:1 (D)NAT and a candidate type for which the external IP address is used")
 	if err := viper.BindPFlag("webrtc.nat1to1", cmd.PersistentFlags().Lookup("webrtc.nat1to1")); err != nil {
 		return err
 	}
 
+	cmd.PersistentFlags().String("webrtc.nat1to1_hosts", "{}", "map of hosts used by clients to connect to public IPs advertised to them, when client connects using one of nat1to1 IPs directly, only that IP is advertised (e.g. {\"a.example.com\":\"203.0.113.1\"})")
+	if err := viper.BindPFlag("webrtc.nat1to1_hosts", cmd.PersistentFlags().Lookup("webrtc.nat1to1_hosts")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().String("webrtc.ip_retrieval_url", "https://checkip.amazonaws.com", "URL address used for retrieval of the external IP address")
 	if err := viper.BindPFlag("webrtc.ip_retrieval_url", cmd.PersistentFlags().Lookup("webrtc.ip_retrieval_url")); err != nil {
 		return err
 	}
 
+	cmd.PersistentFlags().StringSlice("webrtc.interfaces.allow", []string{}, "network interfaces used for ICE candidate gathering, supports wildcards (e.g. eth*), empty means all")
+	if err := viper.BindPFlag("webrtc.interfaces.allow", cmd.PersistentFlags().Lookup("webrtc.interfaces.allow")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringSlice("webrtc.interfaces.deny", []string{}, "network interfaces excluded from ICE candidate gathering, supports wildcards (e.g. docker*), takes precedence over allow")
+	if err := viper.BindPFlag("webrtc.interfaces.deny", cmd.PersistentFlags().Lookup("webrtc.interfaces.deny")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("webrtc.exclude_private_ips", false, "do not gather ICE candidates for private (RFC 1918, RFC 4193) IP addresses")
+	if err := viper.BindPFlag("webrtc.exclude_private_ips", cmd.PersistentFlags().Lookup("webrtc.exclude_private_ips")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Float64("webrtc.admission.max_load", 0, "reject new peers while load of any running video pipeline exceeds this value, load is the time between encoded frames relative to their duration, above 1 means frames are dropped (0 disables)")
+	if err := viper.BindPFlag("webrtc.admission.max_load", cmd.PersistentFlags().Lookup("webrtc.admission.max_load")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.fast_start.duration", 0, "how long new peers receive low quality video after connecting, before switching to the requested video, for faster time to first frame (0 disables)")
+	if err := viper.BindPFlag("webrtc.fast_start.duration", cmd.PersistentFlags().Lookup("webrtc.fast_start.duration")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("webrtc.fast_start.video", "", "video stream ID used for fast start, empty means the lowest video stream")
+	if err := viper.BindPFlag("webrtc.fast_start.video", cmd.PersistentFlags().Lookup("webrtc.fast_start.video")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.media_resume_window", 0, "clients reconnecting within this time after their peer was closed are asked to share their webcam and microphone again (0 disables)")
+	if err := viper.BindPFlag("webrtc.media_resume_window", cmd.PersistentFlags().Lookup("webrtc.media_resume_window")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.failed_peers.threshold", 3, "number of failed webrtc connections of a session within the window, after which the client is told that its network likely blocks media (0 disables)")
+	if err := viper.BindPFlag("webrtc.failed_peers.threshold", cmd.PersistentFlags().Lookup("webrtc.failed_peers.threshold")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.failed_peers.window", 5*time.Minute, "time window in which failed webrtc connections of a session are counted")
+	if err := viper.BindPFlag("webrtc.failed_peers.window", cmd.PersistentFlags().Lookup("webrtc.failed_peers.window")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.video_preference_ttl", time.Hour, "how long video quality manually selected by the client is remembered, reconnecting clients start with it and automatic selection does not upgrade past it (0 disables)")
+	if err := viper.BindPFlag("webrtc.video_preference_ttl", cmd.PersistentFlags().Lookup("webrtc.video_preference_ttl")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.watchdog.interval", 0, "how often connected peers are checked for receiving video, stalled peers get a keyframe and then an ICE restart (0 disables)")
+	if err := viper.BindPFlag("webrtc.watchdog.interval", cmd.PersistentFlags().Lookup("webrtc.watchdog.interval")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.stats.interval", 0, "how often connection statistics (bitrate, packet loss, nacks, rtt, candidate pair) are sent to connected clients (0 disables)")
+	if err := viper.BindPFlag("webrtc.stats.interval", cmd.PersistentFlags().Lookup("webrtc.stats.interval")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.renegotiation.max_concurrent", 0, "maximum renegotiations in progress across all peers, others are queued until an answer is received (0 means unlimited)")
+	if err := viper.BindPFlag("webrtc.renegotiation.max_concurrent", cmd.PersistentFlags().Lookup("webrtc.renegotiation.max_concurrent")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.renegotiation.timeout", 10*time.Second, "how long renegotiation waits for an answer before it stops blocking others")
+	if err := viper.BindPFlag("webrtc.renegotiation.timeout", cmd.PersistentFlags().Lookup("webrtc.renegotiation.timeout")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("webrtc.microphone_route", string(types.MicrophoneRouteDesktop), "default route of shared microphone: desktop (microphone), mix (outbound audio) or both, can be changed by client")
+	if err := viper.BindPFlag("webrtc.microphone_route", cmd.PersistentFlags().Lookup("webrtc.microphone_route")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.shared_media.video_max_bitrate", 0, "max inbound bitrate of shared webcam in kbps, sender is asked to not exceed it (REMB) and packets above 1.5x of it are dropped (0 for unlimited)")
+	if err := viper.BindPFlag("webrtc.shared_media.video_max_bitrate", cmd.PersistentFlags().Lookup("webrtc.shared_media.video_max_bitrate")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.shared_media.audio_max_bitrate", 0, "max inbound bitrate of shared microphone in kbps, packets above 1.5x of it are dropped (0 for unlimited)")
+	if err := viper.BindPFlag("webrtc.shared_media.audio_max_bitrate", cmd.PersistentFlags().Lookup("webrtc.shared_media.audio_max_bitrate")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.shared_media.speakers.threshold", 50, "audio level in -dBov (0 loudest, 127 silence) that shared microphone must exceed to be considered speaking, when concurrent speakers are enabled")
+	if err := viper.BindPFlag("webrtc.shared_media.speakers.threshold", cmd.PersistentFlags().Lookup("webrtc.shared_media.speakers.threshold")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.shared_media.speakers.hold", time.Second, "how long speaker keeps being forwarded after it stopped speaking, so that pauses between words do not mute it")
+	if err := viper.BindPFlag("webrtc.shared_media.speakers.hold", cmd.PersistentFlags().Lookup("webrtc.shared_media.speakers.hold")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.disconnected_grace", 5*time.Second, "how long to wait for disconnected peer connection to recover before closing it, failed connection is closed immediately (0 closes immediately)")
+	if err := viper.BindPFlag("webrtc.disconnected_grace", cmd.PersistentFlags().Lookup("webrtc.disconnected_grace")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("webrtc.disconnected_restart", true, "restart ICE once peer connection gets disconnected, so that clients roaming between networks recover within the grace period without a new peer connection (false waits for ICE to recover on its own)")
+	if err := viper.BindPFlag("webrtc.disconnected_restart", cmd.PersistentFlags().Lookup("webrtc.disconnected_restart")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("webrtc.diagnostics", false, "echo back messages on diagnostics data channel created by client, used to measure data channel round-trip time (debug)")
+	if err := viper.BindPFlag("webrtc.diagnostics", cmd.PersistentFlags().Lookup("webrtc.diagnostics")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("webrtc.dscp.audio", "", "DSCP marking of outbound audio packets, as number (0-63) or name (e.g. EF), empty means not marked")
+	if err := viper.BindPFlag("webrtc.dscp.audio", cmd.PersistentFlags().Lookup("webrtc.dscp.audio")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("webrtc.dscp.video", "", "DSCP marking of outbound video packets, as number (0-63) or name (e.g. AF41), empty means not marked")
+	if err := viper.BindPFlag("webrtc.dscp.video", cmd.PersistentFlags().Lookup("webrtc.dscp.video")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("webrtc.unknown_data_channels", UnknownDataChannelsIgnore, "what happens with data channels opened by client with label no handler claimed: ignore or close")
+	if err := viper.BindPFlag("webrtc.unknown_data_channels", cmd.PersistentFlags().Lookup("webrtc.unknown_data_channels")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.relay.max", 0, "max number of peers using relay (TURN) connection at the same time, 0 means unlimited")
+	if err := viper.BindPFlag("webrtc.relay.max", cmd.PersistentFlags().Lookup("webrtc.relay.max")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("webrtc.relay.overflow", RelayOverflowDirect, "what happens with new peers when relay limit is reached: direct (only host and STUN connections are allowed) or reject")
+	if err := viper.BindPFlag("webrtc.relay.overflow", cmd.PersistentFlags().Lookup("webrtc.relay.overflow")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Uint("webrtc.receive_mtu", defReceiveMTU, fmt.Sprintf("size of buffer for incoming RTP packets in bytes, must be between %d and %d (use values above 1500 only with jumbo frames)", minReceiveMTU, maxReceiveMTU))
+	if err := viper.BindPFlag("webrtc.receive_mtu", cmd.PersistentFlags().Lookup("webrtc.receive_mtu")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.socket.read_buffer", 0, fmt.Sprintf("size of UDP socket receive buffer in bytes, at most %d, system may limit it further (e.g. net.core.rmem_max), 0 keeps the system default", maxSocketBuffer))
+	if err := viper.BindPFlag("webrtc.socket.read_buffer", cmd.PersistentFlags().Lookup("webrtc.socket.read_buffer")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.socket.write_buffer", 0, fmt.Sprintf("size of UDP socket send buffer in bytes, at most %d, system may limit it further (e.g. net.core.wmem_max), 0 keeps the system default", maxSocketBuffer))
+	if err := viper.BindPFlag("webrtc.socket.write_buffer", cmd.PersistentFlags().Lookup("webrtc.socket.write_buffer")); err != nil {
+		return err
+	}
+
+	// audio redundancy
+
+	cmd.PersistentFlags().Bool("webrtc.audio_red.enabled", false, "offers redundant audio encoding (RED) to clients supporting it, it is used automatically on lossy connections")
+	if err := viper.BindPFlag("webrtc.audio_red.enabled", cmd.PersistentFlags().Lookup("webrtc.audio_red.enabled")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.audio_red.distance", 1, "how many previous audio frames are repeated in every packet, must be between 1 and 3")
+	if err := viper.BindPFlag("webrtc.audio_red.distance", cmd.PersistentFlags().Lookup("webrtc.audio_red.distance")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Float64("webrtc.audio_red.loss_threshold", 1, "packet loss in percent reported by the client, from which redundant audio is sent (0 sends it always)")
+	if err := viper.BindPFlag("webrtc.audio_red.loss_threshold", cmd.PersistentFlags().Lookup("webrtc.audio_red.loss_threshold")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.audio_red.jitter_threshold", 30*time.Millisecond, "jitter reported by the client, from which redundant audio is sent regardless of packet loss (0 disables)")
+	if err := viper.BindPFlag("webrtc.audio_red.jitter_threshold", cmd.PersistentFlags().Lookup("webrtc.audio_red.jitter_threshold")); err != nil {
+		return err
+	}
+
+	// audio/video sync
+
+	cmd.PersistentFlags().Duration("webrtc.av_sync.interval", 0, "how often audio/video sync offset of connected peers is estimated (0 disables)")
+	if err := viper.BindPFlag("webrtc.av_sync.interval", cmd.PersistentFlags().Lookup("webrtc.av_sync.interval")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.av_sync.threshold", 100*time.Millisecond, "audio/video offset from which peer is reported as out of sync")
+	if err := viper.BindPFlag("webrtc.av_sync.threshold", cmd.PersistentFlags().Lookup("webrtc.av_sync.threshold")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("webrtc.av_sync.playout_hints", false, "sends clients playout delays for audio and video, that compensate the estimated offset")
+	if err := viper.BindPFlag("webrtc.av_sync.playout_hints", cmd.PersistentFlags().Lookup("webrtc.av_sync.playout_hints")); err != nil {
+		return err
+	}
+
+	// sender reports
+
+	cmd.PersistentFlags().Duration("webrtc.sender_report.interval", time.Second, "how often RTCP sender reports with NTP timestamps are sent for audio and video tracks")
+	if err := viper.BindPFlag("webrtc.sender_report.interval", cmd.PersistentFlags().Lookup("webrtc.sender_report.interval")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("webrtc.sender_report.capture_clock", true, "map RTP timestamps of sender reports to the time of capture, so that clients synchronize audio and video captured by separate pipelines")
+	if err := viper.BindPFlag("webrtc.sender_report.capture_clock", cmd.PersistentFlags().Lookup("webrtc.sender_report.capture_clock")); err != nil {
+		return err
+	}
+
 	// bandwidth estimator
 
 	cmd.PersistentFlags().Bool("webrtc.estimator.enabled", false, "enables the bandwidth estimator")
@@ -124,6 +517,11 @@ func (WebRTC) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().String("webrtc.estimator.congestion_control", CongestionControlGCC, "congestion control used by the bandwidth estimator: 'gcc' reacts to growing delay and suits internet audiences, 'loss' reacts only to packet loss and suffices on LAN")
+	if err := viper.BindPFlag("webrtc.estimator.congestion_control", cmd.PersistentFlags().Lookup("webrtc.estimator.congestion_control")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().Int("webrtc.estimator.initial_bitrate", 1_000_000, "initial bitrate for the bandwidth estimator")
 	if err := viper.BindPFlag("webrtc.estimator.initial_bitrate", cmd.PersistentFlags().Lookup("webrtc.estimator.initial_bitrate")); err != nil {
 		return err
@@ -164,6 +562,31 @@ func (WebRTC) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Duration("webrtc.estimator.restore_delay", 10*time.Second, "how long estimated bandwidth must be sufficient for a higher stream before upgrading after a downgrade (0 disables)")
+	if err := viper.BindPFlag("webrtc.estimator.restore_delay", cmd.PersistentFlags().Lookup("webrtc.estimator.restore_delay")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.estimator.max_upgrades", 0, "maximum upgrades within upgrade window (0 means unlimited)")
+	if err := viper.BindPFlag("webrtc.estimator.max_upgrades", cmd.PersistentFlags().Lookup("webrtc.estimator.max_upgrades")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.estimator.upgrade_window", time.Minute, "window in which upgrades are limited by max upgrades")
+	if err := viper.BindPFlag("webrtc.estimator.upgrade_window", cmd.PersistentFlags().Lookup("webrtc.estimator.upgrade_window")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.estimator.probe_duration", 0, "how long to probe bandwidth at connection start, starting from the lowest stream and upgrading while bandwidth allows (0 disables probing)")
+	if err := viper.BindPFlag("webrtc.estimator.probe_duration", cmd.PersistentFlags().Lookup("webrtc.estimator.probe_duration")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Float64("webrtc.estimator.probe_upgrade_ratio", 1.5, "how many times estimated bitrate must exceed current stream bitrate to upgrade during probing, lower values are more aggressive (at least 1)")
+	if err := viper.BindPFlag("webrtc.estimator.probe_upgrade_ratio", cmd.PersistentFlags().Lookup("webrtc.estimator.probe_upgrade_ratio")); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -229,6 +652,13 @@ func (s *WebRTC) Set() {
 		log.Warn().Err(err).Msgf("unable to parse backend ICE servers")
 	}
 
+	s.ICERegionHeader = viper.GetString("webrtc.iceservers.region_header")
+	if err := viper.UnmarshalKey("webrtc.iceservers.region_map", &s.ICERegionMap, viper.DecodeHook(
+		utils.JsonStringAutoDecode(s.ICERegionMap),
+	)); err != nil {
+		log.Warn().Err(err).Msgf("unable to parse ICE servers region map")
+	}
+
 	if s.ICELite && len(s.ICEServersBackend) > 0 {
 		log.Warn().Msgf("ICE Lite is enabled, but backend ICE servers are configured. Backend ICE servers will be ignored.")
 	}
@@ -257,6 +687,20 @@ func (s *WebRTC) Set() {
 	s.TCPMux = viper.GetInt("webrtc.tcpmux")
 	s.UDPMux = viper.GetInt("webrtc.udpmux")
 
+	s.MuxBindRetries = viper.GetInt("webrtc.mux_bind_retries")
+	if s.MuxBindRetries < 0 {
+		log.Warn().Int("retries", s.MuxBindRetries).Msg("invalid mux bind retries, using 0")
+		s.MuxBindRetries = 0
+	}
+
+	s.MuxBindBackoff = viper.GetDuration("webrtc.mux_bind_backoff")
+	if s.MuxBindBackoff <= 0 {
+		log.Warn().Dur("backoff", s.MuxBindBackoff).Msg("invalid mux bind backoff, using 1s")
+		s.MuxBindBackoff = time.Second
+	}
+
+	s.MuxBindFallback = viper.GetBool("webrtc.mux_bind_fallback")
+
 	epr := viper.GetString("webrtc.epr")
 	if epr != "" {
 		ports := strings.SplitN(epr, "-", -1)
@@ -292,6 +736,11 @@ func (s *WebRTC) Set() {
 	}
 
 	s.NAT1To1IPs = viper.GetStringSlice("webrtc.nat1to1")
+	if err := viper.UnmarshalKey("webrtc.nat1to1_hosts", &s.NAT1To1Hosts, viper.DecodeHook(
+		utils.JsonStringAutoDecode(s.NAT1To1Hosts),
+	)); err != nil {
+		log.Warn().Err(err).Msgf("unable to parse webrtc nat1to1 hosts")
+	}
 	s.IpRetrievalUrl = viper.GetString("webrtc.ip_retrieval_url")
 	if s.IpRetrievalUrl != "" && len(s.NAT1To1IPs) == 0 {
 		ip, err := utils.HttpRequestGET(s.IpRetrievalUrl)
@@ -302,12 +751,204 @@ func (s *WebRTC) Set() {
 		}
 	}
 
+	s.InterfacesAllow = viper.GetStringSlice("webrtc.interfaces.allow")
+	s.InterfacesDeny = viper.GetStringSlice("webrtc.interfaces.deny")
+	s.ExcludePrivateIPs = viper.GetBool("webrtc.exclude_private_ips")
+
+	s.MicrophoneRoute = types.MicrophoneRoute(viper.GetString("webrtc.microphone_route"))
+	switch s.MicrophoneRoute {
+	case types.MicrophoneRouteDesktop, types.MicrophoneRouteMix, types.MicrophoneRouteBoth:
+	default:
+		log.Warn().Str("route", string(s.MicrophoneRoute)).Msg("unknown microphone route, using desktop")
+		s.MicrophoneRoute = types.MicrophoneRouteDesktop
+	}
+
+	s.SharedVideoMaxBitrate = viper.GetInt("webrtc.shared_media.video_max_bitrate")
+	if s.SharedVideoMaxBitrate < 0 {
+		log.Warn().Int("bitrate", s.SharedVideoMaxBitrate).Msg("negative shared video max bitrate, using unlimited")
+		s.SharedVideoMaxBitrate = 0
+	}
+
+	s.SharedAudioMaxBitrate = viper.GetInt("webrtc.shared_media.audio_max_bitrate")
+	if s.SharedAudioMaxBitrate < 0 {
+		log.Warn().Int("bitrate", s.SharedAudioMaxBitrate).Msg("negative shared audio max bitrate, using unlimited")
+		s.SharedAudioMaxBitrate = 0
+	}
+
+	speakersThreshold := viper.GetInt("webrtc.shared_media.speakers.threshold")
+	if speakersThreshold < 0 || speakersThreshold > 127 {
+		log.Warn().Int("threshold", speakersThreshold).Msg("speakers threshold out of range 0-127, using 50")
+		speakersThreshold = 50
+	}
+	s.SpeakersThreshold = uint8(speakersThreshold)
+
+	s.SpeakersHold = viper.GetDuration("webrtc.shared_media.speakers.hold")
+	if s.SpeakersHold < 0 {
+		log.Warn().Dur("hold", s.SpeakersHold).Msg("negative speakers hold, using 1s")
+		s.SpeakersHold = time.Second
+	}
+
+	s.ReceiveMTU = viper.GetUint("webrtc.receive_mtu")
+	if s.ReceiveMTU < minReceiveMTU || s.ReceiveMTU > maxReceiveMTU {
+		log.Warn().
+			Uint("receive_mtu", s.ReceiveMTU).
+			Uint("min", minReceiveMTU).
+			Uint("max", maxReceiveMTU).
+			Msgf("receive MTU out of range, using default %d", defReceiveMTU)
+		s.ReceiveMTU = defReceiveMTU
+	}
+
+	s.SocketReadBuffer = parseSocketBuffer("webrtc.socket.read_buffer")
+	s.SocketWriteBuffer = parseSocketBuffer("webrtc.socket.write_buffer")
+
+	s.DisconnectedGrace = viper.GetDuration("webrtc.disconnected_grace")
+	s.DisconnectedRestart = viper.GetBool("webrtc.disconnected_restart")
+	if s.DisconnectedGrace <= 0 {
+		// disconnected peer is closed right away, there is nothing to restart
+		s.DisconnectedRestart = false
+	}
+	s.MediaResumeWindow = viper.GetDuration("webrtc.media_resume_window")
+	s.FailedPeersThreshold = viper.GetInt("webrtc.failed_peers.threshold")
+	s.FailedPeersWindow = viper.GetDuration("webrtc.failed_peers.window")
+	if s.FailedPeersThreshold > 0 && s.FailedPeersWindow <= 0 {
+		log.Warn().Dur("window", s.FailedPeersWindow).Msg("failed peers window must be positive, using 5m")
+		s.FailedPeersWindow = 5 * time.Minute
+	}
+	s.VideoPreferenceTTL = viper.GetDuration("webrtc.video_preference_ttl")
+	s.Diagnostics = viper.GetBool("webrtc.diagnostics")
+
+	s.UnknownDataChannels = viper.GetString("webrtc.unknown_data_channels")
+	switch s.UnknownDataChannels {
+	case UnknownDataChannelsIgnore, UnknownDataChannelsClose:
+	default:
+		log.Warn().Str("unknown_data_channels", s.UnknownDataChannels).Msg("unknown data channels behavior, using ignore")
+		s.UnknownDataChannels = UnknownDataChannelsIgnore
+	}
+
+	s.AdmissionMaxLoad = viper.GetFloat64("webrtc.admission.max_load")
+	if s.AdmissionMaxLoad < 0 {
+		log.Warn().Float64("max_load", s.AdmissionMaxLoad).Msg("negative admission max load, disabling admission control")
+		s.AdmissionMaxLoad = 0
+	}
+
+	s.FastStartDuration = viper.GetDuration("webrtc.fast_start.duration")
+	if s.FastStartDuration < 0 {
+		log.Warn().Dur("duration", s.FastStartDuration).Msg("negative fast start duration, disabling fast start")
+		s.FastStartDuration = 0
+	}
+	s.FastStartVideo = viper.GetString("webrtc.fast_start.video")
+
+	s.WatchdogInterval = viper.GetDuration("webrtc.watchdog.interval")
+	if s.WatchdogInterval < 0 {
+		log.Warn().Dur("interval", s.WatchdogInterval).Msg("negative watchdog interval, disabling watchdog")
+		s.WatchdogInterval = 0
+	} else if s.WatchdogInterval > 0 && s.WatchdogInterval < 2*time.Second {
+		// receivers send reports about once a second
+		log.Warn().Dur("interval", s.WatchdogInterval).Msg("watchdog interval too short, using 2s")
+		s.WatchdogInterval = 2 * time.Second
+	}
+
+	s.StatsInterval = viper.GetDuration("webrtc.stats.interval")
+	if s.StatsInterval < 0 {
+		log.Warn().Dur("interval", s.StatsInterval).Msg("negative stats interval, disabling stats")
+		s.StatsInterval = 0
+	} else if s.StatsInterval > 0 && s.StatsInterval < time.Second {
+		// receivers send reports about once a second
+		log.Warn().Dur("interval", s.StatsInterval).Msg("stats interval too short, using 1s")
+		s.StatsInterval = time.Second
+	}
+
+	s.RenegotiationMax = viper.GetInt("webrtc.renegotiation.max_concurrent")
+	if s.RenegotiationMax < 0 {
+		log.Warn().Int("max", s.RenegotiationMax).Msg("negative renegotiation limit, using no limit")
+		s.RenegotiationMax = 0
+	}
+	s.RenegotiationTimeout = viper.GetDuration("webrtc.renegotiation.timeout")
+	if s.RenegotiationTimeout <= 0 {
+		log.Warn().Dur("timeout", s.RenegotiationTimeout).Msg("renegotiation timeout must be positive, using 10s")
+		s.RenegotiationTimeout = 10 * time.Second
+	}
+
+	// dscp marking
+
+	s.DSCPAudio = parseDSCP("webrtc.dscp.audio")
+	s.DSCPVideo = parseDSCP("webrtc.dscp.video")
+
+	// relay limit
+
+	s.RelayMax = viper.GetInt("webrtc.relay.max")
+	s.RelayOverflow = viper.GetString("webrtc.relay.overflow")
+	switch s.RelayOverflow {
+	case RelayOverflowDirect, RelayOverflowReject:
+	default:
+		log.Warn().Str("overflow", s.RelayOverflow).Msg("unknown relay overflow, using direct")
+		s.RelayOverflow = RelayOverflowDirect
+	}
+
+	// audio redundancy
+
+	s.AudioRED.Enabled = viper.GetBool("webrtc.audio_red.enabled")
+	s.AudioRED.Distance = viper.GetInt("webrtc.audio_red.distance")
+	if s.AudioRED.Distance < 1 || s.AudioRED.Distance > 3 {
+		log.Warn().Int("distance", s.AudioRED.Distance).Msg("audio red distance must be between 1 and 3, using 1")
+		s.AudioRED.Distance = 1
+	}
+	s.AudioRED.LossThreshold = viper.GetFloat64("webrtc.audio_red.loss_threshold")
+	if s.AudioRED.LossThreshold < 0 || s.AudioRED.LossThreshold > 100 {
+		log.Warn().Float64("threshold", s.AudioRED.LossThreshold).Msg("audio red loss threshold must be between 0 and 100, using 1")
+		s.AudioRED.LossThreshold = 1
+	}
+	s.AudioRED.JitterThreshold = viper.GetDuration("webrtc.audio_red.jitter_threshold")
+	if s.AudioRED.JitterThreshold < 0 {
+		log.Warn().Dur("threshold", s.AudioRED.JitterThreshold).Msg("negative audio red jitter threshold, disabling it")
+		s.AudioRED.JitterThreshold = 0
+	}
+
+	// audio/video sync
+
+	s.AVSync.Interval = viper.GetDuration("webrtc.av_sync.interval")
+	if s.AVSync.Interval < 0 {
+		log.Warn().Dur("interval", s.AVSync.Interval).Msg("negative av sync interval, disabling it")
+		s.AVSync.Interval = 0
+	} else if s.AVSync.Interval > 0 && s.AVSync.Interval < 2*time.Second {
+		// receivers send reports about once a second
+		log.Warn().Dur("interval", s.AVSync.Interval).Msg("av sync interval too short, using 2s")
+		s.AVSync.Interval = 2 * time.Second
+	}
+	s.AVSync.Threshold = viper.GetDuration("webrtc.av_sync.threshold")
+	if s.AVSync.Threshold <= 0 {
+		log.Warn().Dur("threshold", s.AVSync.Threshold).Msg("av sync threshold must be positive, using 100ms")
+		s.AVSync.Threshold = 100 * time.Millisecond
+	}
+	s.AVSync.PlayoutHints = viper.GetBool("webrtc.av_sync.playout_hints")
+
+	// sender reports
+
+	s.SenderReport.Interval = viper.GetDuration("webrtc.sender_report.interval")
+	if s.SenderReport.Interval < 100*time.Millisecond || s.SenderReport.Interval > 5*time.Second {
+		log.Warn().Dur("interval", s.SenderReport.Interval).Msg("sender report interval must be between 100ms and 5s, using 1s")
+		s.SenderReport.Interval = time.Second
+	}
+	s.SenderReport.CaptureClock = viper.GetBool("webrtc.sender_report.capture_clock")
+	if s.SenderReport.CaptureClock && s.AVSync.PlayoutHints {
+		// clients would compensate the offset twice
+		log.Warn().Msg("playout hints already compensate audio/video offset, disabling sender report capture clock")
+		s.SenderReport.CaptureClock = false
+	}
+
 	// bandwidth estimator
 
 	s.Estimator.Enabled = viper.GetBool("webrtc.estimator.enabled")
 	s.Estimator.Passive = viper.GetBool("webrtc.estimator.passive")
 	s.Estimator.Debug = viper.GetBool("webrtc.estimator.debug")
 	s.Estimator.InitialBitrate = viper.GetInt("webrtc.estimator.initial_bitrate")
+	s.Estimator.CongestionControl = viper.GetString("webrtc.estimator.congestion_control")
+	switch s.Estimator.CongestionControl {
+	case CongestionControlGCC, CongestionControlLoss:
+	default:
+		log.Warn().Str("congestion_control", s.Estimator.CongestionControl).Msg("unknown congestion control, using gcc")
+		s.Estimator.CongestionControl = CongestionControlGCC
+	}
 	s.Estimator.ReadInterval = viper.GetDuration("webrtc.estimator.read_interval")
 	s.Estimator.StableDuration = viper.GetDuration("webrtc.estimator.stable_duration")
 	s.Estimator.UnstableDuration = viper.GetDuration("webrtc.estimator.unstable_duration")
@@ -315,6 +956,27 @@ func (s *WebRTC) Set() {
 	s.Estimator.DowngradeBackoff = viper.GetDuration("webrtc.estimator.downgrade_backoff")
 	s.Estimator.UpgradeBackoff = viper.GetDuration("webrtc.estimator.upgrade_backoff")
 	s.Estimator.DiffThreshold = viper.GetFloat64("webrtc.estimator.diff_threshold")
+	s.Estimator.RestoreDelay = viper.GetDuration("webrtc.estimator.restore_delay")
+	if s.Estimator.RestoreDelay < 0 {
+		log.Warn().Dur("delay", s.Estimator.RestoreDelay).Msg("negative estimator restore delay, disabling it")
+		s.Estimator.RestoreDelay = 0
+	}
+	s.Estimator.MaxUpgrades = viper.GetInt("webrtc.estimator.max_upgrades")
+	if s.Estimator.MaxUpgrades < 0 {
+		log.Warn().Int("max", s.Estimator.MaxUpgrades).Msg("negative estimator upgrade limit, using no limit")
+		s.Estimator.MaxUpgrades = 0
+	}
+	s.Estimator.UpgradeWindow = viper.GetDuration("webrtc.estimator.upgrade_window")
+	if s.Estimator.UpgradeWindow <= 0 {
+		log.Warn().Dur("window", s.Estimator.UpgradeWindow).Msg("estimator upgrade window must be positive, using 1m")
+		s.Estimator.UpgradeWindow = time.Minute
+	}
+	s.Estimator.ProbeDuration = viper.GetDuration("webrtc.estimator.probe_duration")
+	s.Estimator.ProbeUpgradeRatio = viper.GetFloat64("webrtc.estimator.probe_upgrade_ratio")
+	if s.Estimator.ProbeUpgradeRatio < 1 {
+		log.Warn().Float64("ratio", s.Estimator.ProbeUpgradeRatio).Msg("estimator probe upgrade ratio must be at least 1, using 1.5")
+		s.Estimator.ProbeUpgradeRatio = 1.5
+	}
 }
 
 func (s *WebRTC) SetV2() {
@@ -413,3 +1075,43 @@ func (s *WebRTC) SetV2() {
 		viper.Set("legacy", true)
 	}
 }
+
+var dscpNames = map[string]int{
+	"CS0": 0, "CS1": 8, "CS2": 16, "CS3": 24, "CS4": 32, "CS5": 40, "CS6": 48, "CS7": 56,
+	"AF11": 10, "AF12": 12, "AF13": 14,
+	"AF21": 18, "AF22": 20, "AF23": 22,
+	"AF31": 26, "AF32": 28, "AF33": 30,
+	"AF41": 34, "AF42": 36, "AF43": 38,
+	"EF": 46,
+}
+
+// parseSocketBuffer returns socket buffer size of given key, invalid values keep the system default.
+func parseSocketBuffer(key string) int {
+	size := viper.GetInt(key)
+	if size < 0 || size > maxSocketBuffer {
+		log.Warn().Int(key, size).Int("max", maxSocketBuffer).Msg("socket buffer size out of range, using system default")
+		return 0
+	}
+
+	return size
+}
+
+// parseDSCP returns DSCP value of given key, invalid values disable marking.
+func parseDSCP(key string) int {
+	value := strings.ToUpper(strings.TrimSpace(viper.GetString(key)))
+	if value == "" {
+		return 0
+	}
+
+	if dscp, ok := dscpNames[value]; ok {
+		return dscp
+	}
+
+	dscp, err := strconv.Atoi(value)
+	if err != nil || dscp < 0 || dscp > 63 {
+		log.Warn().Str(key, value).Msg("invalid DSCP value, must be number between 0 and 63 or a name, disabling marking")
+		return 0
+	}
+
+	return dscp
+}
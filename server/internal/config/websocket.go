@@ -0,0 +1,424 @@
+package config
+
+import (
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/m1k1o/neko/server/pkg/utils"
+)
+
+const (
+	// desktop notifications are sent only to the host
+	NotificationRecipientsHost = "host"
+	// desktop notifications are sent to all sessions that can host
+	NotificationRecipientsControl = "control"
+)
+
+const (
+	// connections are accepted before the desktop is ready
+	StartupModeAccept = "accept"
+	// connections wait until the desktop is ready
+	StartupModeHold = "hold"
+	// connections are rejected until the desktop is ready
+	StartupModeReject = "reject"
+)
+
+type WebSocket struct {
+	// how long a connection can take until its peer is established, 0 disables
+	HandshakeTimeout time.Duration
+	// offer subprotocol with binary framing of hot input events
+	BinaryInput bool
+
+	// what happens to connections before the desktop is ready
+	StartupMode string
+	// how long connections are held until the desktop is ready
+	StartupTimeout time.Duration
+
+	// maximum payload length for logging, 0 means no limit
+	LogPayloadLength int
+	// map of event names to payload fields that are masked in logs
+	LogRedact map[string][]string
+
+	// how long a message handler can run before it is abandoned, 0 disables
+	HandlerTimeout time.Duration
+	// consecutive handler timeouts after which connection is closed, 0 disables
+	HandlerMaxTimeouts int
+	// max messages handled concurrently across connections, 0 handles all serially
+	HandlerConcurrency int
+	// max number of handlers registered by plugins, 0 means unlimited
+	HandlerMax int
+
+	// minimum interval between clipboard syncs to the host
+	ClipboardSyncInterval time.Duration
+	// max size of clipboard image synced to the host, 0 disables image sync
+	ClipboardImageMaxSize int
+
+	// reply with error to unhandled messages
+	UnhandledReply bool
+	// unhandled messages after which connection is closed, 0 disables
+	UnhandledMax int
+
+	// max concurrent connections from a single IP, 0 means unlimited
+	IPLimitMax int
+	// proxies whose forwarded headers are trusted for the real client IP
+	IPLimitTrustedProxies []*net.IPNet
+	// IPs exempt from the limit
+	IPLimitAllowlist []*net.IPNet
+
+	// rules picking default video of a session by its user agent, first match wins
+	VideoDefaults []VideoDefault
+
+	// privacy screen is shown while there is no host for the delay
+	PrivacyScreen      bool
+	PrivacyScreenDelay time.Duration
+
+	// who receives desktop notifications
+	NotificationRecipients string
+	// max desktop notifications forwarded per minute, 0 means unlimited
+	NotificationRate int
+
+	// count of connected sessions is sent to sessions that can host
+	Spectators bool
+	// minimum interval between spectator count updates
+	SpectatorsInterval time.Duration
+	// spectator count is broken down by role
+	SpectatorsByRole bool
+
+	// viewers not receiving media are disconnected after the timeout, 0 disables
+	IdleViewerTimeout time.Duration
+	// hosts not sending input are disconnected after the timeout, 0 applies viewer timeout
+	IdleHostTimeout time.Duration
+	// hosts are never disconnected for inactivity
+	IdleHostExempt bool
+}
+
+type VideoDefault struct {
+	// matched against user agent, nil matches any
+	UserAgent *regexp.Regexp
+	// matches only clients (not) reporting themselves as mobile, nil matches any
+	Mobile *bool
+	// video used by default, if the client does not request one
+	Video string
+}
+
+func (WebSocket) Init(cmd *cobra.Command) error {
+	cmd.PersistentFlags().Int("websocket.log.payload_length", 10_000, "maximum payload length for debug logging, longer payloads are truncated (0 means no limit)")
+	if err := viper.BindPFlag("websocket.log.payload_length", cmd.PersistentFlags().Lookup("websocket.log.payload_length")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("websocket.log.redact", "{}", "map of events and their payload fields masked in debug logs, use '*' to mask whole payload (e.g. {\"clipboard/set\":[\"text\"]})")
+	if err := viper.BindPFlag("websocket.log.redact", cmd.PersistentFlags().Lookup("websocket.log.redact")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("websocket.handshake_timeout", 10*time.Second, "how long a client can take from the upgrade until its connection is established, stalled handshakes are aborted (0 disables)")
+	if err := viper.BindPFlag("websocket.handshake_timeout", cmd.PersistentFlags().Lookup("websocket.handshake_timeout")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("websocket.binary_input", false, "allow clients to send mouse and keyboard input in compact binary frames, negotiated using websocket subprotocol")
+	if err := viper.BindPFlag("websocket.binary_input", cmd.PersistentFlags().Lookup("websocket.binary_input")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("websocket.startup.mode", StartupModeAccept, "connections made before the desktop and capture are ready: accept (possibly with incomplete state), hold until ready or reject asking clients to retry")
+	if err := viper.BindPFlag("websocket.startup.mode", cmd.PersistentFlags().Lookup("websocket.startup.mode")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("websocket.startup.timeout", 10*time.Second, "how long connections are held until the desktop is ready, then they are rejected")
+	if err := viper.BindPFlag("websocket.startup.timeout", cmd.PersistentFlags().Lookup("websocket.startup.timeout")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("websocket.handler.timeout", 5*time.Second, "how long a message handler can run before it is abandoned and following messages are handled (0 disables)")
+	if err := viper.BindPFlag("websocket.handler.timeout", cmd.PersistentFlags().Lookup("websocket.handler.timeout")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("websocket.handler.max_timeouts", 3, "number of consecutive handler timeouts after which the connection is closed (0 means never)")
+	if err := viper.BindPFlag("websocket.handler.max_timeouts", cmd.PersistentFlags().Lookup("websocket.handler.max_timeouts")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("websocket.handler.concurrency", 4, "number of messages with independent handlers (e.g. logs, navigation) handled concurrently across all connections, other messages are always handled in order (0 handles all messages in order)")
+	if err := viper.BindPFlag("websocket.handler.concurrency", cmd.PersistentFlags().Lookup("websocket.handler.concurrency")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("websocket.handler.max", 32, "maximum number of websocket message handlers registered by plugins, handlers registered again under the same name replace previous ones (0 means unlimited)")
+	if err := viper.BindPFlag("websocket.handler.max", cmd.PersistentFlags().Lookup("websocket.handler.max")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("websocket.unhandled.reply", false, "reply with system error to messages with unknown event, otherwise they are only logged")
+	if err := viper.BindPFlag("websocket.unhandled.reply", cmd.PersistentFlags().Lookup("websocket.unhandled.reply")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("websocket.unhandled.max", 0, "number of messages with unknown event after which the connection is closed (0 means never)")
+	if err := viper.BindPFlag("websocket.unhandled.max", cmd.PersistentFlags().Lookup("websocket.unhandled.max")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("websocket.clipboard.sync_interval", 500*time.Millisecond, "minimum interval between clipboard syncs to the host, changes in between are coalesced to the latest value (0 disables limit)")
+	if err := viper.BindPFlag("websocket.clipboard.sync_interval", cmd.PersistentFlags().Lookup("websocket.clipboard.sync_interval")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("websocket.clipboard.image_max_size", 0, "maximum size in bytes of clipboard image synced to the host, larger images are not synced (0 disables image sync)")
+	if err := viper.BindPFlag("websocket.clipboard.image_max_size", cmd.PersistentFlags().Lookup("websocket.clipboard.image_max_size")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("websocket.ip_limit.max", 0, "maximum concurrent websocket connections from a single IP address (0 means unlimited)")
+	if err := viper.BindPFlag("websocket.ip_limit.max", cmd.PersistentFlags().Lookup("websocket.ip_limit.max")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringSlice("websocket.ip_limit.trusted_proxies", []string{}, "IPs or CIDRs of reverse proxies whose X-Forwarded-For and X-Real-IP headers are trusted to determine client IP for the connection limit")
+	if err := viper.BindPFlag("websocket.ip_limit.trusted_proxies", cmd.PersistentFlags().Lookup("websocket.ip_limit.trusted_proxies")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringSlice("websocket.ip_limit.allowlist", []string{}, "IPs or CIDRs exempt from the connection limit")
+	if err := viper.BindPFlag("websocket.ip_limit.allowlist", cmd.PersistentFlags().Lookup("websocket.ip_limit.allowlist")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("websocket.video_defaults", "[]", "ordered rules picking default video of a session by its user agent, first match wins (e.g. [{\"mobile\":true,\"video\":\"lq\"},{\"user_agent\":\"SmartTV\",\"video\":\"hq\"}])")
+	if err := viper.BindPFlag("websocket.video_defaults", cmd.PersistentFlags().Lookup("websocket.video_defaults")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("websocket.privacy_screen.enabled", false, "obscure video and lock the screen while nobody is controlling the desktop")
+	if err := viper.BindPFlag("websocket.privacy_screen.enabled", cmd.PersistentFlags().Lookup("websocket.privacy_screen.enabled")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("websocket.privacy_screen.delay", 5*time.Second, "how long there must be no host before privacy screen is shown, so that handing over control does not trigger it")
+	if err := viper.BindPFlag("websocket.privacy_screen.delay", cmd.PersistentFlags().Lookup("websocket.privacy_screen.delay")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("websocket.notifications.recipients", NotificationRecipientsHost, "who receives desktop notifications: host or control (all connected sessions that can host)")
+	if err := viper.BindPFlag("websocket.notifications.recipients", cmd.PersistentFlags().Lookup("websocket.notifications.recipients")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("websocket.notifications.rate", 10, "maximum desktop notifications forwarded per minute, others are dropped (0 means unlimited)")
+	if err := viper.BindPFlag("websocket.notifications.rate", cmd.PersistentFlags().Lookup("websocket.notifications.rate")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("websocket.spectators.enabled", false, "send count of connected sessions to sessions that can host, whenever it changes")
+	if err := viper.BindPFlag("websocket.spectators.enabled", cmd.PersistentFlags().Lookup("websocket.spectators.enabled")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("websocket.spectators.interval", 2*time.Second, "minimum interval between spectator count updates, changes in between are coalesced")
+	if err := viper.BindPFlag("websocket.spectators.interval", cmd.PersistentFlags().Lookup("websocket.spectators.interval")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("websocket.spectators.by_role", false, "break spectator count down by role: admins, hosts and viewers")
+	if err := viper.BindPFlag("websocket.spectators.by_role", cmd.PersistentFlags().Lookup("websocket.spectators.by_role")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("websocket.idle.viewer_timeout", 0, "disconnect viewers that have not been receiving media for this long (0 disables)")
+	if err := viper.BindPFlag("websocket.idle.viewer_timeout", cmd.PersistentFlags().Lookup("websocket.idle.viewer_timeout")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("websocket.idle.host_timeout", 0, "disconnect hosts that have not sent any input for this long (0 treats hosts as viewers)")
+	if err := viper.BindPFlag("websocket.idle.host_timeout", cmd.PersistentFlags().Lookup("websocket.idle.host_timeout")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("websocket.idle.host_exempt", false, "never disconnect hosts for inactivity")
+	if err := viper.BindPFlag("websocket.idle.host_exempt", cmd.PersistentFlags().Lookup("websocket.idle.host_exempt")); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *WebSocket) Set() {
+	s.LogPayloadLength = viper.GetInt("websocket.log.payload_length")
+	if s.LogPayloadLength < 0 {
+		log.Warn().Int("payload_length", s.LogPayloadLength).Msg("negative log payload length, using no limit")
+		s.LogPayloadLength = 0
+	}
+
+	if err := viper.UnmarshalKey("websocket.log.redact", &s.LogRedact, viper.DecodeHook(
+		utils.JsonStringAutoDecode(s.LogRedact),
+	)); err != nil {
+		log.Warn().Err(err).Msgf("unable to parse websocket log redact rules")
+	}
+
+	s.HandshakeTimeout = viper.GetDuration("websocket.handshake_timeout")
+	if s.HandshakeTimeout < 0 {
+		log.Warn().Dur("timeout", s.HandshakeTimeout).Msg("negative handshake timeout, using no limit")
+		s.HandshakeTimeout = 0
+	}
+	s.BinaryInput = viper.GetBool("websocket.binary_input")
+
+	s.StartupMode = viper.GetString("websocket.startup.mode")
+	switch s.StartupMode {
+	case StartupModeAccept, StartupModeHold, StartupModeReject:
+	default:
+		log.Warn().Str("mode", s.StartupMode).Msg("unknown startup mode, using accept")
+		s.StartupMode = StartupModeAccept
+	}
+	s.StartupTimeout = viper.GetDuration("websocket.startup.timeout")
+	if s.StartupTimeout <= 0 {
+		log.Warn().Dur("timeout", s.StartupTimeout).Msg("startup timeout must be positive, using 10s")
+		s.StartupTimeout = 10 * time.Second
+	}
+
+	s.HandlerTimeout = viper.GetDuration("websocket.handler.timeout")
+	s.HandlerMaxTimeouts = viper.GetInt("websocket.handler.max_timeouts")
+	s.HandlerConcurrency = viper.GetInt("websocket.handler.concurrency")
+	s.HandlerMax = viper.GetInt("websocket.handler.max")
+	if s.HandlerConcurrency < 0 {
+		log.Warn().Msg("websocket handler concurrency must not be negative, handling messages in order")
+		s.HandlerConcurrency = 0
+	}
+	s.ClipboardSyncInterval = viper.GetDuration("websocket.clipboard.sync_interval")
+	s.ClipboardImageMaxSize = viper.GetInt("websocket.clipboard.image_max_size")
+	if s.ClipboardImageMaxSize < 0 {
+		log.Warn().Int("max_size", s.ClipboardImageMaxSize).Msg("negative clipboard image max size, disabling image sync")
+		s.ClipboardImageMaxSize = 0
+	}
+	s.UnhandledReply = viper.GetBool("websocket.unhandled.reply")
+	s.UnhandledMax = viper.GetInt("websocket.unhandled.max")
+
+	s.PrivacyScreen = viper.GetBool("websocket.privacy_screen.enabled")
+	s.PrivacyScreenDelay = viper.GetDuration("websocket.privacy_screen.delay")
+	if s.PrivacyScreenDelay < 0 {
+		log.Warn().Dur("delay", s.PrivacyScreenDelay).Msg("negative privacy screen delay, showing it immediately")
+		s.PrivacyScreenDelay = 0
+	}
+
+	s.NotificationRecipients = viper.GetString("websocket.notifications.recipients")
+	switch s.NotificationRecipients {
+	case NotificationRecipientsHost, NotificationRecipientsControl:
+	default:
+		log.Warn().Str("recipients", s.NotificationRecipients).Msg("unknown notification recipients, using host")
+		s.NotificationRecipients = NotificationRecipientsHost
+	}
+	s.NotificationRate = viper.GetInt("websocket.notifications.rate")
+	if s.NotificationRate < 0 {
+		log.Warn().Int("rate", s.NotificationRate).Msg("negative notification rate, using no limit")
+		s.NotificationRate = 0
+	}
+
+	s.Spectators = viper.GetBool("websocket.spectators.enabled")
+	s.SpectatorsInterval = viper.GetDuration("websocket.spectators.interval")
+	if s.SpectatorsInterval < 0 {
+		log.Warn().Dur("interval", s.SpectatorsInterval).Msg("negative spectators interval, using no limit")
+		s.SpectatorsInterval = 0
+	}
+	s.SpectatorsByRole = viper.GetBool("websocket.spectators.by_role")
+
+	s.IdleViewerTimeout = viper.GetDuration("websocket.idle.viewer_timeout")
+	if s.IdleViewerTimeout < 0 {
+		log.Warn().Dur("timeout", s.IdleViewerTimeout).Msg("negative idle viewer timeout, disabling it")
+		s.IdleViewerTimeout = 0
+	}
+	s.IdleHostTimeout = viper.GetDuration("websocket.idle.host_timeout")
+	if s.IdleHostTimeout < 0 {
+		log.Warn().Dur("timeout", s.IdleHostTimeout).Msg("negative idle host timeout, treating hosts as viewers")
+		s.IdleHostTimeout = 0
+	}
+	s.IdleHostExempt = viper.GetBool("websocket.idle.host_exempt")
+
+	s.IPLimitMax = viper.GetInt("websocket.ip_limit.max")
+	if s.IPLimitMax < 0 {
+		log.Warn().Int("max", s.IPLimitMax).Msg("negative connection limit per IP, using no limit")
+		s.IPLimitMax = 0
+	}
+
+	s.IPLimitTrustedProxies = parseIPNets("websocket.ip_limit.trusted_proxies", viper.GetStringSlice("websocket.ip_limit.trusted_proxies"))
+	s.IPLimitAllowlist = parseIPNets("websocket.ip_limit.allowlist", viper.GetStringSlice("websocket.ip_limit.allowlist"))
+
+	var videoDefaults []struct {
+		UserAgent string `mapstructure:"user_agent"`
+		Mobile    *bool  `mapstructure:"mobile"`
+		Video     string `mapstructure:"video"`
+	}
+	if err := viper.UnmarshalKey("websocket.video_defaults", &videoDefaults, viper.DecodeHook(
+		utils.JsonStringAutoDecode(videoDefaults),
+	)); err != nil {
+		log.Warn().Err(err).Msgf("unable to parse websocket video defaults")
+	}
+
+	s.VideoDefaults = []VideoDefault{}
+	for _, rule := range videoDefaults {
+		if rule.Video == "" {
+			log.Warn().Str("user_agent", rule.UserAgent).Msg("video default without video, skipping")
+			continue
+		}
+
+		var userAgent *regexp.Regexp
+		if rule.UserAgent != "" {
+			var err error
+			userAgent, err = regexp.Compile(rule.UserAgent)
+			if err != nil {
+				log.Warn().Err(err).Str("user_agent", rule.UserAgent).Msg("invalid video default user agent, skipping")
+				continue
+			}
+		}
+
+		s.VideoDefaults = append(s.VideoDefaults, VideoDefault{
+			UserAgent: userAgent,
+			Mobile:    rule.Mobile,
+			Video:     rule.Video,
+		})
+	}
+}
+
+// parseIPNets parses list of IPs and CIDRs, invalid entries are skipped.
+func parseIPNets(key string, values []string) []*net.IPNet {
+	nets := []*net.IPNet{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		// plain IP is a network with a single address
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				log.Warn().Str("key", key).Str("value", value).Msg("invalid IP address, skipping")
+				continue
+			}
+
+			bits := 128
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipnet, err := net.ParseCIDR(value)
+		if err != nil {
+			log.Warn().Err(err).Str("key", key).Str("value", value).Msg("invalid CIDR, skipping")
+			continue
+		}
+
+		nets = append(nets, ipnet)
+	}
+	return nets
+}
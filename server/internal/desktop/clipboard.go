@@ -3,7 +3,12 @@ package desktop
 import (
 	"bytes"
 	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
 	"os/exec"
+	"slices"
 	"strings"
 
 	"github.com/m1k1o/neko/server/pkg/types"
@@ -13,6 +18,7 @@ import (
 const (
 	ClipboardTextPlainTarget = "UTF8_STRING"
 	ClipboardTextHtmlTarget  = "text/html"
+	ClipboardImagePngTarget  = "image/png"
 )
 
 func (manager *DesktopManagerCtx) ClipboardGetText() (*types.ClipboardText, error) {
@@ -30,6 +36,73 @@ func (manager *DesktopManagerCtx) ClipboardGetText() (*types.ClipboardText, erro
 	}, nil
 }
 
+// ClipboardGetImage returns image held by clipboard as PNG, PNG is preferred when
+// the image is offered in multiple formats, otherwise it is converted. Images that
+// cannot be decoded are not returned.
+func (manager *DesktopManagerCtx) ClipboardGetImage() (*types.ClipboardImage, error) {
+	targets, err := manager.ClipboardGetTargets()
+	if err != nil {
+		return nil, err
+	}
+
+	mime, ok := clipboardImageTarget(targets)
+	if !ok {
+		return nil, nil
+	}
+
+	data, err := manager.ClipboardGetBinary(mime)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = clipboardImagePNG(mime, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ClipboardImage{
+		Mime: ClipboardImagePngTarget,
+		Data: data,
+	}, nil
+}
+
+// image formats that can be converted to PNG
+var clipboardImageTargets = []string{"image/jpeg", "image/gif"}
+
+func clipboardImageTarget(targets []string) (string, bool) {
+	found := ""
+	for _, target := range targets {
+		if target == ClipboardImagePngTarget {
+			return target, true
+		}
+
+		if found == "" && slices.Contains(clipboardImageTargets, target) {
+			found = target
+		}
+	}
+
+	return found, found != ""
+}
+
+// clipboardImagePNG converts image of given mime type to PNG.
+func clipboardImagePNG(mime string, data []byte) ([]byte, error) {
+	if mime == ClipboardImagePngTarget {
+		return data, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s clipboard image: %w", mime, err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
 func (manager *DesktopManagerCtx) ClipboardSetText(data types.ClipboardText) error {
 	// TODO: Refactor.
 	// Current implementation is unable to set multiple targets. HTML
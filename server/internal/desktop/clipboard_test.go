@@ -0,0 +1,65 @@
+package desktop
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestClipboardImageTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []string
+		want    string
+	}{
+		{"text only", []string{"text/plain", "text/html"}, ""},
+		{"png preferred", []string{"image/bmp", "image/png", "image/jpeg"}, "image/png"},
+		{"other image", []string{"text/uri-list", "image/jpeg", "image/bmp"}, "image/jpeg"},
+		{"not convertible", []string{"image/bmp", "image/svg+xml"}, ""},
+		{"empty", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := clipboardImageTarget(tt.targets)
+			if got != tt.want || ok != (tt.want != "") {
+				t.Errorf("clipboardImageTarget() = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestClipboardImagePNG(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	img.Set(1, 1, color.RGBA{255, 0, 0, 255})
+
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := clipboardImagePNG("image/jpeg", jpg.Bytes())
+	if err != nil {
+		t.Fatalf("clipboardImagePNG() error = %v", err)
+	}
+
+	converted, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("converted image is not PNG: %v", err)
+	}
+	if converted.Bounds() != img.Bounds() {
+		t.Errorf("converted image bounds %v, want %v", converted.Bounds(), img.Bounds())
+	}
+
+	// PNG is passed through as is
+	if data, err := clipboardImagePNG("image/png", []byte("png")); err != nil || string(data) != "png" {
+		t.Errorf("clipboardImagePNG() = %q, %v, want unchanged", data, err)
+	}
+
+	if _, err := clipboardImagePNG("image/gif", []byte("not an image")); err == nil {
+		t.Error("expected invalid image to fail")
+	}
+}
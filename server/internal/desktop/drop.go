@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/m1k1o/neko/server/pkg/drop"
+	"github.com/m1k1o/neko/server/pkg/types"
 )
 
 // repeat move event multiple times
@@ -12,19 +13,31 @@ const dropMoveRepeat = 4
 // wait after each repeated move event
 const dropMoveDelay = 100 * time.Millisecond
 
-func (manager *DesktopManagerCtx) DropFiles(x int, y int, files []string) bool {
+func (manager *DesktopManagerCtx) DropFiles(x int, y int, files []string) error {
+	size := manager.GetScreenSize()
+	if !inScreenBounds(size, x, y) {
+		return types.ErrDropOutOfBounds
+	}
+
+	startX, startY := manager.config.UploadDropStartX, manager.config.UploadDropStartY
+	if !inScreenBounds(size, startX, startY) {
+		return types.ErrDropOutOfBounds
+	}
+
+	button := manager.config.UploadDropButton
+
 	mu.Lock()
 	defer mu.Unlock()
 
 	drop.Emmiter.Clear()
 
 	drop.Emmiter.Once("create", func(payload ...any) {
-		manager.Move(0, 0)
+		manager.Move(startX, startY)
 	})
 
 	drop.Emmiter.Once("cursor-enter", func(payload ...any) {
 		//nolint
-		manager.ButtonDown(1)
+		manager.ButtonDown(button)
 	})
 
 	drop.Emmiter.Once("button-press", func(payload ...any) {
@@ -38,7 +51,7 @@ func (manager *DesktopManagerCtx) DropFiles(x int, y int, files []string) bool {
 		}
 
 		//nolint
-		manager.ButtonUp(1)
+		manager.ButtonUp(button)
 	})
 
 	finished := make(chan bool)
@@ -56,13 +69,20 @@ func (manager *DesktopManagerCtx) DropFiles(x int, y int, files []string) bool {
 
 	select {
 	case succeeded := <-finished:
-		return succeeded
+		if !succeeded {
+			return types.ErrDropFailed
+		}
+		return nil
 	case <-time.After(1 * time.Second):
 		drop.CloseWindow()
-		return false
+		return types.ErrDropFailed
 	}
 }
 
+func inScreenBounds(size types.ScreenSize, x, y int) bool {
+	return x >= 0 && y >= 0 && x < size.Width && y < size.Height
+}
+
 func (manager *DesktopManagerCtx) IsUploadDropEnabled() bool {
 	return manager.config.UploadDrop
 }
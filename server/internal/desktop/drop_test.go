@@ -0,0 +1,33 @@
+package desktop
+
+import (
+	"testing"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+func TestInScreenBounds(t *testing.T) {
+	size := types.ScreenSize{Width: 1280, Height: 720}
+
+	tests := []struct {
+		name string
+		x, y int
+		want bool
+	}{
+		{"origin", 0, 0, true},
+		{"inside", 640, 360, true},
+		{"last pixel", 1279, 719, true},
+		{"right edge", 1280, 360, false},
+		{"bottom edge", 640, 720, false},
+		{"negative x", -1, 360, false},
+		{"negative y", 640, -1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inScreenBounds(size, tt.x, tt.y); got != tt.want {
+				t.Errorf("inScreenBounds(%d, %d) = %v, want %v", tt.x, tt.y, got, tt.want)
+			}
+		})
+	}
+}
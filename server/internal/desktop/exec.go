@@ -0,0 +1,81 @@
+package desktop
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+var ErrCommandNotAllowed = errors.New("command is not allowed")
+
+// command prepares command executed in the desktop on behalf of a client. It
+// is rejected, if its executable is not in the configured allowlist. Arguments
+// are never interpreted by shell.
+func (manager *DesktopManagerCtx) command(action string, args []string) (*exec.Cmd, error) {
+	if len(args) == 0 {
+		return nil, ErrCommandNotAllowed
+	}
+
+	executable, ok := commandAllowed(manager.config.ExecAllowlist, args[0])
+	if !ok {
+		// audit log
+		manager.logger.Warn().
+			Str("action", action).
+			Str("command", args[0]).
+			Msg("rejected command that is not allowlisted")
+
+		return nil, fmt.Errorf("%w: %s", ErrCommandNotAllowed, args[0])
+	}
+
+	cmd := exec.Command(executable, args[1:]...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("DISPLAY=%s", manager.config.Display))
+	return cmd, nil
+}
+
+// commandAllowed checks executable against allowlist of names or absolute
+// paths, that support wildcards, and returns the executable that should be
+// run. Bare executable is resolved in PATH first, names match only bare
+// executables, absolute paths match the resolved one. Executable given by
+// path must match an absolute path. Empty allowlist allows any executable.
+func commandAllowed(allowlist []string, executable string) (string, bool) {
+	if len(allowlist) == 0 {
+		return executable, true
+	}
+
+	resolved := executable
+	bare := !strings.Contains(executable, "/")
+	if bare {
+		var err error
+		if resolved, err = exec.LookPath(executable); err != nil {
+			return "", false
+		}
+	}
+
+	for _, pattern := range allowlist {
+		if strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, resolved); ok {
+				return resolved, true
+			}
+		} else if bare {
+			if ok, _ := path.Match(pattern, executable); ok {
+				return resolved, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// schemeAllowed checks URL scheme against allowlist, case insensitive.
+func schemeAllowed(allowlist []string, scheme string) bool {
+	for _, allowed := range allowlist {
+		if strings.EqualFold(allowed, scheme) {
+			return true
+		}
+	}
+
+	return false
+}
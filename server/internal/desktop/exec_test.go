@@ -0,0 +1,64 @@
+package desktop
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommandAllowed(t *testing.T) {
+	// executables found in PATH
+	dir := t.TempDir()
+	for _, name := range []string{"xdg-open", "xset", "sh"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+
+	xdgOpen := filepath.Join(dir, "xdg-open")
+
+	tests := []struct {
+		name       string
+		allowlist  []string
+		executable string
+		want       string
+		allowed    bool
+	}{
+		{"empty allowlist", nil, "xdg-open", "xdg-open", true},
+		{"empty allowlist any path", nil, "/tmp/xdg-open", "/tmp/xdg-open", true},
+		{"name", []string{"xdg-open"}, "xdg-open", xdgOpen, true},
+		{"name does not match path", []string{"xdg-open"}, "/tmp/xdg-open", "", false},
+		{"name not in path", []string{"xdg-email"}, "xdg-email", "", false},
+		{"other name", []string{"xdg-open"}, "sh", "", false},
+		{"absolute path", []string{xdgOpen}, xdgOpen, xdgOpen, true},
+		{"absolute path other directory", []string{xdgOpen}, "/tmp/xdg-open", "", false},
+		{"absolute path matches name in path", []string{xdgOpen}, "xdg-open", xdgOpen, true},
+		{"absolute path wildcard", []string{filepath.Join(dir, "xdg-*")}, "xdg-open", xdgOpen, true},
+		{"wildcard", []string{"xdg-*"}, "xdg-open", xdgOpen, true},
+		{"wildcard does not match", []string{"xdg-*"}, "xset", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, allowed := commandAllowed(tt.allowlist, tt.executable)
+			if got != tt.want || allowed != tt.allowed {
+				t.Errorf("commandAllowed(%v, %q) = %q, %v, want %q, %v", tt.allowlist, tt.executable, got, allowed, tt.want, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestSchemeAllowed(t *testing.T) {
+	allowlist := []string{"http", "https"}
+
+	if !schemeAllowed(allowlist, "HTTPS") {
+		t.Error("expected scheme to be allowed case insensitive")
+	}
+	if schemeAllowed(allowlist, "file") {
+		t.Error("expected file scheme to be rejected")
+	}
+	if schemeAllowed(nil, "http") {
+		t.Error("expected empty allowlist to reject any scheme")
+	}
+}
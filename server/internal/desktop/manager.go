@@ -28,6 +28,9 @@ type DesktopManagerCtx struct {
 	screenSize types.ScreenSize // cached screen size
 	input      xinput.Driver
 
+	// key combinations emitted as named shortcuts, nil if none
+	shortcuts *shortcuts
+
 	// Clipboard process holding the most recent clipboard data.
 	// It must remain running to allow pasting clipboard data.
 	// The last command is kept running until it is replaced or shutdown.
@@ -42,7 +45,7 @@ func New(config *config.Desktop) *DesktopManagerCtx {
 		input = xinput.NewDummy()
 	}
 
-	return &DesktopManagerCtx{
+	manager := &DesktopManagerCtx{
 		logger:     log.With().Str("module", "desktop").Logger(),
 		shutdown:   make(chan struct{}),
 		emmiter:    events.New(),
@@ -50,6 +53,9 @@ func New(config *config.Desktop) *DesktopManagerCtx {
 		screenSize: config.ScreenSize,
 		input:      input,
 	}
+
+	manager.initShortcuts()
+	return manager
 }
 
 func (manager *DesktopManagerCtx) Start() {
@@ -100,6 +106,11 @@ func (manager *DesktopManagerCtx) Start() {
 			Msg("X event error occured")
 	})
 
+	if manager.config.Notifications {
+		manager.wg.Add(1)
+		go manager.watchNotifications()
+	}
+
 	manager.wg.Add(1)
 
 	go func() {
@@ -0,0 +1,107 @@
+package desktop
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+)
+
+var (
+	ErrNavigateDisabled   = errors.New("navigation is disabled")
+	ErrNavigateInvalidURL = errors.New("invalid navigation URL")
+	ErrNavigateNotAllowed = errors.New("navigation URL is not allowed")
+)
+
+// Navigate opens URL in the desktop using configured command. Only URLs with
+// allowlisted scheme and hostname matching configured allowlist are accepted.
+func (manager *DesktopManagerCtx) Navigate(rawUrl string) error {
+	if !manager.IsNavigateEnabled() {
+		return ErrNavigateDisabled
+	}
+
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Scheme == "" || (u.Host == "" && u.Opaque == "" && u.Path == "") {
+		return ErrNavigateInvalidURL
+	}
+
+	// web URLs must always have hostname
+	if (u.Scheme == "http" || u.Scheme == "https") && u.Hostname() == "" {
+		return ErrNavigateInvalidURL
+	}
+
+	if !schemeAllowed(manager.config.NavigateSchemes, u.Scheme) {
+		// audit log
+		manager.logger.Warn().
+			Str("scheme", u.Scheme).
+			Msg("rejected navigation to URL scheme that is not allowlisted")
+
+		return ErrNavigateNotAllowed
+	}
+
+	if !hostnameAllowed(manager.config.NavigateAllowlist, u.Hostname()) {
+		// audit log
+		manager.logger.Warn().
+			Str("hostname", u.Hostname()).
+			Msg("rejected navigation to hostname that is not allowlisted")
+
+		return ErrNavigateNotAllowed
+	}
+
+	// url is passed as a single argument, it is never interpreted by shell
+	args := strings.Fields(manager.config.NavigateCommand)
+	hasPlaceholder := false
+	for i, arg := range args {
+		if strings.Contains(arg, "{url}") {
+			args[i] = strings.ReplaceAll(arg, "{url}", u.String())
+			hasPlaceholder = true
+		}
+	}
+	if !hasPlaceholder {
+		args = append(args, u.String())
+	}
+
+	cmd, err := manager.command("navigate", args)
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	// do not leave zombie processes behind
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			manager.logger.Warn().Err(err).Str("url", u.String()).Msg("navigate command failed")
+		}
+	}()
+
+	return nil
+}
+
+func (manager *DesktopManagerCtx) IsNavigateEnabled() bool {
+	return manager.config.NavigateCommand != ""
+}
+
+// hostnameAllowed returns true if hostname matches any pattern of allowlist,
+// empty allowlist denies all and '*' allows any URL, even without hostname.
+func hostnameAllowed(allowlist []string, hostname string) bool {
+	hostname = strings.ToLower(hostname)
+	for _, pattern := range allowlist {
+		if pattern == "*" {
+			return true
+		}
+
+		// URLs without hostname can not match any hostname
+		if hostname == "" {
+			continue
+		}
+
+		if ok, _ := path.Match(strings.ToLower(pattern), hostname); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,108 @@
+package desktop
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/config"
+)
+
+func TestHostnameAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		allowlist []string
+		hostname  string
+		want      bool
+	}{
+		{"empty allowlist", nil, "example.com", false},
+		{"any", []string{"*"}, "example.com", true},
+		{"any without hostname", []string{"*"}, "", true},
+		{"exact", []string{"example.com"}, "example.com", true},
+		{"case insensitive", []string{"Example.com"}, "EXAMPLE.com", true},
+		{"other hostname", []string{"example.com"}, "example.org", false},
+		{"subdomain wildcard", []string{"*.example.com"}, "docs.example.com", true},
+		{"subdomain wildcard excludes domain", []string{"*.example.com"}, "example.com", false},
+		{"suffix is not subdomain", []string{"*.example.com"}, "docs.example.com.evil.org", false},
+		{"without hostname", []string{"*.example.com"}, "", false},
+		{"any of multiple", []string{"example.org", "example.com"}, "example.com", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hostnameAllowed(tt.allowlist, tt.hostname); got != tt.want {
+				t.Errorf("hostnameAllowed(%v, %q) = %v, want %v", tt.allowlist, tt.hostname, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNavigate(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "url")
+	script := filepath.Join(dir, "open")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$1\" > "+out+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := &DesktopManagerCtx{
+		logger: zerolog.Nop(),
+		config: &config.Desktop{
+			NavigateCommand:   script + " {url}",
+			NavigateAllowlist: []string{"*.example.com"},
+			NavigateSchemes:   []string{"http", "https"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		url  string
+		err  error
+	}{
+		{"not a URL", "example", ErrNavigateInvalidURL},
+		{"without hostname", "https:///path", ErrNavigateInvalidURL},
+		{"scheme not allowed", "file:///etc/passwd", ErrNavigateNotAllowed},
+		{"hostname not allowed", "https://example.org/", ErrNavigateNotAllowed},
+		{"userinfo does not count as hostname", "https://docs.example.com@example.org/", ErrNavigateNotAllowed},
+		{"allowed", "https://docs.example.com/page?q=1", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := manager.Navigate(tt.url); !errors.Is(err, tt.err) {
+				t.Errorf("Navigate(%q) = %v, want %v", tt.url, err, tt.err)
+			}
+		})
+	}
+
+	// url is passed to the command as a single argument
+	deadline := time.Now().Add(time.Second)
+	for {
+		data, err := os.ReadFile(out)
+		if err == nil && len(data) > 0 {
+			if got := string(data); got != "https://docs.example.com/page?q=1\n" {
+				t.Errorf("command received %q", got)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("navigate command was not run")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNavigateDisabled(t *testing.T) {
+	manager := &DesktopManagerCtx{
+		logger: zerolog.Nop(),
+		config: &config.Desktop{},
+	}
+
+	if err := manager.Navigate("https://example.com/"); !errors.Is(err, ErrNavigateDisabled) {
+		t.Errorf("Navigate() = %v, want %v", err, ErrNavigateDisabled)
+	}
+}
@@ -0,0 +1,149 @@
+package desktop
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// how long to wait before the notifications command is started again
+const notificationsRestartDelay = 5 * time.Second
+
+// notificationParser reads Notify calls of the org.freedesktop.Notifications
+// interface from dbus-monitor output. Call is a header line followed by its
+// arguments: app name, replaces id, icon, summary and body.
+type notificationParser struct {
+	inNotify bool
+	args     []string
+
+	// string argument spanning multiple lines
+	multiline bool
+	value     string
+}
+
+// line parses single line of the output and returns notification, once all of
+// its arguments were read.
+func (p *notificationParser) line(line string) (types.DesktopNotification, bool) {
+	if p.multiline {
+		if value, ok := strings.CutSuffix(line, `"`); ok {
+			p.multiline = false
+			p.args = append(p.args, p.value+"\n"+value)
+			return p.complete()
+		}
+
+		p.value += "\n" + line
+		return types.DesktopNotification{}, false
+	}
+
+	// every message starts with an unindented header
+	if !strings.HasPrefix(line, " ") {
+		p.inNotify = strings.HasPrefix(line, "method call ") && strings.Contains(line, "member=Notify")
+		p.args = nil
+		return types.DesktopNotification{}, false
+	}
+
+	// arguments are indented by three spaces, nested values by more
+	if !p.inNotify || strings.HasPrefix(line, "    ") {
+		return types.DesktopNotification{}, false
+	}
+
+	value := strings.TrimPrefix(line, "   ")
+	if str, ok := strings.CutPrefix(value, `string "`); ok {
+		if value, ok = strings.CutSuffix(str, `"`); !ok {
+			p.multiline = true
+			p.value = str
+			return types.DesktopNotification{}, false
+		}
+	}
+
+	p.args = append(p.args, value)
+	return p.complete()
+}
+
+func (p *notificationParser) complete() (types.DesktopNotification, bool) {
+	if len(p.args) < 5 {
+		return types.DesktopNotification{}, false
+	}
+
+	notification := types.DesktopNotification{
+		App:     p.args[0],
+		Summary: p.args[3],
+		Body:    p.args[4],
+	}
+
+	p.inNotify = false
+	p.args = nil
+	return notification, true
+}
+
+// watchNotifications runs the notifications command until shutdown, it is
+// started again whenever it exits.
+func (manager *DesktopManagerCtx) watchNotifications() {
+	defer manager.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		<-manager.shutdown
+		cancel()
+	}()
+
+	for {
+		if err := manager.runNotificationsCommand(ctx); err != nil && ctx.Err() == nil {
+			manager.logger.Warn().Err(err).Msg("desktop notifications command failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(notificationsRestartDelay):
+		}
+	}
+}
+
+func (manager *DesktopManagerCtx) runNotificationsCommand(ctx context.Context) error {
+	args := strings.Fields(manager.config.NotificationsCommand)
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("DISPLAY=%s", manager.config.Display))
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	parser := notificationParser{}
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		notification, ok := parser.line(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		manager.logger.Debug().
+			Str("app", notification.App).
+			Str("summary", notification.Summary).
+			Msg("desktop notification")
+
+		manager.emmiter.Emit("notification", notification)
+	}
+
+	return cmd.Wait()
+}
+
+func (manager *DesktopManagerCtx) OnNotification(listener func(notification types.DesktopNotification)) {
+	manager.emmiter.On("notification", func(payload ...any) {
+		listener(payload[0].(types.DesktopNotification))
+	})
+}
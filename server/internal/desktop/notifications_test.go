@@ -0,0 +1,123 @@
+package desktop
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// output of dbus-monitor "interface='org.freedesktop.Notifications'" captured
+// while notify-send and a GLib application were raising notifications
+const notificationsOutput = `signal time=1697360000.000000 sender=org.freedesktop.DBus -> destination=:1.41 serial=2 path=/org/freedesktop/DBus; interface=org.freedesktop.DBus; member=NameAcquired
+   string ":1.41"
+method call time=1697360001.412367 sender=:1.42 -> destination=org.freedesktop.Notifications serial=6 path=/org/freedesktop/Notifications; interface=org.freedesktop.Notifications; member=GetServerInformation
+method return time=1697360001.413021 sender=:1.10 -> destination=:1.42 serial=19 reply_serial=6
+   string "xfce4-notifyd"
+   string "Xfce"
+   string "0.8.2"
+   string "1.2"
+method call time=1697360001.413577 sender=:1.42 -> destination=org.freedesktop.Notifications serial=7 path=/org/freedesktop/Notifications; interface=org.freedesktop.Notifications; member=Notify
+   string "notify-send"
+   uint32 0
+   string "dialog-information"
+   string "Build finished"
+   string "All 42 tests passed"
+   array [
+   ]
+   array [
+      dict entry(
+         string "urgency"
+         variant             byte 1
+      )
+      dict entry(
+         string "sender-pid"
+         variant             int64 12345
+      )
+   ]
+   int32 -1
+method return time=1697360001.415226 sender=:1.10 -> destination=:1.42 serial=20 reply_serial=7
+   uint32 5
+method call time=1697360002.100214 sender=:1.51 -> destination=org.freedesktop.Notifications serial=31 path=/org/freedesktop/Notifications; interface=org.freedesktop.Notifications; member=Notify
+   string "Thunderbird"
+   uint32 5
+   string ""
+   string "New message from "Alice""
+   string "Hi,
+see you at 10:00.
+Bob"
+   array [
+      string "default"
+      string "Open"
+   ]
+   array [
+      dict entry(
+         string "desktop-entry"
+         variant             string "thunderbird"
+      )
+   ]
+   int32 5000
+method call time=1697360003.000000 sender=:1.51 -> destination=org.freedesktop.Notifications serial=32 path=/org/freedesktop/Notifications; interface=org.freedesktop.Notifications; member=CloseNotification
+   uint32 5
+signal time=1697360003.001000 sender=:1.10 -> destination=:1.51 serial=21 path=/org/freedesktop/Notifications; interface=org.freedesktop.Notifications; member=NotificationClosed
+   uint32 5
+   uint32 3
+method call time=1697360004.000000 sender=:1.60 -> destination=org.freedesktop.Notifications serial=4 path=/org/freedesktop/Notifications; interface=org.freedesktop.Notifications; member=Notify
+   string "truncated"
+   uint32 0
+method call time=1697360004.500000 sender=:1.60 -> destination=org.freedesktop.Notifications serial=5 path=/org/freedesktop/Notifications; interface=org.freedesktop.Notifications; member=Notify
+   string "notify-send"
+   uint32 0
+   string ""
+   string "Empty body"
+   string ""
+   array [
+   ]
+   array [
+   ]
+   int32 -1
+`
+
+func TestNotificationParser(t *testing.T) {
+	parser := notificationParser{}
+
+	var got []types.DesktopNotification
+	for _, line := range strings.Split(notificationsOutput, "\n") {
+		if notification, ok := parser.line(line); ok {
+			got = append(got, notification)
+		}
+	}
+
+	want := []types.DesktopNotification{
+		{App: "notify-send", Summary: "Build finished", Body: "All 42 tests passed"},
+		{App: "Thunderbird", Summary: `New message from "Alice"`, Body: "Hi,\nsee you at 10:00.\nBob"},
+		{App: "notify-send", Summary: "Empty body", Body: ""},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsed notifications:\n%+v\nwant:\n%+v", got, want)
+	}
+}
+
+func TestNotificationParserIgnoresReplies(t *testing.T) {
+	parser := notificationParser{}
+
+	// return value of Notify must not be taken as arguments of another call
+	lines := []string{
+		"method call time=1697360001.413577 sender=:1.42 -> destination=org.freedesktop.Notifications serial=7 path=/org/freedesktop/Notifications; interface=org.freedesktop.Notifications; member=Notify",
+		`   string "notify-send"`,
+		"method return time=1697360001.415226 sender=:1.10 -> destination=:1.42 serial=20 reply_serial=7",
+		"   uint32 5",
+		`   string "a"`,
+		`   string "b"`,
+		`   string "c"`,
+		`   string "d"`,
+	}
+
+	for _, line := range lines {
+		if notification, ok := parser.line(line); ok {
+			t.Errorf("unexpected notification %+v", notification)
+		}
+	}
+}
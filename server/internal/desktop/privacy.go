@@ -0,0 +1,36 @@
+package desktop
+
+import "strings"
+
+// SetScreenLocked runs configured command locking or unlocking the screen,
+// it does nothing if the command is not configured. Lock commands may keep
+// running until the screen is unlocked, so they are not waited for.
+func (manager *DesktopManagerCtx) SetScreenLocked(locked bool) error {
+	command := manager.config.PrivacyUnlockCommand
+	if locked {
+		command = manager.config.PrivacyLockCommand
+	}
+
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil
+	}
+
+	cmd, err := manager.command("privacy", args)
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	// do not leave zombie processes behind
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			manager.logger.Warn().Err(err).Bool("locked", locked).Msg("screen lock command failed")
+		}
+	}()
+
+	return nil
+}
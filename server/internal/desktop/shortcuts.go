@@ -0,0 +1,258 @@
+package desktop
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrShortcutInvalid = errors.New("invalid shortcut")
+
+type shortcutModifiers uint8
+
+const (
+	modifierCtrl shortcutModifiers = 1 << iota
+	modifierShift
+	modifierAlt
+	modifierSuper
+)
+
+var shortcutModifierNames = map[string]shortcutModifiers{
+	"ctrl":    modifierCtrl,
+	"control": modifierCtrl,
+	"shift":   modifierShift,
+	"alt":     modifierAlt,
+	"super":   modifierSuper,
+}
+
+// keysyms of left and right modifier keys
+var shortcutModifierKeys = map[uint32]shortcutModifiers{
+	0xffe3: modifierCtrl,  // Control_L
+	0xffe4: modifierCtrl,  // Control_R
+	0xffe1: modifierShift, // Shift_L
+	0xffe2: modifierShift, // Shift_R
+	0xffe9: modifierAlt,   // Alt_L
+	0xffea: modifierAlt,   // Alt_R
+	0xffe7: modifierAlt,   // Meta_L
+	0xffe8: modifierAlt,   // Meta_R
+	0xffeb: modifierSuper, // Super_L
+	0xffec: modifierSuper, // Super_R
+}
+
+// keysyms of keys without a printable character
+var shortcutKeyNames = map[string]uint32{
+	"space":     0x0020,
+	"backspace": 0xff08,
+	"tab":       0xff09,
+	"enter":     0xff0d,
+	"return":    0xff0d,
+	"escape":    0xff1b,
+	"home":      0xff50,
+	"left":      0xff51,
+	"up":        0xff52,
+	"right":     0xff53,
+	"down":      0xff54,
+	"pageup":    0xff55,
+	"pagedown":  0xff56,
+	"end":       0xff57,
+	"print":     0xff61,
+	"insert":    0xff63,
+	"delete":    0xffff,
+	// media keys
+	"audiolowervolume": 0x1008ff11,
+	"audiomute":        0x1008ff12,
+	"audioraisevolume": 0x1008ff13,
+	"audioplay":        0x1008ff14,
+	"audiostop":        0x1008ff15,
+	"audioprev":        0x1008ff16,
+	"audionext":        0x1008ff17,
+}
+
+type shortcutKey struct {
+	modifiers shortcutModifiers
+	keysym    uint32
+}
+
+// parseShortcut parses modifiers joined by '+' with a key, that is either
+// a single character, name of a key, function key (f1-f12) or a keysym in
+// hexadecimal (e.g. 0xff61).
+func parseShortcut(combo string) (shortcutKey, error) {
+	parts := strings.Split(combo, "+")
+
+	var key shortcutKey
+	for _, part := range parts[:len(parts)-1] {
+		modifier, ok := shortcutModifierNames[strings.ToLower(strings.TrimSpace(part))]
+		if !ok {
+			return key, fmt.Errorf("%w: unknown modifier %q", ErrShortcutInvalid, part)
+		}
+		key.modifiers |= modifier
+	}
+
+	keysym, err := parseShortcutKeysym(strings.TrimSpace(parts[len(parts)-1]))
+	if err != nil {
+		return key, err
+	}
+
+	key.keysym = keysym
+	return key, nil
+}
+
+func parseShortcutKeysym(name string) (uint32, error) {
+	if utf8.RuneCountInString(name) == 1 {
+		r, _ := utf8.DecodeRuneInString(name)
+		return normalizeKeysym(runeKeysym(r)), nil
+	}
+
+	lower := strings.ToLower(name)
+	if keysym, ok := shortcutKeyNames[lower]; ok {
+		return keysym, nil
+	}
+
+	if n, ok := strings.CutPrefix(lower, "f"); ok {
+		if i, err := strconv.Atoi(n); err == nil && i >= 1 && i <= 12 {
+			return 0xffbe + uint32(i-1), nil
+		}
+	}
+
+	if hex, ok := strings.CutPrefix(lower, "0x"); ok {
+		if keysym, err := strconv.ParseUint(hex, 16, 32); err == nil {
+			return normalizeKeysym(uint32(keysym)), nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: unknown key %q", ErrShortcutInvalid, name)
+}
+
+// runeKeysym returns keysym of a character, Latin-1 characters have keysyms
+// matching their code, others are offset.
+func runeKeysym(r rune) uint32 {
+	if r < 0x100 {
+		return uint32(r)
+	}
+	return 0x01000000 + uint32(r)
+}
+
+// normalizeKeysym maps keysyms of upper case characters to lower case, so
+// that shortcuts with shift match regardless of the character it produced.
+func normalizeKeysym(keysym uint32) uint32 {
+	switch {
+	case keysym < 0x100:
+		return uint32(unicode.ToLower(rune(keysym)))
+	case keysym > 0x01000000 && keysym <= 0x0110ffff:
+		return runeKeysym(unicode.ToLower(rune(keysym - 0x01000000)))
+	}
+	return keysym
+}
+
+// shortcuts recognizes configured key combinations in keys pressed by the host.
+// Keys completing a shortcut are not injected unless passthrough is enabled,
+// and neither are their releases.
+type shortcuts struct {
+	bindings    map[shortcutKey]string
+	passthrough bool
+
+	mu sync.Mutex
+	// modifier keys held down
+	pressed map[uint32]shortcutModifiers
+	// keys that were not injected, their release is not injected either
+	swallowed map[uint32]struct{}
+}
+
+func newShortcuts(bindings map[shortcutKey]string, passthrough bool) *shortcuts {
+	return &shortcuts{
+		bindings:    bindings,
+		passthrough: passthrough,
+		pressed:     map[uint32]shortcutModifiers{},
+		swallowed:   map[uint32]struct{}{},
+	}
+}
+
+// keyDown returns name of the shortcut completed by the key, if any, and
+// whether the key should be injected.
+func (s *shortcuts) keyDown(keysym uint32) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if modifier, ok := shortcutModifierKeys[keysym]; ok {
+		s.pressed[keysym] = modifier
+		return "", true
+	}
+
+	var modifiers shortcutModifiers
+	for _, modifier := range s.pressed {
+		modifiers |= modifier
+	}
+
+	name, ok := s.bindings[shortcutKey{modifiers, normalizeKeysym(keysym)}]
+	if !ok {
+		return "", true
+	}
+
+	if !s.passthrough {
+		s.swallowed[keysym] = struct{}{}
+		return name, false
+	}
+
+	return name, true
+}
+
+// keyUp returns whether release of the key should be injected.
+func (s *shortcuts) keyUp(keysym uint32) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.pressed, keysym)
+
+	if _, ok := s.swallowed[keysym]; ok {
+		delete(s.swallowed, keysym)
+		return false
+	}
+
+	return true
+}
+
+// reset forgets pressed keys, when all keys of the desktop were released.
+func (s *shortcuts) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.pressed)
+	clear(s.swallowed)
+}
+
+func (manager *DesktopManagerCtx) initShortcuts() {
+	if len(manager.config.ShortcutBindings) == 0 {
+		return
+	}
+
+	bindings := map[shortcutKey]string{}
+	for name, combo := range manager.config.ShortcutBindings {
+		key, err := parseShortcut(combo)
+		if err != nil {
+			manager.logger.Warn().Err(err).Str("shortcut", name).Msg("ignoring shortcut")
+			continue
+		}
+
+		if other, ok := bindings[key]; ok {
+			manager.logger.Warn().
+				Str("shortcut", name).
+				Str("other", other).
+				Msg("ignoring shortcut, key combination is already used")
+			continue
+		}
+
+		bindings[key] = name
+	}
+
+	manager.shortcuts = newShortcuts(bindings, manager.config.ShortcutPassthrough)
+}
+
+func (manager *DesktopManagerCtx) OnShortcut(listener func(name string)) {
+	manager.emmiter.On("shortcut", func(payload ...any) {
+		listener(payload[0].(string))
+	})
+}
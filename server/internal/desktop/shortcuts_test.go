@@ -0,0 +1,89 @@
+package desktop
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseShortcut(t *testing.T) {
+	tests := []struct {
+		combo string
+		want  shortcutKey
+	}{
+		{"ctrl+shift+m", shortcutKey{modifierCtrl | modifierShift, 'm'}},
+		{"Ctrl + M", shortcutKey{modifierCtrl, 'm'}},
+		{"print", shortcutKey{0, 0xff61}},
+		{"super+F5", shortcutKey{modifierSuper, 0xffc2}},
+		{"alt+0x1008ff12", shortcutKey{modifierAlt, 0x1008ff12}},
+		{"ctrl+ä", shortcutKey{modifierCtrl, 0xe4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.combo, func(t *testing.T) {
+			got, err := parseShortcut(tt.combo)
+			if err != nil {
+				t.Fatalf("parseShortcut() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseShortcut() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	for _, combo := range []string{"", "hyper+m", "ctrl+unknown", "f13"} {
+		if _, err := parseShortcut(combo); !errors.Is(err, ErrShortcutInvalid) {
+			t.Errorf("parseShortcut(%q) error = %v, want %v", combo, err, ErrShortcutInvalid)
+		}
+	}
+}
+
+func TestShortcuts(t *testing.T) {
+	s := newShortcuts(map[shortcutKey]string{
+		{modifierCtrl | modifierShift, 'm'}: "mute",
+	}, false)
+
+	// without modifiers key is injected
+	if name, inject := s.keyDown('m'); name != "" || !inject {
+		t.Errorf("keyDown() = %q, %v, want no shortcut", name, inject)
+	}
+	s.keyUp('m')
+
+	s.keyDown(0xffe3) // Control_L
+	s.keyDown(0xffe2) // Shift_R
+
+	// shift produces upper case keysym
+	if name, inject := s.keyDown('M'); name != "mute" || inject {
+		t.Errorf("keyDown() = %q, %v, want swallowed mute", name, inject)
+	}
+	if s.keyUp('M') {
+		t.Error("release of swallowed key is injected")
+	}
+
+	if !s.keyUp(0xffe2) || !s.keyUp(0xffe3) {
+		t.Error("release of modifier is not injected")
+	}
+
+	// modifiers were released
+	if name, _ := s.keyDown('m'); name != "" {
+		t.Errorf("keyDown() = %q after modifiers were released", name)
+	}
+}
+
+func TestShortcutsPassthrough(t *testing.T) {
+	s := newShortcuts(map[shortcutKey]string{
+		{0, 0xff61}: "screenshot",
+	}, true)
+
+	if name, inject := s.keyDown(0xff61); name != "screenshot" || !inject {
+		t.Errorf("keyDown() = %q, %v, want injected screenshot", name, inject)
+	}
+	if !s.keyUp(0xff61) {
+		t.Error("release of passed through key is not injected")
+	}
+
+	s.keyDown(0xffe3)
+	s.reset()
+	if name, _ := s.keyDown(0xff61); name != "screenshot" {
+		t.Errorf("keyDown() = %q, modifier was not reset", name)
+	}
+}
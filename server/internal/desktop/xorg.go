@@ -27,6 +27,17 @@ func (manager *DesktopManagerCtx) ButtonDown(code uint32) error {
 }
 
 func (manager *DesktopManagerCtx) KeyDown(code uint32) error {
+	if manager.shortcuts != nil {
+		name, inject := manager.shortcuts.keyDown(code)
+		if name != "" {
+			manager.logger.Debug().Str("shortcut", name).Msg("shortcut recognized")
+			manager.emmiter.Emit("shortcut", name)
+		}
+		if !inject {
+			return nil
+		}
+	}
+
 	return xorg.KeyDown(code)
 }
 
@@ -35,6 +46,10 @@ func (manager *DesktopManagerCtx) ButtonUp(code uint32) error {
 }
 
 func (manager *DesktopManagerCtx) KeyUp(code uint32) error {
+	if manager.shortcuts != nil && !manager.shortcuts.keyUp(code) {
+		return nil
+	}
+
 	return xorg.KeyUp(code)
 }
 
@@ -63,6 +78,10 @@ func (manager *DesktopManagerCtx) KeyPress(codes ...uint32) error {
 }
 
 func (manager *DesktopManagerCtx) ResetKeys() {
+	if manager.shortcuts != nil {
+		manager.shortcuts.reset()
+	}
+
 	xorg.ResetKeys()
 }
 
@@ -109,8 +128,12 @@ func (manager *DesktopManagerCtx) GetScreenSize() types.ScreenSize {
 
 func (manager *DesktopManagerCtx) SetKeyboardMap(kbd types.KeyboardMap) error {
 	// TOOD: Use native API.
-	cmd := exec.Command("setxkbmap", "-layout", kbd.Layout, "-variant", kbd.Variant)
-	_, err := cmd.Output()
+	cmd, err := manager.command("keyboard", []string{"setxkbmap", "-layout", kbd.Layout, "-variant", kbd.Variant})
+	if err != nil {
+		return err
+	}
+
+	_, err = cmd.Output()
 	return err
 }
 
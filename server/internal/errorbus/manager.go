@@ -0,0 +1,94 @@
+package errorbus
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kataras/go-events"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// same errors are emitted to listeners at most once per interval,
+// occurrences in between are only counted
+const emitInterval = time.Second
+
+type entry struct {
+	count     int
+	emittedAt time.Time
+}
+
+type ErrorBusCtx struct {
+	emmiter events.EventEmmiter
+
+	entries   map[string]*entry
+	entriesMu sync.Mutex
+
+	errorsCounter *prometheus.CounterVec
+}
+
+func New() *ErrorBusCtx {
+	return &ErrorBusCtx{
+		emmiter: events.New(),
+		entries: map[string]*entry{},
+
+		errorsCounter: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name:      "errors_total",
+			Namespace: "neko",
+			Subsystem: "errorbus",
+			Help:      "Total number of asynchronous errors reported by subsystems.",
+		}, []string{"subsystem", "kind"}),
+	}
+}
+
+// Report records an error of given subsystem and kind, session is optional.
+func (bus *ErrorBusCtx) Report(subsystem, kind string, session types.Session, err error) {
+	if err == nil {
+		return
+	}
+
+	bus.errorsCounter.WithLabelValues(subsystem, kind).Inc()
+
+	key := subsystem + "/" + kind
+	now := time.Now()
+
+	bus.entriesMu.Lock()
+	e, ok := bus.entries[key]
+	if !ok {
+		e = &entry{}
+		bus.entries[key] = e
+	}
+
+	e.count++
+	if now.Sub(e.emittedAt) < emitInterval {
+		bus.entriesMu.Unlock()
+		return
+	}
+
+	count := e.count
+	e.count = 0
+	e.emittedAt = now
+	bus.entriesMu.Unlock()
+
+	payload := types.SubsystemError{
+		Subsystem: subsystem,
+		Kind:      kind,
+		Message:   err.Error(),
+		Count:     count,
+		Time:      now,
+	}
+
+	if session != nil {
+		payload.SessionId = session.ID()
+	}
+
+	bus.emmiter.Emit("error", payload)
+}
+
+func (bus *ErrorBusCtx) OnError(listener func(err types.SubsystemError)) {
+	bus.emmiter.On("error", func(payload ...any) {
+		listener(payload[0].(types.SubsystemError))
+	})
+}
@@ -0,0 +1,134 @@
+package errorbus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kataras/go-events"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// metrics of New can be registered only once
+func newTestBus() *ErrorBusCtx {
+	return &ErrorBusCtx{
+		emmiter: events.New(),
+		entries: map[string]*entry{},
+
+		errorsCounter: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "errors_total",
+		}, []string{"subsystem", "kind"}),
+	}
+}
+
+type testSession struct {
+	types.Session
+}
+
+func (testSession) ID() string { return "session" }
+
+func TestReport(t *testing.T) {
+	bus := newTestBus()
+
+	var received []types.SubsystemError
+	bus.OnError(func(err types.SubsystemError) {
+		received = append(received, err)
+	})
+
+	bus.Report("webrtc", "cursor_image", testSession{}, errors.New("failed"))
+
+	if len(received) != 1 {
+		t.Fatalf("received %d errors, want 1", len(received))
+	}
+
+	got := received[0]
+	if got.Subsystem != "webrtc" || got.Kind != "cursor_image" || got.SessionId != "session" ||
+		got.Message != "failed" || got.Count != 1 || got.Time.IsZero() {
+		t.Errorf("received %+v", got)
+	}
+
+	if v := testutil.ToFloat64(bus.errorsCounter.WithLabelValues("webrtc", "cursor_image")); v != 1 {
+		t.Errorf("errors counter = %v, want 1", v)
+	}
+}
+
+func TestReportNil(t *testing.T) {
+	bus := newTestBus()
+
+	bus.OnError(func(err types.SubsystemError) {
+		t.Errorf("received %+v for nil error", err)
+	})
+
+	bus.Report("webrtc", "cursor_image", nil, nil)
+
+	if n := testutil.CollectAndCount(bus.errorsCounter); n != 0 {
+		t.Errorf("errors counter has %d series, want none", n)
+	}
+}
+
+func TestReportWithoutSession(t *testing.T) {
+	bus := newTestBus()
+
+	var received []types.SubsystemError
+	bus.OnError(func(err types.SubsystemError) {
+		received = append(received, err)
+	})
+
+	bus.Report("websocket", "clipboard", nil, errors.New("failed"))
+
+	if len(received) != 1 || received[0].SessionId != "" {
+		t.Errorf("received %+v", received)
+	}
+}
+
+func TestReportThrottled(t *testing.T) {
+	bus := newTestBus()
+
+	var received []types.SubsystemError
+	bus.OnError(func(err types.SubsystemError) {
+		received = append(received, err)
+	})
+
+	// repeated errors within interval are only counted
+	for i := 0; i < 3; i++ {
+		bus.Report("webrtc", "rtcp", nil, errors.New("failed"))
+	}
+
+	// other kinds are not throttled by them
+	bus.Report("webrtc", "cursor_image", nil, errors.New("failed"))
+
+	if len(received) != 2 {
+		t.Fatalf("received %d errors, want 2", len(received))
+	}
+	if v := testutil.ToFloat64(bus.errorsCounter.WithLabelValues("webrtc", "rtcp")); v != 3 {
+		t.Errorf("errors counter = %v, want every error counted", v)
+	}
+
+	// once interval passed, error is emitted with count of occurrences since
+	bus.entries["webrtc/rtcp"].emittedAt = time.Now().Add(-emitInterval)
+	bus.Report("webrtc", "rtcp", nil, errors.New("failed again"))
+
+	if len(received) != 3 {
+		t.Fatalf("received %d errors, want 3", len(received))
+	}
+	if got := received[2]; got.Count != 3 || got.Message != "failed again" {
+		t.Errorf("received %+v, want count of 3", got)
+	}
+}
+
+func TestOnErrorMultipleListeners(t *testing.T) {
+	bus := newTestBus()
+
+	first, second := 0, 0
+	bus.OnError(func(types.SubsystemError) { first++ })
+	bus.OnError(func(types.SubsystemError) { second++ })
+
+	bus.Report("webrtc", "rtcp", nil, errors.New("failed"))
+
+	if first != 1 || second != 1 {
+		t.Errorf("listeners received %d and %d errors, want 1 each", first, second)
+	}
+}
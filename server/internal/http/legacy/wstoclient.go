@@ -512,6 +512,11 @@ func (s *session) wsToClient(msg []byte) error {
 			return err
 		}
 
+		// legacy clients support only text
+		if request.Image != nil {
+			return nil
+		}
+
 		return s.toClient(&oldMessage.Clipboard{
 			Event: oldEvent.CONTROL_CLIPBOARD,
 			Text:  request.Text,
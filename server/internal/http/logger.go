@@ -17,8 +17,8 @@ type logFormatter struct {
 }
 
 func (l *logFormatter) NewLogEntry(r *http.Request) middleware.LogEntry {
-	// exclude health & metrics from logs
-	if r.RequestURI == "/health" || r.RequestURI == "/metrics" {
+	// exclude health, readiness & metrics from logs
+	if r.RequestURI == "/health" || r.RequestURI == "/ready" || r.RequestURI == "/metrics" {
 		return &nulllog{}
 	}
 
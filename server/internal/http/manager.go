@@ -72,6 +72,18 @@ func New(WebSocketManager types.WebSocketManager, ApiManager types.ApiManager, c
 		return err
 	})
 
+	// ready once connections get complete state of the desktop
+	router.Get("/ready", func(w http.ResponseWriter, r *http.Request) error {
+		if !WebSocketManager.Ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, err := w.Write([]byte("false"))
+			return err
+		}
+
+		_, err := w.Write([]byte("true"))
+		return err
+	})
+
 	if config.Metrics {
 		router.Get("/metrics", func(w http.ResponseWriter, r *http.Request) error {
 			promhttp.Handler().ServeHTTP(w, r)
@@ -99,14 +111,25 @@ func New(WebSocketManager types.WebSocketManager, ApiManager types.ApiManager, c
 		pprofHandler(router)
 	}
 
+	// long-lived streaming requests are cancelled on shutdown, otherwise
+	// graceful shutdown would wait for them forever
+	ctx, cancel := context.WithCancel(context.Background())
+
+	server := &http.Server{
+		Addr:              config.Bind,
+		Handler:           router,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+	server.RegisterOnShutdown(cancel)
+
 	return &HttpManagerCtx{
 		logger: logger,
 		config: config,
 		router: router,
-		http: &http.Server{
-			Addr:    config.Bind,
-			Handler: router,
-		},
+		http:   server,
 	}
 }
 
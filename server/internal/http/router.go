@@ -56,9 +56,11 @@ func WithPathPrefix(prefix string) RouterOption {
 	}
 }
 
+// WithRealIP sets RemoteAddr from forwarded headers, address of the proxy is
+// still available as utils.PeerAddr.
 func WithRealIP() RouterOption {
 	return func(r *router) {
-		r.chi.Use(middleware.RealIP)
+		r.chi.Use(utils.WithPeerAddr, middleware.RealIP)
 	}
 }
 
@@ -0,0 +1,26 @@
+package input
+
+import (
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// session scratch store key
+const activityKey = "input/activity"
+
+// touch marks that the session sent input to the desktop.
+func touch(session types.Session) {
+	session.SetValue(activityKey, time.Now())
+}
+
+// LastInput returns when the session last sent input to the desktop, false if
+// it did not send any since it connected.
+func LastInput(session types.Session) (time.Time, bool) {
+	value, ok := session.Value(activityKey)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return value.(time.Time), true
+}
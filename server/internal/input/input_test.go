@@ -0,0 +1,98 @@
+package input
+
+import (
+	"testing"
+	"time"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/internal/session"
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+func newSession(t *testing.T) types.Session {
+	manager := session.New(&config.Session{})
+	s, _, err := manager.Create("test", types.MemberProfile{
+		CanLogin: true,
+		CanHost:  true,
+	})
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+	return s
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	s := newSession(t)
+
+	// not recorded yet
+	Record(s, types.InputEvent{Type: types.InputMove, X: 1, Y: 1})
+
+	StartRecording(s)
+	Record(s, types.InputEvent{Type: types.InputMove, X: 10, Y: 20})
+	time.Sleep(20 * time.Millisecond)
+	Record(s, types.InputEvent{Type: types.InputKeyDown, Code: 65})
+
+	recording, err := StopRecording(s)
+	if err != nil {
+		t.Fatalf("could not stop recording %s", err.Error())
+	}
+
+	if len(recording.Events) != 2 {
+		t.Fatalf("got %d events, expected 2", len(recording.Events))
+	}
+
+	if delay := recording.Events[1].Delay; delay < 20 {
+		t.Errorf("delay is %dms, expected at least 20ms", delay)
+	}
+
+	if _, err := StopRecording(s); err != ErrNotRecording {
+		t.Errorf("recording was not stopped")
+	}
+
+	apply := func(types.InputEvent) error { return nil }
+	if err := Replay(s, recording, apply, func(error) {}); err != ErrNotHost {
+		t.Fatalf("replay without host is allowed")
+	}
+
+	s.SetAsHost()
+
+	var applied []types.InputEvent
+	done := make(chan error, 1)
+	apply = func(event types.InputEvent) error {
+		applied = append(applied, event)
+		return nil
+	}
+
+	if err := Replay(s, recording, apply, func(err error) { done <- err }); err != nil {
+		t.Fatalf("could not replay %s", err.Error())
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("replay failed %s", err.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("replay did not finish")
+	}
+
+	if len(applied) != 2 || applied[1].Code != 65 {
+		t.Errorf("unexpected applied events %v", applied)
+	}
+}
+
+func TestLastInput(t *testing.T) {
+	s := newSession(t)
+
+	if _, ok := LastInput(s); ok {
+		t.Error("LastInput() reported input before any was sent")
+	}
+
+	before := time.Now()
+	Record(s, types.InputEvent{Type: types.InputMove, X: 1, Y: 1})
+
+	last, ok := LastInput(s)
+	if !ok || last.Before(before) {
+		t.Errorf("LastInput() = %v, %v, want time after %v", last, ok, before)
+	}
+}
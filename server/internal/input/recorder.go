@@ -0,0 +1,89 @@
+package input
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// recordings longer than this are truncated, so that a forgotten
+// recording does not grow indefinitely
+const maxRecordedEvents = 100_000
+
+// session scratch store key
+const recorderKey = "input/recorder"
+
+var ErrNotRecording = errors.New("input is not being recorded")
+
+type recorder struct {
+	mu        sync.Mutex
+	recording types.InputRecording
+	last      time.Time
+}
+
+// StartRecording starts recording input of the session, previous
+// recording is discarded.
+func StartRecording(session types.Session) {
+	now := time.Now()
+	session.SetValue(recorderKey, &recorder{
+		recording: types.InputRecording{
+			StartedAt: now,
+			Events:    []types.InputEvent{},
+		},
+		last: now,
+	})
+}
+
+// StopRecording stops recording input of the session and returns the recording.
+func StopRecording(session types.Session) (*types.InputRecording, error) {
+	recording, err := Recording(session)
+	if err != nil {
+		return nil, err
+	}
+
+	session.DeleteValue(recorderKey)
+	return recording, nil
+}
+
+// Recording returns copy of the current recording of the session.
+func Recording(session types.Session) (*types.InputRecording, error) {
+	value, ok := session.Value(recorderKey)
+	if !ok {
+		return nil, ErrNotRecording
+	}
+
+	r := value.(*recorder)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recording := r.recording
+	recording.Events = append([]types.InputEvent{}, r.recording.Events...)
+	return &recording, nil
+}
+
+// Record adds input event to the recording of the session, if it is being
+// recorded. It also marks the session as active.
+func Record(session types.Session, event types.InputEvent) {
+	touch(session)
+
+	value, ok := session.Value(recorderKey)
+	if !ok {
+		return
+	}
+
+	r := value.(*recorder)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.recording.Events) >= maxRecordedEvents {
+		return
+	}
+
+	// advance by the rounded delay, so that rounding errors do not accumulate
+	event.Delay = time.Since(r.last).Milliseconds()
+	r.last = r.last.Add(time.Duration(event.Delay) * time.Millisecond)
+
+	r.recording.Events = append(r.recording.Events, event)
+}
@@ -0,0 +1,109 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// session scratch store key
+const replayKey = "input/replay"
+
+var ErrNotHost = errors.New("session is not the host")
+
+// Apply feeds input event to the desktop.
+func Apply(desktop types.DesktopManager, event types.InputEvent) error {
+	switch event.Type {
+	case types.InputMove:
+		desktop.Move(event.X, event.Y)
+	case types.InputScroll:
+		desktop.Scroll(event.DeltaX, event.DeltaY, event.ControlKey)
+	case types.InputButtonDown:
+		return desktop.ButtonDown(event.Code)
+	case types.InputButtonUp:
+		return desktop.ButtonUp(event.Code)
+	case types.InputButtonPress:
+		return desktop.ButtonPress(event.Code)
+	case types.InputKeyDown:
+		return desktop.KeyDown(event.Code)
+	case types.InputKeyUp:
+		return desktop.KeyUp(event.Code)
+	case types.InputKeyPress:
+		return desktop.KeyPress(event.Code)
+	case types.InputTouchBegin:
+		return desktop.TouchBegin(event.TouchId, event.X, event.Y, event.Pressure)
+	case types.InputTouchUpdate:
+		return desktop.TouchUpdate(event.TouchId, event.X, event.Y, event.Pressure)
+	case types.InputTouchEnd:
+		return desktop.TouchEnd(event.TouchId, event.X, event.Y, event.Pressure)
+	default:
+		return errors.New("unknown input event type")
+	}
+	return nil
+}
+
+// Replay feeds recorded events through apply at their original timing in
+// background, replacing any replay already running for the session. Replay
+// stops when the session stops being the host, so that it is not possible
+// to control desktop without permission.
+func Replay(session types.Session, recording *types.InputRecording, apply func(types.InputEvent) error, done func(err error)) error {
+	if !session.IsHost() {
+		return ErrNotHost
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session.UpdateValue(replayKey, func(value any) any {
+		if value != nil {
+			value.(context.CancelFunc)()
+		}
+		return context.CancelFunc(cancel)
+	})
+
+	go func() {
+		defer cancel()
+		done(replay(ctx, session, recording.Events, apply))
+	}()
+
+	return nil
+}
+
+// StopReplay stops replay running for the session.
+func StopReplay(session types.Session) {
+	session.UpdateValue(replayKey, func(value any) any {
+		if value != nil {
+			value.(context.CancelFunc)()
+		}
+		return nil
+	})
+}
+
+func replay(ctx context.Context, session types.Session, events []types.InputEvent, apply func(types.InputEvent) error) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	for _, event := range events {
+		if event.Delay > 0 {
+			timer.Reset(time.Duration(event.Delay) * time.Millisecond)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-timer.C:
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !session.IsHost() {
+			return ErrNotHost
+		}
+
+		if err := apply(event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
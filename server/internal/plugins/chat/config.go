@@ -1,12 +1,15 @@
 package chat
 
 import (
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
 	Enabled bool
+	// max messages retained in memory and sent to newly connected sessions
+	History int
 }
 
 func (Config) Init(cmd *cobra.Command) error {
@@ -15,9 +18,20 @@ func (Config) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Int("chat.history", 0, "how many last chat messages to keep in memory and send to newly connected sessions, 0 disables history")
+	if err := viper.BindPFlag("chat.history", cmd.PersistentFlags().Lookup("chat.history")); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func (s *Config) Set() {
 	s.Enabled = viper.GetBool("chat.enabled")
+
+	s.History = viper.GetInt("chat.history")
+	if s.History < 0 {
+		log.Warn().Int("history", s.History).Msg("invalid chat history size, disabling history")
+		s.History = 0
+	}
 }
@@ -0,0 +1,49 @@
+package chat
+
+import "sync"
+
+// history is a ring buffer of last chat messages, oldest are evicted once
+// it is full so that memory stays bounded in long-lived rooms.
+type history struct {
+	mu    sync.Mutex
+	buf   []Message
+	start int
+	size  int
+}
+
+func newHistory(max int) *history {
+	return &history{
+		buf: make([]Message, max),
+	}
+}
+
+func (h *history) add(msg Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.buf) == 0 {
+		return
+	}
+
+	if h.size < len(h.buf) {
+		h.buf[(h.start+h.size)%len(h.buf)] = msg
+		h.size++
+		return
+	}
+
+	// overwrite oldest message
+	h.buf[h.start] = msg
+	h.start = (h.start + 1) % len(h.buf)
+}
+
+// list returns copy of retained messages, oldest first.
+func (h *history) list() []Message {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	messages := make([]Message, h.size)
+	for i := 0; i < h.size; i++ {
+		messages[i] = h.buf[(h.start+i)%len(h.buf)]
+	}
+	return messages
+}
@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestHistoryEvictsOldest(t *testing.T) {
+	h := newHistory(3)
+
+	for i := 0; i < 5; i++ {
+		h.add(Message{ID: strconv.Itoa(i)})
+	}
+
+	messages := h.list()
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(messages))
+	}
+
+	for i, msg := range messages {
+		if want := strconv.Itoa(i + 2); msg.ID != want {
+			t.Errorf("message %d: expected id %s, got %s", i, want, msg.ID)
+		}
+	}
+}
+
+func TestHistoryBounded(t *testing.T) {
+	h := newHistory(50)
+
+	for i := 0; i < 100_000; i++ {
+		h.add(Message{ID: strconv.Itoa(i), Content: Content{Text: "hello"}})
+	}
+
+	if len(h.list()) != 50 {
+		t.Fatalf("expected 50 messages, got %d", len(h.list()))
+	}
+
+	// buffer must never grow past its initial capacity
+	if cap(h.buf) != 50 {
+		t.Fatalf("expected buffer capacity 50, got %d", cap(h.buf))
+	}
+
+	if last := h.list()[49].ID; last != "99999" {
+		t.Fatalf("expected last message 99999, got %s", last)
+	}
+}
+
+func TestHistoryDisabled(t *testing.T) {
+	h := newHistory(0)
+	h.add(Message{ID: "0"})
+
+	if len(h.list()) != 0 {
+		t.Fatalf("expected empty history, got %d messages", len(h.list()))
+	}
+}
@@ -25,6 +25,7 @@ func NewManager(
 		logger:   logger,
 		config:   config,
 		sessions: sessions,
+		history:  newHistory(config.History),
 	}
 }
 
@@ -32,6 +33,7 @@ type Manager struct {
 	logger   zerolog.Logger
 	config   *Config
 	sessions types.SessionManager
+	history  *history
 }
 
 type Settings struct {
@@ -66,7 +68,13 @@ func (m *Manager) settingsForSession(session types.Session) (Settings, error) {
 }
 
 func (m *Manager) sendMessage(session types.Session, content Content) {
-	now := time.Now()
+	msg := Message{
+		ID:      session.ID(),
+		Created: time.Now(),
+		Content: content,
+	}
+
+	m.history.add(msg)
 
 	// get all sessions that have chat enabled
 	var sessions []types.Session
@@ -80,20 +88,23 @@ func (m *Manager) sendMessage(session types.Session, content Content) {
 
 	// send content to all sessions
 	for _, s := range sessions {
-		s.Send(CHAT_MESSAGE, Message{
-			ID:      session.ID(),
-			Created: now,
-			Content: content,
-		})
+		s.Send(CHAT_MESSAGE, msg)
 	}
 }
 
 func (m *Manager) Start() error {
 	// send init message once a user connects
 	m.sessions.OnConnected(func(session types.Session) {
-		session.Send(CHAT_INIT, Init{
+		init := Init{
 			Enabled: m.config.Enabled,
-		})
+		}
+
+		// only sessions allowed to receive messages get the history
+		if settings, err := m.settingsForSession(session); err == nil && settings.CanReceive {
+			init.History = m.history.list()
+		}
+
+		session.Send(CHAT_INIT, init)
 	})
 
 	return nil
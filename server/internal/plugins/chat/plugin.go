@@ -26,7 +26,9 @@ func (p *Plugin) Config() types.PluginConfig {
 func (p *Plugin) Start(m types.PluginManagers) error {
 	p.manager = NewManager(m.SessionManager, p.config)
 	m.ApiManager.AddRouter("/chat", p.manager.Route)
-	m.WebSocketManager.AddHandler(p.manager.WebSocketHandler)
+	if err := m.WebSocketManager.AddNamedHandler("chat", p.manager.WebSocketHandler); err != nil {
+		return err
+	}
 	return p.manager.Start()
 }
 
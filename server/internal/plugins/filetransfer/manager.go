@@ -63,7 +63,8 @@ func (m *Manager) isEnabledForSession(session types.Session) (bool, error) {
 		return false, fmt.Errorf("unable to unmarshal %s plugin settings from profile: %w", PluginName, err)
 	}
 
-	return m.config.Enabled && (settings.Enabled || session.Profile().IsAdmin) && profile.Enabled, nil
+	return m.config.Enabled && (settings.Enabled || session.Profile().IsAdmin) && profile.Enabled &&
+		!session.FeatureDisabled(types.FeatureFileTransfer), nil
 }
 
 func (m *Manager) refresh() (error, bool) {
@@ -116,11 +116,13 @@ func (manager *ManagerCtx) SetConfigs() {
 func (manager *ManagerCtx) Start(
 	sessionManager types.SessionManager,
 	webSocketManager types.WebSocketManager,
+	webRTCManager types.WebRTCManager,
 	apiManager types.ApiManager,
 ) {
 	err := manager.plugins.start(types.PluginManagers{
 		SessionManager:        sessionManager,
 		WebSocketManager:      webSocketManager,
+		WebRTCManager:         webRTCManager,
 		ApiManager:            apiManager,
 		LoadServiceFromPlugin: manager.LookupService,
 	})
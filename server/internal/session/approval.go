@@ -0,0 +1,76 @@
+package session
+
+import (
+	"maps"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// pendingProfile restricts profile of a session waiting for approval,
+// so that it can watch but cannot control, share media or chat.
+func pendingProfile(profile types.MemberProfile) types.MemberProfile {
+	profile.CanHost = false
+	profile.CanShareMedia = false
+	profile.CanAccessClipboard = false
+	profile.SendsInactiveCursor = false
+
+	plugins := types.PluginSettings{}
+	maps.Copy(plugins, profile.Plugins)
+	plugins["chat.can_send"] = false
+	profile.Plugins = plugins
+
+	return profile
+}
+
+func (manager *SessionManagerCtx) pendingSession(id string) (*SessionCtx, error) {
+	manager.sessionsMu.Lock()
+	session, ok := manager.sessions[id]
+	manager.sessionsMu.Unlock()
+
+	if !ok {
+		return nil, types.ErrSessionNotFound
+	}
+
+	if !session.state.IsPending {
+		return nil, types.ErrSessionNotPending
+	}
+
+	return session, nil
+}
+
+// Approve lifts restrictions of a session waiting for approval and
+// announces its full profile.
+func (manager *SessionManagerCtx) Approve(id string) error {
+	session, err := manager.pendingSession(id)
+	if err != nil {
+		return err
+	}
+
+	old := session.Profile()
+	session.approved = true
+	session.state.IsPending = false
+
+	session.logger.Info().Msg("session approved")
+
+	manager.emmiter.Emit("state_changed", session)
+	manager.emmiter.Emit("profile_changed", session, session.Profile(), old)
+	return nil
+}
+
+// Deny disconnects a session waiting for approval.
+func (manager *SessionManagerCtx) Deny(id string) error {
+	session, err := manager.pendingSession(id)
+	if err != nil {
+		return err
+	}
+
+	session.logger.Info().Msg("session denied")
+
+	session.DestroyWebSocketPeer("join request denied")
+
+	if session.State().IsWatching {
+		session.GetWebRTCPeer().Destroy()
+	}
+
+	return nil
+}
@@ -41,6 +41,18 @@ func (manager *SessionManagerCtx) CookieClearToken(w http.ResponseWriter, r *htt
 func (manager *SessionManagerCtx) Authenticate(r *http.Request) (types.Session, error) {
 	token, ok := manager.getToken(r)
 	if !ok {
+		// guest session from one-time invite in connect URL
+		if invite := r.URL.Query().Get("invite"); invite != "" {
+			session, _, err := manager.RedeemInvite(invite)
+			return session, err
+		}
+
+		// session moved from another server
+		if handoff := r.URL.Query().Get("handoff"); handoff != "" {
+			session, _, err := manager.RedeemHandoff(handoff)
+			return session, err
+		}
+
 		return nil, errors.New("no authentication provided")
 	}
 
@@ -53,6 +65,11 @@ func (manager *SessionManagerCtx) Authenticate(r *http.Request) (types.Session,
 		return nil, types.ErrSessionLoginDisabled
 	}
 
+	// claims could have changed since login
+	if err := manager.ApplyClaims(session, r); err != nil {
+		manager.logger.Err(err).Str("session_id", session.ID()).Msg("unable to apply claims")
+	}
+
 	return session, nil
 }
 
@@ -0,0 +1,140 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// how many changes are kept in memory, older changes are dropped
+const CHANGES_BUFFER_SIZE = 128
+
+func (manager *SessionManagerCtx) changesEnabled() bool {
+	return manager.config.RejoinChanges
+}
+
+func (manager *SessionManagerCtx) recordChange(kind types.SessionChangeKind, sessionId, message string) {
+	if !manager.changesEnabled() {
+		return
+	}
+
+	manager.changesMu.Lock()
+	defer manager.changesMu.Unlock()
+
+	manager.changes = append(manager.changes, types.SessionChange{
+		Time:      time.Now(),
+		Kind:      kind,
+		SessionId: sessionId,
+		Message:   message,
+	})
+
+	if len(manager.changes) > CHANGES_BUFFER_SIZE {
+		manager.changes = manager.changes[len(manager.changes)-CHANGES_BUFFER_SIZE:]
+	}
+}
+
+// changesSince returns changes recorded after given time, excluding joins and
+// leaves of given session, at most configured limit of the most recent ones.
+func (manager *SessionManagerCtx) changesSince(since time.Time, sessionId string) []types.SessionChange {
+	manager.changesMu.Lock()
+	defer manager.changesMu.Unlock()
+
+	changes := []types.SessionChange{}
+	for _, change := range manager.changes {
+		if !change.Time.After(since) {
+			continue
+		}
+
+		if change.SessionId == sessionId && (change.Kind == types.SessionChangeJoined || change.Kind == types.SessionChangeLeft) {
+			continue
+		}
+
+		changes = append(changes, change)
+	}
+
+	if limit := manager.config.RejoinChangesLimit; limit > 0 && len(changes) > limit {
+		changes = changes[len(changes)-limit:]
+	}
+
+	return changes
+}
+
+func sessionName(session types.Session) string {
+	if name := session.Profile().Name; name != "" {
+		return name
+	}
+	return session.ID()
+}
+
+func (manager *SessionManagerCtx) recordHostChange(session, host types.Session) {
+	if !manager.changesEnabled() {
+		return
+	}
+
+	if host == nil {
+		manager.recordChange(types.SessionChangeHost, session.ID(), fmt.Sprintf("%s released control", sessionName(session)))
+		return
+	}
+
+	if session == nil || session.ID() == host.ID() {
+		manager.recordChange(types.SessionChangeHost, host.ID(), fmt.Sprintf("%s took control", sessionName(host)))
+		return
+	}
+
+	manager.recordChange(types.SessionChangeHost, host.ID(), fmt.Sprintf("%s gave control to %s", sessionName(session), sessionName(host)))
+}
+
+func (manager *SessionManagerCtx) recordSettingsChange(session types.Session, new, old types.Settings) {
+	if !manager.changesEnabled() {
+		return
+	}
+
+	var changed []string
+	toggle := func(name string, new, old bool) {
+		if new == old {
+			return
+		}
+		if new {
+			changed = append(changed, name+" enabled")
+		} else {
+			changed = append(changed, name+" disabled")
+		}
+	}
+
+	toggle("private mode", new.PrivateMode, old.PrivateMode)
+	toggle("locked logins", new.LockedLogins, old.LockedLogins)
+	toggle("locked controls", new.LockedControls, old.LockedControls)
+	toggle("control protection", new.ControlProtection, old.ControlProtection)
+	toggle("implicit hosting", new.ImplicitHosting, old.ImplicitHosting)
+	toggle("inactive cursors", new.InactiveCursors, old.InactiveCursors)
+	toggle("host cursor", new.HostCursor, old.HostCursor)
+	toggle("merciful reconnect", new.MercifulReconnect, old.MercifulReconnect)
+	toggle("join approval", new.JoinApproval, old.JoinApproval)
+
+	if new.HostTakeoverGrace != old.HostTakeoverGrace {
+		changed = append(changed, fmt.Sprintf("host takeover grace set to %ds", new.HostTakeoverGrace))
+	}
+
+	if new.HeartbeatInterval != old.HeartbeatInterval {
+		changed = append(changed, fmt.Sprintf("heartbeat interval set to %ds", new.HeartbeatInterval))
+	}
+
+	// plugin settings are not described in detail
+	if len(changed) == 0 {
+		changed = append(changed, "plugin settings changed")
+	}
+
+	by := "server"
+	if session != nil {
+		by = sessionName(session)
+	}
+
+	var sessionId string
+	if session != nil {
+		sessionId = session.ID()
+	}
+
+	manager.recordChange(types.SessionChangeSettings, sessionId, fmt.Sprintf("%s changed settings: %s", by, strings.Join(changed, ", ")))
+}
@@ -0,0 +1,88 @@
+package session
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// profile permissions that can be granted by claims
+var claimPermissions = map[string]func(profile *types.MemberProfile) *bool{
+	"is_admin":             func(profile *types.MemberProfile) *bool { return &profile.IsAdmin },
+	"can_host":             func(profile *types.MemberProfile) *bool { return &profile.CanHost },
+	"can_share_media":      func(profile *types.MemberProfile) *bool { return &profile.CanShareMedia },
+	"can_access_clipboard": func(profile *types.MemberProfile) *bool { return &profile.CanAccessClipboard },
+}
+
+func (manager *SessionManagerCtx) claimsEnabled() bool {
+	return manager.config.ClaimsHeader != "" && len(manager.config.ClaimsMapping) > 0
+}
+
+func (manager *SessionManagerCtx) validateClaimsMapping() {
+	for claim, permissions := range manager.config.ClaimsMapping {
+		for _, permission := range permissions {
+			if _, ok := claimPermissions[permission]; !ok {
+				manager.logger.Warn().
+					Str("claim", claim).
+					Str("permission", permission).
+					Msg("unknown permission in claims mapping, it will be ignored")
+			}
+		}
+	}
+}
+
+// ApplyClaims updates session profile according to claims provided in request
+// header by trusted authentication proxy. Permissions that are present in the
+// mapping are granted only if session has at least one claim mapped to them,
+// other permissions are left untouched.
+func (manager *SessionManagerCtx) ApplyClaims(session types.Session, r *http.Request) error {
+	if !manager.claimsEnabled() {
+		return nil
+	}
+
+	// API session is not managed by claims
+	if manager.apiSession != nil && session.ID() == manager.apiSession.id {
+		return nil
+	}
+
+	claims := map[string]struct{}{}
+	for _, claim := range strings.Split(r.Header.Get(manager.config.ClaimsHeader), ",") {
+		if claim = strings.TrimSpace(claim); claim != "" {
+			claims[claim] = struct{}{}
+		}
+	}
+
+	granted := map[string]bool{}
+	for claim, permissions := range manager.config.ClaimsMapping {
+		_, hasClaim := claims[claim]
+		for _, permission := range permissions {
+			granted[permission] = granted[permission] || hasClaim
+		}
+	}
+
+	profile := session.Profile()
+	changed := false
+	for permission, value := range granted {
+		field, ok := claimPermissions[permission]
+		if !ok {
+			continue
+		}
+
+		if ptr := field(&profile); *ptr != value {
+			*ptr = value
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	manager.logger.Info().
+		Str("session_id", session.ID()).
+		Interface("granted", granted).
+		Msg("profile updated according to claims")
+
+	return manager.Update(session.ID(), profile)
+}
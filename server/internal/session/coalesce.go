@@ -0,0 +1,104 @@
+package session
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// coalescedEvent holds payloads of one event sent within the window, they are
+// serialized when queued, so that callers can reuse them once Send returns.
+type coalescedEvent struct {
+	payloads []any
+	// index of the latest payload of each session, for summarized events
+	latest map[string]int
+}
+
+// summaryKey returns session the payload describes, if only the latest
+// payload of each session is relevant.
+func summaryKey(payload any) (string, bool) {
+	switch p := payload.(type) {
+	case message.SessionState:
+		return p.ID, true
+	case message.MemberProfile:
+		return p.ID, true
+	}
+	return "", false
+}
+
+func (e *coalescedEvent) add(payload any) error {
+	key, ok := summaryKey(payload)
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	payload = json.RawMessage(data)
+
+	if !ok {
+		e.payloads = append(e.payloads, payload)
+		return nil
+	}
+
+	if i, ok := e.latest[key]; ok {
+		e.payloads[i] = payload
+		return nil
+	}
+
+	if e.latest == nil {
+		e.latest = map[string]int{}
+	}
+	e.latest[key] = len(e.payloads)
+	e.payloads = append(e.payloads, payload)
+	return nil
+}
+
+// coalesce queues payload, the first one starts the window.
+func (session *SessionCtx) coalesce(event string, payload any, window time.Duration) {
+	session.coalescedMu.Lock()
+	defer session.coalescedMu.Unlock()
+
+	if session.coalesced == nil {
+		session.coalesced = map[string]*coalescedEvent{}
+	}
+
+	pending, ok := session.coalesced[event]
+	if !ok {
+		pending = &coalescedEvent{}
+		session.coalesced[event] = pending
+
+		time.AfterFunc(window, func() {
+			session.flushCoalesced(event)
+		})
+	}
+
+	if err := pending.add(payload); err != nil {
+		session.logger.Err(err).Str("event", event).Msg("could not serialize coalesced payload")
+	}
+}
+
+// flushCoalesced sends a single payload as it is, more of them as a batch.
+func (session *SessionCtx) flushCoalesced(name string) {
+	session.coalescedMu.Lock()
+	pending, ok := session.coalesced[name]
+	delete(session.coalesced, name)
+	session.coalescedMu.Unlock()
+
+	if !ok || len(pending.payloads) == 0 {
+		return
+	}
+
+	if len(pending.payloads) == 1 {
+		session.send(name, pending.payloads[0])
+		return
+	}
+
+	session.send(
+		event.SYSTEM_BATCH,
+		message.SystemBatch{
+			Event:    name,
+			Payloads: pending.payloads,
+		})
+}
@@ -0,0 +1,134 @@
+package session
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+func TestBroadcastCoalesce(t *testing.T) {
+	manager := New(&config.Session{
+		BroadcastCoalesce: map[string]time.Duration{
+			"chat/message":      20 * time.Millisecond,
+			event.SESSION_STATE: 20 * time.Millisecond,
+		},
+	})
+
+	session, _, err := manager.Create("test", types.MemberProfile{CanLogin: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peer := &recordingWebSocketPeer{}
+	session.ConnectWebSocketPeer(peer)
+
+	peer.mu.Lock()
+	peer.events, peer.payloads = nil, nil
+	peer.mu.Unlock()
+
+	// state of the same session is summarized, other sessions are kept
+	session.Send(event.SESSION_STATE, message.SessionState{ID: "a"})
+	session.Send(event.SESSION_STATE, message.SessionState{ID: "b"})
+	session.Send(event.SESSION_STATE, message.SessionState{ID: "a", SessionState: types.SessionState{IsConnected: true}})
+	// single payload is sent as it is
+	session.Send("chat/message", "hello")
+	// not coalesced events are sent immediately
+	session.Send(event.SYSTEM_HEARTBEAT, nil)
+
+	peer.mu.Lock()
+	if !reflect.DeepEqual(peer.events, []string{event.SYSTEM_HEARTBEAT}) {
+		t.Errorf("events before window = %v", peer.events)
+	}
+	peer.mu.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	if len(peer.events) != 3 {
+		t.Fatalf("events after window = %v", peer.events)
+	}
+
+	for i, e := range peer.events {
+		switch e {
+		case "chat/message":
+			if got := coalescedJSON(t, peer.payloads[i]); got != `"hello"` {
+				t.Errorf("chat payload = %s", got)
+			}
+		case event.SYSTEM_BATCH:
+			batch := peer.payloads[i].(message.SystemBatch)
+			want := coalescedJSON(t, []any{
+				message.SessionState{ID: "a", SessionState: types.SessionState{IsConnected: true}},
+				message.SessionState{ID: "b"},
+			})
+			if got := coalescedJSON(t, batch.Payloads); batch.Event != event.SESSION_STATE || got != want {
+				t.Errorf("batch = %s, want %s", got, want)
+			}
+		}
+	}
+}
+
+func coalescedJSON(t *testing.T, payload any) string {
+	t.Helper()
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// Payload must not be referenced after Send returns, callers may reuse it
+func TestCoalesceSnapshotsPayload(t *testing.T) {
+	manager := New(&config.Session{
+		BroadcastCoalesce: map[string]time.Duration{
+			"chat/message": 10 * time.Millisecond,
+		},
+	})
+
+	session, _, err := manager.Create("test", types.MemberProfile{CanLogin: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peer := &recordingWebSocketPeer{}
+	session.ConnectWebSocketPeer(peer)
+
+	peer.mu.Lock()
+	peer.events, peer.payloads = nil, nil
+	peer.mu.Unlock()
+
+	payload := map[string]any{"text": "hello"}
+	session.Send("chat/message", payload)
+
+	// caller mutates the payload while it is queued
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			payload["text"] = "changed"
+		}
+	}()
+	wg.Wait()
+
+	time.Sleep(30 * time.Millisecond)
+
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	if len(peer.payloads) != 1 {
+		t.Fatalf("events after window = %v", peer.events)
+	}
+	if got := coalescedJSON(t, peer.payloads[0]); got != `{"text":"hello"}` {
+		t.Errorf("payload = %s, want the one at the time of Send", got)
+	}
+}
@@ -0,0 +1,122 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// consent of a session, it is given once for the lifetime of the session,
+// so that reconnecting sessions are admitted right away.
+type consent struct {
+	mu      sync.Mutex
+	given   bool
+	timeout *time.Timer
+}
+
+// consentProfile restricts profile of a session that did not acknowledge
+// the consent yet, so that it can neither watch nor control.
+func consentProfile(profile types.MemberProfile) types.MemberProfile {
+	profile = pendingProfile(profile)
+	profile.CanWatch = false
+	return profile
+}
+
+// Consent returns text that must be acknowledged before sessions are admitted,
+// its version identifying the text and how long sessions can take to do so.
+func (manager *SessionManagerCtx) Consent() (string, string, time.Duration) {
+	text := manager.config.ConsentText
+	if text == "" {
+		return "", "", 0
+	}
+
+	sum := sha256.Sum256([]byte(text))
+	return text, hex.EncodeToString(sum[:8]), manager.config.ConsentTimeout
+}
+
+// admitted tells whether session connecting now is admitted right away.
+func (session *SessionCtx) admitted() bool {
+	if session.manager.config.ConsentText == "" {
+		return true
+	}
+
+	session.consent.mu.Lock()
+	defer session.consent.mu.Unlock()
+
+	return session.consent.given
+}
+
+// waitForConsent disconnects the peer, if it does not acknowledge the consent
+// in time.
+func (session *SessionCtx) waitForConsent(websocketPeer types.WebSocketPeer) {
+	session.stopConsentTimeout()
+
+	timeout := session.manager.config.ConsentTimeout
+	if timeout <= 0 {
+		return
+	}
+
+	session.consent.mu.Lock()
+	defer session.consent.mu.Unlock()
+
+	session.consent.timeout = time.AfterFunc(timeout, func() {
+		session.websocketMu.Lock()
+		isCurrentPeer := websocketPeer == session.websocketPeer
+		session.websocketMu.Unlock()
+
+		if !isCurrentPeer || session.State().IsAdmitted {
+			return
+		}
+
+		session.logger.Info().Dur("timeout", timeout).Msg("consent was not acknowledged in time")
+		session.DestroyWebSocketPeer("consent timeout")
+	})
+}
+
+func (session *SessionCtx) stopConsentTimeout() {
+	session.consent.mu.Lock()
+	defer session.consent.mu.Unlock()
+
+	if session.consent.timeout != nil {
+		session.consent.timeout.Stop()
+		session.consent.timeout = nil
+	}
+}
+
+// AcknowledgeConsent admits a connected session that acknowledged the consent
+// and announces its full profile. Acknowledgment is logged for compliance.
+func (manager *SessionManagerCtx) AcknowledgeConsent(id string) error {
+	manager.sessionsMu.Lock()
+	session, ok := manager.sessions[id]
+	manager.sessionsMu.Unlock()
+
+	if !ok {
+		return types.ErrSessionNotFound
+	}
+
+	if !session.state.IsConnected || session.state.IsAdmitted {
+		return types.ErrSessionAdmitted
+	}
+
+	old := session.Profile()
+
+	session.consent.mu.Lock()
+	session.consent.given = true
+	session.consent.mu.Unlock()
+
+	session.stopConsentTimeout()
+	session.state.IsAdmitted = true
+
+	_, version, _ := manager.Consent()
+	session.logger.Info().
+		Str("username", session.profile.Name).
+		Str("consent_version", version).
+		Msg("consent acknowledged")
+
+	manager.emmiter.Emit("state_changed", session)
+	manager.emmiter.Emit("profile_changed", session, session.Profile(), old)
+	return nil
+}
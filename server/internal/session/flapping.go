@@ -0,0 +1,105 @@
+package session
+
+import (
+	"sync"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// reconnects tracks how often a session connects again, many reconnects within
+// a short window mean that its connection is flapping.
+type reconnects struct {
+	mu sync.Mutex
+
+	count         int
+	lastConnect   time.Time
+	lastInterval  time.Duration
+	totalInterval time.Duration
+
+	// reconnects within the window
+	recent   []time.Time
+	flapping bool
+}
+
+// connected records new connection of the session and returns true, when the
+// session started flapping. Session is reported again only after it calmed down.
+func (r *reconnects) connected(now time.Time, threshold int, window time.Duration) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last := r.lastConnect
+	r.lastConnect = now
+
+	// first connection is not a reconnect
+	if last.IsZero() {
+		return false
+	}
+
+	r.count++
+	r.lastInterval = now.Sub(last)
+	r.totalInterval += r.lastInterval
+
+	if threshold <= 0 {
+		return false
+	}
+
+	r.recent = append(r.recent, now)
+	for len(r.recent) > 0 && now.Sub(r.recent[0]) > window {
+		r.recent = r.recent[1:]
+	}
+
+	if len(r.recent) < threshold {
+		r.flapping = false
+		return false
+	}
+
+	if r.flapping {
+		return false
+	}
+
+	r.flapping = true
+	return true
+}
+
+func (r *reconnects) stats(now time.Time, window time.Duration) types.ReconnectStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := types.ReconnectStats{
+		Count:        r.count,
+		LastInterval: r.lastInterval.Milliseconds(),
+	}
+
+	if r.count == 0 {
+		return stats
+	}
+
+	lastConnect := r.lastConnect
+	stats.LastReconnectAt = &lastConnect
+	stats.AverageInterval = (r.totalInterval / time.Duration(r.count)).Milliseconds()
+
+	for _, t := range r.recent {
+		if now.Sub(t) <= window {
+			stats.RecentCount++
+		}
+	}
+
+	// stable for the whole window is no longer flapping
+	stats.IsFlapping = r.flapping && now.Sub(r.lastConnect) <= window
+	return stats
+}
+
+func (manager *SessionManagerCtx) reconnected(session *SessionCtx) {
+	if !session.reconnects.connected(time.Now(), manager.config.FlappingThreshold, manager.config.FlappingWindow) {
+		return
+	}
+
+	stats := session.Reconnects()
+	session.logger.Warn().
+		Int("reconnects", stats.RecentCount).
+		Dur("window", manager.config.FlappingWindow).
+		Msg("session connection is flapping")
+
+	manager.emmiter.Emit("flapping", session, stats)
+}
@@ -0,0 +1,91 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+func TestReconnectsFlapping(t *testing.T) {
+	r := reconnects{}
+	start := time.Now()
+	window := time.Minute
+
+	// first connection is not a reconnect
+	if r.connected(start, 3, window) {
+		t.Fatalf("first connection reported as flapping")
+	}
+
+	for i := 1; i <= 2; i++ {
+		if r.connected(start.Add(time.Duration(i)*time.Second), 3, window) {
+			t.Fatalf("reconnect %d reported as flapping, below threshold", i)
+		}
+	}
+
+	if !r.connected(start.Add(3*time.Second), 3, window) {
+		t.Fatalf("third reconnect within window not reported as flapping")
+	}
+
+	// reported only once
+	if r.connected(start.Add(4*time.Second), 3, window) {
+		t.Errorf("flapping session reported again")
+	}
+
+	stats := r.stats(start.Add(4*time.Second), window)
+	if stats.Count != 4 || stats.RecentCount != 4 || !stats.IsFlapping {
+		t.Errorf("stats = %+v, want 4 reconnects flapping", stats)
+	}
+	if stats.LastInterval != 1000 || stats.AverageInterval != 1000 {
+		t.Errorf("intervals = %d, %d, want 1000ms", stats.LastInterval, stats.AverageInterval)
+	}
+
+	// calmed down after the window
+	stats = r.stats(start.Add(4*time.Second+window+time.Second), window)
+	if stats.IsFlapping || stats.RecentCount != 0 {
+		t.Errorf("stats after window = %+v, want not flapping", stats)
+	}
+
+	// reconnect after calm period resets flapping, so that it is reported again
+	if r.connected(start.Add(10*time.Minute), 3, window) {
+		t.Errorf("single reconnect after calm period reported as flapping")
+	}
+}
+
+func TestSessionFlappingEvent(t *testing.T) {
+	manager := New(&config.Session{
+		FlappingThreshold: 2,
+		FlappingWindow:    time.Minute,
+	})
+
+	session, _, err := manager.Create("test", types.MemberProfile{
+		CanLogin:   true,
+		CanConnect: true,
+	})
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+
+	reported := 0
+	manager.OnFlapping(func(s types.Session, stats types.ReconnectStats) {
+		if s != session {
+			t.Errorf("flapping reported for wrong session")
+		}
+		reported++
+	})
+
+	for i := 0; i < 4; i++ {
+		peer := &testWebSocketPeer{}
+		session.ConnectWebSocketPeer(peer)
+		session.DisconnectWebSocketPeer(peer, false)
+	}
+
+	if reported != 1 {
+		t.Errorf("flapping reported %d times, want once", reported)
+	}
+
+	if stats := session.Reconnects(); stats.Count != 3 || !stats.IsFlapping {
+		t.Errorf("Reconnects() = %+v, want 3 reconnects flapping", stats)
+	}
+}
@@ -0,0 +1,110 @@
+package session
+
+import (
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/utils"
+)
+
+// handoff token is signed payload carrying state of a session moved
+// to another server, servers must share the handoff secret
+type handoffPayload struct {
+	Nonce     string              `json:"nonce"`
+	Expires   int64               `json:"exp"`
+	SessionID string              `json:"session_id"`
+	Profile   types.MemberProfile `json:"profile"`
+	IsHost    bool                `json:"is_host"`
+}
+
+func (manager *SessionManagerCtx) handoffEnabled() bool {
+	return manager.config.HandoffSecret != ""
+}
+
+// CreateHandoff serializes state of the session into a signed one-time token,
+// that restores it on another server sharing the handoff secret.
+func (manager *SessionManagerCtx) CreateHandoff(id string) (string, error) {
+	if !manager.handoffEnabled() {
+		return "", types.ErrSessionHandoffDisabled
+	}
+
+	manager.sessionsMu.Lock()
+	session, ok := manager.sessions[id]
+	manager.sessionsMu.Unlock()
+
+	if !ok {
+		return "", types.ErrSessionNotFound
+	}
+
+	nonce, err := utils.NewUID(16)
+	if err != nil {
+		return "", err
+	}
+
+	return signToken(manager.config.HandoffSecret, handoffPayload{
+		Nonce:     nonce,
+		Expires:   time.Now().Add(manager.config.HandoffTTL).Unix(),
+		SessionID: session.id,
+		Profile:   session.profile,
+		IsHost:    session.IsHost(),
+	})
+}
+
+// RedeemHandoff restores session from handoff token created by another server.
+// Existing session with the same ID is updated, host is restored only if there
+// is no host yet. Every handoff token can be redeemed only once.
+func (manager *SessionManagerCtx) RedeemHandoff(token string) (types.Session, string, error) {
+	if !manager.handoffEnabled() {
+		return nil, "", types.ErrSessionHandoffDisabled
+	}
+
+	handoff := &handoffPayload{}
+	if !verifyToken(manager.config.HandoffSecret, token, handoff) || handoff.Nonce == "" || handoff.SessionID == "" {
+		return nil, "", types.ErrSessionHandoffInvalid
+	}
+
+	if time.Now().Unix() >= handoff.Expires {
+		return nil, "", types.ErrSessionHandoffExpired
+	}
+
+	if !handoff.Profile.CanLogin {
+		return nil, "", types.ErrSessionLoginDisabled
+	}
+
+	if !manager.useNonce(handoff.Nonce, time.Unix(handoff.Expires, 0)) {
+		return nil, "", types.ErrSessionHandoffUsed
+	}
+
+	var (
+		session      types.Session
+		sessionToken string
+	)
+
+	manager.sessionsMu.Lock()
+	existing, ok := manager.sessions[handoff.SessionID]
+	manager.sessionsMu.Unlock()
+
+	if ok {
+		if err := manager.Update(handoff.SessionID, handoff.Profile); err != nil {
+			return nil, "", err
+		}
+		session, sessionToken = existing, existing.token
+	} else {
+		var err error
+		session, sessionToken, err = manager.Create(handoff.SessionID, handoff.Profile)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if _, hasHost := manager.GetHost(); handoff.IsHost && !hasHost {
+		session.SetAsHost()
+	}
+
+	manager.logger.Info().
+		Str("session_id", session.ID()).
+		Bool("is_host", handoff.IsHost).
+		Msg("session restored from handoff")
+
+	return session, sessionToken, nil
+}
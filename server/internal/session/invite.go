@@ -0,0 +1,113 @@
+package session
+
+import (
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/utils"
+)
+
+// invite token is signed payload, nonce identifies the invite and the guest session
+type invitePayload struct {
+	Nonce   string              `json:"nonce"`
+	Expires int64               `json:"exp"`
+	Profile types.MemberProfile `json:"profile"`
+}
+
+func (manager *SessionManagerCtx) invitesEnabled() bool {
+	return manager.config.InviteSecret != ""
+}
+
+// CreateInvite returns signed one-time invite token creating a guest session
+// with given profile, ttl <= 0 uses the default validity.
+func (manager *SessionManagerCtx) CreateInvite(profile types.MemberProfile, ttl time.Duration) (string, time.Time, error) {
+	if !manager.invitesEnabled() {
+		return "", time.Time{}, types.ErrSessionInvitesDisabled
+	}
+
+	if ttl <= 0 {
+		ttl = manager.config.InviteTTL
+	}
+
+	nonce, err := utils.NewUID(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expires := time.Now().Add(ttl)
+	token, err := signToken(manager.config.InviteSecret, invitePayload{
+		Nonce:   nonce,
+		Expires: expires.Unix(),
+		Profile: profile,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	manager.invitesMu.Lock()
+	// invites that were never redeemed do not accumulate
+	manager.pruneInvites(time.Now())
+	manager.invites[nonce] = inviteRecord{
+		issued:  time.Now(),
+		expires: expires,
+		profile: profile,
+	}
+	manager.invitesMu.Unlock()
+
+	return token, expires, nil
+}
+
+func (manager *SessionManagerCtx) parseInvite(token string) (*invitePayload, error) {
+	invite := &invitePayload{}
+	if !verifyToken(manager.config.InviteSecret, token, invite) || invite.Nonce == "" {
+		return nil, types.ErrSessionInviteInvalid
+	}
+
+	if time.Now().Unix() >= invite.Expires {
+		return nil, types.ErrSessionInviteExpired
+	}
+
+	return invite, nil
+}
+
+// RedeemInvite validates invite token and creates guest session with the
+// profile it was issued for. Every invite can be redeemed only once.
+func (manager *SessionManagerCtx) RedeemInvite(token string) (types.Session, string, error) {
+	if !manager.invitesEnabled() {
+		return nil, "", types.ErrSessionInvitesDisabled
+	}
+
+	invite, err := manager.parseInvite(token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	profile := invite.Profile
+	if !profile.CanLogin {
+		return nil, "", types.ErrSessionLoginDisabled
+	}
+
+	if !profile.IsAdmin && manager.Settings().LockedLogins {
+		return nil, "", types.ErrSessionLoginsLocked
+	}
+
+	if !manager.claimInvite(invite.Nonce) {
+		return nil, "", types.ErrSessionInviteRevoked
+	}
+
+	if !manager.useNonce(invite.Nonce, time.Unix(invite.Expires, 0)) {
+		return nil, "", types.ErrSessionInviteUsed
+	}
+
+	session, sessionToken, err := manager.Create("guest-"+invite.Nonce, profile)
+	if err != nil {
+		return nil, "", err
+	}
+
+	manager.logger.Info().
+		Str("session_id", session.ID()).
+		Str("name", profile.Name).
+		Msg("guest session created from invite")
+
+	return session, sessionToken, nil
+}
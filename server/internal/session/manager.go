@@ -26,13 +26,21 @@ func New(config *config.Session) *SessionManagerCtx {
 			ControlProtection: config.ControlProtection,
 			ImplicitHosting:   config.ImplicitHosting,
 			InactiveCursors:   config.InactiveCursors,
+			HostCursor:        config.HostCursor,
 			MercifulReconnect: config.MercifulReconnect,
 			HeartbeatInterval: config.HeartbeatInterval,
+			JoinApproval:      config.JoinApproval,
+			HostTakeoverGrace: config.HostTakeoverGrace,
 		},
-		tokens:   make(map[string]string),
-		sessions: make(map[string]*SessionCtx),
-		cursors:  make(map[types.Session][]types.Cursor),
-		emmiter:  events.New(),
+		tokens:          make(map[string]string),
+		sessions:        make(map[string]*SessionCtx),
+		cursors:         make(map[types.Session][]types.Cursor),
+		cursorsSpare:    make(map[types.Session][]types.Cursor),
+		reconnectTokens: make(map[string]string),
+		invites:         make(map[string]inviteRecord),
+		invitesRevoked:  make(map[string]time.Time),
+		noncesUsed:      make(map[string]time.Time),
+		emmiter:         events.New(),
 
 		serverStartedAt: time.Now(),
 	}
@@ -53,12 +61,16 @@ func New(config *config.Session) *SessionManagerCtx {
 				CanHost:            true,
 				CanAccessClipboard: true,
 			},
+			// API session never connects to acknowledge consent
+			consent: consent{given: true},
 		}
 	}
 
 	// try to load sessions from file
 	manager.load()
 
+	manager.validateClaimsMapping()
+
 	return manager
 }
 
@@ -73,10 +85,28 @@ type SessionManagerCtx struct {
 	sessions   map[string]*SessionCtx
 	sessionsMu sync.Mutex
 
-	hostId atomic.Value
+	hostId     atomic.Value
+	hostLocked atomic.Bool
+
+	cursors      map[types.Session][]types.Cursor
+	cursorsSpare map[types.Session][]types.Cursor
+	cursorsMu    sync.Mutex
+
+	reconnectTokens map[string]string
+	reconnectMu     sync.Mutex
 
-	cursors   map[types.Session][]types.Cursor
-	cursorsMu sync.Mutex
+	// invites that were issued and not redeemed yet, by nonce
+	invites map[string]inviteRecord
+	// nonces of revoked invites mapped to their expiration
+	invitesRevoked map[string]time.Time
+	invitesMu      sync.Mutex
+
+	// nonces of redeemed one-time tokens mapped to their expiration
+	noncesUsed   map[string]time.Time
+	noncesUsedMu sync.Mutex
+
+	changes   []types.SessionChange
+	changesMu sync.Mutex
 
 	emmiter    events.EventEmmiter
 	apiSession *SessionCtx
@@ -111,6 +141,8 @@ func (manager *SessionManagerCtx) Create(id string, profile types.MemberProfile)
 		manager: manager,
 		logger:  manager.logger.With().Str("session_id", id).Logger(),
 		profile: profile,
+		// until the session connects securely
+		disabledFeatures: manager.config.SecureFeatures,
 	}
 
 	manager.tokens[token] = id
@@ -132,11 +164,12 @@ func (manager *SessionManagerCtx) Update(id string, profile types.MemberProfile)
 		return types.ErrSessionNotFound
 	}
 
-	old := session.profile
+	// pending sessions announce their restricted profile
+	old := session.Profile()
 	session.profile = profile
 	manager.sessionsMu.Unlock()
 
-	manager.emmiter.Emit("profile_changed", session, profile, old)
+	manager.emmiter.Emit("profile_changed", session, session.Profile(), old)
 	manager.save()
 
 	session.profileChanged()
@@ -155,6 +188,8 @@ func (manager *SessionManagerCtx) Delete(id string) error {
 	delete(manager.sessions, id)
 	manager.sessionsMu.Unlock()
 
+	manager.revokeReconnectToken(session)
+
 	if session.State().IsConnected {
 		session.DestroyWebSocketPeer("session deleted")
 	}
@@ -247,7 +282,19 @@ func (manager *SessionManagerCtx) setHost(session, host types.Session) {
 		hostId = host.ID()
 	}
 
+	// while locked, only the host itself can hand off or release control
+	if manager.hostLocked.Load() && !manager.isHost(session) {
+		manager.logger.Warn().
+			Str("session_id", session.ID()).
+			Str("host_id", hostId).
+			Msg("host is locked, ignoring host change")
+		return
+	}
+
+	// lock belongs to the presenter, it is released with every host change
+	manager.hostLocked.Store(false)
 	manager.hostId.Store(hostId)
+	manager.recordHostChange(session, host)
 	manager.emmiter.Emit("host_changed", session, host)
 }
 
@@ -260,6 +307,24 @@ func (manager *SessionManagerCtx) GetHost() (types.Session, bool) {
 	return manager.Get(hostId)
 }
 
+func (manager *SessionManagerCtx) HostLocked() bool {
+	return manager.hostLocked.Load()
+}
+
+// SetHostLocked locks the current host, so that no other session can take control
+// until the lock is released. Callers are responsible for permission checks.
+func (manager *SessionManagerCtx) SetHostLocked(session types.Session, locked bool) error {
+	if _, ok := manager.GetHost(); !ok && locked {
+		return types.ErrSessionHostNotFound
+	}
+
+	if manager.hostLocked.Swap(locked) != locked {
+		manager.emmiter.Emit("host_lock_changed", session, locked)
+	}
+
+	return nil
+}
+
 func (manager *SessionManagerCtx) isHost(host types.Session) bool {
 	hostId, ok := manager.hostId.Load().(string)
 	return ok && hostId == host.ID()
@@ -282,12 +347,25 @@ func (manager *SessionManagerCtx) SetCursor(cursor types.Cursor, session types.S
 	manager.cursors[session] = list
 }
 
+// clearCursors replaces pending cursors of given session with an empty list,
+// so that next broadcast removes its cursor from clients.
+func (manager *SessionManagerCtx) clearCursors(session types.Session) {
+	manager.cursorsMu.Lock()
+	defer manager.cursorsMu.Unlock()
+
+	manager.cursors[session] = []types.Cursor{}
+}
+
+// PopCursors returns pending cursors and resets them. Maps are double buffered
+// to avoid allocating on every call, so the returned map is only valid until
+// the next call.
 func (manager *SessionManagerCtx) PopCursors() map[types.Session][]types.Cursor {
 	manager.cursorsMu.Lock()
 	defer manager.cursorsMu.Unlock()
 
 	cursors := manager.cursors
-	manager.cursors = make(map[types.Session][]types.Cursor)
+	clear(manager.cursorsSpare)
+	manager.cursors, manager.cursorsSpare = manager.cursorsSpare, cursors
 
 	return cursors
 }
@@ -329,19 +407,69 @@ func (manager *SessionManagerCtx) AdminBroadcast(event string, payload any, excl
 }
 
 func (manager *SessionManagerCtx) InactiveCursorsBroadcast(event string, payload any, exclude ...string) {
-	for _, session := range manager.List() {
+	// filter recipients in place, so that no additional slice is allocated
+	sessions := manager.List()
+	recipients := sessions[:0]
+	for _, session := range sessions {
 		if !session.State().IsConnected || !session.Profile().CanSeeInactiveCursors {
 			continue
 		}
 
+		switch manager.config.InactiveCursorsRecipients {
+		case config.InactiveCursorsRecipientsAdmins:
+			if !session.Profile().IsAdmin {
+				continue
+			}
+		case config.InactiveCursorsRecipientsHost:
+			if !session.Profile().IsAdmin && !session.IsHost() {
+				continue
+			}
+		}
+
 		if len(exclude) > 0 {
 			if in, _ := utils.ArrayIn(session.ID(), exclude); in {
 				continue
 			}
 		}
 
+		recipients = append(recipients, session)
+	}
+
+	sendConcurrently(recipients, manager.config.InactiveCursorsConcurrency, func(session types.Session) {
 		session.Send(event, payload)
+	})
+}
+
+// sendConcurrently calls send for every session using at most workers goroutines,
+// it returns after all sessions have been processed.
+func sendConcurrently(sessions []types.Session, workers int, send func(session types.Session)) {
+	if workers > len(sessions) {
+		workers = len(sessions)
+	}
+
+	if workers <= 1 {
+		for _, session := range sessions {
+			send(session)
+		}
+		return
+	}
+
+	var next atomic.Int32
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for range workers {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= len(sessions) {
+					return
+				}
+				send(sessions[i])
+			}
+		}()
 	}
+	wg.Wait()
 }
 
 // ---
@@ -394,12 +522,24 @@ func (manager *SessionManagerCtx) OnHostChanged(listener func(session, host type
 	})
 }
 
+func (manager *SessionManagerCtx) OnHostLockChanged(listener func(session types.Session, locked bool)) {
+	manager.emmiter.On("host_lock_changed", func(payload ...any) {
+		listener(payload[0].(types.Session), payload[1].(bool))
+	})
+}
+
 func (manager *SessionManagerCtx) OnSettingsChanged(listener func(session types.Session, new, old types.Settings)) {
 	manager.emmiter.On("settings_changed", func(payload ...any) {
 		listener(payload[0].(types.Session), payload[1].(types.Settings), payload[2].(types.Settings))
 	})
 }
 
+func (manager *SessionManagerCtx) OnFlapping(listener func(session types.Session, stats types.ReconnectStats)) {
+	manager.emmiter.On("flapping", func(payload ...any) {
+		listener(payload[0].(types.Session), payload[1].(types.ReconnectStats))
+	})
+}
+
 // ---
 // settings
 // ---
@@ -426,6 +566,7 @@ func (manager *SessionManagerCtx) updateSettings(session types.Session, new, old
 
 			// if session had control, it must release it
 			if enabled && s.IsHost() {
+				manager.hostLocked.Store(false)
 				session.ClearHost()
 			}
 
@@ -461,10 +602,12 @@ func (manager *SessionManagerCtx) updateSettings(session types.Session, new, old
 		// if the host is not admin, it must release controls
 		host, hasHost := manager.GetHost()
 		if hasHost && !host.Profile().IsAdmin {
+			manager.hostLocked.Store(false)
 			session.ClearHost()
 		}
 	}
 
+	manager.recordSettingsChange(session, new, old)
 	manager.emmiter.Emit("settings_changed", session, new, old)
 }
 
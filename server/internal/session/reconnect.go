@@ -0,0 +1,108 @@
+package session
+
+import (
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/utils"
+)
+
+func (manager *SessionManagerCtx) reconnectEnabled() bool {
+	return manager.config.ReconnectTokenTTL > 0
+}
+
+// issue new reconnect token for the session, previous token is revoked
+func (manager *SessionManagerCtx) issueReconnectToken(session *SessionCtx) string {
+	if !manager.reconnectEnabled() {
+		return ""
+	}
+
+	token, err := utils.NewUID(32)
+	if err != nil {
+		session.logger.Err(err).Msg("unable to generate reconnect token")
+		return ""
+	}
+
+	manager.reconnectMu.Lock()
+	defer manager.reconnectMu.Unlock()
+
+	if session.reconnectToken != "" {
+		delete(manager.reconnectTokens, session.reconnectToken)
+	}
+
+	session.reconnectToken = token
+	session.reconnectIssued = time.Now()
+	session.reconnectExpires = time.Time{}
+	manager.reconnectTokens[token] = session.id
+	return token
+}
+
+func (manager *SessionManagerCtx) revokeReconnectToken(session *SessionCtx) {
+	manager.reconnectMu.Lock()
+	defer manager.reconnectMu.Unlock()
+
+	if session.reconnectToken != "" {
+		delete(manager.reconnectTokens, session.reconnectToken)
+		session.reconnectToken = ""
+	}
+}
+
+// expireReconnectToken keeps token of disconnected session valid for its TTL
+// after the websocket was lost, presence and host are released regardless.
+func (manager *SessionManagerCtx) expireReconnectToken(session *SessionCtx, lost time.Time) {
+	manager.reconnectMu.Lock()
+	defer manager.reconnectMu.Unlock()
+
+	if session.reconnectToken != "" {
+		session.reconnectExpires = lost.Add(manager.config.ReconnectTokenTTL)
+	}
+}
+
+// Resume returns session for given reconnect token. Token can be used only
+// once, while the session is connected or within its TTL after unexpected
+// websocket disconnect.
+func (manager *SessionManagerCtx) Resume(token string) (types.Session, error) {
+	if !manager.reconnectEnabled() {
+		return nil, types.ErrSessionReconnectTokenInvalid
+	}
+
+	manager.reconnectMu.Lock()
+	id, ok := manager.reconnectTokens[token]
+	delete(manager.reconnectTokens, token)
+	manager.reconnectMu.Unlock()
+
+	if !ok {
+		return nil, types.ErrSessionReconnectTokenInvalid
+	}
+
+	manager.sessionsMu.Lock()
+	session, ok := manager.sessions[id]
+	manager.sessionsMu.Unlock()
+
+	if !ok {
+		return nil, types.ErrSessionNotFound
+	}
+
+	manager.reconnectMu.Lock()
+	expires := session.reconnectExpires
+	if session.reconnectToken == token {
+		session.reconnectToken = ""
+	}
+	manager.reconnectMu.Unlock()
+
+	// token expires after TTL since the session got disconnected
+	if !expires.IsZero() && time.Now().After(expires) {
+		return nil, types.ErrSessionReconnectTokenInvalid
+	}
+
+	if !session.Profile().CanLogin {
+		return nil, types.ErrSessionLoginDisabled
+	}
+
+	// changes while away are sent once the websocket connects
+	session.websocketMu.Lock()
+	session.resumed = true
+	session.websocketMu.Unlock()
+
+	return session, nil
+}
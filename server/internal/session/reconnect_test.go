@@ -0,0 +1,148 @@
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+func newReconnectSession(t *testing.T, ttl time.Duration) (*SessionManagerCtx, *SessionCtx) {
+	t.Helper()
+
+	manager := New(&config.Session{
+		ReconnectTokenTTL: ttl,
+	})
+
+	session, _, err := manager.Create("test", types.MemberProfile{
+		CanLogin:   true,
+		CanConnect: true,
+	})
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+
+	return manager, session.(*SessionCtx)
+}
+
+func TestReconnectTokenDisabled(t *testing.T) {
+	manager, session := newReconnectSession(t, 0)
+
+	session.ConnectWebSocketPeer(&testWebSocketPeer{})
+	if token := session.ReconnectToken(); token != "" {
+		t.Fatalf("expected no reconnect token, got %q", token)
+	}
+
+	if _, err := manager.Resume("anything"); !errors.Is(err, types.ErrSessionReconnectTokenInvalid) {
+		t.Errorf("expected resume to be rejected, got %v", err)
+	}
+}
+
+func TestReconnectTokenReissued(t *testing.T) {
+	manager, session := newReconnectSession(t, time.Minute)
+
+	session.ConnectWebSocketPeer(&testWebSocketPeer{})
+	first := session.ReconnectToken()
+	if first == "" {
+		t.Fatal("expected reconnect token to be issued")
+	}
+
+	// new connection revokes the previous token
+	session.ConnectWebSocketPeer(&testWebSocketPeer{})
+	second := session.ReconnectToken()
+	if second == "" || second == first {
+		t.Fatalf("expected new reconnect token, got %q", second)
+	}
+
+	if _, err := manager.Resume(first); !errors.Is(err, types.ErrSessionReconnectTokenInvalid) {
+		t.Errorf("expected previous token to be rejected, got %v", err)
+	}
+	if _, err := manager.Resume(second); err != nil {
+		t.Errorf("expected current token to be accepted, got %v", err)
+	}
+}
+
+func TestResumeOnce(t *testing.T) {
+	manager, session := newReconnectSession(t, time.Minute)
+
+	session.ConnectWebSocketPeer(&testWebSocketPeer{})
+	token := session.ReconnectToken()
+
+	resumed, err := manager.Resume(token)
+	if err != nil {
+		t.Fatalf("could not resume session %s", err.Error())
+	}
+	if resumed.ID() != session.ID() {
+		t.Errorf("resumed session %s, want %s", resumed.ID(), session.ID())
+	}
+
+	if _, err := manager.Resume(token); !errors.Is(err, types.ErrSessionReconnectTokenInvalid) {
+		t.Errorf("expected token to be usable only once, got %v", err)
+	}
+}
+
+func TestResumeAfterUnexpectedDisconnect(t *testing.T) {
+	manager, session := newReconnectSession(t, time.Minute)
+
+	// host is released by listeners of the disconnected event
+	disconnected := false
+	manager.OnDisconnected(func(types.Session) {
+		disconnected = true
+	})
+
+	peer := &testWebSocketPeer{}
+	session.ConnectWebSocketPeer(peer)
+	token := session.ReconnectToken()
+
+	// delayed disconnect has elapsed
+	session.disconnectWebSocketPeer(peer, true, false)
+	session.disconnectWebSocketPeer(peer, false, true)
+
+	if session.State().IsConnected || !disconnected {
+		t.Error("expected presence to be released after delayed disconnect")
+	}
+
+	tokens := manager.Tokens()
+	if len(tokens) != 1 || tokens[0].Expires == nil {
+		t.Fatalf("expected reconnect token with expiry, got %+v", tokens)
+	}
+
+	if _, err := manager.Resume(token); err != nil {
+		t.Errorf("expected token to be accepted within TTL, got %v", err)
+	}
+}
+
+func TestResumeExpired(t *testing.T) {
+	manager, session := newReconnectSession(t, 10*time.Millisecond)
+
+	peer := &testWebSocketPeer{}
+	session.ConnectWebSocketPeer(peer)
+	token := session.ReconnectToken()
+
+	session.disconnectWebSocketPeer(peer, false, true)
+	time.Sleep(20 * time.Millisecond)
+
+	if tokens := manager.Tokens(); len(tokens) != 0 {
+		t.Errorf("expected expired token not to be listed, got %+v", tokens)
+	}
+	if _, err := manager.Resume(token); !errors.Is(err, types.ErrSessionReconnectTokenInvalid) {
+		t.Errorf("expected expired token to be rejected, got %v", err)
+	}
+}
+
+func TestResumeAfterDisconnect(t *testing.T) {
+	manager, session := newReconnectSession(t, time.Minute)
+
+	peer := &testWebSocketPeer{}
+	session.ConnectWebSocketPeer(peer)
+	token := session.ReconnectToken()
+
+	// client closed the connection on purpose
+	session.DisconnectWebSocketPeer(peer, false)
+
+	if _, err := manager.Resume(token); !errors.Is(err, types.ErrSessionReconnectTokenInvalid) {
+		t.Errorf("expected token to be revoked, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package session
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/utils"
+)
+
+// isSecureRequest reports whether request came over TLS, directly or through
+// a trusted proxy. Loopback is secure context for browsers as well, unless it
+// is a proxy forwarding other clients. Peer address is used, as RemoteAddr may
+// already be taken from forwarded headers.
+func isSecureRequest(r *http.Request, trustedProxies []*net.IPNet) bool {
+	if r.TLS != nil {
+		return true
+	}
+
+	addr := utils.PeerAddr(r)
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	forwarded := r.Header.Get("X-Forwarded-For") != "" || r.Header.Get("X-Real-IP") != ""
+	if ip.IsLoopback() && !forwarded {
+		return true
+	}
+
+	for _, n := range trustedProxies {
+		if n.Contains(ip) {
+			proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+			return strings.EqualFold(strings.TrimSpace(proto), "https")
+		}
+	}
+
+	return false
+}
+
+// insecureFeatures returns features that must be disabled for the request.
+func (manager *SessionManagerCtx) insecureFeatures(r *http.Request) []string {
+	if len(manager.config.SecureFeatures) == 0 || isSecureRequest(r, manager.config.SecureTrustedProxies) {
+		return nil
+	}
+
+	return manager.config.SecureFeatures
+}
+
+// DisableInsecureFeatures decides which features are disabled for the session
+// by the request of its websocket connection. It is kept for the connection,
+// other requests of the session do not change it.
+func (manager *SessionManagerCtx) DisableInsecureFeatures(session types.Session, r *http.Request) {
+	if s, ok := session.(*SessionCtx); ok {
+		s.setDisabledFeatures(manager.insecureFeatures(r))
+	}
+}
@@ -0,0 +1,110 @@
+package session
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/middleware"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/utils"
+)
+
+func TestIsSecureRequest(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+
+	tests := []struct {
+		name   string
+		remote string
+		tls    bool
+		proto  string
+		xff    string
+		realIP string
+		want   bool
+	}{
+		{"tls", "1.2.3.4:1000", true, "", "", "", true},
+		{"plaintext", "1.2.3.4:1000", false, "", "", "", false},
+		{"untrusted forwarded proto", "1.2.3.4:1000", false, "https", "", "", false},
+		{"trusted proxy https", "10.0.0.1:1000", false, "https", "1.2.3.4", "", true},
+		{"trusted proxy http", "10.0.0.1:1000", false, "http", "1.2.3.4", "", false},
+		{"trusted proxy real ip https", "10.0.0.1:1000", false, "https", "", "1.2.3.4", true},
+		{"localhost", "127.0.0.1:1000", false, "", "", "", true},
+		{"proxied through localhost", "127.0.0.1:1000", false, "https", "1.2.3.4", "", false},
+		{"proxied through localhost real ip", "127.0.0.1:1000", false, "", "", "1.2.3.4", false},
+		{"spoofed loopback", "1.2.3.4:1000", false, "", "", "127.0.0.1", false},
+		{"spoofed trusted proxy", "1.2.3.4:1000", false, "https", "", "10.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// behind server.proxy, RemoteAddr is taken from forwarded headers
+			var got bool
+			handler := utils.WithPeerAddr(middleware.RealIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = isSecureRequest(r, []*net.IPNet{proxies})
+			})))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/ws", nil)
+			r.RemoteAddr = tt.remote
+			r.TLS = nil
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			if tt.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+			if got != tt.want {
+				t.Errorf("isSecureRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisableInsecureFeatures(t *testing.T) {
+	manager := New(&config.Session{
+		SecureFeatures: []string{types.FeatureClipboard},
+	})
+
+	session, token, err := manager.Create("test", types.MemberProfile{CanLogin: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// disabled until the session connects securely
+	if !session.FeatureDisabled(types.FeatureClipboard) || session.FeatureDisabled(types.FeatureMediaSharing) {
+		t.Errorf("disabled features = %v", session.DisabledFeatures())
+	}
+
+	secure := &http.Request{RemoteAddr: "1.2.3.4:1000", Header: http.Header{}, TLS: &tls.ConnectionState{}}
+	manager.DisableInsecureFeatures(session, secure)
+	if len(session.DisabledFeatures()) != 0 {
+		t.Errorf("disabled features = %v", session.DisabledFeatures())
+	}
+
+	// other requests of the session do not change the connection
+	insecure := &http.Request{RemoteAddr: "1.2.3.4:1000", Header: http.Header{}}
+	insecure.Header.Set("Authorization", "Bearer "+token)
+	if _, err := manager.Authenticate(insecure); err != nil {
+		t.Fatal(err)
+	}
+	if len(session.DisabledFeatures()) != 0 {
+		t.Errorf("disabled features = %v", session.DisabledFeatures())
+	}
+
+	// the same session connected insecurely loses the features
+	manager.DisableInsecureFeatures(session, insecure)
+	if !session.FeatureDisabled(types.FeatureClipboard) {
+		t.Errorf("disabled features = %v", session.DisabledFeatures())
+	}
+}
@@ -86,6 +86,8 @@ func (manager *SessionManagerCtx) load() {
 			manager: manager,
 			logger:  manager.logger.With().Str("session_id", session.Id).Logger(),
 			profile: session.Profile,
+			// until the session connects securely
+			disabledFeatures: manager.config.SecureFeatures,
 		}
 	}
 	manager.sessionsMu.Unlock()
@@ -1,6 +1,8 @@
 package session
 
 import (
+	"fmt"
+	"slices"
 	"sync"
 	"time"
 
@@ -22,6 +24,30 @@ type SessionCtx struct {
 	profile types.MemberProfile
 	state   types.SessionState
 
+	// approved by an admin when join approval is required
+	approved bool
+	// acknowledged consent when it is required
+	consent consent
+
+	// features disabled for the latest request, that was not secure
+	disabledFeatures   []string
+	disabledFeaturesMu sync.Mutex
+
+	// token used to resume this session after unexpected disconnect
+	reconnectToken  string
+	reconnectIssued time.Time
+	// token expiry once the session got disconnected, zero while connected
+	reconnectExpires time.Time
+
+	// how often the session connects again
+	reconnects reconnects
+
+	// when the websocket was lost and what changed until it reconnected
+	awaySince        *time.Time
+	changesWhileAway []types.SessionChange
+	// resumed using reconnect token after the delayed disconnect elapsed
+	resumed bool
+
 	websocketPeer types.WebSocketPeer
 	websocketMu   sync.Mutex
 
@@ -31,6 +57,14 @@ type SessionCtx struct {
 
 	webrtcPeer types.WebRTCPeer
 	webrtcMu   sync.Mutex
+
+	// events waiting to be sent as a batch
+	coalesced   map[string]*coalescedEvent
+	coalescedMu sync.Mutex
+
+	// scratch store for handlers, cleared on disconnect
+	values   map[string]any
+	valuesMu sync.Mutex
 }
 
 func (session *SessionCtx) ID() string {
@@ -38,6 +72,13 @@ func (session *SessionCtx) ID() string {
 }
 
 func (session *SessionCtx) Profile() types.MemberProfile {
+	// restricted over websocket and REST until consent is given
+	if !session.admitted() {
+		return consentProfile(session.profile)
+	}
+	if session.state.IsPending {
+		return pendingProfile(session.profile)
+	}
 	return session.profile
 }
 
@@ -65,6 +106,24 @@ func (session *SessionCtx) profileChanged() {
 	}
 }
 
+// ReconnectToken returns token that can be used to resume this session
+// after unexpected disconnect, empty if reconnect tokens are disabled.
+func (session *SessionCtx) ReconnectToken() string {
+	session.manager.reconnectMu.Lock()
+	defer session.manager.reconnectMu.Unlock()
+
+	return session.reconnectToken
+}
+
+// Changes in the room that happened while the session was away, before
+// its websocket has been connected again. Empty if there was no gap.
+func (session *SessionCtx) ChangesWhileAway() []types.SessionChange {
+	session.websocketMu.Lock()
+	defer session.websocketMu.Unlock()
+
+	return session.changesWhileAway
+}
+
 func (session *SessionCtx) State() types.SessionState {
 	return session.state
 }
@@ -95,12 +154,97 @@ func (session *SessionCtx) PrivateModeEnabled() bool {
 	return session.manager.Settings().PrivateMode && !session.profile.IsAdmin
 }
 
+func (session *SessionCtx) HostCursorVisible() bool {
+	return session.manager.Settings().HostCursor && !session.IsHost()
+}
+
+func (session *SessionCtx) DisabledFeatures() []string {
+	session.disabledFeaturesMu.Lock()
+	defer session.disabledFeaturesMu.Unlock()
+
+	return session.disabledFeatures
+}
+
+func (session *SessionCtx) FeatureDisabled(feature string) bool {
+	return slices.Contains(session.DisabledFeatures(), feature)
+}
+
+func (session *SessionCtx) Reconnects() types.ReconnectStats {
+	return session.reconnects.stats(time.Now(), session.manager.config.FlappingWindow)
+}
+
+func (session *SessionCtx) setDisabledFeatures(features []string) {
+	session.disabledFeaturesMu.Lock()
+	defer session.disabledFeaturesMu.Unlock()
+
+	session.disabledFeatures = features
+}
+
 func (session *SessionCtx) SetCursor(cursor types.Cursor) {
 	if session.manager.Settings().InactiveCursors && session.profile.SendsInactiveCursor {
 		session.manager.SetCursor(cursor, session)
 	}
 }
 
+// ---
+// scratch store
+// ---
+
+// Set value in session scratch store, it is kept until the session disconnects.
+func (session *SessionCtx) SetValue(key string, value any) {
+	session.valuesMu.Lock()
+	defer session.valuesMu.Unlock()
+
+	if session.values == nil {
+		session.values = make(map[string]any)
+	}
+
+	session.values[key] = value
+}
+
+// Get value from session scratch store.
+func (session *SessionCtx) Value(key string) (any, bool) {
+	session.valuesMu.Lock()
+	defer session.valuesMu.Unlock()
+
+	value, ok := session.values[key]
+	return value, ok
+}
+
+// Update value in session scratch store atomically, value passed to the
+// function is nil if not set. Returning nil deletes the value.
+func (session *SessionCtx) UpdateValue(key string, f func(value any) any) {
+	session.valuesMu.Lock()
+	defer session.valuesMu.Unlock()
+
+	value := f(session.values[key])
+	if value == nil {
+		delete(session.values, key)
+		return
+	}
+
+	if session.values == nil {
+		session.values = make(map[string]any)
+	}
+
+	session.values[key] = value
+}
+
+// Delete value from session scratch store.
+func (session *SessionCtx) DeleteValue(key string) {
+	session.valuesMu.Lock()
+	defer session.valuesMu.Unlock()
+
+	delete(session.values, key)
+}
+
+func (session *SessionCtx) clearValues() {
+	session.valuesMu.Lock()
+	defer session.valuesMu.Unlock()
+
+	session.values = nil
+}
+
 // ---
 // websocket
 // ---
@@ -108,6 +252,8 @@ func (session *SessionCtx) SetCursor(cursor types.Cursor) {
 // Connect WebSocket peer sets current peer and emits connected event. It also destroys the
 // previous peer, if there was one. If the peer is already set, it will be ignored.
 func (session *SessionCtx) ConnectWebSocketPeer(websocketPeer types.WebSocketPeer) {
+	connectedPeer := websocketPeer
+
 	session.websocketMu.Lock()
 	isCurrentPeer := websocketPeer == session.websocketPeer
 	session.websocketPeer, websocketPeer = websocketPeer, session.websocketPeer
@@ -120,11 +266,34 @@ func (session *SessionCtx) ConnectWebSocketPeer(websocketPeer types.WebSocketPee
 
 	session.logger.Info().Msg("set websocket connected")
 
+	if !session.state.IsConnected {
+		session.manager.recordChange(types.SessionChangeJoined, session.id, fmt.Sprintf("%s joined", sessionName(session)))
+	}
+
+	// collect changes that happened while the session was away, only if it
+	// reconnected mercifully or was resumed, not when it joined again
+	session.websocketMu.Lock()
+	resumed := session.state.IsConnected || session.resumed
+	if resumed && session.awaySince != nil && session.manager.changesEnabled() {
+		session.changesWhileAway = session.manager.changesSince(*session.awaySince, session.id)
+	} else {
+		session.changesWhileAway = nil
+	}
+	session.awaySince = nil
+	session.resumed = false
+	session.websocketMu.Unlock()
+
 	// update state
 	now := time.Now()
 	session.state.IsConnected = true
 	session.state.ConnectedSince = &now
 	session.state.NotConnectedSince = nil
+	session.state.IsPending = !session.approved && !session.profile.IsAdmin && session.manager.Settings().JoinApproval
+	session.state.IsAdmitted = session.admitted()
+
+	if !session.state.IsAdmitted {
+		session.waitForConsent(connectedPeer)
+	}
 
 	if session.profile.IsAdmin {
 		session.manager.totalAdmins.Add(1)
@@ -134,12 +303,14 @@ func (session *SessionCtx) ConnectWebSocketPeer(websocketPeer types.WebSocketPee
 		session.manager.lastUserLeftAt.Store((*time.Time)(nil))
 	}
 
-	session.manager.emmiter.Emit("connected", session)
-
-	// if there is a previous peer, destroy it
+	// if there is a previous peer, destroy it before the new one is announced
 	if websocketPeer != nil {
 		websocketPeer.Destroy("connection replaced")
 	}
+
+	session.manager.issueReconnectToken(session)
+	session.manager.emmiter.Emit("connected", session)
+	session.manager.reconnected(session)
 }
 
 // Disconnect WebSocket peer sets current peer to nil and emits disconnected event. It also
@@ -149,8 +320,19 @@ func (session *SessionCtx) ConnectWebSocketPeer(websocketPeer types.WebSocketPee
 //
 // If the peer is not the current peer or the peer is nil, it will be ignored.
 func (session *SessionCtx) DisconnectWebSocketPeer(websocketPeer types.WebSocketPeer, delayed bool) {
+	session.disconnectWebSocketPeer(websocketPeer, delayed, false)
+}
+
+// resumable keeps reconnect token valid after the disconnect, so that the
+// session can be resumed within its TTL.
+func (session *SessionCtx) disconnectWebSocketPeer(websocketPeer types.WebSocketPeer, delayed, resumable bool) {
 	session.websocketMu.Lock()
 	isCurrentPeer := websocketPeer == session.websocketPeer && websocketPeer != nil
+	if isCurrentPeer && session.awaySince == nil {
+		now := time.Now()
+		session.awaySince = &now
+	}
+	awaySince := session.awaySince
 	session.websocketMu.Unlock()
 
 	// ignore if not current peer
@@ -166,7 +348,7 @@ func (session *SessionCtx) DisconnectWebSocketPeer(websocketPeer types.WebSocket
 
 	if delayed {
 		wsDelayedTimer = time.AfterFunc(WS_DELAYED_DURATION, func() {
-			session.DisconnectWebSocketPeer(websocketPeer, false)
+			session.disconnectWebSocketPeer(websocketPeer, false, true)
 		})
 	}
 
@@ -188,10 +370,30 @@ func (session *SessionCtx) DisconnectWebSocketPeer(websocketPeer types.WebSocket
 
 	session.logger.Info().Msg("set websocket disconnected")
 
+	// session can be resumed only after unexpected disconnect
+	if resumable && awaySince != nil {
+		session.manager.expireReconnectToken(session, *awaySince)
+	} else {
+		session.manager.revokeReconnectToken(session)
+
+		// session left on purpose, it will not be resumed
+		session.websocketMu.Lock()
+		session.awaySince = nil
+		session.websocketMu.Unlock()
+	}
+
+	// remove cursor of disconnected session, so that it does not linger
+	if session.manager.Settings().InactiveCursors && session.manager.config.InactiveCursorsCleanup {
+		session.manager.clearCursors(session)
+	}
+
 	now := time.Now()
 	session.state.IsConnected = false
 	session.state.ConnectedSince = nil
 	session.state.NotConnectedSince = &now
+	session.state.IsPending = false
+	session.state.IsAdmitted = false
+	session.stopConsentTimeout()
 
 	if session.profile.IsAdmin {
 		if session.manager.totalAdmins.Add(-1) == 0 {
@@ -203,6 +405,9 @@ func (session *SessionCtx) DisconnectWebSocketPeer(websocketPeer types.WebSocket
 		}
 	}
 
+	session.clearValues()
+
+	session.manager.recordChange(types.SessionChangeLeft, session.id, fmt.Sprintf("%s left", sessionName(session)))
 	session.manager.emmiter.Emit("disconnected", session)
 
 	session.websocketMu.Lock()
@@ -230,8 +435,17 @@ func (session *SessionCtx) DestroyWebSocketPeer(reason string) {
 	peer.Destroy(reason)
 }
 
-// Send event to websocket peer.
+// Send event to websocket peer, coalesced events are delayed.
 func (session *SessionCtx) Send(event string, payload any) {
+	if window, ok := session.manager.config.BroadcastCoalesce[event]; ok {
+		session.coalesce(event, payload, window)
+		return
+	}
+
+	session.send(event, payload)
+}
+
+func (session *SessionCtx) send(event string, payload any) {
 	session.websocketMu.Lock()
 	peer := session.websocketPeer
 	session.websocketMu.Unlock()
@@ -0,0 +1,892 @@
+package session
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/auth"
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// non-zero size, so that each peer has a distinct address
+type testWebSocketPeer struct{ _ byte }
+
+func (testWebSocketPeer) Send(event string, payload any) {}
+func (testWebSocketPeer) Ping() error                    { return nil }
+func (testWebSocketPeer) Destroy(reason string)          {}
+
+func TestDisconnectClearsInactiveCursors(t *testing.T) {
+	manager := New(&config.Session{
+		InactiveCursors:        true,
+		InactiveCursorsCleanup: true,
+	})
+
+	session, _, err := manager.Create("test", types.MemberProfile{
+		CanLogin:            true,
+		CanConnect:          true,
+		SendsInactiveCursor: true,
+	})
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+
+	peer := &testWebSocketPeer{}
+	session.ConnectWebSocketPeer(peer)
+
+	// cursor is moving while the session disconnects
+	session.SetCursor(types.Cursor{X: 10, Y: 20})
+	session.DisconnectWebSocketPeer(peer, false)
+
+	cursors := manager.PopCursors()
+
+	list, ok := cursors[session]
+	if !ok {
+		t.Fatalf("disconnected session is not in cursors, its cursor would not be removed")
+	}
+
+	if len(list) != 0 {
+		t.Errorf("disconnected session has %d cursors, expected none", len(list))
+	}
+
+	// cursor is removed only once
+	if cursors := manager.PopCursors(); len(cursors) != 0 {
+		t.Errorf("cursors are not empty after pop")
+	}
+}
+
+func TestDisconnectKeepsInactiveCursorsWithoutCleanup(t *testing.T) {
+	manager := New(&config.Session{
+		InactiveCursors:        true,
+		InactiveCursorsCleanup: false,
+	})
+
+	session, _, err := manager.Create("test", types.MemberProfile{
+		CanLogin:            true,
+		CanConnect:          true,
+		SendsInactiveCursor: true,
+	})
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+
+	peer := &testWebSocketPeer{}
+	session.ConnectWebSocketPeer(peer)
+
+	session.SetCursor(types.Cursor{X: 10, Y: 20})
+	session.DisconnectWebSocketPeer(peer, false)
+
+	if list := manager.PopCursors()[session]; len(list) != 1 {
+		t.Errorf("session has %d cursors, expected 1", len(list))
+	}
+}
+
+func TestRejoinChangesWhileAway(t *testing.T) {
+	manager := New(&config.Session{
+		RejoinChanges: true,
+	})
+
+	profile := types.MemberProfile{
+		CanLogin:   true,
+		CanConnect: true,
+		CanHost:    true,
+	}
+
+	session, _, err := manager.Create("test", profile)
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+
+	other, _, err := manager.Create("other", profile)
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+
+	peer := &testWebSocketPeer{}
+	session.ConnectWebSocketPeer(peer)
+
+	if changes := session.ChangesWhileAway(); len(changes) != 0 {
+		t.Errorf("session has %d changes on first connect, expected none", len(changes))
+	}
+
+	// unexpected disconnect, other session joins and takes control
+	session.DisconnectWebSocketPeer(peer, true)
+	other.ConnectWebSocketPeer(&testWebSocketPeer{})
+	other.SetAsHost()
+
+	session.ConnectWebSocketPeer(&testWebSocketPeer{})
+
+	changes := session.ChangesWhileAway()
+	if len(changes) != 2 {
+		t.Fatalf("session has %d changes after rejoin, expected 2", len(changes))
+	}
+
+	if changes[0].Kind != types.SessionChangeJoined || changes[1].Kind != types.SessionChangeHost {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+}
+
+func TestValuesClearedOnDisconnect(t *testing.T) {
+	manager := New(&config.Session{})
+
+	session, _, err := manager.Create("test", types.MemberProfile{
+		CanLogin:   true,
+		CanConnect: true,
+	})
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+
+	peer := &testWebSocketPeer{}
+	session.ConnectWebSocketPeer(peer)
+
+	session.SetValue("mode", "drawing")
+	session.UpdateValue("counter", func(value any) any {
+		counter, _ := value.(int)
+		return counter + 1
+	})
+
+	if value, ok := session.Value("counter"); !ok || value != 1 {
+		t.Errorf("counter is %v, expected 1", value)
+	}
+
+	// values survive delayed disconnect
+	session.DisconnectWebSocketPeer(peer, true)
+	if _, ok := session.Value("mode"); !ok {
+		t.Errorf("value was cleared on delayed disconnect")
+	}
+
+	session.DisconnectWebSocketPeer(peer, false)
+	if _, ok := session.Value("mode"); ok {
+		t.Errorf("value was not cleared on disconnect")
+	}
+}
+
+func TestApplyClaims(t *testing.T) {
+	manager := New(&config.Session{
+		ClaimsHeader: "X-Forwarded-Groups",
+		ClaimsMapping: map[string][]string{
+			"admins":     {"is_admin", "can_host"},
+			"presenters": {"can_host", "can_share_media"},
+		},
+	})
+
+	session, _, err := manager.Create("test", types.MemberProfile{
+		CanLogin:           true,
+		CanAccessClipboard: true,
+	})
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Forwarded-Groups", "users, presenters")
+
+	if err := manager.ApplyClaims(session, r); err != nil {
+		t.Fatalf("could not apply claims %s", err.Error())
+	}
+
+	profile := session.Profile()
+	if profile.IsAdmin || !profile.CanHost || !profile.CanShareMedia || !profile.CanAccessClipboard {
+		t.Errorf("unexpected profile %+v", profile)
+	}
+
+	// claim removed on reconnect revokes permissions
+	r.Header.Set("X-Forwarded-Groups", "users")
+
+	if err := manager.ApplyClaims(session, r); err != nil {
+		t.Fatalf("could not apply claims %s", err.Error())
+	}
+
+	profile = session.Profile()
+	if profile.CanHost || profile.CanShareMedia || !profile.CanAccessClipboard {
+		t.Errorf("unexpected profile %+v", profile)
+	}
+}
+
+type recordingWebSocketPeer struct {
+	mu        sync.Mutex
+	events    []string
+	payloads  []any
+	destroyed bool
+}
+
+func (p *recordingWebSocketPeer) Send(event string, payload any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	p.payloads = append(p.payloads, payload)
+}
+
+func (p *recordingWebSocketPeer) Ping() error { return nil }
+
+func (p *recordingWebSocketPeer) Destroy(reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.destroyed = true
+}
+
+func (p *recordingWebSocketPeer) isDestroyed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.destroyed
+}
+
+func TestRapidReconnectReplacesPeer(t *testing.T) {
+	manager := New(&config.Session{})
+
+	session, _, err := manager.Create("test", types.MemberProfile{
+		CanLogin:   true,
+		CanConnect: true,
+	})
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+
+	peers := []*recordingWebSocketPeer{}
+	manager.OnConnected(func(s types.Session) {
+		// all previous peers must be destroyed before connected is emitted
+		for _, peer := range peers[:len(peers)-1] {
+			if !peer.isDestroyed() {
+				t.Errorf("previous peer is not destroyed when new peer is connected")
+			}
+		}
+
+		s.Send("test/connected", nil)
+	})
+
+	for i := 0; i < 5; i++ {
+		peer := &recordingWebSocketPeer{}
+		peers = append(peers, peer)
+		session.ConnectWebSocketPeer(peer)
+	}
+
+	session.Send("test/message", nil)
+
+	for i, peer := range peers {
+		last := i == len(peers)-1
+
+		if peer.isDestroyed() == last {
+			t.Errorf("peer %d destroyed is %v", i, peer.isDestroyed())
+		}
+
+		// every peer got only its own connected event, messages go only to the last one
+		expected := []string{"test/connected"}
+		if last {
+			expected = append(expected, "test/message")
+		}
+
+		if len(peer.events) != len(expected) {
+			t.Errorf("peer %d got events %v, expected %v", i, peer.events, expected)
+		}
+	}
+
+	if !session.State().IsConnected {
+		t.Errorf("session is not connected")
+	}
+}
+
+func TestInactiveCursorsBroadcastConcurrently(t *testing.T) {
+	manager := New(&config.Session{
+		InactiveCursors:            true,
+		InactiveCursorsConcurrency: 4,
+	})
+
+	var peers []*recordingWebSocketPeer
+	for i := 0; i < 10; i++ {
+		session, _, err := manager.Create(fmt.Sprintf("test%d", i), types.MemberProfile{
+			CanLogin:              true,
+			CanConnect:            true,
+			CanSeeInactiveCursors: true,
+		})
+		if err != nil {
+			t.Fatalf("could not create session %s", err.Error())
+		}
+
+		peer := &recordingWebSocketPeer{}
+		peers = append(peers, peer)
+		session.ConnectWebSocketPeer(peer)
+	}
+
+	manager.InactiveCursorsBroadcast("test/cursors", nil, "test0")
+
+	for i, peer := range peers {
+		count := 0
+		for _, event := range peer.events {
+			if event == "test/cursors" {
+				count++
+			}
+		}
+
+		expected := 1
+		if i == 0 {
+			expected = 0
+		}
+
+		if count != expected {
+			t.Errorf("peer %d got cursors %d times, expected %d", i, count, expected)
+		}
+	}
+}
+
+func TestHostLockAllowsOnlyHandoff(t *testing.T) {
+	manager := New(&config.Session{})
+
+	profile := types.MemberProfile{
+		CanLogin: true,
+		CanHost:  true,
+	}
+
+	host, _, _ := manager.Create("host", profile)
+	other, _, _ := manager.Create("other", profile)
+	third, _, _ := manager.Create("third", profile)
+
+	if err := manager.SetHostLocked(host, true); err == nil {
+		t.Fatalf("lock without host should fail")
+	}
+
+	host.SetAsHost()
+	if err := manager.SetHostLocked(host, true); err != nil {
+		t.Fatalf("could not lock host %s", err.Error())
+	}
+
+	// others cannot take control while locked
+	other.SetAsHost()
+	if !host.IsHost() {
+		t.Fatalf("host changed while locked")
+	}
+
+	// host can hand off voluntarily, which releases the lock
+	third.SetAsHostBy(host)
+	if !third.IsHost() {
+		t.Fatalf("host could not hand off while locked")
+	}
+
+	if manager.HostLocked() {
+		t.Errorf("lock was not released with host change")
+	}
+}
+
+func TestHostCursorVisible(t *testing.T) {
+	manager := New(&config.Session{HostCursor: true})
+
+	profile := types.MemberProfile{
+		CanLogin: true,
+		CanHost:  true,
+	}
+
+	host, _, _ := manager.Create("host", profile)
+	viewer, _, _ := manager.Create("viewer", profile)
+	host.SetAsHost()
+
+	if host.HostCursorVisible() {
+		t.Errorf("host cursor is visible to the host itself")
+	}
+	if !viewer.HostCursorVisible() {
+		t.Errorf("host cursor is not visible to viewer")
+	}
+
+	manager.UpdateSettingsFunc(host, func(settings *types.Settings) bool {
+		settings.HostCursor = false
+		return true
+	})
+
+	if viewer.HostCursorVisible() {
+		t.Errorf("host cursor is visible to viewer after it was hidden")
+	}
+}
+
+func TestInviteRedeemedOnce(t *testing.T) {
+	manager := New(&config.Session{
+		InviteSecret: "secret",
+		InviteTTL:    time.Hour,
+	})
+
+	token, _, err := manager.CreateInvite(types.MemberProfile{
+		Name:       "Guest",
+		CanLogin:   true,
+		CanConnect: true,
+		CanWatch:   true,
+	}, 0)
+	if err != nil {
+		t.Fatalf("could not create invite %s", err.Error())
+	}
+
+	session, sessionToken, err := manager.RedeemInvite(token)
+	if err != nil {
+		t.Fatalf("could not redeem invite %s", err.Error())
+	}
+
+	if session.Profile().Name != "Guest" || session.Profile().CanHost {
+		t.Fatalf("unexpected guest profile %+v", session.Profile())
+	}
+
+	if s, ok := manager.GetByToken(sessionToken); !ok || s.ID() != session.ID() {
+		t.Fatal("guest session is not reachable by its token")
+	}
+
+	if _, _, err := manager.RedeemInvite(token); !errors.Is(err, types.ErrSessionInviteUsed) {
+		t.Fatalf("expected reused invite to be rejected, got %v", err)
+	}
+}
+
+func TestInviteRejectsTamperedAndExpired(t *testing.T) {
+	manager := New(&config.Session{
+		InviteSecret: "secret",
+		InviteTTL:    time.Hour,
+	})
+
+	token, _, err := manager.CreateInvite(types.MemberProfile{CanLogin: true}, 0)
+	if err != nil {
+		t.Fatalf("could not create invite %s", err.Error())
+	}
+
+	// token signed by another secret
+	other := New(&config.Session{
+		InviteSecret: "other",
+		InviteTTL:    time.Hour,
+	})
+	if _, _, err := other.RedeemInvite(token); !errors.Is(err, types.ErrSessionInviteInvalid) {
+		t.Fatalf("expected foreign invite to be rejected, got %v", err)
+	}
+
+	// payload with elevated permissions, but the original signature
+	payload, signature, _ := strings.Cut(token, ".")
+	data, _ := base64.RawURLEncoding.DecodeString(payload)
+	data = []byte(strings.Replace(string(data), `"is_admin":false`, `"is_admin":true`, 1))
+	tampered := base64.RawURLEncoding.EncodeToString(data) + "." + signature
+	if _, _, err := manager.RedeemInvite(tampered); !errors.Is(err, types.ErrSessionInviteInvalid) {
+		t.Fatalf("expected tampered invite to be rejected, got %v", err)
+	}
+
+	// non-positive ttl uses the default, so it is made to be in the past
+	manager.config.InviteTTL = -time.Hour
+	expired, _, err := manager.CreateInvite(types.MemberProfile{CanLogin: true}, 0)
+	if err != nil {
+		t.Fatalf("could not create invite %s", err.Error())
+	}
+	if _, _, err := manager.RedeemInvite(expired); !errors.Is(err, types.ErrSessionInviteExpired) {
+		t.Fatalf("expected expired invite to be rejected, got %v", err)
+	}
+}
+
+func TestRevokedInviteRejected(t *testing.T) {
+	manager := New(&config.Session{
+		InviteSecret: "secret",
+		InviteTTL:    time.Hour,
+	})
+
+	profile := types.MemberProfile{Name: "Guest", CanLogin: true}
+	token, _, err := manager.CreateInvite(profile, 0)
+	if err != nil {
+		t.Fatalf("could not create invite %s", err.Error())
+	}
+	kept, _, err := manager.CreateInvite(profile, 0)
+	if err != nil {
+		t.Fatalf("could not create invite %s", err.Error())
+	}
+
+	tokens := manager.Tokens()
+	if len(tokens) != 2 || tokens[0].Kind != types.SessionTokenInvite || tokens[0].IssuedTo != "Guest" {
+		t.Fatalf("unexpected outstanding tokens %+v", tokens)
+	}
+
+	invite, _ := manager.parseInvite(token)
+	if err := manager.RevokeToken(types.SessionTokenInvite, invite.Nonce); err != nil {
+		t.Fatalf("could not revoke invite %s", err.Error())
+	}
+
+	if _, _, err := manager.RedeemInvite(token); !errors.Is(err, types.ErrSessionInviteRevoked) {
+		t.Fatalf("expected revoked invite to be rejected, got %v", err)
+	}
+	if err := manager.RevokeToken(types.SessionTokenInvite, invite.Nonce); !errors.Is(err, types.ErrSessionTokenNotFound) {
+		t.Fatalf("expected revoked invite to be gone, got %v", err)
+	}
+
+	// other invites are not affected, redeemed ones are no longer listed
+	if _, _, err := manager.RedeemInvite(kept); err != nil {
+		t.Fatalf("could not redeem invite %s", err.Error())
+	}
+	if tokens := manager.Tokens(); len(tokens) != 0 {
+		t.Fatalf("expected no outstanding tokens, got %+v", tokens)
+	}
+}
+
+func TestRevokedReconnectTokenRejected(t *testing.T) {
+	manager := New(&config.Session{
+		ReconnectTokenTTL: time.Minute,
+	})
+
+	session, _, err := manager.Create("test", types.MemberProfile{
+		CanLogin:   true,
+		CanConnect: true,
+	})
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+
+	session.ConnectWebSocketPeer(&testWebSocketPeer{})
+	token := session.(*SessionCtx).ReconnectToken()
+
+	tokens := manager.Tokens()
+	if len(tokens) != 1 || tokens[0].Kind != types.SessionTokenReconnect || tokens[0].ID != "test" {
+		t.Fatalf("unexpected outstanding tokens %+v", tokens)
+	}
+
+	if err := manager.RevokeToken(types.SessionTokenReconnect, "test"); err != nil {
+		t.Fatalf("could not revoke reconnect token %s", err.Error())
+	}
+
+	if _, err := manager.Resume(token); !errors.Is(err, types.ErrSessionReconnectTokenInvalid) {
+		t.Fatalf("expected revoked reconnect token to be rejected, got %v", err)
+	}
+	if err := manager.RevokeToken(types.SessionTokenReconnect, "test"); !errors.Is(err, types.ErrSessionTokenNotFound) {
+		t.Fatalf("expected revoked reconnect token to be gone, got %v", err)
+	}
+}
+
+func TestJoinApproval(t *testing.T) {
+	manager := New(&config.Session{
+		JoinApproval: true,
+	})
+
+	profile := types.MemberProfile{
+		CanLogin:   true,
+		CanConnect: true,
+		CanWatch:   true,
+		CanHost:    true,
+	}
+
+	approved, _, err := manager.Create("approved", profile)
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+
+	approved.ConnectWebSocketPeer(&testWebSocketPeer{})
+	if !approved.State().IsPending {
+		t.Fatal("session is not pending after connect")
+	}
+
+	if approved.Profile().CanHost || approved.Profile().Plugins["chat.can_send"] != false {
+		t.Fatalf("pending session is not restricted %+v", approved.Profile())
+	}
+
+	var changed types.MemberProfile
+	manager.OnProfileChanged(func(session types.Session, new, old types.MemberProfile) {
+		changed = new
+	})
+
+	if err := manager.Approve("approved"); err != nil {
+		t.Fatalf("could not approve session %s", err.Error())
+	}
+
+	if approved.State().IsPending || !approved.Profile().CanHost || !changed.CanHost {
+		t.Fatalf("approved session is still restricted %+v", approved.Profile())
+	}
+
+	if err := manager.Approve("approved"); !errors.Is(err, types.ErrSessionNotPending) {
+		t.Fatalf("expected approved session not to be pending, got %v", err)
+	}
+
+	denied, _, err := manager.Create("denied", profile)
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+
+	peer := &recordingWebSocketPeer{}
+	denied.ConnectWebSocketPeer(peer)
+
+	if err := manager.Deny("denied"); err != nil {
+		t.Fatalf("could not deny session %s", err.Error())
+	}
+
+	if !peer.isDestroyed() || denied.State().IsConnected {
+		t.Fatal("denied session is still connected")
+	}
+
+	// admins are never pending
+	admin, _, err := manager.Create("admin", types.MemberProfile{
+		IsAdmin:    true,
+		CanLogin:   true,
+		CanConnect: true,
+	})
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+
+	admin.ConnectWebSocketPeer(&testWebSocketPeer{})
+	if admin.State().IsPending {
+		t.Fatal("admin session is pending")
+	}
+}
+
+func TestConsent(t *testing.T) {
+	manager := New(&config.Session{
+		ConsentText:    "terms",
+		ConsentTimeout: 50 * time.Millisecond,
+	})
+
+	profile := types.MemberProfile{
+		CanLogin:   true,
+		CanConnect: true,
+		CanWatch:   true,
+		CanHost:    true,
+	}
+
+	session, _, err := manager.Create("consent", profile)
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+
+	first := &testWebSocketPeer{}
+	session.ConnectWebSocketPeer(first)
+	if session.State().IsAdmitted {
+		t.Fatal("session is admitted before consent")
+	}
+
+	if session.Profile().CanWatch || session.Profile().CanHost {
+		t.Fatalf("session is not restricted before consent %+v", session.Profile())
+	}
+
+	if err := manager.AcknowledgeConsent("consent"); err != nil {
+		t.Fatalf("could not acknowledge consent %s", err.Error())
+	}
+
+	if !session.State().IsAdmitted || !session.Profile().CanWatch {
+		t.Fatalf("session is restricted after consent %+v", session.Profile())
+	}
+
+	if err := manager.AcknowledgeConsent("consent"); !errors.Is(err, types.ErrSessionAdmitted) {
+		t.Fatalf("expected session to be admitted, got %v", err)
+	}
+
+	// consent is given once for the session
+	session.DisconnectWebSocketPeer(first, false)
+	session.ConnectWebSocketPeer(&testWebSocketPeer{})
+	if !session.State().IsAdmitted {
+		t.Fatal("session is not admitted after reconnect")
+	}
+
+	// unacknowledged session is disconnected after timeout
+	waiting, _, err := manager.Create("waiting", profile)
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+
+	peer := &recordingWebSocketPeer{}
+	waiting.ConnectWebSocketPeer(peer)
+
+	deadline := time.Now().Add(time.Second)
+	for !peer.isDestroyed() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if !peer.isDestroyed() || waiting.State().IsConnected {
+		t.Fatal("unacknowledged session is still connected")
+	}
+}
+
+// Session that never connects must not bypass consent over REST
+func TestConsentRestrictsREST(t *testing.T) {
+	manager := New(&config.Session{
+		ConsentText: "terms",
+		APIToken:    "api",
+	})
+
+	_, token, err := manager.Create("rest", types.MemberProfile{
+		CanLogin:   true,
+		CanConnect: true,
+		CanWatch:   true,
+	})
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+
+	r := &http.Request{Header: http.Header{}}
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	session, err := manager.Authenticate(r)
+	if err != nil {
+		t.Fatalf("could not authenticate %s", err.Error())
+	}
+
+	r = r.WithContext(auth.SetSession(r, session))
+	if _, err := auth.CanWatchOnly(nil, r); err == nil {
+		t.Error("session can watch over REST before consent")
+	}
+
+	// API session is not restricted
+	r.Header.Set("Authorization", "Bearer api")
+	if session, err = manager.Authenticate(r); err != nil {
+		t.Fatalf("could not authenticate %s", err.Error())
+	}
+
+	r = r.WithContext(auth.SetSession(r, session))
+	if _, err := auth.CanWatchOnly(nil, r); err != nil {
+		t.Errorf("API session cannot watch: %v", err)
+	}
+}
+
+func TestConsentDisabled(t *testing.T) {
+	manager := New(&config.Session{})
+
+	session, _, err := manager.Create("test", types.MemberProfile{
+		CanLogin:   true,
+		CanConnect: true,
+		CanWatch:   true,
+	})
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+
+	session.ConnectWebSocketPeer(&testWebSocketPeer{})
+	if !session.State().IsAdmitted || !session.Profile().CanWatch {
+		t.Fatal("session is not admitted without consent")
+	}
+}
+
+func TestHandoffRestoresSession(t *testing.T) {
+	source := New(&config.Session{
+		HandoffSecret: "shared",
+		HandoffTTL:    time.Minute,
+	})
+	target := New(&config.Session{
+		HandoffSecret: "shared",
+		HandoffTTL:    time.Minute,
+	})
+
+	session, _, err := source.Create("moving", types.MemberProfile{
+		Name:       "Mover",
+		CanLogin:   true,
+		CanConnect: true,
+		CanHost:    true,
+	})
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+	session.SetAsHost()
+
+	token, err := source.CreateHandoff("moving")
+	if err != nil {
+		t.Fatalf("could not create handoff %s", err.Error())
+	}
+
+	restored, restoredToken, err := target.RedeemHandoff(token)
+	if err != nil {
+		t.Fatalf("could not redeem handoff %s", err.Error())
+	}
+
+	if restored.ID() != "moving" || restored.Profile().Name != "Mover" || !restored.IsHost() {
+		t.Fatalf("session was not restored, id %s profile %+v host %v", restored.ID(), restored.Profile(), restored.IsHost())
+	}
+
+	if s, ok := target.GetByToken(restoredToken); !ok || s.ID() != "moving" {
+		t.Fatal("restored session is not reachable by its token")
+	}
+
+	if _, _, err := target.RedeemHandoff(token); !errors.Is(err, types.ErrSessionHandoffUsed) {
+		t.Fatalf("expected reused handoff to be rejected, got %v", err)
+	}
+
+	// token signed by another secret
+	other := New(&config.Session{
+		HandoffSecret: "other",
+		HandoffTTL:    time.Minute,
+	})
+	if _, _, err := other.RedeemHandoff(token); !errors.Is(err, types.ErrSessionHandoffInvalid) {
+		t.Fatalf("expected foreign handoff to be rejected, got %v", err)
+	}
+}
+
+func TestRejoinChangesOnlyWhenResumed(t *testing.T) {
+	manager := New(&config.Session{
+		RejoinChanges:     true,
+		ReconnectTokenTTL: time.Minute,
+	})
+
+	profile := types.MemberProfile{
+		CanLogin:   true,
+		CanConnect: true,
+	}
+
+	session, _, err := manager.Create("test", profile)
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+
+	other, _, err := manager.Create("other", profile)
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+	otherPeer := &testWebSocketPeer{}
+
+	// session left on purpose and joined again
+	peer := &testWebSocketPeer{}
+	session.ConnectWebSocketPeer(peer)
+	session.DisconnectWebSocketPeer(peer, false)
+	other.ConnectWebSocketPeer(otherPeer)
+
+	peer = &testWebSocketPeer{}
+	session.ConnectWebSocketPeer(peer)
+	if changes := session.ChangesWhileAway(); len(changes) != 0 {
+		t.Errorf("session has %d changes after joining again, expected none", len(changes))
+	}
+
+	// delayed disconnect elapsed, session logged in again without resuming
+	sessionCtx := session.(*SessionCtx)
+	sessionCtx.disconnectWebSocketPeer(peer, false, true)
+	other.DisconnectWebSocketPeer(otherPeer, false)
+
+	peer = &testWebSocketPeer{}
+	session.ConnectWebSocketPeer(peer)
+	if changes := session.ChangesWhileAway(); len(changes) != 0 {
+		t.Errorf("session has %d changes after logging in again, expected none", len(changes))
+	}
+
+	// delayed disconnect elapsed, session resumed using its token
+	token := session.ReconnectToken()
+	sessionCtx.disconnectWebSocketPeer(peer, false, true)
+	other.ConnectWebSocketPeer(&testWebSocketPeer{})
+
+	if _, err := manager.Resume(token); err != nil {
+		t.Fatalf("could not resume session %s", err.Error())
+	}
+	session.ConnectWebSocketPeer(&testWebSocketPeer{})
+
+	changes := session.ChangesWhileAway()
+	if len(changes) != 1 || changes[0].Kind != types.SessionChangeJoined {
+		t.Errorf("unexpected changes after resume %+v", changes)
+	}
+}
+
+func TestInvitePrunedOnExpiry(t *testing.T) {
+	manager := New(&config.Session{
+		InviteSecret: "secret",
+		InviteTTL:    time.Hour,
+	})
+
+	if _, _, err := manager.CreateInvite(types.MemberProfile{CanLogin: true}, 10*time.Millisecond); err != nil {
+		t.Fatalf("could not create invite %s", err.Error())
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	// issuing another invite forgets the expired one, without listing tokens
+	if _, _, err := manager.CreateInvite(types.MemberProfile{CanLogin: true}, 0); err != nil {
+		t.Fatalf("could not create invite %s", err.Error())
+	}
+
+	manager.invitesMu.Lock()
+	outstanding := len(manager.invites)
+	manager.invitesMu.Unlock()
+
+	if outstanding != 1 {
+		t.Errorf("%d invites are remembered, want only the valid one", outstanding)
+	}
+}
@@ -0,0 +1,65 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// signed tokens are base64 encoded JSON payload and its HMAC-SHA256
+// signature separated by a dot
+
+func tokenSignature(secret, data string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signToken(secret string, payload any) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+	return encoded + "." + tokenSignature(secret, encoded), nil
+}
+
+// verifyToken decodes payload of the token, false if its signature or payload is invalid.
+func verifyToken(secret, token string, payload any) bool {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(tokenSignature(secret, encoded))) {
+		return false
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+
+	return json.Unmarshal(data, payload) == nil
+}
+
+// useNonce marks nonce of a one-time token as used, false if it was used already.
+func (manager *SessionManagerCtx) useNonce(nonce string, expires time.Time) bool {
+	manager.noncesUsedMu.Lock()
+	defer manager.noncesUsedMu.Unlock()
+
+	// expired tokens are rejected anyway, no need to remember them
+	now := time.Now()
+	for nonce, expires := range manager.noncesUsed {
+		if now.After(expires) {
+			delete(manager.noncesUsed, nonce)
+		}
+	}
+
+	if _, ok := manager.noncesUsed[nonce]; ok {
+		return false
+	}
+
+	manager.noncesUsed[nonce] = expires
+	return true
+}
@@ -0,0 +1,152 @@
+package session
+
+import (
+	"sort"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// inviteRecord is an invite that was issued and not redeemed yet. Records
+// are kept in memory only, invites issued before restart are not listed.
+type inviteRecord struct {
+	issued  time.Time
+	expires time.Time
+	profile types.MemberProfile
+}
+
+// pruneInvites forgets expired invites, they are rejected anyway. Must be
+// called with invites lock held.
+func (manager *SessionManagerCtx) pruneInvites(now time.Time) {
+	for nonce, invite := range manager.invites {
+		if now.After(invite.expires) {
+			delete(manager.invites, nonce)
+		}
+	}
+
+	for nonce, expires := range manager.invitesRevoked {
+		if now.After(expires) {
+			delete(manager.invitesRevoked, nonce)
+		}
+	}
+}
+
+// claimInvite removes the invite from outstanding ones before it is redeemed,
+// false if it was revoked.
+func (manager *SessionManagerCtx) claimInvite(nonce string) bool {
+	manager.invitesMu.Lock()
+	defer manager.invitesMu.Unlock()
+
+	if _, ok := manager.invitesRevoked[nonce]; ok {
+		return false
+	}
+
+	delete(manager.invites, nonce)
+	return true
+}
+
+// Tokens lists outstanding invites and reconnect tokens, ordered by issue time.
+func (manager *SessionManagerCtx) Tokens() []types.SessionToken {
+	tokens := []types.SessionToken{}
+	now := time.Now()
+
+	manager.invitesMu.Lock()
+	manager.pruneInvites(now)
+	for nonce, invite := range manager.invites {
+		expires, profile := invite.expires, invite.profile
+		tokens = append(tokens, types.SessionToken{
+			ID:       nonce,
+			Kind:     types.SessionTokenInvite,
+			IssuedTo: profile.Name,
+			IssuedAt: invite.issued,
+			Expires:  &expires,
+			Profile:  &profile,
+		})
+	}
+	manager.invitesMu.Unlock()
+
+	manager.sessionsMu.Lock()
+	sessions := make([]*SessionCtx, 0, len(manager.sessions))
+	for _, session := range manager.sessions {
+		sessions = append(sessions, session)
+	}
+	manager.sessionsMu.Unlock()
+
+	manager.reconnectMu.Lock()
+	for _, session := range sessions {
+		expired := !session.reconnectExpires.IsZero() && now.After(session.reconnectExpires)
+		if session.reconnectToken == "" || expired {
+			continue
+		}
+
+		token := types.SessionToken{
+			ID:       session.id,
+			Kind:     types.SessionTokenReconnect,
+			IssuedTo: session.id,
+			IssuedAt: session.reconnectIssued,
+		}
+		if !session.reconnectExpires.IsZero() {
+			expires := session.reconnectExpires
+			token.Expires = &expires
+		}
+
+		tokens = append(tokens, token)
+	}
+	manager.reconnectMu.Unlock()
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].IssuedAt.Before(tokens[j].IssuedAt)
+	})
+
+	return tokens
+}
+
+// RevokeToken invalidates outstanding token, so that its future use is rejected.
+func (manager *SessionManagerCtx) RevokeToken(kind types.SessionTokenKind, id string) error {
+	switch kind {
+	case types.SessionTokenInvite:
+		manager.invitesMu.Lock()
+		invite, ok := manager.invites[id]
+		if ok {
+			delete(manager.invites, id)
+			manager.invitesRevoked[id] = invite.expires
+		}
+		manager.pruneInvites(time.Now())
+		manager.invitesMu.Unlock()
+
+		if !ok {
+			return types.ErrSessionTokenNotFound
+		}
+	case types.SessionTokenReconnect:
+		session, ok := manager.sessionWithReconnectToken(id)
+		if !ok {
+			return types.ErrSessionTokenNotFound
+		}
+
+		manager.revokeReconnectToken(session)
+	default:
+		return types.ErrSessionTokenNotFound
+	}
+
+	manager.logger.Info().
+		Str("kind", string(kind)).
+		Str("token_id", id).
+		Msg("token revoked")
+
+	return nil
+}
+
+func (manager *SessionManagerCtx) sessionWithReconnectToken(id string) (*SessionCtx, bool) {
+	manager.sessionsMu.Lock()
+	session, ok := manager.sessions[id]
+	manager.sessionsMu.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	manager.reconnectMu.Lock()
+	defer manager.reconnectMu.Unlock()
+
+	return session, session.reconnectToken != ""
+}
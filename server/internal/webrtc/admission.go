@@ -0,0 +1,30 @@
+package webrtc
+
+import (
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// admit returns error if video pipelines are overloaded and new peer must
+// not be created, load of pipelines not running yet is unknown.
+func (manager *WebRTCManagerCtx) admit(logger zerolog.Logger) error {
+	maxLoad := manager.config.AdmissionMaxLoad
+	if maxLoad <= 0 {
+		return nil
+	}
+
+	for id, load := range manager.capture.VideoLoad() {
+		if load.Load > maxLoad {
+			logger.Warn().
+				Str("video_id", id).
+				Float64("load", load.Load).
+				Float64("max_load", maxLoad).
+				Msg("video pipeline is overloaded, rejecting new peer")
+			manager.admissionRejected.Inc()
+			return types.ErrWebRTCServerBusy
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package webrtc
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// seconds between NTP (1900) and unix (1970) epoch
+const ntpEpochOffset = 2208988800
+
+// playout delays are rounded, so that small changes are not sent to clients
+const playoutDelayStep = 20 * time.Millisecond
+
+// trackSync measures delays of a track, that are needed to estimate audio/video
+// offset. Audio and video are captured by separate pipelines, so their delays
+// from capture until sent differ.
+type trackSync struct {
+	// smoothed time from capture until sent, in nanoseconds
+	latency atomic.Int64
+	// round trip time from the last receiver report, in nanoseconds
+	rtt atomic.Int64
+}
+
+// sent records delay of sample written at now, it is called from one goroutine.
+func (s *trackSync) sent(sample types.Sample, now time.Time) {
+	latency := int64(sample.Latency + now.Sub(sample.Timestamp))
+
+	old := s.latency.Load()
+	if old == 0 {
+		s.latency.Store(latency)
+		return
+	}
+
+	s.latency.Store(old + (latency-old)/8)
+}
+
+// onReport computes round trip time from the last sender report referenced by
+// the receiver report (RFC 3550 section 6.4.1).
+func (s *trackSync) onReport(report rtcp.ReceptionReport, now time.Time) {
+	if report.LastSenderReport == 0 {
+		return
+	}
+
+	rtt := ntpMiddle(now) - report.LastSenderReport - report.Delay
+
+	// wrapped around, report is older than the sender report
+	if rtt > 1<<20 {
+		return
+	}
+
+	s.rtt.Store(int64(time.Duration(rtt) * time.Second / (1 << 16)))
+}
+
+// ntpMiddle returns middle 32 bits of NTP timestamp, as used in reports.
+func ntpMiddle(t time.Time) uint32 {
+	seconds := uint64(t.Unix()) + ntpEpochOffset
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return uint32(seconds<<16 | fraction>>16)
+}
+
+func avSyncStats(audio, video *trackSync) (types.PeerAVSync, bool) {
+	audioLatency := time.Duration(audio.latency.Load())
+	videoLatency := time.Duration(video.latency.Load())
+	if audioLatency == 0 || videoLatency == 0 {
+		return types.PeerAVSync{}, false
+	}
+
+	audioRTT := time.Duration(audio.rtt.Load())
+	videoRTT := time.Duration(video.rtt.Load())
+
+	// media share the transport, so both take about half of the round trip
+	offset := (audioLatency + audioRTT/2) - (videoLatency + videoRTT/2)
+
+	return types.PeerAVSync{
+		Offset:       offset.Milliseconds(),
+		AudioLatency: audioLatency.Milliseconds(),
+		VideoLatency: videoLatency.Milliseconds(),
+		AudioRTT:     audioRTT.Milliseconds(),
+		VideoRTT:     videoRTT.Milliseconds(),
+	}, true
+}
+
+// playoutDelay delays media that is played early by the offset, so that the
+// client plays audio and video in sync. Offsets below threshold are ignored.
+func playoutDelay(stats types.PeerAVSync, threshold time.Duration) message.SignalPlayoutDelay {
+	offset := (time.Duration(stats.Offset) * time.Millisecond).Round(playoutDelayStep)
+	if offset.Abs() < threshold {
+		return message.SignalPlayoutDelay{}
+	}
+
+	if offset > 0 {
+		return message.SignalPlayoutDelay{Video: offset.Milliseconds()}
+	}
+	return message.SignalPlayoutDelay{Audio: -offset.Milliseconds()}
+}
+
+// AVSync estimates how much audio is played late compared to video.
+func (peer *WebRTCPeerCtx) AVSync() (types.PeerAVSync, bool) {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	if peer.audioTrack == nil || peer.videoTrack == nil {
+		return types.PeerAVSync{}, false
+	}
+
+	if peer.audioTrack.Paused() || peer.videoTrack.Paused() {
+		return types.PeerAVSync{}, false
+	}
+
+	return avSyncStats(&peer.audioTrack.sync, &peer.videoTrack.sync)
+}
+
+// avSyncMonitor periodically estimates audio/video offset of connected peer,
+// logs when it gets out of sync and optionally sends playout delays to client.
+func (manager *WebRTCManagerCtx) avSyncMonitor(peer *WebRTCPeerCtx) {
+	ticker := time.NewTicker(manager.config.AVSync.Interval)
+	defer ticker.Stop()
+
+	threshold := manager.config.AVSync.Threshold
+	outOfSync := false
+	delay := message.SignalPlayoutDelay{}
+
+	for {
+		select {
+		case <-peer.closed:
+			return
+		case <-ticker.C:
+		}
+
+		if peer.connection.ConnectionState() != webrtc.PeerConnectionStateConnected {
+			continue
+		}
+
+		stats, ok := peer.AVSync()
+		if !ok {
+			continue
+		}
+
+		manager.avSyncOffset.Observe(float64(stats.Offset) / 1000)
+
+		offset := time.Duration(stats.Offset) * time.Millisecond
+		if exceeded := offset.Abs() >= threshold; exceeded != outOfSync {
+			outOfSync = exceeded
+
+			logger := peer.logger.Info()
+			if exceeded {
+				logger = peer.logger.Warn()
+			}
+			logger.
+				Dur("offset", offset).
+				Int64("audio_latency", stats.AudioLatency).
+				Int64("video_latency", stats.VideoLatency).
+				Bool("out_of_sync", exceeded).
+				Msg("audio/video sync changed")
+		}
+
+		if !manager.config.AVSync.PlayoutHints {
+			continue
+		}
+
+		if hint := playoutDelay(stats, threshold); hint != delay {
+			delay = hint
+			peer.session.Send(event.SIGNAL_PLAYOUT_DELAY, delay)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+func TestTrackSyncLatency(t *testing.T) {
+	now := time.Now()
+	s := trackSync{}
+
+	s.sent(types.Sample{Timestamp: now.Add(-10 * time.Millisecond), Latency: 30 * time.Millisecond}, now)
+	if got := time.Duration(s.latency.Load()); got != 40*time.Millisecond {
+		t.Errorf("latency = %v, want 40ms", got)
+	}
+
+	// smoothed towards new value
+	s.sent(types.Sample{Timestamp: now, Latency: 120 * time.Millisecond}, now)
+	if got := time.Duration(s.latency.Load()); got != 50*time.Millisecond {
+		t.Errorf("smoothed latency = %v, want 50ms", got)
+	}
+}
+
+func TestTrackSyncRTT(t *testing.T) {
+	now := time.Now()
+	s := trackSync{}
+
+	// without sender report, round trip is unknown
+	s.onReport(rtcp.ReceptionReport{}, now)
+	if s.rtt.Load() != 0 {
+		t.Errorf("rtt without sender report = %d, want 0", s.rtt.Load())
+	}
+
+	// sender report sent 150ms ago, receiver held it for 50ms
+	s.onReport(rtcp.ReceptionReport{
+		LastSenderReport: ntpMiddle(now.Add(-150 * time.Millisecond)),
+		Delay:            1 << 16 / 20,
+	}, now)
+	if got := time.Duration(s.rtt.Load()); got < 99*time.Millisecond || got > 101*time.Millisecond {
+		t.Errorf("rtt = %v, want 100ms", got)
+	}
+}
+
+func TestAVSyncStats(t *testing.T) {
+	audio, video := trackSync{}, trackSync{}
+
+	if _, ok := avSyncStats(&audio, &video); ok {
+		t.Errorf("avSyncStats() without samples is available")
+	}
+
+	audio.latency.Store(int64(150 * time.Millisecond))
+	audio.rtt.Store(int64(40 * time.Millisecond))
+	video.latency.Store(int64(60 * time.Millisecond))
+	video.rtt.Store(int64(20 * time.Millisecond))
+
+	stats, ok := avSyncStats(&audio, &video)
+	if !ok {
+		t.Fatalf("avSyncStats() is not available")
+	}
+	if stats.Offset != 100 {
+		t.Errorf("offset = %dms, want 100ms", stats.Offset)
+	}
+}
+
+func TestPlayoutDelay(t *testing.T) {
+	tests := []struct {
+		offset int64
+		want   message.SignalPlayoutDelay
+	}{
+		{offset: 30, want: message.SignalPlayoutDelay{}},
+		{offset: 107, want: message.SignalPlayoutDelay{Video: 100}},
+		{offset: -135, want: message.SignalPlayoutDelay{Audio: 140}},
+	}
+
+	for _, tt := range tests {
+		got := playoutDelay(types.PeerAVSync{Offset: tt.offset}, 80*time.Millisecond)
+		if got != tt.want {
+			t.Errorf("playoutDelay(%d) = %+v, want %+v", tt.offset, got, tt.want)
+		}
+	}
+}
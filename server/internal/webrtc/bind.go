@@ -0,0 +1,57 @@
+package webrtc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// upper limit of delay between mux bind retries
+const muxBindMaxBackoff = 30 * time.Second
+
+// bindMux calls bind with the configured port until it succeeds or retries are
+// exhausted. If fallback is enabled, it finally binds to an ephemeral port (0).
+// Returned error is meant to be shown to the user as is.
+func bindMux(logger zerolog.Logger, name string, port int, retries int, backoff time.Duration, fallback bool, bind func(port int) error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = bind(port); err == nil {
+			return nil
+		}
+
+		if attempt >= retries {
+			break
+		}
+
+		logger.Warn().Err(err).
+			Int("port", port).
+			Int("attempt", attempt+1).
+			Int("retries", retries).
+			Dur("backoff", backoff).
+			Msgf("unable to bind %s mux port, retrying", name)
+
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > muxBindMaxBackoff {
+			backoff = muxBindMaxBackoff
+		}
+	}
+
+	if fallback {
+		logger.Warn().Err(err).
+			Int("port", port).
+			Msgf("unable to bind %s mux port, falling back to ephemeral port", name)
+
+		if err := bind(0); err != nil {
+			return fmt.Errorf("unable to bind %s mux to ephemeral port: %w", name, err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unable to bind %s mux port %d after %d retries, "+
+		"make sure no other process (e.g. previous instance) uses it, "+
+		"choose a different port with --webrtc.%smux or enable --webrtc.mux_bind_fallback: %w",
+		name, port, retries, name, err)
+}
@@ -0,0 +1,67 @@
+package webrtc
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var errPortInUse = errors.New("address already in use")
+
+// bind fails until given number of failures is reached
+func failingBind(failures int, ports *[]int) func(port int) error {
+	return func(port int) error {
+		*ports = append(*ports, port)
+		if len(*ports) <= failures {
+			return errPortInUse
+		}
+		return nil
+	}
+}
+
+func TestBindMuxRetry(t *testing.T) {
+	var ports []int
+	err := bindMux(zerolog.Nop(), "udp", 59000, 3, time.Millisecond, false, failingBind(2, &ports))
+	if err != nil {
+		t.Fatalf("bindMux() = %v", err)
+	}
+
+	if !reflect.DeepEqual(ports, []int{59000, 59000, 59000}) {
+		t.Errorf("bound ports %v, want configured port retried", ports)
+	}
+}
+
+func TestBindMuxExhausted(t *testing.T) {
+	var ports []int
+	err := bindMux(zerolog.Nop(), "tcp", 59000, 2, time.Millisecond, false, failingBind(10, &ports))
+
+	if !errors.Is(err, errPortInUse) || !strings.Contains(err.Error(), "--webrtc.tcpmux") {
+		t.Errorf("bindMux() = %v, want wrapped error with hint", err)
+	}
+	if len(ports) != 3 {
+		t.Errorf("bind called %d times, want initial attempt and 2 retries", len(ports))
+	}
+}
+
+func TestBindMuxFallback(t *testing.T) {
+	var ports []int
+	err := bindMux(zerolog.Nop(), "udp", 59000, 1, time.Millisecond, true, failingBind(2, &ports))
+	if err != nil {
+		t.Fatalf("bindMux() = %v", err)
+	}
+
+	if !reflect.DeepEqual(ports, []int{59000, 59000, 0}) {
+		t.Errorf("bound ports %v, want ephemeral port last", ports)
+	}
+
+	// fallback can fail as well
+	ports = nil
+	err = bindMux(zerolog.Nop(), "udp", 59000, 0, time.Millisecond, true, failingBind(10, &ports))
+	if !errors.Is(err, errPortInUse) {
+		t.Errorf("bindMux() = %v, want %v", err, errPortInUse)
+	}
+}
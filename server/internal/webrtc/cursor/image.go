@@ -30,6 +30,7 @@ type imageEntry struct {
 type image struct {
 	logger  zerolog.Logger
 	desktop types.DesktopManager
+	errors  types.ErrorBus
 
 	listeners   map[uintptr]ImageListener
 	listenersMu sync.RWMutex
@@ -40,10 +41,11 @@ type image struct {
 	maxSerial uint64
 }
 
-func NewImage(logger zerolog.Logger, desktop types.DesktopManager) *image {
+func NewImage(logger zerolog.Logger, desktop types.DesktopManager, errors types.ErrorBus) *image {
 	return &image{
 		logger:    logger.With().Str("submodule", "cursor-image").Logger(),
 		desktop:   desktop,
+		errors:    errors,
 		listeners: map[uintptr]ImageListener{},
 		cache:     map[uint64]*imageEntry{},
 		maxSerial: 300, // TODO: Cleanup?
@@ -55,6 +57,7 @@ func (manager *image) Start() {
 		entry, err := manager.getCached(serial)
 		if err != nil {
 			manager.logger.Err(err).Msg("failed to get cursor image")
+			manager.errors.Report("webrtc", "cursor_image_get", nil, err)
 			return
 		}
 
@@ -64,6 +67,7 @@ func (manager *image) Start() {
 		for _, l := range manager.listeners {
 			if err := l.SendCursorImage(entry.CursorImage, entry.ImagePNG); err != nil {
 				manager.logger.Err(err).Msg("failed to set cursor image")
+				manager.errors.Report("webrtc", "cursor_image_send", nil, err)
 			}
 		}
 		manager.listenersMu.RUnlock()
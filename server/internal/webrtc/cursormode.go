@@ -0,0 +1,93 @@
+package webrtc
+
+import (
+	"fmt"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// SetCursorMode sets which cursor updates are sent over data channel, clients
+// not needing cursor shape can save bandwidth and render default cursor.
+func (peer *WebRTCPeerCtx) SetCursorMode(mode types.CursorMode) error {
+	peer.mu.Lock()
+
+	if peer.dataOnly {
+		peer.mu.Unlock()
+		return types.ErrWebRTCDataOnly
+	}
+
+	switch mode {
+	case types.CursorModeFull, types.CursorModePosition, types.CursorModeNone:
+	default:
+		peer.mu.Unlock()
+		return fmt.Errorf("unknown cursor mode %q", mode)
+	}
+
+	changed := peer.cursorMode != mode
+	peer.cursorMode = mode
+	apply := peer.cursorApply
+	peer.mu.Unlock()
+
+	if !changed {
+		return nil
+	}
+
+	peer.logger.Info().Str("mode", string(mode)).Msg("set cursor mode")
+
+	// listeners are registered once data channel is open
+	if apply != nil {
+		apply()
+	}
+
+	return nil
+}
+
+func (peer *WebRTCPeerCtx) CursorMode() types.CursorMode {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	// data only peers do not receive cursor
+	if peer.dataOnly {
+		return types.CursorModeNone
+	}
+
+	return peer.cursorMode
+}
+
+// applyCursorMode registers cursor listeners of the peer according to its
+// cursor mode and sends current cursor, that it did not receive until now.
+func (manager *WebRTCManagerCtx) applyCursorMode(peer *WebRTCPeerCtx) {
+	mode := peer.CursorMode()
+
+	if mode == types.CursorModeFull {
+		manager.curImage.AddListener(peer)
+
+		// send initial cursor image
+		cur, img, err := manager.curImage.GetCurrent()
+		if err == nil {
+			err := peer.SendCursorImage(cur, img)
+			if err != nil {
+				peer.logger.Err(err).Msg("failed to set cursor image")
+				manager.errors.Report("webrtc", "cursor_image_send", peer.session, err)
+			}
+		} else {
+			peer.logger.Err(err).Msg("failed to get cursor image")
+			manager.errors.Report("webrtc", "cursor_image_get", peer.session, err)
+		}
+	} else {
+		manager.curImage.RemoveListener(peer)
+	}
+
+	if mode != types.CursorModeNone {
+		manager.curPosition.AddListener(peer)
+
+		// send initial cursor position
+		x, y := manager.desktop.GetCursorPosition()
+		err := peer.SendCursorPosition(x, y)
+		if err != nil {
+			peer.logger.Err(err).Msg("failed to set cursor position")
+		}
+	} else {
+		manager.curPosition.RemoveListener(peer)
+	}
+}
@@ -0,0 +1,58 @@
+package webrtc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+func TestCursorMode(t *testing.T) {
+	applied := 0
+	peer := &WebRTCPeerCtx{
+		logger:      zerolog.Nop(),
+		cursorMode:  types.CursorModeFull,
+		cursorApply: func() { applied++ },
+	}
+
+	if err := peer.SetCursorMode(types.CursorModePosition); err != nil {
+		t.Fatalf("SetCursorMode() = %v", err)
+	}
+	if mode := peer.CursorMode(); mode != types.CursorModePosition {
+		t.Errorf("CursorMode() = %q, want %q", mode, types.CursorModePosition)
+	}
+	if applied != 1 {
+		t.Errorf("cursor mode applied %d times, want 1", applied)
+	}
+
+	// unchanged mode is not applied again
+	if err := peer.SetCursorMode(types.CursorModePosition); err != nil {
+		t.Fatalf("SetCursorMode() = %v", err)
+	}
+	if applied != 1 {
+		t.Errorf("cursor mode applied %d times, want 1", applied)
+	}
+
+	if err := peer.SetCursorMode("shape"); err == nil {
+		t.Error("SetCursorMode() accepted unknown mode")
+	}
+	if mode := peer.CursorMode(); mode != types.CursorModePosition {
+		t.Errorf("CursorMode() = %q after unknown mode, want %q", mode, types.CursorModePosition)
+	}
+}
+
+func TestCursorModeDataOnly(t *testing.T) {
+	peer := &WebRTCPeerCtx{
+		logger:   zerolog.Nop(),
+		dataOnly: true,
+	}
+
+	if err := peer.SetCursorMode(types.CursorModeFull); !errors.Is(err, types.ErrWebRTCDataOnly) {
+		t.Errorf("SetCursorMode() = %v, want %v", err, types.ErrWebRTCDataOnly)
+	}
+	if mode := peer.CursorMode(); mode != types.CursorModeNone {
+		t.Errorf("CursorMode() = %q, want %q", mode, types.CursorModeNone)
+	}
+}
@@ -0,0 +1,59 @@
+package webrtc
+
+import (
+	"fmt"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// labels of data channels handled by the server itself
+var reservedDataChannelLabels = []string{
+	"data",
+	diagnosticsDataChannelLabel,
+}
+
+// AddDataChannelHandler claims data channels opened by clients with the label,
+// every label can be claimed only once.
+func (manager *WebRTCManagerCtx) AddDataChannelHandler(label string, handler types.WebRTCDataChannelHandler) error {
+	for _, reserved := range reservedDataChannelLabels {
+		if label == reserved {
+			return fmt.Errorf("%w: %s is reserved", types.ErrWebRTCDataChannelClaimed, label)
+		}
+	}
+
+	manager.dataChannelHandlersMu.Lock()
+	defer manager.dataChannelHandlersMu.Unlock()
+
+	if _, ok := manager.dataChannelHandlers[label]; ok {
+		return fmt.Errorf("%w: %s", types.ErrWebRTCDataChannelClaimed, label)
+	}
+
+	manager.dataChannelHandlers[label] = handler
+	return nil
+}
+
+func (manager *WebRTCManagerCtx) dataChannelHandler(label string) (types.WebRTCDataChannelHandler, bool) {
+	manager.dataChannelHandlersMu.Lock()
+	defer manager.dataChannelHandlersMu.Unlock()
+
+	handler, ok := manager.dataChannelHandlers[label]
+	return handler, ok
+}
+
+// handleUnknownDataChannel applies configured behavior to data channel, that
+// no handler claimed.
+func (manager *WebRTCManagerCtx) handleUnknownDataChannel(logger zerolog.Logger, dc *webrtc.DataChannel) {
+	if manager.config.UnknownDataChannels != config.UnknownDataChannelsClose {
+		logger.Debug().Str("label", dc.Label()).Msg("ignoring unknown data channel")
+		return
+	}
+
+	logger.Debug().Str("label", dc.Label()).Msg("closing unknown data channel")
+	if err := dc.Close(); err != nil {
+		logger.Err(err).Msg("failed to close unknown data channel")
+	}
+}
@@ -0,0 +1,41 @@
+package webrtc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+func TestAddDataChannelHandler(t *testing.T) {
+	manager := &WebRTCManagerCtx{
+		dataChannelHandlers: map[string]types.WebRTCDataChannelHandler{},
+	}
+
+	handler := func(session types.Session, dc *webrtc.DataChannel) {}
+
+	if err := manager.AddDataChannelHandler("telemetry", handler); err != nil {
+		t.Fatalf("AddDataChannelHandler() error = %v", err)
+	}
+
+	if _, ok := manager.dataChannelHandler("telemetry"); !ok {
+		t.Errorf("claimed label has no handler")
+	}
+
+	if _, ok := manager.dataChannelHandler("unknown"); ok {
+		t.Errorf("unclaimed label has handler")
+	}
+
+	// every label can be claimed only once
+	if err := manager.AddDataChannelHandler("telemetry", handler); !errors.Is(err, types.ErrWebRTCDataChannelClaimed) {
+		t.Errorf("second claim error = %v, want %v", err, types.ErrWebRTCDataChannelClaimed)
+	}
+
+	for _, label := range reservedDataChannelLabels {
+		if err := manager.AddDataChannelHandler(label, handler); !errors.Is(err, types.ErrWebRTCDataChannelClaimed) {
+			t.Errorf("claim of reserved %q error = %v, want %v", label, err, types.ErrWebRTCDataChannelClaimed)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package webrtc
+
+import (
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+)
+
+// label of data channel created by client for diagnostics
+const diagnosticsDataChannelLabel = "diagnostics"
+
+// handleDiagnostics echoes back every message received on diagnostics data
+// channel, so that client can measure round-trip time over the data path.
+// Client is expected to include its timestamp in the message. Unlike ping on
+// the main data channel, it is not affected by input events sent by client.
+func (manager *WebRTCManagerCtx) handleDiagnostics(logger zerolog.Logger, dc *webrtc.DataChannel) {
+	if !manager.config.Diagnostics {
+		logger.Warn().Msg("diagnostics data channel is disabled, closing it")
+		if err := dc.Close(); err != nil {
+			logger.Err(err).Msg("failed to close diagnostics data channel")
+		}
+		return
+	}
+
+	logger.Debug().Msg("diagnostics data channel opened")
+
+	dc.OnMessage(func(message webrtc.DataChannelMessage) {
+		var err error
+		if message.IsString {
+			err = dc.SendText(string(message.Data))
+		} else {
+			err = dc.Send(message.Data)
+		}
+
+		if err != nil {
+			logger.Err(err).Msg("failed to echo diagnostics message")
+		}
+	})
+}
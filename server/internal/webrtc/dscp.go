@@ -0,0 +1,182 @@
+package webrtc
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"syscall"
+
+	"github.com/pion/transport/v2"
+	"github.com/rs/zerolog"
+)
+
+// DSCP marking is applied by setting IP_TOS and IPV6_TCLASS socket options. Audio
+// and video of a peer are bundled on a single socket, so the traffic class is
+// switched per packet based on the SSRC found in the unencrypted RTP header.
+//
+// Limitations:
+//   - works only on platforms honoring socket traffic class (Linux, macOS),
+//     Windows ignores it without qWAVE policies
+//   - only UDP candidates are marked, TCP candidates are sent unmarked
+//   - marks can be rewritten by container networking, NAT or routers, with
+//     Docker the host must not reset them (e.g. iptables DSCP target)
+//   - RTCP and DTLS packets keep class of the last RTP packet
+type dscpMarker struct {
+	logger zerolog.Logger
+	audio  int
+	video  int
+
+	audioSSRCs sync.Map
+}
+
+func newDSCPMarker(logger zerolog.Logger, audio, video int) *dscpMarker {
+	return &dscpMarker{
+		logger: logger.With().Str("submodule", "dscp").Logger(),
+		audio:  audio,
+		video:  video,
+	}
+}
+
+func (m *dscpMarker) addAudio(ssrc uint32) {
+	m.audioSSRCs.Store(ssrc, struct{}{})
+}
+
+func (m *dscpMarker) removeAudio(ssrc uint32) {
+	m.audioSSRCs.Delete(ssrc)
+}
+
+// classify returns DSCP value for the packet, false if it is not an RTP packet.
+func (m *dscpMarker) classify(b []byte) (int, bool) {
+	// RTP version 2 with fixed header
+	if len(b) < 12 || b[0]>>6 != 2 {
+		return 0, false
+	}
+
+	// RTCP multiplexed with RTP (RFC 5761)
+	if pt := b[1] & 0x7f; pt >= 64 && pt <= 95 {
+		return 0, false
+	}
+
+	ssrc := binary.BigEndian.Uint32(b[8:12])
+	if _, ok := m.audioSSRCs.Load(ssrc); ok {
+		return m.audio, true
+	}
+
+	return m.video, true
+}
+
+// wrap returns network for ICE agent and UDP mux, that marks outbound packets.
+func (m *dscpMarker) wrap(n transport.Net) transport.Net {
+	return &dscpNet{Net: n, marker: m}
+}
+
+type dscpNet struct {
+	transport.Net
+	marker *dscpMarker
+}
+
+func (n *dscpNet) ListenUDP(network string, locAddr *net.UDPAddr) (transport.UDPConn, error) {
+	conn, err := n.Net.ListenUDP(network, locAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return newDSCPConn(conn, n.marker), nil
+}
+
+type dscpConn struct {
+	transport.UDPConn
+	marker *dscpMarker
+
+	mu       sync.Mutex
+	raw      syscall.RawConn
+	current  int
+	applied  bool
+	disabled bool
+}
+
+func newDSCPConn(conn transport.UDPConn, marker *dscpMarker) *dscpConn {
+	c := &dscpConn{
+		UDPConn: conn,
+		marker:  marker,
+	}
+
+	if sc, ok := conn.(syscall.Conn); ok {
+		c.raw, _ = sc.SyscallConn()
+	}
+
+	if c.raw == nil {
+		marker.logger.Warn().Str("addr", conn.LocalAddr().String()).Msg("socket does not support DSCP marking")
+		c.disabled = true
+	}
+
+	return c
+}
+
+// mark sets traffic class of the socket, must be called with mutex held.
+func (c *dscpConn) mark(b []byte) {
+	if c.disabled {
+		return
+	}
+
+	dscp, ok := c.marker.classify(b)
+	if !ok || dscp == c.current {
+		return
+	}
+
+	tos := dscp << 2
+
+	var errV4, errV6 error
+	err := c.raw.Control(func(fd uintptr) {
+		errV4 = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS, tos)
+		errV6 = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS, tos)
+	})
+
+	// socket is either IPv4 or IPv6, one of the options is enough
+	if err == nil && errV4 != nil && errV6 != nil {
+		err = errV4
+	}
+
+	if err != nil {
+		c.marker.logger.Warn().Err(err).
+			Str("addr", c.LocalAddr().String()).
+			Int("dscp", dscp).
+			Msg("unable to apply DSCP marking, disabling it for this socket")
+		c.disabled = true
+		return
+	}
+
+	if !c.applied {
+		c.applied = true
+		c.marker.logger.Info().
+			Str("addr", c.LocalAddr().String()).
+			Int("dscp", dscp).
+			Msg("DSCP marking applied")
+	}
+
+	c.current = dscp
+}
+
+func (c *dscpConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.mark(p)
+	return c.UDPConn.WriteTo(p, addr)
+}
+
+func (c *dscpConn) WriteToUDP(p []byte, addr *net.UDPAddr) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.mark(p)
+	return c.UDPConn.WriteToUDP(p, addr)
+}
+
+func (c *dscpConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.mark(p)
+	return c.UDPConn.Write(p)
+}
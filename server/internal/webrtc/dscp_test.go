@@ -0,0 +1,96 @@
+package webrtc
+
+import (
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/pion/transport/v2/stdnet"
+	"github.com/rs/zerolog"
+)
+
+func rtpPacket(pt byte, ssrc uint32) []byte {
+	b := make([]byte, 12)
+	b[0] = 0x80
+	b[1] = pt
+	b[8], b[9], b[10], b[11] = byte(ssrc>>24), byte(ssrc>>16), byte(ssrc>>8), byte(ssrc)
+	return b
+}
+
+// Ensure that packets are marked based on their SSRC and RTCP is ignored
+func TestDSCPClassify(t *testing.T) {
+	marker := newDSCPMarker(zerolog.Nop(), 46, 34)
+	marker.addAudio(1)
+
+	if dscp, ok := marker.classify(rtpPacket(111, 1)); !ok || dscp != 46 {
+		t.Errorf("audio packet classified as %d, %v", dscp, ok)
+	}
+
+	if dscp, ok := marker.classify(rtpPacket(96, 2)); !ok || dscp != 34 {
+		t.Errorf("video packet classified as %d, %v", dscp, ok)
+	}
+
+	// receiver report
+	if _, ok := marker.classify(rtpPacket(201, 1)); ok {
+		t.Errorf("rtcp packet was classified")
+	}
+
+	// stun binding request
+	if _, ok := marker.classify(make([]byte, 20)); ok {
+		t.Errorf("stun packet was classified")
+	}
+
+	marker.removeAudio(1)
+	if dscp, _ := marker.classify(rtpPacket(111, 1)); dscp != 34 {
+		t.Errorf("removed audio packet classified as %d", dscp)
+	}
+}
+
+// Ensure that socket traffic class follows written packets
+func TestDSCPConnMarksSocket(t *testing.T) {
+	marker := newDSCPMarker(zerolog.Nop(), 46, 34)
+	marker.addAudio(1)
+
+	n, err := stdnet.NewNet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := marker.wrap(n).ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	tos := func() int {
+		raw, err := conn.(*dscpConn).UDPConn.(syscall.Conn).SyscallConn()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var value int
+		_ = raw.Control(func(fd uintptr) {
+			value, err = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+
+	addr := conn.LocalAddr()
+
+	if _, err := conn.WriteTo(rtpPacket(111, 1), addr); err != nil {
+		t.Fatal(err)
+	}
+	if v := tos(); v != 46<<2 {
+		t.Errorf("audio tos is %d, expected %d", v, 46<<2)
+	}
+
+	if _, err := conn.WriteTo(rtpPacket(96, 2), addr); err != nil {
+		t.Fatal(err)
+	}
+	if v := tos(); v != 34<<2 {
+		t.Errorf("video tos is %d, expected %d", v, 34<<2)
+	}
+}
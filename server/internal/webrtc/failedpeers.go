@@ -0,0 +1,96 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// failedPeers counts peers of sessions that failed to connect, sessions that
+// keep failing are likely on a network blocking media, e.g. without TURN.
+type failedPeers struct {
+	mu        sync.Mutex
+	threshold int
+	window    time.Duration
+	sessions  map[string][]time.Time
+}
+
+func newFailedPeers(threshold int, window time.Duration) *failedPeers {
+	return &failedPeers{
+		threshold: threshold,
+		window:    window,
+		sessions:  map[string][]time.Time{},
+	}
+}
+
+// failed records failed peer of the session, it returns number of failures
+// within the window, once it reached the threshold. Failures are forgotten
+// then, so that the session is reported again after another threshold.
+func (f *failedPeers) failed(sessionId string, now time.Time) (int, bool) {
+	if f.threshold <= 0 {
+		return 0, false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	since := now.Add(-f.window)
+	for id, times := range f.sessions {
+		if len(times) > 0 && times[len(times)-1].Before(since) {
+			delete(f.sessions, id)
+		}
+	}
+
+	times := []time.Time{}
+	for _, t := range f.sessions[sessionId] {
+		if !t.Before(since) {
+			times = append(times, t)
+		}
+	}
+	times = append(times, now)
+
+	if len(times) < f.threshold {
+		f.sessions[sessionId] = times
+		return len(times), false
+	}
+
+	delete(f.sessions, sessionId)
+	return len(times), true
+}
+
+// connected forgets failures of the session, its media works.
+func (f *failedPeers) connected(sessionId string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.sessions, sessionId)
+}
+
+// peerFailed is called when peer connection of the session failed, client is
+// notified when it keeps failing, so that it can suggest network remediation.
+func (manager *WebRTCManagerCtx) peerFailed(session types.Session) {
+	attempts, reached := manager.failedPeers.failed(session.ID(), time.Now())
+	if !reached {
+		return
+	}
+
+	screencast := manager.capture.Screencast().Enabled()
+
+	manager.logger.Warn().
+		Str("session_id", session.ID()).
+		Int("attempts", attempts).
+		Bool("screencast", screencast).
+		Msg("webrtc keeps failing for session")
+
+	session.Send(
+		event.SIGNAL_FAILED,
+		message.SignalFailed{
+			Attempts:   attempts,
+			Reason:     "ice_failed",
+			ICEServers: len(manager.ICEServers()) > 0,
+			Screencast: screencast,
+		})
+}
@@ -0,0 +1,59 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFailedPeers(t *testing.T) {
+	f := newFailedPeers(3, time.Minute)
+	now := time.Now()
+
+	if _, reached := f.failed("a", now); reached {
+		t.Error("first failure reached threshold")
+	}
+	if _, reached := f.failed("a", now.Add(10*time.Second)); reached {
+		t.Error("second failure reached threshold")
+	}
+
+	// other sessions are counted separately
+	if _, reached := f.failed("b", now.Add(10*time.Second)); reached {
+		t.Error("failure of other session reached threshold")
+	}
+
+	attempts, reached := f.failed("a", now.Add(20*time.Second))
+	if !reached || attempts != 3 {
+		t.Errorf("failed() = %d, %v, want 3, true", attempts, reached)
+	}
+
+	// failures are forgotten once reported
+	if _, reached := f.failed("a", now.Add(30*time.Second)); reached {
+		t.Error("failure after report reached threshold")
+	}
+}
+
+func TestFailedPeersWindow(t *testing.T) {
+	f := newFailedPeers(2, time.Minute)
+	now := time.Now()
+
+	f.failed("a", now)
+	if _, reached := f.failed("a", now.Add(2*time.Minute)); reached {
+		t.Error("failures outside of window reached threshold")
+	}
+
+	// connected session starts counting again
+	f.connected("a")
+	if _, reached := f.failed("a", now.Add(2*time.Minute)); reached {
+		t.Error("failure after connection reached threshold")
+	}
+}
+
+func TestFailedPeersDisabled(t *testing.T) {
+	f := newFailedPeers(0, time.Minute)
+
+	for i := 0; i < 5; i++ {
+		if _, reached := f.failed("a", time.Now()); reached {
+			t.Fatal("disabled failed peers reached threshold")
+		}
+	}
+}
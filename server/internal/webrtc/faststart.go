@@ -0,0 +1,99 @@
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// how often connection state is checked while waiting for fast start
+const fastStartPollInterval = 100 * time.Millisecond
+
+// fastStartSelector replaces the first requested video with the fast start
+// video and returns ID of the requested one to ramp up to. It returns false,
+// if fast start does not apply to the request. Must be called with mutex held.
+func (peer *WebRTCPeerCtx) fastStartSelector(r *types.PeerVideoRequest) (string, bool) {
+	if peer.fastStartDuration <= 0 || r.Selector == nil {
+		return "", false
+	}
+
+	// bandwidth probing already starts from the lowest stream
+	auto := peer.videoAuto
+	if r.Auto != nil {
+		auto = *r.Auto
+	}
+	conf := peer.estimatorConfig
+	if auto && peer.estimator != nil && conf.ProbeDuration > 0 && !conf.Passive {
+		return "", false
+	}
+
+	target, ok := peer.video.GetStream(*r.Selector)
+	if !ok {
+		return "", false
+	}
+
+	startId := peer.fastStartVideo
+	if startId == "" {
+		// stream IDs are ordered from the highest to the lowest
+		ids := peer.video.IDs()
+		startId = ids[len(ids)-1]
+	}
+
+	if _, ok := peer.video.GetStream(types.StreamSelector{ID: startId, Type: types.StreamSelectorTypeExact}); !ok {
+		peer.logger.Warn().Str("video_id", startId).Msg("fast start video not found")
+		return "", false
+	}
+
+	if startId == target.ID() {
+		return "", false
+	}
+
+	r.Selector = &types.StreamSelector{
+		ID:   startId,
+		Type: types.StreamSelectorTypeExact,
+	}
+
+	return target.ID(), true
+}
+
+// fastStartRamp switches to the target video once connection has been connected
+// for fast start duration. If the video was changed meanwhile by the client or
+// the bandwidth estimator, it is kept.
+func (peer *WebRTCPeerCtx) fastStartRamp(startId, targetId string) {
+	ticker := time.NewTicker(fastStartPollInterval)
+	defer ticker.Stop()
+
+	// wait until connected, so that the first frames are the fast ones
+	for peer.connection.ConnectionState() != webrtc.PeerConnectionStateConnected {
+		if peer.connection.ConnectionState() == webrtc.PeerConnectionStateClosed {
+			return
+		}
+		<-ticker.C
+	}
+
+	time.Sleep(peer.fastStartDuration)
+
+	if peer.connection.ConnectionState() == webrtc.PeerConnectionStateClosed {
+		return
+	}
+
+	if video := peer.Video(); video.ID != startId {
+		peer.logger.Debug().Str("video_id", video.ID).Msg("video changed during fast start, keeping it")
+		return
+	}
+
+	err := peer.setVideo(types.PeerVideoRequest{
+		Selector: &types.StreamSelector{
+			ID:   targetId,
+			Type: types.StreamSelectorTypeExact,
+		},
+	}, false)
+	if err != nil {
+		peer.logger.Warn().Err(err).Str("video_id", targetId).Msg("failed to ramp up from fast start video")
+		return
+	}
+
+	peer.logger.Info().Str("video_id", targetId).Msg("ramped up from fast start video")
+}
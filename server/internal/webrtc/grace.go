@@ -0,0 +1,71 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// disconnectGrace closes peer connection that failed or that has not recovered
+// from disconnected state within grace period, ICE may recover on its own.
+// Optionally ICE is restarted once the connection gets disconnected, so that
+// it can recover also when the client changed network.
+type disconnectGrace struct {
+	grace   time.Duration
+	restart func()
+	close   func()
+
+	mu    sync.Mutex
+	timer *time.Timer
+}
+
+func newDisconnectGrace(grace time.Duration, restart, close func()) *disconnectGrace {
+	return &disconnectGrace{
+		grace:   grace,
+		restart: restart,
+		close:   close,
+	}
+}
+
+func (g *disconnectGrace) stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+}
+
+// handle must be called on every peer connection state change.
+func (g *disconnectGrace) handle(state webrtc.PeerConnectionState) {
+	switch state {
+	case webrtc.PeerConnectionStateDisconnected:
+		if g.grace <= 0 {
+			g.close()
+			return
+		}
+
+		g.mu.Lock()
+		disconnected := g.timer == nil
+		if disconnected {
+			g.timer = time.AfterFunc(g.grace, g.close)
+		}
+		g.mu.Unlock()
+
+		// restarted only once per disconnection, restart itself can
+		// end up disconnected when the client does not answer
+		if disconnected && g.restart != nil {
+			go g.restart()
+		}
+	case webrtc.PeerConnectionStateFailed:
+		g.stop()
+		g.close()
+	case webrtc.PeerConnectionStateConnecting:
+		// restarted ICE is checking candidates, grace period continues
+	default:
+		// connected again or closed
+		g.stop()
+	}
+}
@@ -0,0 +1,324 @@
+package webrtc
+
+import (
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/ice/v2"
+	"github.com/pion/logging"
+	"github.com/pion/transport/v2/vnet"
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// Ensure that connection recovering from disconnected state is not closed
+func TestDisconnectGraceRecovered(t *testing.T) {
+	var closed atomic.Int32
+	grace := newDisconnectGrace(50*time.Millisecond, nil, func() {
+		closed.Add(1)
+	})
+
+	grace.handle(webrtc.PeerConnectionStateConnected)
+	grace.handle(webrtc.PeerConnectionStateDisconnected)
+	grace.handle(webrtc.PeerConnectionStateConnected)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if n := closed.Load(); n != 0 {
+		t.Errorf("recovered connection was closed %d times", n)
+	}
+}
+
+// Ensure that connection not recovering from disconnected state is closed
+func TestDisconnectGraceExpired(t *testing.T) {
+	var closed atomic.Int32
+	grace := newDisconnectGrace(50*time.Millisecond, nil, func() {
+		closed.Add(1)
+	})
+
+	grace.handle(webrtc.PeerConnectionStateDisconnected)
+	// repeated disconnected state does not restart grace period
+	grace.handle(webrtc.PeerConnectionStateDisconnected)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if n := closed.Load(); n != 1 {
+		t.Errorf("connection was closed %d times, expected once", n)
+	}
+}
+
+// Ensure that failed connection is closed immediately
+func TestDisconnectGraceFailed(t *testing.T) {
+	var closed atomic.Int32
+	grace := newDisconnectGrace(time.Hour, nil, func() {
+		closed.Add(1)
+	})
+
+	grace.handle(webrtc.PeerConnectionStateDisconnected)
+	grace.handle(webrtc.PeerConnectionStateFailed)
+
+	if n := closed.Load(); n != 1 {
+		t.Errorf("connection was closed %d times, expected once", n)
+	}
+}
+
+// Ensure that without grace period, disconnected connection is closed immediately
+func TestDisconnectGraceDisabled(t *testing.T) {
+	var closed atomic.Int32
+	grace := newDisconnectGrace(0, nil, func() {
+		closed.Add(1)
+	})
+
+	grace.handle(webrtc.PeerConnectionStateDisconnected)
+
+	if n := closed.Load(); n != 1 {
+		t.Errorf("connection was closed %d times, expected once", n)
+	}
+}
+
+// Ensure that ICE is restarted once per disconnection, without stopping grace period
+func TestDisconnectGraceRestart(t *testing.T) {
+	var closed, restarted atomic.Int32
+	grace := newDisconnectGrace(50*time.Millisecond, func() {
+		restarted.Add(1)
+	}, func() {
+		closed.Add(1)
+	})
+
+	grace.handle(webrtc.PeerConnectionStateDisconnected)
+	grace.handle(webrtc.PeerConnectionStateConnecting)
+	grace.handle(webrtc.PeerConnectionStateDisconnected)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if n := restarted.Load(); n != 1 {
+		t.Errorf("connection was restarted %d times, expected once", n)
+	}
+	if n := closed.Load(); n != 1 {
+		t.Errorf("connection was closed %d times, expected once", n)
+	}
+
+	// next disconnection is restarted again
+	grace.handle(webrtc.PeerConnectionStateConnected)
+	grace.handle(webrtc.PeerConnectionStateDisconnected)
+	grace.stop()
+
+	time.Sleep(10 * time.Millisecond)
+
+	if n := restarted.Load(); n != 2 {
+		t.Errorf("connection was restarted %d times, expected twice", n)
+	}
+}
+
+type restartTestSession struct {
+	types.Session
+	offers chan string
+}
+
+func (s *restartTestSession) Send(ev string, payload any) {
+	if desc, ok := payload.(message.SignalDescription); ok && ev == event.SIGNAL_OFFER {
+		s.offers <- desc.SDP
+	}
+}
+
+func newRestartTestConnection(t *testing.T, network *vnet.Net, ipFilter func(net.IP) bool) *webrtc.PeerConnection {
+	t.Helper()
+
+	settings := webrtc.SettingEngine{}
+	settings.SetVNet(network)
+	settings.SetICEMulticastDNSMode(ice.MulticastDNSModeDisabled)
+	settings.SetICETimeouts(time.Second, 10*time.Second, 200*time.Millisecond)
+	if ipFilter != nil {
+		settings.SetIPFilter(ipFilter)
+	}
+
+	connection, err := webrtc.NewAPI(webrtc.WithSettingEngine(settings)).NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatalf("unable to create peer connection: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = connection.Close()
+	})
+
+	return connection
+}
+
+// answer sets offer of the server to the client and returns its answer with
+// all candidates gathered.
+func answerRestartTest(t *testing.T, client *webrtc.PeerConnection, sdp string) webrtc.SessionDescription {
+	t.Helper()
+
+	err := client.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: sdp})
+	if err != nil {
+		t.Fatalf("unable to set remote offer: %v", err)
+	}
+
+	gatherComplete := webrtc.GatheringCompletePromise(client)
+	createTestAnswer(t, client)
+	<-gatherComplete
+
+	return *client.LocalDescription()
+}
+
+// Simulate client changing network, so that its candidates are not valid
+// anymore, ICE restart recovers the connection with the same data channel.
+func TestDisconnectGraceRestartICE(t *testing.T) {
+	wan, err := vnet.NewRouter(&vnet.RouterConfig{
+		CIDR:          "1.2.3.0/24",
+		LoggerFactory: logging.NewDefaultLoggerFactory(),
+	})
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+
+	serverNet, err := vnet.NewNet(&vnet.NetConfig{StaticIPs: []string{"1.2.3.4"}})
+	if err != nil {
+		t.Fatalf("unable to create server network: %v", err)
+	}
+	clientNet, err := vnet.NewNet(&vnet.NetConfig{StaticIPs: []string{"1.2.3.5", "1.2.3.6"}})
+	if err != nil {
+		t.Fatalf("unable to create client network: %v", err)
+	}
+	if err := wan.AddNet(serverNet); err != nil {
+		t.Fatalf("unable to add server network: %v", err)
+	}
+	if err := wan.AddNet(clientNet); err != nil {
+		t.Fatalf("unable to add client network: %v", err)
+	}
+
+	// client uses one address at a time, the previous one stops working
+	var clientIP, invalidIP atomic.Value
+	clientIP.Store("1.2.3.5")
+	invalidIP.Store("")
+	wan.AddChunkFilter(func(c vnet.Chunk) bool {
+		invalid := invalidIP.Load().(string)
+		return !strings.HasPrefix(c.SourceAddr().String(), invalid+":") &&
+			!strings.HasPrefix(c.DestinationAddr().String(), invalid+":")
+	})
+
+	if err := wan.Start(); err != nil {
+		t.Fatalf("unable to start router: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = wan.Stop()
+	})
+
+	server := newRestartTestConnection(t, serverNet, nil)
+	client := newRestartTestConnection(t, clientNet, func(ip net.IP) bool {
+		return ip.String() == clientIP.Load().(string)
+	})
+
+	dataChannel, err := server.CreateDataChannel("data", nil)
+	if err != nil {
+		t.Fatalf("unable to create data channel: %v", err)
+	}
+
+	session := &restartTestSession{offers: make(chan string, 1)}
+	peer := &WebRTCPeerCtx{
+		logger:     zerolog.Nop(),
+		session:    session,
+		connection: server,
+	}
+
+	var closed atomic.Int32
+	grace := newDisconnectGrace(10*time.Second, func() {
+		if err := peer.RestartICE(); err != nil {
+			t.Errorf("unable to restart ice: %v", err)
+		}
+	}, func() {
+		closed.Add(1)
+	})
+
+	connected := make(chan struct{}, 1)
+	server.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		grace.handle(state)
+		if state == webrtc.PeerConnectionStateConnected {
+			select {
+			case connected <- struct{}{}:
+			default:
+			}
+		}
+	})
+
+	messages := make(chan string, 1)
+	client.OnDataChannel(func(dc *webrtc.DataChannel) {
+		dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+			messages <- string(msg.Data)
+		})
+	})
+
+	wait := func(ch <-chan struct{}, what string) {
+		t.Helper()
+		select {
+		case <-ch:
+		case <-time.After(15 * time.Second):
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+
+	offer, err := peer.CreateOffer(false)
+	if err != nil {
+		t.Fatalf("unable to create offer: %v", err)
+	}
+	if err := server.SetRemoteDescription(answerRestartTest(t, client, offer.SDP)); err != nil {
+		t.Fatalf("unable to set remote answer: %v", err)
+	}
+
+	wait(connected, "connection")
+
+	opened := make(chan struct{})
+	dataChannel.OnOpen(func() { close(opened) })
+	if dataChannel.ReadyState() != webrtc.DataChannelStateOpen {
+		wait(opened, "data channel")
+	}
+
+	// client changes network
+	clientIP.Store("1.2.3.6")
+	invalidIP.Store("1.2.3.5")
+
+	var restartOffer string
+	select {
+	case restartOffer = <-session.offers:
+	case <-time.After(15 * time.Second):
+		t.Fatalf("timed out waiting for restart offer")
+	}
+
+	if err := server.SetRemoteDescription(answerRestartTest(t, client, restartOffer)); err != nil {
+		t.Fatalf("unable to set remote restart answer: %v", err)
+	}
+
+	wait(connected, "reconnection")
+
+	pair, err := server.SCTP().Transport().ICETransport().GetSelectedCandidatePair()
+	if err != nil || pair == nil {
+		t.Fatalf("no selected candidate pair: %v", err)
+	}
+	if pair.Remote.Address != "1.2.3.6" {
+		t.Errorf("remote candidate address = %s, want 1.2.3.6", pair.Remote.Address)
+	}
+
+	// the same data channel keeps working
+	if err := dataChannel.SendText("hello"); err != nil {
+		t.Fatalf("unable to send over data channel: %v", err)
+	}
+	select {
+	case msg := <-messages:
+		if msg != "hello" {
+			t.Errorf("received %q, want hello", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for data channel message")
+	}
+
+	if n := closed.Load(); n != 0 {
+		t.Errorf("recovered connection was closed %d times", n)
+	}
+}
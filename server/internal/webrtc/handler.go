@@ -6,6 +6,7 @@ import (
 	"math"
 	"time"
 
+	"github.com/m1k1o/neko/server/internal/input"
 	"github.com/m1k1o/neko/server/internal/webrtc/payload"
 	"github.com/m1k1o/neko/server/pkg/types"
 
@@ -47,6 +48,7 @@ func (manager *WebRTCManagerCtx) handle(
 			// handle active cursor movement
 			manager.desktop.Move(x, y)
 			manager.curPosition.Set(x, y)
+			input.Record(session, types.InputEvent{Type: types.InputMove, X: x, Y: y})
 		} else {
 			// handle inactive cursor movement
 			session.SetCursor(types.Cursor{
@@ -106,6 +108,7 @@ func (manager *WebRTCManagerCtx) handle(
 			}
 
 			manager.desktop.Scroll(int(payload.X), int(payload.Y), false)
+			input.Record(session, types.InputEvent{Type: types.InputScroll, DeltaX: int(payload.X), DeltaY: int(payload.Y)})
 			logger.Trace().
 				Int16("x", payload.X).
 				Int16("y", payload.Y).
@@ -117,6 +120,7 @@ func (manager *WebRTCManagerCtx) handle(
 			}
 
 			manager.desktop.Scroll(int(payload.DeltaX), int(payload.DeltaY), payload.ControlKey)
+			input.Record(session, types.InputEvent{Type: types.InputScroll, DeltaX: int(payload.DeltaX), DeltaY: int(payload.DeltaY), ControlKey: payload.ControlKey})
 			logger.Trace().
 				Int16("deltaX", payload.DeltaX).
 				Int16("deltaY", payload.DeltaY).
@@ -133,6 +137,7 @@ func (manager *WebRTCManagerCtx) handle(
 			logger.Warn().Err(err).Uint32("key", payload.Key).Msg("key down failed")
 		} else {
 			logger.Trace().Uint32("key", payload.Key).Msg("key down")
+			input.Record(session, types.InputEvent{Type: types.InputKeyDown, Code: payload.Key})
 		}
 	case payload.OP_KEY_UP:
 		payload := &payload.Key{}
@@ -144,6 +149,7 @@ func (manager *WebRTCManagerCtx) handle(
 			logger.Warn().Err(err).Uint32("key", payload.Key).Msg("key up failed")
 		} else {
 			logger.Trace().Uint32("key", payload.Key).Msg("key up")
+			input.Record(session, types.InputEvent{Type: types.InputKeyUp, Code: payload.Key})
 		}
 	case payload.OP_BTN_DOWN:
 		payload := &payload.Key{}
@@ -155,6 +161,7 @@ func (manager *WebRTCManagerCtx) handle(
 			logger.Warn().Err(err).Uint32("key", payload.Key).Msg("button down failed")
 		} else {
 			logger.Trace().Uint32("key", payload.Key).Msg("button down")
+			input.Record(session, types.InputEvent{Type: types.InputButtonDown, Code: payload.Key})
 		}
 	case payload.OP_BTN_UP:
 		payload := &payload.Key{}
@@ -166,6 +173,7 @@ func (manager *WebRTCManagerCtx) handle(
 			logger.Warn().Err(err).Uint32("key", payload.Key).Msg("button up failed")
 		} else {
 			logger.Trace().Uint32("key", payload.Key).Msg("button up")
+			input.Record(session, types.InputEvent{Type: types.InputButtonUp, Code: payload.Key})
 		}
 	case payload.OP_TOUCH_BEGIN:
 		payload := &payload.Touch{}
@@ -177,6 +185,7 @@ func (manager *WebRTCManagerCtx) handle(
 			logger.Warn().Err(err).Uint32("touchId", payload.TouchId).Msg("touch begin failed")
 		} else {
 			logger.Trace().Uint32("touchId", payload.TouchId).Msg("touch begin")
+			input.Record(session, types.InputEvent{Type: types.InputTouchBegin, TouchId: payload.TouchId, X: int(payload.X), Y: int(payload.Y), Pressure: payload.Pressure})
 		}
 	case payload.OP_TOUCH_UPDATE:
 		payload := &payload.Touch{}
@@ -188,6 +197,7 @@ func (manager *WebRTCManagerCtx) handle(
 			logger.Warn().Err(err).Uint32("touchId", payload.TouchId).Msg("touch update failed")
 		} else {
 			logger.Trace().Uint32("touchId", payload.TouchId).Msg("touch update")
+			input.Record(session, types.InputEvent{Type: types.InputTouchUpdate, TouchId: payload.TouchId, X: int(payload.X), Y: int(payload.Y), Pressure: payload.Pressure})
 		}
 	case payload.OP_TOUCH_END:
 		payload := &payload.Touch{}
@@ -199,6 +209,7 @@ func (manager *WebRTCManagerCtx) handle(
 			logger.Warn().Err(err).Uint32("touchId", payload.TouchId).Msg("touch end failed")
 		} else {
 			logger.Trace().Uint32("touchId", payload.TouchId).Msg("touch end")
+			input.Record(session, types.InputEvent{Type: types.InputTouchEnd, TouchId: payload.TouchId, X: int(payload.X), Y: int(payload.Y), Pressure: payload.Pressure})
 		}
 	}
 
@@ -0,0 +1,56 @@
+package webrtc
+
+import (
+	"time"
+)
+
+const (
+	// window over which inbound bitrate is measured
+	inboundLimiterWindow = time.Second
+	// how much inbound bitrate can exceed the limit before packets are dropped,
+	// the sender is asked to stay below the limit, this only allows for bursts
+	inboundLimiterTolerance = 1.5
+)
+
+// inboundLimiter drops packets of a remote track once its bitrate exceeds the
+// limit (including tolerance) within the current window. It is used only from
+// the goroutine reading the track, so it is not safe for concurrent use.
+type inboundLimiter struct {
+	maxBytes    int
+	windowStart time.Time
+	windowBytes int
+	dropped     int
+}
+
+// newInboundLimiter creates limiter for given bitrate in kbps.
+func newInboundLimiter(kbps int) *inboundLimiter {
+	bytesPerSecond := float64(kbps) * 1000 / 8
+	return &inboundLimiter{
+		maxBytes: int(bytesPerSecond * inboundLimiterTolerance * inboundLimiterWindow.Seconds()),
+	}
+}
+
+// allow returns whether packet of given size can be accepted at given time.
+func (l *inboundLimiter) allow(size int, now time.Time) bool {
+	if now.Sub(l.windowStart) >= inboundLimiterWindow {
+		l.windowStart = now
+		l.windowBytes = 0
+	}
+
+	if l.windowBytes+size > l.maxBytes {
+		l.dropped++
+		return false
+	}
+
+	l.windowBytes += size
+	return true
+}
+
+// inboundMaxBitrate returns max inbound bitrate in kbps of shared media of
+// given kind, 0 if unlimited.
+func (manager *WebRTCManagerCtx) inboundMaxBitrate(video bool) int {
+	if video {
+		return manager.config.SharedVideoMaxBitrate
+	}
+	return manager.config.SharedAudioMaxBitrate
+}
@@ -0,0 +1,32 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+)
+
+// Ensure that packets above the limit are dropped until the next window
+func TestInboundLimiter(t *testing.T) {
+	// 80 kbps = 10000 bytes per second, 15000 bytes with tolerance
+	limiter := newInboundLimiter(80)
+	now := time.Now()
+
+	accepted := 0
+	for i := 0; i < 20; i++ {
+		if limiter.allow(1000, now) {
+			accepted++
+		}
+	}
+
+	if accepted != 15 {
+		t.Errorf("accepted %d packets, expected 15", accepted)
+	}
+
+	if limiter.dropped != 5 {
+		t.Errorf("dropped %d packets, expected 5", limiter.dropped)
+	}
+
+	if !limiter.allow(1000, now.Add(inboundLimiterWindow)) {
+		t.Errorf("packet in the next window was dropped")
+	}
+}
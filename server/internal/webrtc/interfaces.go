@@ -0,0 +1,71 @@
+package webrtc
+
+import (
+	"net"
+	"path"
+)
+
+func matchInterface(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// interfaceFilter returns filter for network interfaces used for ICE
+// candidate gathering, nil if all interfaces should be used.
+func (manager *WebRTCManagerCtx) interfaceFilter() func(string) bool {
+	allow := manager.config.InterfacesAllow
+	deny := manager.config.InterfacesDeny
+
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil
+	}
+
+	return func(name string) bool {
+		if matchInterface(deny, name) {
+			return false
+		}
+		return len(allow) == 0 || matchInterface(allow, name)
+	}
+}
+
+// ipFilter returns filter for IP addresses used for ICE candidate
+// gathering, nil if all addresses should be used.
+func (manager *WebRTCManagerCtx) ipFilter() func(net.IP) bool {
+	if !manager.config.ExcludePrivateIPs {
+		return nil
+	}
+
+	return func(ip net.IP) bool {
+		return !ip.IsPrivate()
+	}
+}
+
+// logInterfaces logs which network interfaces are used for ICE candidate gathering.
+func (manager *WebRTCManagerCtx) logInterfaces() {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		manager.logger.Warn().Err(err).Msg("unable to list network interfaces")
+		return
+	}
+
+	filter := manager.interfaceFilter()
+
+	used, ignored := []string{}, []string{}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || (filter != nil && !filter(iface.Name)) {
+			ignored = append(ignored, iface.Name)
+		} else {
+			used = append(used, iface.Name)
+		}
+	}
+
+	manager.logger.Info().
+		Strs("used", used).
+		Strs("ignored", ignored).
+		Bool("exclude_private_ips", manager.config.ExcludePrivateIPs).
+		Msg("network interfaces for ICE candidate gathering")
+}
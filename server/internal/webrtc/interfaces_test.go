@@ -0,0 +1,81 @@
+package webrtc
+
+import (
+	"net"
+	"testing"
+
+	"github.com/m1k1o/neko/server/internal/config"
+)
+
+func TestInterfaceFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		allow []string
+		deny  []string
+		want  map[string]bool
+	}{
+		{
+			name: "no lists",
+		},
+		{
+			name:  "allow wildcard",
+			allow: []string{"eth*"},
+			want:  map[string]bool{"eth0": true, "eth1": true, "wlan0": false},
+		},
+		{
+			name: "deny wildcard",
+			deny: []string{"docker*", "veth*"},
+			want: map[string]bool{"eth0": true, "docker0": false, "veth12ab": false},
+		},
+		{
+			name:  "deny wins over allow",
+			allow: []string{"eth*", "docker0"},
+			deny:  []string{"docker*"},
+			want:  map[string]bool{"eth0": true, "docker0": false, "lo": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &WebRTCManagerCtx{
+				config: &config.WebRTC{InterfacesAllow: tt.allow, InterfacesDeny: tt.deny},
+			}
+
+			filter := manager.interfaceFilter()
+			if tt.want == nil {
+				if filter != nil {
+					t.Error("interfaceFilter() is set, want all interfaces")
+				}
+				return
+			}
+
+			for name, want := range tt.want {
+				if got := filter(name); got != want {
+					t.Errorf("filter(%q) = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIPFilter(t *testing.T) {
+	manager := &WebRTCManagerCtx{config: &config.WebRTC{}}
+	if manager.ipFilter() != nil {
+		t.Fatal("ipFilter() is set, want all addresses")
+	}
+
+	manager.config.ExcludePrivateIPs = true
+	filter := manager.ipFilter()
+
+	for ip, want := range map[string]bool{
+		"10.0.0.1":    false,
+		"192.168.1.1": false,
+		"fd00::1":     false,
+		"8.8.8.8":     true,
+		"2001:db8::1": true,
+	} {
+		if got := filter(net.ParseIP(ip)); got != want {
+			t.Errorf("filter(%s) = %v, want %v", ip, got, want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"strconv"
 
+	"github.com/m1k1o/neko/server/internal/input"
 	"github.com/m1k1o/neko/server/pkg/types"
 
 	"github.com/rs/zerolog"
@@ -71,6 +72,7 @@ func (manager *WebRTCManagerCtx) handleLegacy(
 		}
 
 		manager.desktop.Move(int(payload.X), int(payload.Y))
+		input.Record(session, types.InputEvent{Type: types.InputMove, X: int(payload.X), Y: int(payload.Y)})
 	case OP_SCROLL:
 		payload := &PayloadScroll{}
 		if err := binary.Read(buffer, binary.LittleEndian, payload); err != nil {
@@ -84,6 +86,7 @@ func (manager *WebRTCManagerCtx) handleLegacy(
 			Msg("scroll")
 
 		manager.desktop.Scroll(int(payload.X), int(payload.Y), false)
+		input.Record(session, types.InputEvent{Type: types.InputScroll, DeltaX: int(payload.X), DeltaY: int(payload.Y)})
 	case OP_KEY_DOWN:
 		payload := &PayloadKey{}
 		if err := binary.Read(buffer, binary.LittleEndian, payload); err != nil {
@@ -98,6 +101,7 @@ func (manager *WebRTCManagerCtx) handleLegacy(
 			}
 
 			logger.Trace().Msgf("button down %d", payload.Key)
+			input.Record(session, types.InputEvent{Type: types.InputButtonDown, Code: uint32(payload.Key)})
 		} else {
 			err := manager.desktop.KeyDown(uint32(payload.Key))
 			if err != nil {
@@ -106,6 +110,7 @@ func (manager *WebRTCManagerCtx) handleLegacy(
 			}
 
 			logger.Trace().Msgf("key down %d", payload.Key)
+			input.Record(session, types.InputEvent{Type: types.InputKeyDown, Code: uint32(payload.Key)})
 		}
 	case OP_KEY_UP:
 		payload := &PayloadKey{}
@@ -122,6 +127,7 @@ func (manager *WebRTCManagerCtx) handleLegacy(
 			}
 
 			logger.Trace().Msgf("button up %d", payload.Key)
+			input.Record(session, types.InputEvent{Type: types.InputButtonUp, Code: uint32(payload.Key)})
 		} else {
 			err := manager.desktop.KeyUp(uint32(payload.Key))
 			if err != nil {
@@ -130,6 +136,7 @@ func (manager *WebRTCManagerCtx) handleLegacy(
 			}
 
 			logger.Trace().Msgf("key up %d", payload.Key)
+			input.Record(session, types.InputEvent{Type: types.InputKeyUp, Code: uint32(payload.Key)})
 		}
 	case OP_KEY_CLK:
 		// unused
@@ -0,0 +1,94 @@
+package webrtc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/m1k1o/neko/server/internal/input"
+	"github.com/m1k1o/neko/server/pkg/types"
+
+	"github.com/rs/zerolog"
+)
+
+type legacyTestDesktop struct {
+	types.DesktopManager
+}
+
+func (legacyTestDesktop) Move(x, y int)                              {}
+func (legacyTestDesktop) Scroll(deltaX, deltaY int, controlKey bool) {}
+func (legacyTestDesktop) ButtonDown(code uint32) error               { return nil }
+func (legacyTestDesktop) ButtonUp(code uint32) error                 { return nil }
+func (legacyTestDesktop) KeyDown(code uint32) error                  { return nil }
+func (legacyTestDesktop) KeyUp(code uint32) error                    { return nil }
+
+type legacyTestSession struct {
+	types.Session
+	values map[string]any
+}
+
+func (s *legacyTestSession) LegacyIsHost() bool { return true }
+
+func (s *legacyTestSession) Value(key string) (any, bool) {
+	value, ok := s.values[key]
+	return value, ok
+}
+
+func (s *legacyTestSession) SetValue(key string, value any) {
+	s.values[key] = value
+}
+
+func (s *legacyTestSession) DeleteValue(key string) {
+	delete(s.values, key)
+}
+
+func legacyPayload(t *testing.T, payload any) []byte {
+	t.Helper()
+
+	buffer := &bytes.Buffer{}
+	if err := binary.Write(buffer, binary.LittleEndian, payload); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+// Legacy clients must keep host sessions active the same way as current clients
+func TestLegacyHandlerRecordsInput(t *testing.T) {
+	manager := &WebRTCManagerCtx{desktop: legacyTestDesktop{}}
+
+	tests := []struct {
+		name    string
+		payload any
+		want    types.InputEventType
+	}{
+		{"move", PayloadMove{PayloadHeader{OP_MOVE, 7}, 10, 20}, types.InputMove},
+		{"scroll", PayloadScroll{PayloadHeader{OP_SCROLL, 7}, 1, -1}, types.InputScroll},
+		{"button down", PayloadKey{PayloadHeader{OP_KEY_DOWN, 11}, 1}, types.InputButtonDown},
+		{"key down", PayloadKey{PayloadHeader{OP_KEY_DOWN, 11}, 65}, types.InputKeyDown},
+		{"button up", PayloadKey{PayloadHeader{OP_KEY_UP, 11}, 1}, types.InputButtonUp},
+		{"key up", PayloadKey{PayloadHeader{OP_KEY_UP, 11}, 65}, types.InputKeyUp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &legacyTestSession{values: map[string]any{}}
+			input.StartRecording(session)
+
+			if err := manager.handleLegacy(zerolog.Nop(), legacyPayload(t, tt.payload), session); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, ok := input.LastInput(session); !ok {
+				t.Error("expected input activity to be recorded")
+			}
+
+			recording, err := input.Recording(session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(recording.Events) != 1 || recording.Events[0].Type != tt.want {
+				t.Errorf("recorded events = %+v, want %s", recording.Events, tt.want)
+			}
+		})
+	}
+}
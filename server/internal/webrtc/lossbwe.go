@@ -0,0 +1,143 @@
+package webrtc
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+)
+
+// thresholds of loss based control from draft-ietf-rmcat-gcc-02 section 6
+const (
+	lossIncreaseThreshold = 0.02
+	lossIncreaseFactor    = 1.05
+	lossDecreaseThreshold = 0.1
+	// minimum time between two changes in the same direction
+	lossChangeInterval = 200 * time.Millisecond
+
+	lossMinBitrate = 100_000     // 100 kbit
+	lossMaxBitrate = 100_000_000 // 100 mbit
+)
+
+// lossBasedBWE estimates bandwidth only from packet loss reported by the client
+// in receiver reports. Unlike GCC it needs no transport-wide feedback, but it
+// lowers bitrate only once packets are already lost. Maximum bitrate estimated
+// by the client (REMB), if it sends one, caps the estimate.
+type lossBasedBWE struct {
+	mu           sync.Mutex
+	bitrate      int
+	remb         int
+	averageLoss  float64
+	lastUpdate   time.Time
+	lastIncrease time.Time
+	lastDecrease time.Time
+	onChange     func(bitrate int)
+}
+
+func newLossBasedBWE(initialBitrate int) *lossBasedBWE {
+	return &lossBasedBWE{
+		bitrate: min(max(initialBitrate, lossMinBitrate), lossMaxBitrate),
+	}
+}
+
+func (e *lossBasedBWE) AddStream(_ *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	return writer
+}
+
+func (e *lossBasedBWE) WriteRTCP(pkts []rtcp.Packet, _ interceptor.Attributes) error {
+	for _, pkt := range pkts {
+		switch pkt := pkt.(type) {
+		case *rtcp.ReceiverReport:
+			for _, report := range pkt.Reports {
+				e.update(float64(report.FractionLost)/256, time.Now())
+			}
+		case *rtcp.ReceiverEstimatedMaximumBitrate:
+			e.updateRemb(int(pkt.Bitrate))
+		}
+	}
+	return nil
+}
+
+// updateRemb caps bitrate to maximum bitrate estimated by the client.
+func (e *lossBasedBWE) updateRemb(remb int) {
+	e.mu.Lock()
+
+	e.remb = remb
+	bitrate := e.capped(e.bitrate)
+
+	changed := bitrate != e.bitrate
+	e.bitrate = bitrate
+	onChange := e.onChange
+	e.mu.Unlock()
+
+	if changed && onChange != nil {
+		onChange(bitrate)
+	}
+}
+
+func (e *lossBasedBWE) capped(bitrate int) int {
+	maxBitrate := lossMaxBitrate
+	if e.remb > 0 {
+		maxBitrate = min(e.remb, maxBitrate)
+	}
+	return min(max(bitrate, lossMinBitrate), maxBitrate)
+}
+
+// update adjusts bitrate to fraction of packets lost since the last report.
+func (e *lossBasedBWE) update(loss float64, now time.Time) {
+	e.mu.Lock()
+
+	// short term loss is smoothed, so that single report does not decide
+	e.averageLoss = loss + math.Exp(-float64(now.Sub(e.lastUpdate).Milliseconds())/200)*(e.averageLoss-loss)
+	e.lastUpdate = now
+
+	bitrate := e.bitrate
+	if increaseLoss := math.Max(e.averageLoss, loss); increaseLoss < lossIncreaseThreshold && now.Sub(e.lastIncrease) > lossChangeInterval {
+		e.lastIncrease = now
+		bitrate = int(float64(bitrate) * lossIncreaseFactor)
+	} else if decreaseLoss := math.Min(e.averageLoss, loss); decreaseLoss > lossDecreaseThreshold && now.Sub(e.lastDecrease) > lossChangeInterval {
+		e.lastDecrease = now
+		bitrate = int(float64(bitrate) * (1 - 0.5*decreaseLoss))
+	}
+	bitrate = e.capped(bitrate)
+
+	changed := bitrate != e.bitrate
+	e.bitrate = bitrate
+	onChange := e.onChange
+	e.mu.Unlock()
+
+	if changed && onChange != nil {
+		onChange(bitrate)
+	}
+}
+
+func (e *lossBasedBWE) GetTargetBitrate() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.bitrate
+}
+
+func (e *lossBasedBWE) OnTargetBitrateChange(f func(bitrate int)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.onChange = f
+}
+
+func (e *lossBasedBWE) GetStats() map[string]any {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return map[string]any{
+		"lossTargetBitrate": e.bitrate,
+		"averageLoss":       e.averageLoss,
+		"rembBitrate":       e.remb,
+	}
+}
+
+func (e *lossBasedBWE) Close() error {
+	return nil
+}
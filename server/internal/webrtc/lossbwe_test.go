@@ -0,0 +1,90 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+func TestLossBasedBWE(t *testing.T) {
+	e := newLossBasedBWE(1_000_000)
+
+	changes := 0
+	e.OnTargetBitrateChange(func(bitrate int) { changes++ })
+
+	// no loss, bitrate is increased
+	now := time.Now()
+	e.update(0, now)
+	if got := e.GetTargetBitrate(); got != 1_050_000 {
+		t.Errorf("bitrate without loss = %d, want 1050000", got)
+	}
+
+	// moderate loss, bitrate is kept
+	now = now.Add(time.Second)
+	e.update(0.05, now)
+	if got := e.GetTargetBitrate(); got != 1_050_000 {
+		t.Errorf("bitrate with moderate loss = %d, want 1050000", got)
+	}
+
+	// high loss, bitrate is decreased
+	now = now.Add(time.Second)
+	e.update(0.2, now)
+	// by about half of the smoothed loss
+	if got := e.GetTargetBitrate(); got < 940_000 || got > 950_000 {
+		t.Errorf("bitrate with high loss = %d, want about 945000", got)
+	}
+
+	if changes != 2 {
+		t.Errorf("bitrate changes = %d, want 2", changes)
+	}
+}
+
+func TestLossBasedBWEReceiverReport(t *testing.T) {
+	e := newLossBasedBWE(1_000_000)
+
+	err := e.WriteRTCP([]rtcp.Packet{
+		&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{FractionLost: 128}}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("WriteRTCP() = %v", err)
+	}
+
+	if got := e.GetTargetBitrate(); got != 750_000 {
+		t.Errorf("bitrate after half of packets lost = %d, want 750000", got)
+	}
+}
+
+func TestLossBasedBWERemb(t *testing.T) {
+	e := newLossBasedBWE(1_000_000)
+
+	err := e.WriteRTCP([]rtcp.Packet{
+		&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 500_000},
+	}, nil)
+	if err != nil {
+		t.Fatalf("WriteRTCP() = %v", err)
+	}
+
+	if got := e.GetTargetBitrate(); got != 500_000 {
+		t.Errorf("bitrate after remb = %d, want 500000", got)
+	}
+
+	// no loss does not increase bitrate above remb
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Second)
+		e.update(0, now)
+	}
+
+	if got := e.GetTargetBitrate(); got != 500_000 {
+		t.Errorf("bitrate without loss = %d, want 500000", got)
+	}
+
+	// higher remb allows increase again
+	e.updateRemb(2_000_000)
+	e.update(0, now.Add(time.Second))
+
+	if got := e.GetTargetBitrate(); got != 525_000 {
+		t.Errorf("bitrate after remb increase = %d, want 525000", got)
+	}
+}
@@ -12,8 +12,13 @@ import (
 	"github.com/pion/interceptor"
 	"github.com/pion/interceptor/pkg/cc"
 	"github.com/pion/interceptor/pkg/gcc"
+	"github.com/pion/interceptor/pkg/report"
 	"github.com/pion/rtcp"
+	"github.com/pion/transport/v2"
+	"github.com/pion/transport/v2/stdnet"
 	"github.com/pion/webrtc/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
@@ -48,7 +53,7 @@ const (
 	rtcpPLIInterval = 3 * time.Second
 )
 
-func New(desktop types.DesktopManager, capture types.CaptureManager, config *config.WebRTC) *WebRTCManagerCtx {
+func New(desktop types.DesktopManager, capture types.CaptureManager, errors types.ErrorBus, config *config.WebRTC) *WebRTCManagerCtx {
 	logger := log.With().Str("module", "webrtc").Logger()
 
 	configuration := webrtc.Configuration{
@@ -75,7 +80,7 @@ func New(desktop types.DesktopManager, capture types.CaptureManager, config *con
 		configuration.ICEServers = ICEServers
 	}
 
-	return &WebRTCManagerCtx{
+	manager := &WebRTCManagerCtx{
 		logger:  logger,
 		config:  config,
 		metrics: newMetricsManager(),
@@ -84,9 +89,62 @@ func New(desktop types.DesktopManager, capture types.CaptureManager, config *con
 
 		desktop:     desktop,
 		capture:     capture,
-		curImage:    cursor.NewImage(logger, desktop),
+		errors:      errors,
+		curImage:    cursor.NewImage(logger, desktop, errors),
 		curPosition: cursor.NewPosition(logger),
+
+		publicIPs: map[string]string{},
+		regions:   map[string]string{},
+
+		dataChannelHandlers: map[string]types.WebRTCDataChannelHandler{},
+
+		mediaResume: newMediaResume(config.MediaResumeWindow),
+		failedPeers: newFailedPeers(config.FailedPeersThreshold, config.FailedPeersWindow),
+		videoPrefs:  newVideoPreferences(config.VideoPreferenceTTL),
+
+		admissionRejected: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "admission_rejected_total",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Total number of peers rejected because video pipelines were overloaded.",
+		}),
+
+		watchdogRecoveries: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name:      "watchdog_recoveries_total",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Total number of recovery actions taken for peers receiving no video.",
+		}, []string{"action"}),
+
+		timeToFirstFrame: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:      "time_to_first_frame_seconds",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Time from creating a peer until the client received its media.",
+			Buckets:   prometheus.ExponentialBuckets(0.25, 2, 8),
+		}, []string{"media"}),
+
+		avSyncOffset: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:      "av_sync_offset_seconds",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Estimated offset of audio behind video of connected peers, negative when audio is ahead.",
+			Buckets:   prometheus.LinearBuckets(-0.4, 0.05, 17),
+		}),
+	}
+
+	manager.relay = newRelayTracker(config.RelayMax)
+	manager.renegotiations = newRenegotiationLimiter(config.RenegotiationMax)
+
+	if config.DSCPAudio > 0 || config.DSCPVideo > 0 {
+		manager.dscp = newDSCPMarker(logger, config.DSCPAudio, config.DSCPVideo)
+	}
+
+	if slots := len(capture.Speakers()); slots > 0 {
+		manager.speakers = newSpeakers(slots, config.SpeakersThreshold, config.SpeakersHold)
 	}
+
+	return manager
 }
 
 type WebRTCManagerCtx struct {
@@ -97,6 +155,7 @@ type WebRTCManagerCtx struct {
 
 	desktop     types.DesktopManager
 	capture     types.CaptureManager
+	errors      types.ErrorBus
 	curImage    cursor.Image
 	curPosition cursor.Position
 
@@ -105,7 +164,47 @@ type WebRTCManagerCtx struct {
 	tcpMux ice.TCPMux
 	udpMux ice.UDPMux
 
-	camStop, micStop *func()
+	// shared webcam and microphone
+	cam, mic sharedMedia
+	// concurrently shared microphones, nil if only one can be shared
+	speakers *speakers
+	// shared media of sessions whose peers were closed
+	mediaResume *mediaResume
+	failedPeers *failedPeers
+	// video manually selected by sessions, used when they reconnect
+	videoPrefs *videoPreferences
+
+	// public IPs pinned per session
+	publicIPs   map[string]string
+	publicIPsMu sync.Mutex
+
+	// regions of clients pinned by session
+	regions   map[string]string
+	regionsMu sync.Mutex
+
+	// peers using relay connection
+	relay *relayTracker
+
+	// renegotiations in progress across all peers
+	renegotiations *renegotiationLimiter
+
+	// handlers of data channels opened by clients, by label
+	dataChannelHandlers   map[string]types.WebRTCDataChannelHandler
+	dataChannelHandlersMu sync.Mutex
+
+	// peers rejected because of overloaded pipelines
+	admissionRejected prometheus.Counter
+	// recovery actions of the media watchdog
+	watchdogRecoveries *prometheus.CounterVec
+	// time until media of new peers was received
+	timeToFirstFrame *prometheus.HistogramVec
+	// estimated audio/video offset of peers
+	avSyncOffset prometheus.Histogram
+
+	// marks outbound packets, nil if disabled
+	dscp *dscpMarker
+	// network for UDP sockets, nil if the default one is used
+	udpNet transport.Net
 }
 
 func (manager *WebRTCManagerCtx) Start() {
@@ -113,17 +212,45 @@ func (manager *WebRTCManagerCtx) Start() {
 
 	logger := pionlog.New(manager.logger)
 
+	// network setting socket buffers and marking outbound packets with DSCP
+	if manager.dscp != nil || manager.config.SocketReadBuffer > 0 || manager.config.SocketWriteBuffer > 0 {
+		n, err := stdnet.NewNet()
+		if err != nil {
+			manager.logger.Fatal().Err(err).Msg("unable to setup UDP network")
+		}
+
+		manager.udpNet = n
+		if manager.config.SocketReadBuffer > 0 || manager.config.SocketWriteBuffer > 0 {
+			manager.udpNet = newSocketBufferNet(manager.udpNet, manager.logger, manager.config.SocketReadBuffer, manager.config.SocketWriteBuffer)
+		}
+		if manager.dscp != nil {
+			manager.udpNet = manager.dscp.wrap(manager.udpNet)
+		}
+	}
+
 	// add TCP Mux listener
 	if manager.config.TCPMux > 0 {
-		tcpListener, err := net.ListenTCP("tcp", &net.TCPAddr{
-			IP:   net.IP{0, 0, 0, 0},
-			Port: manager.config.TCPMux,
-		})
+		var tcpListener *net.TCPListener
+		err := bindMux(manager.logger, "tcp", manager.config.TCPMux,
+			manager.config.MuxBindRetries, manager.config.MuxBindBackoff, manager.config.MuxBindFallback,
+			func(port int) (err error) {
+				tcpListener, err = net.ListenTCP("tcp", &net.TCPAddr{
+					IP:   net.IP{0, 0, 0, 0},
+					Port: port,
+				})
+				return
+			})
 
 		if err != nil {
 			manager.logger.Fatal().Err(err).Msg("unable to setup ice TCP mux")
 		}
 
+		// report port actually used, it differs when fallen back to ephemeral port
+		if port := tcpListener.Addr().(*net.TCPAddr).Port; port != manager.config.TCPMux {
+			manager.logger.Warn().Int("port", port).Msg("ice TCP mux bound to ephemeral port")
+			manager.config.TCPMux = port
+		}
+
 		manager.tcpMux = ice.NewTCPMuxDefault(ice.TCPMuxParams{
 			Listener:        tcpListener,
 			Logger:          logger.NewLogger("ice-tcp"),
@@ -135,13 +262,39 @@ func (manager *WebRTCManagerCtx) Start() {
 	// add UDP Mux listener
 	if manager.config.UDPMux > 0 {
 		var err error
-		manager.udpMux, err = ice.NewMultiUDPMuxFromPort(manager.config.UDPMux,
+		opts := []ice.UDPMuxFromPortOption{
 			ice.UDPMuxFromPortWithLogger(logger.NewLogger("ice-udp")),
-		)
+		}
+		if filter := manager.interfaceFilter(); filter != nil {
+			opts = append(opts, ice.UDPMuxFromPortWithInterfaceFilter(filter))
+		}
+		if filter := manager.ipFilter(); filter != nil {
+			opts = append(opts, ice.UDPMuxFromPortWithIPFilter(filter))
+		}
+		if manager.udpNet != nil {
+			opts = append(opts, ice.UDPMuxFromPortWithNet(manager.udpNet))
+		}
+
+		var udpMux *ice.MultiUDPMuxDefault
+		err = bindMux(manager.logger, "udp", manager.config.UDPMux,
+			manager.config.MuxBindRetries, manager.config.MuxBindBackoff, manager.config.MuxBindFallback,
+			func(port int) (err error) {
+				udpMux, err = ice.NewMultiUDPMuxFromPort(port, opts...)
+				return
+			})
 
 		if err != nil {
 			manager.logger.Fatal().Err(err).Msg("unable to setup ice UDP mux")
 		}
+
+		// report ports actually used, with ephemeral port each address has its own
+		for _, addr := range udpMux.GetListenAddresses() {
+			if udpAddr, ok := addr.(*net.UDPAddr); ok && udpAddr.Port != manager.config.UDPMux {
+				manager.logger.Warn().Str("addr", addr.String()).Msg("ice UDP mux bound to ephemeral port")
+			}
+		}
+
+		manager.udpMux = udpMux
 	}
 
 	manager.logger.Info().
@@ -153,7 +306,13 @@ func (manager *WebRTCManagerCtx) Start() {
 		Str("epr", fmt.Sprintf("%d-%d", manager.config.EphemeralMin, manager.config.EphemeralMax)).
 		Int("tcpmux", manager.config.TCPMux).
 		Int("udpmux", manager.config.UDPMux).
+		Int("socket-read-buffer", manager.config.SocketReadBuffer).
+		Int("socket-write-buffer", manager.config.SocketWriteBuffer).
+		Int("dscp-audio", manager.config.DSCPAudio).
+		Int("dscp-video", manager.config.DSCPVideo).
 		Msg("webrtc starting")
+
+	manager.logInterfaces()
 }
 
 func (manager *WebRTCManagerCtx) Shutdown() error {
@@ -169,12 +328,19 @@ func (manager *WebRTCManagerCtx) ICEServers() []types.ICEServer {
 	return manager.config.ICEServersFrontend
 }
 
-func (manager *WebRTCManagerCtx) newPeerConnection(logger zerolog.Logger, codecs []codec.RTPCodec) (*webrtc.PeerConnection, cc.BandwidthEstimator, error) {
+func (manager *WebRTCManagerCtx) newPeerConnection(logger zerolog.Logger, codecs []codec.RTPCodec, nat1To1IPs []string, relayAllowed bool) (*webrtc.PeerConnection, cc.BandwidthEstimator, *senderReports, error) {
 	// create media engine
 	engine := &webrtc.MediaEngine{}
 	for _, codec := range codecs {
 		if err := codec.Register(engine); err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
+		}
+	}
+
+	// audio level of shared microphones selects concurrent speakers
+	if manager.speakers != nil {
+		if err := engine.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{URI: audioLevelURI}, webrtc.RTPCodecTypeAudio); err != nil {
+			return nil, nil, nil, err
 		}
 	}
 
@@ -185,8 +351,15 @@ func (manager *WebRTCManagerCtx) newPeerConnection(logger zerolog.Logger, codecs
 
 	settings.DisableMediaEngineCopy(true)
 	settings.SetICETimeouts(disconnectedTimeout, failedTimeout, keepAliveInterval)
-	settings.SetNAT1To1IPs(manager.config.NAT1To1IPs, webrtc.ICECandidateTypeHost)
+	settings.SetNAT1To1IPs(nat1To1IPs, webrtc.ICECandidateTypeHost)
 	settings.SetLite(manager.config.ICELite)
+	settings.SetReceiveMTU(manager.config.ReceiveMTU)
+	if filter := manager.interfaceFilter(); filter != nil {
+		settings.SetInterfaceFilter(filter)
+	}
+	if filter := manager.ipFilter(); filter != nil {
+		settings.SetIPFilter(filter)
+	}
 	// make sure server answer sdp setup as passive, to not force DTLS renegotiation
 	// otherwise iOS renegotiation fails with: Failed to set SSL role for the transport.
 	settings.SetAnsweringDTLSRole(webrtc.DTLSRoleServer)
@@ -202,6 +375,9 @@ func (manager *WebRTCManagerCtx) newPeerConnection(logger zerolog.Logger, codecs
 		)
 	} else if manager.config.EphemeralMax != 0 {
 		_ = settings.SetEphemeralUDPPortRange(manager.config.EphemeralMin, manager.config.EphemeralMax)
+		if manager.udpNet != nil {
+			settings.SetNet(manager.udpNet)
+		}
 		networkType = append(networkType,
 			webrtc.NetworkTypeUDP4,
 			webrtc.NetworkTypeUDP6,
@@ -225,15 +401,19 @@ func (manager *WebRTCManagerCtx) newPeerConnection(logger zerolog.Logger, codecs
 
 	// create bandwidth estimator
 	estimatorChan := make(chan cc.BandwidthEstimator, 1)
-	if manager.config.Estimator.Enabled {
+	if conf := manager.config.Estimator; conf.Enabled {
 		congestionController, err := cc.NewInterceptor(func() (cc.BandwidthEstimator, error) {
+			if conf.CongestionControl == config.CongestionControlLoss {
+				return newLossBasedBWE(conf.InitialBitrate), nil
+			}
+
 			return gcc.NewSendSideBWE(
-				gcc.SendSideBWEInitialBitrate(manager.config.Estimator.InitialBitrate),
+				gcc.SendSideBWEInitialBitrate(conf.InitialBitrate),
 				gcc.SendSideBWEPacer(gcc.NewNoOpPacer()),
 			)
 		})
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 
 		congestionController.OnNewPeerConnection(func(id string, estimator cc.BandwidthEstimator) {
@@ -241,16 +421,36 @@ func (manager *WebRTCManagerCtx) newPeerConnection(logger zerolog.Logger, codecs
 		})
 
 		registry.Add(congestionController)
-		if err = webrtc.ConfigureTWCCHeaderExtensionSender(engine, registry); err != nil {
-			return nil, nil, err
+
+		// loss based control uses receiver reports, that are always sent
+		if conf.CongestionControl == config.CongestionControlGCC {
+			if err = webrtc.ConfigureTWCCHeaderExtensionSender(engine, registry); err != nil {
+				return nil, nil, nil, err
+			}
 		}
+
+		logger.Info().Str("congestion_control", conf.CongestionControl).Msg("using bandwidth estimator")
 	} else {
 		// no estimator, send nil
 		estimatorChan <- nil
 	}
 
-	if err := webrtc.RegisterDefaultInterceptors(engine, registry); err != nil {
-		return nil, nil, err
+	if err := webrtc.ConfigureNack(engine, registry); err != nil {
+		return nil, nil, nil, err
+	}
+
+	receiverReports, err := report.NewReceiverInterceptor()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	registry.Add(receiverReports)
+
+	// sender reports of all tracks use the same clock
+	senderReports := newSenderReports(manager.config.SenderReport.Interval, manager.config.SenderReport.CaptureClock)
+	registry.Add(senderReports)
+
+	if err := webrtc.ConfigureTWCCSender(engine, registry); err != nil {
+		return nil, nil, nil, err
 	}
 
 	// create new API
@@ -262,12 +462,23 @@ func (manager *WebRTCManagerCtx) newPeerConnection(logger zerolog.Logger, codecs
 
 	// create new peer connection
 	configuration := manager.webrtcConfiguration
+	if !relayAllowed {
+		configuration.ICEServers = directWebRTCICEServers(configuration.ICEServers)
+	}
+
 	connection, err := api.NewPeerConnection(configuration)
-	return connection, <-estimatorChan, err
+	return connection, <-estimatorChan, senderReports, err
 }
 
-func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.SessionDescription, types.WebRTCPeer, error) {
+func (manager *WebRTCManagerCtx) CreatePeer(session types.Session, dataOnly bool) (*webrtc.SessionDescription, types.WebRTCPeer, error) {
 	id := atomic.AddInt32(&manager.peerId, 1)
+	createdAt := time.Now()
+
+	// counter restarts with the server, random id is unique across restarts
+	uid, err := utils.NewUID(16)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// get metrics for session
 	metrics := manager.metrics.getBySession(session)
@@ -275,18 +486,65 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 
 	// add session id to logger context
 	logger := manager.logger.With().Str("session_id", session.ID()).Int32("peer_id", id).Logger()
-	logger.Info().Msg("creating webrtc peer")
+	logger.Info().Bool("data_only", dataOnly).Msg("creating webrtc peer")
+
+	var video types.StreamSelectorManager
+	var audio types.StreamSinkManager
+	var videoCodec codec.RTPCodec
+	codecs := []codec.RTPCodec{}
+
+	// data only peers do not use any pipelines
+	if !dataOnly {
+		// all videos must have the same codec
+		video = manager.capture.Video()
+		if len(video.IDs()) == 0 {
+			return nil, nil, types.ErrWebRTCNoVideoStreams
+		}
 
-	// all audios must have the same codec
-	audio := manager.capture.Audio()
-	audioCodec := audio.Codec()
+		videoCodec = video.Codec()
+		codecs = append(codecs, videoCodec)
+
+		// all audios must have the same codec, audio might be disabled
+		audio = manager.capture.Audio()
+		if audio != nil {
+			codecs = append(codecs, audio.Codec())
+
+			// registered after the primary codec, so that microphones of clients keep
+			// sending the primary codec, redundancy is only sent to them
+			if manager.audioRED(audio.Codec()) {
+				codecs = append(codecs, codec.RED(audio.Codec()))
+			}
+		}
+	}
 
-	// all videos must have the same codec
-	video := manager.capture.Video()
-	videoCodec := video.Codec()
+	nat1To1IPs := manager.nat1To1IPs(session)
+	logger.Info().Strs("nat1to1", nat1To1IPs).Msg("using public IPs")
 
-	connection, estimator, err := manager.newPeerConnection(
-		logger, []codec.RTPCodec{audioCodec, videoCodec})
+	// adding peers to overloaded pipelines would degrade everyone
+	if !dataOnly {
+		if err := manager.admit(logger); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// servers closest to the client are preferred
+	iceServers, region := manager.iceServers(session)
+	if turn, ok := firstTURNServer(iceServers); ok {
+		logger.Info().Str("region", region).Strs("urls", turn.URLs).Msg("using turn server")
+	}
+
+	// over relay limit, new peers are allowed only direct connection or rejected
+	relayAllowed := manager.relay.allowed()
+	if !relayAllowed {
+		if manager.config.RelayOverflow == config.RelayOverflowReject {
+			return nil, nil, types.ErrWebRTCRelayLimit
+		}
+
+		logger.Info().Msg("relay limit reached, allowing only direct connection")
+		iceServers = directICEServers(iceServers)
+	}
+
+	connection, estimator, senderReports, err := manager.newPeerConnection(logger, codecs, nat1To1IPs, relayAllowed)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -307,26 +565,42 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 		})
 	}
 
-	// audio track
-	audioTrack, err := NewTrack(logger, audioCodec, connection)
-	if err != nil {
-		return nil, nil, err
-	}
+	// audio track, only if audio is enabled
+	var audioTrack *Track
+	audioOptions := []trackOption{WithSenderReports(senderReports)}
+	if audio != nil {
+		if manager.audioRED(audio.Codec()) {
+			audioOptions = append(audioOptions, WithRED(manager.config.AudioRED))
+		}
+
+		audioTrack, err = NewTrack(logger, audio.Codec(), connection, audioOptions...)
+		if err != nil {
+			return nil, nil, err
+		}
 
-	// we disable audio by default manually
-	audioTrack.SetPaused(true)
+		// we disable audio by default manually
+		audioTrack.SetPaused(true)
 
-	// set stream for audio track
-	_, err = audioTrack.SetStream(audio)
-	if err != nil {
-		return nil, nil, err
+		// audio packets are marked differently than video
+		if manager.dscp != nil {
+			manager.dscp.addAudio(audioTrack.SSRC())
+		}
+
+		// set stream for audio track
+		_, err = audioTrack.SetStream(audio)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
-	// video track
+	// video track, only if media is requested
+	var videoTrack *Track
 	videoRtcp := make(chan []rtcp.Packet, 1)
-	videoTrack, err := NewTrack(logger, videoCodec, connection, WithRtcpChan(videoRtcp))
-	if err != nil {
-		return nil, nil, err
+	if !dataOnly {
+		videoTrack, err = NewTrack(logger, videoCodec, connection, WithRtcpChan(videoRtcp), WithSenderReports(senderReports))
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	//
@@ -341,6 +615,7 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 	}
 
 	peer := &WebRTCPeerCtx{
+		id:         uid,
 		logger:     logger,
 		session:    session,
 		metrics:    metrics,
@@ -362,16 +637,36 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 		video: video,
 		audio: audio,
 		// tracks & channels
-		audioTrack:  audioTrack,
-		videoTrack:  videoTrack,
-		dataChannel: dataChannel,
-		rtcpChannel: videoRtcp,
+		audioTrack:   audioTrack,
+		audioOptions: audioOptions,
+		videoTrack:   videoTrack,
+		dataChannel:  dataChannel,
+		rtcpChannel:  videoRtcp,
+		closed:       make(chan struct{}),
 		// config
+		dataOnly:        dataOnly,
+		cursorMode:      types.CursorModeFull,
 		iceTrickle:      manager.config.ICETrickle,
+		iceServers:      iceServers,
+		relayAllowed:    relayAllowed,
 		estimatorConfig: manager.config.Estimator,
 		audioDisabled:   true, // we disable audio by default manually
+		microphoneRoute: manager.config.MicrophoneRoute,
+		microphoneMix:   manager.capture.MicrophoneMix(),
+		dscp:            manager.dscp,
+		// fast start
+		fastStartDuration: manager.config.FastStartDuration,
+		fastStartVideo:    manager.config.FastStartVideo,
+		// manually selected video
+		videoCap:   manager.videoPrefs.get(session.ID()),
+		videoPrefs: manager.videoPrefs,
 	}
 
+	connection.SCTP().Transport().ICETransport().OnSelectedCandidatePairChange(func(pair *webrtc.ICECandidatePair) {
+		logger.Info().Str("pair", pair.String()).Msg("selected candidate pair changed")
+		manager.relay.update(peer, pair)
+	})
+
 	connection.OnTrack(func(track *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
 		logger := logger.With().
 			Str("kind", track.Kind().String()).
@@ -380,7 +675,7 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 
 		logger.Info().Msgf("received new remote track")
 
-		if !session.Profile().CanShareMedia {
+		if !session.Profile().CanShareMedia || dataOnly || session.FeatureDisabled(types.FeatureMediaSharing) {
 			err := receiver.Stop()
 			logger.Warn().Err(err).Msg("media sharing is disabled for this session")
 			return
@@ -396,36 +691,32 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 
 		var srcManager types.StreamSrcManager
 
-		stopped := false
+		// can be called from another goroutine, when replaced or session closed
+		var stopOnce sync.Once
 		stopFn := func() {
-			if stopped {
-				return
-			}
-
-			stopped = true
-			err := receiver.Stop()
-			srcManager.Stop()
-			logger.Err(err).Msg("remote track stopped")
+			stopOnce.Do(func() {
+				err := receiver.Stop()
+				srcManager.Stop()
+				logger.Err(err).Msg("remote track stopped")
+			})
 		}
 
 		if track.Kind() == webrtc.RTPCodecTypeAudio {
-			// audio -> microphone
-			srcManager = manager.capture.Microphone()
+			// audio -> microphone and/or outbound audio
 			defer stopFn()
 
-			if manager.micStop != nil {
-				(*manager.micStop)()
+			if manager.speakers != nil {
+				srcManager = manager.speakerSrc(logger, session.ID(), peer.MicrophoneRoute(), audioLevelExtension(receiver), stopFn)
+			} else {
+				srcManager = manager.microphoneSrc(peer.MicrophoneRoute())
+				manager.mic.replace(session.ID(), stopFn)
 			}
-			manager.micStop = &stopFn
 		} else if track.Kind() == webrtc.RTPCodecTypeVideo {
 			// video -> webcam
 			srcManager = manager.capture.Webcam()
 			defer stopFn()
 
-			if manager.camStop != nil {
-				(*manager.camStop)()
-			}
-			manager.camStop = &stopFn
+			manager.cam.replace(session.ID(), stopFn)
 		} else {
 			err := receiver.Stop()
 			logger.Warn().Err(err).Msg("remote track with unsupported codec type")
@@ -435,36 +726,66 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 		err := srcManager.Start(codec)
 		if err != nil {
 			logger.Err(err).Msg("failed to start pipeline")
+			manager.errors.Report("webrtc", "remote_track_pipeline", session, err)
+
+			// let the sharer know, that the media is not received
+			session.Send(event.SIGNAL_MEDIA_UNAVAILABLE, mediaUnavailable(track.Kind(), err))
 			return
 		}
 
+		isVideo := track.Kind() == webrtc.RTPCodecTypeVideo
+		maxBitrate := manager.inboundMaxBitrate(isVideo)
+
+		// ask the sender to stay below max bitrate, its encoder honors it
+		// only if goog-remb feedback was negotiated for the video codec
+		var remb []rtcp.Packet
+		if isVideo && maxBitrate > 0 {
+			remb = []rtcp.Packet{
+				&rtcp.ReceiverEstimatedMaximumBitrate{
+					Bitrate: float32(maxBitrate * 1000),
+					SSRCs:   []uint32{uint32(track.SSRC())},
+				},
+			}
+
+			if err := connection.WriteRTCP(remb); err != nil {
+				logger.Err(err).Msg("remote track rtcp send err")
+			}
+		}
+
+		// drop packets exceeding max bitrate, for video the periodic
+		// keyframe request allows the decoder to recover afterwards
+		push := srcManager.Push
+		if maxBitrate > 0 {
+			limiter := newInboundLimiter(maxBitrate)
+			push = func(data []byte) {
+				if limiter.allow(len(data), time.Now()) {
+					srcManager.Push(data)
+				} else if limiter.dropped == 1 {
+					logger.Warn().Int("max_bitrate", maxBitrate).Msg("remote track exceeds max bitrate, dropping packets")
+				}
+			}
+		}
+
 		ticker := time.NewTicker(rtcpPLIInterval)
 		defer ticker.Stop()
 
 		go func() {
 			for range ticker.C {
-				err := connection.WriteRTCP([]rtcp.Packet{
+				err := connection.WriteRTCP(append([]rtcp.Packet{
 					&rtcp.PictureLossIndication{
 						MediaSSRC: uint32(track.SSRC()),
 					},
-				})
+				}, remb...))
 
 				if err != nil {
 					logger.Err(err).Msg("remote track rtcp send err")
+					manager.errors.Report("webrtc", "rtcp_send", session, err)
 				}
 			}
 		}()
 
-		buf := make([]byte, 1400)
-		for {
-			i, _, err := track.Read(buf)
-			if err != nil {
-				logger.Warn().Err(err).Msg("failed read from remote track")
-				break
-			}
-
-			srcManager.Push(buf[:i])
-		}
+		err = readRemoteTrack(track, manager.config.ReceiveMTU, push)
+		logger.Warn().Err(err).Msg("failed read from remote track")
 
 		logger.Info().Msg("remote track data finished")
 	})
@@ -472,6 +793,18 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 	connection.OnDataChannel(func(dc *webrtc.DataChannel) {
 		logger.Info().Interface("data_channel", dc).Msg("got remote data channel")
 
+		// diagnostics data channel is created by client
+		if dc.Label() == diagnosticsDataChannelLabel {
+			manager.handleDiagnostics(logger, dc)
+			return
+		}
+
+		// auxiliary data channels claimed by plugins
+		if handler, ok := manager.dataChannelHandler(dc.Label()); ok {
+			handler(session, dc)
+			return
+		}
+
 		//
 		// old implementation created a new data channel on client side
 		// new implementation creates a new data channel on server side
@@ -482,32 +815,74 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 			dc.OnMessage(func(message webrtc.DataChannelMessage) {
 				if err := manager.handleLegacy(logger, message.Data, session); err != nil {
 					logger.Err(err).Msg("data handle failed")
+					manager.errors.Report("webrtc", "data_handle", session, err)
 				}
 			})
 
 			// handle legacy data channel
 			peer.dataChannel = dc
+			return
 		}
+
+		manager.handleUnknownDataChannel(logger, dc)
 	})
 
 	var once sync.Once
+	// client that changed network needs new candidates to recover
+	var restart func()
+	if manager.config.DisconnectedRestart {
+		restart = func() {
+			logger.Info().Msg("connection disconnected, restarting ice")
+			if err := peer.RestartICE(); err != nil {
+				logger.Err(err).Msg("failed to restart ice")
+				manager.errors.Report("webrtc", "disconnected_restart", session, err)
+			}
+		}
+	}
+
+	// disconnected connection gets a chance to recover before it is destroyed
+	grace := newDisconnectGrace(manager.config.DisconnectedGrace, restart, func() {
+		logger.Info().Msg("connection did not recover, destroying peer")
+		peer.Destroy()
+	})
+
 	connection.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		grace.handle(state)
+
 		switch state {
 		case webrtc.PeerConnectionStateConnected:
+			manager.failedPeers.connected(session.ID())
 			session.SetWebRTCConnected(peer, true)
-		case webrtc.PeerConnectionStateDisconnected,
-			webrtc.PeerConnectionStateFailed:
-			peer.Destroy()
+			if !dataOnly {
+				manager.resumeSharedMedia(session)
+			}
+		case webrtc.PeerConnectionStateFailed:
+			metrics.NewFailure()
+			manager.peerFailed(session)
 		case webrtc.PeerConnectionStateClosed:
 			// ensure we only run this once
 			once.Do(func() {
+				// replaced peers do not own shared media anymore
+				if session.GetWebRTCPeer() == peer {
+					manager.rememberSharedMedia(session)
+					// remembered from now on, peer could be connected for long
+					manager.videoPrefs.remember(session.ID(), peer.Video().Cap)
+				}
+
 				session.SetWebRTCConnected(peer, false)
-				//
-				// TODO: Shutdown peer?
-				//
-				audioTrack.Shutdown()
-				videoTrack.Shutdown()
+				manager.relay.remove(peer)
+				// data channel might not be closed properly, when peer is
+				// replaced, so we make sure to remove cursor listeners
+				manager.curImage.RemoveListener(peer)
+				manager.curPosition.RemoveListener(peer)
+				// audio track might have been added or removed since
+				peer.shutdownAudioTrack()
+				if videoTrack != nil {
+					videoTrack.Shutdown()
+				}
 				close(videoRtcp)
+				close(peer.closed)
+				peer.renegotiation.finish()
 			})
 		}
 
@@ -515,26 +890,17 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 	})
 
 	dataChannel.OnOpen(func() {
-		manager.curImage.AddListener(peer)
-		manager.curPosition.AddListener(peer)
-
-		// send initial cursor image
-		cur, img, err := manager.curImage.GetCurrent()
-		if err == nil {
-			err := peer.SendCursorImage(cur, img)
-			if err != nil {
-				logger.Err(err).Msg("failed to set cursor image")
-			}
-		} else {
-			logger.Err(err).Msg("failed to get cursor image")
+		// data only peers do not receive cursor
+		if dataOnly {
+			return
 		}
 
-		// send initial cursor position
-		x, y := manager.desktop.GetCursorPosition()
-		err = peer.SendCursorPosition(x, y)
-		if err != nil {
-			logger.Err(err).Msg("failed to set cursor position")
-		}
+		// from now on, cursor mode changes are applied right away
+		peer.mu.Lock()
+		peer.cursorApply = func() { manager.applyCursorMode(peer) }
+		peer.mu.Unlock()
+
+		manager.applyCursorMode(peer)
 	})
 
 	dataChannel.OnClose(func() {
@@ -559,36 +925,71 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 	// offer, otherwise it can fire and intercept sucessful negotiation
 
 	connection.OnNegotiationNeeded(func() {
-		logger.Warn().Msg("negotiation is needed")
+		manager.negotiationNeeded(peer)
+	})
 
-		if connection.SignalingState() != webrtc.SignalingStateStable {
-			logger.Warn().Msg("connection isn't stable yet; postponing...")
-			return
-		}
+	// start metrics collectors
+	go metrics.connectionStats(connection)
 
-		offer, err := peer.CreateOffer(false)
-		if err != nil {
-			logger.Err(err).Msg("sdp offer failed")
-			return
-		}
+	// send connection statistics to client, optional
+	if manager.config.StatsInterval > 0 {
+		go manager.statsMonitor(peer)
+	}
 
-		session.Send(
-			event.SIGNAL_OFFER,
-			message.SignalDescription{
-				SDP: offer.SDP,
-			})
-	})
+	// the rest is needed only for media
+	if dataOnly {
+		return offer, peer, nil
+	}
 
-	// start metrics collectors
 	go metrics.rtcpReceiver(videoRtcp)
-	go metrics.connectionStats(connection)
 
 	// start estimator reader
 	go peer.estimatorReader()
 
+	// let client know when media is received
+	go manager.mediaPlaying(peer, audioTrack, videoTrack, createdAt)
+
+	// recover peers receiving no video, optional
+	if manager.config.WatchdogInterval > 0 {
+		go manager.mediaWatchdog(peer)
+	}
+
+	// estimate audio/video sync, optional
+	if manager.config.AVSync.Interval > 0 {
+		go manager.avSyncMonitor(peer)
+	}
+
 	return offer, peer, nil
 }
 
+// ClosePeers closes all WebRTC resources of a session: its peer connection,
+// cursor listeners and shared webcam or microphone owned by the session.
+func (manager *WebRTCManagerCtx) ClosePeers(session types.Session) {
+	if peer := session.GetWebRTCPeer(); peer != nil {
+		// listeners are removed also when connection gets closed, but
+		// that happens asynchronously, so we remove them right away
+		manager.curImage.RemoveListener(peer)
+		manager.curPosition.RemoveListener(peer)
+		peer.Destroy()
+	}
+
+	manager.rememberSharedMedia(session)
+	manager.mic.stopOwnedBy(session.ID())
+	manager.cam.stopOwnedBy(session.ID())
+	if manager.speakers != nil {
+		manager.speakers.stopOwnedBy(session.ID())
+	}
+
+	manager.unpinPublicIP(session)
+	manager.unpinRegion(session)
+}
+
+// ForgetSession removes state kept for the session after its peers were
+// closed, so that it does not outlive the session.
+func (manager *WebRTCManagerCtx) ForgetSession(session types.Session) {
+	manager.mediaResume.forget(session.ID())
+}
+
 func (manager *WebRTCManagerCtx) SetCursorPosition(x, y int) {
 	manager.curPosition.Set(x, y)
 }
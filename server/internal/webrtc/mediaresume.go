@@ -0,0 +1,116 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// mediaResume remembers media shared by sessions whose peers were closed,
+// so that the client can be asked to share them again when it reconnects.
+type mediaResume struct {
+	mu       sync.Mutex
+	window   time.Duration
+	sessions map[string]resumableMedia
+}
+
+type resumableMedia struct {
+	microphone bool
+	webcam     bool
+	until      time.Time
+}
+
+func newMediaResume(window time.Duration) *mediaResume {
+	return &mediaResume{
+		window:   window,
+		sessions: map[string]resumableMedia{},
+	}
+}
+
+func (r *mediaResume) remember(sessionId string, microphone, webcam bool) {
+	if r.window <= 0 || (!microphone && !webcam) {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+
+	// sessions that did not reconnect within the window are forgotten
+	for id, media := range r.sessions {
+		if now.After(media.until) {
+			delete(r.sessions, id)
+		}
+	}
+
+	// media stopped by an earlier peer of the session is kept
+	media := r.sessions[sessionId]
+
+	r.sessions[sessionId] = resumableMedia{
+		microphone: media.microphone || microphone,
+		webcam:     media.webcam || webcam,
+		until:      now.Add(r.window),
+	}
+}
+
+// forget removes media remembered for the session.
+func (r *mediaResume) forget(sessionId string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.sessions, sessionId)
+}
+
+// take returns media the session was sharing, if it reconnected within the window.
+func (r *mediaResume) take(sessionId string) (microphone, webcam bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	media, ok := r.sessions[sessionId]
+	delete(r.sessions, sessionId)
+
+	if !ok || time.Now().After(media.until) {
+		return false, false
+	}
+
+	return media.microphone, media.webcam
+}
+
+// rememberSharedMedia remembers media shared by the session, before its peers are closed.
+func (manager *WebRTCManagerCtx) rememberSharedMedia(session types.Session) {
+	manager.mediaResume.remember(session.ID(),
+		manager.mic.ownedBy(session.ID()) ||
+			(manager.speakers != nil && manager.speakers.ownedBy(session.ID())),
+		manager.cam.ownedBy(session.ID()))
+}
+
+// resumeSharedMedia asks the client to share media again, that it was sharing
+// before its peer was closed. New tracks take over the devices, even if they
+// were shared by another session meanwhile.
+func (manager *WebRTCManagerCtx) resumeSharedMedia(session types.Session) {
+	microphone, webcam := manager.mediaResume.take(session.ID())
+	if !microphone && !webcam {
+		return
+	}
+
+	if !session.Profile().CanShareMedia || session.FeatureDisabled(types.FeatureMediaSharing) {
+		return
+	}
+
+	manager.logger.Info().
+		Str("session_id", session.ID()).
+		Bool("microphone", microphone).
+		Bool("webcam", webcam).
+		Msg("asking client to resume shared media")
+
+	session.Send(
+		event.SIGNAL_MEDIA_RESUME,
+		message.SignalMediaResume{
+			Microphone: microphone,
+			Webcam:     webcam,
+		})
+}
@@ -0,0 +1,86 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMediaResume(t *testing.T) {
+	r := newMediaResume(time.Minute)
+
+	r.remember("a", true, false)
+	r.remember("a", false, true)
+	r.remember("b", false, false)
+
+	if mic, cam := r.take("a"); !mic || !cam {
+		t.Errorf("take(a) = %v, %v, want both", mic, cam)
+	}
+
+	// taken only once
+	if mic, cam := r.take("a"); mic || cam {
+		t.Errorf("second take(a) = %v, %v, want none", mic, cam)
+	}
+
+	if mic, cam := r.take("b"); mic || cam {
+		t.Errorf("take(b) = %v, %v, want none", mic, cam)
+	}
+}
+
+func TestMediaResumeExpired(t *testing.T) {
+	r := newMediaResume(10 * time.Millisecond)
+	r.remember("a", true, true)
+
+	time.Sleep(20 * time.Millisecond)
+
+	if mic, cam := r.take("a"); mic || cam {
+		t.Errorf("take(a) = %v, %v after window, want none", mic, cam)
+	}
+}
+
+// sessions that never reconnect do not accumulate
+func TestMediaResumePruned(t *testing.T) {
+	r := newMediaResume(10 * time.Millisecond)
+	r.remember("a", true, true)
+
+	time.Sleep(20 * time.Millisecond)
+	r.remember("b", true, false)
+
+	r.mu.Lock()
+	_, ok := r.sessions["a"]
+	r.mu.Unlock()
+	if ok {
+		t.Error("expired media of a was not pruned")
+	}
+}
+
+func TestMediaResumeForget(t *testing.T) {
+	manager := &WebRTCManagerCtx{mediaResume: newMediaResume(time.Minute)}
+	manager.mediaResume.remember("a", true, true)
+
+	// deleted session does not come back
+	manager.ForgetSession(&sharedMediaTestSession{id: "a"})
+
+	if mic, cam := manager.mediaResume.take("a"); mic || cam {
+		t.Errorf("take(a) = %v, %v after session was forgotten, want none", mic, cam)
+	}
+}
+
+// reconnecting sharer takes the device back from whoever replaced it
+func TestSharedMediaReclaim(t *testing.T) {
+	m := sharedMedia{}
+	stopped := map[string]int{}
+
+	m.replace("a", func() { stopped["a"]++ })
+	m.stopOwnedBy("a")
+	m.replace("b", func() { stopped["b"]++ })
+
+	if m.ownedBy("a") || !m.ownedBy("b") {
+		t.Fatal("media is not owned by b")
+	}
+
+	// a reconnects and takes the device back
+	m.replace("a", func() { stopped["a2"]++ })
+	if !m.ownedBy("a") || stopped["b"] != 1 || stopped["a2"] != 0 {
+		t.Errorf("unexpected state after reclaim, stopped %v", stopped)
+	}
+}
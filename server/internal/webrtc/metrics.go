@@ -70,6 +70,15 @@ func (m *metricsManager) getBySession(session types.Session) *metrics {
 				"session_id": sessionId,
 			},
 		}),
+		connectionFailures: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "connection_failures_count",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Count of failed connections of a session.",
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+			},
+		}),
 
 		iceCandidates:   map[string]struct{}{},
 		iceCandidatesMu: &sync.Mutex{},
@@ -228,6 +237,7 @@ type metrics struct {
 	connectionState      prometheus.Gauge
 	connectionStateCount prometheus.Counter
 	connectionCount      prometheus.Counter
+	connectionFailures   prometheus.Counter
 
 	iceCandidates         map[string]struct{}
 	iceCandidatesMu       *sync.Mutex
@@ -275,6 +285,10 @@ func (met *metrics) NewConnection() {
 	met.connectionCount.Add(1)
 }
 
+func (met *metrics) NewFailure() {
+	met.connectionFailures.Add(1)
+}
+
 func (met *metrics) NewICECandidate(candidate webrtc.ICECandidateStats) {
 	met.iceCandidatesMu.Lock()
 	defer met.iceCandidatesMu.Unlock()
@@ -0,0 +1,42 @@
+package webrtc
+
+import (
+	"github.com/pion/webrtc/v3"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// negotiator implements perfect negotiation, so that simultaneous offers from
+// both sides (glare) are resolved deterministically. Server is always the
+// impolite peer. It ignores colliding remote offer and waits for an answer to
+// its own offer, client as the polite peer is expected to roll back its offer
+// and answer. Server cannot be the polite peer, because pion v3 rejects every
+// rollback: its signaling state machine has no rollback transitions, even
+// though SetLocalDescription and SetRemoteDescription accept rollback type.
+//
+// https://w3c.github.io/webrtc-pc/#perfect-negotiation-example
+type negotiator struct {
+	ignoreOffer bool
+}
+
+func (n *negotiator) setRemoteDescription(connection *webrtc.PeerConnection, desc webrtc.SessionDescription) error {
+	if desc.Type == webrtc.SDPTypeOffer {
+		n.ignoreOffer = connection.SignalingState() != webrtc.SignalingStateStable
+		if n.ignoreOffer {
+			return types.ErrWebRTCOfferIgnored
+		}
+	}
+
+	return connection.SetRemoteDescription(desc)
+}
+
+func (n *negotiator) addICECandidate(connection *webrtc.PeerConnection, candidate webrtc.ICECandidateInit) error {
+	err := connection.AddICECandidate(candidate)
+
+	// candidates belonging to ignored offer are expected to fail
+	if err != nil && n.ignoreOffer {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,149 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+func newTestConnection(t *testing.T) *webrtc.PeerConnection {
+	t.Helper()
+
+	connection, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatalf("unable to create peer connection: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = connection.Close()
+	})
+
+	if _, err := connection.CreateDataChannel("data", nil); err != nil {
+		t.Fatalf("unable to create data channel: %v", err)
+	}
+
+	return connection
+}
+
+func createTestOffer(t *testing.T, connection *webrtc.PeerConnection) webrtc.SessionDescription {
+	t.Helper()
+
+	offer, err := connection.CreateOffer(nil)
+	if err != nil {
+		t.Fatalf("unable to create offer: %v", err)
+	}
+
+	if err := connection.SetLocalDescription(offer); err != nil {
+		t.Fatalf("unable to set local offer: %v", err)
+	}
+
+	return offer
+}
+
+func createTestAnswer(t *testing.T, connection *webrtc.PeerConnection) webrtc.SessionDescription {
+	t.Helper()
+
+	answer, err := connection.CreateAnswer(nil)
+	if err != nil {
+		t.Fatalf("unable to create answer: %v", err)
+	}
+
+	if err := connection.SetLocalDescription(answer); err != nil {
+		t.Fatalf("unable to set local answer: %v", err)
+	}
+
+	return answer
+}
+
+// Simulate both peers sending an offer at the same time, server as impolite
+// peer must ignore remote offer and keep its own offer pending.
+func TestNegotiatorGlare(t *testing.T) {
+	server := newTestConnection(t)
+	client := newTestConnection(t)
+
+	n := &negotiator{}
+
+	// both peers create offers simultaneously
+	serverOffer := createTestOffer(t, server)
+	clientOffer := createTestOffer(t, client)
+
+	// offers cross on the wire
+	if err := n.setRemoteDescription(server, clientOffer); err != types.ErrWebRTCOfferIgnored {
+		t.Fatalf("colliding offer was not ignored: %v", err)
+	}
+
+	if state := server.SignalingState(); state != webrtc.SignalingStateHaveLocalOffer {
+		t.Fatalf("server is in %s state after colliding offer", state)
+	}
+
+	// candidates of ignored offer must not fail
+	if err := n.addICECandidate(server, webrtc.ICECandidateInit{Candidate: "invalid"}); err != nil {
+		t.Errorf("candidate of ignored offer failed: %v", err)
+	}
+
+	// polite client rolls back its offer and answers the server offer, fresh
+	// connection stands in for browser client, because pion cannot roll back
+	client = newTestConnection(t)
+	if err := client.SetRemoteDescription(serverOffer); err != nil {
+		t.Fatalf("client failed to set server offer: %v", err)
+	}
+
+	answer := createTestAnswer(t, client)
+	if err := n.setRemoteDescription(server, answer); err != nil {
+		t.Fatalf("server failed to set answer: %v", err)
+	}
+
+	if state := server.SignalingState(); state != webrtc.SignalingStateStable {
+		t.Errorf("server is in %s state after answer", state)
+	}
+}
+
+// Ensure that offers without collision are accepted by impolite peer.
+func TestNegotiatorNoGlare(t *testing.T) {
+	server := newTestConnection(t)
+	client := newTestConnection(t)
+
+	n := &negotiator{}
+
+	clientOffer := createTestOffer(t, client)
+	if err := n.setRemoteDescription(server, clientOffer); err != nil {
+		t.Fatalf("failed to accept offer: %v", err)
+	}
+
+	answer := createTestAnswer(t, server)
+	if err := client.SetRemoteDescription(answer); err != nil {
+		t.Fatalf("failed to set answer: %v", err)
+	}
+
+	if n.ignoreOffer {
+		t.Errorf("offer was marked as ignored")
+	}
+
+	if state := server.SignalingState(); state != webrtc.SignalingStateStable {
+		t.Errorf("server is in %s state after answer", state)
+	}
+}
+
+// Ensure that pion still rejects rollback of local offer, server can be the
+// polite peer only once this test fails.
+func TestNegotiatorRollbackUnsupported(t *testing.T) {
+	server := newTestConnection(t)
+	client := newTestConnection(t)
+
+	serverOffer := createTestOffer(t, server)
+	createTestOffer(t, client)
+
+	err := server.SetLocalDescription(webrtc.SessionDescription{
+		Type: webrtc.SDPTypeRollback,
+		SDP:  serverOffer.SDP,
+	})
+	if err == nil {
+		t.Fatalf("pion rolled back local offer, server can be the polite peer now")
+	}
+
+	if state := server.SignalingState(); state != webrtc.SignalingStateHaveLocalOffer {
+		t.Errorf("server is in %s state after rejected rollback", state)
+	}
+}
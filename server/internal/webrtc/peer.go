@@ -3,6 +3,8 @@ package webrtc
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -15,15 +17,20 @@ import (
 	"github.com/m1k1o/neko/server/internal/webrtc/payload"
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
 	"github.com/m1k1o/neko/server/pkg/utils"
 )
 
 type WebRTCPeerCtx struct {
+	id         string
 	mu         sync.Mutex
 	logger     zerolog.Logger
 	session    types.Session
 	metrics    *metrics
 	connection *webrtc.PeerConnection
+	negotiator negotiator
+	// server initiated offer waiting for an answer
+	renegotiation renegotiation
 	// bandwidth estimator
 	estimator     cc.BandwidthEstimator
 	estimateTrend *utils.TrendDetector
@@ -34,20 +41,46 @@ type WebRTCPeerCtx struct {
 	audioTrack  *Track
 	videoTrack  *Track
 	dataChannel *webrtc.DataChannel
-	rtcpChannel chan []rtcp.Packet
+	// options of audio tracks created when audio is enabled again
+	audioOptions []trackOption
+	rtcpChannel  chan []rtcp.Packet
+	// closed when the connection is closed
+	closed chan struct{}
 	// config
+	dataOnly        bool
 	iceTrickle      bool
+	iceServers      []types.ICEServer
+	relayAllowed    bool
 	estimatorConfig config.WebRTCEstimator
 	paused          bool
 	videoAuto       bool
 	videoDisabled   bool
 	audioDisabled   bool
+	microphoneRoute types.MicrophoneRoute
+	// cursor updates, applied once data channel is open
+	cursorMode  types.CursorMode
+	cursorApply func()
+	// low quality video at start, ramped up after duration
+	fastStartDuration time.Duration
+	fastStartVideo    string
+	videoSelected     bool
+	// video manually selected by the client, automatic selection does not exceed it
+	videoCap   string
+	videoPrefs *videoPreferences
+	// used to validate microphone route
+	microphoneMix types.StreamSrcManager
+	// marks packets of audio tracks, optional
+	dscp *dscpMarker
 }
 
 //
 // connection
 //
 
+func (peer *WebRTCPeerCtx) ID() string {
+	return peer.id
+}
+
 func (peer *WebRTCPeerCtx) CreateOffer(ICERestart bool) (*webrtc.SessionDescription, error) {
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
@@ -62,6 +95,24 @@ func (peer *WebRTCPeerCtx) CreateOffer(ICERestart bool) (*webrtc.SessionDescript
 	return peer.setLocalDescription(offer)
 }
 
+// RestartICE sends client an offer restarting ICE, so that candidates are
+// gathered again. Tracks and data channel are kept, client answers it as
+// any other offer.
+func (peer *WebRTCPeerCtx) RestartICE() error {
+	offer, err := peer.CreateOffer(true)
+	if err != nil {
+		return err
+	}
+
+	peer.session.Send(
+		event.SIGNAL_OFFER,
+		message.SignalDescription{
+			SDP: offer.SDP,
+		})
+
+	return nil
+}
+
 func (peer *WebRTCPeerCtx) CreateAnswer() (*webrtc.SessionDescription, error) {
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
@@ -97,14 +148,38 @@ func (peer *WebRTCPeerCtx) SetRemoteDescription(desc webrtc.SessionDescription)
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
 
-	return peer.connection.SetRemoteDescription(desc)
+	// over relay limit, only direct connection is allowed
+	if !peer.relayAllowed {
+		desc.SDP = stripRelayCandidates(desc.SDP)
+	}
+
+	if err := peer.negotiator.setRemoteDescription(peer.connection, desc); err != nil {
+		return err
+	}
+
+	if desc.Type == webrtc.SDPTypeAnswer {
+		peer.renegotiation.finish()
+	}
+
+	return nil
 }
 
 func (peer *WebRTCPeerCtx) SetCandidate(candidate webrtc.ICECandidateInit) error {
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
 
-	return peer.connection.AddICECandidate(candidate)
+	// over relay limit, only direct connection is allowed
+	if !peer.relayAllowed && isRelayCandidate(candidate.Candidate) {
+		peer.logger.Debug().Str("candidate", candidate.Candidate).Msg("ignoring relay candidate")
+		return nil
+	}
+
+	return peer.negotiator.addICECandidate(peer.connection, candidate)
+}
+
+// ICEServers returns ICE servers the client should use for this peer.
+func (peer *WebRTCPeerCtx) ICEServers() []types.ICEServer {
+	return peer.iceServers
 }
 
 // TODO: Add shutdown function?
@@ -138,6 +213,11 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 		return
 	}
 
+	// pick initial video stream based on available bandwidth
+	if conf.ProbeDuration > 0 && !conf.Passive {
+		peer.probeBandwidth(debugLogger)
+	}
+
 	// use a ticker to get current client target bitrate
 	ticker := time.NewTicker(conf.ReadInterval)
 	defer ticker.Stop()
@@ -151,6 +231,8 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 	// when was the last upgrade/downgrade
 	lastUpgradeTime := time.Time{}
 	lastDowngradeTime := time.Time{}
+	// hysteresis of upgrades after congestion
+	restore := newQualityRestore(conf.RestoreDelay, conf.MaxUpgrades, conf.UpgradeWindow)
 
 	for range ticker.C {
 		targetBitrate := peer.estimator.GetTargetBitrate()
@@ -212,6 +294,7 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 		if direction == utils.TrendDirectionDownward || stalled {
 			// we reset the stable time because we are congesting
 			stableSince = time.Now()
+			restore.observe(time.Now(), false)
 
 			// if we downgraded recently, we wait for some more time
 			if time.Since(lastDowngradeTime) < conf.DowngradeBackoff {
@@ -239,12 +322,12 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 				continue
 			}
 
-			err := peer.SetVideo(types.PeerVideoRequest{
+			err := peer.setVideo(types.PeerVideoRequest{
 				Selector: &types.StreamSelector{
 					ID:   streamId,
 					Type: types.StreamSelectorTypeLower,
 				},
-			})
+			}, false)
 			if err != nil && err != types.ErrWebRTCStreamNotFound {
 				peer.logger.Warn().Err(err).Msg("failed to downgrade video stream")
 			}
@@ -253,6 +336,7 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 			if err == types.ErrWebRTCStreamNotFound {
 				debugLogger.Info().Msg("looks like we are already on the lowest stream")
 			} else {
+				restore.downgraded()
 				debugLogger.Info().Msg("downgraded video stream")
 			}
 			continue
@@ -260,6 +344,7 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 
 		// we reset the unstable time because we are not congesting
 		unstableSince = time.Now()
+		restore.observe(time.Now(), conf.DiffThreshold < 0 || diff >= 1+conf.DiffThreshold)
 
 		// if we have a neutral or upward trend, that means our estimate is stable
 		// if we are on the highest stream, we don't need to do anything
@@ -292,16 +377,27 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 			continue
 		}
 
-		err := peer.SetVideo(types.PeerVideoRequest{
+		// after congestion, bandwidth must be good for some time and upgrades are limited
+		if wait := restore.waiting(time.Now()); wait > 0 {
+			debugLogger.Debug().
+				Dur("wait", wait).
+				Msg("restoring quality after congestion, waiting before upgrade")
+			continue
+		}
+
+		err := peer.setVideo(types.PeerVideoRequest{
 			Selector: &types.StreamSelector{
 				ID:   streamId,
 				Type: types.StreamSelectorTypeHigher,
 			},
-		})
+		}, false)
 		if err != nil && err != types.ErrWebRTCStreamNotFound {
 			peer.logger.Warn().Err(err).Msg("failed to upgrade video stream")
 		}
 		lastUpgradeTime = time.Now()
+		if err == nil || err == types.ErrWebRTCStreamNotFound {
+			restore.upgraded(lastUpgradeTime, err == types.ErrWebRTCStreamNotFound)
+		}
 
 		if err == types.ErrWebRTCStreamNotFound {
 			debugLogger.Info().Msg("looks like we are already on the highest stream")
@@ -315,8 +411,12 @@ func (peer *WebRTCPeerCtx) SetPaused(isPaused bool) error {
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
 
-	peer.videoTrack.SetPaused(isPaused || peer.videoDisabled)
-	peer.audioTrack.SetPaused(isPaused || peer.audioDisabled)
+	if peer.videoTrack != nil {
+		peer.videoTrack.SetPaused(isPaused || peer.videoDisabled)
+	}
+	if peer.audioTrack != nil {
+		peer.audioTrack.SetPaused(isPaused || peer.audioDisabled)
+	}
 
 	peer.logger.Info().Bool("is_paused", isPaused).Msg("set paused")
 	peer.paused = isPaused
@@ -336,11 +436,39 @@ func (peer *WebRTCPeerCtx) Paused() bool {
 //
 
 func (peer *WebRTCPeerCtx) SetVideo(r types.PeerVideoRequest) error {
+	return peer.setVideo(r, true)
+}
+
+// setVideo changes video of the peer. Videos selected manually by the client
+// cap videos selected automatically, that are rejected if above the cap.
+func (peer *WebRTCPeerCtx) setVideo(r types.PeerVideoRequest, manual bool) error {
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
 
+	if peer.dataOnly {
+		return types.ErrWebRTCDataOnly
+	}
+
 	modified := false
 
+	// first selected video might be replaced by fast start video
+	fastStartTarget := ""
+	firstSelected := r.Selector != nil && !peer.videoSelected
+	if firstSelected {
+		peer.videoSelected = true
+
+		// reconnecting client gets video it selected before
+		peer.capSelector(&r)
+
+		if targetId, ok := peer.fastStartSelector(&r); ok {
+			peer.logger.Info().
+				Str("video_id", r.Selector.ID).
+				Str("target_id", targetId).
+				Msg("using fast start video")
+			fastStartTarget = targetId
+		}
+	}
+
 	// video disabled
 	if r.Disabled != nil {
 		disabled := *r.Disabled
@@ -365,6 +493,10 @@ func (peer *WebRTCPeerCtx) SetVideo(r types.PeerVideoRequest) error {
 			return types.ErrWebRTCStreamNotFound
 		}
 
+		if !manual && peer.aboveVideoCap(stream.ID()) {
+			return types.ErrWebRTCStreamNotFound
+		}
+
 		// set video stream to track
 		changed, err := peer.videoTrack.SetStream(stream)
 		if err != nil {
@@ -379,6 +511,11 @@ func (peer *WebRTCPeerCtx) SetVideo(r types.PeerVideoRequest) error {
 			peer.logger.Info().Str("video_id", videoID).Msg("set video")
 			modified = true
 		}
+
+		// initial video is not chosen by the user
+		if manual && !firstSelected && peer.setVideoCap(stream.ID()) {
+			modified = true
+		}
 	}
 
 	// video auto
@@ -407,13 +544,45 @@ func (peer *WebRTCPeerCtx) SetVideo(r types.PeerVideoRequest) error {
 		}()
 	}
 
+	if fastStartTarget != "" {
+		go peer.fastStartRamp(r.Selector.ID, fastStartTarget)
+	}
+
 	return nil
 }
 
+// Bandwidth returns bandwidth estimated for the peer along with bitrate of
+// its video stream, that is switched when video auto is enabled.
+func (peer *WebRTCPeerCtx) Bandwidth() (types.PeerBandwidth, bool) {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	if peer.estimator == nil || peer.dataOnly {
+		return types.PeerBandwidth{}, false
+	}
+
+	bandwidth := types.PeerBandwidth{
+		Estimate: peer.estimator.GetTargetBitrate(),
+		Auto:     peer.videoAuto,
+	}
+
+	if stream, ok := peer.videoTrack.Stream(); ok {
+		bandwidth.VideoID = stream.ID()
+		bandwidth.StreamBitrate = stream.Bitrate()
+	}
+
+	return bandwidth, true
+}
+
 func (peer *WebRTCPeerCtx) Video() types.PeerVideo {
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
 
+	// data only peers have no video
+	if peer.dataOnly {
+		return types.PeerVideo{Disabled: true}
+	}
+
 	// get current video stream ID
 	ID := ""
 	stream, ok := peer.videoTrack.Stream()
@@ -425,6 +594,7 @@ func (peer *WebRTCPeerCtx) Video() types.PeerVideo {
 		Disabled: peer.videoDisabled,
 		ID:       ID,
 		Video:    ID, // TODO: Remove, used for backward compatibility
+		Cap:      peer.videoCap,
 		Auto:     peer.videoAuto,
 	}
 }
@@ -437,8 +607,28 @@ func (peer *WebRTCPeerCtx) SetAudio(r types.PeerAudioRequest) error {
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
 
+	// audio is not available, it stays disabled
+	if peer.audio == nil {
+		return nil
+	}
+
 	modified := false
 
+	// audio track
+	if r.Track != nil {
+		enabled := *r.Track
+
+		// update only if changed
+		if (peer.audioTrack != nil) != enabled {
+			if err := peer.setAudioTrack(enabled); err != nil {
+				return err
+			}
+
+			peer.logger.Info().Bool("track", enabled).Msg("set audio track")
+			modified = true
+		}
+	}
+
 	// audio disabled
 	if r.Disabled != nil {
 		disabled := *r.Disabled
@@ -446,7 +636,9 @@ func (peer *WebRTCPeerCtx) SetAudio(r types.PeerAudioRequest) error {
 		// update only if changed
 		if peer.audioDisabled != disabled {
 			peer.audioDisabled = disabled
-			peer.audioTrack.SetPaused(disabled || peer.paused)
+			if peer.audioTrack != nil {
+				peer.audioTrack.SetPaused(disabled || peer.paused)
+			}
 
 			peer.logger.Info().Bool("disabled", disabled).Msg("set audio disabled")
 			modified = true
@@ -470,9 +662,97 @@ func (peer *WebRTCPeerCtx) Audio() types.PeerAudio {
 
 	return types.PeerAudio{
 		Disabled: peer.audioDisabled,
+		Track:    peer.audioTrack != nil,
 	}
 }
 
+// setAudioTrack adds or removes audio track. Connection fires negotiation
+// needed and new offer is sent once its signaling state is stable.
+func (peer *WebRTCPeerCtx) setAudioTrack(enabled bool) error {
+	if !enabled {
+		track := peer.audioTrack
+		peer.audioTrack = nil
+
+		if peer.dscp != nil {
+			peer.dscp.removeAudio(track.SSRC())
+		}
+
+		return track.Remove(peer.connection)
+	}
+
+	track, err := NewTrack(peer.logger, peer.audio.Codec(), peer.connection, peer.audioOptions...)
+	if err != nil {
+		return err
+	}
+
+	track.SetPaused(peer.audioDisabled || peer.paused)
+
+	if peer.dscp != nil {
+		peer.dscp.addAudio(track.SSRC())
+	}
+
+	if _, err := track.SetStream(peer.audio); err != nil {
+		if peer.dscp != nil {
+			peer.dscp.removeAudio(track.SSRC())
+		}
+
+		_ = track.Remove(peer.connection)
+		return err
+	}
+
+	peer.audioTrack = track
+	return nil
+}
+
+// shutdownAudioTrack releases audio track after connection was closed.
+func (peer *WebRTCPeerCtx) shutdownAudioTrack() {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	if peer.audioTrack == nil {
+		return
+	}
+
+	if peer.dscp != nil {
+		peer.dscp.removeAudio(peer.audioTrack.SSRC())
+	}
+
+	peer.audioTrack.Shutdown()
+	peer.audioTrack = nil
+}
+
+//
+// microphone
+//
+
+// SetMicrophoneRoute sets where shared microphone is routed to, it is applied
+// when the microphone track is received.
+func (peer *WebRTCPeerCtx) SetMicrophoneRoute(route types.MicrophoneRoute) error {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	switch route {
+	case types.MicrophoneRouteDesktop:
+	case types.MicrophoneRouteMix, types.MicrophoneRouteBoth:
+		if peer.microphoneMix == nil || !peer.microphoneMix.Enabled() {
+			return errors.New("microphone mixing is disabled")
+		}
+	default:
+		return fmt.Errorf("unknown microphone route %q", route)
+	}
+
+	peer.microphoneRoute = route
+	peer.logger.Info().Str("route", string(route)).Msg("set microphone route")
+	return nil
+}
+
+func (peer *WebRTCPeerCtx) MicrophoneRoute() types.MicrophoneRoute {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	return peer.microphoneRoute
+}
+
 //
 // data channel
 //
@@ -481,8 +761,8 @@ func (peer *WebRTCPeerCtx) SendCursorPosition(x, y int) error {
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
 
-	// do not send cursor position to host
-	if peer.session.IsHost() {
+	// do not send cursor position to host, or when it is hidden
+	if !peer.session.HostCursorVisible() {
 		return nil
 	}
 
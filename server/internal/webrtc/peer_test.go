@@ -0,0 +1,154 @@
+package webrtc
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/codec"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+)
+
+func TestDataOnlyPeer(t *testing.T) {
+	peer := &WebRTCPeerCtx{
+		logger:        zerolog.Nop(),
+		dataOnly:      true,
+		audioDisabled: true,
+	}
+
+	disabled := false
+	err := peer.SetVideo(types.PeerVideoRequest{Disabled: &disabled})
+	if !errors.Is(err, types.ErrWebRTCDataOnly) {
+		t.Errorf("SetVideo() = %v, want %v", err, types.ErrWebRTCDataOnly)
+	}
+
+	if video := peer.Video(); !video.Disabled || video.ID != "" {
+		t.Errorf("Video() = %+v, want disabled", video)
+	}
+
+	// there is no audio to enable
+	if err := peer.SetAudio(types.PeerAudioRequest{Disabled: &disabled}); err != nil {
+		t.Errorf("SetAudio() = %v", err)
+	}
+	if audio := peer.Audio(); !audio.Disabled || audio.Track {
+		t.Errorf("Audio() = %+v, want disabled", audio)
+	}
+
+	if err := peer.SetPaused(true); err != nil {
+		t.Errorf("SetPaused() = %v", err)
+	}
+}
+
+type audioTrackTestStream struct {
+	types.StreamSinkManager
+	mu        sync.Mutex
+	listeners int
+}
+
+func (s *audioTrackTestStream) Codec() codec.RTPCodec { return codec.Opus() }
+
+func (s *audioTrackTestStream) AddListener(listener types.SampleListener) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listeners++
+	return nil
+}
+
+func (s *audioTrackTestStream) RemoveListener(listener types.SampleListener) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listeners--
+	return nil
+}
+
+func (s *audioTrackTestStream) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.listeners
+}
+
+type audioTrackTestSession struct {
+	types.Session
+	audio chan types.PeerAudio
+}
+
+func (s *audioTrackTestSession) Send(ev string, payload any) {
+	if audio, ok := payload.(types.PeerAudio); ok && ev == event.SIGNAL_AUDIO {
+		s.audio <- audio
+	}
+}
+
+// sendingTracks returns number of tracks sent over the connection
+func sendingTracks(connection *webrtc.PeerConnection) int {
+	count := 0
+	for _, sender := range connection.GetSenders() {
+		if sender.Track() != nil {
+			count++
+		}
+	}
+	return count
+}
+
+func TestSetAudioTrack(t *testing.T) {
+	stream := &audioTrackTestStream{}
+	session := &audioTrackTestSession{audio: make(chan types.PeerAudio, 2)}
+	peer := &WebRTCPeerCtx{
+		logger:     zerolog.Nop(),
+		session:    session,
+		connection: newTestConnection(t),
+		audio:      stream,
+	}
+
+	expectSignal := func(track bool) {
+		t.Helper()
+
+		select {
+		case audio := <-session.audio:
+			if audio.Track != track {
+				t.Errorf("signaled track = %v, want %v", audio.Track, track)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("audio change was not signaled")
+		}
+	}
+
+	enabled := true
+	if err := peer.SetAudio(types.PeerAudioRequest{Track: &enabled}); err != nil {
+		t.Fatalf("SetAudio() = %v", err)
+	}
+	expectSignal(true)
+	if !peer.Audio().Track || sendingTracks(peer.connection) != 1 || stream.count() != 1 {
+		t.Errorf("audio track was not added")
+	}
+
+	// unchanged request is not signaled
+	if err := peer.SetAudio(types.PeerAudioRequest{Track: &enabled}); err != nil {
+		t.Fatalf("SetAudio() = %v", err)
+	}
+
+	enabled = false
+	if err := peer.SetAudio(types.PeerAudioRequest{Track: &enabled}); err != nil {
+		t.Fatalf("SetAudio() = %v", err)
+	}
+	expectSignal(false)
+	if peer.Audio().Track || sendingTracks(peer.connection) != 0 || stream.count() != 0 {
+		t.Errorf("audio track was not removed")
+	}
+
+	// removed track is not released twice when connection closes
+	peer.shutdownAudioTrack()
+
+	select {
+	case audio := <-session.audio:
+		t.Errorf("unexpected audio signal %+v", audio)
+	default:
+	}
+}
@@ -0,0 +1,47 @@
+package webrtc
+
+import (
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// mediaPlaying notifies the client once it reported receiving media, being
+// connected does not yet mean that anything can be played. Audio track is
+// optional.
+func (manager *WebRTCManagerCtx) mediaPlaying(peer *WebRTCPeerCtx, audio, video *Track, createdAt time.Time) {
+	var audioFlowing <-chan struct{}
+	if audio != nil {
+		audioFlowing = audio.Flowing()
+	}
+
+	media := "video"
+	select {
+	case <-video.Flowing():
+	case <-audioFlowing:
+		media = "audio"
+	case <-peer.closed:
+		return
+	}
+
+	elapsed := time.Since(createdAt)
+	manager.timeToFirstFrame.WithLabelValues(media).Observe(elapsed.Seconds())
+
+	peer.logger.Info().
+		Str("media", media).
+		Dur("time_to_first_frame", elapsed).
+		Msg("media is flowing")
+
+	// peer might have been replaced meanwhile
+	if peer.session.GetWebRTCPeer() != peer {
+		return
+	}
+
+	peer.session.Send(
+		event.SIGNAL_PLAYING,
+		message.SignalPlaying{
+			Media:            media,
+			TimeToFirstFrame: elapsed.Milliseconds(),
+		})
+}
@@ -0,0 +1,107 @@
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// probeBandwidth runs at connection start when automatic video is enabled. It
+// switches to the lowest video stream and upgrades it step by step while the
+// estimated bitrate is sufficiently higher than bitrate of the current stream,
+// so that the initial stream matches available bandwidth. If the estimate is
+// inconclusive, the peer stays on the lower stream reached so far.
+func (peer *WebRTCPeerCtx) probeBandwidth(debugLogger zerolog.Logger) {
+	conf := peer.estimatorConfig
+
+	ticker := time.NewTicker(conf.ReadInterval)
+	defer ticker.Stop()
+
+	// wait until connected, estimates are available only when media flows
+	for peer.connection.ConnectionState() != webrtc.PeerConnectionStateConnected {
+		if peer.connection.ConnectionState() == webrtc.PeerConnectionStateClosed {
+			return
+		}
+		<-ticker.C
+	}
+
+	if !peer.videoAuto || peer.videoDisabled || peer.paused {
+		return
+	}
+
+	ids := peer.video.IDs()
+	if len(ids) < 2 {
+		return
+	}
+
+	// stream IDs are ordered from the highest to the lowest
+	streamId := ids[len(ids)-1]
+	err := peer.setVideo(types.PeerVideoRequest{
+		Selector: &types.StreamSelector{
+			ID:   streamId,
+			Type: types.StreamSelectorTypeExact,
+		},
+	}, false)
+	if err != nil {
+		peer.logger.Warn().Err(err).Msg("failed to start bandwidth probing on the lowest video stream")
+		return
+	}
+
+	// every stream gets equal share of the probing time
+	step := conf.ProbeDuration / time.Duration(len(ids)-1)
+	deadline := time.Now().Add(conf.ProbeDuration)
+
+	for time.Now().Before(deadline) {
+		time.Sleep(step)
+
+		if peer.connection.ConnectionState() == webrtc.PeerConnectionStateClosed {
+			return
+		}
+
+		stream, ok := peer.videoTrack.Stream()
+		if !ok {
+			break
+		}
+
+		targetBitrate := peer.estimator.GetTargetBitrate()
+		streamId, streamBitrate := stream.ID(), stream.Bitrate()
+
+		debugLogger.Info().
+			Int("target_bitrate", targetBitrate).
+			Uint64("stream_bitrate", streamBitrate).
+			Str("stream_id", streamId).
+			Msg("bandwidth probing")
+
+		// inconclusive, stay on the current stream
+		if !probeUpgrade(targetBitrate, streamBitrate, conf.ProbeUpgradeRatio) {
+			break
+		}
+
+		err := peer.setVideo(types.PeerVideoRequest{
+			Selector: &types.StreamSelector{
+				ID:   streamId,
+				Type: types.StreamSelectorTypeHigher,
+			},
+		}, false)
+		if err == types.ErrWebRTCStreamNotFound {
+			// already on the highest allowed stream
+			break
+		}
+		if err != nil {
+			peer.logger.Warn().Err(err).Msg("failed to upgrade video stream during bandwidth probing")
+			break
+		}
+	}
+
+	video := peer.Video()
+	peer.logger.Info().Str("video_id", video.ID).Msg("bandwidth probing finished")
+}
+
+// probeUpgrade tells whether estimated bitrate is sufficiently higher than
+// bitrate of the current stream to upgrade it during probing.
+func probeUpgrade(targetBitrate int, streamBitrate uint64, ratio float64) bool {
+	return streamBitrate > 0 && float64(targetBitrate) >= float64(streamBitrate)*ratio
+}
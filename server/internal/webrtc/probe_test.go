@@ -0,0 +1,26 @@
+package webrtc
+
+import "testing"
+
+func TestProbeUpgrade(t *testing.T) {
+	tests := []struct {
+		name          string
+		targetBitrate int
+		streamBitrate uint64
+		want          bool
+	}{
+		{"unknown stream bitrate", 5_000_000, 0, false},
+		{"no estimate", 0, 1_000_000, false},
+		{"below ratio", 1_400_000, 1_000_000, false},
+		{"at ratio", 1_500_000, 1_000_000, true},
+		{"above ratio", 3_000_000, 1_000_000, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := probeUpgrade(tt.targetBitrate, tt.streamBitrate, 1.5); got != tt.want {
+				t.Errorf("probeUpgrade(%d, %d) = %v, want %v", tt.targetBitrate, tt.streamBitrate, got, tt.want)
+			}
+		})
+	}
+}
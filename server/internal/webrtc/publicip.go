@@ -0,0 +1,65 @@
+package webrtc
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// ingressPublicIP returns public IP matching the ingress used by the client,
+// that is either mapped from the request host, or the host itself if it is
+// one of NAT 1:1 IPs. Empty if no public IP matches.
+func (manager *WebRTCManagerCtx) ingressPublicIP(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if ip, ok := manager.config.NAT1To1Hosts[host]; ok {
+		return ip
+	}
+
+	for _, ip := range manager.config.NAT1To1IPs {
+		if ip == host {
+			return ip
+		}
+	}
+
+	return ""
+}
+
+// PinPublicIP pins public IP advertised to the session according to the
+// ingress it used, peers created for the session use only this IP.
+func (manager *WebRTCManagerCtx) PinPublicIP(session types.Session, r *http.Request) {
+	ip := manager.ingressPublicIP(r)
+
+	manager.publicIPsMu.Lock()
+	defer manager.publicIPsMu.Unlock()
+
+	if ip == "" {
+		delete(manager.publicIPs, session.ID())
+		return
+	}
+
+	manager.publicIPs[session.ID()] = ip
+}
+
+func (manager *WebRTCManagerCtx) unpinPublicIP(session types.Session) {
+	manager.publicIPsMu.Lock()
+	defer manager.publicIPsMu.Unlock()
+
+	delete(manager.publicIPs, session.ID())
+}
+
+// nat1To1IPs returns public IPs advertised to the session.
+func (manager *WebRTCManagerCtx) nat1To1IPs(session types.Session) []string {
+	manager.publicIPsMu.Lock()
+	defer manager.publicIPsMu.Unlock()
+
+	if ip, ok := manager.publicIPs[session.ID()]; ok {
+		return []string{ip}
+	}
+
+	return manager.config.NAT1To1IPs
+}
@@ -0,0 +1,68 @@
+package webrtc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/m1k1o/neko/server/internal/config"
+)
+
+func newPublicIPTestManager() *WebRTCManagerCtx {
+	return &WebRTCManagerCtx{
+		config: &config.WebRTC{
+			NAT1To1IPs:   []string{"203.0.113.1", "198.51.100.1"},
+			NAT1To1Hosts: map[string]string{"b.example.com": "198.51.100.1"},
+		},
+		publicIPs: map[string]string{},
+	}
+}
+
+func TestIngressPublicIP(t *testing.T) {
+	manager := newPublicIPTestManager()
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"b.example.com", "198.51.100.1"},
+		{"b.example.com:8080", "198.51.100.1"},
+		{"203.0.113.1:8080", "203.0.113.1"},
+		{"a.example.com", ""},
+		{"192.0.2.1", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := manager.ingressPublicIP(&http.Request{Host: tt.host}); got != tt.want {
+				t.Errorf("ingressPublicIP(%q) = %q, want %q", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPinPublicIP(t *testing.T) {
+	manager := newPublicIPTestManager()
+	session := &sharedMediaTestSession{id: "a"}
+	all := manager.config.NAT1To1IPs
+
+	if got := manager.nat1To1IPs(session); !reflect.DeepEqual(got, all) {
+		t.Errorf("nat1To1IPs() = %v before pin, want all", got)
+	}
+
+	manager.PinPublicIP(session, &http.Request{Host: "b.example.com"})
+	if got := manager.nat1To1IPs(session); !reflect.DeepEqual(got, []string{"198.51.100.1"}) {
+		t.Errorf("nat1To1IPs() = %v, want pinned IP", got)
+	}
+
+	// other sessions still get all IPs
+	if got := manager.nat1To1IPs(&sharedMediaTestSession{id: "b"}); !reflect.DeepEqual(got, all) {
+		t.Errorf("nat1To1IPs() = %v for other session, want all", got)
+	}
+
+	// reconnecting through unknown ingress drops the pin
+	manager.PinPublicIP(session, &http.Request{Host: "a.example.com"})
+	if got := manager.nat1To1IPs(session); !reflect.DeepEqual(got, all) {
+		t.Errorf("nat1To1IPs() = %v after unknown ingress, want all", got)
+	}
+}
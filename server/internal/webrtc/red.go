@@ -0,0 +1,254 @@
+package webrtc
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types/codec"
+)
+
+// limits of redundant blocks given by the RED block header fields
+const (
+	redMaxOffset = 1 << 14
+	redMaxLength = 1 << 10
+)
+
+// redundant blocks are dropped, when the packet would exceed this size
+const redMaxPayload = 1200
+
+// audioRED reports whether redundancy is offered for the audio codec, it is
+// supported only with opus.
+func (manager *WebRTCManagerCtx) audioRED(audio codec.RTPCodec) bool {
+	return manager.config.AudioRED.Enabled && audio.Name == codec.Opus().Name
+}
+
+type redBlock struct {
+	timestamp uint32
+	data      []byte
+}
+
+type redBinding struct {
+	id          string
+	ssrc        webrtc.SSRC
+	payloadType webrtc.PayloadType
+	// zero, when the receiver does not support redundancy
+	redPayloadType webrtc.PayloadType
+	writeStream    webrtc.TrackLocalWriter
+}
+
+// redTrack is a local audio track, that repeats previous frames in every packet
+// using redundant encoding (RFC 2198), so that the receiver can recover isolated
+// losses without retransmission. Receivers that did not negotiate redundancy
+// get only the primary codec. Redundancy is sent only while the receiver
+// reports packet loss or jitter above the configured thresholds.
+type redTrack struct {
+	logger   zerolog.Logger
+	id       string
+	streamID string
+	codec    webrtc.RTPCodecCapability
+	config   config.WebRTCAudioRED
+
+	active atomic.Bool
+
+	mu        sync.Mutex
+	bindings  []redBinding
+	sequencer rtp.Sequencer
+	timestamp uint32
+	history   []redBlock
+}
+
+func newRedTrack(logger zerolog.Logger, capability webrtc.RTPCodecCapability, id, streamID string, conf config.WebRTCAudioRED) *redTrack {
+	t := &redTrack{
+		logger:   logger,
+		id:       id,
+		streamID: streamID,
+		codec:    capability,
+		config:   conf,
+
+		sequencer: rtp.NewRandomSequencer(),
+		timestamp: rand.Uint32(),
+	}
+
+	// without threshold, redundancy is always sent
+	t.active.Store(conf.LossThreshold == 0)
+	return t
+}
+
+func (t *redTrack) ID() string                { return t.id }
+func (t *redTrack) RID() string               { return "" }
+func (t *redTrack) StreamID() string          { return t.streamID }
+func (t *redTrack) Kind() webrtc.RTPCodecType { return webrtc.RTPCodecTypeAudio }
+
+// Bind uses redundant encoding, if the receiver negotiated it for the primary
+// codec, otherwise only the primary codec is used.
+func (t *redTrack) Bind(ctx webrtc.TrackLocalContext) (webrtc.RTPCodecParameters, error) {
+	var primary, red *webrtc.RTPCodecParameters
+
+	params := ctx.CodecParameters()
+	for i := range params {
+		switch {
+		case primary == nil && strings.EqualFold(params[i].MimeType, t.codec.MimeType):
+			primary = &params[i]
+		case red == nil && strings.EqualFold(params[i].MimeType, codec.MimeTypeRED):
+			red = &params[i]
+		}
+	}
+
+	if primary == nil {
+		return webrtc.RTPCodecParameters{}, webrtc.ErrUnsupportedCodec
+	}
+
+	binding := redBinding{
+		id:          ctx.ID(),
+		ssrc:        ctx.SSRC(),
+		payloadType: primary.PayloadType,
+		writeStream: ctx.WriteStream(),
+	}
+
+	// redundancy must carry the negotiated primary codec
+	if red != nil && strings.HasPrefix(red.SDPFmtpLine, fmt.Sprintf("%d/", primary.PayloadType)) {
+		binding.redPayloadType = red.PayloadType
+	}
+
+	t.mu.Lock()
+	t.bindings = append(t.bindings, binding)
+	t.mu.Unlock()
+
+	t.logger.Debug().Bool("red", binding.redPayloadType != 0).Msg("audio track bound")
+
+	if binding.redPayloadType != 0 {
+		return *red, nil
+	}
+	return *primary, nil
+}
+
+func (t *redTrack) Unbind(ctx webrtc.TrackLocalContext) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i := range t.bindings {
+		if t.bindings[i].id == ctx.ID() {
+			t.bindings = append(t.bindings[:i], t.bindings[i+1:]...)
+			return nil
+		}
+	}
+
+	return webrtc.ErrUnbindFailed
+}
+
+// WriteSample sends the sample as a single packet, opus frames always fit.
+func (t *redTrack) WriteSample(sample media.Sample) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	active := t.active.Load()
+	sequenceNumber := t.sequencer.NextSequenceNumber()
+
+	var errs []error
+	for _, b := range t.bindings {
+		header := rtp.Header{
+			Version:        2,
+			PayloadType:    uint8(b.payloadType),
+			SequenceNumber: sequenceNumber,
+			Timestamp:      t.timestamp,
+			SSRC:           uint32(b.ssrc),
+		}
+
+		payload := sample.Data
+		if b.redPayloadType != 0 {
+			header.PayloadType = uint8(b.redPayloadType)
+
+			history := t.history
+			if !active {
+				history = nil
+			}
+			payload = encodeRED(uint8(b.payloadType), t.timestamp, sample.Data, history)
+		}
+
+		if _, err := b.writeStream.WriteRTP(&header, payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// history is kept also while inactive, so that redundancy starts immediately
+	t.history = append(t.history, redBlock{
+		timestamp: t.timestamp,
+		data:      append([]byte(nil), sample.Data...),
+	})
+	if len(t.history) > t.config.Distance {
+		t.history = t.history[len(t.history)-t.config.Distance:]
+	}
+
+	t.timestamp += uint32(sample.Duration.Seconds() * float64(t.codec.ClockRate))
+
+	return errors.Join(errs...)
+}
+
+// onReport decides from receiver report, whether redundancy is sent.
+func (t *redTrack) onReport(report rtcp.ReceptionReport) {
+	if t.config.LossThreshold == 0 {
+		return
+	}
+
+	loss := float64(report.FractionLost) * 100 / 256
+	jitter := time.Duration(float64(report.Jitter) / float64(t.codec.ClockRate) * float64(time.Second))
+
+	active := loss >= t.config.LossThreshold ||
+		(t.config.JitterThreshold > 0 && jitter >= t.config.JitterThreshold)
+
+	if t.active.Swap(active) != active {
+		t.logger.Debug().
+			Bool("active", active).
+			Float64("loss", loss).
+			Dur("jitter", jitter).
+			Msg("audio redundancy changed")
+	}
+}
+
+// encodeRED builds RED payload of the primary data preceded by the newest
+// previous blocks, that fit into the header fields and the packet size.
+func encodeRED(payloadType uint8, timestamp uint32, primary []byte, history []redBlock) []byte {
+	size := 1 + len(primary)
+
+	// select from the newest, but send from the oldest
+	first := len(history)
+	for i := len(history) - 1; i >= 0; i-- {
+		b := history[i]
+		offset := timestamp - b.timestamp
+		if offset == 0 || offset >= redMaxOffset || len(b.data) >= redMaxLength || size+4+len(b.data) > redMaxPayload {
+			break
+		}
+		size += 4 + len(b.data)
+		first = i
+	}
+	blocks := history[first:]
+
+	payload := make([]byte, 0, size)
+	for _, b := range blocks {
+		offset := timestamp - b.timestamp
+		length := len(b.data)
+		payload = append(payload,
+			0x80|payloadType,
+			byte(offset>>6),
+			byte(offset<<2)|byte(length>>8),
+			byte(length))
+	}
+
+	payload = append(payload, payloadType&0x7f)
+	for _, b := range blocks {
+		payload = append(payload, b.data...)
+	}
+	return append(payload, primary...)
+}
@@ -0,0 +1,165 @@
+package webrtc
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types/codec"
+)
+
+type redTestWriter struct {
+	headers  []rtp.Header
+	payloads [][]byte
+}
+
+func (w *redTestWriter) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	w.headers = append(w.headers, *header)
+	w.payloads = append(w.payloads, append([]byte(nil), payload...))
+	return len(payload), nil
+}
+
+func (w *redTestWriter) Write(b []byte) (int, error) { return len(b), nil }
+
+type redTestContext struct {
+	params []webrtc.RTPCodecParameters
+	writer *redTestWriter
+}
+
+func (c *redTestContext) CodecParameters() []webrtc.RTPCodecParameters { return c.params }
+func (c *redTestContext) HeaderExtensions() []webrtc.RTPHeaderExtensionParameter {
+	return nil
+}
+func (c *redTestContext) SSRC() webrtc.SSRC                    { return 1234 }
+func (c *redTestContext) WriteStream() webrtc.TrackLocalWriter { return c.writer }
+func (c *redTestContext) ID() string                           { return "test" }
+func (c *redTestContext) RTCPReader() interceptor.RTCPReader   { return nil }
+
+func redTestParams(withRED bool) []webrtc.RTPCodecParameters {
+	opus, red := codec.Opus(), codec.RED(codec.Opus())
+	params := []webrtc.RTPCodecParameters{
+		{RTPCodecCapability: opus.Capability, PayloadType: opus.PayloadType},
+	}
+	if withRED {
+		params = append(params, webrtc.RTPCodecParameters{RTPCodecCapability: red.Capability, PayloadType: red.PayloadType})
+	}
+	return params
+}
+
+func TestEncodeRED(t *testing.T) {
+	history := []redBlock{
+		{timestamp: 1000, data: []byte{2, 2, 2}},
+	}
+
+	payload := encodeRED(111, 1960, []byte{9}, history)
+
+	want := []byte{
+		0x80 | 111, 960 >> 6, (960 & 0x3f) << 2, 3,
+		111,
+		2, 2, 2,
+		9,
+	}
+	if !bytes.Equal(payload, want) {
+		t.Errorf("encodeRED() = %v, want %v", payload, want)
+	}
+}
+
+func TestEncodeREDOrder(t *testing.T) {
+	history := []redBlock{
+		{timestamp: 0, data: []byte{1}},
+		{timestamp: 960, data: []byte{2}},
+	}
+
+	payload := encodeRED(111, 1920, []byte{3}, history)
+
+	want := []byte{
+		0x80 | 111, 1920 >> 6, (1920 & 0x3f) << 2, 1,
+		0x80 | 111, 960 >> 6, (960 & 0x3f) << 2, 1,
+		111,
+		1, 2, 3,
+	}
+	if !bytes.Equal(payload, want) {
+		t.Errorf("encodeRED() = %v, want %v", payload, want)
+	}
+}
+
+func TestEncodeREDLimits(t *testing.T) {
+	history := []redBlock{
+		{timestamp: 0, data: []byte{1}},              // offset too big
+		{timestamp: 20000, data: make([]byte, 1024)}, // too long
+		{timestamp: 30000, data: make([]byte, 10)},   // fits
+	}
+
+	payload := encodeRED(111, 30960, []byte{3}, history)
+	if len(payload) != 4+1+10+1 {
+		t.Errorf("len(encodeRED()) = %d, want only the newest block", len(payload))
+	}
+
+	if payload := encodeRED(111, 30960, []byte{3}, nil); !bytes.Equal(payload, []byte{111, 3}) {
+		t.Errorf("encodeRED() without history = %v, want only primary", payload)
+	}
+}
+
+func TestRedTrack(t *testing.T) {
+	conf := config.WebRTCAudioRED{Distance: 1, LossThreshold: 5}
+	sample := media.Sample{Data: []byte{7, 7}, Duration: 20 * time.Millisecond}
+
+	track := newRedTrack(zerolog.Nop(), codec.Opus().Capability, "audio", "stream", conf)
+	ctx := &redTestContext{params: redTestParams(true), writer: &redTestWriter{}}
+
+	params, err := track.Bind(ctx)
+	if err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+	if params.MimeType != codec.MimeTypeRED {
+		t.Errorf("Bind() = %s, want %s", params.MimeType, codec.MimeTypeRED)
+	}
+
+	_ = track.WriteSample(sample)
+
+	// no loss reported yet, only primary is wrapped
+	if got := ctx.writer.payloads[0]; !bytes.Equal(got, []byte{111, 7, 7}) {
+		t.Errorf("payload without loss = %v, want only primary", got)
+	}
+
+	track.onReport(rtcp.ReceptionReport{FractionLost: 64})
+	_ = track.WriteSample(sample)
+
+	h0, h1 := ctx.writer.headers[0], ctx.writer.headers[1]
+	if h1.PayloadType != 63 || h1.SequenceNumber != h0.SequenceNumber+1 || h1.Timestamp != h0.Timestamp+960 {
+		t.Errorf("second header = %+v, first %+v", h1, h0)
+	}
+	if got := ctx.writer.payloads[1]; len(got) != 4+1+2+2 {
+		t.Errorf("payload with loss = %v, want one redundant block", got)
+	}
+}
+
+func TestRedTrackUnsupported(t *testing.T) {
+	conf := config.WebRTCAudioRED{Distance: 1}
+
+	track := newRedTrack(zerolog.Nop(), codec.Opus().Capability, "audio", "stream", conf)
+	ctx := &redTestContext{params: redTestParams(false), writer: &redTestWriter{}}
+
+	params, err := track.Bind(ctx)
+	if err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+	if params.MimeType != webrtc.MimeTypeOpus {
+		t.Errorf("Bind() = %s, want %s", params.MimeType, webrtc.MimeTypeOpus)
+	}
+
+	_ = track.WriteSample(media.Sample{Data: []byte{7}, Duration: 20 * time.Millisecond})
+	_ = track.WriteSample(media.Sample{Data: []byte{8}, Duration: 20 * time.Millisecond})
+
+	if h := ctx.writer.headers[1]; h.PayloadType != 111 || !bytes.Equal(ctx.writer.payloads[1], []byte{8}) {
+		t.Errorf("packet = %+v %v, want plain opus", h, ctx.writer.payloads[1])
+	}
+}
@@ -0,0 +1,89 @@
+package webrtc
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// clientRegion returns region of the client, either sent by the client
+// itself or from the header set by geo-IP aware proxy. Empty if unknown.
+func (manager *WebRTCManagerCtx) clientRegion(r *http.Request) string {
+	region := r.URL.Query().Get("region")
+	if region == "" && manager.config.ICERegionHeader != "" {
+		region = r.Header.Get(manager.config.ICERegionHeader)
+	}
+
+	region = strings.TrimSpace(region)
+	if mapped, ok := manager.config.ICERegionMap[region]; ok {
+		return mapped
+	}
+
+	return region
+}
+
+// PinRegion pins region of the client, frontend ICE servers of this region
+// are preferred by peers created for the session.
+func (manager *WebRTCManagerCtx) PinRegion(session types.Session, r *http.Request) {
+	region := manager.clientRegion(r)
+
+	manager.regionsMu.Lock()
+	defer manager.regionsMu.Unlock()
+
+	if region == "" {
+		delete(manager.regions, session.ID())
+		return
+	}
+
+	manager.regions[session.ID()] = region
+}
+
+func (manager *WebRTCManagerCtx) unpinRegion(session types.Session) {
+	manager.regionsMu.Lock()
+	defer manager.regionsMu.Unlock()
+
+	delete(manager.regions, session.ID())
+}
+
+// iceServers returns frontend ICE servers for the session, servers of its
+// region go first, so that the closest relay is preferred.
+func (manager *WebRTCManagerCtx) iceServers(session types.Session) ([]types.ICEServer, string) {
+	manager.regionsMu.Lock()
+	region, ok := manager.regions[session.ID()]
+	manager.regionsMu.Unlock()
+
+	if !ok {
+		return manager.config.ICEServersFrontend, ""
+	}
+
+	return preferRegion(manager.config.ICEServersFrontend, region), region
+}
+
+// preferRegion moves servers of the region in front of the others, keeping their order.
+func preferRegion(servers []types.ICEServer, region string) []types.ICEServer {
+	preferred := []types.ICEServer{}
+	others := []types.ICEServer{}
+	for _, server := range servers {
+		if strings.EqualFold(server.Region, region) {
+			preferred = append(preferred, server)
+		} else {
+			others = append(others, server)
+		}
+	}
+
+	return append(preferred, others...)
+}
+
+// firstTURNServer returns the first server providing relay, that is preferred by the client.
+func firstTURNServer(servers []types.ICEServer) (types.ICEServer, bool) {
+	for _, server := range servers {
+		for _, url := range server.URLs {
+			if isRelayURL(url) {
+				return server, true
+			}
+		}
+	}
+
+	return types.ICEServer{}, false
+}
@@ -0,0 +1,66 @@
+package webrtc
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+func TestClientRegion(t *testing.T) {
+	manager := &WebRTCManagerCtx{
+		config: &config.WebRTC{
+			ICERegionHeader: "CF-IPCountry",
+			ICERegionMap:    map[string]string{"DE": "eu"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		header string
+		want   string
+	}{
+		{"unknown", "", "", ""},
+		{"mapped header", "", "DE", "eu"},
+		{"unmapped header", "", "JP", "JP"},
+		{"client hint wins", "region=us", "DE", "us"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &http.Request{URL: &url.URL{RawQuery: tt.query}, Header: http.Header{}}
+			if tt.header != "" {
+				r.Header.Set("CF-IPCountry", tt.header)
+			}
+
+			if got := manager.clientRegion(r); got != tt.want {
+				t.Errorf("clientRegion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreferRegion(t *testing.T) {
+	servers := []types.ICEServer{
+		{URLs: []string{"stun:stun.example.com"}},
+		{URLs: []string{"turn:us.example.com"}, Region: "us"},
+		{URLs: []string{"turn:eu1.example.com"}, Region: "eu"},
+		{URLs: []string{"turn:eu2.example.com"}, Region: "eu"},
+	}
+
+	got := preferRegion(servers, "eu")
+	want := []string{"turn:eu1.example.com", "turn:eu2.example.com", "stun:stun.example.com", "turn:us.example.com"}
+	for i, server := range got {
+		if server.URLs[0] != want[i] {
+			t.Errorf("server %d = %s, want %s", i, server.URLs[0], want[i])
+		}
+	}
+
+	turn, ok := firstTURNServer(got)
+	if !ok || turn.URLs[0] != "turn:eu1.example.com" {
+		t.Errorf("first turn server = %v, want eu1", turn.URLs)
+	}
+}
@@ -0,0 +1,129 @@
+package webrtc
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// relayTracker accounts peers whose selected candidate pair uses a relay. The
+// limit is only checked when a peer is created, peers created below the limit
+// can still end up on relay, so it can be exceeded briefly.
+type relayTracker struct {
+	max int
+
+	mu    sync.Mutex
+	peers map[*WebRTCPeerCtx]struct{}
+
+	usage prometheus.Gauge
+}
+
+func newRelayTracker(max int) *relayTracker {
+	return &relayTracker{
+		max:   max,
+		peers: map[*WebRTCPeerCtx]struct{}{},
+
+		usage: promauto.NewGauge(prometheus.GaugeOpts{
+			Name:      "relay_peers",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Current number of peers using relay (TURN) connection.",
+		}),
+	}
+}
+
+// allowed returns whether new peer may use relay.
+func (r *relayTracker) allowed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.max <= 0 || len(r.peers) < r.max
+}
+
+// update accounts peer based on its selected candidate pair.
+func (r *relayTracker) update(peer *WebRTCPeerCtx, pair *webrtc.ICECandidatePair) {
+	relayed := pair != nil && (pair.Local.Typ == webrtc.ICECandidateTypeRelay ||
+		pair.Remote.Typ == webrtc.ICECandidateTypeRelay)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if relayed {
+		r.peers[peer] = struct{}{}
+	} else {
+		delete(r.peers, peer)
+	}
+
+	r.usage.Set(float64(len(r.peers)))
+}
+
+func (r *relayTracker) remove(peer *WebRTCPeerCtx) {
+	r.update(peer, nil)
+}
+
+func isRelayURL(url string) bool {
+	return strings.HasPrefix(url, "turn:") || strings.HasPrefix(url, "turns:")
+}
+
+// directICEServers returns frontend ICE servers without relay URLs.
+func directICEServers(servers []types.ICEServer) []types.ICEServer {
+	direct := []types.ICEServer{}
+	for _, server := range servers {
+		urls := []string{}
+		for _, url := range server.URLs {
+			if !isRelayURL(url) {
+				urls = append(urls, url)
+			}
+		}
+
+		if len(urls) > 0 {
+			server.URLs = urls
+			direct = append(direct, server)
+		}
+	}
+	return direct
+}
+
+// directWebRTCICEServers returns backend ICE servers without relay URLs.
+func directWebRTCICEServers(servers []webrtc.ICEServer) []webrtc.ICEServer {
+	direct := []webrtc.ICEServer{}
+	for _, server := range servers {
+		urls := []string{}
+		for _, url := range server.URLs {
+			if !isRelayURL(url) {
+				urls = append(urls, url)
+			}
+		}
+
+		if len(urls) > 0 {
+			server.URLs = urls
+			direct = append(direct, server)
+		}
+	}
+	return direct
+}
+
+// isRelayCandidate returns whether remote candidate is of relay type.
+func isRelayCandidate(candidate string) bool {
+	return strings.Contains(candidate, " typ relay")
+}
+
+// stripRelayCandidates removes relay candidates from session description.
+func stripRelayCandidates(sdp string) string {
+	lines := strings.SplitAfter(sdp, "\n")
+
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if strings.HasPrefix(line, "a=candidate:") && isRelayCandidate(line) {
+			continue
+		}
+		result = append(result, line)
+	}
+
+	return strings.Join(result, "")
+}
@@ -0,0 +1,39 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// Ensure that only relay candidates are removed from session description
+func TestStripRelayCandidates(t *testing.T) {
+	sdp := "v=0\r\n" +
+		"a=candidate:1 1 udp 2130706431 192.168.1.2 50000 typ host\r\n" +
+		"a=candidate:2 1 udp 16777215 203.0.113.1 3478 typ relay raddr 0.0.0.0 rport 0\r\n" +
+		"a=end-of-candidates\r\n"
+
+	expected := "v=0\r\n" +
+		"a=candidate:1 1 udp 2130706431 192.168.1.2 50000 typ host\r\n" +
+		"a=end-of-candidates\r\n"
+
+	if result := stripRelayCandidates(sdp); result != expected {
+		t.Errorf("unexpected sdp:\n%s", result)
+	}
+}
+
+// Ensure that relay URLs are removed and servers without other URLs are dropped
+func TestDirectICEServers(t *testing.T) {
+	servers := directICEServers([]types.ICEServer{
+		{URLs: []string{"stun:stun.example.com:3478", "turn:turn.example.com:3478"}},
+		{URLs: []string{"turns:turn.example.com:5349"}},
+	})
+
+	if len(servers) != 1 {
+		t.Fatalf("got %d servers, expected 1", len(servers))
+	}
+
+	if len(servers[0].URLs) != 1 || servers[0].URLs[0] != "stun:stun.example.com:3478" {
+		t.Errorf("unexpected urls %v", servers[0].URLs)
+	}
+}
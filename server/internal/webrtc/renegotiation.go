@@ -0,0 +1,173 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// renegotiationLimiter limits renegotiations in progress across all peers, so
+// that bursts of media changes do not overwhelm the signaling path. Peers over
+// the limit wait until a slot is released.
+type renegotiationLimiter struct {
+	// nil means unlimited
+	slots chan struct{}
+}
+
+func newRenegotiationLimiter(max int) *renegotiationLimiter {
+	l := &renegotiationLimiter{}
+	if max > 0 {
+		l.slots = make(chan struct{}, max)
+	}
+	return l
+}
+
+// acquire waits for a free slot, it returns false if canceled meanwhile.
+func (l *renegotiationLimiter) acquire(cancel <-chan struct{}) bool {
+	if l.slots == nil {
+		return true
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-cancel:
+		return false
+	}
+}
+
+func (l *renegotiationLimiter) release() {
+	if l.slots == nil {
+		return
+	}
+
+	<-l.slots
+}
+
+// renegotiation is server initiated offer of a peer waiting for an answer.
+// Connection is not stable until it is answered, so there is at most one.
+type renegotiation struct {
+	mu      sync.Mutex
+	release func()
+	timer   *time.Timer
+
+	// renegotiation is waiting for a slot
+	queued bool
+}
+
+// queue returns false if renegotiation is already waiting for a slot, so that
+// negotiation needed meanwhile results in a single offer.
+func (r *renegotiation) queue() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.queued {
+		return false
+	}
+
+	r.queued = true
+	return true
+}
+
+func (r *renegotiation) dequeue() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.queued = false
+}
+
+// started holds the slot until renegotiation finishes or timeout elapses.
+func (r *renegotiation) started(release func(), timeout time.Duration, onTimeout func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.release = release
+	if timeout > 0 {
+		r.timer = time.AfterFunc(timeout, func() {
+			if r.finish() {
+				onTimeout()
+			}
+		})
+	}
+}
+
+// finish releases slot of renegotiation in progress and returns true, if
+// there was any.
+func (r *renegotiation) finish() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.release == nil {
+		return false
+	}
+
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+
+	r.release()
+	r.release = nil
+	return true
+}
+
+// negotiationNeeded handles negotiationneeded event of the connection. It is
+// not fired while connection is not stable, pion fires it again once it gets
+// stable, if negotiation is still needed.
+func (manager *WebRTCManagerCtx) negotiationNeeded(peer *WebRTCPeerCtx) {
+	peer.logger.Warn().Msg("negotiation is needed")
+
+	if peer.connection.SignalingState() != webrtc.SignalingStateStable {
+		peer.logger.Warn().Msg("connection isn't stable yet; postponing...")
+		return
+	}
+
+	manager.renegotiate(peer)
+}
+
+// renegotiate sends new offer to the client once a slot is free, so that only
+// limited number of peers renegotiate at the same time.
+func (manager *WebRTCManagerCtx) renegotiate(peer *WebRTCPeerCtx) {
+	if !peer.renegotiation.queue() {
+		peer.logger.Debug().Msg("renegotiation is already waiting for a slot")
+		return
+	}
+
+	// handler is called from operations of the connection, it must not block
+	go func() {
+		defer peer.renegotiation.dequeue()
+
+		if !manager.renegotiations.acquire(peer.closed) {
+			return
+		}
+
+		// connection may have changed while waiting, negotiation needed is
+		// fired again once it gets stable
+		if peer.connection.SignalingState() != webrtc.SignalingStateStable {
+			peer.logger.Warn().Msg("connection isn't stable yet; postponing...")
+			manager.renegotiations.release()
+			return
+		}
+
+		offer, err := peer.CreateOffer(false)
+		if err != nil {
+			peer.logger.Err(err).Msg("sdp offer failed")
+			manager.renegotiations.release()
+			return
+		}
+
+		peer.renegotiation.started(manager.renegotiations.release, manager.config.RenegotiationTimeout, func() {
+			peer.logger.Warn().Msg("renegotiation was not answered in time")
+		})
+
+		peer.session.Send(
+			event.SIGNAL_OFFER,
+			message.SignalDescription{
+				SDP: offer.SDP,
+			})
+	}()
+}
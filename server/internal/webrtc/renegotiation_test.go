@@ -0,0 +1,192 @@
+package webrtc
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+)
+
+func TestRenegotiationLimiter(t *testing.T) {
+	l := newRenegotiationLimiter(1)
+	cancel := make(chan struct{})
+
+	if !l.acquire(cancel) {
+		t.Fatalf("acquire() = false, want free slot")
+	}
+
+	acquired := make(chan bool)
+	go func() {
+		acquired <- l.acquire(cancel)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatalf("acquire() did not wait for slot over the limit")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	l.release()
+	if !<-acquired {
+		t.Errorf("acquire() = false after slot was released")
+	}
+
+	close(cancel)
+	if l.acquire(cancel) {
+		t.Errorf("acquire() = true, want canceled")
+	}
+}
+
+func TestRenegotiationLimiterUnlimited(t *testing.T) {
+	l := newRenegotiationLimiter(0)
+	for i := 0; i < 10; i++ {
+		if !l.acquire(nil) {
+			t.Fatalf("acquire() = false without limit")
+		}
+	}
+	l.release()
+}
+
+func TestRenegotiationFinish(t *testing.T) {
+	r := renegotiation{}
+	released := 0
+
+	if r.finish() {
+		t.Errorf("finish() = true without renegotiation in progress")
+	}
+
+	r.started(func() { released++ }, time.Minute, func() {
+		t.Errorf("renegotiation timed out")
+	})
+
+	if !r.finish() || r.finish() {
+		t.Errorf("finish() must report renegotiation only once")
+	}
+	if released != 1 {
+		t.Errorf("slot released %d times, want once", released)
+	}
+}
+
+func TestRenegotiationTimeout(t *testing.T) {
+	r := renegotiation{}
+	timedOut := make(chan struct{})
+
+	r.started(func() {}, 10*time.Millisecond, func() {
+		close(timedOut)
+	})
+
+	select {
+	case <-timedOut:
+	case <-time.After(time.Second):
+		t.Fatalf("renegotiation did not time out")
+	}
+
+	if r.finish() {
+		t.Errorf("finish() = true after timeout")
+	}
+}
+
+func TestRenegotiationQueue(t *testing.T) {
+	r := renegotiation{}
+
+	if !r.queue() {
+		t.Errorf("queue() = false without renegotiation waiting")
+	}
+	if r.queue() {
+		t.Errorf("queue() = true while renegotiation is waiting")
+	}
+
+	r.dequeue()
+	if !r.queue() {
+		t.Errorf("queue() = false after renegotiation stopped waiting")
+	}
+}
+
+type renegotiationTestSession struct {
+	types.Session
+	offers atomic.Int32
+}
+
+func (s *renegotiationTestSession) Send(ev string, payload any) {
+	if ev == event.SIGNAL_OFFER {
+		s.offers.Add(1)
+	}
+}
+
+// Negotiation needed while renegotiation waits for a slot and connection gets
+// unstable meanwhile, must result in exactly one offer once it is stable.
+func TestRenegotiationSingleOffer(t *testing.T) {
+	server := newTestConnection(t)
+	client, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatalf("unable to create peer connection: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	// initial negotiation
+	if err := client.SetRemoteDescription(createTestOffer(t, server)); err != nil {
+		t.Fatalf("unable to set server offer: %v", err)
+	}
+	if err := server.SetRemoteDescription(createTestAnswer(t, client)); err != nil {
+		t.Fatalf("unable to set client answer: %v", err)
+	}
+
+	session := &renegotiationTestSession{}
+	manager := &WebRTCManagerCtx{
+		config:         &config.WebRTC{RenegotiationTimeout: time.Minute},
+		renegotiations: newRenegotiationLimiter(1),
+	}
+	peer := &WebRTCPeerCtx{
+		logger:     zerolog.Nop(),
+		session:    session,
+		connection: server,
+		closed:     make(chan struct{}),
+		iceTrickle: true,
+	}
+	server.OnNegotiationNeeded(func() {
+		manager.negotiationNeeded(peer)
+	})
+
+	// other peer holds the only slot
+	manager.renegotiations.acquire(nil)
+
+	track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus}, "audio", "neko")
+	if err != nil {
+		t.Fatalf("unable to create track: %v", err)
+	}
+	if _, err := server.AddTrack(track); err != nil {
+		t.Fatalf("unable to add track: %v", err)
+	}
+
+	// client offers while renegotiation waits
+	time.Sleep(20 * time.Millisecond)
+	if err := server.SetRemoteDescription(createTestOffer(t, client)); err != nil {
+		t.Fatalf("unable to set client offer: %v", err)
+	}
+
+	manager.renegotiations.release()
+	time.Sleep(20 * time.Millisecond)
+
+	if n := session.offers.Load(); n != 0 {
+		t.Fatalf("sent %d offers while connection was not stable", n)
+	}
+
+	// answer makes connection stable again
+	if err := client.SetRemoteDescription(createTestAnswer(t, server)); err != nil {
+		t.Fatalf("unable to set server answer: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if n := session.offers.Load(); n != 1 {
+		t.Errorf("sent %d offers, want exactly one", n)
+	}
+}
@@ -0,0 +1,85 @@
+package webrtc
+
+import "time"
+
+// qualityRestore adds hysteresis to upgrades of the estimator, so that quality
+// does not oscillate on connections with bandwidth around stream bitrate. After
+// congestion caused a downgrade, bandwidth must be good for the restore delay
+// before every upgrade, until the highest stream is reached. Upgrades within a
+// window are limited regardless of congestion.
+type qualityRestore struct {
+	delay       time.Duration
+	maxUpgrades int
+	window      time.Duration
+
+	// since when bandwidth is good, zero if it is not
+	goodSince time.Time
+	// quality was downgraded and is not fully restored yet
+	restoring bool
+	// times of upgrades within the window
+	upgrades []time.Time
+}
+
+func newQualityRestore(delay time.Duration, maxUpgrades int, window time.Duration) *qualityRestore {
+	return &qualityRestore{
+		delay:       delay,
+		maxUpgrades: maxUpgrades,
+		window:      window,
+	}
+}
+
+// observe records whether estimated bandwidth can accommodate higher stream.
+func (r *qualityRestore) observe(now time.Time, good bool) {
+	if !good {
+		r.goodSince = time.Time{}
+	} else if r.goodSince.IsZero() {
+		r.goodSince = now
+	}
+}
+
+func (r *qualityRestore) downgraded() {
+	r.restoring = true
+	r.goodSince = time.Time{}
+}
+
+// upgraded records an upgrade, next one needs sustained good bandwidth again.
+// Once on the highest stream, quality is restored.
+func (r *qualityRestore) upgraded(now time.Time, highest bool) {
+	if highest {
+		r.restoring = false
+		return
+	}
+
+	r.goodSince = now
+	r.upgrades = append(r.upgrades, now)
+}
+
+// waiting returns for how long upgrade must wait, zero if it is allowed now.
+func (r *qualityRestore) waiting(now time.Time) time.Duration {
+	var wait time.Duration
+
+	if r.restoring && r.delay > 0 {
+		if r.goodSince.IsZero() {
+			wait = r.delay
+		} else if good := now.Sub(r.goodSince); good < r.delay {
+			wait = r.delay - good
+		}
+	}
+
+	if r.maxUpgrades <= 0 {
+		return wait
+	}
+
+	// forget upgrades outside of the window
+	i := 0
+	for i < len(r.upgrades) && now.Sub(r.upgrades[i]) >= r.window {
+		i++
+	}
+	r.upgrades = r.upgrades[i:]
+
+	if len(r.upgrades) >= r.maxUpgrades {
+		wait = max(wait, r.window-now.Sub(r.upgrades[0]))
+	}
+
+	return wait
+}
@@ -0,0 +1,65 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQualityRestoreDelay(t *testing.T) {
+	r := newQualityRestore(10*time.Second, 0, time.Minute)
+	now := time.Now()
+
+	r.observe(now, true)
+	if wait := r.waiting(now); wait != 0 {
+		t.Fatalf("waiting() = %v without congestion, want 0", wait)
+	}
+
+	r.downgraded()
+	if wait := r.waiting(now); wait != 10*time.Second {
+		t.Errorf("waiting() = %v right after downgrade, want 10s", wait)
+	}
+
+	r.observe(now, true)
+	if wait := r.waiting(now.Add(4 * time.Second)); wait != 6*time.Second {
+		t.Errorf("waiting() = %v, want 6s", wait)
+	}
+
+	// bandwidth dropped meanwhile, delay starts again
+	r.observe(now.Add(5*time.Second), false)
+	r.observe(now.Add(6*time.Second), true)
+	if wait := r.waiting(now.Add(12 * time.Second)); wait != 4*time.Second {
+		t.Errorf("waiting() = %v after blip, want 4s", wait)
+	}
+
+	if wait := r.waiting(now.Add(16 * time.Second)); wait != 0 {
+		t.Errorf("waiting() = %v after sustained good bandwidth, want 0", wait)
+	}
+
+	// every step needs sustained good bandwidth until highest stream
+	r.upgraded(now.Add(16*time.Second), false)
+	if wait := r.waiting(now.Add(17 * time.Second)); wait != 9*time.Second {
+		t.Errorf("waiting() = %v after upgrade, want 9s", wait)
+	}
+
+	r.upgraded(now.Add(26*time.Second), true)
+	if wait := r.waiting(now.Add(26 * time.Second)); wait != 0 {
+		t.Errorf("waiting() = %v on highest stream, want 0", wait)
+	}
+}
+
+func TestQualityRestoreMaxUpgrades(t *testing.T) {
+	r := newQualityRestore(0, 2, time.Minute)
+	now := time.Now()
+
+	r.upgraded(now, false)
+	r.upgraded(now.Add(10*time.Second), false)
+
+	if wait := r.waiting(now.Add(20 * time.Second)); wait != 40*time.Second {
+		t.Errorf("waiting() = %v over limit, want 40s", wait)
+	}
+
+	// first upgrade left the window
+	if wait := r.waiting(now.Add(time.Minute)); wait != 0 {
+		t.Errorf("waiting() = %v, want 0", wait)
+	}
+}
@@ -0,0 +1,216 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// senderReports sends RTCP sender reports of all tracks of a peer connection,
+// that map RTP timestamps to NTP timestamps of one wall clock. Receivers use
+// them to play audio and video in sync.
+//
+// RTP timestamps are derived from durations of samples, so only the time when
+// the last packet was sent is known. Audio and video pipelines take different
+// time from capture until the packet is sent, with capture clock the mapping
+// is moved back by latency of the track, so that both tracks refer to the
+// time of capture.
+type senderReports struct {
+	interval     time.Duration
+	captureClock bool
+	now          func() time.Time
+
+	mu sync.Mutex
+	// delays of tracks by their SSRC
+	tracks map[uint32]*trackSync
+}
+
+func newSenderReports(interval time.Duration, captureClock bool) *senderReports {
+	return &senderReports{
+		interval:     interval,
+		captureClock: captureClock,
+		now:          time.Now,
+		tracks:       map[uint32]*trackSync{},
+	}
+}
+
+func (s *senderReports) addTrack(ssrc uint32, delays *trackSync) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tracks[ssrc] = delays
+}
+
+func (s *senderReports) removeTrack(ssrc uint32) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tracks, ssrc)
+}
+
+// latency returns time from capture until packets of the track are sent.
+func (s *senderReports) latency(ssrc uint32) time.Duration {
+	if !s.captureClock {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delays, ok := s.tracks[ssrc]
+	if !ok {
+		return 0
+	}
+
+	return time.Duration(delays.latency.Load())
+}
+
+// NewInterceptor implements interceptor.Factory, registry of every peer
+// connection has its own instance of sender reports.
+func (s *senderReports) NewInterceptor(_ string) (interceptor.Interceptor, error) {
+	return &senderReportInterceptor{
+		reports: s,
+		close:   make(chan struct{}),
+	}, nil
+}
+
+type senderReportInterceptor struct {
+	interceptor.NoOp
+	reports *senderReports
+
+	streams sync.Map // ssrc -> *senderReportStream
+	wg      sync.WaitGroup
+	close   chan struct{}
+	once    sync.Once
+}
+
+func (i *senderReportInterceptor) BindRTCPWriter(writer interceptor.RTCPWriter) interceptor.RTCPWriter {
+	i.wg.Add(1)
+	go i.loop(writer)
+
+	return writer
+}
+
+func (i *senderReportInterceptor) BindLocalStream(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	stream := &senderReportStream{
+		ssrc:      info.SSRC,
+		clockRate: float64(info.ClockRate),
+	}
+	i.streams.Store(info.SSRC, stream)
+
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, a interceptor.Attributes) (int, error) {
+		stream.sent(i.reports.now(), header, payload)
+
+		return writer.Write(header, payload, a)
+	})
+}
+
+func (i *senderReportInterceptor) UnbindLocalStream(info *interceptor.StreamInfo) {
+	i.streams.Delete(info.SSRC)
+}
+
+func (i *senderReportInterceptor) Close() error {
+	i.once.Do(func() {
+		close(i.close)
+	})
+
+	i.wg.Wait()
+	return nil
+}
+
+func (i *senderReportInterceptor) loop(writer interceptor.RTCPWriter) {
+	defer i.wg.Done()
+
+	ticker := time.NewTicker(i.reports.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-i.close:
+			return
+		case <-ticker.C:
+		}
+
+		// all reports of the connection use the same clock reading
+		now := i.reports.now()
+		i.streams.Range(func(_, value any) bool {
+			stream := value.(*senderReportStream)
+
+			report, ok := stream.report(now, i.reports.latency(stream.ssrc))
+			if ok {
+				_, _ = writer.Write([]rtcp.Packet{report}, interceptor.Attributes{})
+			}
+
+			return true
+		})
+	}
+}
+
+type senderReportStream struct {
+	ssrc      uint32
+	clockRate float64
+
+	mu          sync.Mutex
+	lastRTPTime uint32
+	lastSent    time.Time
+	lastSeq     uint16
+	packets     uint32
+	octets      uint32
+}
+
+func (s *senderReportStream) sent(now time.Time, header *rtp.Header, payload []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// only in order packets advance the timestamp mapping
+	diff := header.SequenceNumber - s.lastSeq
+	if s.packets == 0 || (diff > 0 && diff < 1<<15) {
+		s.lastSeq = header.SequenceNumber
+		s.lastRTPTime = header.Timestamp
+		s.lastSent = now
+	}
+
+	s.packets++
+	s.octets += uint32(len(payload))
+}
+
+// report maps RTP timestamp to NTP timestamp at now. Packet sent at the last
+// send time was captured by latency earlier, so RTP time is extrapolated from
+// the time of capture.
+func (s *senderReportStream) report(now time.Time, latency time.Duration) (*rtcp.SenderReport, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// nothing was sent yet
+	if s.packets == 0 {
+		return nil, false
+	}
+
+	elapsed := now.Sub(s.lastSent) + latency
+
+	return &rtcp.SenderReport{
+		SSRC:        s.ssrc,
+		NTPTime:     ntpTime(now),
+		RTPTime:     s.lastRTPTime + uint32(int64(elapsed.Seconds()*s.clockRate)),
+		PacketCount: s.packets,
+		OctetCount:  s.octets,
+	}, true
+}
+
+// ntpTime returns 64 bit NTP timestamp, seconds since 1900 with fraction.
+func ntpTime(t time.Time) uint64 {
+	seconds := uint64(t.Unix()) + ntpEpochOffset
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return seconds<<32 | fraction
+}
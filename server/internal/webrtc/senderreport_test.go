@@ -0,0 +1,133 @@
+package webrtc
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+func TestSenderReportStream(t *testing.T) {
+	stream := &senderReportStream{ssrc: 1, clockRate: 90000}
+	sent := time.Unix(1700000000, 0)
+
+	if _, ok := stream.report(sent, 0); ok {
+		t.Fatal("report() returned report before any packet was sent")
+	}
+
+	stream.sent(sent, &rtp.Header{SequenceNumber: 10, Timestamp: 1000}, make([]byte, 100))
+	// out of order packet does not move the mapping
+	stream.sent(sent.Add(time.Second), &rtp.Header{SequenceNumber: 9, Timestamp: 500}, make([]byte, 50))
+
+	now := sent.Add(100 * time.Millisecond)
+	report, ok := stream.report(now, 0)
+	if !ok {
+		t.Fatal("report() returned no report")
+	}
+
+	if want := uint32(1000 + 9000); report.RTPTime != want {
+		t.Errorf("RTPTime = %d, want %d", report.RTPTime, want)
+	}
+	if report.NTPTime != ntpTime(now) {
+		t.Errorf("NTPTime = %x, want %x", report.NTPTime, ntpTime(now))
+	}
+	if report.PacketCount != 2 || report.OctetCount != 150 {
+		t.Errorf("counts = %d/%d, want 2/150", report.PacketCount, report.OctetCount)
+	}
+
+	// packet captured 50ms before it was sent
+	report, _ = stream.report(now, 50*time.Millisecond)
+	if want := uint32(1000 + 13500); report.RTPTime != want {
+		t.Errorf("RTPTime with latency = %d, want %d", report.RTPTime, want)
+	}
+}
+
+func TestNTPTime(t *testing.T) {
+	now := time.Unix(1700000000, int64(250*time.Millisecond))
+
+	ntp := ntpTime(now)
+	if seconds := ntp >> 32; seconds != 1700000000+ntpEpochOffset {
+		t.Errorf("seconds = %d, want %d", seconds, 1700000000+ntpEpochOffset)
+	}
+	if fraction := uint32(ntp); fraction != 1<<30 {
+		t.Errorf("fraction = %d, want %d", fraction, 1<<30)
+	}
+	if middle := uint32(ntp >> 16); middle != ntpMiddle(now) {
+		t.Errorf("middle bits = %x, want %x", middle, ntpMiddle(now))
+	}
+}
+
+type rtcpCollector struct {
+	mu      sync.Mutex
+	reports []*rtcp.SenderReport
+}
+
+func (c *rtcpCollector) Write(pkts []rtcp.Packet, _ interceptor.Attributes) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, pkt := range pkts {
+		if sr, ok := pkt.(*rtcp.SenderReport); ok {
+			c.reports = append(c.reports, sr)
+		}
+	}
+	return 0, nil
+}
+
+func TestSenderReportsCommonClock(t *testing.T) {
+	reports := newSenderReports(10*time.Millisecond, true)
+
+	audio, video := &trackSync{}, &trackSync{}
+	audio.latency.Store(int64(20 * time.Millisecond))
+	video.latency.Store(int64(80 * time.Millisecond))
+	reports.addTrack(1, audio)
+	reports.addTrack(2, video)
+
+	i, err := reports.NewInterceptor("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collector := &rtcpCollector{}
+	i.BindRTCPWriter(collector)
+
+	noop := interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, _ interceptor.Attributes) (int, error) {
+		return len(payload), nil
+	})
+	audioWriter := i.BindLocalStream(&interceptor.StreamInfo{SSRC: 1, ClockRate: 48000}, noop)
+	videoWriter := i.BindLocalStream(&interceptor.StreamInfo{SSRC: 2, ClockRate: 90000}, noop)
+	_, _ = audioWriter.Write(&rtp.Header{SequenceNumber: 1}, []byte{0}, nil)
+	_, _ = videoWriter.Write(&rtp.Header{SequenceNumber: 1}, []byte{0}, nil)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		collector.mu.Lock()
+		n := len(collector.reports)
+		collector.mu.Unlock()
+
+		if n >= 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := i.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	if len(collector.reports) < 2 {
+		t.Fatalf("got %d sender reports, want at least 2", len(collector.reports))
+	}
+
+	// reports of one tick share the clock reading
+	first, second := collector.reports[0], collector.reports[1]
+	if first.NTPTime != second.NTPTime {
+		t.Errorf("NTP times differ: %x and %x", first.NTPTime, second.NTPTime)
+	}
+}
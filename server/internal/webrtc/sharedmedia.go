@@ -0,0 +1,46 @@
+package webrtc
+
+import "sync"
+
+// sharedMedia holds stop function of a media shared by a session, only
+// one session can share the same media type at a time.
+type sharedMedia struct {
+	mu        sync.Mutex
+	sessionId string
+	stop      func()
+}
+
+// replace stops currently shared media and sets a new one.
+func (m *sharedMedia) replace(sessionId string, stop func()) {
+	m.mu.Lock()
+	prev := m.stop
+	m.sessionId, m.stop = sessionId, stop
+	m.mu.Unlock()
+
+	if prev != nil {
+		prev()
+	}
+}
+
+// stopOwnedBy stops shared media, if it is owned by given session.
+func (m *sharedMedia) stopOwnedBy(sessionId string) {
+	m.mu.Lock()
+	if m.sessionId != sessionId || m.stop == nil {
+		m.mu.Unlock()
+		return
+	}
+
+	stop := m.stop
+	m.sessionId, m.stop = "", nil
+	m.mu.Unlock()
+
+	stop()
+}
+
+// ownedBy tells whether the media is shared by given session.
+func (m *sharedMedia) ownedBy(sessionId string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sessionId == sessionId && m.stop != nil
+}
@@ -0,0 +1,69 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+type sharedMediaTestSession struct {
+	types.Session
+	id string
+}
+
+func (s *sharedMediaTestSession) ID() string                      { return s.id }
+func (s *sharedMediaTestSession) GetWebRTCPeer() types.WebRTCPeer { return nil }
+
+func TestSharedMediaReplace(t *testing.T) {
+	m := sharedMedia{}
+	stopped := map[string]int{}
+
+	m.replace("a", func() { stopped["a"]++ })
+	m.replace("b", func() { stopped["b"]++ })
+	if stopped["a"] != 1 || !m.ownedBy("b") {
+		t.Fatalf("previous media was not replaced, stopped %v", stopped)
+	}
+
+	// only owner can stop the media
+	m.stopOwnedBy("a")
+	if stopped["b"] != 0 || !m.ownedBy("b") {
+		t.Errorf("media stopped by session that does not own it, stopped %v", stopped)
+	}
+
+	m.stopOwnedBy("b")
+	m.stopOwnedBy("b")
+	if stopped["b"] != 1 || m.ownedBy("b") {
+		t.Errorf("media stopped %d times, want once", stopped["b"])
+	}
+}
+
+// closing peers of a session releases only devices it shares
+func TestClosePeersStopsOwnedMedia(t *testing.T) {
+	manager := &WebRTCManagerCtx{
+		mediaResume: newMediaResume(time.Minute),
+		publicIPs:   map[string]string{"a": "1.2.3.4", "b": "5.6.7.8"},
+		regions:     map[string]string{"a": "eu"},
+	}
+
+	stopped := map[string]int{}
+	manager.mic.replace("a", func() { stopped["mic"]++ })
+	manager.cam.replace("b", func() { stopped["cam"]++ })
+
+	manager.ClosePeers(&sharedMediaTestSession{id: "a"})
+
+	if stopped["mic"] != 1 || stopped["cam"] != 0 {
+		t.Errorf("stopped %v, want only microphone of the session", stopped)
+	}
+	if _, ok := manager.publicIPs["a"]; ok || len(manager.publicIPs) != 1 {
+		t.Errorf("public IPs %v, want only pin of the session removed", manager.publicIPs)
+	}
+	if len(manager.regions) != 0 {
+		t.Errorf("regions %v, want region of the session removed", manager.regions)
+	}
+
+	// shared media is offered back once the session reconnects
+	if mic, cam := manager.mediaResume.take("a"); !mic || cam {
+		t.Errorf("take(a) = %v, %v, want microphone only", mic, cam)
+	}
+}
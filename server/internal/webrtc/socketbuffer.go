@@ -0,0 +1,48 @@
+package webrtc
+
+import (
+	"net"
+
+	"github.com/pion/transport/v2"
+	"github.com/rs/zerolog"
+)
+
+// socketBufferNet sets receive and send buffer sizes of UDP sockets, so that bursts
+// of high bitrate streams are not dropped by the kernel. Sizes are capped by the
+// system (net.core.rmem_max and net.core.wmem_max on Linux), that is only logged.
+type socketBufferNet struct {
+	transport.Net
+	logger zerolog.Logger
+	read   int
+	write  int
+}
+
+func newSocketBufferNet(n transport.Net, logger zerolog.Logger, read, write int) *socketBufferNet {
+	return &socketBufferNet{
+		Net:    n,
+		logger: logger.With().Str("submodule", "socket-buffer").Logger(),
+		read:   read,
+		write:  write,
+	}
+}
+
+func (n *socketBufferNet) ListenUDP(network string, locAddr *net.UDPAddr) (transport.UDPConn, error) {
+	conn, err := n.Net.ListenUDP(network, locAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.read > 0 {
+		if err := conn.SetReadBuffer(n.read); err != nil {
+			n.logger.Warn().Err(err).Str("addr", conn.LocalAddr().String()).Int("size", n.read).Msg("unable to set socket read buffer")
+		}
+	}
+
+	if n.write > 0 {
+		if err := conn.SetWriteBuffer(n.write); err != nil {
+			n.logger.Warn().Err(err).Str("addr", conn.LocalAddr().String()).Int("size", n.write).Msg("unable to set socket write buffer")
+		}
+	}
+
+	return conn, nil
+}
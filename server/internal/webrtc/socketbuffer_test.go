@@ -0,0 +1,50 @@
+package webrtc
+
+import (
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/pion/transport/v2/stdnet"
+	"github.com/rs/zerolog"
+)
+
+// Ensure that socket buffers are set on created UDP sockets
+func TestSocketBufferNet(t *testing.T) {
+	n, err := stdnet.NewNet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const read, write = 32 << 10, 48 << 10
+
+	conn, err := newSocketBufferNet(n, zerolog.Nop(), read, write).ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	raw, err := conn.(syscall.Conn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sockopt := func(opt int) int {
+		var value int
+		_ = raw.Control(func(fd uintptr) {
+			value, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, opt)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+
+	// kernel may reserve more than requested for bookkeeping
+	if v := sockopt(syscall.SO_RCVBUF); v < read {
+		t.Errorf("read buffer is %d, expected at least %d", v, read)
+	}
+	if v := sockopt(syscall.SO_SNDBUF); v < write {
+		t.Errorf("write buffer is %d, expected at least %d", v, write)
+	}
+}
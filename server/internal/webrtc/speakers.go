@@ -0,0 +1,331 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/codec"
+)
+
+const (
+	audioLevelURI = "urn:ietf:params:rtp-hdrext:ssrc-audio-level"
+
+	// weight of a new audio level in the smoothed loudness of a speaker
+	speakerSmoothing = 0.2
+	// how much louder in dB must a speaker be to take slot of another one,
+	// so that two speakers talking at once do not take turns every packet
+	speakerPreemptMargin = 6
+)
+
+// speakerMic is a microphone shared by a session.
+type speakerMic struct {
+	sessionId string
+	stop      func()
+
+	// smoothed loudness in dB above silence (-127 dBov)
+	loudness float64
+	// when its level was last above the threshold
+	heard time.Time
+	// slot it is forwarded to, -1 if it is muted
+	slot int
+}
+
+// speakers allows concurrently shared microphones and selects the loudest of
+// them based on audio level RTP header extension. Selected speakers are
+// assigned to capture slots, others are muted. Speakers keep their slot until
+// they are silent for the hold time, or a louder speaker needs it.
+type speakers struct {
+	threshold uint8
+	hold      time.Duration
+
+	mu sync.Mutex
+	// shared microphones by session id
+	mics map[string]*speakerMic
+	// speaker in each slot, nil if the slot is free
+	assigned []*speakerMic
+}
+
+func newSpeakers(slots int, threshold uint8, hold time.Duration) *speakers {
+	return &speakers{
+		threshold: threshold,
+		hold:      hold,
+		mics:      map[string]*speakerMic{},
+		assigned:  make([]*speakerMic, slots),
+	}
+}
+
+// replace stops microphone previously shared by the session and adds a new one.
+func (s *speakers) replace(sessionId string, stop func()) *speakerMic {
+	mic := &speakerMic{
+		sessionId: sessionId,
+		stop:      stop,
+		slot:      -1,
+	}
+
+	s.mu.Lock()
+	prev := s.mics[sessionId]
+	s.mics[sessionId] = mic
+	s.mu.Unlock()
+
+	if prev != nil {
+		prev.stop()
+	}
+
+	return mic
+}
+
+// remove forgets the microphone and returns slot it was forwarded to, -1 if none.
+func (s *speakers) remove(mic *speakerMic) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.mics[mic.sessionId] == mic {
+		delete(s.mics, mic.sessionId)
+	}
+
+	slot := mic.slot
+	mic.slot = -1
+
+	if slot < 0 || s.assigned[slot] != mic {
+		return -1
+	}
+
+	s.assigned[slot] = nil
+	return slot
+}
+
+// stopOwnedBy stops microphone shared by given session.
+func (s *speakers) stopOwnedBy(sessionId string) {
+	s.mu.Lock()
+	mic, ok := s.mics[sessionId]
+	s.mu.Unlock()
+
+	if ok {
+		mic.stop()
+	}
+}
+
+// ownedBy tells whether given session shares a microphone.
+func (s *speakers) ownedBy(sessionId string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.mics[sessionId]
+	return ok
+}
+
+// observe records audio level of the microphone in -dBov and returns slot it
+// should be forwarded to, -1 if it is muted.
+func (s *speakers) observe(mic *speakerMic, level uint8, now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.mics[mic.sessionId] != mic {
+		return -1
+	}
+
+	mic.loudness += speakerSmoothing * (float64(127-level) - mic.loudness)
+	if level < s.threshold {
+		mic.heard = now
+	}
+
+	// free slots of speakers that are silent for longer than hold
+	for i, other := range s.assigned {
+		if other != nil && !s.speaking(other, now) {
+			other.slot = -1
+			s.assigned[i] = nil
+		}
+	}
+
+	if mic.slot >= 0 || !s.speaking(mic, now) {
+		return mic.slot
+	}
+
+	// take free slot, or slot of the quietest speaker that is quieter enough
+	slot := -1
+	for i, other := range s.assigned {
+		if other == nil {
+			slot = i
+			break
+		}
+
+		if mic.loudness-other.loudness < speakerPreemptMargin {
+			continue
+		}
+
+		if slot < 0 || other.loudness < s.assigned[slot].loudness {
+			slot = i
+		}
+	}
+
+	if slot < 0 {
+		return -1
+	}
+
+	if other := s.assigned[slot]; other != nil {
+		other.slot = -1
+	}
+
+	s.assigned[slot] = mic
+	mic.slot = slot
+	return slot
+}
+
+func (s *speakers) speaking(mic *speakerMic, now time.Time) bool {
+	return !mic.heard.IsZero() && now.Sub(mic.heard) <= s.hold
+}
+
+// audioLevelExtension returns id of negotiated audio level header extension,
+// 0 if it was not negotiated.
+func audioLevelExtension(receiver *webrtc.RTPReceiver) uint8 {
+	for _, ext := range receiver.GetParameters().HeaderExtensions {
+		if ext.URI == audioLevelURI {
+			return uint8(ext.ID)
+		}
+	}
+	return 0
+}
+
+// speakerSrc forwards shared microphone to the slot it was assigned, while it
+// is among the loudest speakers.
+type speakerSrc struct {
+	logger   zerolog.Logger
+	speakers *speakers
+	slots    []types.SpeakerSrc
+	route    types.MicrophoneRoute
+	// id of audio level header extension, 0 if it was not negotiated
+	extension uint8
+
+	mic *speakerMic
+
+	mu    sync.Mutex
+	codec codec.RTPCodec
+	// slot whose sources are started for this microphone, -1 if none
+	slot int
+	// source of the slot for the route
+	src types.StreamSrcManager
+}
+
+// speakerSrc returns stream source of shared microphone of a concurrent speaker.
+func (manager *WebRTCManagerCtx) speakerSrc(logger zerolog.Logger, sessionId string, route types.MicrophoneRoute, extension uint8, stop func()) *speakerSrc {
+	if extension == 0 {
+		logger.Warn().Msg("audio level was not negotiated, microphone is considered always speaking")
+	}
+
+	return &speakerSrc{
+		logger:    logger,
+		speakers:  manager.speakers,
+		slots:     manager.capture.Speakers(),
+		route:     route,
+		extension: extension,
+		mic:       manager.speakers.replace(sessionId, stop),
+		slot:      -1,
+	}
+}
+
+func (src *speakerSrc) Enabled() bool {
+	return src.slots[0].Microphone.Enabled()
+}
+
+func (src *speakerSrc) Codec() codec.RTPCodec {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+
+	return src.codec
+}
+
+// Start only remembers the codec, sources are started once the microphone
+// gets a slot.
+func (src *speakerSrc) Start(codec codec.RTPCodec) error {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+
+	src.codec = codec
+	return nil
+}
+
+func (src *speakerSrc) Stop() {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+
+	if slot := src.speakers.remove(src.mic); slot >= 0 && slot == src.slot {
+		src.slots[slot].Microphone.Stop()
+		src.slots[slot].MicrophoneMix.Stop()
+	}
+	src.slot, src.src = -1, nil
+}
+
+func (src *speakerSrc) Push(bytes []byte) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+
+	slot := src.speakers.observe(src.mic, src.level(bytes), time.Now())
+	if slot < 0 {
+		// slot may be taken by another speaker meanwhile
+		src.slot, src.src = -1, nil
+		return
+	}
+
+	if slot != src.slot {
+		src.slot, src.src = slot, nil
+
+		// sources may be started by previous speaker with another route
+		src.slots[slot].Microphone.Stop()
+		src.slots[slot].MicrophoneMix.Stop()
+
+		routed := routedSrc(src.route, src.slots[slot].Microphone, src.slots[slot].MicrophoneMix)
+		if err := routed.Start(src.codec); err != nil {
+			src.logger.Err(err).Int("slot", slot).Msg("failed to start speaker pipeline")
+			return
+		}
+
+		src.logger.Debug().Int("slot", slot).Msg("speaker is forwarded")
+		src.src = routed
+	}
+
+	if src.src != nil {
+		src.src.Push(bytes)
+	}
+}
+
+func (src *speakerSrc) Started() bool {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+
+	return src.src != nil && src.src.Started()
+}
+
+// level returns audio level of the packet in -dBov, packets without it are
+// considered just loud enough to be speaking.
+func (src *speakerSrc) level(bytes []byte) uint8 {
+	fallback := src.speakers.threshold
+	if fallback > 0 {
+		fallback--
+	}
+
+	if src.extension == 0 {
+		return fallback
+	}
+
+	header := rtp.Header{}
+	if _, err := header.Unmarshal(bytes); err != nil {
+		return fallback
+	}
+
+	ext := header.GetExtension(src.extension)
+	if ext == nil {
+		return fallback
+	}
+
+	audioLevel := rtp.AudioLevelExtension{}
+	if err := audioLevel.Unmarshal(ext); err != nil {
+		return fallback
+	}
+
+	return audioLevel.Level
+}
@@ -0,0 +1,94 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSpeakers(t *testing.T) {
+	s := newSpeakers(1, 50, time.Second)
+	now := time.Now()
+
+	a := s.replace("a", func() {})
+	b := s.replace("b", func() {})
+
+	// silence does not take a slot
+	if slot := s.observe(a, 127, now); slot != -1 {
+		t.Fatalf("expected silent speaker to be muted, got slot %d", slot)
+	}
+
+	// first speaker takes the free slot
+	for i := 0; i < 20; i++ {
+		if slot := s.observe(a, 40, now); slot != 0 {
+			t.Fatalf("expected speaker a in slot 0, got %d", slot)
+		}
+	}
+
+	// slightly louder speaker does not take it
+	if slot := s.observe(b, 38, now); slot != -1 {
+		t.Fatalf("expected speaker b to be muted, got slot %d", slot)
+	}
+
+	// much louder speaker takes it once its loudness builds up
+	slot := -1
+	for i := 0; i < 20 && slot < 0; i++ {
+		slot = s.observe(b, 10, now)
+	}
+	if slot != 0 {
+		t.Fatalf("expected speaker b to take slot 0, got %d", slot)
+	}
+	if slot := s.observe(a, 40, now); slot != -1 {
+		t.Fatalf("expected speaker a to be muted, got slot %d", slot)
+	}
+
+	// speaker keeps the slot during pauses shorter than hold
+	now = now.Add(500 * time.Millisecond)
+	if slot := s.observe(b, 127, now); slot != 0 {
+		t.Fatalf("expected speaker b to keep slot during pause, got %d", slot)
+	}
+
+	// silent speaker loses the slot after hold
+	now = now.Add(time.Second)
+	if slot := s.observe(a, 40, now); slot != 0 {
+		t.Fatalf("expected speaker a to take slot after hold, got %d", slot)
+	}
+	if slot := s.observe(b, 127, now); slot != -1 {
+		t.Fatalf("expected speaker b to be muted, got slot %d", slot)
+	}
+
+	// removed speaker frees its slot
+	if slot := s.remove(a); slot != 0 {
+		t.Fatalf("expected removed speaker to free slot 0, got %d", slot)
+	}
+	if slot := s.observe(b, 40, now); slot != 0 {
+		t.Fatalf("expected speaker b to take free slot, got %d", slot)
+	}
+}
+
+func TestSpeakersReplace(t *testing.T) {
+	s := newSpeakers(2, 50, time.Second)
+
+	stopped := false
+	old := s.replace("a", func() { stopped = true })
+	s.observe(old, 10, time.Now())
+
+	mic := s.replace("a", func() {})
+	if !stopped {
+		t.Fatalf("expected previous microphone to be stopped")
+	}
+
+	// stale microphone is muted and keeps its slot only until it is removed
+	if slot := s.observe(old, 10, time.Now()); slot != -1 {
+		t.Fatalf("expected replaced microphone to be muted, got slot %d", slot)
+	}
+	if slot := s.observe(mic, 10, time.Now()); slot != 1 {
+		t.Fatalf("expected new microphone in slot 1, got %d", slot)
+	}
+
+	if slot := s.remove(old); slot != 0 {
+		t.Fatalf("expected removed microphone to free slot 0, got %d", slot)
+	}
+	if !s.ownedBy("a") {
+		t.Fatalf("expected session to still share microphone")
+	}
+}
@@ -0,0 +1,148 @@
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// trackCounters is a snapshot of track counters, bitrate is computed from
+// difference of two snapshots.
+type trackCounters struct {
+	at           time.Time
+	bytesSent    uint64
+	nacks        uint64
+	packetsLost  int64
+	fractionLost uint8
+	jitter       time.Duration
+	rtt          time.Duration
+}
+
+// onReport records loss and jitter from receiver report of the track.
+func (t *Track) onReport(report rtcp.ReceptionReport) {
+	// cumulative loss is a signed 24-bit number, negative with duplicates
+	lost := int64(report.TotalLost & 0xffffff)
+	if lost&0x800000 != 0 {
+		lost -= 0x1000000
+	}
+
+	t.packetsLost.Store(lost)
+	t.fractionLost.Store(uint32(report.FractionLost))
+	t.jitter.Store(report.Jitter)
+}
+
+func (t *Track) counters(now time.Time) trackCounters {
+	counters := trackCounters{
+		at:           now,
+		bytesSent:    t.bytesSent.Load(),
+		nacks:        t.nacks.Load(),
+		packetsLost:  t.packetsLost.Load(),
+		fractionLost: uint8(t.fractionLost.Load()),
+		rtt:          time.Duration(t.sync.rtt.Load()),
+	}
+
+	if t.clockRate > 0 {
+		counters.jitter = time.Duration(float64(t.jitter.Load()) / float64(t.clockRate) * float64(time.Second))
+	}
+
+	return counters
+}
+
+// trackStats computes statistics of the track since the previous snapshot.
+// Tracks can be replaced meanwhile, then their bitrate is unknown.
+func trackStats(prev, cur trackCounters) types.PeerTrackStats {
+	stats := types.PeerTrackStats{
+		PacketsLost:  cur.packetsLost,
+		FractionLost: float64(cur.fractionLost) * 100 / 256,
+		Jitter:       cur.jitter.Milliseconds(),
+		Nacks:        cur.nacks,
+		RTT:          cur.rtt.Milliseconds(),
+	}
+
+	elapsed := cur.at.Sub(prev.at)
+	if !prev.at.IsZero() && elapsed > 0 && cur.bytesSent >= prev.bytesSent {
+		stats.Bitrate = uint64(float64(cur.bytesSent-prev.bytesSent) * 8 / elapsed.Seconds())
+	}
+
+	return stats
+}
+
+// trackCounters returns snapshots of counters of audio and video tracks, nil
+// if the peer does not receive the track.
+func (peer *WebRTCPeerCtx) trackCounters(now time.Time) (audio, video *trackCounters) {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	if peer.audioTrack != nil {
+		counters := peer.audioTrack.counters(now)
+		audio = &counters
+	}
+
+	if peer.videoTrack != nil {
+		counters := peer.videoTrack.counters(now)
+		video = &counters
+	}
+
+	return
+}
+
+// candidatePair sets types of selected ICE candidate pair to the stats.
+func (peer *WebRTCPeerCtx) candidatePair(stats *types.PeerStats) {
+	sctp := peer.connection.SCTP()
+	if sctp == nil {
+		return
+	}
+
+	pair, err := sctp.Transport().ICETransport().GetSelectedCandidatePair()
+	if err != nil || pair == nil || pair.Local == nil || pair.Remote == nil {
+		return
+	}
+
+	stats.LocalCandidate = pair.Local.Typ.String()
+	stats.RemoteCandidate = pair.Remote.Typ.String()
+	stats.Protocol = pair.Local.Protocol.String()
+}
+
+// statsMonitor periodically sends connection statistics to client. RTP stats
+// are not provided by the peer connection, so they are gathered from tracks
+// and RTCP feedback of the receiver.
+func (manager *WebRTCManagerCtx) statsMonitor(peer *WebRTCPeerCtx) {
+	ticker := time.NewTicker(manager.config.StatsInterval)
+	defer ticker.Stop()
+
+	var prevAudio, prevVideo trackCounters
+
+	for {
+		select {
+		case <-peer.closed:
+			return
+		case <-ticker.C:
+		}
+
+		if peer.connection.ConnectionState() != webrtc.PeerConnectionStateConnected {
+			continue
+		}
+
+		stats := types.PeerStats{}
+		peer.candidatePair(&stats)
+
+		audio, video := peer.trackCounters(time.Now())
+		if audio != nil {
+			trackStats := trackStats(prevAudio, *audio)
+			stats.Audio, prevAudio = &trackStats, *audio
+		}
+		if video != nil {
+			trackStats := trackStats(prevVideo, *video)
+			stats.Video, prevVideo = &trackStats, *video
+		}
+
+		peer.session.Send(event.SIGNAL_STATS, message.SignalStats{
+			PeerStats: stats,
+		})
+	}
+}
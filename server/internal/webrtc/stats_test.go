@@ -0,0 +1,59 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+func TestTrackStats(t *testing.T) {
+	now := time.Now()
+	track := &Track{clockRate: 48000}
+
+	track.bytesSent.Store(1000)
+	prev := track.counters(now)
+
+	track.bytesSent.Store(251000)
+	track.nacks.Store(3)
+	track.onReport(rtcp.ReceptionReport{
+		TotalLost:    12,
+		FractionLost: 64,
+		Jitter:       960,
+	})
+
+	stats := trackStats(prev, track.counters(now.Add(2*time.Second)))
+	if stats.Bitrate != 1000000 {
+		t.Errorf("bitrate = %d, want 1000000", stats.Bitrate)
+	}
+	if stats.PacketsLost != 12 || stats.FractionLost != 25 {
+		t.Errorf("loss = %d (%v%%), want 12 (25%%)", stats.PacketsLost, stats.FractionLost)
+	}
+	if stats.Jitter != 20 {
+		t.Errorf("jitter = %dms, want 20ms", stats.Jitter)
+	}
+	if stats.Nacks != 3 {
+		t.Errorf("nacks = %d, want 3", stats.Nacks)
+	}
+
+	// without previous snapshot, bitrate is unknown
+	if stats := trackStats(trackCounters{}, prev); stats.Bitrate != 0 {
+		t.Errorf("bitrate without previous snapshot = %d, want 0", stats.Bitrate)
+	}
+
+	// replaced track starts counting from zero
+	replaced := (&Track{clockRate: 48000}).counters(now.Add(4 * time.Second))
+	if stats := trackStats(prev, replaced); stats.Bitrate != 0 {
+		t.Errorf("bitrate of replaced track = %d, want 0", stats.Bitrate)
+	}
+}
+
+func TestTrackStatsNegativeLoss(t *testing.T) {
+	track := &Track{}
+
+	// duplicated packets make cumulative loss negative
+	track.onReport(rtcp.ReceptionReport{TotalLost: 0xfffffe})
+	if lost := track.packetsLost.Load(); lost != -2 {
+		t.Errorf("packets lost = %d, want -2", lost)
+	}
+}
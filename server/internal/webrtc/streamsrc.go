@@ -0,0 +1,97 @@
+package webrtc
+
+import (
+	"errors"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/codec"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// microphoneSrc returns stream sources that shared microphone is routed to.
+func (manager *WebRTCManagerCtx) microphoneSrc(route types.MicrophoneRoute) types.StreamSrcManager {
+	return routedSrc(route, manager.capture.Microphone(), manager.capture.MicrophoneMix())
+}
+
+// routedSrc returns microphone and/or mix source, depending on the route.
+func routedSrc(route types.MicrophoneRoute, microphone, mix types.StreamSrcManager) types.StreamSrcManager {
+	switch route {
+	case types.MicrophoneRouteMix:
+		return mix
+	case types.MicrophoneRouteBoth:
+		return multiStreamSrc{microphone, mix}
+	default:
+		return microphone
+	}
+}
+
+// mediaUnavailable describes why shared media could not be received.
+func mediaUnavailable(kind webrtc.RTPCodecType, err error) message.SignalMediaUnavailable {
+	media := "microphone"
+	if kind == webrtc.RTPCodecTypeVideo {
+		media = "webcam"
+	}
+
+	reason := "pipeline_failed"
+	if errors.Is(err, types.ErrCaptureDeviceUnavailable) {
+		reason = "device_unavailable"
+	}
+
+	return message.SignalMediaUnavailable{
+		Media:  media,
+		Reason: reason,
+		Error:  err.Error(),
+	}
+}
+
+// multiStreamSrc pushes the same data to multiple stream sources.
+type multiStreamSrc []types.StreamSrcManager
+
+func (m multiStreamSrc) Enabled() bool {
+	for _, src := range m {
+		if !src.Enabled() {
+			return false
+		}
+	}
+	return true
+}
+
+func (m multiStreamSrc) Codec() codec.RTPCodec {
+	return m[0].Codec()
+}
+
+func (m multiStreamSrc) Start(codec codec.RTPCodec) error {
+	for i, src := range m {
+		if err := src.Start(codec); err != nil {
+			// stop already started sources
+			for _, started := range m[:i] {
+				started.Stop()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiStreamSrc) Stop() {
+	for _, src := range m {
+		src.Stop()
+	}
+}
+
+func (m multiStreamSrc) Push(bytes []byte) {
+	for _, src := range m {
+		src.Push(bytes)
+	}
+}
+
+func (m multiStreamSrc) Started() bool {
+	for _, src := range m {
+		if src.Started() {
+			return true
+		}
+	}
+	return false
+}
@@ -4,19 +4,36 @@ import (
 	"errors"
 	"io"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/pion/interceptor"
 	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v3"
 	"github.com/pion/webrtc/v3/pkg/media"
 	"github.com/rs/zerolog"
 
+	"github.com/m1k1o/neko/server/internal/config"
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/types/codec"
 )
 
+type localTrack interface {
+	webrtc.TrackLocal
+	WriteSample(media.Sample) error
+}
+
 type Track struct {
 	logger zerolog.Logger
-	track  *webrtc.TrackLocalStaticSample
+	track  localTrack
+	sender *webrtc.RTPSender
+	ssrc   uint32
+	// clock rate of RTP timestamps, used to convert jitter
+	clockRate uint32
+
+	// redundant audio encoding, optional
+	red       *redTrack
+	redConfig *config.WebRTCAudioRED
 
 	rtcpCh chan []rtcp.Packet
 	sample chan types.Sample
@@ -24,6 +41,26 @@ type Track struct {
 	paused   bool
 	stream   types.StreamSinkManager
 	streamMu sync.Mutex
+
+	// samples written and highest sequence number reported by the receiver
+	samplesSent atomic.Uint64
+	receivedSeq atomic.Uint32
+
+	// counters sent to clients as connection statistics
+	bytesSent    atomic.Uint64
+	nacks        atomic.Uint64
+	packetsLost  atomic.Int64
+	fractionLost atomic.Uint32
+	jitter       atomic.Uint32
+
+	// delays used to estimate audio/video sync
+	sync trackSync
+	// sender reports correcting timestamps by the delays, optional
+	senderReports *senderReports
+
+	// closed once the receiver reported packets of a written sample
+	flowing     chan struct{}
+	flowingOnce sync.Once
 }
 
 type trackOption func(*Track)
@@ -34,28 +71,64 @@ func WithRtcpChan(rtcp chan []rtcp.Packet) trackOption {
 	}
 }
 
+// WithRED sends audio using redundant encoding, when the receiver supports it.
+func WithRED(conf config.WebRTCAudioRED) trackOption {
+	return func(t *Track) {
+		t.redConfig = &conf
+	}
+}
+
+// WithSenderReports maps timestamps of the track to the time of capture in
+// sender reports.
+func WithSenderReports(reports *senderReports) trackOption {
+	return func(t *Track) {
+		t.senderReports = reports
+	}
+}
+
 func NewTrack(logger zerolog.Logger, codec codec.RTPCodec, connection *webrtc.PeerConnection, opts ...trackOption) (*Track, error) {
 	id := codec.Type.String()
-	track, err := webrtc.NewTrackLocalStaticSample(codec.Capability, id, "stream")
-	if err != nil {
-		return nil, err
-	}
 
 	t := &Track{
-		logger: logger.With().Str("id", id).Logger(),
-		track:  track,
-		rtcpCh: nil,
-		sample: make(chan types.Sample),
+		logger:    logger.With().Str("id", id).Logger(),
+		clockRate: codec.Capability.ClockRate,
+		rtcpCh:    nil,
+		sample:    make(chan types.Sample),
+
+		flowing: make(chan struct{}),
 	}
 
 	for _, opt := range opts {
 		opt(t)
 	}
 
+	if t.redConfig != nil {
+		t.red = newRedTrack(t.logger, codec.Capability, id, "stream", *t.redConfig)
+		t.track = t.red
+	} else {
+		track, err := webrtc.NewTrackLocalStaticSample(codec.Capability, id, "stream")
+		if err != nil {
+			return nil, err
+		}
+		t.track = track
+	}
+
 	sender, err := connection.AddTrack(t.track)
 	if err != nil {
 		return nil, err
 	}
+	t.sender = sender
+
+	if encodings := sender.GetParameters().Encodings; len(encodings) > 0 {
+		t.ssrc = uint32(encodings[0].SSRC)
+	}
+
+	// pion assigns random SSRC to the sender, it is logged so that external
+	// RTP consumers can be correlated with the track
+	t.logger = t.logger.With().Uint32("ssrc", t.ssrc).Logger()
+	t.logger.Info().Msg("track added")
+
+	t.senderReports.addTrack(t.ssrc, &t.sync)
 
 	go t.rtcpReader(sender)
 	go t.sampleReader()
@@ -63,11 +136,25 @@ func NewTrack(logger zerolog.Logger, codec codec.RTPCodec, connection *webrtc.Pe
 	return t, nil
 }
 
+// SSRC returns synchronization source of outbound RTP packets.
+func (t *Track) SSRC() uint32 {
+	return t.ssrc
+}
+
 func (t *Track) Shutdown() {
+	t.senderReports.removeTrack(t.ssrc)
 	t.RemoveStream()
 	close(t.sample)
 }
 
+// Remove stops sending the track over the connection and shuts it down,
+// connection needs to be renegotiated afterwards.
+func (t *Track) Remove(connection *webrtc.PeerConnection) error {
+	err := connection.RemoveTrack(t.sender)
+	t.Shutdown()
+	return err
+}
+
 func (t *Track) rtcpReader(sender *webrtc.RTPSender) {
 	for {
 		packets, _, err := sender.ReadRTCP()
@@ -81,12 +168,67 @@ func (t *Track) rtcpReader(sender *webrtc.RTPSender) {
 			continue
 		}
 
+		t.onRTCP(packets)
+
 		if t.rtcpCh != nil {
 			t.rtcpCh <- packets
 		}
 	}
 }
 
+func (t *Track) onRTCP(packets []rtcp.Packet) {
+	for _, p := range packets {
+		switch p := p.(type) {
+		case *rtcp.ReceiverReport:
+			t.onReceptionReports(p.Reports)
+		// clients sending media themselves report reception in sender reports
+		case *rtcp.SenderReport:
+			t.onReceptionReports(p.Reports)
+		case *rtcp.TransportLayerNack:
+			if p.MediaSSRC == t.ssrc {
+				for _, nack := range p.Nacks {
+					t.nacks.Add(uint64(len(nack.PacketList())))
+				}
+			}
+		}
+	}
+}
+
+func (t *Track) onReceptionReports(reports []rtcp.ReceptionReport) {
+	for _, report := range reports {
+		if report.SSRC != t.ssrc {
+			continue
+		}
+
+		t.receivedSeq.Store(report.LastSequenceNumber)
+		t.markFlowing()
+		t.sync.onReport(report, time.Now())
+		t.onReport(report)
+
+		if t.red != nil {
+			t.red.onReport(report)
+		}
+	}
+}
+
+// markFlowing is called when receiver reported packets, reception reports in
+// receiver or sender reports only include sources it has received packets from.
+func (t *Track) markFlowing() {
+	// samples are written starting with a keyframe
+	if t.samplesSent.Load() == 0 {
+		return
+	}
+
+	t.flowingOnce.Do(func() {
+		close(t.flowing)
+	})
+}
+
+// Flowing returns channel closed once the receiver got media of this track.
+func (t *Track) Flowing() <-chan struct{} {
+	return t.flowing
+}
+
 // --- sample  ---
 
 func (t *Track) sampleReader() {
@@ -103,7 +245,11 @@ func (t *Track) sampleReader() {
 			Timestamp: sample.Timestamp,
 		})
 
-		if err != nil && !errors.Is(err, io.ErrClosedPipe) {
+		if err == nil {
+			t.samplesSent.Add(1)
+			t.bytesSent.Add(uint64(len(sample.Data)))
+			t.sync.sent(sample, time.Now())
+		} else if !errors.Is(err, io.ErrClosedPipe) {
 			t.logger.Warn().Err(err).Msg("failed to write sample to track")
 		}
 	}
@@ -162,6 +308,23 @@ func (t *Track) RemoveStream() {
 	t.stream = nil
 }
 
+// Resync adds the track to its stream again, so that it waits for the next
+// keyframe. The keyframe is requested, unless someone else is waiting already.
+func (t *Track) Resync() error {
+	t.streamMu.Lock()
+	defer t.streamMu.Unlock()
+
+	if t.stream == nil || t.paused {
+		return nil
+	}
+
+	if err := t.stream.RemoveListener(t); err != nil {
+		return err
+	}
+
+	return t.stream.AddListener(t)
+}
+
 func (t *Track) Stream() (types.StreamSinkManager, bool) {
 	t.streamMu.Lock()
 	defer t.streamMu.Unlock()
@@ -201,3 +364,24 @@ func (t *Track) Paused() bool {
 
 	return t.paused
 }
+
+// --- remote track ---
+
+type remoteTrackReader interface {
+	Read(b []byte) (int, interceptor.Attributes, error)
+}
+
+// readRemoteTrack reads packets from remote track and pushes them until reading
+// fails. Buffer is allocated with the receive MTU size, so that packets up to
+// this size are not truncated.
+func readRemoteTrack(track remoteTrackReader, mtu uint, push func([]byte)) error {
+	buf := make([]byte, mtu)
+	for {
+		i, _, err := track.Read(buf)
+		if err != nil {
+			return err
+		}
+
+		push(buf[:i])
+	}
+}
@@ -0,0 +1,181 @@
+package webrtc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types/codec"
+)
+
+type testRemoteTrack struct {
+	packets [][]byte
+}
+
+func (t *testRemoteTrack) Read(b []byte) (int, interceptor.Attributes, error) {
+	if len(t.packets) == 0 {
+		return 0, nil, io.EOF
+	}
+
+	packet := t.packets[0]
+	t.packets = t.packets[1:]
+
+	if len(b) < len(packet) {
+		return 0, nil, io.ErrShortBuffer
+	}
+
+	return copy(b, packet), nil, nil
+}
+
+// Ensure that packets up to the receive MTU are not truncated
+func TestReadRemoteTrack(t *testing.T) {
+	packets := [][]byte{
+		bytes.Repeat([]byte{1}, 1400),
+		bytes.Repeat([]byte{2}, 4000),
+		bytes.Repeat([]byte{3}, 9000),
+	}
+
+	track := &testRemoteTrack{packets: append([][]byte{}, packets...)}
+
+	var received [][]byte
+	err := readRemoteTrack(track, 9000, func(b []byte) {
+		received = append(received, append([]byte{}, b...))
+	})
+
+	if err != io.EOF {
+		t.Errorf("readRemoteTrack() returned unexpected error: %v", err)
+	}
+
+	if len(received) != len(packets) {
+		t.Fatalf("received %d packets, expected %d", len(received), len(packets))
+	}
+
+	for i, packet := range packets {
+		if !bytes.Equal(received[i], packet) {
+			t.Errorf("packet %d: received %d bytes, expected %d", i, len(received[i]), len(packet))
+		}
+	}
+}
+
+// Ensure that packets bigger than the receive MTU are not silently truncated
+func TestReadRemoteTrackShortBuffer(t *testing.T) {
+	track := &testRemoteTrack{packets: [][]byte{
+		bytes.Repeat([]byte{1}, 1500),
+	}}
+
+	err := readRemoteTrack(track, 1400, func(b []byte) {
+		t.Errorf("unexpected packet with %d bytes", len(b))
+	})
+
+	if err != io.ErrShortBuffer {
+		t.Errorf("readRemoteTrack() returned unexpected error: %v", err)
+	}
+}
+
+func TestTrackFlowing(t *testing.T) {
+	track := &Track{
+		logger:  zerolog.Nop(),
+		flowing: make(chan struct{}),
+	}
+
+	// receiver reports before any sample was written do not count
+	track.markFlowing()
+	select {
+	case <-track.Flowing():
+		t.Fatal("track is flowing before any sample was sent")
+	default:
+	}
+
+	track.samplesSent.Add(1)
+	track.markFlowing()
+	track.markFlowing()
+
+	select {
+	case <-track.Flowing():
+	default:
+		t.Fatal("track is not flowing after receiver report")
+	}
+}
+
+// Client sending its own media reports reception only in sender reports, it
+// must count as playing and feed audio redundancy and A/V sync as well
+func TestTrackSenderReport(t *testing.T) {
+	track := &Track{
+		logger:  zerolog.Nop(),
+		ssrc:    1,
+		red:     newRedTrack(zerolog.Nop(), codec.Opus().Capability, "audio", "stream", config.WebRTCAudioRED{Distance: 1, LossThreshold: 5}),
+		flowing: make(chan struct{}),
+	}
+	track.samplesSent.Add(1)
+
+	now := ntpMiddle(time.Now())
+	track.onRTCP([]rtcp.Packet{&rtcp.SenderReport{
+		SSRC: 2,
+		Reports: []rtcp.ReceptionReport{
+			// other sources are ignored
+			{SSRC: 3, FractionLost: 255},
+			{SSRC: 1, FractionLost: 64, LastSequenceNumber: 10, LastSenderReport: now - 1<<14},
+		},
+	}})
+
+	select {
+	case <-track.Flowing():
+	default:
+		t.Error("track is not flowing after sender report")
+	}
+
+	if seq := track.receivedSeq.Load(); seq != 10 {
+		t.Errorf("received sequence = %d, want 10", seq)
+	}
+	if !track.red.active.Load() {
+		t.Error("audio redundancy is not active after reported loss")
+	}
+	if rtt := time.Duration(track.sync.rtt.Load()); rtt <= 0 {
+		t.Errorf("rtt = %v, want positive", rtt)
+	}
+}
+
+// Logged SSRC must be the one pion assigned to the sender
+func TestTrackSSRC(t *testing.T) {
+	connection, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connection.Close()
+
+	var logs bytes.Buffer
+	track, err := NewTrack(zerolog.New(&logs), codec.VP8(), connection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer track.Shutdown()
+
+	encodings := track.sender.GetParameters().Encodings
+	if len(encodings) == 0 {
+		t.Fatal("sender has no encodings")
+	}
+
+	want := uint32(encodings[0].SSRC)
+	if track.SSRC() != want {
+		t.Errorf("SSRC() = %d, want %d", track.SSRC(), want)
+	}
+
+	var entry struct {
+		SSRC uint32 `json:"ssrc"`
+	}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("could not parse log %q: %v", logs.String(), err)
+	}
+
+	if entry.SSRC != want {
+		t.Errorf("logged ssrc = %d, want %d", entry.SSRC, want)
+	}
+}
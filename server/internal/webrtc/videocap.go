@@ -0,0 +1,115 @@
+package webrtc
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// videoPreferences remembers video manually selected by sessions, so that it
+// is used as the initial video when they reconnect.
+type videoPreferences struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	sessions map[string]videoPreference
+}
+
+type videoPreference struct {
+	videoId string
+	until   time.Time
+}
+
+func newVideoPreferences(ttl time.Duration) *videoPreferences {
+	return &videoPreferences{
+		ttl:      ttl,
+		sessions: map[string]videoPreference{},
+	}
+}
+
+func (p *videoPreferences) remember(sessionId, videoId string) {
+	if p == nil || p.ttl <= 0 || videoId == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for id, pref := range p.sessions {
+		if now.After(pref.until) {
+			delete(p.sessions, id)
+		}
+	}
+
+	p.sessions[sessionId] = videoPreference{
+		videoId: videoId,
+		until:   now.Add(p.ttl),
+	}
+}
+
+// get returns video manually selected by the session, if not expired.
+func (p *videoPreferences) get(sessionId string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pref, ok := p.sessions[sessionId]
+	if !ok || time.Now().After(pref.until) {
+		return ""
+	}
+
+	return pref.videoId
+}
+
+// aboveVideoCap returns true, if video is of higher quality than the video
+// manually selected by the client. Must be called with mutex held.
+func (peer *WebRTCPeerCtx) aboveVideoCap(videoId string) bool {
+	if peer.videoCap == "" {
+		return false
+	}
+
+	// stream IDs are ordered from the highest to the lowest
+	ids := peer.video.IDs()
+	capIdx := slices.Index(ids, peer.videoCap)
+	if capIdx < 0 {
+		return false
+	}
+
+	return slices.Index(ids, videoId) < capIdx
+}
+
+// capSelector replaces the first requested video with the video manually
+// selected by the client, if it is of higher quality. Must be called with
+// mutex held.
+func (peer *WebRTCPeerCtx) capSelector(r *types.PeerVideoRequest) {
+	target, ok := peer.video.GetStream(*r.Selector)
+	if !ok || !peer.aboveVideoCap(target.ID()) {
+		return
+	}
+
+	peer.logger.Info().
+		Str("video_id", target.ID()).
+		Str("cap_id", peer.videoCap).
+		Msg("using manually selected video")
+
+	r.Selector = &types.StreamSelector{
+		ID:   peer.videoCap,
+		Type: types.StreamSelectorTypeExact,
+	}
+}
+
+// setVideoCap sets video manually selected by the client, automatic selection
+// does not exceed it. It returns true, if the cap changed. Must be called with
+// mutex held.
+func (peer *WebRTCPeerCtx) setVideoCap(videoId string) bool {
+	peer.videoPrefs.remember(peer.session.ID(), videoId)
+
+	if peer.videoCap == videoId {
+		return false
+	}
+
+	peer.videoCap = videoId
+	peer.logger.Info().Str("video_id", videoId).Msg("set video cap")
+	return true
+}
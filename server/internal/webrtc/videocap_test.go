@@ -0,0 +1,78 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/codec"
+)
+
+type videoIDs []string
+
+func (ids videoIDs) IDs() []string         { return ids }
+func (ids videoIDs) Codec() codec.RTPCodec { return codec.VP8() }
+func (ids videoIDs) GetStream(selector types.StreamSelector) (types.StreamSinkManager, bool) {
+	return nil, false
+}
+
+func TestVideoPreferences(t *testing.T) {
+	p := newVideoPreferences(time.Minute)
+
+	p.remember("a", "lq")
+	p.remember("a", "sd")
+	p.remember("b", "")
+
+	if got := p.get("a"); got != "sd" {
+		t.Errorf("get(a) = %q, want sd", got)
+	}
+
+	// kept for further reconnects
+	if got := p.get("a"); got != "sd" {
+		t.Errorf("second get(a) = %q, want sd", got)
+	}
+
+	if got := p.get("b"); got != "" {
+		t.Errorf("get(b) = %q, want none", got)
+	}
+}
+
+func TestVideoPreferencesExpired(t *testing.T) {
+	p := newVideoPreferences(10 * time.Millisecond)
+	p.remember("a", "sd")
+
+	time.Sleep(20 * time.Millisecond)
+
+	if got := p.get("a"); got != "" {
+		t.Errorf("get(a) = %q after ttl, want none", got)
+	}
+}
+
+func TestAboveVideoCap(t *testing.T) {
+	peer := &WebRTCPeerCtx{
+		logger: zerolog.Nop(),
+		video:  videoIDs{"hd", "sd", "lq"},
+	}
+
+	// without cap, any video is allowed
+	if peer.aboveVideoCap("hd") {
+		t.Errorf("aboveVideoCap(hd) without cap = true, want false")
+	}
+
+	peer.videoCap = "sd"
+
+	tests := map[string]bool{"hd": true, "sd": false, "lq": false}
+	for videoId, want := range tests {
+		if got := peer.aboveVideoCap(videoId); got != want {
+			t.Errorf("aboveVideoCap(%s) = %v, want %v", videoId, got, want)
+		}
+	}
+
+	// unknown cap is ignored
+	peer.videoCap = "unknown"
+	if peer.aboveVideoCap("hd") {
+		t.Errorf("aboveVideoCap(hd) with unknown cap = true, want false")
+	}
+}
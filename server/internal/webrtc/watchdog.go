@@ -0,0 +1,83 @@
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// mediaStall counts consecutive checks in which video was sent, but the
+// receiver did not report any new packets.
+type mediaStall struct {
+	sent     uint64
+	received uint32
+	strikes  int
+}
+
+func (s *mediaStall) check(sent uint64, received uint32) int {
+	stalled := sent != s.sent && received == s.received
+	s.sent, s.received = sent, received
+
+	if stalled {
+		s.strikes++
+	} else {
+		s.strikes = 0
+	}
+
+	return s.strikes
+}
+
+func (s *mediaStall) reset(sent uint64, received uint32) {
+	s.sent, s.received = sent, received
+	s.strikes = 0
+}
+
+// mediaWatchdog recovers connected peers, that do not receive any video. First
+// a keyframe is requested and if that does not help, connection is renegotiated
+// with ICE restart.
+func (manager *WebRTCManagerCtx) mediaWatchdog(peer *WebRTCPeerCtx) {
+	ticker := time.NewTicker(manager.config.WatchdogInterval)
+	defer ticker.Stop()
+
+	track := peer.videoTrack
+	stall := mediaStall{}
+
+	for range ticker.C {
+		sent, received := track.samplesSent.Load(), track.receivedSeq.Load()
+
+		switch peer.connection.ConnectionState() {
+		case webrtc.PeerConnectionStateClosed:
+			return
+		case webrtc.PeerConnectionStateConnected:
+		default:
+			stall.reset(sent, received)
+			continue
+		}
+
+		if track.Paused() {
+			stall.reset(sent, received)
+			continue
+		}
+
+		switch stall.check(sent, received) {
+		case 1:
+			peer.logger.Warn().Msg("peer is connected but receives no video, requesting keyframe")
+			manager.watchdogRecoveries.WithLabelValues("keyframe").Inc()
+
+			if err := track.Resync(); err != nil {
+				peer.logger.Err(err).Msg("failed to request keyframe")
+			}
+		case 2:
+			peer.logger.Warn().Msg("peer still receives no video, restarting connection")
+			manager.watchdogRecoveries.WithLabelValues("restart").Inc()
+
+			if err := peer.RestartICE(); err != nil {
+				peer.logger.Err(err).Msg("failed to create restart offer")
+				manager.errors.Report("webrtc", "watchdog_restart", peer.session, err)
+			}
+
+			// start over, if the restart does not help either
+			stall.reset(sent, received)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtcp"
+	"github.com/rs/zerolog"
+)
+
+func TestMediaStall(t *testing.T) {
+	s := mediaStall{}
+
+	steps := []struct {
+		sent     uint64
+		received uint32
+		strikes  int
+	}{
+		{10, 100, 0}, // receiving
+		{20, 100, 1}, // sent, nothing received
+		{30, 100, 2}, // still nothing received
+		{30, 100, 0}, // nothing sent, nothing expected
+		{40, 100, 1},
+		{50, 150, 0}, // recovered
+	}
+
+	for i, step := range steps {
+		if strikes := s.check(step.sent, step.received); strikes != step.strikes {
+			t.Errorf("step %d: strikes = %d, want %d", i, strikes, step.strikes)
+		}
+	}
+}
+
+// Client sending its own media reports reception only in sender reports
+func TestMediaStallSenderReportOnly(t *testing.T) {
+	track := &Track{
+		logger:  zerolog.Nop(),
+		ssrc:    1,
+		flowing: make(chan struct{}),
+	}
+
+	s := mediaStall{}
+	track.samplesSent.Store(10)
+	s.check(track.samplesSent.Load(), track.receivedSeq.Load())
+
+	for i := uint32(1); i <= 3; i++ {
+		track.samplesSent.Add(10)
+		track.onRTCP([]rtcp.Packet{&rtcp.SenderReport{
+			SSRC: 2,
+			Reports: []rtcp.ReceptionReport{
+				{SSRC: 1, LastSequenceNumber: 100 * i},
+			},
+		}})
+
+		if strikes := s.check(track.samplesSent.Load(), track.receivedSeq.Load()); strikes != 0 {
+			t.Fatalf("report %d: strikes = %d, want 0", i, strikes)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/m1k1o/neko/server/internal/webrtc/payload"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// binaryInputProtocol is websocket subprotocol requested by clients that send
+// hot input events in binary frames, JSON text frames are accepted as well.
+//
+// Binary frames use the same big endian layout as the data channel, a header
+// followed by the body:
+//
+//	header      event uint8, length uint16 (of the body)
+//	move        x uint16, y uint16
+//	scroll      delta_x int16, delta_y int16, control_key bool
+//	key up/down keysym uint32
+//	btn up/down code uint32
+const binaryInputProtocol = "neko-binary-input"
+
+var ErrBinaryInputEvent = errors.New("unsupported binary input event")
+
+// decodeBinaryInput converts binary frame to the event and payload it stands
+// for. Payload is passed to the handler as it is, without JSON round trip.
+func decodeBinaryInput(raw []byte) (string, any, error) {
+	buffer := bytes.NewBuffer(raw)
+
+	header := payload.Header{}
+	if err := binary.Read(buffer, binary.BigEndian, &header); err != nil {
+		return "", nil, err
+	}
+
+	if int(header.Length) != buffer.Len() {
+		return "", nil, fmt.Errorf("body length %d does not match header length %d", buffer.Len(), header.Length)
+	}
+
+	var name string
+	var body any
+
+	switch header.Event {
+	case payload.OP_MOVE:
+		move := payload.Move{}
+		if err := binary.Read(buffer, binary.BigEndian, &move); err != nil {
+			return "", nil, err
+		}
+
+		name = event.CONTROL_MOVE
+		body = &message.ControlPos{X: int(move.X), Y: int(move.Y)}
+	case payload.OP_SCROLL:
+		scroll := payload.Scroll{}
+		if err := binary.Read(buffer, binary.BigEndian, &scroll); err != nil {
+			return "", nil, err
+		}
+
+		name = event.CONTROL_SCROLL
+		body = &message.ControlScroll{
+			DeltaX:     int(scroll.DeltaX),
+			DeltaY:     int(scroll.DeltaY),
+			ControlKey: scroll.ControlKey,
+		}
+	case payload.OP_KEY_DOWN, payload.OP_KEY_UP:
+		key := payload.Key{}
+		if err := binary.Read(buffer, binary.BigEndian, &key); err != nil {
+			return "", nil, err
+		}
+
+		name = event.CONTROL_KEYDOWN
+		if header.Event == payload.OP_KEY_UP {
+			name = event.CONTROL_KEYUP
+		}
+		body = &message.ControlKey{Keysym: key.Key}
+	case payload.OP_BTN_DOWN, payload.OP_BTN_UP:
+		button := payload.Key{}
+		if err := binary.Read(buffer, binary.BigEndian, &button); err != nil {
+			return "", nil, err
+		}
+
+		name = event.CONTROL_BUTTONDOWN
+		if header.Event == payload.OP_BTN_UP {
+			name = event.CONTROL_BUTTONUP
+		}
+		body = &message.ControlButton{Code: button.Key}
+	default:
+		return "", nil, fmt.Errorf("%w: %d", ErrBinaryInputEvent, header.Event)
+	}
+
+	return name, body, nil
+}
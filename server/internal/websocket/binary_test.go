@@ -0,0 +1,109 @@
+package websocket
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/internal/session"
+	"github.com/m1k1o/neko/server/internal/websocket/handler"
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+func TestDecodeBinaryInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     []byte
+		event   string
+		payload any
+	}{
+		{"move", []byte{0x01, 0, 4, 0x01, 0x00, 0x00, 0x80}, event.CONTROL_MOVE, &message.ControlPos{X: 256, Y: 128}},
+		{"scroll", []byte{0x02, 0, 5, 0xff, 0xfe, 0x00, 0x03, 0x01}, event.CONTROL_SCROLL, &message.ControlScroll{DeltaX: -2, DeltaY: 3, ControlKey: true}},
+		{"key down", []byte{0x03, 0, 4, 0x00, 0x00, 0xff, 0x0d}, event.CONTROL_KEYDOWN, &message.ControlKey{Keysym: 0xff0d}},
+		{"key up", []byte{0x04, 0, 4, 0x00, 0x00, 0x00, 0x61}, event.CONTROL_KEYUP, &message.ControlKey{Keysym: 0x61}},
+		{"button down", []byte{0x05, 0, 4, 0x00, 0x00, 0x00, 0x01}, event.CONTROL_BUTTONDOWN, &message.ControlButton{Code: 1}},
+		{"button up", []byte{0x06, 0, 4, 0x00, 0x00, 0x00, 0x03}, event.CONTROL_BUTTONUP, &message.ControlButton{Code: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, payload, err := decodeBinaryInput(tt.raw)
+			if err != nil {
+				t.Fatalf("decodeBinaryInput() error = %v", err)
+			}
+
+			if name != tt.event {
+				t.Errorf("event = %q, want %q", name, tt.event)
+			}
+			if !reflect.DeepEqual(payload, tt.payload) {
+				t.Errorf("payload = %+v, want %+v", payload, tt.payload)
+			}
+		})
+	}
+}
+
+func TestDecodeBinaryInputInvalid(t *testing.T) {
+	if _, _, err := decodeBinaryInput([]byte{0x01, 0}); err == nil {
+		t.Error("expected error for truncated header")
+	}
+
+	if _, _, err := decodeBinaryInput([]byte{0x01, 0, 4, 0x00, 0x01}); err == nil {
+		t.Error("expected error for length mismatch")
+	}
+
+	if _, _, err := decodeBinaryInput([]byte{0x01, 0, 2, 0x00, 0x01}); err == nil {
+		t.Error("expected error for truncated body")
+	}
+
+	// ping is handled only over the data channel
+	_, _, err := decodeBinaryInput([]byte{0x07, 0, 8, 0, 0, 0, 0, 0, 0, 0, 0})
+	if !errors.Is(err, ErrBinaryInputEvent) {
+		t.Errorf("error = %v, want %v", err, ErrBinaryInputEvent)
+	}
+}
+
+type binaryTestDesktop struct {
+	types.DesktopManager
+	keys []uint32
+}
+
+func (d *binaryTestDesktop) KeyDown(code uint32) error {
+	d.keys = append(d.keys, code)
+	return nil
+}
+
+func TestDispatchBinaryInput(t *testing.T) {
+	sessions := session.New(&config.Session{})
+	s, _, err := sessions.Create("test", types.MemberProfile{CanLogin: true, CanHost: true})
+	if err != nil {
+		t.Fatalf("could not create session %s", err.Error())
+	}
+	s.SetAsHost()
+
+	desktop := &binaryTestDesktop{}
+	manager := &WebSocketManagerCtx{
+		config:   &config.WebSocket{},
+		handler:  handler.New(sessions, desktop, nil, nil),
+		handlers: newHandlerRegistry(0),
+	}
+
+	name, input, err := decodeBinaryInput([]byte{0x03, 0, 4, 0x00, 0x00, 0xff, 0x0d})
+	if err != nil {
+		t.Fatalf("decodeBinaryInput() error = %v", err)
+	}
+
+	// message has no JSON payload, handler must use the decoded one
+	data := queuedMessage{WebSocketMessage: types.WebSocketMessage{Event: name}, input: input}
+	if handled, _ := manager.dispatch(zerolog.Nop(), s, data); !handled {
+		t.Fatal("binary input was not handled")
+	}
+
+	if !reflect.DeepEqual(desktop.keys, []uint32{0xff0d}) {
+		t.Errorf("desktop received keys %v, want [65293]", desktop.keys)
+	}
+}
@@ -0,0 +1,142 @@
+package websocket
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/internal/session"
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+type clipboardTestDesktop struct {
+	types.DesktopManager
+	text  *types.ClipboardText
+	image *types.ClipboardImage
+}
+
+func (d *clipboardTestDesktop) ClipboardGetText() (*types.ClipboardText, error) {
+	if d.text == nil {
+		return nil, errors.New("no text target")
+	}
+	return d.text, nil
+}
+
+func (d *clipboardTestDesktop) ClipboardGetImage() (*types.ClipboardImage, error) {
+	return d.image, nil
+}
+
+type clipboardTestErrors struct {
+	types.ErrorBus
+	reported int
+}
+
+func (e *clipboardTestErrors) Report(subsystem, kind string, session types.Session, err error) {
+	e.reported++
+}
+
+type clipboardTestPeer struct {
+	data []message.ClipboardData
+}
+
+func (p *clipboardTestPeer) Send(ev string, payload any) {
+	if data, ok := payload.(message.ClipboardData); ok && ev == event.CLIPBOARD_UPDATED {
+		p.data = append(p.data, data)
+	}
+}
+
+func (p *clipboardTestPeer) Ping() error           { return nil }
+func (p *clipboardTestPeer) Destroy(reason string) {}
+
+func newClipboardTest(t *testing.T, desktop *clipboardTestDesktop, maxSize int) (*WebSocketManagerCtx, *clipboardTestPeer, *clipboardTestErrors) {
+	t.Helper()
+
+	sessions := session.New(&config.Session{})
+	host, _, err := sessions.Create("host", types.MemberProfile{
+		CanLogin:           true,
+		CanConnect:         true,
+		CanHost:            true,
+		CanAccessClipboard: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peer := &clipboardTestPeer{}
+	host.ConnectWebSocketPeer(peer)
+	host.SetAsHost()
+
+	errs := &clipboardTestErrors{}
+	manager := &WebSocketManagerCtx{
+		logger:   zerolog.Nop(),
+		config:   &config.WebSocket{ClipboardImageMaxSize: maxSize},
+		sessions: sessions,
+		desktop:  desktop,
+		errors:   errs,
+	}
+
+	return manager, peer, errs
+}
+
+func TestSyncClipboardTextWithImage(t *testing.T) {
+	manager, peer, _ := newClipboardTest(t, &clipboardTestDesktop{
+		text:  &types.ClipboardText{Text: "screenshot.png"},
+		image: &types.ClipboardImage{Mime: "image/png", Data: []byte("png")},
+	}, 1024)
+
+	manager.syncClipboard()
+
+	if len(peer.data) != 1 {
+		t.Fatalf("host received %d clipboard updates, want 1", len(peer.data))
+	}
+	data := peer.data[0]
+	if data.Text != "screenshot.png" || data.Image == nil || string(data.Image.Data) != "png" {
+		t.Errorf("host received %+v, want both text and image", data)
+	}
+}
+
+func TestSyncClipboardImageOnly(t *testing.T) {
+	manager, peer, errs := newClipboardTest(t, &clipboardTestDesktop{
+		image: &types.ClipboardImage{Mime: "image/png", Data: []byte("png")},
+	}, 1024)
+
+	manager.syncClipboard()
+
+	if len(peer.data) != 1 || peer.data[0].Image == nil || peer.data[0].Text != "" {
+		t.Fatalf("host received %+v, want image without text", peer.data)
+	}
+	if errs.reported != 0 {
+		t.Errorf("missing text was reported %d times", errs.reported)
+	}
+}
+
+func TestSyncClipboardImageTooLarge(t *testing.T) {
+	manager, peer, _ := newClipboardTest(t, &clipboardTestDesktop{
+		text:  &types.ClipboardText{Text: "text"},
+		image: &types.ClipboardImage{Mime: "image/png", Data: make([]byte, 2048)},
+	}, 1024)
+
+	manager.syncClipboard()
+
+	// text is still synced
+	if len(peer.data) != 1 || peer.data[0].Image != nil || peer.data[0].Text != "text" {
+		t.Fatalf("host received %+v, want text without image", peer.data)
+	}
+}
+
+func TestSyncClipboardEmpty(t *testing.T) {
+	manager, peer, errs := newClipboardTest(t, &clipboardTestDesktop{}, 1024)
+
+	manager.syncClipboard()
+
+	if len(peer.data) != 0 {
+		t.Errorf("host received %+v, want nothing", peer.data)
+	}
+	if errs.reported != 1 {
+		t.Errorf("error reported %d times, want once", errs.reported)
+	}
+}
@@ -0,0 +1,59 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// how long to wait for replaced connection to be closed
+const replaceTimeout = 5 * time.Second
+
+type activeConnection struct {
+	peer *WebSocketPeerCtx
+	done chan struct{}
+}
+
+// addConnection registers active connection of the session, returned function
+// must be called when the connection is fully closed.
+func (manager *WebSocketManagerCtx) addConnection(sessionId string, peer *WebSocketPeerCtx) func() {
+	conn := &activeConnection{
+		peer: peer,
+		done: make(chan struct{}),
+	}
+
+	manager.connectionsMu.Lock()
+	manager.connections[sessionId] = conn
+	manager.connectionsMu.Unlock()
+
+	return func() {
+		manager.connectionsMu.Lock()
+		if manager.connections[sessionId] == conn {
+			delete(manager.connections, sessionId)
+		}
+		manager.connectionsMu.Unlock()
+
+		close(conn.done)
+	}
+}
+
+// closeConnection destroys active connection of the session and waits until
+// its handlers finish, so that it does not interfere with a new connection.
+func (manager *WebSocketManagerCtx) closeConnection(sessionId string, logger zerolog.Logger) {
+	manager.connectionsMu.Lock()
+	conn, ok := manager.connections[sessionId]
+	manager.connectionsMu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	conn.peer.Destroy("connection replaced")
+
+	select {
+	case <-conn.done:
+		logger.Debug().Msg("replaced connection closed")
+	case <-time.After(replaceTimeout):
+		logger.Warn().Msg("replaced connection did not close in time")
+	}
+}
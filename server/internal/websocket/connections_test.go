@@ -0,0 +1,100 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// Ensure that replaced connection is destroyed and its handler finished
+// before the new connection of the same session proceeds
+func TestCloseConnectionReplacesLivePeer(t *testing.T) {
+	manager := &WebSocketManagerCtx{
+		config:      &config.WebSocket{},
+		connections: map[string]*activeConnection{},
+	}
+
+	var handlersDone atomic.Int32
+	peers := make(chan *WebSocketPeerCtx, 2)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connection, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		peer := newPeer(zerolog.Nop(), manager.config, connection, "session", nil)
+		closed := manager.addConnection("session", peer)
+		defer closed()
+		peers <- peer
+
+		for {
+			if _, _, err := connection.ReadMessage(); err != nil {
+				break
+			}
+		}
+
+		// teardown of the handler takes a while
+		time.Sleep(20 * time.Millisecond)
+		handlersDone.Add(1)
+	}))
+	defer server.Close()
+
+	dial := func() *websocket.Conn {
+		client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		if err != nil {
+			t.Fatalf("Dial() = %v", err)
+		}
+		return client
+	}
+
+	first := dial()
+	defer first.Close()
+	<-peers
+
+	// rapid reconnect replaces the live connection
+	manager.closeConnection("session", zerolog.Nop())
+	if n := handlersDone.Load(); n != 1 {
+		t.Fatalf("replaced connection handler finished %d times before new connection, want once", n)
+	}
+
+	// client of the replaced connection is told why
+	var msg types.WebSocketMessage
+	if err := first.ReadJSON(&msg); err != nil {
+		t.Fatalf("ReadJSON() = %v", err)
+	}
+	var disconnect message.SystemDisconnect
+	if err := json.Unmarshal(msg.Payload, &disconnect); err != nil || msg.Event != event.SYSTEM_DISCONNECT || disconnect.Message != "connection replaced" {
+		t.Errorf("replaced client received %s %s, want disconnect", msg.Event, msg.Payload)
+	}
+
+	second := dial()
+	defer second.Close()
+	peer := <-peers
+
+	manager.connectionsMu.Lock()
+	conn := manager.connections["session"]
+	manager.connectionsMu.Unlock()
+	if conn == nil || conn.peer != peer {
+		t.Error("new connection is not registered as active")
+	}
+
+	// nothing to replace once the connection is gone
+	_ = second.Close()
+	manager.closeConnection("session", zerolog.Nop())
+	if n := handlersDone.Load(); n != 2 {
+		t.Errorf("handlers finished %d times, want twice", n)
+	}
+}
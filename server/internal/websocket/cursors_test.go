@@ -0,0 +1,93 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/internal/session"
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+)
+
+type cursorsTestPeer struct {
+	received chan string
+}
+
+func (p *cursorsTestPeer) Send(event string, payload any) {
+	select {
+	case p.received <- event:
+	default:
+	}
+}
+
+func (p *cursorsTestPeer) Ping() error           { return nil }
+func (p *cursorsTestPeer) Destroy(reason string) {}
+
+func TestFlushCursorsOnDisconnect(t *testing.T) {
+	sessions := session.New(&config.Session{
+		InactiveCursors:        true,
+		InactiveCursorsCleanup: true,
+	})
+
+	manager := &WebSocketManagerCtx{
+		logger:               zerolog.Nop(),
+		sessions:             sessions,
+		flushInactiveCursors: make(chan struct{}, 1),
+		inactiveCursorsDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "test_inactive_cursors_duration_seconds",
+		}),
+		inactiveCursorsOverruns: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "test_inactive_cursors_overruns_total",
+		}),
+	}
+
+	observer, _, err := sessions.Create("observer", types.MemberProfile{
+		CanLogin:              true,
+		CanConnect:            true,
+		CanSeeInactiveCursors: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer := &cursorsTestPeer{received: make(chan string, 16)}
+	observer.ConnectWebSocketPeer(peer)
+
+	mover, _, err := sessions.Create("mover", types.MemberProfile{
+		CanLogin:            true,
+		CanConnect:          true,
+		SendsInactiveCursor: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	moverPeer := &cursorsTestPeer{received: make(chan string, 16)}
+	mover.ConnectWebSocketPeer(moverPeer)
+
+	manager.startInactiveCursors()
+	defer func() {
+		manager.stopInactiveCursors()
+		manager.wg.Wait()
+	}()
+
+	// session disconnects while its cursor is active
+	mover.SetCursor(types.Cursor{X: 10, Y: 20})
+	mover.DisconnectWebSocketPeer(moverPeer, false)
+	manager.flushCursors()
+
+	// broadcast must not wait for the next tick
+	timeout := time.After(inactiveCursorsPeriod / 2)
+	for {
+		select {
+		case e := <-peer.received:
+			if e == event.SESSION_CURSORS {
+				return
+			}
+		case <-timeout:
+			t.Fatal("cursors were not broadcast right after disconnect")
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+func (h *MessageHandlerCtx) broadcastStart(session types.Session, payload *message.BroadcastStart) error {
+	if !session.Profile().IsAdmin {
+		return errors.New("is not the admin")
+	}
+
+	broadcast := h.capture.Broadcast()
+	if broadcast.Started() {
+		return errors.New("server is already broadcasting")
+	}
+
+	// use previous or configured URL, if not provided
+	url := payload.URL
+	if url == "" {
+		url = broadcast.Url()
+	}
+	if url == "" {
+		return errors.New("missing broadcast URL")
+	}
+
+	return broadcast.Start(url)
+}
+
+func (h *MessageHandlerCtx) broadcastStop(session types.Session) error {
+	if !session.Profile().IsAdmin {
+		return errors.New("is not the admin")
+	}
+
+	broadcast := h.capture.Broadcast()
+	if !broadcast.Started() {
+		return errors.New("server is not broadcasting")
+	}
+
+	broadcast.Stop()
+	return nil
+}
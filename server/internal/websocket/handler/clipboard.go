@@ -12,6 +12,10 @@ func (h *MessageHandlerCtx) clipboardSet(session types.Session, payload *message
 		return errors.New("cannot access clipboard")
 	}
 
+	if session.FeatureDisabled(types.FeatureClipboard) {
+		return errors.New("clipboard requires secure connection")
+	}
+
 	if !session.IsHost() {
 		return errors.New("is not the host")
 	}
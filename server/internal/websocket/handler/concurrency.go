@@ -0,0 +1,21 @@
+package handler
+
+import "github.com/m1k1o/neko/server/pkg/types/event"
+
+// events with handlers that block on I/O and do not depend on the order of
+// other messages of the session. Clipboard is not among them, because paste
+// must see clipboard set before it.
+var concurrentEvents = map[string]struct{}{
+	event.SYSTEM_LOGS:      {},
+	event.SYSTEM_WHOAMI:    {},
+	event.SYSTEM_VERSION:   {},
+	event.SYSTEM_STATE:     {},
+	event.DESKTOP_NAVIGATE: {},
+}
+
+// Concurrent tells whether message may be handled concurrently with other
+// messages of the same session, all other messages are handled serially.
+func Concurrent(event string) bool {
+	_, ok := concurrentEvents[event]
+	return ok
+}
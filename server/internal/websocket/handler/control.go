@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"context"
 	"errors"
+	"time"
 
+	"github.com/m1k1o/neko/server/internal/input"
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/types/event"
 	"github.com/m1k1o/neko/server/pkg/types/message"
@@ -14,6 +17,7 @@ var (
 	ErrIsNotTheHost       = errors.New("is not the host")
 	ErrIsAlreadyTheHost   = errors.New("is already the host")
 	ErrIsAlreadyHosted    = errors.New("is already hosted")
+	ErrIsHostLocked       = errors.New("host is locked")
 )
 
 func (h *MessageHandlerCtx) controlRelease(session types.Session) error {
@@ -28,6 +32,8 @@ func (h *MessageHandlerCtx) controlRelease(session types.Session) error {
 	h.desktop.ResetKeys()
 	session.ClearHost()
 
+	// releasing control accepts pending takeover
+	h.takeoverAccept(session)
 	return nil
 }
 
@@ -40,12 +46,24 @@ func (h *MessageHandlerCtx) controlRequest(session types.Session) error {
 		return ErrIsAlreadyTheHost
 	}
 
+	// presenter does not want to be interrupted
+	if h.sessions.HostLocked() {
+		return ErrIsHostLocked
+	}
+
 	if h.sessions.Settings().LockedControls && !session.Profile().IsAdmin {
 		return ErrIsNotAllowedToHost
 	}
 
 	// if implicit hosting is enabled, set session as host without asking
 	if h.sessions.Settings().ImplicitHosting {
+		// unless the current host should have a chance to object
+		if grace := h.sessions.Settings().HostTakeoverGrace; grace > 0 {
+			if host, hasHost := h.sessions.GetHost(); hasHost {
+				return h.takeoverRequest(session, host, time.Duration(grace)*time.Second)
+			}
+		}
+
 		session.SetAsHost()
 		return nil
 	}
@@ -69,19 +87,42 @@ func (h *MessageHandlerCtx) controlRequest(session types.Session) error {
 	return ErrIsAlreadyHosted
 }
 
-func (h *MessageHandlerCtx) controlMove(session types.Session, payload *message.ControlPos) error {
+// controlInput requests control for session sending input, it returns false
+// if input must not be applied, e.g. while the host can object to takeover.
+func (h *MessageHandlerCtx) controlInput(session types.Session) (bool, error) {
 	if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
+		return false, err
+	}
+
+	return session.IsHost(), nil
+}
+
+func (h *MessageHandlerCtx) controlLock(session types.Session, payload *message.ControlLock) error {
+	if !session.IsHost() && !session.Profile().IsAdmin {
+		return ErrIsNotTheHost
+	}
+
+	return h.sessions.SetHostLocked(session, payload.Locked)
+}
+
+func (h *MessageHandlerCtx) controlMove(session types.Session, payload *message.ControlPos) error {
+	if ok, err := h.controlInput(session); !ok {
 		return err
 	}
 
-	// handle active cursor movement
+	h.move(session, payload)
+	return nil
+}
+
+// move handles active cursor movement.
+func (h *MessageHandlerCtx) move(session types.Session, payload *message.ControlPos) {
 	h.desktop.Move(payload.X, payload.Y)
 	h.webrtc.SetCursorPosition(payload.X, payload.Y)
-	return nil
+	input.Record(session, types.InputEvent{Type: types.InputMove, X: payload.X, Y: payload.Y})
 }
 
 func (h *MessageHandlerCtx) controlScroll(session types.Session, payload *message.ControlScroll) error {
-	if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
+	if ok, err := h.controlInput(session); !ok {
 		return err
 	}
 
@@ -92,104 +133,150 @@ func (h *MessageHandlerCtx) controlScroll(session types.Session, payload *messag
 	}
 
 	h.desktop.Scroll(payload.DeltaX, payload.DeltaY, payload.ControlKey)
+	input.Record(session, types.InputEvent{Type: types.InputScroll, DeltaX: payload.DeltaX, DeltaY: payload.DeltaY, ControlKey: payload.ControlKey})
 	return nil
 }
 
 func (h *MessageHandlerCtx) controlButtonPress(session types.Session, payload *message.ControlButton) error {
+	if ok, err := h.controlInput(session); !ok {
+		return err
+	}
+
 	if payload.ControlPos != nil {
-		if err := h.controlMove(session, payload.ControlPos); err != nil {
-			return err
-		}
-	} else if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
+		h.move(session, payload.ControlPos)
+	}
+
+	if err := h.desktop.ButtonPress(payload.Code); err != nil {
 		return err
 	}
 
-	return h.desktop.ButtonPress(payload.Code)
+	input.Record(session, types.InputEvent{Type: types.InputButtonPress, Code: payload.Code})
+	return nil
 }
 
 func (h *MessageHandlerCtx) controlButtonDown(session types.Session, payload *message.ControlButton) error {
+	if ok, err := h.controlInput(session); !ok {
+		return err
+	}
+
 	if payload.ControlPos != nil {
-		if err := h.controlMove(session, payload.ControlPos); err != nil {
-			return err
-		}
-	} else if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
+		h.move(session, payload.ControlPos)
+	}
+
+	if err := h.desktop.ButtonDown(payload.Code); err != nil {
 		return err
 	}
 
-	return h.desktop.ButtonDown(payload.Code)
+	input.Record(session, types.InputEvent{Type: types.InputButtonDown, Code: payload.Code})
+	return nil
 }
 
 func (h *MessageHandlerCtx) controlButtonUp(session types.Session, payload *message.ControlButton) error {
+	if ok, err := h.controlInput(session); !ok {
+		return err
+	}
+
 	if payload.ControlPos != nil {
-		if err := h.controlMove(session, payload.ControlPos); err != nil {
-			return err
-		}
-	} else if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
+		h.move(session, payload.ControlPos)
+	}
+
+	if err := h.desktop.ButtonUp(payload.Code); err != nil {
 		return err
 	}
 
-	return h.desktop.ButtonUp(payload.Code)
+	input.Record(session, types.InputEvent{Type: types.InputButtonUp, Code: payload.Code})
+	return nil
 }
 
 func (h *MessageHandlerCtx) controlKeyPress(session types.Session, payload *message.ControlKey) error {
+	if ok, err := h.controlInput(session); !ok {
+		return err
+	}
+
 	if payload.ControlPos != nil {
-		if err := h.controlMove(session, payload.ControlPos); err != nil {
-			return err
-		}
-	} else if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
+		h.move(session, payload.ControlPos)
+	}
+
+	if err := h.desktop.KeyPress(payload.Keysym); err != nil {
 		return err
 	}
 
-	return h.desktop.KeyPress(payload.Keysym)
+	input.Record(session, types.InputEvent{Type: types.InputKeyPress, Code: payload.Keysym})
+	return nil
 }
 
 func (h *MessageHandlerCtx) controlKeyDown(session types.Session, payload *message.ControlKey) error {
+	if ok, err := h.controlInput(session); !ok {
+		return err
+	}
+
 	if payload.ControlPos != nil {
-		if err := h.controlMove(session, payload.ControlPos); err != nil {
-			return err
-		}
-	} else if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
+		h.move(session, payload.ControlPos)
+	}
+
+	if err := h.desktop.KeyDown(payload.Keysym); err != nil {
 		return err
 	}
 
-	return h.desktop.KeyDown(payload.Keysym)
+	input.Record(session, types.InputEvent{Type: types.InputKeyDown, Code: payload.Keysym})
+	return nil
 }
 
 func (h *MessageHandlerCtx) controlKeyUp(session types.Session, payload *message.ControlKey) error {
+	if ok, err := h.controlInput(session); !ok {
+		return err
+	}
+
 	if payload.ControlPos != nil {
-		if err := h.controlMove(session, payload.ControlPos); err != nil {
-			return err
-		}
-	} else if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
+		h.move(session, payload.ControlPos)
+	}
+
+	if err := h.desktop.KeyUp(payload.Keysym); err != nil {
 		return err
 	}
 
-	return h.desktop.KeyUp(payload.Keysym)
+	input.Record(session, types.InputEvent{Type: types.InputKeyUp, Code: payload.Keysym})
+	return nil
 }
 
 func (h *MessageHandlerCtx) controlTouchBegin(session types.Session, payload *message.ControlTouch) error {
-	if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
+	if ok, err := h.controlInput(session); !ok {
+		return err
+	}
+	if err := h.desktop.TouchBegin(payload.TouchId, payload.X, payload.Y, payload.Pressure); err != nil {
 		return err
 	}
-	return h.desktop.TouchBegin(payload.TouchId, payload.X, payload.Y, payload.Pressure)
+
+	input.Record(session, types.InputEvent{Type: types.InputTouchBegin, TouchId: payload.TouchId, X: payload.X, Y: payload.Y, Pressure: payload.Pressure})
+	return nil
 }
 
 func (h *MessageHandlerCtx) controlTouchUpdate(session types.Session, payload *message.ControlTouch) error {
-	if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
+	if ok, err := h.controlInput(session); !ok {
 		return err
 	}
-	return h.desktop.TouchUpdate(payload.TouchId, payload.X, payload.Y, payload.Pressure)
+	if err := h.desktop.TouchUpdate(payload.TouchId, payload.X, payload.Y, payload.Pressure); err != nil {
+		return err
+	}
+
+	input.Record(session, types.InputEvent{Type: types.InputTouchUpdate, TouchId: payload.TouchId, X: payload.X, Y: payload.Y, Pressure: payload.Pressure})
+	return nil
 }
 
 func (h *MessageHandlerCtx) controlTouchEnd(session types.Session, payload *message.ControlTouch) error {
-	if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
+	if ok, err := h.controlInput(session); !ok {
+		return err
+	}
+	if err := h.desktop.TouchEnd(payload.TouchId, payload.X, payload.Y, payload.Pressure); err != nil {
 		return err
 	}
-	return h.desktop.TouchEnd(payload.TouchId, payload.X, payload.Y, payload.Pressure)
+
+	input.Record(session, types.InputEvent{Type: types.InputTouchEnd, TouchId: payload.TouchId, X: payload.X, Y: payload.Y, Pressure: payload.Pressure})
+	return nil
 }
 
 func (h *MessageHandlerCtx) controlCut(session types.Session) error {
-	if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
+	if ok, err := h.controlInput(session); !ok {
 		return err
 	}
 
@@ -197,7 +284,7 @@ func (h *MessageHandlerCtx) controlCut(session types.Session) error {
 }
 
 func (h *MessageHandlerCtx) controlCopy(session types.Session) error {
-	if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
+	if ok, err := h.controlInput(session); !ok {
 		return err
 	}
 
@@ -205,7 +292,7 @@ func (h *MessageHandlerCtx) controlCopy(session types.Session) error {
 }
 
 func (h *MessageHandlerCtx) controlPaste(session types.Session, payload *message.ClipboardData) error {
-	if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
+	if ok, err := h.controlInput(session); !ok {
 		return err
 	}
 
@@ -220,9 +307,63 @@ func (h *MessageHandlerCtx) controlPaste(session types.Session, payload *message
 }
 
 func (h *MessageHandlerCtx) controlSelectAll(session types.Session) error {
-	if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
+	if ok, err := h.controlInput(session); !ok {
 		return err
 	}
 
 	return h.desktop.KeyPress(xorg.XK_Control_L, xorg.XK_a)
 }
+
+func (h *MessageHandlerCtx) controlRecordStart(session types.Session) error {
+	if !session.Profile().CanHost || session.PrivateModeEnabled() {
+		return ErrIsNotAllowedToHost
+	}
+
+	input.StartRecording(session)
+	return nil
+}
+
+func (h *MessageHandlerCtx) controlRecordStop(session types.Session) error {
+	recording, err := input.StopRecording(session)
+	if err != nil {
+		return err
+	}
+
+	session.Send(
+		event.CONTROL_RECORDING,
+		message.ControlRecording{
+			InputRecording: *recording,
+		})
+
+	return nil
+}
+
+func (h *MessageHandlerCtx) controlReplay(session types.Session, payload *message.ControlRecording) error {
+	if !session.Profile().CanHost || session.PrivateModeEnabled() {
+		return ErrIsNotAllowedToHost
+	}
+
+	if !session.IsHost() {
+		return ErrIsNotTheHost
+	}
+
+	apply := func(event types.InputEvent) error {
+		if event.Type == types.InputMove {
+			h.webrtc.SetCursorPosition(event.X, event.Y)
+		}
+		return input.Apply(h.desktop, event)
+	}
+
+	return input.Replay(session, &payload.InputRecording, apply, func(err error) {
+		var msg message.ControlReplayed
+		if err != nil {
+			// keys pressed by interrupted replay must not stay pressed
+			h.desktop.ResetKeys()
+			if !errors.Is(err, context.Canceled) {
+				msg.Error = err.Error()
+			}
+		}
+
+		session.Send(event.CONTROL_REPLAYED, msg)
+	})
+}
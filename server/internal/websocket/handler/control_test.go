@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/m1k1o/neko/server/internal/input"
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+type recordTestDesktop struct {
+	takeoverTestDesktop
+	err error
+}
+
+func (d *recordTestDesktop) KeyDown(code uint32) error { return d.err }
+
+// Ensure that input is recorded only once it was applied, same as on webrtc
+func TestControlRecordsAppliedInput(t *testing.T) {
+	h, host, _, _ := newTakeoverTest(t)
+	desktop := &recordTestDesktop{}
+	h.desktop = desktop
+
+	input.StartRecording(host)
+
+	desktop.err = errors.New("key down failed")
+	if err := h.controlKeyDown(host, &message.ControlKey{Keysym: 1}); err == nil {
+		t.Fatal("controlKeyDown() succeeded, want error")
+	}
+
+	desktop.err = nil
+	if err := h.controlKeyDown(host, &message.ControlKey{Keysym: 2}); err != nil {
+		t.Fatalf("controlKeyDown() = %v", err)
+	}
+
+	recording, err := input.StopRecording(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recording.Events) != 1 || recording.Events[0].Type != types.InputKeyDown || recording.Events[0].Code != 2 {
+		t.Errorf("recorded %+v, want only applied key down", recording.Events)
+	}
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+func (h *MessageHandlerCtx) desktopNavigate(session types.Session, payload *message.DesktopNavigate) error {
+	if !session.Profile().IsAdmin {
+		return errors.New("is not the admin")
+	}
+
+	err := h.desktop.Navigate(payload.URL)
+
+	// audit log
+	h.logger.Info().
+		Err(err).
+		Str("session_id", session.ID()).
+		Str("url", payload.URL).
+		Msg("desktop navigate")
+
+	return err
+}
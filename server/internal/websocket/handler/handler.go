@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"sync"
+
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"github.com/m1k1o/neko/server/internal/input"
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/types/event"
 	"github.com/m1k1o/neko/server/pkg/types/message"
@@ -31,6 +34,9 @@ type MessageHandlerCtx struct {
 	webrtc   types.WebRTCManager
 	desktop  types.DesktopManager
 	capture  types.CaptureManager
+
+	takeoverMu sync.Mutex
+	takeover   *hostTakeover
 }
 
 func (h *MessageHandlerCtx) Message(session types.Session, data types.WebSocketMessage) bool {
@@ -46,6 +52,14 @@ func (h *MessageHandlerCtx) Message(session types.Session, data types.WebSocketM
 		err = utils.Unmarshal(payload, data.Payload, func() error {
 			return h.systemLogs(session, payload)
 		})
+	case event.SYSTEM_WHOAMI:
+		err = h.systemWhoami(session)
+	case event.SYSTEM_VERSION:
+		err = h.systemVersion(session)
+	case event.SYSTEM_STATE:
+		err = h.systemState(session)
+	case event.SYSTEM_CONSENT:
+		err = h.systemConsent(session)
 
 	// Signal Events
 	case event.SIGNAL_REQUEST:
@@ -55,6 +69,8 @@ func (h *MessageHandlerCtx) Message(session types.Session, data types.WebSocketM
 		})
 	case event.SIGNAL_RESTART:
 		err = h.signalRestart(session)
+	case event.SIGNAL_RECONNECT:
+		err = h.signalReconnect(session)
 	case event.SIGNAL_OFFER:
 		payload := &message.SignalDescription{}
 		err = utils.Unmarshal(payload, data.Payload, func() error {
@@ -80,12 +96,37 @@ func (h *MessageHandlerCtx) Message(session types.Session, data types.WebSocketM
 		err = utils.Unmarshal(payload, data.Payload, func() error {
 			return h.signalAudio(session, payload)
 		})
+	case event.SIGNAL_CURSOR:
+		payload := &message.SignalCursor{}
+		err = utils.Unmarshal(payload, data.Payload, func() error {
+			return h.signalCursor(session, payload)
+		})
+	case event.SIGNAL_BANDWIDTH:
+		err = h.signalBandwidth(session)
 
 	// Control Events
 	case event.CONTROL_RELEASE:
 		err = h.controlRelease(session)
 	case event.CONTROL_REQUEST:
 		err = h.controlRequest(session)
+	case event.CONTROL_RECORD_START:
+		err = h.controlRecordStart(session)
+	case event.CONTROL_RECORD_STOP:
+		err = h.controlRecordStop(session)
+	case event.CONTROL_REPLAY:
+		payload := &message.ControlRecording{}
+		err = utils.Unmarshal(payload, data.Payload, func() error {
+			return h.controlReplay(session, payload)
+		})
+	case event.CONTROL_REPLAY_STOP:
+		input.StopReplay(session)
+	case event.CONTROL_LOCK:
+		payload := &message.ControlLock{}
+		err = utils.Unmarshal(payload, data.Payload, func() error {
+			return h.controlLock(session, payload)
+		})
+	case event.CONTROL_OBJECT:
+		err = h.controlObject(session)
 	case event.CONTROL_MOVE:
 		payload := &message.ControlPos{}
 		err = utils.Unmarshal(payload, data.Payload, func() error {
@@ -161,6 +202,18 @@ func (h *MessageHandlerCtx) Message(session types.Session, data types.WebSocketM
 		err = utils.Unmarshal(payload, data.Payload, func() error {
 			return h.screenSet(session, payload)
 		})
+	case event.SCREEN_REGION_SET:
+		payload := &message.ScreenRegion{}
+		err = utils.Unmarshal(payload, data.Payload, func() error {
+			return h.screenRegionSet(session, payload)
+		})
+
+	// Desktop Events
+	case event.DESKTOP_NAVIGATE:
+		payload := &message.DesktopNavigate{}
+		err = utils.Unmarshal(payload, data.Payload, func() error {
+			return h.desktopNavigate(session, payload)
+		})
 
 	// Clipboard Events
 	case event.CLIPBOARD_SET:
@@ -181,6 +234,15 @@ func (h *MessageHandlerCtx) Message(session types.Session, data types.WebSocketM
 			return h.keyboardModifiers(session, payload)
 		})
 
+	// Broadcast Events
+	case event.BROADCAST_START:
+		payload := &message.BroadcastStart{}
+		err = utils.Unmarshal(payload, data.Payload, func() error {
+			return h.broadcastStart(session, payload)
+		})
+	case event.BROADCAST_STOP:
+		err = h.broadcastStop(session)
+
 	// Send Events
 	case event.SEND_UNICAST:
 		payload := &message.SendUnicast{}
@@ -205,3 +267,51 @@ func (h *MessageHandlerCtx) Message(session types.Session, data types.WebSocketM
 
 	return true
 }
+
+// Input handles already decoded input event, so that payloads that did not
+// arrive as JSON do not need to be encoded and unmarshalled again. Returns
+// false if the payload does not belong to the event.
+func (h *MessageHandlerCtx) Input(session types.Session, name string, payload any) bool {
+	var err error
+	switch payload := payload.(type) {
+	case *message.ControlPos:
+		if name != event.CONTROL_MOVE {
+			return false
+		}
+		err = h.controlMove(session, payload)
+	case *message.ControlScroll:
+		if name != event.CONTROL_SCROLL {
+			return false
+		}
+		err = h.controlScroll(session, payload)
+	case *message.ControlKey:
+		switch name {
+		case event.CONTROL_KEYDOWN:
+			err = h.controlKeyDown(session, payload)
+		case event.CONTROL_KEYUP:
+			err = h.controlKeyUp(session, payload)
+		default:
+			return false
+		}
+	case *message.ControlButton:
+		switch name {
+		case event.CONTROL_BUTTONDOWN:
+			err = h.controlButtonDown(session, payload)
+		case event.CONTROL_BUTTONUP:
+			err = h.controlButtonUp(session, payload)
+		default:
+			return false
+		}
+	default:
+		return false
+	}
+
+	if err != nil {
+		h.logger.Warn().Err(err).
+			Str("event", name).
+			Str("session_id", session.ID()).
+			Msg("message handler has failed")
+	}
+
+	return true
+}
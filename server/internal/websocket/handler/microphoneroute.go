@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// session scratch store key
+const microphoneRouteKey = "handler/microphone_route"
+
+// setMicrophoneRoute sets shared microphone route of the peer. When the request does not
+// specify one, route selected for the previous peer of the session is used again.
+func (h *MessageHandlerCtx) setMicrophoneRoute(session types.Session, peer types.WebRTCPeer, requested types.MicrophoneRoute) error {
+	route := requested
+	if route == "" {
+		value, ok := session.Value(microphoneRouteKey)
+		if !ok {
+			return nil
+		}
+		route, _ = value.(types.MicrophoneRoute)
+	}
+
+	if err := peer.SetMicrophoneRoute(route); err != nil {
+		if requested != "" {
+			return err
+		}
+
+		// route is no longer available, e.g. mixing got disabled
+		h.logger.Warn().Err(err).
+			Str("session_id", session.ID()).
+			Str("route", string(route)).
+			Msg("could not restore microphone route")
+		session.DeleteValue(microphoneRouteKey)
+		return nil
+	}
+
+	session.SetValue(microphoneRouteKey, route)
+	return nil
+}
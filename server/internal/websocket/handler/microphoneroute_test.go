@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/internal/session"
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+type microphoneRouteTestPeer struct {
+	types.WebRTCPeer
+	mixDisabled bool
+	route       types.MicrophoneRoute
+}
+
+func (p *microphoneRouteTestPeer) SetMicrophoneRoute(route types.MicrophoneRoute) error {
+	if p.mixDisabled && route != types.MicrophoneRouteDesktop {
+		return errors.New("microphone mixing is disabled")
+	}
+	p.route = route
+	return nil
+}
+
+func TestMicrophoneRoute(t *testing.T) {
+	sessions := session.New(&config.Session{})
+	h := New(sessions, nil, nil, nil)
+
+	s, _, err := sessions.Create("test", types.MemberProfile{CanLogin: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing requested nor selected before, default of the peer is kept
+	peer := &microphoneRouteTestPeer{}
+	if err := h.setMicrophoneRoute(s, peer, ""); err != nil || peer.route != "" {
+		t.Fatalf("setMicrophoneRoute() = %v, route %q, want default", err, peer.route)
+	}
+
+	if err := h.setMicrophoneRoute(s, peer, types.MicrophoneRouteBoth); err != nil || peer.route != types.MicrophoneRouteBoth {
+		t.Fatalf("setMicrophoneRoute() = %v, route %q, want %q", err, peer.route, types.MicrophoneRouteBoth)
+	}
+
+	// recreated peer gets the route selected before
+	peer = &microphoneRouteTestPeer{}
+	if err := h.setMicrophoneRoute(s, peer, ""); err != nil || peer.route != types.MicrophoneRouteBoth {
+		t.Fatalf("setMicrophoneRoute() = %v, route %q, want %q", err, peer.route, types.MicrophoneRouteBoth)
+	}
+
+	// requested route replaces the previous one
+	if err := h.setMicrophoneRoute(s, peer, types.MicrophoneRouteDesktop); err != nil {
+		t.Fatal(err)
+	}
+	peer = &microphoneRouteTestPeer{}
+	if err := h.setMicrophoneRoute(s, peer, ""); err != nil || peer.route != types.MicrophoneRouteDesktop {
+		t.Fatalf("setMicrophoneRoute() = %v, route %q, want %q", err, peer.route, types.MicrophoneRouteDesktop)
+	}
+}
+
+func TestMicrophoneRouteUnavailable(t *testing.T) {
+	sessions := session.New(&config.Session{})
+	h := New(sessions, nil, nil, nil)
+
+	s, _, err := sessions.Create("test", types.MemberProfile{CanLogin: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peer := &microphoneRouteTestPeer{mixDisabled: true}
+	if err := h.setMicrophoneRoute(s, peer, types.MicrophoneRouteMix); err == nil {
+		t.Fatal("expected requested route to be rejected")
+	}
+
+	// route that is no longer available is dropped, not failing the request
+	s.SetValue(microphoneRouteKey, types.MicrophoneRouteMix)
+	if err := h.setMicrophoneRoute(s, peer, ""); err != nil || peer.route != "" {
+		t.Fatalf("setMicrophoneRoute() = %v, route %q, want default", err, peer.route)
+	}
+	if _, ok := s.Value(microphoneRouteKey); ok {
+		t.Error("expected unavailable route to be forgotten")
+	}
+}
@@ -24,3 +24,20 @@ func (h *MessageHandlerCtx) screenSet(session types.Session, payload *message.Sc
 	})
 	return nil
 }
+
+func (h *MessageHandlerCtx) screenRegionSet(session types.Session, payload *message.ScreenRegion) error {
+	if !session.IsHost() {
+		return errors.New("is not the host")
+	}
+
+	region, err := h.capture.SetVideoRegion(payload.Region)
+	if err != nil {
+		return err
+	}
+
+	h.sessions.Broadcast(event.SCREEN_REGION_UPDATED, message.ScreenRegionUpdate{
+		ID:     session.ID(),
+		Region: region,
+	})
+	return nil
+}
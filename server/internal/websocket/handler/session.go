@@ -33,6 +33,27 @@ func (h *MessageHandlerCtx) SessionConnected(session types.Session) error {
 		return err
 	}
 
+	// terms must be acknowledged before session is admitted
+	if !session.State().IsAdmitted {
+		text, version, timeout := h.sessions.Consent()
+		session.Send(
+			event.SYSTEM_CONSENT,
+			message.SystemConsent{
+				Text:    text,
+				Version: version,
+				Timeout: timeout.Milliseconds(),
+			})
+	}
+
+	// summary of what changed while the session was away
+	if changes := session.ChangesWhileAway(); len(changes) > 0 {
+		session.Send(
+			event.SYSTEM_CHANGES,
+			message.SystemChanges{
+				Changes: changes,
+			})
+	}
+
 	if session.Profile().IsAdmin {
 		if err := h.systemAdmin(session); err != nil {
 			return err
@@ -53,6 +74,9 @@ func (h *MessageHandlerCtx) SessionConnected(session types.Session) error {
 }
 
 func (h *MessageHandlerCtx) SessionDisconnected(session types.Session) error {
+	// make sure no webrtc resources outlive the session
+	h.webrtc.ClosePeers(session)
+
 	// clear host if exists
 	if session.IsHost() {
 		h.desktop.ResetKeys()
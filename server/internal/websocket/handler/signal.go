@@ -2,6 +2,8 @@ package handler
 
 import (
 	"errors"
+	"slices"
+	"time"
 
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/types/event"
@@ -11,11 +13,30 @@ import (
 )
 
 func (h *MessageHandlerCtx) signalRequest(session types.Session, payload *message.SignalRequest) error {
+	if payload.DataOnly {
+		return h.signalRequestDataOnly(session)
+	}
+
 	if !session.Profile().CanWatch {
 		return errors.New("not allowed to watch")
 	}
 
-	offer, peer, err := h.webrtc.CreatePeer(session)
+	offer, peer, err := h.webrtc.CreatePeer(session, false)
+	if errors.Is(err, types.ErrWebRTCNoVideoStreams) {
+		h.videoUnavailable(session, "", "")
+		return err
+	}
+	if errors.Is(err, types.ErrWebRTCServerBusy) {
+		session.Send(
+			event.SYSTEM_ERROR,
+			message.SystemError{
+				Subsystem: "webrtc",
+				Kind:      "server_busy",
+				Message:   "server is busy, try again later",
+				Time:      time.Now(),
+			})
+		return err
+	}
 	if err != nil {
 		return err
 	}
@@ -27,11 +48,16 @@ func (h *MessageHandlerCtx) signalRequest(session types.Session, payload *messag
 
 	video := payload.Video
 
-	// use default first video, if not provided
+	// use default video of the session or the first one, if not provided
 	if video.Selector == nil {
 		videos := h.capture.Video().IDs()
+		videoId := videos[0]
+		if id, ok := defaultVideo(session); ok && slices.Contains(videos, id) {
+			videoId = id
+		}
+
 		video.Selector = &types.StreamSelector{
-			ID:   videos[0],
+			ID:   videoId,
 			Type: types.StreamSelectorTypeExact,
 		}
 	}
@@ -43,6 +69,24 @@ func (h *MessageHandlerCtx) signalRequest(session types.Session, payload *messag
 
 	// set video stream
 	err = peer.SetVideo(video)
+	if errors.Is(err, types.ErrWebRTCStreamNotFound) && video.Selector.ID != "" {
+		// requested video does not exist, fall back to the default one
+		requested := video.Selector.ID
+		fallback := h.capture.Video().IDs()[0]
+
+		h.logger.Warn().
+			Str("session_id", session.ID()).
+			Str("requested", requested).
+			Str("fallback", fallback).
+			Msg("requested video not found, using fallback")
+		h.videoUnavailable(session, requested, fallback)
+
+		video.Selector = &types.StreamSelector{
+			ID:   fallback,
+			Type: types.StreamSelectorTypeExact,
+		}
+		err = peer.SetVideo(video)
+	}
 	if err != nil {
 		return err
 	}
@@ -61,40 +105,106 @@ func (h *MessageHandlerCtx) signalRequest(session types.Session, payload *messag
 		return err
 	}
 
+	// set shared microphone route, if requested or selected before
+	err = h.setMicrophoneRoute(session, peer, payload.MicrophoneRoute)
+	if err != nil {
+		return err
+	}
+
+	// limit cursor updates, if requested
+	if payload.Cursor != "" {
+		err = peer.SetCursorMode(payload.Cursor)
+		if err != nil {
+			return err
+		}
+	}
+
 	session.Send(
 		event.SIGNAL_PROVIDE,
 		message.SignalProvide{
+			PeerID:     peer.ID(),
 			SDP:        offer.SDP,
-			ICEServers: h.webrtc.ICEServers(),
+			ICEServers: peer.ICEServers(),
 
-			Video: peer.Video(),
-			Audio: peer.Audio(),
+			Video:  peer.Video(),
+			Audio:  peer.Audio(),
+			Cursor: peer.CursorMode(),
 		})
 
 	return nil
 }
 
-func (h *MessageHandlerCtx) signalRestart(session types.Session) error {
-	peer := session.GetWebRTCPeer()
-	if peer == nil {
-		return errors.New("webRTC peer does not exist")
+// signalRequestDataOnly creates peer without media, that is only used to
+// control the desktop. Input is still accepted only from the host.
+func (h *MessageHandlerCtx) signalRequestDataOnly(session types.Session) error {
+	if !session.Profile().CanHost || session.PrivateModeEnabled() {
+		return ErrIsNotAllowedToHost
 	}
 
-	offer, err := peer.CreateOffer(true)
+	offer, peer, err := h.webrtc.CreatePeer(session, true)
 	if err != nil {
 		return err
 	}
 
-	// TODO: Use offer event instead.
 	session.Send(
-		event.SIGNAL_RESTART,
-		message.SignalDescription{
-			SDP: offer.SDP,
+		event.SIGNAL_PROVIDE,
+		message.SignalProvide{
+			PeerID:     peer.ID(),
+			SDP:        offer.SDP,
+			ICEServers: peer.ICEServers(),
+
+			Video:  peer.Video(),
+			Audio:  peer.Audio(),
+			Cursor: peer.CursorMode(),
 		})
 
 	return nil
 }
 
+func (h *MessageHandlerCtx) signalRestart(session types.Session) error {
+	peer := session.GetWebRTCPeer()
+	if peer == nil {
+		return errors.New("webRTC peer does not exist")
+	}
+
+	return peer.RestartICE()
+}
+
+// Reconnect creates a completely new webrtc peer while keeping the websocket
+// connection alive. Current video and audio settings are carried over, the
+// old peer is destroyed when the new one replaces it in the session.
+func (h *MessageHandlerCtx) signalReconnect(session types.Session) error {
+	peer := session.GetWebRTCPeer()
+	if peer == nil {
+		return errors.New("webRTC peer does not exist")
+	}
+
+	video := peer.Video()
+	audio := peer.Audio()
+
+	request := &message.SignalRequest{
+		Video: types.PeerVideoRequest{
+			Disabled: &video.Disabled,
+			Auto:     &video.Auto,
+		},
+		Audio: types.PeerAudioRequest{
+			Disabled: &audio.Disabled,
+			Track:    &audio.Track,
+		},
+		Cursor: peer.CursorMode(),
+	}
+
+	// keep the same video stream, if there was one
+	if video.ID != "" {
+		request.Video.Selector = &types.StreamSelector{
+			ID:   video.ID,
+			Type: types.StreamSelectorTypeExact,
+		}
+	}
+
+	return h.signalRequest(session, request)
+}
+
 func (h *MessageHandlerCtx) signalOffer(session types.Session, payload *message.SignalDescription) error {
 	peer := session.GetWebRTCPeer()
 	if peer == nil {
@@ -105,6 +215,11 @@ func (h *MessageHandlerCtx) signalOffer(session types.Session, payload *message.
 		SDP:  payload.SDP,
 		Type: webrtc.SDPTypeOffer,
 	})
+	if errors.Is(err, types.ErrWebRTCOfferIgnored) {
+		// our offer takes precedence, client is expected to answer it
+		h.logger.Debug().Str("session_id", session.ID()).Msg("colliding offer ignored")
+		return nil
+	}
 	if err != nil {
 		return err
 	}
@@ -150,7 +265,27 @@ func (h *MessageHandlerCtx) signalVideo(session types.Session, payload *message.
 		return errors.New("webRTC peer does not exist")
 	}
 
-	return peer.SetVideo(payload.PeerVideoRequest)
+	err := peer.SetVideo(payload.PeerVideoRequest)
+	if errors.Is(err, types.ErrWebRTCStreamNotFound) {
+		// let client know which videos it can choose from
+		if selector := payload.Selector; selector != nil && selector.Type == types.StreamSelectorTypeExact {
+			h.videoUnavailable(session, selector.ID, "")
+		}
+	}
+
+	return err
+}
+
+// videoUnavailable notifies client that requested video is not available,
+// along with IDs of all available videos it can choose from.
+func (h *MessageHandlerCtx) videoUnavailable(session types.Session, requested, fallback string) {
+	session.Send(
+		event.SIGNAL_VIDEO_UNAVAILABLE,
+		message.SignalVideoUnavailable{
+			Requested: requested,
+			Fallback:  fallback,
+			Videos:    h.capture.Video().IDs(),
+		})
 }
 
 func (h *MessageHandlerCtx) signalAudio(session types.Session, payload *message.SignalAudio) error {
@@ -161,3 +296,45 @@ func (h *MessageHandlerCtx) signalAudio(session types.Session, payload *message.
 
 	return peer.SetAudio(payload.PeerAudioRequest)
 }
+
+func (h *MessageHandlerCtx) signalCursor(session types.Session, payload *message.SignalCursor) error {
+	peer := session.GetWebRTCPeer()
+	if peer == nil {
+		return errors.New("webRTC peer does not exist")
+	}
+
+	if err := peer.SetCursorMode(payload.Mode); err != nil {
+		return err
+	}
+
+	// client renders default cursor, when it does not receive cursor image
+	session.Send(
+		event.SIGNAL_CURSOR,
+		message.SignalCursor{
+			Mode: peer.CursorMode(),
+		})
+
+	return nil
+}
+
+// signalBandwidth sends bandwidth estimated for the peer, so that client can
+// display it along with bitrate of the video it receives.
+func (h *MessageHandlerCtx) signalBandwidth(session types.Session) error {
+	peer := session.GetWebRTCPeer()
+	if peer == nil {
+		return errors.New("webRTC peer does not exist")
+	}
+
+	bandwidth, ok := peer.Bandwidth()
+	if !ok {
+		return errors.New("bandwidth estimator is disabled")
+	}
+
+	session.Send(
+		event.SIGNAL_BANDWIDTH,
+		message.SignalBandwidth{
+			PeerBandwidth: bandwidth,
+		})
+
+	return nil
+}
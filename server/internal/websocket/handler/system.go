@@ -4,12 +4,13 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	neko "github.com/m1k1o/neko/server"
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/types/event"
 	"github.com/m1k1o/neko/server/pkg/types/message"
 )
 
-func (h *MessageHandlerCtx) systemInit(session types.Session) error {
+func (h *MessageHandlerCtx) controlHost() message.ControlHost {
 	host, hasHost := h.sessions.GetHost()
 
 	var hostID string
@@ -17,11 +18,22 @@ func (h *MessageHandlerCtx) systemInit(session types.Session) error {
 		hostID = host.ID()
 	}
 
-	controlHost := message.ControlHost{
+	return message.ControlHost{
 		HasHost: hasHost,
 		HostID:  hostID,
+		Locked:  h.sessions.HostLocked(),
+	}
+}
+
+func (h *MessageHandlerCtx) systemWebRTC() message.SystemWebRTC {
+	return message.SystemWebRTC{
+		Videos:    h.capture.Video().IDs(),
+		Audio:     h.capture.Audio() != nil,
+		Framerate: h.capture.VideoFramerate(),
 	}
+}
 
+func (h *MessageHandlerCtx) systemInit(session types.Session) error {
 	sessions := map[string]message.SessionData{}
 	for _, session := range h.sessions.List() {
 		sessionId := session.ID()
@@ -36,15 +48,18 @@ func (h *MessageHandlerCtx) systemInit(session types.Session) error {
 		event.SYSTEM_INIT,
 		message.SystemInit{
 			SessionId:         session.ID(),
-			ControlHost:       controlHost,
+			ControlHost:       h.controlHost(),
 			ScreenSize:        h.desktop.GetScreenSize(),
+			ScreenRegion:      h.capture.VideoRegion(),
 			Sessions:          sessions,
 			Settings:          h.sessions.Settings(),
 			TouchEvents:       h.desktop.HasTouchSupport(),
 			ScreencastEnabled: h.capture.Screencast().Enabled(),
-			WebRTC: message.SystemWebRTC{
-				Videos: h.capture.Video().IDs(),
-			},
+			DisabledFeatures:  session.DisabledFeatures(),
+			PrivacyScreen:     h.capture.Privacy(),
+			WebRTC:            h.systemWebRTC(),
+			ReconnectToken:    session.ReconnectToken(),
+			Version:           systemVersion(),
 		})
 
 	return nil
@@ -62,15 +77,107 @@ func (h *MessageHandlerCtx) systemAdmin(session types.Session) error {
 		})
 	}
 
-	broadcast := h.capture.Broadcast()
 	session.Send(
 		event.SYSTEM_ADMIN,
 		message.SystemAdmin{
-			ScreenSizesList: list, // TODO: remove
-			BroadcastStatus: message.BroadcastStatus{
-				IsActive: broadcast.Started(),
-				URL:      broadcast.Url(),
-			},
+			ScreenSizesList:  list, // TODO: remove
+			BroadcastStatus:  message.BroadcastStatus(h.capture.Broadcast().Status()),
+			CaptureFramerate: h.capture.VideoFramerate(),
+		})
+
+	return nil
+}
+
+func systemVersion() message.SystemVersion {
+	return message.SystemVersion{
+		Version:   neko.Version.String(),
+		GitCommit: neko.Version.GitCommit,
+		GitBranch: neko.Version.GitBranch,
+		GitTag:    neko.Version.GitTag,
+		BuildDate: neko.Version.BuildDate,
+		GoVersion: neko.Version.GoVersion,
+		Platform:  neko.Version.Platform,
+		Protocol:  neko.ProtocolVersion,
+	}
+}
+
+func (h *MessageHandlerCtx) systemVersion(session types.Session) error {
+	session.Send(event.SYSTEM_VERSION, systemVersion())
+	return nil
+}
+
+func (h *MessageHandlerCtx) systemCapabilities(session types.Session) message.SystemCapabilities {
+	capabilities := message.SystemCapabilities{
+		TouchEvents:       h.desktop.HasTouchSupport(),
+		ScreencastEnabled: h.capture.Screencast().Enabled(),
+		DisabledFeatures:  session.DisabledFeatures(),
+	}
+
+	// add negotiated media state, if webrtc peer exists
+	if peer := session.GetWebRTCPeer(); peer != nil {
+		video := peer.Video()
+		audio := peer.Audio()
+
+		capabilities.WebRTC = true
+		capabilities.Video = &video
+		capabilities.Audio = &audio
+	}
+
+	return capabilities
+}
+
+func (h *MessageHandlerCtx) systemWhoami(session types.Session) error {
+	profile := session.Profile()
+
+	session.Send(
+		event.SYSTEM_WHOAMI,
+		message.SystemWhoami{
+			ID:           session.ID(),
+			Profile:      profile,
+			State:        session.State(),
+			IsAdmin:      profile.IsAdmin,
+			IsHost:       session.IsHost(),
+			Capabilities: h.systemCapabilities(session),
+		})
+
+	return nil
+}
+
+// systemState sends the current room state in one message, so that clients
+// and external tooling do not need to piece it together from several events.
+func (h *MessageHandlerCtx) systemState(session types.Session) error {
+	var members message.SystemMembers
+	for _, s := range h.sessions.List() {
+		members.Total++
+
+		state := s.State()
+		if state.IsConnected {
+			members.Connected++
+		}
+		if state.IsWatching {
+			members.Watching++
+		}
+	}
+
+	var broadcast *message.BroadcastStatus
+	if session.Profile().IsAdmin {
+		status := message.BroadcastStatus(h.capture.Broadcast().Status())
+		broadcast = &status
+	}
+
+	session.Send(
+		event.SYSTEM_STATE,
+		message.SystemState{
+			Settings:        h.sessions.Settings(),
+			ControlHost:     h.controlHost(),
+			Members:         members,
+			ScreenSize:      h.desktop.GetScreenSize(),
+			ScreenRegion:    h.capture.VideoRegion(),
+			PrivacyScreen:   h.capture.Privacy(),
+			WebRTC:          h.systemWebRTC(),
+			Capabilities:    h.systemCapabilities(session),
+			BroadcastStatus: broadcast,
+			Version:         systemVersion(),
 		})
 
 	return nil
@@ -94,3 +201,7 @@ func (h *MessageHandlerCtx) systemLogs(session types.Session, payload *message.S
 
 	return nil
 }
+
+func (h *MessageHandlerCtx) systemConsent(session types.Session) error {
+	return h.sessions.AcknowledgeConsent(session.ID())
+}
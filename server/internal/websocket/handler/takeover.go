@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+var (
+	ErrHostTakeoverPending = errors.New("another host takeover is pending")
+	ErrNoHostTakeover      = errors.New("there is no host takeover to object to")
+)
+
+// hostTakeover is implicit hosting request waiting for the grace period, in
+// which the current host can object.
+type hostTakeover struct {
+	sessionId string
+	hostId    string
+	timer     *time.Timer
+}
+
+// takeoverRequest announces that session is going to take control from the
+// host, pending takeover is reported with control/takeover event. Only one
+// takeover can be pending at a time.
+func (h *MessageHandlerCtx) takeoverRequest(session, host types.Session, grace time.Duration) error {
+	h.takeoverMu.Lock()
+	defer h.takeoverMu.Unlock()
+
+	if h.takeover != nil {
+		// input of the requester is ignored until the takeover completes
+		if h.takeover.sessionId == session.ID() {
+			return nil
+		}
+		return ErrHostTakeoverPending
+	}
+
+	takeover := &hostTakeover{
+		sessionId: session.ID(),
+		hostId:    host.ID(),
+	}
+	takeover.timer = time.AfterFunc(grace, func() {
+		h.takeoverComplete(takeover)
+	})
+	h.takeover = takeover
+
+	h.logger.Debug().
+		Str("session_id", session.ID()).
+		Str("host_id", host.ID()).
+		Msg("host takeover requested")
+
+	h.sessions.Broadcast(
+		event.CONTROL_TAKEOVER,
+		message.ControlTakeover{
+			ID:        session.ID(),
+			HostID:    host.ID(),
+			ExpiresAt: time.Now().Add(grace),
+		})
+
+	return nil
+}
+
+// takeoverComplete hands control over once the grace period passed, unless
+// anything relevant changed meanwhile.
+func (h *MessageHandlerCtx) takeoverComplete(takeover *hostTakeover) {
+	h.takeoverMu.Lock()
+	if h.takeover != takeover {
+		h.takeoverMu.Unlock()
+		return
+	}
+	h.takeover = nil
+	h.takeoverMu.Unlock()
+
+	session, ok := h.sessions.Get(takeover.sessionId)
+	host, hasHost := h.sessions.GetHost()
+
+	if !ok || !session.State().IsConnected ||
+		!session.Profile().CanHost || session.PrivateModeEnabled() ||
+		!h.sessions.Settings().ImplicitHosting || h.sessions.HostLocked() ||
+		(hasHost && host.ID() != takeover.hostId) {
+		h.sessions.Broadcast(
+			event.CONTROL_TAKEOVER_CANCELED,
+			message.ControlTakeoverCanceled{
+				ID: takeover.sessionId,
+			})
+		return
+	}
+
+	// keys pressed by the previous host must not stay pressed
+	h.desktop.ResetKeys()
+	session.SetAsHost()
+}
+
+// takeoverAccept hands control over right away, if the host released control
+// while takeover was pending.
+func (h *MessageHandlerCtx) takeoverAccept(host types.Session) {
+	h.takeoverMu.Lock()
+	takeover := h.takeover
+	if takeover == nil || takeover.hostId != host.ID() {
+		h.takeoverMu.Unlock()
+		return
+	}
+
+	takeover.timer.Stop()
+	h.takeoverMu.Unlock()
+
+	h.takeoverComplete(takeover)
+}
+
+// controlObject cancels pending takeover, only the host or an admin can object.
+func (h *MessageHandlerCtx) controlObject(session types.Session) error {
+	h.takeoverMu.Lock()
+	takeover := h.takeover
+	if takeover == nil {
+		h.takeoverMu.Unlock()
+		return ErrNoHostTakeover
+	}
+
+	if takeover.hostId != session.ID() && !session.Profile().IsAdmin {
+		h.takeoverMu.Unlock()
+		return ErrIsNotTheHost
+	}
+
+	takeover.timer.Stop()
+	h.takeover = nil
+	h.takeoverMu.Unlock()
+
+	h.logger.Debug().
+		Str("session_id", takeover.sessionId).
+		Str("by_id", session.ID()).
+		Msg("host takeover objected")
+
+	h.sessions.Broadcast(
+		event.CONTROL_TAKEOVER_CANCELED,
+		message.ControlTakeoverCanceled{
+			ID:   takeover.sessionId,
+			ByID: session.ID(),
+		})
+
+	return nil
+}
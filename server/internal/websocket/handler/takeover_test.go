@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/internal/session"
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+type takeoverTestPeer struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (p *takeoverTestPeer) Send(event string, payload any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = append(p.events, event)
+}
+
+func (p *takeoverTestPeer) Ping() error           { return nil }
+func (p *takeoverTestPeer) Destroy(reason string) {}
+
+func (p *takeoverTestPeer) received(event string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, e := range p.events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+type takeoverTestDesktop struct {
+	types.DesktopManager
+	moves int
+}
+
+func (d *takeoverTestDesktop) Move(x, y int) { d.moves++ }
+func (d *takeoverTestDesktop) ResetKeys()    {}
+
+type takeoverTestWebRTC struct {
+	types.WebRTCManager
+}
+
+func (takeoverTestWebRTC) SetCursorPosition(x, y int) {}
+
+func newTakeoverTest(t *testing.T) (h *MessageHandlerCtx, host, requester types.Session, peer *takeoverTestPeer) {
+	t.Helper()
+
+	sessions := session.New(&config.Session{
+		ImplicitHosting:   true,
+		HostTakeoverGrace: 1,
+	})
+
+	h = New(sessions, &takeoverTestDesktop{}, nil, takeoverTestWebRTC{})
+
+	profile := types.MemberProfile{CanLogin: true, CanHost: true}
+	host, _, err := sessions.Create("host", profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requester, _, err = sessions.Create("requester", profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peer = &takeoverTestPeer{}
+	host.ConnectWebSocketPeer(peer)
+	requester.ConnectWebSocketPeer(&takeoverTestPeer{})
+	host.SetAsHost()
+
+	return
+}
+
+func TestTakeoverRequest(t *testing.T) {
+	h, host, requester, peer := newTakeoverTest(t)
+	desktop := h.desktop.(*takeoverTestDesktop)
+
+	// pending takeover is not a failure
+	if err := h.controlRequest(requester); err != nil {
+		t.Fatalf("controlRequest() = %v", err)
+	}
+	if !peer.received(event.CONTROL_TAKEOVER) {
+		t.Error("takeover was not announced")
+	}
+
+	// input of the requester is ignored meanwhile
+	if err := h.controlMove(requester, &message.ControlPos{X: 1, Y: 1}); err != nil {
+		t.Errorf("controlMove() = %v", err)
+	}
+	if desktop.moves != 0 {
+		t.Errorf("input was applied while takeover is pending")
+	}
+	if !host.IsHost() {
+		t.Error("host changed before grace period passed")
+	}
+}
+
+func TestTakeoverAccept(t *testing.T) {
+	h, host, requester, _ := newTakeoverTest(t)
+
+	if err := h.controlRequest(requester); err != nil {
+		t.Fatalf("controlRequest() = %v", err)
+	}
+
+	// host releasing control accepts the takeover
+	if err := h.controlRelease(host); err != nil {
+		t.Fatalf("controlRelease() = %v", err)
+	}
+	if !requester.IsHost() {
+		t.Error("requester did not get control after host released it")
+	}
+}
+
+func TestTakeoverDeny(t *testing.T) {
+	h, host, requester, peer := newTakeoverTest(t)
+
+	if err := h.takeoverRequest(requester, host, 20*time.Millisecond); err != nil {
+		t.Fatalf("takeoverRequest() = %v", err)
+	}
+
+	// only the host or an admin can object
+	if err := h.controlObject(requester); err != ErrIsNotTheHost {
+		t.Errorf("controlObject() by requester = %v, want %v", err, ErrIsNotTheHost)
+	}
+	if err := h.controlObject(host); err != nil {
+		t.Fatalf("controlObject() = %v", err)
+	}
+	if !peer.received(event.CONTROL_TAKEOVER_CANCELED) {
+		t.Error("objection was not announced")
+	}
+	if err := h.controlObject(host); err != ErrNoHostTakeover {
+		t.Errorf("controlObject() without takeover = %v, want %v", err, ErrNoHostTakeover)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if !host.IsHost() || requester.IsHost() {
+		t.Error("control was handed over after objection")
+	}
+}
+
+func TestTakeoverTimeout(t *testing.T) {
+	h, host, requester, _ := newTakeoverTest(t)
+
+	if err := h.takeoverRequest(requester, host, 10*time.Millisecond); err != nil {
+		t.Fatalf("takeoverRequest() = %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if !requester.IsHost() {
+		t.Error("control was not handed over once grace period passed")
+	}
+}
@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// session scratch store key
+const videoDefaultKey = "handler/video_default"
+
+// SetDefaultVideo sets video used for the session, if it does not request one.
+func SetDefaultVideo(session types.Session, videoId string) {
+	session.SetValue(videoDefaultKey, videoId)
+}
+
+func defaultVideo(session types.Session) (string, bool) {
+	value, ok := session.Value(videoDefaultKey)
+	if !ok {
+		return "", false
+	}
+
+	videoId, ok := value.(string)
+	return videoId, ok
+}
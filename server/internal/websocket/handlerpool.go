@@ -0,0 +1,43 @@
+package websocket
+
+import "sync"
+
+// handlerPool bounds number of messages handled concurrently across all
+// connections, nil pool handles every message serially.
+type handlerPool struct {
+	slots chan struct{}
+}
+
+func newHandlerPool(size int) *handlerPool {
+	if size <= 0 {
+		return nil
+	}
+
+	return &handlerPool{
+		slots: make(chan struct{}, size),
+	}
+}
+
+// run starts fn in a new goroutine tracked by wg, if there is a free slot. It
+// returns false when the pool is exhausted, then fn must be run by the caller.
+func (p *handlerPool) run(wg *sync.WaitGroup, fn func()) bool {
+	if p == nil {
+		return false
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		return false
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() { <-p.slots }()
+
+		fn()
+	}()
+
+	return true
+}
@@ -0,0 +1,42 @@
+package websocket
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestHandlerPool(t *testing.T) {
+	var wg sync.WaitGroup
+
+	var nilPool *handlerPool
+	if nilPool.run(&wg, func() {}) {
+		t.Error("nil pool must not run handlers")
+	}
+
+	if newHandlerPool(0) != nil {
+		t.Error("pool with zero size must be nil")
+	}
+
+	pool := newHandlerPool(2)
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	for i := 0; i < 2; i++ {
+		if !pool.run(&wg, func() { started <- struct{}{}; <-release }) {
+			t.Fatalf("handler %d was not run by pool", i)
+		}
+	}
+
+	<-started
+	<-started
+	if pool.run(&wg, func() {}) {
+		t.Error("exhausted pool must not run handlers")
+	}
+
+	close(release)
+	wg.Wait()
+
+	if !pool.run(&wg, func() {}) {
+		t.Error("pool must run handlers after slots are released")
+	}
+	wg.Wait()
+}
@@ -0,0 +1,86 @@
+package websocket
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+type namedHandler struct {
+	name    string
+	handler types.WebSocketHandler
+}
+
+// handlerRegistry keeps handlers in order of registration. Handler registered
+// again with the same name replaces the previous one, e.g. on plugin reload.
+type handlerRegistry struct {
+	mu       sync.RWMutex
+	max      int
+	handlers []namedHandler
+}
+
+func newHandlerRegistry(max int) *handlerRegistry {
+	return &handlerRegistry{
+		max: max,
+	}
+}
+
+// add registers handler, it returns true if handler with the same name was
+// replaced. Handlers above configured maximum are rejected.
+func (r *handlerRegistry) add(name string, handler types.WebSocketHandler) (bool, error) {
+	if name == "" {
+		return false, types.ErrWebSocketHandlerName
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.handlers {
+		if r.handlers[i].name == name {
+			r.handlers[i].handler = handler
+			return true, nil
+		}
+	}
+
+	if err := r.limit(); err != nil {
+		return false, err
+	}
+
+	r.handlers = append(r.handlers, namedHandler{name, handler})
+	return false, nil
+}
+
+// addUnnamed registers handler that cannot be replaced, it is rejected above
+// configured maximum as well.
+func (r *handlerRegistry) addUnnamed(handler types.WebSocketHandler) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.limit(); err != nil {
+		return err
+	}
+
+	r.handlers = append(r.handlers, namedHandler{"", handler})
+	return nil
+}
+
+// limit returns error if no more handlers can be added, must be called with lock held.
+func (r *handlerRegistry) limit() error {
+	if r.max > 0 && len(r.handlers) >= r.max {
+		return fmt.Errorf("%w: %d", types.ErrWebSocketHandlerLimit, r.max)
+	}
+	return nil
+}
+
+// list returns snapshot of handlers, so that they can be called without lock.
+func (r *handlerRegistry) list() []types.WebSocketHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	handlers := make([]types.WebSocketHandler, len(r.handlers))
+	for i, h := range r.handlers {
+		handlers[i] = h.handler
+	}
+	return handlers
+}
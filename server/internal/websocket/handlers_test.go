@@ -0,0 +1,194 @@
+package websocket
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/internal/session"
+	"github.com/m1k1o/neko/server/internal/websocket/handler"
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+func testHandler(result bool) types.WebSocketHandler {
+	return func(types.Session, types.WebSocketMessage) bool {
+		return result
+	}
+}
+
+func TestHandlerRegistry(t *testing.T) {
+	r := newHandlerRegistry(2)
+
+	if _, err := r.add("", testHandler(true)); !errors.Is(err, types.ErrWebSocketHandlerName) {
+		t.Errorf("add() without name = %v, want %v", err, types.ErrWebSocketHandlerName)
+	}
+
+	if replaced, err := r.add("chat", testHandler(false)); err != nil || replaced {
+		t.Fatalf("add() = %v, %v, want false, nil", replaced, err)
+	}
+	if replaced, err := r.add("filetransfer", testHandler(false)); err != nil || replaced {
+		t.Fatalf("add() = %v, %v, want false, nil", replaced, err)
+	}
+
+	// re-registered handler replaces the previous one, even at the limit
+	if replaced, err := r.add("chat", testHandler(true)); err != nil || !replaced {
+		t.Fatalf("add() = %v, %v, want true, nil", replaced, err)
+	}
+
+	if _, err := r.add("other", testHandler(true)); !errors.Is(err, types.ErrWebSocketHandlerLimit) {
+		t.Errorf("add() above limit = %v, want %v", err, types.ErrWebSocketHandlerLimit)
+	}
+
+	handlers := r.list()
+	if len(handlers) != 2 {
+		t.Fatalf("list() returned %d handlers, want 2", len(handlers))
+	}
+
+	// order of registration is kept
+	if !handlers[0](nil, types.WebSocketMessage{}) || handlers[1](nil, types.WebSocketMessage{}) {
+		t.Error("list() did not return replaced handler at its original position")
+	}
+}
+
+func TestHandlerRegistryUnlimited(t *testing.T) {
+	r := newHandlerRegistry(0)
+
+	for _, name := range []string{"a", "b", "c"} {
+		if _, err := r.add(name, testHandler(false)); err != nil {
+			t.Fatalf("add(%q) = %v", name, err)
+		}
+	}
+
+	if n := len(r.list()); n != 3 {
+		t.Errorf("list() returned %d handlers, want 3", n)
+	}
+}
+
+func TestHandlerRegistryUnnamed(t *testing.T) {
+	r := newHandlerRegistry(2)
+
+	if _, err := r.add("chat", testHandler(false)); err != nil {
+		t.Fatalf("add() = %v", err)
+	}
+
+	// unnamed handlers are never replaced, but count towards the limit
+	if err := r.addUnnamed(testHandler(true)); err != nil {
+		t.Fatalf("addUnnamed() = %v", err)
+	}
+	if err := r.addUnnamed(testHandler(true)); !errors.Is(err, types.ErrWebSocketHandlerLimit) {
+		t.Errorf("addUnnamed() above limit = %v, want %v", err, types.ErrWebSocketHandlerLimit)
+	}
+
+	// named handler can still be replaced at the limit
+	if replaced, err := r.add("chat", testHandler(true)); err != nil || !replaced {
+		t.Errorf("add() = %v, %v, want true, nil", replaced, err)
+	}
+
+	handlers := r.list()
+	if len(handlers) != 2 || !handlers[0](nil, types.WebSocketMessage{}) || !handlers[1](nil, types.WebSocketMessage{}) {
+		t.Errorf("list() returned unexpected handlers")
+	}
+}
+
+func newDispatchManager(t *testing.T, timeout time.Duration, h types.WebSocketHandler) *WebSocketManagerCtx {
+	t.Helper()
+
+	manager := &WebSocketManagerCtx{
+		config:   &config.WebSocket{HandlerTimeout: timeout},
+		handler:  handler.New(session.New(&config.Session{}), nil, nil, nil),
+		handlers: newHandlerRegistry(0),
+	}
+	if _, err := manager.handlers.add("test", h); err != nil {
+		t.Fatalf("could not add handler %s", err.Error())
+	}
+
+	return manager
+}
+
+func TestDispatchInTime(t *testing.T) {
+	manager := newDispatchManager(t, time.Second, testHandler(false))
+
+	data := queuedMessage{WebSocketMessage: types.WebSocketMessage{Event: "test/event"}}
+	if handled, inTime := manager.dispatch(zerolog.Nop(), nil, data); handled || !inTime {
+		t.Errorf("dispatch() = %v, %v, want false, true", handled, inTime)
+	}
+}
+
+func TestDispatchTimeout(t *testing.T) {
+	release := make(chan struct{})
+	manager := newDispatchManager(t, 20*time.Millisecond, func(types.Session, types.WebSocketMessage) bool {
+		<-release
+		return true
+	})
+
+	// stuck handler must not block dispatching of following messages
+	start := time.Now()
+	data := queuedMessage{WebSocketMessage: types.WebSocketMessage{Event: "test/event"}}
+	if handled, inTime := manager.dispatch(zerolog.Nop(), nil, data); !handled || inTime {
+		t.Errorf("dispatch() = %v, %v, want true, false", handled, inTime)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("dispatch() returned after %s, want around timeout", elapsed)
+	}
+
+	// abandoned handler is awaited on shutdown
+	close(release)
+	manager.wg.Wait()
+}
+
+type unhandledTestPeer struct {
+	errors    []message.SystemError
+	destroyed string
+}
+
+func (p *unhandledTestPeer) Send(ev string, payload any) {
+	if err, ok := payload.(message.SystemError); ok && ev == event.SYSTEM_ERROR {
+		p.errors = append(p.errors, err)
+	}
+}
+
+func (p *unhandledTestPeer) Ping() error           { return nil }
+func (p *unhandledTestPeer) Destroy(reason string) { p.destroyed = reason }
+
+func TestUnhandledMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    config.WebSocket
+		replies   int
+		destroyed bool
+	}{
+		{"only logged", config.WebSocket{}, 0, false},
+		{"reply", config.WebSocket{UnhandledReply: true}, 3, false},
+		{"close", config.WebSocket{UnhandledMax: 3}, 0, true},
+		{"below max", config.WebSocket{UnhandledMax: 4}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &WebSocketManagerCtx{config: &tt.config}
+			peer := &unhandledTestPeer{}
+
+			data := types.WebSocketMessage{Event: "unknown/event"}
+			for count := 1; count <= 3; count++ {
+				manager.unhandledMessage(zerolog.Nop(), peer, data, count)
+			}
+
+			if len(peer.errors) != tt.replies {
+				t.Errorf("received %d errors, want %d", len(peer.errors), tt.replies)
+			}
+			if tt.replies > 0 {
+				if err := peer.errors[tt.replies-1]; err.Kind != "unknown_event" || err.Count != 3 {
+					t.Errorf("unexpected error %+v", err)
+				}
+			}
+			if destroyed := peer.destroyed != ""; destroyed != tt.destroyed {
+				t.Errorf("destroyed = %v, want %v", destroyed, tt.destroyed)
+			}
+		})
+	}
+}
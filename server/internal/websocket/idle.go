@@ -0,0 +1,156 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/m1k1o/neko/server/internal/input"
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// how often sessions are checked for inactivity
+const idleCheckInterval = 10 * time.Second
+
+// idleTracker remembers since when sessions are inactive. Hosts are active
+// only while they send input, viewers while they receive media.
+type idleTracker struct {
+	sessions map[string]idleState
+}
+
+type idleState struct {
+	host       bool
+	lastActive time.Time
+}
+
+func newIdleTracker() *idleTracker {
+	return &idleTracker{
+		sessions: map[string]idleState{},
+	}
+}
+
+// check records activity of the session and returns for how long it has been
+// inactive. Sessions becoming or ceasing to be host start as active.
+func (t *idleTracker) check(id string, host, active bool, lastInput, now time.Time) time.Duration {
+	state, ok := t.sessions[id]
+	if !ok || state.host != host {
+		state = idleState{host: host, lastActive: now}
+	}
+
+	if active {
+		state.lastActive = now
+	}
+
+	if host && lastInput.After(state.lastActive) {
+		state.lastActive = lastInput
+	}
+
+	t.sessions[id] = state
+	return now.Sub(state.lastActive)
+}
+
+func (t *idleTracker) forget(id string) {
+	delete(t.sessions, id)
+}
+
+// receivingMedia tells whether the session receives any audio or video.
+func receivingMedia(session types.Session) bool {
+	if !session.State().IsWatching {
+		return false
+	}
+
+	peer := session.GetWebRTCPeer()
+	if peer == nil || peer.Paused() {
+		return false
+	}
+
+	return !peer.Video().Disabled || !peer.Audio().Disabled
+}
+
+func (manager *WebSocketManagerCtx) startIdleMonitor() {
+	if manager.config.IdleViewerTimeout <= 0 && (manager.config.IdleHostTimeout <= 0 || manager.config.IdleHostExempt) {
+		return
+	}
+
+	manager.logger.Info().
+		Dur("viewer_timeout", manager.config.IdleViewerTimeout).
+		Dur("host_timeout", manager.config.IdleHostTimeout).
+		Bool("host_exempt", manager.config.IdleHostExempt).
+		Msg("starting idle monitor")
+
+	manager.wg.Add(1)
+	go func() {
+		defer manager.wg.Done()
+
+		ticker := time.NewTicker(idleCheckInterval)
+		defer ticker.Stop()
+
+		tracker := newIdleTracker()
+		for {
+			select {
+			case <-manager.shutdown:
+				return
+			case <-ticker.C:
+				manager.checkIdle(tracker, time.Now())
+			}
+		}
+	}()
+}
+
+// checkIdle disconnects sessions inactive for longer than their timeout.
+func (manager *WebSocketManagerCtx) checkIdle(tracker *idleTracker, now time.Time) {
+	connected := map[string]struct{}{}
+
+	for _, session := range manager.sessions.List() {
+		if !session.State().IsConnected {
+			continue
+		}
+
+		id := session.ID()
+		connected[id] = struct{}{}
+
+		host := session.IsHost()
+		if host && manager.config.IdleHostExempt {
+			tracker.forget(id)
+			continue
+		}
+
+		// without host timeout, hosts are treated as viewers
+		timeout := manager.config.IdleViewerTimeout
+		hostPolicy := host && manager.config.IdleHostTimeout > 0
+		if hostPolicy {
+			timeout = manager.config.IdleHostTimeout
+		}
+
+		if timeout <= 0 {
+			tracker.forget(id)
+			continue
+		}
+
+		var idle time.Duration
+		if hostPolicy {
+			lastInput, _ := input.LastInput(session)
+			idle = tracker.check(id, true, false, lastInput, now)
+		} else {
+			idle = tracker.check(id, false, receivingMedia(session), time.Time{}, now)
+		}
+
+		if idle < timeout {
+			continue
+		}
+
+		manager.logger.Info().
+			Str("session_id", id).
+			Bool("is_host", host).
+			Dur("idle", idle).
+			Msg("disconnecting inactive session")
+
+		tracker.forget(id)
+		session.DestroyWebSocketPeer("inactivity timeout")
+	}
+
+	// sessions that disconnected start as active when they connect again
+	for id := range tracker.sessions {
+		if _, ok := connected[id]; !ok {
+			tracker.forget(id)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdleTrackerViewer(t *testing.T) {
+	tracker := newIdleTracker()
+	now := time.Now()
+
+	if idle := tracker.check("a", false, false, time.Time{}, now); idle != 0 {
+		t.Errorf("new session idle = %v, want 0", idle)
+	}
+
+	if idle := tracker.check("a", false, false, time.Time{}, now.Add(time.Minute)); idle != time.Minute {
+		t.Errorf("idle = %v, want 1m", idle)
+	}
+
+	// receiving media again
+	if idle := tracker.check("a", false, true, time.Time{}, now.Add(2*time.Minute)); idle != 0 {
+		t.Errorf("active session idle = %v, want 0", idle)
+	}
+}
+
+func TestIdleTrackerHost(t *testing.T) {
+	tracker := newIdleTracker()
+	now := time.Now()
+
+	tracker.check("a", true, false, time.Time{}, now)
+
+	// input resets inactivity
+	lastInput := now.Add(30 * time.Second)
+	if idle := tracker.check("a", true, false, lastInput, now.Add(time.Minute)); idle != 30*time.Second {
+		t.Errorf("idle = %v, want 30s", idle)
+	}
+
+	// input from before becoming host does not count
+	tracker.check("b", false, false, time.Time{}, now)
+	if idle := tracker.check("b", true, false, now.Add(-time.Hour), now.Add(time.Minute)); idle != 0 {
+		t.Errorf("new host idle = %v, want 0", idle)
+	}
+
+	tracker.forget("a")
+	if idle := tracker.check("a", true, false, time.Time{}, now.Add(2*time.Minute)); idle != 0 {
+		t.Errorf("forgotten session idle = %v, want 0", idle)
+	}
+}
@@ -0,0 +1,107 @@
+package websocket
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/m1k1o/neko/server/pkg/utils"
+)
+
+// ipLimiter caps concurrent connections from a single client IP.
+type ipLimiter struct {
+	max       int
+	trusted   []*net.IPNet
+	allowlist []*net.IPNet
+
+	mu    sync.Mutex
+	conns map[string]int
+}
+
+func newIPLimiter(max int, trusted, allowlist []*net.IPNet) *ipLimiter {
+	return &ipLimiter{
+		max:       max,
+		trusted:   trusted,
+		allowlist: allowlist,
+		conns:     map[string]int{},
+	}
+}
+
+func ipInNets(ip net.IP, nets []*net.IPNet) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns IP of the client. Forwarded headers are used only when the
+// request comes from a trusted proxy, the rightmost untrusted address of
+// X-Forwarded-For is taken, so that clients cannot spoof it. Proxy is checked
+// by peer address, RemoteAddr may already be taken from forwarded headers.
+func (l *ipLimiter) clientIP(r *http.Request) net.IP {
+	host := utils.PeerAddr(r)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !ipInNets(ip, l.trusted) {
+		return ip
+	}
+
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		addrs := strings.Split(xff, ",")
+		for i := len(addrs) - 1; i >= 0; i-- {
+			forwarded := net.ParseIP(strings.TrimSpace(addrs[i]))
+			if forwarded == nil {
+				break
+			}
+
+			ip = forwarded
+			if !ipInNets(ip, l.trusted) {
+				break
+			}
+		}
+		return ip
+	}
+
+	if forwarded := net.ParseIP(r.Header.Get("X-Real-IP")); forwarded != nil {
+		return forwarded
+	}
+
+	return ip
+}
+
+// acquire accounts new connection from the IP, false if the limit is reached.
+// Returned function must be called when the connection ends.
+func (l *ipLimiter) acquire(ip net.IP) (func(), bool) {
+	if l.max <= 0 || ip == nil || ipInNets(ip, l.allowlist) {
+		return func() {}, true
+	}
+
+	key := ip.String()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conns[key] >= l.max {
+		return nil, false
+	}
+	l.conns[key]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+
+			l.conns[key]--
+			if l.conns[key] <= 0 {
+				delete(l.conns, key)
+			}
+		})
+	}, true
+}
@@ -0,0 +1,134 @@
+package websocket
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/middleware"
+
+	"github.com/m1k1o/neko/server/pkg/utils"
+)
+
+func mustNets(t *testing.T, cidrs ...string) []*net.IPNet {
+	nets := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+func TestIPLimiterClientIP(t *testing.T) {
+	l := newIPLimiter(1, mustNets(t, "10.0.0.0/8"), nil)
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    string
+		realIP string
+		want   string
+	}{
+		{"direct", "1.2.3.4:1000", "", "", "1.2.3.4"},
+		{"untrusted forwarded", "1.2.3.4:1000", "5.6.7.8", "", "1.2.3.4"},
+		{"trusted forwarded", "10.0.0.1:1000", "5.6.7.8", "", "5.6.7.8"},
+		{"spoofed chain", "10.0.0.1:1000", "9.9.9.9, 5.6.7.8, 10.0.0.2", "", "5.6.7.8"},
+		{"trusted real ip", "10.0.0.1:1000", "", "5.6.7.8", "5.6.7.8"},
+		{"trusted without headers", "10.0.0.1:1000", "", "", "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &http.Request{RemoteAddr: tt.remote, Header: http.Header{}}
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			if got := l.clientIP(r).String(); got != tt.want {
+				t.Errorf("clientIP() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// Behind server.proxy, RemoteAddr is rewritten from headers before the limiter
+// sees the request, the proxy must be checked by the peer address.
+func TestIPLimiterClientIPSpoofed(t *testing.T) {
+	l := newIPLimiter(1, mustNets(t, "10.0.0.0/8"), nil)
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    string
+		realIP string
+		want   string
+	}{
+		{"spoofed real ip", "1.2.3.4:1000", "", "10.0.0.1", "1.2.3.4"},
+		{"spoofed forwarded", "1.2.3.4:1000", "5.6.7.8", "10.0.0.1", "1.2.3.4"},
+		{"trusted proxy", "10.0.0.1:1000", "5.6.7.8", "", "5.6.7.8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got net.IP
+			handler := utils.WithPeerAddr(middleware.RealIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = l.clientIP(r)
+			})))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/ws", nil)
+			r.RemoteAddr = tt.remote
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+			if got.String() != tt.want {
+				t.Errorf("clientIP() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPLimiterAcquire(t *testing.T) {
+	l := newIPLimiter(2, nil, mustNets(t, "192.168.0.0/16"))
+	ip := net.ParseIP("1.2.3.4")
+
+	release1, ok := l.acquire(ip)
+	if !ok {
+		t.Fatal("first connection rejected")
+	}
+	if _, ok := l.acquire(ip); !ok {
+		t.Fatal("second connection rejected")
+	}
+	if _, ok := l.acquire(ip); ok {
+		t.Fatal("third connection accepted")
+	}
+
+	// released slot can be reused, releasing twice has no effect
+	release1()
+	release1()
+	if _, ok := l.acquire(ip); !ok {
+		t.Fatal("connection after release rejected")
+	}
+	if _, ok := l.acquire(ip); ok {
+		t.Fatal("connection over limit accepted")
+	}
+
+	// allowlisted IPs are not limited
+	allowed := net.ParseIP("192.168.1.1")
+	for i := 0; i < 5; i++ {
+		if _, ok := l.acquire(allowed); !ok {
+			t.Fatal("allowlisted connection rejected")
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/pkg/auth"
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/utils"
+)
+
+// buffered lifecycle events per subscriber, slower subscribers miss events
+const lifecycleBufferSize = 64
+
+// keep alive comment sent to idle streams, so that proxies do not close them
+const lifecycleKeepAlive = 15 * time.Second
+
+type lifecycleBus struct {
+	logger zerolog.Logger
+
+	subscribers   map[chan types.LifecycleEvent]struct{}
+	subscribersMu sync.Mutex
+}
+
+func newLifecycleBus(logger zerolog.Logger) *lifecycleBus {
+	return &lifecycleBus{
+		logger:      logger.With().Str("submodule", "lifecycle").Logger(),
+		subscribers: map[chan types.LifecycleEvent]struct{}{},
+	}
+}
+
+func (bus *lifecycleBus) subscribe() (<-chan types.LifecycleEvent, func()) {
+	ch := make(chan types.LifecycleEvent, lifecycleBufferSize)
+
+	bus.subscribersMu.Lock()
+	bus.subscribers[ch] = struct{}{}
+	bus.subscribersMu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			bus.subscribersMu.Lock()
+			delete(bus.subscribers, ch)
+			bus.subscribersMu.Unlock()
+		})
+	}
+}
+
+func (bus *lifecycleBus) publish(typ string, session types.Session, data any) {
+	bus.subscribersMu.Lock()
+	defer bus.subscribersMu.Unlock()
+
+	if len(bus.subscribers) == 0 {
+		return
+	}
+
+	event := types.LifecycleEvent{
+		Type:      typ,
+		SessionID: session.ID(),
+		Time:      time.Now(),
+		Data:      data,
+	}
+
+	for ch := range bus.subscribers {
+		select {
+		case ch <- event:
+		default:
+			bus.logger.Warn().Str("type", typ).Msg("lifecycle subscriber is too slow, dropping event")
+		}
+	}
+}
+
+// SubscribeLifecycle returns channel receiving session lifecycle events and
+// function that must be called to unsubscribe.
+func (manager *WebSocketManagerCtx) SubscribeLifecycle() (<-chan types.LifecycleEvent, func()) {
+	return manager.lifecycle.subscribe()
+}
+
+func (manager *WebSocketManagerCtx) LifecycleRoute(r types.Router) {
+	r.With(auth.AdminsOnly).Get("/", manager.lifecycleStream)
+}
+
+// lifecycleStream streams lifecycle events as server-sent events.
+func (manager *WebSocketManagerCtx) lifecycleStream(w http.ResponseWriter, r *http.Request) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return utils.HttpInternalServerError().WithInternalMsg("streaming is not supported")
+	}
+
+	events, unsubscribe := manager.SubscribeLifecycle()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// disable response buffering in nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(lifecycleKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-manager.shutdown:
+			return nil
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				manager.logger.Err(err).Str("type", event.Type).Msg("could not serialize lifecycle event")
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return nil
+			}
+		}
+
+		flusher.Flush()
+	}
+}
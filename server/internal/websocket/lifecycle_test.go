@@ -0,0 +1,106 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+type lifecycleTestSession struct {
+	types.Session
+	id string
+}
+
+func (s lifecycleTestSession) ID() string { return s.id }
+
+func TestLifecycleBus(t *testing.T) {
+	bus := newLifecycleBus(zerolog.Nop())
+
+	// nobody is subscribed, event is discarded
+	bus.publish("session_created", lifecycleTestSession{id: "a"}, nil)
+
+	events, unsubscribe := bus.subscribe()
+	bus.publish("session_connected", lifecycleTestSession{id: "a"}, nil)
+
+	select {
+	case event := <-events:
+		if event.Type != "session_connected" || event.SessionID != "a" {
+			t.Errorf("received %+v, want session_connected of a", event)
+		}
+	default:
+		t.Fatal("subscriber did not receive event")
+	}
+
+	// slow subscriber misses events instead of blocking publisher
+	for i := 0; i < lifecycleBufferSize+1; i++ {
+		bus.publish("session_state_changed", lifecycleTestSession{id: "a"}, nil)
+	}
+	if n := len(events); n != lifecycleBufferSize {
+		t.Errorf("buffered %d events, want %d", n, lifecycleBufferSize)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	if n := len(bus.subscribers); n != 0 {
+		t.Errorf("%d subscribers after unsubscribe, want 0", n)
+	}
+}
+
+func TestLifecycleStream(t *testing.T) {
+	manager := &WebSocketManagerCtx{
+		logger:    zerolog.Nop(),
+		shutdown:  make(chan struct{}),
+		lifecycle: newLifecycleBus(zerolog.Nop()),
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = manager.lifecycleStream(w, r)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	// headers are flushed only after subscribing
+	manager.lifecycle.publish("session_connected", lifecycleTestSession{id: "a"}, nil)
+
+	reader := bufio.NewReader(res.Body)
+	line, err := reader.ReadString('\n')
+	if err != nil || line != "event: session_connected\n" {
+		t.Fatalf("read %q, %v, want event line", line, err)
+	}
+	line, err = reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "data: {") || !strings.Contains(line, `"session_id":"a"`) {
+		t.Fatalf("read %q, %v, want data line", line, err)
+	}
+
+	// stream ends on shutdown
+	close(manager.shutdown)
+	for {
+		if _, err := reader.ReadString('\n'); err != nil {
+			break
+		}
+	}
+	if ctx.Err() != nil {
+		t.Error("stream did not end on shutdown")
+	}
+}
@@ -3,14 +3,19 @@ package websocket
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"github.com/m1k1o/neko/server/internal/config"
 	"github.com/m1k1o/neko/server/internal/websocket/handler"
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/types/event"
@@ -21,11 +26,24 @@ import (
 // send pings to peer with this period - must be less than pongWait
 const pingPeriod = 10 * time.Second
 
+// connection was not established within handshake timeout
+var errHandshakeTimeout = errors.New("handshake timeout")
+
 // period for sending inactive cursor messages
 const inactiveCursorsPeriod = 750 * time.Millisecond
 
-// maximum payload length for logging
-const maxPayloadLogLength = 10_000
+// maximum number of received messages waiting for handler
+const handlerQueueSize = 128
+
+// header carrying reconnect token of the session to resume
+const reconnectTokenHeader = "X-Reconnect-Token"
+
+// queuedMessage is received message waiting for handler, input holds payload
+// decoded from binary frame that is passed to the handler instead of JSON.
+type queuedMessage struct {
+	types.WebSocketMessage
+	input any
+}
 
 // events that are not logged in debug mode
 var nologEvents = []string{
@@ -36,6 +54,8 @@ var nologEvents = []string{
 	event.CLIENT_HEARTBEAT,
 	// don't log every cursor update
 	event.SESSION_CURSORS,
+	// don't log every spectator count update
+	event.SESSION_SPECTATORS,
 }
 
 func New(
@@ -43,37 +63,110 @@ func New(
 	desktop types.DesktopManager,
 	capture types.CaptureManager,
 	webrtc types.WebRTCManager,
+	errors types.ErrorBus,
+	config *config.WebSocket,
 ) *WebSocketManagerCtx {
 	logger := log.With().Str("module", "websocket").Logger()
 
 	return &WebSocketManagerCtx{
 		logger:   logger,
+		config:   config,
 		shutdown: make(chan struct{}),
 		sessions: sessions,
 		desktop:  desktop,
+		capture:  capture,
+		webrtc:   webrtc,
+		errors:   errors,
 		handler:  handler.New(sessions, desktop, capture, webrtc),
-		handlers: []types.WebSocketHandler{},
+		handlers: newHandlerRegistry(config.HandlerMax),
+		pool:     newHandlerPool(config.HandlerConcurrency),
+
+		connections: map[string]*activeConnection{},
+		lifecycle:   newLifecycleBus(logger),
+		ipLimit:     newIPLimiter(config.IPLimitMax, config.IPLimitTrustedProxies, config.IPLimitAllowlist),
+
+		flushInactiveCursors: make(chan struct{}, 1),
+		inactiveCursorsDuration: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:      "inactive_cursors_duration_seconds",
+			Namespace: "neko",
+			Subsystem: "websocket",
+			Help:      "Time taken to serialize and broadcast inactive cursors in a single tick.",
+			Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, .75, 1, 2.5},
+		}),
+		inactiveCursorsOverruns: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "inactive_cursors_overruns_total",
+			Namespace: "neko",
+			Subsystem: "websocket",
+			Help:      "Total number of inactive cursors ticks that took longer than the tick period.",
+		}),
+
+		flappingCounter: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "flapping_total",
+			Namespace: "neko",
+			Subsystem: "session",
+			Help:      "Total number of times a session was detected reconnecting too often.",
+		}),
+
+		sendMetrics: newSendMetrics(),
 	}
 }
 
 type WebSocketManagerCtx struct {
 	logger   zerolog.Logger
+	config   *config.WebSocket
 	wg       sync.WaitGroup
 	shutdown chan struct{}
 	sessions types.SessionManager
 	desktop  types.DesktopManager
+	capture  types.CaptureManager
+	webrtc   types.WebRTCManager
+	errors   types.ErrorBus
 	handler  *handler.MessageHandlerCtx
-	handlers []types.WebSocketHandler
+	handlers *handlerRegistry
+	pool     *handlerPool
+	ipLimit  *ipLimiter
 
 	shutdownInactiveCursors chan struct{}
+	flushInactiveCursors    chan struct{}
+	inactiveCursorsDuration prometheus.Histogram
+	inactiveCursorsOverruns prometheus.Counter
+
+	flappingCounter prometheus.Counter
+
+	sendMetrics *sendMetrics
+
+	clipboardSync *utils.Throttle
+	// coalesces spectator count updates, nil if disabled
+	spectators *utils.Throttle
+
+	connections   map[string]*activeConnection
+	connectionsMu sync.Mutex
+
+	lifecycle *lifecycleBus
+
+	// shows privacy screen after there was no host for a while
+	privacyTimer *time.Timer
+	privacyMu    sync.Mutex
+
+	// limits forwarded desktop notifications
+	notifications notificationLimiter
+
+	// screen size is being changed, capture is not ready meanwhile
+	resizing atomic.Bool
 }
 
 func (manager *WebSocketManagerCtx) Start() {
+	if manager.config.Spectators {
+		manager.spectators = utils.NewThrottle(manager.config.SpectatorsInterval, manager.sendSpectators)
+	}
+
 	manager.sessions.OnCreated(func(session types.Session) {
 		err := manager.handler.SessionCreated(session)
 		manager.logger.Err(err).
 			Str("session_id", session.ID()).
 			Msg("session created")
+
+		manager.lifecycle.publish("session_created", session, nil)
 	})
 
 	manager.sessions.OnDeleted(func(session types.Session) {
@@ -81,6 +174,11 @@ func (manager *WebSocketManagerCtx) Start() {
 		manager.logger.Err(err).
 			Str("session_id", session.ID()).
 			Msg("session deleted")
+
+		manager.lifecycle.publish("session_deleted", session, nil)
+		manager.spectatorsChanged()
+		manager.sendMetrics.forget(session.ID())
+		manager.webrtc.ForgetSession(session)
 	})
 
 	manager.sessions.OnConnected(func(session types.Session) {
@@ -88,6 +186,9 @@ func (manager *WebSocketManagerCtx) Start() {
 		manager.logger.Err(err).
 			Str("session_id", session.ID()).
 			Msg("session connected")
+
+		manager.lifecycle.publish("session_connected", session, nil)
+		manager.spectatorsChanged()
 	})
 
 	manager.sessions.OnDisconnected(func(session types.Session) {
@@ -95,6 +196,14 @@ func (manager *WebSocketManagerCtx) Start() {
 		manager.logger.Err(err).
 			Str("session_id", session.ID()).
 			Msg("session disconnected")
+
+		manager.lifecycle.publish("session_disconnected", session, nil)
+		manager.spectatorsChanged()
+
+		// cursor of disconnected session is removed without waiting for next tick
+		if manager.sessions.Settings().InactiveCursors {
+			manager.flushCursors()
+		}
 	})
 
 	manager.sessions.OnProfileChanged(func(session types.Session, new, old types.MemberProfile) {
@@ -104,6 +213,9 @@ func (manager *WebSocketManagerCtx) Start() {
 			Interface("new", new).
 			Interface("old", old).
 			Msg("session profile changed")
+
+		manager.lifecycle.publish("session_profile_changed", session, new)
+		manager.spectatorsChanged()
 	})
 
 	manager.sessions.OnStateChanged(func(session types.Session) {
@@ -111,12 +223,16 @@ func (manager *WebSocketManagerCtx) Start() {
 		manager.logger.Err(err).
 			Str("session_id", session.ID()).
 			Msg("session state changed")
+
+		manager.lifecycle.publish("session_state_changed", session, session.State())
+		manager.spectatorsChanged()
 	})
 
 	manager.sessions.OnHostChanged(func(session, host types.Session) {
 		payload := message.ControlHost{
 			ID:      session.ID(),
 			HasHost: host != nil,
+			Locked:  manager.sessions.HostLocked(),
 		}
 
 		if payload.HasHost {
@@ -124,12 +240,32 @@ func (manager *WebSocketManagerCtx) Start() {
 		}
 
 		manager.sessions.Broadcast(event.CONTROL_HOST, payload)
+		manager.privacyScreenHostChanged(payload.HasHost)
 
 		manager.logger.Info().
 			Str("session_id", session.ID()).
 			Bool("has_host", payload.HasHost).
 			Str("host_id", payload.HostID).
 			Msg("session host changed")
+
+		manager.lifecycle.publish("host_changed", session, payload)
+	})
+
+	manager.sessions.OnHostLockChanged(func(session types.Session, locked bool) {
+		manager.sessions.Broadcast(event.CONTROL_LOCKED, message.ControlLocked{
+			ID:     session.ID(),
+			Locked: locked,
+		})
+
+		manager.logger.Info().
+			Str("session_id", session.ID()).
+			Bool("locked", locked).
+			Msg("session host lock changed")
+
+		manager.lifecycle.publish("host_lock_changed", session, message.ControlLocked{
+			ID:     session.ID(),
+			Locked: locked,
+		})
 	})
 
 	manager.sessions.OnSettingsChanged(func(session types.Session, new, old types.Settings) {
@@ -153,61 +289,190 @@ func (manager *WebSocketManagerCtx) Start() {
 			Interface("new", new).
 			Interface("old", old).
 			Msg("settings changed")
-	})
 
-	manager.desktop.OnClipboardUpdated(func() {
-		host, hasHost := manager.sessions.GetHost()
-		if !hasHost || !host.Profile().CanAccessClipboard {
-			return
-		}
+		manager.lifecycle.publish("settings_changed", session, new)
+	})
 
-		manager.logger.Info().Msg("sync clipboard")
+	manager.sessions.OnFlapping(func(session types.Session, stats types.ReconnectStats) {
+		manager.flappingCounter.Inc()
 
-		data, err := manager.desktop.ClipboardGetText()
-		if err != nil {
-			manager.logger.Err(err).Msg("could not get clipboard content")
-			return
+		payload := message.SessionFlapping{
+			ID:         session.ID(),
+			Reconnects: stats,
 		}
 
-		host.Send(
-			event.CLIPBOARD_UPDATED,
-			message.ClipboardData{
-				Text: data.Text,
-				// TODO: Send HTML?
-			})
+		manager.sessions.AdminBroadcast(event.SESSION_FLAPPING, payload)
+		manager.lifecycle.publish("session_flapping", session, stats)
+	})
+
+	manager.errors.OnError(func(err types.SubsystemError) {
+		manager.sessions.AdminBroadcast(event.SYSTEM_ERROR, message.SystemError(err))
 	})
 
+	// rapid clipboard changes are coalesced, so that only the latest is synced
+	manager.clipboardSync = utils.NewThrottle(manager.config.ClipboardSyncInterval, manager.syncClipboard)
+	manager.desktop.OnClipboardUpdated(manager.clipboardSync.Trigger)
+
 	if manager.desktop.IsFileChooserDialogEnabled() {
 		manager.fileChooserDialogEvents()
 	}
 
+	manager.desktop.OnNotification(manager.forwardNotification)
+
+	manager.startReadiness()
+
+	// recognized shortcuts are sent to the host whose keys they were
+	manager.desktop.OnShortcut(func(name string) {
+		if host, ok := manager.sessions.GetHost(); ok {
+			host.Send(event.CONTROL_SHORTCUT, message.ControlShortcut{Name: name})
+		}
+	})
+
+	// region is reset on screen size change, clients must not expect it anymore
+	manager.capture.OnVideoRegionReset(func() {
+		manager.sessions.Broadcast(event.SCREEN_REGION_UPDATED, message.ScreenRegionUpdate{})
+	})
+
+	// status changes by admins as well as failures of the broadcast
+	manager.capture.Broadcast().OnStatusChange(func(status types.BroadcastStatus) {
+		manager.sessions.AdminBroadcast(event.BROADCAST_STATUS, message.BroadcastStatus(status))
+	})
+
 	if manager.sessions.Settings().InactiveCursors {
 		manager.startInactiveCursors()
 	}
 
+	manager.startIdleMonitor()
+
+	// nobody is controlling the desktop yet
+	if _, ok := manager.sessions.GetHost(); !ok {
+		manager.privacyScreenHostChanged(false)
+	}
+
 	manager.logger.Info().Msg("websocket starting")
 }
 
+func (manager *WebSocketManagerCtx) syncClipboard() {
+	host, hasHost := manager.sessions.GetHost()
+	if !hasHost || !host.Profile().CanAccessClipboard || host.FeatureDisabled(types.FeatureClipboard) {
+		return
+	}
+
+	manager.logger.Info().Msg("sync clipboard")
+
+	image := manager.clipboardImage()
+
+	// clipboard holding only an image has no text
+	data, err := manager.desktop.ClipboardGetText()
+	if err != nil && image == nil {
+		manager.logger.Err(err).Msg("could not get clipboard content")
+		manager.errors.Report("websocket", "clipboard_get", host, err)
+		return
+	}
+
+	payload := message.ClipboardData{
+		Image: image,
+		// TODO: Send HTML?
+	}
+	if err == nil {
+		payload.Text = data.Text
+	}
+
+	host.Send(event.CLIPBOARD_UPDATED, payload)
+}
+
+// clipboardImage returns PNG image held by clipboard, if image sync is enabled.
+// Images larger than configured maximum are not returned.
+func (manager *WebSocketManagerCtx) clipboardImage() *message.ClipboardImage {
+	maxSize := manager.config.ClipboardImageMaxSize
+	if maxSize <= 0 {
+		return nil
+	}
+
+	image, err := manager.desktop.ClipboardGetImage()
+	if err != nil {
+		// empty clipboard has no targets, text sync reports it
+		manager.logger.Debug().Err(err).Msg("could not get clipboard image")
+		return nil
+	}
+
+	if image == nil {
+		return nil
+	}
+
+	if len(image.Data) > maxSize {
+		manager.logger.Warn().
+			Str("mime", image.Mime).
+			Int("size", len(image.Data)).
+			Int("max_size", maxSize).
+			Msg("clipboard image is too large, not syncing it")
+		return nil
+	}
+
+	return &message.ClipboardImage{
+		Mime: image.Mime,
+		Data: image.Data,
+	}
+}
+
 func (manager *WebSocketManagerCtx) Shutdown() error {
 	manager.logger.Info().Msg("shutdown")
 	close(manager.shutdown)
 	manager.stopInactiveCursors()
+	manager.stopPrivacyScreen()
+	if manager.clipboardSync != nil {
+		manager.clipboardSync.Stop()
+	}
 	manager.wg.Wait()
 	return nil
 }
 
+// AddHandler registers handler without a name, it cannot be replaced. Handlers above
+// configured maximum are rejected, which is only logged.
 func (manager *WebSocketManagerCtx) AddHandler(handler types.WebSocketHandler) {
-	manager.handlers = append(manager.handlers, handler)
+	if err := manager.handlers.addUnnamed(handler); err != nil {
+		manager.logger.Warn().Err(err).Msg("rejected websocket handler")
+	}
+}
+
+// AddNamedHandler registers handler, that replaces previous handler with the same name.
+func (manager *WebSocketManagerCtx) AddNamedHandler(name string, handler types.WebSocketHandler) error {
+	replaced, err := manager.handlers.add(name, handler)
+	if err != nil {
+		manager.logger.Warn().Err(err).Str("name", name).Msg("rejected websocket handler")
+		return err
+	}
+
+	if replaced {
+		manager.logger.Info().Str("name", name).Msg("replaced websocket handler")
+	}
+
+	return nil
 }
 
 func (manager *WebSocketManagerCtx) Upgrade(checkOrigin types.CheckOrigin) types.RouterHandler {
 	return func(w http.ResponseWriter, r *http.Request) error {
+		ip := manager.ipLimit.clientIP(r)
+		release, ok := manager.ipLimit.acquire(ip)
+		if !ok {
+			manager.logger.Warn().Str("ip", ip.String()).Msg("too many connections from IP")
+			return utils.HttpError(http.StatusTooManyRequests, "too many connections from this IP")
+		}
+		// connection is handled synchronously, it has ended once we return
+		defer release()
+
 		upgrader := websocket.Upgrader{
-			CheckOrigin: checkOrigin,
+			CheckOrigin:      checkOrigin,
+			HandshakeTimeout: manager.config.HandshakeTimeout,
 			// Do not return any error while handshake
 			Error: func(w http.ResponseWriter, r *http.Request, status int, reason error) {},
 		}
 
+		// binary input is opt-in, clients not requesting it use JSON only
+		if manager.config.BinaryInput {
+			upgrader.Subprotocols = []string{binaryInputProtocol}
+		}
+
 		connection, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			return utils.HttpBadRequest().WithInternalErr(err)
@@ -220,10 +485,39 @@ func (manager *WebSocketManagerCtx) Upgrade(checkOrigin types.CheckOrigin) types
 }
 
 func (manager *WebSocketManagerCtx) connect(connection *websocket.Conn, r *http.Request) {
-	session, err := manager.sessions.Authenticate(r)
+	var session types.Session
+	var err error
+
+	// connections made before the desktop is ready would get incomplete state
+	if !manager.admitStartup() {
+		manager.logger.Warn().Str("mode", manager.config.StartupMode).Msg("desktop is not ready, rejecting connection")
+		newPeer(manager.logger, manager.config, connection, "", nil).Destroy(startupReason)
+		return
+	}
+
+	// stalled clients must not hold the connection before it is established
+	var deadline time.Time
+	if timeout := manager.config.HandshakeTimeout; timeout > 0 {
+		deadline = time.Now().Add(timeout)
+		_ = connection.SetReadDeadline(deadline)
+		_ = connection.SetWriteDeadline(deadline)
+	}
+
+	// resume previous session using reconnect token, if provided, it is not
+	// accepted in the URL so that it does not end up in access logs
+	reconnectToken := r.Header.Get(reconnectTokenHeader)
+	if reconnectToken != "" {
+		session, err = manager.sessions.Resume(reconnectToken)
+		if err == nil {
+			err = manager.sessions.ApplyClaims(session, r)
+		}
+	} else {
+		session, err = manager.sessions.Authenticate(r)
+	}
+
 	if err != nil {
 		manager.logger.Warn().Err(err).Msg("authentication failed")
-		newPeer(manager.logger, connection).Destroy(err.Error())
+		newPeer(manager.logger, manager.config, connection, "", nil).Destroy(err.Error())
 		return
 	}
 
@@ -231,7 +525,7 @@ func (manager *WebSocketManagerCtx) connect(connection *websocket.Conn, r *http.
 	logger := manager.logger.With().Str("session_id", session.ID()).Logger()
 
 	// create new peer
-	peer := newPeer(logger, connection)
+	peer := newPeer(logger, manager.config, connection, session.ID(), manager.sendMetrics)
 
 	if !session.Profile().CanConnect {
 		logger.Warn().Msg("connection disabled")
@@ -242,21 +536,60 @@ func (manager *WebSocketManagerCtx) connect(connection *websocket.Conn, r *http.
 	if session.State().IsConnected {
 		logger.Warn().Msg("already connected")
 
-		if !manager.sessions.Settings().MercifulReconnect {
+		// valid reconnect token allows replacing the connection
+		if !manager.sessions.Settings().MercifulReconnect && reconnectToken == "" {
 			peer.Destroy("already connected")
 			return
 		}
 
 		logger.Info().Msg("replacing peer connection")
+
+		// previous connection must be fully closed before the new one
+		// starts, so that its handlers and webrtc peer do not interfere
+		manager.closeConnection(session.ID(), logger)
+		manager.webrtc.ClosePeers(session)
 	}
 
+	// features are decided once for the whole connection
+	manager.sessions.DisableInsecureFeatures(session, r)
+
+	closed := manager.addConnection(session.ID(), peer)
+	defer closed()
+
 	logger.Info().
 		Str("address", connection.RemoteAddr().String()).
 		Str("agent", r.UserAgent()).
 		Msg("connection started")
 
+	// advertise public IP matching the ingress used by the client
+	manager.webrtc.PinPublicIP(session, r)
+	// prefer ICE servers of the client region
+	manager.webrtc.PinRegion(session, r)
+
+	if !deadline.IsZero() && time.Now().After(deadline) {
+		logger.Warn().Msg("handshake timed out")
+		peer.Destroy(errHandshakeTimeout.Error())
+		return
+	}
+
 	session.ConnectWebSocketPeer(peer)
 
+	// initial messages were sent, connection is not limited in time anymore
+	if err := peer.established(deadline); err != nil {
+		logger.Warn().Err(err).Msg("handshake failed")
+		peer.Destroy(err.Error())
+		session.DisconnectWebSocketPeer(peer, false)
+		return
+	}
+
+	// default video is picked by user agent, before the client requests one
+	if videoId := defaultVideo(manager.config.VideoDefaults, r); videoId != "" {
+		handler.SetDefaultVideo(session, videoId)
+	}
+
+	// client reconnecting with webrtc peer from previous connection
+	resetStaleWebRTCPeer(session, r.URL.Query().Get("webrtc_peer"), logger)
+
 	// this is a blocking function that lives
 	// throughout whole websocket connection
 	err = manager.handle(connection, peer, session)
@@ -303,61 +636,130 @@ func (manager *WebSocketManagerCtx) handle(connection *websocket.Conn, peer type
 	// add session id to logger context
 	logger := manager.logger.With().Str("session_id", session.ID()).Logger()
 
-	bytes := make(chan []byte)
-	cancel := make(chan error)
+	// binary frames are accepted only if subprotocol was negotiated
+	binaryInput := connection.Subprotocol() == binaryInputProtocol
+
+	type frame struct {
+		binary bool
+		raw    []byte
+	}
+
+	frames := make(chan frame)
+	cancel := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
 
 	ticker := time.NewTicker(pingPeriod)
 	defer ticker.Stop()
 
+	// messages are handled in a separate goroutine, so that a slow
+	// handler does not block pings and reading from the connection
+	messages := make(chan queuedMessage, handlerQueueSize)
+	workerDone := make(chan struct{})
+
+	// wait for pending messages to be handled before returning
+	defer func() {
+		close(messages)
+		<-workerDone
+	}()
+
+	manager.wg.Add(1)
+	go func() {
+		defer manager.wg.Done()
+		defer close(workerDone)
+
+		// messages handled concurrently must finish as well
+		var concurrent sync.WaitGroup
+		defer concurrent.Wait()
+
+		timeouts, unhandled := 0, 0
+		for data := range messages {
+			// handled in the pool, their timeouts only release the pool slot
+			if handler.Concurrent(data.Event) && manager.pool.run(&concurrent, func() {
+				manager.dispatch(logger, session, data)
+			}) {
+				continue
+			}
+
+			handled, inTime := manager.dispatch(logger, session, data)
+
+			if !handled {
+				unhandled++
+				manager.unhandledMessage(logger, peer, data.WebSocketMessage, unhandled)
+			}
+
+			if inTime {
+				timeouts = 0
+				continue
+			}
+
+			timeouts++
+			if maxTimeouts := manager.config.HandlerMaxTimeouts; maxTimeouts > 0 && timeouts >= maxTimeouts {
+				logger.Warn().Int("timeouts", timeouts).Msg("too many handler timeouts, closing connection")
+				peer.Destroy("handler timeout")
+			}
+		}
+	}()
+
 	manager.wg.Add(1)
 	go func() {
 		defer manager.wg.Done()
 
 		for {
-			_, raw, err := connection.ReadMessage()
+			messageType, raw, err := connection.ReadMessage()
 			if err != nil {
 				cancel <- err
 				break
 			}
 
-			bytes <- raw
+			select {
+			case frames <- frame{messageType == websocket.BinaryMessage, raw}:
+			case <-done:
+				return
+			}
 		}
 	}()
 
 	for {
 		select {
-		case raw := <-bytes:
-			data := types.WebSocketMessage{}
-			if err := json.Unmarshal(raw, &data); err != nil {
+		case frame := <-frames:
+			data := queuedMessage{}
+			if frame.binary {
+				if !binaryInput {
+					logger.Warn().Msg("binary message without negotiated binary input")
+					break
+				}
+
+				var err error
+				if data.Event, data.input, err = decodeBinaryInput(frame.raw); err != nil {
+					logger.Err(err).Msg("binary message decoding has failed")
+					break
+				}
+			} else if err := json.Unmarshal(frame.raw, &data.WebSocketMessage); err != nil {
 				logger.Err(err).Msg("message unmarshalling has failed")
 				break
 			}
 
-			// log events if not ignored
+			// log events if not ignored, binary input is encoded only for the log
 			if ok, _ := utils.ArrayIn(data.Event, nologEvents); !ok {
-				payload := data.Payload
-				if len(payload) > maxPayloadLogLength {
-					payload = []byte("<truncated>")
+				if e := logger.Debug(); e.Enabled() {
+					payload := data.Payload
+					if data.input != nil {
+						payload, _ = json.Marshal(data.input)
+					}
+
+					e.Str("address", connection.RemoteAddr().String()).
+						Str("event", data.Event).
+						Str("payload", logPayload(manager.config, data.Event, payload)).
+						Msg("received message from client")
 				}
-
-				logger.Debug().
-					Str("address", connection.RemoteAddr().String()).
-					Str("event", data.Event).
-					Str("payload", string(payload)).
-					Msg("received message from client")
 			}
 
-			handled := manager.handler.Message(session, data)
-			for _, handler := range manager.handlers {
-				if handled {
-					break
-				}
-
-				handled = handler(session, data)
-			}
-
-			if !handled {
-				logger.Warn().Str("event", data.Event).Msg("unhandled message")
+			select {
+			case messages <- data:
+			default:
+				logger.Warn().Str("event", data.Event).Msg("handler queue is full, closing connection")
+				peer.Destroy("handler queue full")
 			}
 		case err := <-cancel:
 			return err
@@ -372,6 +774,81 @@ func (manager *WebSocketManagerCtx) handle(connection *websocket.Conn, peer type
 	}
 }
 
+// dispatch passes message to handlers, returns whether message was handled and whether
+// handling finished within configured timeout. Handlers cannot be interrupted, so when
+// the timeout elapses, the handler is left running in the background and dispatch returns,
+// allowing following messages to be handled. Decoded binary input is handled by the core
+// handler only.
+func (manager *WebSocketManagerCtx) dispatch(logger zerolog.Logger, session types.Session, data queuedMessage) (bool, bool) {
+	timeout := manager.config.HandlerTimeout
+	if timeout <= 0 {
+		return manager.handleMessage(session, data), true
+	}
+
+	result := make(chan bool, 1)
+	manager.wg.Add(1)
+	go func() {
+		defer manager.wg.Done()
+		result <- manager.handleMessage(session, data)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case handled := <-result:
+		return handled, true
+	case <-timer.C:
+		logger.Warn().
+			Str("event", data.Event).
+			Dur("timeout", timeout).
+			Msg("message handler timed out, handling next messages")
+		// outcome is unknown, handler is still running
+		return true, false
+	}
+}
+
+// handleMessage passes message to the core handler and then to registered handlers
+// until one of them handles it.
+func (manager *WebSocketManagerCtx) handleMessage(session types.Session, data queuedMessage) bool {
+	if data.input != nil {
+		return manager.handler.Input(session, data.Event, data.input)
+	}
+
+	if manager.handler.Message(session, data.WebSocketMessage) {
+		return true
+	}
+
+	for _, handler := range manager.handlers.list() {
+		if handler(session, data.WebSocketMessage) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unhandledMessage applies configured policy for unhandled messages, count is the
+// number of unhandled messages received over the connection so far.
+func (manager *WebSocketManagerCtx) unhandledMessage(logger zerolog.Logger, peer types.WebSocketPeer, data types.WebSocketMessage, count int) {
+	logger.Warn().Str("event", data.Event).Int("count", count).Msg("unhandled message")
+
+	if manager.config.UnhandledReply {
+		peer.Send(event.SYSTEM_ERROR, message.SystemError{
+			Subsystem: "websocket",
+			Kind:      "unknown_event",
+			Message:   fmt.Sprintf("unknown event: %s", data.Event),
+			Count:     count,
+			Time:      time.Now(),
+		})
+	}
+
+	if max := manager.config.UnhandledMax; max > 0 && count >= max {
+		logger.Warn().Int("count", count).Msg("too many unhandled messages, closing connection")
+		peer.Destroy("too many unknown events")
+	}
+}
+
 func (manager *WebSocketManagerCtx) startInactiveCursors() {
 	if manager.shutdownInactiveCursors != nil {
 		manager.logger.Warn().Msg("inactive cursors handler already running")
@@ -391,6 +868,52 @@ func (manager *WebSocketManagerCtx) startInactiveCursors() {
 		var currentEmpty bool
 		var lastEmpty = false
 
+		// reused between ticks, it is serialized before the broadcast
+		sessionCursors := []message.SessionCursors{}
+
+		broadcast := func() {
+			cursorsMap := manager.sessions.PopCursors()
+
+			currentEmpty = len(cursorsMap) == 0
+			if currentEmpty && lastEmpty {
+				return
+			}
+			lastEmpty = currentEmpty
+
+			start := time.Now()
+
+			sessionCursors = sessionCursors[:0]
+			for session, cursors := range cursorsMap {
+				sessionCursors = append(
+					sessionCursors,
+					message.SessionCursors{
+						ID:      session.ID(),
+						Cursors: cursors,
+					},
+				)
+			}
+
+			// serialize only once for all recipients, payload may still be
+			// queued for sending after the broadcast returns
+			payload, err := json.Marshal(sessionCursors)
+			if err != nil {
+				manager.logger.Err(err).Msg("could not serialize inactive cursors")
+				return
+			}
+
+			manager.sessions.InactiveCursorsBroadcast(event.SESSION_CURSORS, json.RawMessage(payload))
+
+			elapsed := time.Since(start)
+			manager.inactiveCursorsDuration.Observe(elapsed.Seconds())
+			if elapsed > inactiveCursorsPeriod {
+				manager.inactiveCursorsOverruns.Inc()
+				manager.logger.Warn().
+					Dur("elapsed", elapsed).
+					Int("sessions", len(sessionCursors)).
+					Msg("inactive cursors broadcast is not keeping up")
+			}
+		}
+
 		for {
 			select {
 			case <-manager.shutdownInactiveCursors:
@@ -401,32 +924,24 @@ func (manager *WebSocketManagerCtx) startInactiveCursors() {
 				_ = manager.sessions.PopCursors()
 				manager.sessions.InactiveCursorsBroadcast(event.SESSION_CURSORS, []message.SessionCursors{})
 				return
+			case <-manager.flushInactiveCursors:
+				broadcast()
 			case <-ticker.C:
-				cursorsMap := manager.sessions.PopCursors()
-
-				currentEmpty = len(cursorsMap) == 0
-				if currentEmpty && lastEmpty {
-					continue
-				}
-				lastEmpty = currentEmpty
-
-				sessionCursors := []message.SessionCursors{}
-				for session, cursors := range cursorsMap {
-					sessionCursors = append(
-						sessionCursors,
-						message.SessionCursors{
-							ID:      session.ID(),
-							Cursors: cursors,
-						},
-					)
-				}
-
-				manager.sessions.InactiveCursorsBroadcast(event.SESSION_CURSORS, sessionCursors)
+				broadcast()
 			}
 		}
 	}()
 }
 
+// flushCursors requests broadcast of pending inactive cursors before the next tick,
+// requests are merged if the broadcast is already requested.
+func (manager *WebSocketManagerCtx) flushCursors() {
+	select {
+	case manager.flushInactiveCursors <- struct{}{}:
+	default:
+	}
+}
+
 func (manager *WebSocketManagerCtx) stopInactiveCursors() {
 	if manager.shutdownInactiveCursors != nil {
 		close(manager.shutdownInactiveCursors)
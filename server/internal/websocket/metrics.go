@@ -0,0 +1,58 @@
+package websocket
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// sendMetrics records size of messages sent to clients. Sizes are of the
+// serialized messages, websocket compression is not enabled. Series of a
+// session are removed once it is deleted.
+type sendMetrics struct {
+	eventBytes   *prometheus.CounterVec
+	eventSize    *prometheus.HistogramVec
+	sessionBytes *prometheus.CounterVec
+}
+
+func newSendMetrics() *sendMetrics {
+	return &sendMetrics{
+		eventBytes: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name:      "sent_bytes_total",
+			Namespace: "neko",
+			Subsystem: "websocket",
+			Help:      "Total bytes of serialized messages sent to clients by event.",
+		}, []string{"event"}),
+		eventSize: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:      "sent_message_size_bytes",
+			Namespace: "neko",
+			Subsystem: "websocket",
+			Help:      "Size of serialized messages sent to clients by event.",
+			Buckets:   prometheus.ExponentialBuckets(64, 4, 8),
+		}, []string{"event"}),
+		sessionBytes: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name:      "session_sent_bytes_total",
+			Namespace: "neko",
+			Subsystem: "websocket",
+			Help:      "Total bytes of serialized messages sent to a session.",
+		}, []string{"session_id"}),
+	}
+}
+
+func (m *sendMetrics) observe(sessionId, event string, size int) {
+	if m == nil {
+		return
+	}
+
+	m.eventBytes.WithLabelValues(event).Add(float64(size))
+	m.eventSize.WithLabelValues(event).Observe(float64(size))
+	m.sessionBytes.WithLabelValues(sessionId).Add(float64(size))
+}
+
+// forget removes series of deleted session, so that they do not accumulate.
+func (m *sendMetrics) forget(sessionId string) {
+	if m == nil {
+		return
+	}
+
+	m.sessionBytes.DeleteLabelValues(sessionId)
+}
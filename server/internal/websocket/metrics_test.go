@@ -0,0 +1,28 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSendMetricsForget(t *testing.T) {
+	m := newSendMetrics()
+
+	m.observe("a", "chat/message", 10)
+	m.observe("b", "chat/message", 20)
+
+	m.forget("a")
+
+	if n := testutil.CollectAndCount(m.sessionBytes); n != 1 {
+		t.Fatalf("session series = %d, want 1 after session was deleted", n)
+	}
+	if v := testutil.ToFloat64(m.sessionBytes.WithLabelValues("b")); v != 20 {
+		t.Errorf("bytes of remaining session = %v, want 20", v)
+	}
+
+	// per event series are kept
+	if v := testutil.ToFloat64(m.eventBytes.WithLabelValues("chat/message")); v != 30 {
+		t.Errorf("bytes of event = %v, want 30", v)
+	}
+}
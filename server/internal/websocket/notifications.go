@@ -0,0 +1,65 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// notificationLimiter allows at most rate notifications within a minute.
+type notificationLimiter struct {
+	mu   sync.Mutex
+	sent []time.Time
+}
+
+func (l *notificationLimiter) allow(now time.Time, rate int) bool {
+	if rate <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for len(l.sent) > 0 && now.Sub(l.sent[0]) >= time.Minute {
+		l.sent = l.sent[1:]
+	}
+
+	if len(l.sent) >= rate {
+		return false
+	}
+
+	l.sent = append(l.sent, now)
+	return true
+}
+
+// forwardNotification sends desktop notification to the host, or to all
+// connected sessions that can host, depending on configuration.
+func (manager *WebSocketManagerCtx) forwardNotification(notification types.DesktopNotification) {
+	if !manager.notifications.allow(time.Now(), manager.config.NotificationRate) {
+		manager.logger.Debug().
+			Str("app", notification.App).
+			Msg("desktop notification dropped, rate limit reached")
+		return
+	}
+
+	payload := message.SystemNotification{
+		DesktopNotification: notification,
+	}
+
+	if manager.config.NotificationRecipients == config.NotificationRecipientsHost {
+		if host, ok := manager.sessions.GetHost(); ok {
+			host.Send(event.SYSTEM_NOTIFICATION, payload)
+		}
+		return
+	}
+
+	for _, session := range manager.sessions.List() {
+		if session.Profile().CanHost && session.State().IsConnected {
+			session.Send(event.SYSTEM_NOTIFICATION, payload)
+		}
+	}
+}
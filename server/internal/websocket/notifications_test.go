@@ -0,0 +1,30 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotificationLimiter(t *testing.T) {
+	l := notificationLimiter{}
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if !l.allow(now.Add(time.Duration(i)*time.Second), 3) {
+			t.Fatalf("notification %d not allowed, below rate", i)
+		}
+	}
+
+	if l.allow(now.Add(10*time.Second), 3) {
+		t.Errorf("notification allowed above rate")
+	}
+
+	// oldest notification left the window
+	if !l.allow(now.Add(time.Minute), 3) {
+		t.Errorf("notification not allowed after window")
+	}
+
+	if !l.allow(now, 0) {
+		t.Errorf("notification not allowed without limit")
+	}
+}
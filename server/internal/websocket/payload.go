@@ -0,0 +1,88 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/m1k1o/neko/server/internal/config"
+)
+
+const (
+	payloadTruncated = "<truncated>"
+	payloadRedacted  = "<redacted>"
+)
+
+// logPayload returns payload as it should appear in debug logs. Configured
+// fields are masked regardless of the payload length, afterwards the payload
+// is truncated if it exceeds configured length.
+func logPayload(config *config.WebSocket, event string, payload []byte) string {
+	if fields, ok := config.LogRedact[event]; ok && len(fields) > 0 {
+		payload = redactPayload(payload, fields)
+	}
+
+	if config.LogPayloadLength > 0 && len(payload) > config.LogPayloadLength {
+		return payloadTruncated
+	}
+
+	return string(payload)
+}
+
+// redactPayload masks given fields in JSON payload, nested fields can be
+// specified using dot notation, fields of objects in arrays are masked in
+// every element. If the payload cannot be parsed or '*' is specified, whole
+// payload is masked.
+func redactPayload(payload []byte, fields []string) []byte {
+	if len(payload) == 0 {
+		return payload
+	}
+
+	var data any
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return []byte(payloadRedacted)
+	}
+
+	for _, field := range fields {
+		if field == "*" {
+			return []byte(payloadRedacted)
+		}
+
+		redactField(data, strings.Split(field, "."))
+	}
+
+	// placeholder must not be escaped
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(data); err != nil {
+		return []byte(payloadRedacted)
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+func redactField(data any, path []string) {
+	if arr, ok := data.([]any); ok {
+		for _, item := range arr {
+			redactField(item, path)
+		}
+		return
+	}
+
+	obj, ok := data.(map[string]any)
+	if !ok {
+		return
+	}
+
+	value, ok := obj[path[0]]
+	if !ok {
+		return
+	}
+
+	if len(path) == 1 {
+		obj[path[0]] = payloadRedacted
+		return
+	}
+
+	redactField(value, path[1:])
+}
@@ -0,0 +1,65 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/m1k1o/neko/server/internal/config"
+)
+
+func TestRedactPayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		fields  []string
+		want    string
+	}{
+		{"field", `{"text":"secret","id":"a"}`, []string{"text"}, `{"id":"a","text":"<redacted>"}`},
+		{"missing field", `{"id":"a"}`, []string{"text"}, `{"id":"a"}`},
+		{"nested field", `{"data":{"token":"secret"}}`, []string{"data.token"}, `{"data":{"token":"<redacted>"}}`},
+		{"whole object", `{"data":{"token":"secret"}}`, []string{"data"}, `{"data":"<redacted>"}`},
+		{"array payload", `[{"text":"a"},{"text":"b"}]`, []string{"text"}, `[{"text":"<redacted>"},{"text":"<redacted>"}]`},
+		{"array field", `{"messages":[{"text":"a","id":1},{"text":"b","id":2}]}`, []string{"messages.text"}, `{"messages":[{"id":1,"text":"<redacted>"},{"id":2,"text":"<redacted>"}]}`},
+		{"nested arrays", `{"a":[[{"b":"x"}],[{"b":"y"}]]}`, []string{"a.b"}, `{"a":[[{"b":"<redacted>"}],[{"b":"<redacted>"}]]}`},
+		{"array of scalars", `{"a":["x","y"]}`, []string{"a.b"}, `{"a":["x","y"]}`},
+		{"wildcard", `{"text":"secret"}`, []string{"*"}, `<redacted>`},
+		{"invalid json", `{"text":`, []string{"text"}, `<redacted>`},
+		{"empty", ``, []string{"text"}, ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(redactPayload([]byte(tt.payload), tt.fields)); got != tt.want {
+				t.Errorf("redactPayload(%s, %v) = %s, want %s", tt.payload, tt.fields, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogPayload(t *testing.T) {
+	cfg := &config.WebSocket{
+		LogPayloadLength: 40,
+		LogRedact: map[string][]string{
+			"chat/message": {"content.text"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		event   string
+		payload string
+		want    string
+	}{
+		{"not redacted event", "clipboard/set", `{"text":"visible"}`, `{"text":"visible"}`},
+		{"redacted event", "chat/message", `{"content":{"text":"secret"}}`, `{"content":{"text":"<redacted>"}}`},
+		{"truncated", "clipboard/set", `{"text":"this payload is longer than the limit"}`, `<truncated>`},
+		{"redacted before truncated", "chat/message", `{"content":{"text":"secret that is too long to log"}}`, `{"content":{"text":"<redacted>"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := logPayload(cfg, tt.event, []byte(tt.payload)); got != tt.want {
+				t.Errorf("logPayload(%s) = %s, want %s", tt.payload, got, tt.want)
+			}
+		})
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/rs/zerolog"
 
+	"github.com/m1k1o/neko/server/internal/config"
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/types/event"
 	"github.com/m1k1o/neko/server/pkg/types/message"
@@ -17,13 +19,22 @@ import (
 type WebSocketPeerCtx struct {
 	mu         sync.Mutex
 	logger     zerolog.Logger
+	config     *config.WebSocket
 	connection *websocket.Conn
+	destroyed  bool
+
+	// sent messages are accounted to the session, optional
+	sessionId string
+	metrics   *sendMetrics
 }
 
-func newPeer(logger zerolog.Logger, connection *websocket.Conn) *WebSocketPeerCtx {
+func newPeer(logger zerolog.Logger, config *config.WebSocket, connection *websocket.Conn, sessionId string, metrics *sendMetrics) *WebSocketPeerCtx {
 	return &WebSocketPeerCtx{
 		logger:     logger.With().Str("submodule", "peer").Logger(),
+		config:     config,
 		connection: connection,
+		sessionId:  sessionId,
+		metrics:    metrics,
 	}
 }
 
@@ -37,11 +48,16 @@ func (peer *WebSocketPeerCtx) Send(event string, payload any) {
 		return
 	}
 
-	err = peer.connection.WriteJSON(types.WebSocketMessage{
+	data, err := json.Marshal(types.WebSocketMessage{
 		Event:   event,
 		Payload: raw,
 	})
+	if err != nil {
+		peer.logger.Err(err).Str("event", event).Msg("message marshalling has failed")
+		return
+	}
 
+	err = peer.connection.WriteMessage(websocket.TextMessage, data)
 	if err != nil {
 		if e := errors.Unwrap(err); e != nil {
 			err = e // unwrap if possible
@@ -50,18 +66,34 @@ func (peer *WebSocketPeerCtx) Send(event string, payload any) {
 		return
 	}
 
-	// log events if not ignored
+	peer.metrics.observe(peer.sessionId, event, len(data))
+
+	// log events if not ignored, payload is formatted only for the log
 	if ok, _ := utils.ArrayIn(event, nologEvents); !ok {
-		if len(raw) > maxPayloadLogLength {
-			raw = []byte("<truncated>")
+		if e := peer.logger.Debug(); e.Enabled() {
+			e.Str("address", peer.connection.RemoteAddr().String()).
+				Str("event", event).
+				Str("payload", logPayload(peer.config, event, raw)).
+				Msg("sending message to client")
 		}
+	}
+}
+
+// established clears handshake deadlines of the connection, it fails if the
+// deadline has passed meanwhile and messages might not have been sent.
+func (peer *WebSocketPeerCtx) established(deadline time.Time) error {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
 
-		peer.logger.Debug().
-			Str("address", peer.connection.RemoteAddr().String()).
-			Str("event", event).
-			Str("payload", string(raw)).
-			Msg("sending message to client")
+	if !deadline.IsZero() && time.Now().After(deadline) {
+		return errHandshakeTimeout
 	}
+
+	if err := peer.connection.SetReadDeadline(time.Time{}); err != nil {
+		return err
+	}
+
+	return peer.connection.SetWriteDeadline(time.Time{})
 }
 
 func (peer *WebSocketPeerCtx) Ping() error {
@@ -79,6 +111,16 @@ func (peer *WebSocketPeerCtx) Ping() error {
 }
 
 func (peer *WebSocketPeerCtx) Destroy(reason string) {
+	peer.mu.Lock()
+	destroyed := peer.destroyed
+	peer.destroyed = true
+	peer.mu.Unlock()
+
+	// already destroyed, e.g. when replaced
+	if destroyed {
+		return
+	}
+
 	peer.Send(
 		event.SYSTEM_DISCONNECT,
 		message.SystemDisconnect{
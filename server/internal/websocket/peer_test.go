@@ -0,0 +1,66 @@
+package websocket
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/config"
+)
+
+func TestPeerEstablished(t *testing.T) {
+	result := make(chan error, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connection, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			result <- err
+			return
+		}
+		defer connection.Close()
+
+		deadline := time.Now().Add(50 * time.Millisecond)
+		_ = connection.SetReadDeadline(deadline)
+
+		peer := newPeer(zerolog.Nop(), &config.WebSocket{}, connection, "", nil)
+		if err := peer.established(deadline); err != nil {
+			result <- err
+			return
+		}
+
+		// message sent after the handshake deadline is still received
+		_, _, err = connection.ReadMessage()
+		result <- err
+	}))
+	defer server.Close()
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() = %v", err)
+	}
+	defer client.Close()
+
+	time.Sleep(100 * time.Millisecond)
+	if err := client.WriteMessage(websocket.TextMessage, []byte("{}")); err != nil {
+		t.Fatalf("WriteMessage() = %v", err)
+	}
+
+	if err := <-result; err != nil {
+		t.Errorf("established connection = %v, want no error", err)
+	}
+}
+
+func TestPeerEstablishedTimeout(t *testing.T) {
+	peer := newPeer(zerolog.Nop(), &config.WebSocket{}, nil, "", nil)
+
+	err := peer.established(time.Now().Add(-time.Second))
+	if !errors.Is(err, errHandshakeTimeout) {
+		t.Errorf("established() = %v, want %v", err, errHandshakeTimeout)
+	}
+}
@@ -0,0 +1,82 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// privacyScreenHostChanged shows privacy screen when there has been no host
+// for the delay, and hides it as soon as someone takes control again.
+func (manager *WebSocketManagerCtx) privacyScreenHostChanged(hasHost bool) {
+	if !manager.config.PrivacyScreen {
+		return
+	}
+
+	manager.privacyMu.Lock()
+	defer manager.privacyMu.Unlock()
+
+	if manager.privacyTimer != nil {
+		manager.privacyTimer.Stop()
+		manager.privacyTimer = nil
+	}
+
+	if hasHost {
+		manager.setPrivacyScreen(false)
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(manager.config.PrivacyScreenDelay, func() {
+		manager.privacyMu.Lock()
+		defer manager.privacyMu.Unlock()
+
+		// host changed meanwhile
+		if manager.privacyTimer != timer {
+			return
+		}
+		manager.privacyTimer = nil
+
+		if _, ok := manager.sessions.GetHost(); ok {
+			return
+		}
+
+		manager.setPrivacyScreen(true)
+	})
+	manager.privacyTimer = timer
+}
+
+// setPrivacyScreen obscures video, locks the screen and lets clients show an
+// overlay. Must be called with privacy mutex held.
+func (manager *WebSocketManagerCtx) setPrivacyScreen(active bool) {
+	if manager.capture.Privacy() == active {
+		return
+	}
+
+	if err := manager.capture.SetPrivacy(active); err != nil {
+		manager.logger.Err(err).Bool("active", active).Msg("could not change video privacy")
+		manager.errors.Report("websocket", "privacy_screen", nil, err)
+	}
+
+	if err := manager.desktop.SetScreenLocked(active); err != nil {
+		manager.logger.Err(err).Bool("active", active).Msg("could not change screen lock")
+		manager.errors.Report("websocket", "privacy_screen", nil, err)
+	}
+
+	manager.logger.Info().Bool("active", active).Msg("privacy screen changed")
+
+	manager.sessions.Broadcast(event.SYSTEM_PRIVACY, message.SystemPrivacy{
+		Active: active,
+	})
+}
+
+func (manager *WebSocketManagerCtx) stopPrivacyScreen() {
+	manager.privacyMu.Lock()
+	defer manager.privacyMu.Unlock()
+
+	if manager.privacyTimer != nil {
+		manager.privacyTimer.Stop()
+		manager.privacyTimer = nil
+	}
+}
@@ -0,0 +1,75 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/m1k1o/neko/server/internal/config"
+)
+
+// how often readiness is checked while connections are held
+const readyPollInterval = 100 * time.Millisecond
+
+// reason sent to clients rejected before the desktop is ready
+const startupReason = "starting up, retry"
+
+// Ready tells whether the desktop and capture are ready for connections. It
+// is not ready until the screen size is known and while the screen size is
+// changed, because capture pipelines are being recreated meanwhile.
+func (manager *WebSocketManagerCtx) Ready() bool {
+	if manager.resizing.Load() {
+		return false
+	}
+
+	size := manager.desktop.GetScreenSize()
+	return size.Width > 0 && size.Height > 0
+}
+
+func (manager *WebSocketManagerCtx) startReadiness() {
+	manager.desktop.OnBeforeScreenSizeChange(func() {
+		manager.resizing.Store(true)
+	})
+
+	manager.desktop.OnAfterScreenSizeChange(func() {
+		manager.resizing.Store(false)
+	})
+}
+
+// admitStartup applies configured startup mode to a new connection, it
+// returns false if the connection should be rejected.
+func (manager *WebSocketManagerCtx) admitStartup() bool {
+	switch manager.config.StartupMode {
+	case config.StartupModeHold:
+		return waitReady(manager.Ready, manager.config.StartupTimeout, manager.shutdown)
+	case config.StartupModeReject:
+		return manager.Ready()
+	}
+
+	return true
+}
+
+// waitReady waits until ready returns true, it returns false if it does not
+// within timeout or if canceled meanwhile.
+func waitReady(ready func() bool, timeout time.Duration, cancel <-chan struct{}) bool {
+	if ready() {
+		return true
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cancel:
+			return false
+		case <-deadline.C:
+			return false
+		case <-ticker.C:
+			if ready() {
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package websocket
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/m1k1o/neko/server/internal/config"
+)
+
+func TestWaitReady(t *testing.T) {
+	if !waitReady(func() bool { return true }, time.Millisecond, nil) {
+		t.Error("waitReady() = false, want ready right away")
+	}
+
+	var ready atomic.Bool
+	time.AfterFunc(50*time.Millisecond, func() { ready.Store(true) })
+	if !waitReady(ready.Load, time.Second, nil) {
+		t.Error("waitReady() = false, want ready after a while")
+	}
+
+	if waitReady(func() bool { return false }, 50*time.Millisecond, nil) {
+		t.Error("waitReady() = true, want timeout")
+	}
+
+	cancel := make(chan struct{})
+	close(cancel)
+	if waitReady(func() bool { return false }, time.Minute, cancel) {
+		t.Error("waitReady() = true, want canceled")
+	}
+}
+
+func TestAdmitStartup(t *testing.T) {
+	tests := []struct {
+		mode string
+		want bool
+	}{
+		// previous behavior is kept unless configured otherwise
+		{"", true},
+		{config.StartupModeAccept, true},
+		{config.StartupModeHold, false},
+		{config.StartupModeReject, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			manager := &WebSocketManagerCtx{
+				config: &config.WebSocket{
+					StartupMode:    tt.mode,
+					StartupTimeout: 50 * time.Millisecond,
+				},
+			}
+
+			// desktop is being resized, so it is not ready
+			manager.resizing.Store(true)
+
+			if got := manager.admitStartup(); got != tt.want {
+				t.Errorf("admitStartup() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package websocket
+
+import (
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// addSpectator counts session, if it is connected.
+func addSpectator(count *message.SessionSpectators, profile types.MemberProfile, state types.SessionState) {
+	if !state.IsConnected {
+		return
+	}
+
+	count.Connected++
+	if state.IsWatching {
+		count.Watching++
+	}
+
+	if count.Roles == nil {
+		return
+	}
+
+	switch {
+	case profile.IsAdmin:
+		count.Roles.Admins++
+	case profile.CanHost:
+		count.Roles.Hosts++
+	default:
+		count.Roles.Viewers++
+	}
+}
+
+// sendSpectators sends count of connected sessions to all connected sessions,
+// that can host. It is called through throttle, whenever the count may change.
+func (manager *WebSocketManagerCtx) sendSpectators() {
+	count := message.SessionSpectators{}
+	if manager.config.SpectatorsByRole {
+		count.Roles = &message.SpectatorRoles{}
+	}
+
+	recipients := []types.Session{}
+	manager.sessions.Range(func(session types.Session) bool {
+		profile, state := session.Profile(), session.State()
+		addSpectator(&count, profile, state)
+
+		if profile.CanHost && state.IsConnected {
+			recipients = append(recipients, session)
+		}
+		return true
+	})
+
+	for _, session := range recipients {
+		session.Send(event.SESSION_SPECTATORS, count)
+	}
+}
+
+func (manager *WebSocketManagerCtx) spectatorsChanged() {
+	if manager.spectators != nil {
+		manager.spectators.Trigger()
+	}
+}
@@ -0,0 +1,36 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+func TestAddSpectator(t *testing.T) {
+	connected := types.SessionState{IsConnected: true}
+	watching := types.SessionState{IsConnected: true, IsWatching: true}
+
+	count := message.SessionSpectators{Roles: &message.SpectatorRoles{}}
+	addSpectator(&count, types.MemberProfile{IsAdmin: true, CanHost: true}, watching)
+	addSpectator(&count, types.MemberProfile{CanHost: true}, connected)
+	addSpectator(&count, types.MemberProfile{}, watching)
+	addSpectator(&count, types.MemberProfile{}, watching)
+	addSpectator(&count, types.MemberProfile{}, types.SessionState{})
+
+	if count.Connected != 4 || count.Watching != 3 {
+		t.Errorf("count = %+v, want 4 connected and 3 watching", count)
+	}
+
+	want := message.SpectatorRoles{Admins: 1, Hosts: 1, Viewers: 2}
+	if *count.Roles != want {
+		t.Errorf("roles = %+v, want %+v", *count.Roles, want)
+	}
+
+	// without breakdown by role
+	count = message.SessionSpectators{}
+	addSpectator(&count, types.MemberProfile{IsAdmin: true}, watching)
+	if count.Connected != 1 || count.Roles != nil {
+		t.Errorf("count = %+v, want 1 connected without roles", count)
+	}
+}
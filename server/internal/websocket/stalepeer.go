@@ -0,0 +1,37 @@
+package websocket
+
+import (
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// staleWebRTCPeer tells whether webrtc peer the client is holding is unknown
+// to the server. Empty peer id means the client is not holding any peer.
+func staleWebRTCPeer(peer types.WebRTCPeer, peerId string) bool {
+	if peerId == "" {
+		return false
+	}
+
+	return peer == nil || peer.ID() != peerId
+}
+
+// resetStaleWebRTCPeer asks reconnecting client to recreate its webrtc peer, if
+// the server has no record of it, e.g. after an unclean server restart. Client
+// would otherwise keep the half-open connection until ICE fails.
+func resetStaleWebRTCPeer(session types.Session, peerId string, logger zerolog.Logger) {
+	if !staleWebRTCPeer(session.GetWebRTCPeer(), peerId) {
+		return
+	}
+
+	logger.Info().Str("webrtc_peer", peerId).Msg("client references unknown webrtc peer, resetting it")
+
+	session.Send(
+		event.SIGNAL_RESET,
+		message.SignalReset{
+			PeerID: peerId,
+			Reason: "unknown_peer",
+		})
+}
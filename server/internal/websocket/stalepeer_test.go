@@ -0,0 +1,39 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+type testWebRTCPeer struct {
+	types.WebRTCPeer
+	id string
+}
+
+func (p testWebRTCPeer) ID() string {
+	return p.id
+}
+
+func TestStaleWebRTCPeer(t *testing.T) {
+	tests := []struct {
+		name   string
+		peer   types.WebRTCPeer
+		peerId string
+		want   bool
+	}{
+		{"client without peer", nil, "", false},
+		{"client without peer, server with peer", testWebRTCPeer{id: "abc"}, "", false},
+		{"server without peer", nil, "abc", true},
+		{"same peer", testWebRTCPeer{id: "abc"}, "abc", false},
+		{"different peer", testWebRTCPeer{id: "def"}, "abc", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := staleWebRTCPeer(tt.peer, tt.peerId); got != tt.want {
+				t.Errorf("staleWebRTCPeer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package websocket
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/m1k1o/neko/server/internal/config"
+)
+
+// isMobile tells whether the client is a mobile device, client hint is
+// preferred and user agent is used only when the hint is not sent.
+func isMobile(r *http.Request) bool {
+	switch r.Header.Get("Sec-CH-UA-Mobile") {
+	case "?1":
+		return true
+	case "?0":
+		return false
+	}
+
+	return strings.Contains(r.UserAgent(), "Mobi")
+}
+
+// defaultVideo returns video of the first rule matching the client, empty if none matches.
+func defaultVideo(rules []config.VideoDefault, r *http.Request) string {
+	if len(rules) == 0 {
+		return ""
+	}
+
+	userAgent := r.UserAgent()
+	mobile := isMobile(r)
+
+	for _, rule := range rules {
+		if rule.UserAgent != nil && !rule.UserAgent.MatchString(userAgent) {
+			continue
+		}
+
+		if rule.Mobile != nil && *rule.Mobile != mobile {
+			continue
+		}
+
+		return rule.Video
+	}
+
+	return ""
+}
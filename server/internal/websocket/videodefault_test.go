@@ -0,0 +1,44 @@
+package websocket
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/m1k1o/neko/server/internal/config"
+)
+
+func TestDefaultVideo(t *testing.T) {
+	mobile := true
+	rules := []config.VideoDefault{
+		{UserAgent: regexp.MustCompile("SmartTV"), Video: "hq"},
+		{Mobile: &mobile, Video: "lq"},
+	}
+
+	tests := []struct {
+		name      string
+		userAgent string
+		hint      string
+		want      string
+	}{
+		{"desktop", "Mozilla/5.0 (X11; Linux x86_64) Firefox/120.0", "", ""},
+		{"phone", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0) Mobile/15E148 Safari/604.1", "", "lq"},
+		{"phone hint", "Mozilla/5.0 (Linux; Android 10; K) Chrome/120.0", "?1", "lq"},
+		{"desktop hint", "Mozilla/5.0 (Mobile) Chrome/120.0", "?0", ""},
+		{"tv", "Mozilla/5.0 (SmartTV; Mobile)", "", "hq"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &http.Request{Header: http.Header{}}
+			r.Header.Set("User-Agent", tt.userAgent)
+			if tt.hint != "" {
+				r.Header.Set("Sec-CH-UA-Mobile", tt.hint)
+			}
+
+			if got := defaultVideo(rules, r); got != tt.want {
+				t.Errorf("defaultVideo() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
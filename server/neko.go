@@ -26,6 +26,9 @@ var (
 	gitTag = "dev"
 )
 
+// version of the client protocol, increased on incompatible changes
+const ProtocolVersion = 3
+
 var Version = &version{
 	GitCommit: gitCommit,
 	GitBranch: gitBranch,
@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/m1k1o/neko/server/pkg/utils"
+)
+
+// FeatureOnly returns middleware rejecting sessions the feature is disabled for.
+func FeatureOnly(feature string) func(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	return func(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+		session, ok := GetSession(r)
+		if !ok || session.FeatureDisabled(feature) {
+			return nil, utils.HttpForbidden(feature + " requires secure connection")
+		}
+
+		return nil, nil
+	}
+}
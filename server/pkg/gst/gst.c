@@ -83,6 +83,30 @@ GstPipelineCtx *gstreamer_pipeline_create(char *pipelineStr, int pipelineId, GEr
   return ctx;
 }
 
+// time since the buffer was captured, estimated from its timestamp and the
+// pipeline clock, zero if unknown.
+static GstClockTime gstreamer_buffer_latency(GstPipelineCtx *ctx, GstBuffer *buffer) {
+  GstClockTime latency = 0;
+
+  if (!GST_BUFFER_PTS_IS_VALID(buffer)) {
+    return latency;
+  }
+
+  GstClock *clock = gst_element_get_clock(ctx->pipeline);
+  if (!clock) {
+    return latency;
+  }
+
+  GstClockTime now = gst_clock_get_time(clock);
+  GstClockTime captured = gst_element_get_base_time(ctx->pipeline) + GST_BUFFER_PTS(buffer);
+  if (now > captured) {
+    latency = now - captured;
+  }
+
+  gst_object_unref(clock);
+  return latency;
+}
+
 static GstFlowReturn gstreamer_send_new_sample_handler(GstElement *object, gpointer user_data) {
   GstPipelineCtx *ctx = (GstPipelineCtx *)user_data;
   GstSample *sample = NULL;
@@ -97,6 +121,7 @@ static GstFlowReturn gstreamer_send_new_sample_handler(GstElement *object, gpoin
       gst_buffer_extract_dup(buffer, 0, gst_buffer_get_size(buffer), &copy, &copy_size);
       goHandlePipelineBuffer(ctx->pipelineId, copy, copy_size,
         GST_BUFFER_DURATION(buffer),
+        gstreamer_buffer_latency(ctx, buffer),
         GST_BUFFER_FLAG_IS_SET(buffer, GST_BUFFER_FLAG_DELTA_UNIT)
       );
     }
@@ -215,3 +240,24 @@ gboolean gstreamer_pipeline_emit_video_keyframe(GstPipelineCtx *ctx) {
 	GstEvent *keyFrameEvent = gst_video_event_new_downstream_force_key_unit(now, time, now, TRUE, 0);
 	return gst_element_send_event(GST_ELEMENT(ctx->pipeline), keyFrameEvent);
 }
+
+char *gstreamer_pipeline_poll_error(GstPipelineCtx *ctx) {
+  GstBus *bus = gst_pipeline_get_bus(GST_PIPELINE(ctx->pipeline));
+  GstMessage *msg = gst_bus_pop_filtered(bus, GST_MESSAGE_ERROR | GST_MESSAGE_EOS);
+  gst_object_unref(bus);
+
+  if (msg == NULL) return NULL;
+
+  gchar *text;
+  if (GST_MESSAGE_TYPE(msg) == GST_MESSAGE_ERROR) {
+    GError *err = NULL;
+    gst_message_parse_error(msg, &err, NULL);
+    text = g_strdup_printf("error from element %s: %s", GST_OBJECT_NAME(msg->src), err->message);
+    g_error_free(err);
+  } else {
+    text = g_strdup("end of stream");
+  }
+
+  gst_message_unref(msg);
+  return text;
+}
@@ -48,6 +48,8 @@ type Pipeline interface {
 	SetCapsResolution(binName string, width, height int) bool
 	// emit video keyframe
 	EmitVideoKeyframe() bool
+	// error or end of stream posted by the pipeline since the last poll
+	PollError() error
 }
 
 type pipeline struct {
@@ -184,6 +186,16 @@ func (p *pipeline) EmitVideoKeyframe() bool {
 	return ok == C.TRUE
 }
 
+func (p *pipeline) PollError() error {
+	msgUnsafe := C.gstreamer_pipeline_poll_error(p.ctx)
+	if msgUnsafe == nil {
+		return nil
+	}
+	defer C.g_free(C.gpointer(unsafe.Pointer(msgUnsafe)))
+
+	return fmt.Errorf("(pipeline error) %s", C.GoString(msgUnsafe))
+}
+
 // gst-inspect-1.0
 func CheckPlugins(plugins []string) error {
 	var plugin *C.GstPlugin
@@ -200,7 +212,7 @@ func CheckPlugins(plugins []string) error {
 }
 
 //export goHandlePipelineBuffer
-func goHandlePipelineBuffer(pipelineID C.int, buf C.gpointer, bufLen C.int, duration C.guint64, deltaUnit C.gboolean) {
+func goHandlePipelineBuffer(pipelineID C.int, buf C.gpointer, bufLen C.int, duration C.guint64, latency C.guint64, deltaUnit C.gboolean) {
 	defer C.g_free(buf)
 
 	pipelinesLock.Lock()
@@ -213,6 +225,7 @@ func goHandlePipelineBuffer(pipelineID C.int, buf C.gpointer, bufLen C.int, dura
 			Length:    int(bufLen),
 			Timestamp: time.Now(),
 			Duration:  time.Duration(duration),
+			Latency:   time.Duration(latency),
 			DeltaUnit: deltaUnit == C.TRUE,
 		}
 	} else {
@@ -0,0 +1,17 @@
+package types
+
+import "time"
+
+// AnalyticsRecord is a structured record about session behavior, emitted
+// at session milestones and when the session ends.
+type AnalyticsRecord struct {
+	Time      time.Time      `json:"time"`
+	Event     string         `json:"event"`
+	SessionId string         `json:"session_id"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+type AnalyticsSink interface {
+	Record(record AnalyticsRecord) error
+	Close() error
+}
@@ -15,12 +15,16 @@ import (
 
 var (
 	ErrCapturePipelineAlreadyExists = errors.New("capture pipeline already exists")
+	ErrCaptureRegionInvalid         = errors.New("capture region is not within the screen")
+	ErrCaptureDeviceUnavailable     = errors.New("capture device is not available")
 )
 
 type Sample struct {
 	// timing information
 	Timestamp time.Time
 	Duration  time.Duration
+	// time from capture until the timestamp, zero if unknown
+	Latency time.Duration
 	// metadata
 	DeltaUnit bool // this unit cannot be decoded independently.
 	// buffer length
@@ -38,6 +42,17 @@ type BroadcastManager interface {
 	Stop()
 	Started() bool
 	Url() string
+	Status() BroadcastStatus
+	OnStatusChange(listener func(status BroadcastStatus))
+}
+
+type BroadcastStatus struct {
+	IsActive bool   `json:"is_active"`
+	URL      string `json:"url,omitempty"`
+	// pipeline failed and is being restarted
+	Reconnecting bool `json:"reconnecting,omitempty"`
+	// last error of the pipeline, stream key is redacted
+	Error string `json:"error,omitempty"`
 }
 
 type ScreencastManager interface {
@@ -110,10 +125,20 @@ type StreamSelectorManager interface {
 	GetStream(selector StreamSelector) (StreamSinkManager, bool)
 }
 
+// StreamLoad describes how well a pipeline keeps up with its framerate.
+type StreamLoad struct {
+	// averaged wall-clock time between frames relative to their duration,
+	// above 1 means the pipeline cannot keep up and frames are dropped
+	Load float64 `json:"load"`
+	// frames estimated to be dropped since the pipeline was created
+	DroppedFrames uint64 `json:"dropped_frames"`
+}
+
 type StreamSinkManager interface {
 	ID() string
 	Codec() codec.RTPCodec
 	Bitrate() uint64
+	Load() StreamLoad
 
 	AddListener(listener SampleListener) error
 	RemoveListener(listener SampleListener) error
@@ -127,6 +152,7 @@ type StreamSinkManager interface {
 }
 
 type StreamSrcManager interface {
+	Enabled() bool
 	Codec() codec.RTPCodec
 
 	Start(codec codec.RTPCodec) error
@@ -136,6 +162,14 @@ type StreamSrcManager interface {
 	Started() bool
 }
 
+// SpeakerSrc is a slot for shared microphone of a concurrent speaker, with
+// its own sources playing into the microphone and the mix device, so that
+// pulseaudio mixes all speakers together.
+type SpeakerSrc struct {
+	Microphone    StreamSrcManager
+	MicrophoneMix StreamSrcManager
+}
+
 type CaptureManager interface {
 	Start()
 	Shutdown() error
@@ -144,9 +178,30 @@ type CaptureManager interface {
 	Screencast() ScreencastManager
 	Audio() StreamSinkManager
 	Video() StreamSelectorManager
+	VideoFramerate() int16
+	VideoLoad() map[string]StreamLoad
 
 	Webcam() StreamSrcManager
 	Microphone() StreamSrcManager
+	MicrophoneMix() StreamSrcManager
+	// sources of concurrent speakers, empty if only one microphone can be shared
+	Speakers() []SpeakerSrc
+
+	VideoRegion() *CaptureRegion
+	SetVideoRegion(region *CaptureRegion) (*CaptureRegion, error)
+	OnVideoRegionReset(listener func())
+
+	// video streams show black screen instead of the desktop
+	Privacy() bool
+	SetPrivacy(enabled bool) error
+}
+
+// CaptureRegion is a rectangle of the screen that video streams are cropped to.
+type CaptureRegion struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
 }
 
 type VideoConfig struct {
@@ -157,12 +212,60 @@ type VideoConfig struct {
 	GstPrefix   string            `mapstructure:"gst_prefix"`   // pipeline prefix, starts with !
 	GstEncoder  string            `mapstructure:"gst_encoder"`  // gst encoder name
 	GstParams   map[string]string `mapstructure:"gst_params"`   // map of expressions
+	Tune        VideoTune         `mapstructure:"tune"`         // encoder tuning profile, gst_params take precedence
 	GstSuffix   string            `mapstructure:"gst_suffix"`   // pipeline suffix, starts with !
 	GstPipeline string            `mapstructure:"gst_pipeline"` // whole pipeline as a string
 	ShowPointer bool              `mapstructure:"show_pointer"` // show pointer in the video
 }
 
-func (config *VideoConfig) GetPipeline(screen ScreenSize) (string, error) {
+// GetFramerate returns framerate of the pipeline for given screen size, that
+// is its fps expression or the screen refresh rate, capped to maxFps if it is
+// greater than zero.
+func (config *VideoConfig) GetFramerate(screen ScreenSize, maxFps int16) (float64, error) {
+	// capture at most maxFps, even if the screen refresh rate is higher
+	if maxFps > 0 && (screen.Rate <= 0 || screen.Rate > maxFps) {
+		screen.Rate = maxFps
+	}
+
+	if config.Fps == "" {
+		return float64(screen.Rate), nil
+	}
+
+	language := []gval.Language{
+		gval.Function("round", func(args ...any) (any, error) {
+			return (int)(math.Round(args[0].(float64))), nil
+		}),
+	}
+
+	eval, err := gval.Full(language...).NewEvaluable(config.Fps)
+	if err != nil {
+		return 0, err
+	}
+
+	val, err := eval.EvalFloat64(context.Background(), map[string]any{
+		"width":  screen.Width,
+		"height": screen.Height,
+		"fps":    screen.Rate,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if maxFps > 0 && val > float64(maxFps) {
+		val = float64(maxFps)
+	}
+
+	return val, nil
+}
+
+// GetPipeline returns pipeline for given screen size, framerate is capped
+// to maxFps if it is greater than zero.
+func (config *VideoConfig) GetPipeline(screen ScreenSize, maxFps int16) (string, error) {
+	// capture at most maxFps, even if the screen refresh rate is higher
+	if maxFps > 0 && (screen.Rate <= 0 || screen.Rate > maxFps) {
+		screen.Rate = maxFps
+	}
+
 	values := map[string]any{
 		"width":  screen.Width,
 		"height": screen.Height,
@@ -177,13 +280,8 @@ func (config *VideoConfig) GetPipeline(screen ScreenSize) (string, error) {
 
 	// get fps pipeline
 	fpsPipeline := "! video/x-raw ! videoconvert ! queue"
-	if config.Fps != "" {
-		eval, err := gval.Full(language...).NewEvaluable(config.Fps)
-		if err != nil {
-			return "", err
-		}
-
-		val, err := eval.EvalFloat64(context.Background(), values)
+	if config.Fps != "" || maxFps > 0 {
+		val, err := config.GetFramerate(screen, maxFps)
 		if err != nil {
 			return "", err
 		}
@@ -220,7 +318,8 @@ func (config *VideoConfig) GetPipeline(screen ScreenSize) (string, error) {
 
 	// get encoder pipeline
 	encPipeline := fmt.Sprintf("! %s name=encoder", config.GstEncoder)
-	for key, expr := range config.GstParams {
+	// empty expression unsets parameter of the tuning profile
+	for key, expr := range config.Tune.params(config.GstEncoder, config.GstParams) {
 		if expr == "" {
 			continue
 		}
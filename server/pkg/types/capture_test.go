@@ -0,0 +1,39 @@
+package types
+
+import "testing"
+
+func TestVideoConfigGetFramerate(t *testing.T) {
+	screen := ScreenSize{Width: 1280, Height: 720, Rate: 60}
+
+	tests := []struct {
+		name   string
+		fps    string
+		maxFps int16
+		want   float64
+	}{
+		{"screen rate", "", 0, 60},
+		{"screen rate capped", "", 30, 30},
+		{"expression", "fps / 2", 0, 30},
+		{"expression with size", "round(width / 64)", 0, 20},
+		{"expression capped", "fps", 25, 25},
+		{"expression below cap", "15", 25, 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := VideoConfig{Fps: tt.fps}
+			got, err := config.GetFramerate(screen, tt.maxFps)
+			if err != nil {
+				t.Fatalf("GetFramerate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetFramerate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	config := VideoConfig{Fps: "fps +"}
+	if _, err := config.GetFramerate(screen, 0); err == nil {
+		t.Error("expected invalid expression to fail")
+	}
+}
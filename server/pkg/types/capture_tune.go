@@ -0,0 +1,122 @@
+package types
+
+import "strings"
+
+// VideoTune is an encoder tuning profile. It sets latency related encoder
+// parameters, that are not explicitly set in gst_params.
+type VideoTune string
+
+const (
+	// Encoder parameters are used exactly as configured.
+	VideoTuneNone VideoTune = "none"
+	// Lowest latency for remote desktop use: no B-frames and no lookahead, so
+	// that every frame is sent as soon as it is encoded, keyframe every second
+	// for fast recovery from packet loss and fastest encoder presets. Costs
+	// compression efficiency, the same quality needs roughly 20-30% more bitrate.
+	VideoTuneInteractive VideoTune = "interactive"
+	// Better quality for the same bitrate, mostly for watching videos: B-frames
+	// and lookahead add a few frames of latency, keyframe every five seconds
+	// leaves more bitrate for the other frames but recovery from loss is slower.
+	VideoTuneQuality VideoTune = "quality"
+)
+
+// encoder parameters of every tuning profile, values are expressions
+var videoTuneParams = map[VideoTune]map[string]map[string]string{
+	VideoTuneInteractive: {
+		"vp8enc": {
+			"deadline":          "1",
+			"lag-in-frames":     "0",
+			"auto-alt-ref":      "false",
+			"keyframe-max-dist": "fps",
+		},
+		"vp9enc": {
+			"deadline":          "1",
+			"lag-in-frames":     "0",
+			"auto-alt-ref":      "false",
+			"keyframe-max-dist": "fps",
+		},
+		"av1enc": {
+			"lag-in-frames":     "0",
+			"keyframe-max-dist": "fps",
+		},
+		"x264enc": {
+			"tune":         "zerolatency",
+			"bframes":      "0",
+			"key-int-max":  "fps",
+			"speed-preset": "veryfast",
+		},
+		"x265enc": {
+			"tune":         "zerolatency",
+			"key-int-max":  "fps",
+			"speed-preset": "veryfast",
+		},
+		"nvh264enc": {
+			"zerolatency": "true",
+			"gop-size":    "fps",
+			"preset":      `"low-latency-hq"`,
+		},
+		"vaapih264enc": {
+			"max-bframes":     "0",
+			"keyframe-period": "fps",
+		},
+	},
+	VideoTuneQuality: {
+		"vp8enc": {
+			"lag-in-frames":     "16",
+			"auto-alt-ref":      "true",
+			"keyframe-max-dist": "fps * 5",
+		},
+		"vp9enc": {
+			"lag-in-frames":     "16",
+			"auto-alt-ref":      "true",
+			"keyframe-max-dist": "fps * 5",
+		},
+		"av1enc": {
+			"lag-in-frames":     "16",
+			"keyframe-max-dist": "fps * 5",
+		},
+		"x264enc": {
+			"bframes":      "2",
+			"key-int-max":  "fps * 5",
+			"speed-preset": "faster",
+		},
+		"x265enc": {
+			"key-int-max":  "fps * 5",
+			"speed-preset": "faster",
+		},
+		"nvh264enc": {
+			"gop-size": "fps * 5",
+			"preset":   "hq",
+		},
+		"vaapih264enc": {
+			"max-bframes":     "2",
+			"keyframe-period": "fps * 5",
+		},
+	},
+}
+
+func ParseVideoTune(tune string) (VideoTune, bool) {
+	switch t := VideoTune(strings.ToLower(tune)); t {
+	case VideoTuneNone, VideoTuneInteractive, VideoTuneQuality:
+		return t, true
+	}
+	return "", false
+}
+
+// params returns encoder parameters of the tuning profile overridden by
+// explicitly set parameters.
+func (tune VideoTune) params(encoder string, explicit map[string]string) map[string]string {
+	tuned, ok := videoTuneParams[tune][encoder]
+	if !ok {
+		return explicit
+	}
+
+	params := map[string]string{}
+	for key, expr := range tuned {
+		params[key] = expr
+	}
+	for key, expr := range explicit {
+		params[key] = expr
+	}
+	return params
+}
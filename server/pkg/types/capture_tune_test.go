@@ -0,0 +1,96 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVideoConfigTune(t *testing.T) {
+	screen := ScreenSize{Width: 1280, Height: 720, Rate: 30}
+
+	tests := []struct {
+		name    string
+		tune    VideoTune
+		encoder string
+		params  map[string]string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "interactive",
+			tune:    VideoTuneInteractive,
+			encoder: "x264enc",
+			want:    []string{"tune=zerolatency", "bframes=0", "key-int-max=30", "speed-preset=veryfast"},
+		},
+		{
+			name:    "interactive hardware",
+			tune:    VideoTuneInteractive,
+			encoder: "nvh264enc",
+			want:    []string{"zerolatency=true", "gop-size=30", "preset=low-latency-hq"},
+		},
+		{
+			name:    "quality",
+			tune:    VideoTuneQuality,
+			encoder: "nvh264enc",
+			want:    []string{"gop-size=150", "preset=hq"},
+			notWant: []string{"zerolatency"},
+		},
+		{
+			name:    "explicit params win",
+			tune:    VideoTuneInteractive,
+			encoder: "vp8enc",
+			params:  map[string]string{"keyframe-max-dist": "25", "auto-alt-ref": ""},
+			want:    []string{"keyframe-max-dist=25", "lag-in-frames=0"},
+			notWant: []string{"keyframe-max-dist=30", "auto-alt-ref"},
+		},
+		{
+			name:    "none",
+			tune:    VideoTuneNone,
+			encoder: "x264enc",
+			params:  map[string]string{"bitrate": "4096"},
+			want:    []string{"bitrate=4096"},
+			notWant: []string{"tune=", "bframes"},
+		},
+		{
+			name:    "unknown encoder",
+			tune:    VideoTuneInteractive,
+			encoder: "openh264enc",
+			notWant: []string{"key-int-max", "keyframe"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := VideoConfig{
+				GstEncoder: tt.encoder,
+				GstParams:  tt.params,
+				Tune:       tt.tune,
+			}
+
+			pipeline, err := config.GetPipeline(screen, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, s := range tt.want {
+				if !strings.Contains(pipeline, " "+s) {
+					t.Errorf("pipeline %q does not contain %q", pipeline, s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(pipeline, s) {
+					t.Errorf("pipeline %q contains %q", pipeline, s)
+				}
+			}
+		})
+	}
+}
+
+func TestParseVideoTune(t *testing.T) {
+	if tune, ok := ParseVideoTune("Interactive"); !ok || tune != VideoTuneInteractive {
+		t.Errorf("ParseVideoTune(Interactive) = %q, %v", tune, ok)
+	}
+	if _, ok := ParseVideoTune("fast"); ok {
+		t.Error("ParseVideoTune(fast) accepted unknown tune")
+	}
+}
@@ -1,6 +1,8 @@
 package codec
 
 import (
+	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/pion/webrtc/v3"
@@ -173,6 +175,55 @@ func Opus() RTPCodec {
 	}
 }
 
+// sample rates supported by opus, RTP clock rate is always 48kHz
+var OpusSampleRates = []int{8000, 12000, 16000, 24000, 48000}
+
+// OpusFormat returns opus codec encoding audio with given sample rate and
+// number of channels, signaled to the receiver in SDP format parameters.
+func OpusFormat(sampleRate, channels int) (RTPCodec, error) {
+	if !slices.Contains(OpusSampleRates, sampleRate) {
+		return RTPCodec{}, fmt.Errorf("unsupported opus sample rate %d", sampleRate)
+	}
+
+	if channels != 1 && channels != 2 {
+		return RTPCodec{}, fmt.Errorf("unsupported opus channel count %d", channels)
+	}
+
+	stereo := 0
+	if channels == 2 {
+		stereo = 1
+	}
+
+	codec := Opus()
+	codec.Capability.SDPFmtpLine = fmt.Sprintf("useinbandfec=1;stereo=%d;sprop-stereo=%d", stereo, stereo)
+	if sampleRate != 48000 {
+		codec.Capability.SDPFmtpLine += fmt.Sprintf(";maxplaybackrate=%d;sprop-maxcapturerate=%d", sampleRate, sampleRate)
+	}
+	codec.Pipeline = fmt.Sprintf("audioresample ! audio/x-raw,rate=%d,channels=%d ! %s", sampleRate, channels, codec.Pipeline)
+
+	return codec, nil
+}
+
+// MimeTypeRED is redundant audio data (RFC 2198).
+const MimeTypeRED = "audio/red"
+
+// RED returns redundant encoding of the primary audio codec. It only wraps
+// payloads of the primary codec, so it has no pipeline.
+func RED(primary RTPCodec) RTPCodec {
+	return RTPCodec{
+		Name:        "red",
+		PayloadType: 63,
+		Type:        webrtc.RTPCodecTypeAudio,
+		Capability: webrtc.RTPCodecCapability{
+			MimeType:     MimeTypeRED,
+			ClockRate:    primary.Capability.ClockRate,
+			Channels:     primary.Capability.Channels,
+			SDPFmtpLine:  fmt.Sprintf("%d/%d", primary.PayloadType, primary.PayloadType),
+			RTCPFeedback: []webrtc.RTCPFeedback{},
+		},
+	}
+}
+
 func G722() RTPCodec {
 	return RTPCodec{
 		Name:        "g722",
@@ -0,0 +1,60 @@
+package codec
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+)
+
+func TestOpusFormatOffer(t *testing.T) {
+	opus, err := OpusFormat(16000, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := &webrtc.MediaEngine{}
+	if err := opus.Register(engine); err != nil {
+		t.Fatal(err)
+	}
+
+	connection, err := webrtc.NewAPI(webrtc.WithMediaEngine(engine)).NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connection.Close()
+
+	track, err := webrtc.NewTrackLocalStaticSample(opus.Capability, "audio", "stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := connection.AddTrack(track); err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := connection.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// clock rate and channels in rtpmap are fixed for opus
+	for _, line := range []string{
+		"a=rtpmap:111 opus/48000/2",
+		"a=fmtp:111 useinbandfec=1;stereo=0;sprop-stereo=0;maxplaybackrate=16000;sprop-maxcapturerate=16000",
+	} {
+		if !strings.Contains(offer.SDP, line) {
+			t.Errorf("offer does not contain %q:\n%s", line, offer.SDP)
+		}
+	}
+}
+
+func TestOpusFormatInvalid(t *testing.T) {
+	if _, err := OpusFormat(44100, 2); err == nil {
+		t.Error("unsupported sample rate accepted")
+	}
+
+	if _, err := OpusFormat(48000, 6); err == nil {
+		t.Error("unsupported channel count accepted")
+	}
+}
@@ -1,10 +1,16 @@
 package types
 
 import (
+	"errors"
 	"fmt"
 	"image"
 )
 
+var (
+	ErrDropOutOfBounds = errors.New("drop coordinates are out of screen bounds")
+	ErrDropFailed      = errors.New("unable to drop files")
+)
+
 type CursorImage struct {
 	Width  uint16
 	Height uint16
@@ -45,6 +51,18 @@ type ClipboardText struct {
 	HTML string
 }
 
+type ClipboardImage struct {
+	Mime string
+	Data []byte
+}
+
+// notification raised by an application on the desktop
+type DesktopNotification struct {
+	App     string `json:"app"`
+	Summary string `json:"summary"`
+	Body    string `json:"body"`
+}
+
 type DesktopManager interface {
 	Start()
 	Shutdown() error
@@ -88,12 +106,14 @@ type DesktopManager interface {
 	// clipboard
 	ClipboardGetText() (*ClipboardText, error)
 	ClipboardSetText(data ClipboardText) error
+	// nil when clipboard holds no image
+	ClipboardGetImage() (*ClipboardImage, error)
 	ClipboardGetBinary(mime string) ([]byte, error)
 	ClipboardSetBinary(mime string, data []byte) error
 	ClipboardGetTargets() ([]string, error)
 
 	// drop
-	DropFiles(x int, y int, files []string) bool
+	DropFiles(x int, y int, files []string) error
 	IsUploadDropEnabled() bool
 
 	// filechooser
@@ -101,4 +121,17 @@ type DesktopManager interface {
 	CloseFileChooserDialog()
 	IsFileChooserDialogEnabled() bool
 	IsFileChooserDialogOpened() bool
+
+	// navigate
+	Navigate(url string) error
+	IsNavigateEnabled() bool
+
+	// privacy
+	SetScreenLocked(locked bool) error
+
+	// notifications
+	OnNotification(listener func(notification DesktopNotification))
+
+	// shortcuts
+	OnShortcut(listener func(name string))
 }
@@ -0,0 +1,17 @@
+package types
+
+import "time"
+
+type SubsystemError struct {
+	Subsystem string    `json:"subsystem"`
+	Kind      string    `json:"kind"`
+	SessionId string    `json:"session_id,omitempty"`
+	Message   string    `json:"message"`
+	Count     int       `json:"count"`
+	Time      time.Time `json:"time"`
+}
+
+type ErrorBus interface {
+	Report(subsystem, kind string, session Session, err error)
+	OnError(listener func(err SubsystemError))
+}
@@ -1,12 +1,22 @@
 package event
 
 const (
-	SYSTEM_INIT       = "system/init"
-	SYSTEM_ADMIN      = "system/admin"
-	SYSTEM_SETTINGS   = "system/settings"
-	SYSTEM_LOGS       = "system/logs"
-	SYSTEM_DISCONNECT = "system/disconnect"
-	SYSTEM_HEARTBEAT  = "system/heartbeat"
+	SYSTEM_INIT         = "system/init"
+	SYSTEM_ADMIN        = "system/admin"
+	SYSTEM_SETTINGS     = "system/settings"
+	SYSTEM_LOGS         = "system/logs"
+	SYSTEM_DISCONNECT   = "system/disconnect"
+	SYSTEM_HEARTBEAT    = "system/heartbeat"
+	SYSTEM_WHOAMI       = "system/whoami"
+	SYSTEM_ERROR        = "system/error"
+	SYSTEM_CHANGES      = "system/changes"
+	SYSTEM_HANDOFF      = "system/handoff"
+	SYSTEM_VERSION      = "system/version"
+	SYSTEM_BATCH        = "system/batch"
+	SYSTEM_PRIVACY      = "system/privacy"
+	SYSTEM_NOTIFICATION = "system/notification"
+	SYSTEM_STATE        = "system/state"
+	SYSTEM_CONSENT      = "system/consent"
 )
 
 const (
@@ -16,6 +26,7 @@ const (
 const (
 	SIGNAL_REQUEST   = "signal/request"
 	SIGNAL_RESTART   = "signal/restart"
+	SIGNAL_RECONNECT = "signal/reconnect"
 	SIGNAL_OFFER     = "signal/offer"
 	SIGNAL_ANSWER    = "signal/answer"
 	SIGNAL_PROVIDE   = "signal/provide"
@@ -23,6 +34,17 @@ const (
 	SIGNAL_VIDEO     = "signal/video"
 	SIGNAL_AUDIO     = "signal/audio"
 	SIGNAL_CLOSE     = "signal/close"
+	SIGNAL_CURSOR    = "signal/cursor"
+
+	SIGNAL_VIDEO_UNAVAILABLE = "signal/video_unavailable"
+	SIGNAL_MEDIA_RESUME      = "signal/media_resume"
+	SIGNAL_PLAYING           = "signal/playing"
+	SIGNAL_PLAYOUT_DELAY     = "signal/playout_delay"
+	SIGNAL_MEDIA_UNAVAILABLE = "signal/media_unavailable"
+	SIGNAL_RESET             = "signal/reset"
+	SIGNAL_FAILED            = "signal/failed"
+	SIGNAL_BANDWIDTH         = "signal/bandwidth"
+	SIGNAL_STATS             = "signal/stats"
 )
 
 const (
@@ -31,12 +53,29 @@ const (
 	SESSION_PROFILE = "session/profile"
 	SESSION_STATE   = "session/state"
 	SESSION_CURSORS = "session/cursors"
+
+	SESSION_FLAPPING   = "session/flapping"
+	SESSION_SPECTATORS = "session/spectators"
 )
 
 const (
 	CONTROL_HOST    = "control/host"
 	CONTROL_RELEASE = "control/release"
 	CONTROL_REQUEST = "control/request"
+	CONTROL_LOCK    = "control/lock"
+	CONTROL_LOCKED  = "control/locked"
+
+	CONTROL_TAKEOVER          = "control/takeover"
+	CONTROL_TAKEOVER_CANCELED = "control/takeover_canceled"
+	CONTROL_OBJECT            = "control/object"
+
+	// input recording
+	CONTROL_RECORD_START = "control/record_start"
+	CONTROL_RECORD_STOP  = "control/record_stop"
+	CONTROL_RECORDING    = "control/recording"
+	CONTROL_REPLAY       = "control/replay"
+	CONTROL_REPLAY_STOP  = "control/replay_stop"
+	CONTROL_REPLAYED     = "control/replayed"
 	// mouse
 	CONTROL_MOVE        = "control/move"
 	CONTROL_SCROLL      = "control/scroll"
@@ -56,11 +95,20 @@ const (
 	CONTROL_COPY       = "control/copy"
 	CONTROL_PASTE      = "control/paste"
 	CONTROL_SELECT_ALL = "control/select_all"
+
+	CONTROL_SHORTCUT = "control/shortcut"
 )
 
 const (
 	SCREEN_UPDATED = "screen/updated"
 	SCREEN_SET     = "screen/set"
+
+	SCREEN_REGION_UPDATED = "screen/region_updated"
+	SCREEN_REGION_SET     = "screen/region_set"
+)
+
+const (
+	DESKTOP_NAVIGATE = "desktop/navigate"
 )
 
 const (
@@ -75,6 +123,8 @@ const (
 
 const (
 	BROADCAST_STATUS = "broadcast/status"
+	BROADCAST_START  = "broadcast/start"
+	BROADCAST_STOP   = "broadcast/stop"
 )
 
 const (
@@ -0,0 +1,40 @@
+package types
+
+import "time"
+
+type InputEventType string
+
+const (
+	InputMove        InputEventType = "move"
+	InputScroll      InputEventType = "scroll"
+	InputButtonDown  InputEventType = "buttondown"
+	InputButtonUp    InputEventType = "buttonup"
+	InputButtonPress InputEventType = "buttonpress"
+	InputKeyDown     InputEventType = "keydown"
+	InputKeyUp       InputEventType = "keyup"
+	InputKeyPress    InputEventType = "keypress"
+	InputTouchBegin  InputEventType = "touchbegin"
+	InputTouchUpdate InputEventType = "touchupdate"
+	InputTouchEnd    InputEventType = "touchend"
+)
+
+// InputEvent is a single recorded input event, delay is in milliseconds
+// relative to the previous event.
+type InputEvent struct {
+	Delay int64          `json:"delay"`
+	Type  InputEventType `json:"type"`
+
+	X          int    `json:"x,omitempty"`
+	Y          int    `json:"y,omitempty"`
+	DeltaX     int    `json:"delta_x,omitempty"`
+	DeltaY     int    `json:"delta_y,omitempty"`
+	ControlKey bool   `json:"control_key,omitempty"`
+	Code       uint32 `json:"code,omitempty"`
+	TouchId    uint32 `json:"touch_id,omitempty"`
+	Pressure   uint8  `json:"pressure,omitempty"`
+}
+
+type InputRecording struct {
+	StartedAt time.Time    `json:"started_at"`
+	Events    []InputEvent `json:"events"`
+}
@@ -1,6 +1,8 @@
 package message
 
 import (
+	"time"
+
 	"github.com/pion/webrtc/v3"
 
 	"github.com/m1k1o/neko/server/pkg/types"
@@ -11,23 +13,53 @@ import (
 /////////////////////////////
 
 type SystemWebRTC struct {
-	Videos []string `json:"videos"`
+	Videos    []string `json:"videos"`
+	Audio     bool     `json:"audio"`
+	Framerate int16    `json:"framerate"`
 }
 
 type SystemInit struct {
 	SessionId         string                 `json:"session_id"`
 	ControlHost       ControlHost            `json:"control_host"`
 	ScreenSize        types.ScreenSize       `json:"screen_size"`
+	ScreenRegion      *types.CaptureRegion   `json:"screen_region,omitempty"`
 	Sessions          map[string]SessionData `json:"sessions"`
 	Settings          types.Settings         `json:"settings"`
 	TouchEvents       bool                   `json:"touch_events"`
 	ScreencastEnabled bool                   `json:"screencast_enabled"`
+	DisabledFeatures  []string               `json:"disabled_features,omitempty"`
+	PrivacyScreen     bool                   `json:"privacy_screen"`
 	WebRTC            SystemWebRTC           `json:"webrtc"`
+	ReconnectToken    string                 `json:"reconnect_token,omitempty"`
+	Version           SystemVersion          `json:"version"`
 }
 
 type SystemAdmin struct {
-	ScreenSizesList []types.ScreenSize `json:"screen_sizes_list"`
-	BroadcastStatus BroadcastStatus    `json:"broadcast_status"`
+	ScreenSizesList  []types.ScreenSize `json:"screen_sizes_list"`
+	BroadcastStatus  BroadcastStatus    `json:"broadcast_status"`
+	CaptureFramerate int16              `json:"capture_framerate"`
+}
+
+type SystemError types.SubsystemError
+
+// terms that must be acknowledged, client acknowledges them by sending back
+// the same event with empty payload
+type SystemConsent struct {
+	Text    string `json:"text"`
+	Version string `json:"version"`
+	Timeout int64  `json:"timeout,omitempty"` // in milliseconds
+}
+
+type SystemPrivacy struct {
+	Active bool `json:"active"`
+}
+
+type SystemNotification struct {
+	types.DesktopNotification
+}
+
+type SystemChanges struct {
+	Changes []types.SessionChange `json:"changes"`
 }
 
 type SystemLogs = []SystemLog
@@ -42,6 +74,69 @@ type SystemDisconnect struct {
 	Message string `json:"message"`
 }
 
+type SystemHandoff struct {
+	// server the client should reconnect to
+	URL string `json:"url"`
+	// restores the session on that server
+	Token string `json:"token"`
+}
+
+type SystemCapabilities struct {
+	TouchEvents       bool             `json:"touch_events"`
+	ScreencastEnabled bool             `json:"screencast_enabled"`
+	DisabledFeatures  []string         `json:"disabled_features,omitempty"` // require secure connection
+	WebRTC            bool             `json:"webrtc"`
+	Video             *types.PeerVideo `json:"video,omitempty"`
+	Audio             *types.PeerAudio `json:"audio,omitempty"`
+}
+
+// events coalesced within a window, in order they were sent
+type SystemBatch struct {
+	Event    string `json:"event"`
+	Payloads []any  `json:"payloads"`
+}
+
+type SystemVersion struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	GitBranch string `json:"git_branch"`
+	GitTag    string `json:"git_tag"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+	Protocol  int    `json:"protocol"`
+}
+
+type SystemWhoami struct {
+	ID           string              `json:"id"`
+	Profile      types.MemberProfile `json:"profile"`
+	State        types.SessionState  `json:"state"`
+	IsAdmin      bool                `json:"is_admin"`
+	IsHost       bool                `json:"is_host"`
+	Capabilities SystemCapabilities  `json:"capabilities"`
+}
+
+// snapshot of the room, as seen by the requesting session
+type SystemState struct {
+	Settings      types.Settings       `json:"settings"`
+	ControlHost   ControlHost          `json:"control_host"`
+	Members       SystemMembers        `json:"members"`
+	ScreenSize    types.ScreenSize     `json:"screen_size"`
+	ScreenRegion  *types.CaptureRegion `json:"screen_region,omitempty"`
+	PrivacyScreen bool                 `json:"privacy_screen"`
+	WebRTC        SystemWebRTC         `json:"webrtc"`
+	Capabilities  SystemCapabilities   `json:"capabilities"`
+	// only for admins, URL may contain stream key
+	BroadcastStatus *BroadcastStatus `json:"broadcast_status,omitempty"`
+	Version         SystemVersion    `json:"version"`
+}
+
+type SystemMembers struct {
+	Total     int `json:"total"`
+	Connected int `json:"connected"`
+	Watching  int `json:"watching"`
+}
+
 type SystemSettingsUpdate struct {
 	ID string `json:"id"`
 	types.Settings
@@ -55,15 +150,40 @@ type SignalRequest struct {
 	Video types.PeerVideoRequest `json:"video"`
 	Audio types.PeerAudioRequest `json:"audio"`
 
+	MicrophoneRoute types.MicrophoneRoute `json:"microphone_route,omitempty"`
+	// cursor updates sent over data channel, full by default
+	Cursor types.CursorMode `json:"cursor,omitempty"`
+
+	// only data channel for control, without any media
+	DataOnly bool `json:"data_only,omitempty"`
+
 	Auto bool `json:"auto"` // TODO: Remove this
 }
 
 type SignalProvide struct {
+	PeerID     string            `json:"peer_id"`
 	SDP        string            `json:"sdp"`
 	ICEServers []types.ICEServer `json:"iceservers"`
 
-	Video types.PeerVideo `json:"video"`
-	Audio types.PeerAudio `json:"audio"`
+	Video  types.PeerVideo  `json:"video"`
+	Audio  types.PeerAudio  `json:"audio"`
+	Cursor types.CursorMode `json:"cursor"`
+}
+
+// webrtc keeps failing while websocket works, e.g. UDP is blocked
+type SignalFailed struct {
+	Attempts int    `json:"attempts"`
+	Reason   string `json:"reason"`
+	// whether any ICE servers are configured, without TURN relay media can
+	// not pass restrictive networks
+	ICEServers bool `json:"ice_servers"`
+	// screencast can be used as fallback
+	Screencast bool `json:"screencast"`
+}
+
+type SignalReset struct {
+	PeerID string `json:"peer_id"`
+	Reason string `json:"reason"`
 }
 
 type SignalCandidate struct {
@@ -78,10 +198,54 @@ type SignalVideo struct {
 	types.PeerVideoRequest
 }
 
+type SignalVideoUnavailable struct {
+	Requested string   `json:"requested,omitempty"`
+	Fallback  string   `json:"fallback,omitempty"`
+	Videos    []string `json:"videos"`
+}
+
+// media the client was sharing before its peer was closed
+type SignalMediaResume struct {
+	Microphone bool `json:"microphone"`
+	Webcam     bool `json:"webcam"`
+}
+
+// media shared by the client that could not be received
+type SignalMediaUnavailable struct {
+	Media  string `json:"media"`  // microphone or webcam
+	Reason string `json:"reason"` // device_unavailable or pipeline_failed
+	Error  string `json:"error"`
+}
+
+// first media received by the client
+type SignalPlaying struct {
+	Media            string `json:"media"`               // audio or video
+	TimeToFirstFrame int64  `json:"time_to_first_frame"` // in milliseconds
+}
+
+// playout delays compensating audio/video offset, client sets them as
+// jitter buffer targets of its receivers
+type SignalBandwidth struct {
+	types.PeerBandwidth
+}
+
+type SignalStats struct {
+	types.PeerStats
+}
+
+type SignalPlayoutDelay struct {
+	Audio int64 `json:"audio"` // in milliseconds
+	Video int64 `json:"video"` // in milliseconds
+}
+
 type SignalAudio struct {
 	types.PeerAudioRequest
 }
 
+type SignalCursor struct {
+	Mode types.CursorMode `json:"mode"`
+}
+
 /////////////////////////////
 // Session
 /////////////////////////////
@@ -106,6 +270,26 @@ type SessionData struct {
 	State   types.SessionState  `json:"state"`
 }
 
+type SessionFlapping struct {
+	ID         string               `json:"id"`
+	Reconnects types.ReconnectStats `json:"reconnects"`
+}
+
+// count of connected sessions, sent to sessions that can host
+type SessionSpectators struct {
+	Connected int `json:"connected"`
+	Watching  int `json:"watching"`
+	// only if breakdown by role is enabled
+	Roles *SpectatorRoles `json:"roles,omitempty"`
+}
+
+// connected sessions by their highest role
+type SpectatorRoles struct {
+	Admins  int `json:"admins"`
+	Hosts   int `json:"hosts"`
+	Viewers int `json:"viewers"`
+}
+
 type SessionCursors struct {
 	ID      string         `json:"id"`
 	Cursors []types.Cursor `json:"cursors"`
@@ -119,6 +303,35 @@ type ControlHost struct {
 	ID      string `json:"id"`
 	HasHost bool   `json:"has_host"`
 	HostID  string `json:"host_id,omitempty"`
+	Locked  bool   `json:"locked"`
+}
+
+type ControlLock struct {
+	Locked bool `json:"locked"`
+}
+
+type ControlLocked struct {
+	ID     string `json:"id"`
+	Locked bool   `json:"locked"`
+}
+
+type ControlTakeover struct {
+	ID        string    `json:"id"`
+	HostID    string    `json:"host_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ControlTakeoverCanceled struct {
+	ID   string `json:"id"`
+	ByID string `json:"by_id,omitempty"`
+}
+
+type ControlRecording struct {
+	types.InputRecording
+}
+
+type ControlReplayed struct {
+	Error string `json:"error,omitempty"`
 }
 
 type ControlScroll struct {
@@ -146,6 +359,11 @@ type ControlKey struct {
 	Keysym uint32 `json:"keysym"`
 }
 
+// key combination of the host recognized as a named shortcut
+type ControlShortcut struct {
+	Name string `json:"name"`
+}
+
 type ControlTouch struct {
 	*ControlPos
 	TouchId  uint32 `json:"touch_id"`
@@ -165,12 +383,35 @@ type ScreenSizeUpdate struct {
 	types.ScreenSize
 }
 
+type ScreenRegion struct {
+	Region *types.CaptureRegion `json:"region"`
+}
+
+type ScreenRegionUpdate struct {
+	ID     string               `json:"id"`
+	Region *types.CaptureRegion `json:"region"`
+}
+
+/////////////////////////////
+// Desktop
+/////////////////////////////
+
+type DesktopNavigate struct {
+	URL string `json:"url"`
+}
+
 /////////////////////////////
 // Clipboard
 /////////////////////////////
 
 type ClipboardData struct {
-	Text string `json:"text"`
+	Text  string          `json:"text"`
+	Image *ClipboardImage `json:"image,omitempty"`
+}
+
+type ClipboardImage struct {
+	Mime string `json:"mime"`
+	Data []byte `json:"data"` // base64 encoded
 }
 
 /////////////////////////////
@@ -189,9 +430,11 @@ type KeyboardModifiers struct {
 // Broadcast
 /////////////////////////////
 
-type BroadcastStatus struct {
-	IsActive bool   `json:"is_active"`
-	URL      string `json:"url,omitempty"`
+type BroadcastStatus types.BroadcastStatus
+
+type BroadcastStart struct {
+	// configured URL is used, if empty
+	URL string `json:"url,omitempty"`
 }
 
 /////////////////////////////
@@ -46,6 +46,7 @@ type PluginMetadata struct {
 type PluginManagers struct {
 	SessionManager        SessionManager
 	WebSocketManager      WebSocketManager
+	WebRTCManager         WebRTCManager
 	ApiManager            ApiManager
 	LoadServiceFromPlugin func(string) (any, error)
 }
@@ -59,6 +60,10 @@ func (p *PluginManagers) Validate() error {
 		return errors.New("WebSocketManager is nil")
 	}
 
+	if p.WebRTCManager == nil {
+		return errors.New("WebRTCManager is nil")
+	}
+
 	if p.ApiManager == nil {
 		return errors.New("ApiManager is nil")
 	}
@@ -12,6 +12,25 @@ var (
 	ErrSessionAlreadyConnected = errors.New("session is already connected")
 	ErrSessionLoginDisabled    = errors.New("session login disabled")
 	ErrSessionLoginsLocked     = errors.New("session logins locked")
+	ErrSessionHostNotFound     = errors.New("session host not found")
+
+	ErrSessionReconnectTokenInvalid = errors.New("session reconnect token invalid")
+
+	ErrSessionInvitesDisabled = errors.New("session invites disabled")
+	ErrSessionInviteInvalid   = errors.New("session invite invalid")
+	ErrSessionInviteExpired   = errors.New("session invite expired")
+	ErrSessionInviteUsed      = errors.New("session invite already used")
+	ErrSessionInviteRevoked   = errors.New("session invite revoked")
+
+	ErrSessionTokenNotFound = errors.New("session token not found")
+
+	ErrSessionNotPending = errors.New("session is not pending approval")
+	ErrSessionAdmitted   = errors.New("session is already admitted")
+
+	ErrSessionHandoffDisabled = errors.New("session handoff disabled")
+	ErrSessionHandoffInvalid  = errors.New("session handoff invalid")
+	ErrSessionHandoffExpired  = errors.New("session handoff expired")
+	ErrSessionHandoffUsed     = errors.New("session handoff already used")
 )
 
 type Cursor struct {
@@ -19,6 +38,44 @@ type Cursor struct {
 	Y int `json:"y"`
 }
 
+type SessionChangeKind string
+
+const (
+	SessionChangeHost     SessionChangeKind = "host"
+	SessionChangeJoined   SessionChangeKind = "joined"
+	SessionChangeLeft     SessionChangeKind = "left"
+	SessionChangeSettings SessionChangeKind = "settings"
+)
+
+// SessionChange is a human-readable record of a change in the room.
+type SessionChange struct {
+	Time      time.Time         `json:"time"`
+	Kind      SessionChangeKind `json:"kind"`
+	SessionId string            `json:"session_id,omitempty"`
+	Message   string            `json:"message"`
+}
+
+type SessionTokenKind string
+
+const (
+	SessionTokenInvite    SessionTokenKind = "invite"
+	SessionTokenReconnect SessionTokenKind = "reconnect"
+)
+
+// SessionToken describes an outstanding token, without the token itself.
+type SessionToken struct {
+	// nonce of an invite, session id of a reconnect token
+	ID   string           `json:"id"`
+	Kind SessionTokenKind `json:"kind"`
+	// session the token was issued to, or name of the invited profile
+	IssuedTo string    `json:"issued_to"`
+	IssuedAt time.Time `json:"issued_at"`
+	// empty if the token expires with the session
+	Expires *time.Time `json:"expires,omitempty"`
+	// profile granted by an invite
+	Profile *MemberProfile `json:"profile,omitempty"`
+}
+
 type SessionProfile struct {
 	Id      string
 	Token   string
@@ -37,6 +94,36 @@ type SessionState struct {
 	WatchingSince *time.Time `json:"watching_since,omitempty"`
 	// when the session was last not watching
 	NotWatchingSince *time.Time `json:"not_watching_since,omitempty"`
+
+	// waiting for approval by an admin, can only watch until then
+	IsPending bool `json:"is_pending"`
+	// acknowledged the consent, if required, cannot watch or control until then
+	IsAdmitted bool `json:"is_admitted"`
+}
+
+// ReconnectStats describes how often the session reconnects, intervals are
+// between consecutive connections.
+type ReconnectStats struct {
+	Count           int        `json:"count"`
+	LastReconnectAt *time.Time `json:"last_reconnect_at,omitempty"`
+	LastInterval    int64      `json:"last_interval"`    // in milliseconds
+	AverageInterval int64      `json:"average_interval"` // in milliseconds
+	// reconnects within the flapping window
+	RecentCount int  `json:"recent_count"`
+	IsFlapping  bool `json:"is_flapping"`
+}
+
+// Features that can be restricted to sessions connected over secure transport.
+const (
+	FeatureClipboard    = "clipboard"
+	FeatureFileTransfer = "file_transfer"
+	FeatureMediaSharing = "media_sharing"
+)
+
+var SecureFeatures = []string{
+	FeatureClipboard,
+	FeatureFileTransfer,
+	FeatureMediaSharing,
 }
 
 type Settings struct {
@@ -46,8 +133,11 @@ type Settings struct {
 	ControlProtection bool `json:"control_protection"`
 	ImplicitHosting   bool `json:"implicit_hosting"`
 	InactiveCursors   bool `json:"inactive_cursors"`
+	HostCursor        bool `json:"host_cursor"`
 	MercifulReconnect bool `json:"merciful_reconnect"`
 	HeartbeatInterval int  `json:"heartbeat_interval"`
+	JoinApproval      bool `json:"join_approval"`
+	HostTakeoverGrace int  `json:"host_takeover_grace"`
 
 	// plugin scope
 	Plugins PluginSettings `json:"plugins"`
@@ -67,16 +157,30 @@ type Session interface {
 	ID() string
 	Profile() MemberProfile
 	State() SessionState
+	ReconnectToken() string
+	ChangesWhileAway() []SessionChange
 	IsHost() bool
 	LegacyIsHost() bool
 	SetAsHost()
 	SetAsHostBy(session Session)
 	ClearHost()
 	PrivateModeEnabled() bool
+	// cursor position of the host is sent to this session
+	HostCursorVisible() bool
+	// features disabled, because the connection is not secure
+	DisabledFeatures() []string
+	FeatureDisabled(feature string) bool
+	Reconnects() ReconnectStats
 
 	// cursor
 	SetCursor(cursor Cursor)
 
+	// scratch store, cleared on disconnect
+	SetValue(key string, value any)
+	Value(key string) (any, bool)
+	UpdateValue(key string, f func(value any) any)
+	DeleteValue(key string)
+
 	// websocket
 	ConnectWebSocketPeer(websocketPeer WebSocketPeer)
 	DisconnectWebSocketPeer(websocketPeer WebSocketPeer, delayed bool)
@@ -100,6 +204,8 @@ type SessionManager interface {
 	Range(func(Session) bool)
 
 	GetHost() (Session, bool)
+	HostLocked() bool
+	SetHostLocked(session Session, locked bool) error
 
 	SetCursor(cursor Cursor, session Session)
 	PopCursors() map[Session][]Cursor
@@ -115,7 +221,9 @@ type SessionManager interface {
 	OnProfileChanged(listener func(session Session, new, old MemberProfile))
 	OnStateChanged(listener func(session Session))
 	OnHostChanged(listener func(session, host Session))
+	OnHostLockChanged(listener func(session Session, locked bool))
 	OnSettingsChanged(listener func(session Session, new, old Settings))
+	OnFlapping(listener func(session Session, stats ReconnectStats))
 
 	UpdateSettingsFunc(session Session, f func(settings *Settings) bool)
 	Settings() Settings
@@ -126,4 +234,19 @@ type SessionManager interface {
 	CookieSetToken(w http.ResponseWriter, token string)
 	CookieClearToken(w http.ResponseWriter, r *http.Request)
 	Authenticate(r *http.Request) (Session, error)
+	Resume(token string) (Session, error)
+	CreateInvite(profile MemberProfile, ttl time.Duration) (string, time.Time, error)
+	RedeemInvite(token string) (Session, string, error)
+	Tokens() []SessionToken
+	RevokeToken(kind SessionTokenKind, id string) error
+	CreateHandoff(id string) (string, error)
+	RedeemHandoff(token string) (Session, string, error)
+	ApplyClaims(session Session, r *http.Request) error
+	DisableInsecureFeatures(session Session, r *http.Request)
+
+	Approve(id string) error
+	Deny(id string) error
+
+	Consent() (text string, version string, timeout time.Duration)
+	AcknowledgeConsent(id string) error
 }
@@ -2,6 +2,7 @@ package types
 
 import (
 	"errors"
+	"net/http"
 
 	"github.com/pion/webrtc/v3"
 )
@@ -10,12 +11,24 @@ var (
 	ErrWebRTCDataChannelNotFound = errors.New("webrtc data channel not found")
 	ErrWebRTCConnectionNotFound  = errors.New("webrtc connection not found")
 	ErrWebRTCStreamNotFound      = errors.New("webrtc stream not found")
+	ErrWebRTCOfferIgnored        = errors.New("webrtc colliding offer ignored")
+	ErrWebRTCNoVideoStreams      = errors.New("webrtc no video streams available")
+	ErrWebRTCRelayLimit          = errors.New("webrtc relay limit reached")
+	ErrWebRTCServerBusy          = errors.New("webrtc server busy")
+	ErrWebRTCDataOnly            = errors.New("webrtc peer has data channel only")
+	ErrWebRTCDataChannelClaimed  = errors.New("webrtc data channel label already claimed")
 )
 
+// WebRTCDataChannelHandler is called when client opens data channel with
+// claimed label, it is responsible for reading and closing the channel.
+type WebRTCDataChannelHandler func(session Session, dc *webrtc.DataChannel)
+
 type ICEServer struct {
 	URLs       []string `mapstructure:"urls"       json:"urls"`
 	Username   string   `mapstructure:"username"   json:"username,omitempty"`
 	Credential string   `mapstructure:"credential" json:"credential,omitempty"`
+	// servers of the client region are preferred, not sent to clients
+	Region string `mapstructure:"region" json:"-"`
 }
 
 type PeerVideo struct {
@@ -23,6 +36,7 @@ type PeerVideo struct {
 	ID       string `json:"id"`
 	Video    string `json:"video"` // TODO: Remove this, used for compatibility with old clients.
 	Auto     bool   `json:"auto"`
+	Cap      string `json:"cap,omitempty"` // video manually selected by the user
 }
 
 type PeerVideoRequest struct {
@@ -33,17 +47,97 @@ type PeerVideoRequest struct {
 
 type PeerAudio struct {
 	Disabled bool `json:"disabled"`
+	Track    bool `json:"track"`
 }
 
 type PeerAudioRequest struct {
 	Disabled *bool `json:"disabled,omitempty"`
+	// add or remove audio track, peer connection is renegotiated
+	Track *bool `json:"track,omitempty"`
+}
+
+// estimated audio/video synchronization of a peer
+type PeerAVSync struct {
+	// audio is played late by this offset, negative when it is played early
+	Offset int64 `json:"offset"` // in milliseconds
+	// from capture until the media was sent
+	AudioLatency int64 `json:"audio_latency"` // in milliseconds
+	VideoLatency int64 `json:"video_latency"` // in milliseconds
+	// round trip time reported by the receiver, zero if unknown
+	AudioRTT int64 `json:"audio_rtt"` // in milliseconds
+	VideoRTT int64 `json:"video_rtt"` // in milliseconds
+}
+
+type PeerBandwidth struct {
+	// bandwidth available to the peer estimated by the server
+	Estimate int `json:"estimate"` // in bits per second
+	// bitrate of the video stream the peer receives, zero if unknown
+	StreamBitrate uint64 `json:"stream_bitrate"` // in bits per second
+	VideoID       string `json:"video_id"`
+	// video is switched automatically based on the estimate
+	Auto bool `json:"auto"`
 }
 
+type PeerTrackStats struct {
+	// outbound bitrate since the previous statistics
+	Bitrate uint64 `json:"bitrate"` // in bits per second
+	// cumulative number of packets lost reported by the receiver
+	PacketsLost int64 `json:"packets_lost"`
+	// packets lost since the previous receiver report
+	FractionLost float64 `json:"fraction_lost"` // in percent
+	Jitter       int64   `json:"jitter"`        // in milliseconds
+	// cumulative number of packets retransmission was requested for
+	Nacks uint64 `json:"nacks"`
+	// round trip time reported by the receiver, zero if unknown
+	RTT int64 `json:"rtt"` // in milliseconds
+}
+
+type PeerStats struct {
+	// nil when the peer does not receive the track
+	Audio *PeerTrackStats `json:"audio,omitempty"`
+	Video *PeerTrackStats `json:"video,omitempty"`
+	// types of selected ICE candidates (host, srflx, prflx, relay),
+	// empty if no candidate pair is selected yet
+	LocalCandidate  string `json:"local_candidate"`
+	RemoteCandidate string `json:"remote_candidate"`
+	Protocol        string `json:"protocol"`
+}
+
+// where shared microphone of a peer is routed to
+type MicrophoneRoute string
+
+const (
+	// to the desktop microphone
+	MicrophoneRouteDesktop MicrophoneRoute = "desktop"
+	// to the outbound audio heard by everyone
+	MicrophoneRouteMix MicrophoneRoute = "mix"
+	// to both, desktop microphone and outbound audio
+	MicrophoneRouteBoth MicrophoneRoute = "both"
+)
+
+// cursor updates sent to a peer over data channel
+type CursorMode string
+
+const (
+	// cursor image and position
+	CursorModeFull CursorMode = "full"
+	// only cursor position, client renders default cursor
+	CursorModePosition CursorMode = "position"
+	// no cursor updates
+	CursorModeNone CursorMode = "none"
+)
+
 type WebRTCPeer interface {
+	// unique identifier, clients reference it when reconnecting websocket
+	ID() string
+
 	CreateOffer(ICERestart bool) (*webrtc.SessionDescription, error)
+	// sends client an offer restarting ICE, tracks and data channel are kept
+	RestartICE() error
 	CreateAnswer() (*webrtc.SessionDescription, error)
 	SetRemoteDescription(webrtc.SessionDescription) error
 	SetCandidate(webrtc.ICECandidateInit) error
+	ICEServers() []ICEServer
 
 	SetPaused(isPaused bool) error
 	Paused() bool
@@ -52,7 +146,15 @@ type WebRTCPeer interface {
 	Video() PeerVideo
 	SetAudio(PeerAudioRequest) error
 	Audio() PeerAudio
-
+	SetMicrophoneRoute(MicrophoneRoute) error
+	MicrophoneRoute() MicrophoneRoute
+	// false, when peer does not receive both audio and video
+	AVSync() (PeerAVSync, bool)
+	// false, when bandwidth estimator is disabled
+	Bandwidth() (PeerBandwidth, bool)
+
+	SetCursorMode(CursorMode) error
+	CursorMode() CursorMode
 	SendCursorPosition(x, y int) error
 	SendCursorImage(cur *CursorImage, img []byte) error
 
@@ -65,6 +167,15 @@ type WebRTCManager interface {
 
 	ICEServers() []ICEServer
 
-	CreatePeer(session Session) (*webrtc.SessionDescription, WebRTCPeer, error)
+	// data only peers have no media, they are used only to control the desktop
+	CreatePeer(session Session, dataOnly bool) (*webrtc.SessionDescription, WebRTCPeer, error)
+	ClosePeers(session Session)
+	// called when session is deleted
+	ForgetSession(session Session)
+	PinPublicIP(session Session, r *http.Request)
+	PinRegion(session Session, r *http.Request)
 	SetCursorPosition(x, y int)
+
+	// data channels opened by clients are passed to handler claiming their label
+	AddDataChannelHandler(label string, handler WebRTCDataChannelHandler) error
 }
@@ -2,7 +2,14 @@ package types
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
+)
+
+var (
+	ErrWebSocketHandlerName  = errors.New("websocket handler must have a name")
+	ErrWebSocketHandlerLimit = errors.New("websocket handler limit reached")
 )
 
 type WebSocketMessage struct {
@@ -20,9 +27,25 @@ type WebSocketPeer interface {
 	Destroy(reason string)
 }
 
+// LifecycleEvent describes a change of session lifecycle, it is published
+// for observability tooling and is not part of the client protocol.
+type LifecycleEvent struct {
+	Type      string    `json:"type"`
+	SessionID string    `json:"session_id,omitempty"`
+	Time      time.Time `json:"time"`
+	Data      any       `json:"data,omitempty"`
+}
+
 type WebSocketManager interface {
 	Start()
 	Shutdown() error
 	AddHandler(handler WebSocketHandler)
+	// handler registered again with the same name replaces the previous one
+	AddNamedHandler(name string, handler WebSocketHandler) error
 	Upgrade(checkOrigin CheckOrigin) RouterHandler
+	// desktop and capture are ready for connections
+	Ready() bool
+
+	SubscribeLifecycle() (<-chan LifecycleEvent, func())
+	LifecycleRoute(r Router)
 }
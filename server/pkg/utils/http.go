@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -131,3 +132,24 @@ func HttpUnprocessableEntity(res ...string) *HTTPError {
 func HttpInternalServerError(res ...string) *HTTPError {
 	return HttpError(http.StatusInternalServerError, res...)
 }
+
+type peerAddrKey struct{}
+
+// WithPeerAddr keeps address of the connected peer in the request context, it
+// must run before RemoteAddr is rewritten from client supplied headers.
+func WithPeerAddr(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), peerAddrKey{}, r.RemoteAddr)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// PeerAddr returns address of the connected peer, unlike RemoteAddr it is not
+// affected by forwarded headers.
+func PeerAddr(r *http.Request) string {
+	if addr, ok := r.Context().Value(peerAddrKey{}).(string); ok {
+		return addr
+	}
+
+	return r.RemoteAddr
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// Throttle calls function at most once per interval. Calls within the interval
+// are coalesced into a single call at the end of the interval.
+type Throttle struct {
+	mu       sync.Mutex
+	interval time.Duration
+	fn       func()
+	last     time.Time
+	timer    *time.Timer
+}
+
+func NewThrottle(interval time.Duration, fn func()) *Throttle {
+	return &Throttle{
+		interval: interval,
+		fn:       fn,
+	}
+}
+
+func (t *Throttle) Trigger() {
+	t.mu.Lock()
+
+	// call is already scheduled
+	if t.timer != nil {
+		t.mu.Unlock()
+		return
+	}
+
+	wait := t.interval - time.Since(t.last)
+	if wait <= 0 {
+		t.last = time.Now()
+		t.mu.Unlock()
+		t.fn()
+		return
+	}
+
+	t.timer = time.AfterFunc(wait, func() {
+		t.mu.Lock()
+		t.timer = nil
+		t.last = time.Now()
+		t.mu.Unlock()
+		t.fn()
+	})
+	t.mu.Unlock()
+}
+
+// Stop cancels scheduled call, if any.
+func (t *Throttle) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+}
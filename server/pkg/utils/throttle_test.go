@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestThrottleCoalesce(t *testing.T) {
+	var calls atomic.Int32
+	throttle := NewThrottle(50*time.Millisecond, func() { calls.Add(1) })
+
+	// first call goes through right away
+	throttle.Trigger()
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("calls = %d after first trigger, want 1", n)
+	}
+
+	// rapid calls within the interval are coalesced into one
+	for i := 0; i < 10; i++ {
+		throttle.Trigger()
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("calls = %d within interval, want 1", n)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("calls = %d after interval, want 2", n)
+	}
+}
+
+func TestThrottleStop(t *testing.T) {
+	var calls atomic.Int32
+	throttle := NewThrottle(20*time.Millisecond, func() { calls.Add(1) })
+
+	throttle.Trigger()
+	throttle.Trigger()
+	throttle.Stop()
+
+	time.Sleep(50 * time.Millisecond)
+	if n := calls.Load(); n != 1 {
+		t.Errorf("calls = %d, scheduled call was not cancelled", n)
+	}
+}